@@ -0,0 +1,215 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// accessLogFields lists every field an access-log record can carry, in output
+// order.
+var accessLogFields = []string{
+	"host", "method", "path", "query", "proto", "status", "duration_ms", "bytes",
+	"container", "group", "client_ip", "user_agent", "referer", "request_id",
+}
+
+// defaultAccessLogFields is used when access_log.fields is empty. Query
+// strings, user agents and referers are opt-in: they are verbose and may
+// carry tokens.
+var defaultAccessLogFields = []string{
+	"host", "method", "path", "status", "duration_ms", "bytes",
+	"container", "group", "client_ip", "request_id",
+}
+
+// knownAccessLogFields is the lookup set of accessLogFields.
+var knownAccessLogFields = func() map[string]bool {
+	m := make(map[string]bool, len(accessLogFields))
+	for _, f := range accessLogFields {
+		m[f] = true
+	}
+	return m
+}()
+
+// accessEntry describes one proxied request.
+type accessEntry struct {
+	Host      string
+	Method    string
+	Path      string
+	Query     string
+	Proto     string
+	Status    int
+	Duration  time.Duration
+	Bytes     int64
+	Container string
+	Group     string
+	ClientIP  string
+	UserAgent string
+	Referer   string
+	RequestID string
+}
+
+// AccessLogger writes one structured JSON record per proxied request, to
+// stdout or to a rotating file, and to the syslog / Loki forwarder.
+type AccessLogger struct {
+	stdout io.Writer
+
+	mu      sync.RWMutex
+	enabled bool
+	fields  []string
+	sample  uint64
+	logger  *slog.Logger
+	file    *RotatingFile
+	fileCfg LogFileConfig
+
+	// successes counts the successful requests seen, to pick one in sample.
+	successes atomic.Uint64
+}
+
+// NewAccessLogger creates a disabled AccessLogger writing JSON lines to
+// stdout unless a file is configured.
+func NewAccessLogger(stdout io.Writer) *AccessLogger {
+	return &AccessLogger{
+		stdout: stdout,
+		fields: defaultAccessLogFields,
+		sample: 1,
+		logger: newAccessJSONLogger(stdout),
+	}
+}
+
+// newAccessJSONLogger writes records to w and to the syslog / Loki forwarder.
+func newAccessJSONLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(io.MultiWriter(w, ForwardedLog("access")), nil))
+}
+
+// Sync applies the access-log configuration. The log file is reopened only
+// when its settings changed; if it cannot be opened, records go to stdout.
+func (a *AccessLogger) Sync(cfg AccessLogConfig) {
+	fields := cfg.Fields
+	if len(fields) == 0 {
+		fields = defaultAccessLogFields
+	}
+	fileCfg := cfg.File
+	if !cfg.Enabled {
+		fileCfg = LogFileConfig{}
+	}
+
+	a.mu.Lock()
+	a.enabled = cfg.Enabled
+	a.fields = fields
+	a.sample = uint64(max(cfg.Sample, 1))
+	var old *RotatingFile
+	if fileCfg != a.fileCfg {
+		old = a.file
+		a.file, a.fileCfg = nil, fileCfg
+		a.logger = newAccessJSONLogger(a.stdout)
+		if fileCfg.Path != "" {
+			if f, err := OpenRotatingFile(fileCfg); err != nil {
+				slog.Error("access log: cannot open file, writing to stdout", "error", err)
+			} else {
+				a.file = f
+				a.logger = newAccessJSONLogger(f)
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+}
+
+// Close closes the access-log file, if any.
+func (a *AccessLogger) Close() error {
+	a.mu.Lock()
+	f := a.file
+	a.file, a.fileCfg = nil, LogFileConfig{}
+	a.logger = newAccessJSONLogger(a.stdout)
+	a.mu.Unlock()
+	if f != nil {
+		return f.Close()
+	}
+	return nil
+}
+
+// Enabled reports whether records are written for the given container.
+func (a *AccessLogger) Enabled(cfg *ContainerConfig) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.enabled && cfg != nil && !cfg.DisableAccessLog
+}
+
+// Log writes e with the configured field set. With sampling, only one in
+// sample successful requests is written; errors are always written.
+func (a *AccessLogger) Log(e accessEntry) {
+	a.mu.RLock()
+	fields, logger, sample := a.fields, a.logger, a.sample
+	a.mu.RUnlock()
+	if e.Status < 400 && sample > 1 && a.successes.Add(1)%sample != 0 {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		if attr, ok := e.attr(f); ok {
+			attrs = append(attrs, attr)
+		}
+	}
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "access", attrs...)
+}
+
+// attr returns the named field of e. Empty optional values are omitted.
+func (e accessEntry) attr(field string) (slog.Attr, bool) {
+	str := func(key, v string) (slog.Attr, bool) {
+		return slog.String(key, v), v != ""
+	}
+	switch field {
+	case "host":
+		return str(field, e.Host)
+	case "method":
+		return str(field, e.Method)
+	case "path":
+		return str(field, e.Path)
+	case "query":
+		return str(field, e.Query)
+	case "proto":
+		return str(field, e.Proto)
+	case "status":
+		return slog.Int(field, e.Status), true
+	case "duration_ms":
+		return slog.Float64(field, float64(e.Duration.Microseconds())/1000), true
+	case "bytes":
+		return slog.Int64(field, e.Bytes), true
+	case "container":
+		return str(field, e.Container)
+	case "group":
+		return str(field, e.Group)
+	case "client_ip":
+		return str(field, e.ClientIP)
+	case "user_agent":
+		return str(field, e.UserAgent)
+	case "referer":
+		return str(field, e.Referer)
+	case "request_id":
+		return str(field, e.RequestID)
+	}
+	return slog.Attr{}, false
+}
+
+// newAccessEntry captures the request side of an access-log record.
+func (s *Server) newAccessEntry(r *http.Request) accessEntry {
+	return accessEntry{
+		Host:      r.Host,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		Proto:     r.Proto,
+		ClientIP:  s.clientIP(r),
+		UserAgent: r.UserAgent(),
+		Referer:   r.Referer(),
+		RequestID: requestIDFrom(r.Context(), "req"),
+	}
+}
@@ -0,0 +1,150 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ─── Records ──────────────────────────────────────────────────────────────────
+
+func testAccessEntry() accessEntry {
+	return accessEntry{
+		Host:      "app.example.com",
+		Method:    "GET",
+		Path:      "/api/items",
+		Query:     "token=secret",
+		Status:    200,
+		Duration:  1500 * time.Microsecond,
+		Bytes:     512,
+		Container: "app",
+		ClientIP:  "203.0.113.7",
+		UserAgent: "curl/8.0",
+		RequestID: "req-1",
+	}
+}
+
+func decodeAccessRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("access record is not JSON: %v (%q)", err, buf.String())
+	}
+	return rec
+}
+
+func TestAccessLogger_DefaultFields(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAccessLogger(&buf)
+	a.Sync(AccessLogConfig{Enabled: true})
+	a.Log(testAccessEntry())
+
+	rec := decodeAccessRecord(t, &buf)
+	if rec["msg"] != "access" {
+		t.Errorf("msg = %v, want access", rec["msg"])
+	}
+	want := map[string]any{
+		"host":        "app.example.com",
+		"method":      "GET",
+		"path":        "/api/items",
+		"status":      float64(200),
+		"duration_ms": 1.5,
+		"bytes":       float64(512),
+		"container":   "app",
+		"client_ip":   "203.0.113.7",
+		"request_id":  "req-1",
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("%s = %v, want %v", k, rec[k], v)
+		}
+	}
+	for _, k := range []string{"query", "user_agent", "group"} {
+		if _, ok := rec[k]; ok {
+			t.Errorf("%s should not be logged by default", k)
+		}
+	}
+}
+
+func TestAccessLogger_CustomFields(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAccessLogger(&buf)
+	a.Sync(AccessLogConfig{Enabled: true, Fields: []string{"path", "query", "user_agent"}})
+	a.Log(testAccessEntry())
+
+	rec := decodeAccessRecord(t, &buf)
+	if rec["query"] != "token=secret" || rec["user_agent"] != "curl/8.0" {
+		t.Errorf("record = %v, want opted-in query and user_agent", rec)
+	}
+	if _, ok := rec["status"]; ok {
+		t.Error("status was not selected and should be omitted")
+	}
+}
+
+// ─── Enablement ───────────────────────────────────────────────────────────────
+
+func TestAccessLogger_Enabled(t *testing.T) {
+	a := NewAccessLogger(&bytes.Buffer{})
+	app := &ContainerConfig{Name: "app"}
+	quiet := &ContainerConfig{Name: "quiet", DisableAccessLog: true}
+
+	if a.Enabled(app) {
+		t.Error("access log should be disabled by default")
+	}
+	a.Sync(AccessLogConfig{Enabled: true})
+	if !a.Enabled(app) {
+		t.Error("access log should be enabled for app")
+	}
+	if a.Enabled(quiet) {
+		t.Error("disable_access_log should suppress records")
+	}
+	if a.Enabled(nil) {
+		t.Error("requests not routed to a container are not logged")
+	}
+}
+
+func TestAccessLogger_Sample(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAccessLogger(&buf)
+	a.Sync(AccessLogConfig{Enabled: true, Sample: 10})
+
+	ok, failed := testAccessEntry(), testAccessEntry()
+	failed.Status = 502
+	for i := 0; i < 30; i++ {
+		a.Log(ok)
+	}
+	for i := 0; i < 3; i++ {
+		a.Log(failed)
+	}
+
+	if n := bytes.Count(buf.Bytes(), []byte(`"status":200`)); n != 3 {
+		t.Errorf("successful records = %d, want 3 (1 in 10 of 30)", n)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte(`"status":502`)); n != 3 {
+		t.Errorf("error records = %d, want all 3", n)
+	}
+}
+
+func TestAccessLogger_File(t *testing.T) {
+	var stdout bytes.Buffer
+	a := NewAccessLogger(&stdout)
+	path := filepath.Join(t.TempDir(), "access.log")
+	a.Sync(AccessLogConfig{Enabled: true, File: LogFileConfig{Path: path, MaxSizeMB: 1}})
+	a.Log(testAccessEntry())
+	a.Close()
+
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want records in the file only", stdout.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading access log: %v", err)
+	}
+	rec := decodeAccessRecord(t, bytes.NewBuffer(data))
+	if rec["container"] != "app" {
+		t.Errorf("file record = %v", rec)
+	}
+}
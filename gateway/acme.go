@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// newACMEManager builds the certificate manager of gateway.acme. Certificates
+// are only requested for hosts policy accepts.
+func newACMEManager(cfg ACMEConfig, policy autocert.HostPolicy) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		HostPolicy: policy,
+		Email:      cfg.Email,
+	}
+	if cfg.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+	return m
+}
+
+// acmeHostPolicy accepts the hosts the gateway routes — containers, groups
+// and host_pattern names of known containers, static or discovered — and
+// acme.hosts. Anything else is refused, so a client cannot make the gateway
+// request certificates for names it picked.
+func (s *Server) acmeHostPolicy(_ context.Context, host string) error {
+	if s.acmeHost(host) {
+		return nil
+	}
+	return fmt.Errorf("acme: host %q is not served by the gateway", host)
+}
+
+// acmeHost reports whether host gets a certificate.
+func (s *Server) acmeHost(host string) bool {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return false
+	}
+	if slices.ContainsFunc(s.acmeCfg.Hosts, func(h string) bool { return strings.EqualFold(h, host) }) {
+		return true
+	}
+	return s.matchRoute(host, "/").Kind != "none" || s.hasPrefixRoutes(host)
+}
+
+// acmeTLSConfig serves the certificates of m and answers TLS-ALPN-01
+// challenges. HTTP/2 is not offered: WebSocket tunnels and half-closes rely
+// on HTTP/1.1 connections, as on the plain listener.
+func acmeTLSConfig(m *autocert.Manager) *tls.Config {
+	cfg := m.TLSConfig()
+	cfg.NextProtos = []string{"http/1.1", acme.ALPNProto}
+	return cfg
+}
+
+// plainHandler is the handler of gateway.port. With gateway.acme it answers
+// HTTP-01 challenges first and, with redirect_http, sends the hosts that
+// have a certificate to HTTPS.
+func (s *Server) plainHandler() http.Handler {
+	if s.acme == nil {
+		return s
+	}
+	var next http.Handler = s
+	if s.acmeCfg.RedirectHTTP {
+		next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.acmeHost(r.Host) {
+				s.ServeHTTP(w, r)
+				return
+			}
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if s.acmeCfg.HTTPSPort != "443" {
+				host = net.JoinHostPort(host, s.acmeCfg.HTTPSPort)
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		})
+	}
+	return s.acme.HTTPHandler(next)
+}
+
+// listenTLS binds the HTTPS port of gateway.acme and serves on it in the
+// background, with the same settings as listen. The caller holds listenMu.
+func (s *Server) listenTLS(port string) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           s,
+		TLSConfig:         acmeTLSConfig(s.acme),
+		ReadHeaderTimeout: s.srvCfg.ReadHeaderTimeout,
+		ReadTimeout:       s.srvCfg.ReadTimeout,
+		WriteTimeout:      s.srvCfg.WriteTimeout,
+		IdleTimeout:       s.srvCfg.IdleTimeout,
+		MaxHeaderBytes:    s.srvCfg.MaxHeaderBytes,
+		ConnState:         trackConnState,
+	}
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("acme: cannot listen on https_port %s: %w", port, err)
+	}
+	s.tlsAddr = ln.Addr()
+	ln = newLimitListener(ln, s.srvCfg.MaxConnections)
+
+	serveErr := s.serveErr
+	go func() {
+		if err := srv.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+			select {
+			case serveErr <- err:
+			default:
+			}
+		}
+	}()
+	return srv, nil
+}
+
+// TLSAddr returns the address HTTPS is served on, or nil without
+// gateway.acme or before Start.
+func (s *Server) TLSAddr() net.Addr {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	return s.tlsAddr
+}
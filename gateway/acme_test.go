@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func acmeTestConfig(t *testing.T, port string) *GatewayConfig {
+	return &GatewayConfig{
+		Gateway: GlobalConfig{
+			HostPattern: "{container}.apps.example.com",
+			ACME: ACMEConfig{
+				Enabled:  true,
+				CacheDir: t.TempDir(),
+				Hosts:    []string{"Dash.example.com"},
+			},
+		},
+		Containers: []ContainerConfig{
+			{Name: "app", Host: "app.example.com", TargetPort: port},
+			{Name: "db", TargetPort: "5432"},
+		},
+		Groups: []GroupConfig{{Name: "web", Host: "web.example.com", Members: []GroupMember{{Name: "app"}}}},
+	}
+}
+
+func TestACMEHostPolicy(t *testing.T) {
+	g := newFakeGatewayConfig(t, NewFakeRuntime(), acmeTestConfig(t, "80"))
+	g.server.acmeCfg = g.server.GetConfig().Gateway.ACME
+
+	for host, want := range map[string]bool{
+		"app.example.com":       true,
+		"APP.example.com:443":   true,
+		"web.example.com":       true,
+		"db.apps.example.com":   true,
+		"dash.example.com":      true,
+		"nope.apps.example.com": false,
+		"evil.example.net":      false,
+		"192.0.2.10":            false,
+		"":                      false,
+	} {
+		if err := g.server.acmeHostPolicy(context.Background(), host); (err == nil) != want {
+			t.Errorf("acmeHostPolicy(%q) = %v, want accepted=%v", host, err, want)
+		}
+	}
+}
+
+// writeCachedCert stores a self-signed certificate for host in dir the way
+// autocert caches the ones it obtained.
+func writeCachedCert(t *testing.T, dir, host string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	if err := os.WriteFile(filepath.Join(dir, host), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestACME_ServesHTTPS(t *testing.T) {
+	host, port := newBackend(t, "ok", echoHeaders("X-Forwarded-Proto"))
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	cfg := acmeTestConfig(t, port)
+	cfg.Gateway.ACME.HTTPSPort = "0"
+	g := newFakeGatewayConfig(t, rt, cfg)
+	cert := writeCachedCert(t, cfg.Gateway.ACME.CacheDir, "app.example.com")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.server.listener = ln
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- g.server.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var addr net.Addr
+	for deadline := time.Now().Add(5 * time.Second); addr == nil && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		addr = g.server.TLSAddr()
+	}
+	if addr == nil {
+		t.Fatal("HTTPS listener not started")
+	}
+	_, tlsPort, _ := net.SplitHostPort(addr.String())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "app.example.com"},
+	}}
+	req, _ := http.NewRequest(http.MethodGet, "https://127.0.0.1:"+tlsPort+"/", nil)
+	req.Host = "app.example.com"
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Echo-X-Forwarded-Proto") != "https" {
+		t.Errorf("status %d, X-Forwarded-Proto %q; want 200 and https", resp.StatusCode, resp.Header.Get("X-Echo-X-Forwarded-Proto"))
+	}
+
+	// A name the gateway does not route gets no certificate.
+	conn, err := tls.Dial("tcp", addr.String(), &tls.Config{ServerName: "evil.example.net", InsecureSkipVerify: true})
+	if err == nil {
+		conn.Close()
+		t.Error("TLS handshake for an unknown host succeeded")
+	}
+}
+
+func TestACME_PlainHandler(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited"})
+	cfg := acmeTestConfig(t, "80")
+	cfg.Gateway.ACME.RedirectHTTP = true
+	g := newFakeGatewayConfig(t, rt, cfg)
+	g.server.handler.Store(g.handler)
+	g.server.acmeCfg = g.server.GetConfig().Gateway.ACME
+	g.server.acme = newACMEManager(g.server.acmeCfg, g.server.acmeHostPolicy)
+	h := g.server.plainHandler()
+
+	serve := func(host, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Host = host
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// Challenges are answered by the certificate manager, not proxied.
+	if w := serve("app.example.com", "/.well-known/acme-challenge/token"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown challenge token: status %d, want 404", w.Code)
+	}
+	if calls := rt.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want the container left asleep", calls)
+	}
+
+	w := serve("app.example.com:8080", "/path?q=1")
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "https://app.example.com:8443/path?q=1" {
+		t.Errorf("redirect: status %d, Location %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := serve("localhost:8080", "/_gateway/healthz"); w.Code != http.StatusOK {
+		t.Errorf("healthz on localhost: status %d, want 200 without redirect", w.Code)
+	}
+}
+
+func TestValidate_ACME(t *testing.T) {
+	for _, tt := range []struct {
+		acme ACMEConfig
+		want string
+	}{
+		{ACMEConfig{Enabled: true}, "acme: cache_dir is required"},
+		{ACMEConfig{Enabled: true, CacheDir: "/data", HTTPSPort: "8080"}, "already used by port"},
+		{ACMEConfig{Enabled: true, CacheDir: "/data", DirectoryURL: "http://ca.local/dir"}, "must be an https:// URL"},
+		{ACMEConfig{Enabled: true, CacheDir: "/data", Hosts: []string{"*.example.com"}}, "plain host name"},
+	} {
+		cfg := &GatewayConfig{Gateway: GlobalConfig{ACME: tt.acme}}
+		applyDefaults(cfg)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: error = %v, want %q", tt.acme, err, tt.want)
+		}
+	}
+}
@@ -20,7 +20,8 @@ func adminAuthMiddleware(next http.Handler, cfg *AdminAuthConfig) http.Handler {
 			if !checkBasicAuth(r, cfg.Username, cfg.Password) {
 				w.Header().Set("WWW-Authenticate", `Basic realm="DAG Admin"`)
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				slog.Warn("admin auth failed",
+				RecordAdminAuthFailure("basic")
+				slog.WarnContext(r.Context(), "admin auth failed",
 					"method", "basic",
 					"remote", r.RemoteAddr,
 					"path", r.URL.Path,
@@ -33,7 +34,8 @@ func adminAuthMiddleware(next http.Handler, cfg *AdminAuthConfig) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if !checkBearerToken(r, cfg.Token) {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				slog.Warn("admin auth failed",
+				RecordAdminAuthFailure("bearer")
+				slog.WarnContext(r.Context(), "admin auth failed",
 					"method", "bearer",
 					"remote", r.RemoteAddr,
 					"path", r.URL.Path,
@@ -0,0 +1,383 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// adminAPIContainers is the collection of the container CRUD API.
+const adminAPIContainers = "/_api/v1/containers"
+
+// maxAPIBody bounds the container definition a request may send.
+const maxAPIBody = 1 << 20
+
+// apiContainerJSON is a container as served by /_api/v1/containers. Config
+// uses the keys of config.yaml, with every default filled in.
+type apiContainerJSON struct {
+	Name   string         `json:"name"`
+	Source string         `json:"source"`
+	Config map[string]any `json:"config"`
+}
+
+// handleAPIContainers serves the container CRUD API of gateway.admin_api:
+//
+//	GET    /_api/v1/containers         list the active containers
+//	POST   /_api/v1/containers         add a container
+//	GET    /_api/v1/containers/{name}  show one
+//	PUT    /_api/v1/containers/{name}  replace its definition
+//	DELETE /_api/v1/containers/{name}  remove it
+//
+// Changes apply to the static configuration, like an edit of config.yaml
+// followed by SIGHUP, and are reported by /_admin/reload/status. The
+// container actions, which leave the configuration alone, are served
+// without admin_api.enabled:
+//
+//	POST   /_api/v1/containers/{name}/kill   same as /_status/kill
+//	POST   /_api/v1/containers/{name}/reset  same as /_status/reset
+func (s *Server) handleAPIContainers(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, adminAPIContainers), "/"), "/")
+	if action != "" {
+		s.apiContainerAction(w, r, name, action)
+		return
+	}
+	api := s.GetConfig().Gateway.AdminAPI
+	if !api.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && !validateOrigin(r) {
+		http.Error(w, "cross-origin request blocked", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		s.apiListContainers(w)
+	case name == "" && r.Method == http.MethodPost:
+		s.apiChangeContainer(w, r, "", api.Persist)
+	case name != "" && r.Method == http.MethodGet:
+		s.apiGetContainer(w, name)
+	case name != "" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		s.apiChangeContainer(w, r, name, api.Persist)
+	default:
+		if name == "" {
+			w.Header().Set("Allow", "GET, POST")
+		} else {
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+		}
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// apiContainerAction serves POST /_api/v1/containers/{name}/{action}.
+func (s *Server) apiContainerAction(w http.ResponseWriter, r *http.Request, name, action string) {
+	var do func(http.ResponseWriter, *http.Request, string)
+	switch action {
+	case "kill":
+		do = s.killContainer
+	case "reset":
+		do = s.resetContainer
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !validateOrigin(r) {
+		http.Error(w, "cross-origin request blocked", http.StatusForbidden)
+		return
+	}
+	if !s.allowRate(w, r, "status_"+action) {
+		return
+	}
+	if s.activeContainer(name) == nil {
+		http.Error(w, "unknown container", http.StatusNotFound)
+		return
+	}
+	do(w, r, name)
+}
+
+func (s *Server) apiListContainers(w http.ResponseWriter) {
+	cfg := s.GetConfig()
+	out := struct {
+		Containers []apiContainerJSON `json:"containers"`
+	}{Containers: make([]apiContainerJSON, 0, len(cfg.Containers))}
+	for i := range cfg.Containers {
+		doc, err := containerDocument(&cfg.Containers[i])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out.Containers = append(out.Containers, doc)
+	}
+	writeAPIJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) apiGetContainer(w http.ResponseWriter, name string) {
+	if s.activeContainer(name) == nil {
+		http.Error(w, "unknown container", http.StatusNotFound)
+		return
+	}
+	s.apiWriteContainer(w, name, http.StatusOK)
+}
+
+// activeContainer returns the container name of the active configuration,
+// static or discovered.
+func (s *Server) activeContainer(name string) *ContainerConfig {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.containerMap[name]
+}
+
+// apiChangeContainer adds (POST, name ""), replaces (PUT) or removes
+// (DELETE) a container of the static configuration and applies the result.
+// With persist the change is then written to the config file.
+func (s *Server) apiChangeContainer(w http.ResponseWriter, r *http.Request, name string, persist bool) {
+	var (
+		ctr ContainerConfig
+		raw *yaml.Node
+	)
+	if r.Method != http.MethodDelete {
+		var err error
+		ctr, raw, err = decodeAPIContainer(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch {
+		case name == "" && ctr.Name == "":
+			http.Error(w, "container is missing required field 'name'", http.StatusBadRequest)
+			return
+		case name != "" && ctr.Name == "":
+			ctr.Name = name
+			raw.Content = append([]*yaml.Node{
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: "name"},
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+			}, raw.Content...)
+		case name != "" && ctr.Name != name:
+			http.Error(w, "the container name cannot be changed", http.StatusBadRequest)
+			return
+		}
+		ctr.setDefaults()
+	}
+	target := name
+	if target == "" {
+		target = ctr.Name
+	}
+
+	s.apiMu.Lock()
+	defer s.apiMu.Unlock()
+
+	static := s.staticConfig()
+	i := slices.IndexFunc(static.Containers, func(c ContainerConfig) bool { return c.Name == target })
+	next := *static
+	next.Containers = slices.Clone(static.Containers)
+	switch {
+	case name == "" && i >= 0:
+		http.Error(w, fmt.Sprintf("container %q already exists", target), http.StatusConflict)
+		return
+	case name == "":
+		next.Containers = append(next.Containers, ctr)
+	case i < 0:
+		if s.activeContainer(name) != nil {
+			http.Error(w, fmt.Sprintf("container %q is defined by dag.* labels; change its labels instead", name), http.StatusConflict)
+		} else {
+			http.Error(w, "unknown container", http.StatusNotFound)
+		}
+		return
+	case r.Method == http.MethodDelete:
+		next.Containers = slices.Delete(next.Containers, i, i+1)
+	default:
+		next.Containers[i] = ctr
+	}
+
+	if err := s.applyStatic(&next, ReloadTriggerAPI); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	requestLogger(r.Context()).Info("container changed via admin API", "method", r.Method, "container", target)
+
+	if persist {
+		if err := persistContainer(configPath(), target, raw); err != nil {
+			requestLogger(r.Context()).Error("admin API: cannot save config file", "path", configPath(), "error", err)
+			http.Error(w, fmt.Sprintf("change applied but not saved to the config file: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		w.Header().Set("Location", adminAPIContainers+"/"+target)
+		s.apiWriteContainer(w, target, http.StatusCreated)
+	default:
+		s.apiWriteContainer(w, target, http.StatusOK)
+	}
+}
+
+// apiWriteContainer answers with the active definition of name.
+func (s *Server) apiWriteContainer(w http.ResponseWriter, name string, status int) {
+	c := s.activeContainer(name)
+	if c == nil {
+		// Shadowed: another container took its host first.
+		w.WriteHeader(status)
+		return
+	}
+	doc, err := containerDocument(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAPIJSON(w, status, doc)
+}
+
+// decodeAPIContainer reads a container definition in the format of a
+// config.yaml containers entry, as YAML or JSON. Unknown keys are rejected.
+// It also returns the definition as a YAML mapping, for persistContainer.
+func decodeAPIContainer(w http.ResponseWriter, r *http.Request) (ContainerConfig, *yaml.Node, error) {
+	var ctr ContainerConfig
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAPIBody))
+	if err != nil {
+		return ctr, nil, fmt.Errorf("cannot read body: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return ctr, nil, fmt.Errorf("invalid body: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return ctr, nil, errors.New("body must be a container object")
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+	dec.KnownFields(true)
+	if err := dec.Decode(&ctr); err != nil {
+		return ctr, nil, fmt.Errorf("invalid container: %w", err)
+	}
+	node := doc.Content[0]
+	clearYAMLStyle(node)
+	return ctr, node, nil
+}
+
+// containerDocument renders c with its config.yaml keys.
+func containerDocument(c *ContainerConfig) (apiContainerJSON, error) {
+	out := apiContainerJSON{Name: c.Name, Source: routeSource(c)}
+	data, err := yaml.Marshal(c)
+	if err == nil {
+		err = yaml.Unmarshal(data, &out.Config)
+	}
+	return out, err
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// reloadStatic validates and applies a changed static configuration when
+// the server runs without discovery, recording the outcome like a reload.
+func (s *Server) reloadStatic(cfg *GatewayConfig, trigger string) error {
+	old := s.GetConfig()
+	err := cfg.Validate()
+	if err != nil {
+		err = fmt.Errorf("invalid configuration: %w", err)
+	} else {
+		err = s.ReloadConfig(cfg)
+	}
+	s.reloads.record(trigger, old, cfg, err)
+	return err
+}
+
+// persistContainer replaces the entry of the container name in the
+// containers section of the config file at path with def, appending it when
+// there is none, or removes the entry when def is nil. The rest of the file,
+// comments included, is kept. The file is rewritten in place, so it can be a
+// single-file bind mount.
+func persistContainer(path, name string, def *yaml.Node) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return errors.New("the top level of the file is not a mapping")
+	}
+
+	var seq *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "containers" {
+			seq = root.Content[i+1]
+		}
+	}
+	switch {
+	case seq == nil && def == nil:
+		return nil
+	case seq == nil:
+		seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "containers"}, seq)
+	case seq.Kind == yaml.ScalarNode && seq.Tag == "!!null":
+		*seq = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	case seq.Kind != yaml.SequenceNode:
+		return errors.New("containers is not a list")
+	}
+
+	i := slices.IndexFunc(seq.Content, func(n *yaml.Node) bool {
+		for j := 0; j+1 < len(n.Content); j += 2 {
+			if n.Content[j].Value == "name" {
+				return n.Content[j+1].Value == name
+			}
+		}
+		return false
+	})
+	switch {
+	case def == nil && i < 0:
+		return nil
+	case def == nil:
+		seq.Content = slices.Delete(seq.Content, i, i+1)
+	case i < 0:
+		seq.Content = append(seq.Content, def)
+	default:
+		def.HeadComment = seq.Content[i].HeadComment
+		seq.Content[i] = def
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// clearYAMLStyle drops the flow and quoting styles of a definition sent as
+// JSON, so it is written to the config file in block style.
+func clearYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearYAMLStyle(c)
+	}
+}
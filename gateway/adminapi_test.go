@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAdminAPIGateway(t *testing.T, persist bool) *fakeGateway {
+	t.Helper()
+	return newFakeGatewayConfig(t, NewFakeRuntime(), &GatewayConfig{
+		Gateway:    GlobalConfig{AdminAPI: AdminAPIConfig{Enabled: true, Persist: persist}},
+		Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "3000"}},
+	})
+}
+
+// apiDo sends body to the admin API.
+func (g *fakeGateway) apiDo(method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Host = "gw.local"
+	g.handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdminAPI_Disabled(t *testing.T) {
+	g := newFakeGateway(t, NewFakeRuntime(), ContainerConfig{Name: "app", Host: "app.local"})
+	if w := g.apiDo(http.MethodGet, "/_api/v1/containers", ""); w.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404 without admin_api.enabled", w.Code)
+	}
+}
+
+func TestAdminAPI_CRUD(t *testing.T) {
+	g := newAdminAPIGateway(t, false)
+
+	w := g.apiDo(http.MethodGet, "/_api/v1/containers", "")
+	var list struct {
+		Containers []apiContainerJSON `json:"containers"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Containers) != 1 || list.Containers[0].Source != routeSourceStatic ||
+		list.Containers[0].Config["target_port"] != "3000" || list.Containers[0].Config["start_timeout"] != "1m0s" {
+		t.Fatalf("list = %+v", list.Containers)
+	}
+
+	w = g.apiDo(http.MethodPost, "/_api/v1/containers",
+		`{"name": "new", "host": "new.local", "target_port": "8080", "idle_timeout": "15m"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/_api/v1/containers/new" {
+		t.Fatalf("POST: status %d, Location %q: %s", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	c := g.server.activeContainer("new")
+	if c == nil || c.IdleTimeout != 15*time.Minute || c.StartTimeout != time.Minute {
+		t.Fatalf("new container = %+v, want idle_timeout 15m and the defaults", c)
+	}
+	if st := g.server.reloads.Status(); st.Last == nil || st.Last.Trigger != ReloadTriggerAPI ||
+		len(st.Last.Diff.ContainersAdded) != 1 {
+		t.Errorf("reload status = %+v, want an api reload adding the container", st.Last)
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+		code               int
+		want               string
+	}{
+		{http.MethodPost, "/_api/v1/containers", `{"name": "new", "host": "x.local"}`, http.StatusConflict, "already exists"},
+		{http.MethodPost, "/_api/v1/containers", `{"name": "x", "hots": "x.local"}`, http.StatusBadRequest, "field hots not found"},
+		{http.MethodPost, "/_api/v1/containers", `{"name": "x", "host": "app.local"}`, http.StatusBadRequest, "duplicate host"},
+		{http.MethodPost, "/_api/v1/containers", `[1, 2]`, http.StatusBadRequest, "container object"},
+		{http.MethodPut, "/_api/v1/containers/new", `{"name": "renamed"}`, http.StatusBadRequest, "cannot be changed"},
+		{http.MethodPut, "/_api/v1/containers/missing", `{"host": "m.local"}`, http.StatusNotFound, "unknown container"},
+		{http.MethodPatch, "/_api/v1/containers/new", ``, http.StatusMethodNotAllowed, "method not allowed"},
+	} {
+		w := g.apiDo(tt.method, tt.path, tt.body)
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s %s %s: status %d, %q; want %d, %q", tt.method, tt.path, tt.body, w.Code, w.Body, tt.code, tt.want)
+		}
+	}
+
+	// PUT takes YAML too, and keeps the name of the path.
+	w = g.apiDo(http.MethodPut, "/_api/v1/containers/new", "host: other.local\ntarget_port: \"9000\"\n")
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT: status %d: %s", w.Code, w.Body)
+	}
+	if c := g.server.activeContainer("new"); c == nil || c.Host != "other.local" || c.IdleTimeout != 0 {
+		t.Errorf("after PUT = %+v, want the definition replaced", c)
+	}
+
+	if w := g.apiDo(http.MethodDelete, "/_api/v1/containers/new", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: status %d: %s", w.Code, w.Body)
+	}
+	if w := g.apiDo(http.MethodGet, "/_api/v1/containers/new", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE: status %d, want 404", w.Code)
+	}
+}
+
+func TestAdminAPI_DiscoveredContainer(t *testing.T) {
+	g := newAdminAPIGateway(t, false)
+	static := g.server.GetConfig()
+	active := *static
+	active.Containers = append(active.Containers, ContainerConfig{Name: "labeled", Host: "labeled.local", Discovered: true})
+	applyDefaults(&active)
+	if err := g.server.ReloadConfig(&active); err != nil {
+		t.Fatal(err)
+	}
+	g.server.staticConfig = func() *GatewayConfig { return static }
+
+	if w := g.apiDo(http.MethodDelete, "/_api/v1/containers/labeled", ""); w.Code != http.StatusConflict {
+		t.Errorf("DELETE of a discovered container: status %d, want 409", w.Code)
+	}
+	w := g.apiDo(http.MethodGet, "/_api/v1/containers/labeled", "")
+	var doc apiContainerJSON
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil || doc.Source != routeSourceDiscovery {
+		t.Errorf("GET: %+v (%v), want source discovery", doc, err)
+	}
+}
+
+func TestAdminAPI_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	const file = `# gateway settings
+gateway:
+  port: "8080"
+containers:
+  # the main app
+  - name: app
+    host: app.local
+    target_port: "3000"
+`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_PATH", path)
+	g := newAdminAPIGateway(t, true)
+
+	if w := g.apiDo(http.MethodPost, "/_api/v1/containers", `{"name": "new", "host": "new.local", "idle_timeout": "15m"}`); w.Code != http.StatusCreated {
+		t.Fatalf("POST: status %d: %s", w.Code, w.Body)
+	}
+	if w := g.apiDo(http.MethodPut, "/_api/v1/containers/app", `{"host": "app.example.com", "target_port": "3000"}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT: status %d: %s", w.Code, w.Body)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"# gateway settings", "# the main app", "host: app.example.com", "- name: new", "idle_timeout: 15m"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config file lacks %q:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "start_timeout") {
+		t.Errorf("defaults written to the config file:\n%s", data)
+	}
+
+	if w := g.apiDo(http.MethodDelete, "/_api/v1/containers/new", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: status %d: %s", w.Code, w.Body)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Containers) != 1 || cfg.Containers[0].Host != "app.example.com" {
+		t.Errorf("reloaded containers = %+v, want app on its new host only", cfg.Containers)
+	}
+}
+
+func TestAdminAPI_ContainerActions(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running"})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local"}) // admin_api disabled
+
+	g.manager.setStartState("app", statusFailed, "boom")
+	w := g.apiDo(http.MethodPost, "/_api/v1/containers/app/reset", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"previous":"failed"`) {
+		t.Fatalf("reset: status %d: %s", w.Code, w.Body)
+	}
+	if w := g.apiDo(http.MethodPost, "/_api/v1/containers/app/kill", ""); w.Code != http.StatusOK {
+		t.Fatalf("kill: status %d: %s", w.Code, w.Body)
+	}
+	if calls := rt.Calls(); len(calls) == 0 || calls[len(calls)-1] != "kill app" {
+		t.Errorf("calls = %v, want the container killed", calls)
+	}
+
+	for _, tt := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/_api/v1/containers/app/kill", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/_api/v1/containers/missing/kill", http.StatusNotFound},
+		{http.MethodPost, "/_api/v1/containers/app/restart", http.StatusNotFound},
+		{http.MethodPost, "/_api/v1/containers/app/kill/now", http.StatusNotFound},
+	} {
+		if w := g.apiDo(tt.method, tt.path, ""); w.Code != tt.code {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, w.Code, tt.code)
+		}
+	}
+}
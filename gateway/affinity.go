@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// affinityCookiePrefix starts the name of the cookie pinning a client to a
+// member of a group with affinity "cookie".
+const affinityCookiePrefix = "dag_affinity_"
+
+// newAffinityKey returns a random key for affinity cookies, used when
+// gateway.affinity_secret is not set.
+func newAffinityKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// affinityCookieName returns the cookie name of group, with the characters
+// a cookie name cannot hold replaced.
+func affinityCookieName(group string) string {
+	return affinityCookiePrefix + strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, group)
+}
+
+// affinitySignature signs member of group with the affinity key.
+func (s *Server) affinitySignature(group, member string) string {
+	key := s.affinityKey
+	if secret := s.GetConfig().Gateway.AffinitySecret; secret != "" {
+		sum := sha256.Sum256([]byte(secret))
+		key = sum[:]
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(group + "\x00" + member))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// stickyMember returns the member of group the affinity cookie of r pins it
+// to, or "" when the group has no cookie affinity, the cookie is missing or
+// forged, or the member left the group or is not eligible.
+func (s *Server) stickyMember(r *http.Request, group *GroupConfig, eligible func(name string) bool) string {
+	if group.Affinity != affinityCookie {
+		return ""
+	}
+	c, err := r.Cookie(affinityCookieName(group.Name))
+	if err != nil {
+		return ""
+	}
+	// Member names may hold dots; the base64url signature never does.
+	i := strings.LastIndexByte(c.Value, '.')
+	if i < 0 {
+		return ""
+	}
+	member, sig := c.Value[:i], c.Value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.affinitySignature(group.Name, member))) {
+		return ""
+	}
+	if !slices.Contains(group.Containers, member) || (eligible != nil && !eligible(member)) {
+		return ""
+	}
+	return member
+}
+
+// setAffinityCookie pins the client of r to member of group. The cookie
+// lasts for the browser session.
+func (s *Server) setAffinityCookie(w http.ResponseWriter, r *http.Request, group *GroupConfig, member string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     affinityCookieName(group.Name),
+		Value:    member + "." + s.affinitySignature(group.Name, member),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newAffinityGateway(t *testing.T, affinity, secret string, names ...string) *fakeGateway {
+	t.Helper()
+	if len(names) == 0 {
+		names = []string{"a", "b"}
+	}
+	rt := NewFakeRuntime()
+	var ctrs []ContainerConfig
+	var members []GroupMember
+	for _, name := range names {
+		host, port := newBackend(t, name, echoURI)
+		rt.AddContainer(name, FakeContainer{Status: "running", Host: host, Port: port})
+		ctrs = append(ctrs, ContainerConfig{Name: name, TargetPort: port})
+		members = append(members, GroupMember{Name: name})
+	}
+	return newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway:    GlobalConfig{AffinitySecret: secret},
+		Containers: ctrs,
+		Groups:     []GroupConfig{{Name: "web app", Host: "app.local", Affinity: affinity, Members: members}},
+	})
+}
+
+// getCookie sends a request to host with cookie, if any.
+func (g *fakeGateway) getCookie(host string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = host
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	g.handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestAffinity_Cookie(t *testing.T) {
+	g := newAffinityGateway(t, affinityCookie, "")
+
+	w := g.getCookie("app.local", nil)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "dag_affinity_web_app" || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %+v, want one HttpOnly dag_affinity_web_app", cookies)
+	}
+	member, _, _ := strings.Cut(w.Body.String(), " ")
+	for i := 0; i < 6; i++ {
+		w := g.getCookie("app.local", cookies[0])
+		if got, _, _ := strings.Cut(w.Body.String(), " "); got != member {
+			t.Fatalf("request %d went to %q, want %q", i, got, member)
+		}
+		if len(w.Result().Cookies()) != 0 {
+			t.Errorf("cookie set again on a pinned request")
+		}
+	}
+
+	// A forged cookie is ignored and replaced.
+	other := "a"
+	if member == "a" {
+		other = "b"
+	}
+	forged := &http.Cookie{Name: cookies[0].Name, Value: other + cookies[0].Value[strings.LastIndex(cookies[0].Value, "."):]}
+	w = g.getCookie("app.local", forged)
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].Value == forged.Value {
+		t.Errorf("cookies = %+v, want a fresh affinity cookie", c)
+	}
+}
+
+func TestAffinity_DottedMemberName(t *testing.T) {
+	g := newAffinityGateway(t, affinityCookie, "", "app.v1", "app.v2")
+	w := g.getCookie("app.local", nil)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %+v, want one", cookies)
+	}
+	member, _, _ := strings.Cut(w.Body.String(), " ")
+	for i := 0; i < 4; i++ {
+		w := g.getCookie("app.local", cookies[0])
+		if got, _, _ := strings.Cut(w.Body.String(), " "); got != member {
+			t.Fatalf("request %d went to %q, want %q", i, got, member)
+		}
+	}
+}
+
+func TestAffinity_None(t *testing.T) {
+	g := newAffinityGateway(t, "", "")
+	seen := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		w := g.getCookie("app.local", nil)
+		if len(w.Result().Cookies()) != 0 {
+			t.Fatal("affinity cookie set without affinity")
+		}
+		member, _, _ := strings.Cut(w.Body.String(), " ")
+		seen[member] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("members = %v, want both by round-robin", seen)
+	}
+}
+
+func TestAffinity_Secret(t *testing.T) {
+	g1 := newAffinityGateway(t, affinityCookie, "s3cret")
+	g2 := newAffinityGateway(t, affinityCookie, "s3cret")
+	g3 := newAffinityGateway(t, affinityCookie, "")
+	if g1.server.affinitySignature("g", "a") != g2.server.affinitySignature("g", "a") {
+		t.Error("replicas sharing affinity_secret sign differently")
+	}
+	if g1.server.affinitySignature("g", "a") == g3.server.affinitySignature("g", "a") {
+		t.Error("random key signs like affinity_secret")
+	}
+}
+
+func TestValidate_GroupAffinity(t *testing.T) {
+	cfg := &GatewayConfig{
+		Containers: []ContainerConfig{{Name: "a"}, {Name: "b"}},
+		Groups:     []GroupConfig{{Name: "g", Host: "g.local", Affinity: "ip", Members: []GroupMember{{Name: "a"}, {Name: "b"}}}},
+	}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `unknown affinity "ip"`) {
+		t.Errorf("error = %v, want unknown affinity", err)
+	}
+	cfg.Groups[0].Affinity = ""
+	applyDefaults(cfg)
+	if cfg.Groups[0].Affinity != affinityNone {
+		t.Errorf("affinity = %q, want none", cfg.Groups[0].Affinity)
+	}
+}
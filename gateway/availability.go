@@ -0,0 +1,322 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// availabilityDays is how far back the lifecycle log reaches: the
+	// longest window reported on the dashboard.
+	availabilityDays = 30
+	// maxLifecycleEvents caps the log of one container; the oldest events
+	// are dropped first.
+	maxLifecycleEvents = 4096
+	// availabilityTick is how often the lifecycle log is pruned and saved.
+	availabilityTick = time.Minute
+)
+
+// availabilityWindows are the windows reported in /_status/api, by key.
+var availabilityWindows = []struct {
+	key string
+	d   time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", availabilityDays * 24 * time.Hour},
+}
+
+// lifecyclePhase is what a container was doing between two lifecycle events.
+type lifecyclePhase string
+
+const (
+	phaseUp     lifecyclePhase = "up"     // running
+	phaseAsleep lifecyclePhase = "asleep" // stopped on purpose, woken on demand
+	phaseDown   lifecyclePhase = "down"   // the last start failed
+)
+
+// Causes of a lifecycleEvent. Transitions noticed by the metrics refresher
+// have none.
+const (
+	causeWake         = "wake"
+	causeStartFailure = "start_failure"
+	causeSleep        = "sleep"
+)
+
+// lifecycleEvent records that a container entered Phase at At.
+type lifecycleEvent struct {
+	At    time.Time      `json:"at"`
+	Phase lifecyclePhase `json:"phase"`
+	Cause string         `json:"cause,omitempty"`
+	// ColdStart is how long the wake took, from docker start to ready.
+	ColdStart time.Duration `json:"cold_start_ns,omitempty"`
+}
+
+// lifecycleLog keeps the lifecycle events of each container over the last
+// availabilityDays, from which availabilityStats are computed.
+type lifecycleLog struct {
+	mu     sync.Mutex
+	events map[string][]lifecycleEvent
+	dirty  bool
+}
+
+func newLifecycleLog() *lifecycleLog {
+	return &lifecycleLog{events: make(map[string][]lifecycleEvent)}
+}
+
+// wake records a successful on-demand start that took coldStart.
+func (l *lifecycleLog) wake(name string, at time.Time, coldStart time.Duration) {
+	l.append(name, lifecycleEvent{At: at, Phase: phaseUp, Cause: causeWake, ColdStart: coldStart})
+}
+
+// fail records a failed start: the container is down until it runs again.
+func (l *lifecycleLog) fail(name string, at time.Time) {
+	l.append(name, lifecycleEvent{At: at, Phase: phaseDown, Cause: causeStartFailure})
+}
+
+// sleep records that the gateway stopped the container.
+func (l *lifecycleLog) sleep(name string, at time.Time) {
+	l.append(name, lifecycleEvent{At: at, Phase: phaseAsleep, Cause: causeSleep})
+}
+
+// observe records the phase the container is seen in, when it changed since
+// the last event: containers started or stopped outside the gateway, and
+// failed starts that were reset.
+func (l *lifecycleLog) observe(name string, running, failed bool, at time.Time) {
+	phase := phaseAsleep
+	switch {
+	case running:
+		phase = phaseUp
+	case failed:
+		phase = phaseDown
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if evs := l.events[name]; len(evs) > 0 && evs[len(evs)-1].Phase == phase {
+		return
+	}
+	l.appendLocked(name, lifecycleEvent{At: at, Phase: phase})
+}
+
+func (l *lifecycleLog) append(name string, ev lifecycleEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.appendLocked(name, ev)
+}
+
+// appendLocked adds ev to the log of name. Caller must hold l.mu.
+func (l *lifecycleLog) appendLocked(name string, ev lifecycleEvent) {
+	evs := append(l.events[name], ev)
+	if len(evs) > maxLifecycleEvents {
+		evs = evs[len(evs)-maxLifecycleEvents:]
+	}
+	l.events[name] = evs
+	l.dirty = true
+}
+
+// forget drops the log of a container that is no longer managed.
+func (l *lifecycleLog) forget(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.events[name]; ok {
+		delete(l.events, name)
+		l.dirty = true
+	}
+}
+
+// prune drops the events older than availabilityDays. The last of them is
+// kept, moved to the cutoff, as it tells the phase the window starts in.
+func (l *lifecycleLog) prune(now time.Time) {
+	cutoff := now.Add(-availabilityDays * 24 * time.Hour)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for name, evs := range l.events {
+		if len(evs) == 0 {
+			delete(l.events, name)
+			continue
+		}
+		i := 0
+		for i+1 < len(evs) && !evs[i+1].At.After(cutoff) {
+			i++
+		}
+		if evs[i].At.Before(cutoff) {
+			evs[i] = lifecycleEvent{At: cutoff, Phase: evs[i].Phase}
+			l.events[name] = evs[i:]
+			l.dirty = true
+		}
+	}
+}
+
+// availabilityStats describes a container over one window. Only the time
+// covered by the lifecycle log counts: a container first seen an hour ago
+// has one hour of history in every window.
+type availabilityStats struct {
+	// AvailabilityPct is the share of the tracked time the container was
+	// not down after a failed start; sleeping does not count against it.
+	// Null while nothing was tracked yet.
+	AvailabilityPct *float64 `json:"availability_pct"`
+	AwakeSeconds    int64    `json:"awake_seconds"`
+	AsleepSeconds   int64    `json:"asleep_seconds"`
+	DownSeconds     int64    `json:"down_seconds"`
+	Wakes           int      `json:"wakes"`
+	FailedStarts    int      `json:"failed_starts"`
+	// AvgColdStartMs is the mean duration of the wakes in the window.
+	AvgColdStartMs int64 `json:"avg_cold_start_ms"`
+}
+
+// stats computes the availability of name over the window ending at now.
+func (l *lifecycleLog) stats(name string, window time.Duration, now time.Time) availabilityStats {
+	from := now.Add(-window)
+	var s availabilityStats
+	var up, asleep, down, coldStart time.Duration
+
+	l.mu.Lock()
+	evs := l.events[name]
+	for i, ev := range evs {
+		end := now
+		if i+1 < len(evs) {
+			end = evs[i+1].At
+		}
+		start := ev.At
+		if start.Before(from) {
+			start = from
+		}
+		if d := end.Sub(start); d > 0 {
+			switch ev.Phase {
+			case phaseUp:
+				up += d
+			case phaseDown:
+				down += d
+			default:
+				asleep += d
+			}
+		}
+		if ev.At.Before(from) {
+			continue
+		}
+		switch ev.Cause {
+		case causeWake:
+			s.Wakes++
+			coldStart += ev.ColdStart
+		case causeStartFailure:
+			s.FailedStarts++
+		}
+	}
+	l.mu.Unlock()
+
+	s.AwakeSeconds = int64(up.Seconds())
+	s.AsleepSeconds = int64(asleep.Seconds())
+	s.DownSeconds = int64(down.Seconds())
+	if tracked := up + asleep + down; tracked > 0 {
+		pct := math.Round(float64(up+asleep)/float64(tracked)*10000) / 100
+		s.AvailabilityPct = &pct
+	}
+	if s.Wakes > 0 {
+		s.AvgColdStartMs = (coldStart / time.Duration(s.Wakes)).Milliseconds()
+	}
+	return s
+}
+
+// lifecycleFile is the on-disk format of the lifecycle log.
+type lifecycleFile struct {
+	Version    int                         `json:"version"`
+	Containers map[string][]lifecycleEvent `json:"containers"`
+}
+
+// load replaces the log with the contents of path. A missing file is not an
+// error: the log starts empty.
+func (l *lifecycleLog) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f lifecycleFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	if f.Containers == nil {
+		f.Containers = make(map[string][]lifecycleEvent)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = f.Containers
+	l.dirty = false
+	return nil
+}
+
+// save writes the log to path if it changed since the last save.
+func (l *lifecycleLog) save(path string) error {
+	l.mu.Lock()
+	if !l.dirty {
+		l.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(lifecycleFile{Version: 1, Containers: l.events})
+	l.dirty = false
+	l.mu.Unlock()
+
+	if err == nil {
+		err = writeFileAtomic(path, data)
+	}
+	if err != nil {
+		l.mu.Lock()
+		l.dirty = true
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// Availability returns the availability of a container over the last 24
+// hours, 7 and 30 days, keyed "24h", "7d" and "30d".
+func (m *ContainerManager) Availability(name string) map[string]availabilityStats {
+	now := time.Now()
+	out := make(map[string]availabilityStats, len(availabilityWindows))
+	for _, w := range availabilityWindows {
+		out[w.key] = m.lifecycle.stats(name, w.d, now)
+	}
+	return out
+}
+
+// StartAvailabilityLog begins a background routine that prunes the lifecycle
+// log and saves it to gateway.availability.history_file, from which it is
+// loaded first.
+func (m *ContainerManager) StartAvailabilityLog(ctx context.Context, configProvider func() *GatewayConfig) {
+	if path := configProvider().Gateway.Availability.HistoryFile; path != "" {
+		if err := m.lifecycle.load(path); err != nil {
+			slog.Warn("availability: cannot load lifecycle log", "path", path, "error", err)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(availabilityTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				m.saveLifecycle(configProvider().Gateway.Availability.HistoryFile)
+				return
+			case <-ticker.C:
+				m.lifecycle.prune(time.Now())
+				m.saveLifecycle(configProvider().Gateway.Availability.HistoryFile)
+			}
+		}
+	}()
+}
+
+func (m *ContainerManager) saveLifecycle(path string) {
+	if path == "" {
+		return
+	}
+	if err := m.lifecycle.save(path); err != nil {
+		slog.Warn("availability: cannot save lifecycle log", "path", path, "error", err)
+	}
+}
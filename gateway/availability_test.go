@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLifecycleLog_Stats(t *testing.T) {
+	l := newLifecycleLog()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(h float64) time.Time { return now.Add(time.Duration(h * float64(time.Hour))) }
+
+	l.observe("app", false, false, at(-30)) // asleep since before the 24h window
+	l.wake("app", at(-10), 4*time.Second)   // 2h up
+	l.sleep("app", at(-8))                  // 3h asleep
+	l.fail("app", at(-5))                   // 1h down
+	l.fail("app", at(-4))                   // 1h down
+	l.observe("app", false, false, at(-3))  // reset: 1h asleep
+	l.wake("app", at(-2), 2*time.Second)    // 2h up until now
+	l.observe("app", true, false, at(-1))   // no change, not recorded
+
+	s := l.stats("app", 24*time.Hour, now)
+	if s.AwakeSeconds != 4*3600 || s.DownSeconds != 2*3600 || s.AsleepSeconds != 18*3600 {
+		t.Errorf("awake/asleep/down = %d/%d/%d s, want 4h/18h/2h", s.AwakeSeconds, s.AsleepSeconds, s.DownSeconds)
+	}
+	if s.Wakes != 2 || s.FailedStarts != 2 || s.AvgColdStartMs != 3000 {
+		t.Errorf("wakes %d, failed starts %d, avg cold start %dms; want 2, 2, 3000", s.Wakes, s.FailedStarts, s.AvgColdStartMs)
+	}
+	if s.AvailabilityPct == nil || *s.AvailabilityPct != 91.67 {
+		t.Errorf("availability = %v, want 91.67", s.AvailabilityPct)
+	}
+
+	// The 7-day window covers the 30 tracked hours only.
+	if s := l.stats("app", 7*24*time.Hour, now); s.AsleepSeconds != 24*3600 {
+		t.Errorf("7d asleep = %ds, want 24h", s.AsleepSeconds)
+	}
+	if s := l.stats("missing", 24*time.Hour, now); s.AvailabilityPct != nil || s.Wakes != 0 {
+		t.Errorf("untracked container: %+v, want no availability", s)
+	}
+}
+
+func TestLifecycleLog_Prune(t *testing.T) {
+	l := newLifecycleLog()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	l.wake("app", now.AddDate(0, 0, -40), time.Second)
+	l.sleep("app", now.AddDate(0, 0, -35))
+	l.wake("app", now.AddDate(0, 0, -1), time.Second)
+
+	l.prune(now)
+	evs := l.events["app"]
+	if len(evs) != 2 || evs[0].Phase != phaseAsleep || !evs[0].At.Equal(now.AddDate(0, 0, -availabilityDays)) {
+		t.Fatalf("events after prune = %+v, want the sleep moved to the cutoff and the last wake", evs)
+	}
+	if s := l.stats("app", availabilityDays*24*time.Hour, now); s.Wakes != 1 || s.AwakeSeconds != 24*3600 {
+		t.Errorf("30d stats = %+v, want one wake and one day up", s)
+	}
+}
+
+func TestLifecycleLog_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lifecycle.json")
+	now := time.Now()
+	l := newLifecycleLog()
+	l.wake("app", now.Add(-time.Hour), 1500*time.Millisecond)
+	if err := l.save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded := newLifecycleLog()
+	if err := loaded.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if s := loaded.stats("app", 24*time.Hour, now); s.Wakes != 1 || s.AvgColdStartMs != 1500 {
+		t.Errorf("stats after reload = %+v, want the wake", s)
+	}
+}
+
+func TestAvailability_StatusAPI(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	rt.AddContainer("broken", FakeContainer{Status: "exited", StartErr: errors.New("no such image")})
+	g := newFakeGateway(t, rt,
+		ContainerConfig{Name: "app", Host: "app.local", TargetPort: port},
+		ContainerConfig{Name: "broken", Host: "broken.local", TargetPort: "80"},
+	)
+
+	g.get("app.local", "/")
+	g.waitStarted(t, "app")
+	g.get("broken.local", "/")
+	g.waitStarted(t, "broken")
+
+	w := g.get("gw.local", "/_status/api")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	var resp struct {
+		Containers []struct {
+			Name         string                       `json:"name"`
+			Availability map[string]availabilityStats `json:"availability"`
+		} `json:"containers"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]availabilityStats{}
+	for _, c := range resp.Containers {
+		for _, key := range []string{"24h", "7d", "30d"} {
+			if _, ok := c.Availability[key]; !ok {
+				t.Errorf("%s: no %s window", c.Name, key)
+			}
+		}
+		got[c.Name] = c.Availability["7d"]
+	}
+	if s := got["app"]; s.Wakes != 1 || s.FailedStarts != 0 {
+		t.Errorf("app: %+v, want one wake", s)
+	}
+	if s := got["broken"]; s.Wakes != 0 || s.FailedStarts != 1 {
+		t.Errorf("broken: %+v, want one failed start", s)
+	}
+}
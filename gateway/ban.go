@@ -0,0 +1,255 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Strike reasons recorded by BanList.Strike and reported in /_status/bans.
+const (
+	strikeAuthFailure = "auth_failure"
+	strikeRateLimited = "rate_limited"
+	strikeUnknownHost = "unknown_host"
+)
+
+// banEntry is an active ban.
+type banEntry struct {
+	IP       string    `json:"ip"`
+	Reason   string    `json:"reason"`
+	Strikes  int       `json:"strikes"`
+	BannedAt time.Time `json:"banned_at"`
+	Until    time.Time `json:"expires_at"`
+}
+
+// BanList counts failure signals (strikes) per client IP and bans an IP for
+// AutoBanConfig.Duration once it collects Threshold strikes within Window.
+// A nil or disabled BanList never bans anyone.
+type BanList struct {
+	mu      sync.Mutex
+	cfg     AutoBanConfig
+	exempt  []*net.IPNet
+	strikes map[string][]time.Time // recent strike times, oldest first
+	bans    map[string]*banEntry
+	now     func() time.Time
+}
+
+// NewBanList creates an empty, disabled BanList.
+func NewBanList() *BanList {
+	return &BanList{
+		strikes: make(map[string][]time.Time),
+		bans:    make(map[string]*banEntry),
+		now:     time.Now,
+	}
+}
+
+// Sync applies a new auto_ban configuration. Active bans are kept, so a
+// reload cannot be used to lift them; disabling auto_ban lifts them all.
+func (b *BanList) Sync(cfg AutoBanConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cfg = cfg
+	b.exempt = parseTrustedProxies(cfg.Exempt)
+	if !cfg.Enabled {
+		b.strikes = make(map[string][]time.Time)
+		b.bans = make(map[string]*banEntry)
+		BannedClients.Set(0)
+	}
+}
+
+// Strike records a failure signal for ip and bans it when the threshold is
+// reached. Exempt IPs and already banned IPs are ignored.
+func (b *BanList) Strike(ip, reason string) {
+	if b == nil || ip == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.cfg.Enabled || isTrustedProxy(ip, b.exempt) {
+		return
+	}
+	now := b.now()
+	if _, banned := b.activeBan(ip, now); banned {
+		return
+	}
+
+	recent := pruneStrikes(b.strikes[ip], now.Add(-b.cfg.Window))
+	recent = append(recent, now)
+	if len(recent) < b.cfg.Threshold {
+		b.strikes[ip] = recent
+		return
+	}
+
+	delete(b.strikes, ip)
+	b.bans[ip] = &banEntry{
+		IP:       ip,
+		Reason:   reason,
+		Strikes:  len(recent),
+		BannedAt: now,
+		Until:    now.Add(b.cfg.Duration),
+	}
+	BansTotal.WithLabelValues(reason).Inc()
+	BannedClients.Set(float64(len(b.bans)))
+	slog.Warn("client banned", "ip", ip, "reason", reason, "strikes", len(recent), "duration", b.cfg.Duration)
+}
+
+// pruneStrikes drops the strikes older than cutoff.
+func pruneStrikes(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// Banned reports whether ip is banned and until when.
+func (b *BanList) Banned(ip string) (time.Time, bool) {
+	if b == nil {
+		return time.Time{}, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.activeBan(ip, b.now())
+}
+
+// activeBan returns the expiry of ip's ban, dropping it once expired.
+// Callers must hold b.mu.
+func (b *BanList) activeBan(ip string, now time.Time) (time.Time, bool) {
+	e, ok := b.bans[ip]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !now.Before(e.Until) {
+		delete(b.bans, ip)
+		BannedClients.Set(float64(len(b.bans)))
+		return time.Time{}, false
+	}
+	return e.Until, true
+}
+
+// Unban lifts the ban of ip and clears its strikes. It reports whether ip
+// was banned.
+func (b *BanList) Unban(ip string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.activeBan(ip, b.now())
+	delete(b.bans, ip)
+	delete(b.strikes, ip)
+	BannedClients.Set(float64(len(b.bans)))
+	return ok
+}
+
+// List returns the active bans sorted by expiry.
+func (b *BanList) List() []banEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	list := make([]banEntry, 0, len(b.bans))
+	for ip := range b.bans {
+		if _, ok := b.activeBan(ip, now); ok {
+			list = append(list, *b.bans[ip])
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Until.Before(list[j].Until) })
+	return list
+}
+
+// startCleanup periodically drops expired bans and stale strikes.
+func (b *BanList) startCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.evictStale()
+			}
+		}
+	}()
+}
+
+// evictStale drops expired bans and IPs without strikes inside the window.
+func (b *BanList) evictStale() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	for ip := range b.bans {
+		b.activeBan(ip, now)
+	}
+	cutoff := now.Add(-b.cfg.Window)
+	for ip, times := range b.strikes {
+		if recent := pruneStrikes(times, cutoff); len(recent) == 0 {
+			delete(b.strikes, ip)
+		} else {
+			b.strikes[ip] = recent
+		}
+	}
+}
+
+// banMiddleware rejects requests from banned clients with 403 before they
+// reach any handler.
+func (s *Server) banMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if until, banned := s.bans.Banned(s.clientIP(r)); banned {
+			BannedRequestsTotal.Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// strikeOnUnauthorized records an auth-failure strike for every 401 returned
+// by next, i.e. by adminAuthMiddleware.
+func (s *Server) strikeOnUnauthorized(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.statusCode == http.StatusUnauthorized {
+			s.bans.Strike(s.clientIP(r), strikeAuthFailure)
+		}
+	})
+}
+
+type bansResponse struct {
+	Bans []banEntry `json:"bans"`
+}
+
+// handleStatusBans lists the active bans (GET) or lifts the ban of
+// ?ip= (DELETE).
+func (s *Server) handleStatusBans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(bansResponse{Bans: s.bans.List()})
+	case http.MethodDelete:
+		if !validateOrigin(r) {
+			http.Error(w, "cross-origin request blocked", http.StatusForbidden)
+			return
+		}
+		ip := r.URL.Query().Get("ip")
+		if ip == "" {
+			http.Error(w, "missing ip parameter", http.StatusBadRequest)
+			return
+		}
+		if !s.bans.Unban(ip) {
+			http.Error(w, "ip is not banned", http.StatusNotFound)
+			return
+		}
+		slog.InfoContext(r.Context(), "client unbanned via admin API", "ip", ip)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "unbanned", "ip": ip})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
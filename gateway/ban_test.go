@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestBanList returns an enabled BanList driven by a fake clock.
+func newTestBanList(cfg AutoBanConfig) (*BanList, func(time.Duration)) {
+	b := NewBanList()
+	now, advance := fakeClock()
+	b.now = now
+	cfg.Enabled = true
+	b.Sync(cfg)
+	return b, advance
+}
+
+func TestBanList_BansAtThreshold(t *testing.T) {
+	b, advance := newTestBanList(AutoBanConfig{Threshold: 3, Window: time.Minute, Duration: 10 * time.Minute})
+
+	b.Strike("10.0.0.1", strikeUnknownHost)
+	b.Strike("10.0.0.1", strikeUnknownHost)
+	if _, banned := b.Banned("10.0.0.1"); banned {
+		t.Fatal("banned before reaching the threshold")
+	}
+	b.Strike("10.0.0.1", strikeAuthFailure)
+	until, banned := b.Banned("10.0.0.1")
+	if !banned {
+		t.Fatal("not banned at the threshold")
+	}
+	if got := until.Sub(b.now()); got != 10*time.Minute {
+		t.Errorf("ban lasts %v, want 10m", got)
+	}
+	if _, banned := b.Banned("10.0.0.2"); banned {
+		t.Error("other IPs should not be banned")
+	}
+	if list := b.List(); len(list) != 1 || list[0].Reason != strikeAuthFailure || list[0].Strikes != 3 {
+		t.Errorf("List = %+v", list)
+	}
+
+	// The ban expires on its own.
+	advance(10 * time.Minute)
+	if _, banned := b.Banned("10.0.0.1"); banned {
+		t.Error("ban should have expired")
+	}
+}
+
+func TestBanList_StrikesOutsideWindowDontCount(t *testing.T) {
+	b, advance := newTestBanList(AutoBanConfig{Threshold: 3, Window: time.Minute, Duration: time.Minute})
+
+	b.Strike("10.0.0.1", strikeRateLimited)
+	b.Strike("10.0.0.1", strikeRateLimited)
+	advance(61 * time.Second)
+	b.Strike("10.0.0.1", strikeRateLimited)
+	if _, banned := b.Banned("10.0.0.1"); banned {
+		t.Error("strikes older than the window should not count")
+	}
+}
+
+func TestBanList_ExemptAndDisabled(t *testing.T) {
+	b, _ := newTestBanList(AutoBanConfig{Threshold: 1, Window: time.Minute, Duration: time.Minute, Exempt: []string{"192.168.0.0/16"}})
+	b.Strike("192.168.1.10", strikeAuthFailure)
+	if _, banned := b.Banned("192.168.1.10"); banned {
+		t.Error("exempt IP should never be banned")
+	}
+
+	b.Strike("10.0.0.1", strikeAuthFailure)
+	b.Sync(AutoBanConfig{Enabled: false})
+	if _, banned := b.Banned("10.0.0.1"); banned {
+		t.Error("disabling auto_ban should lift active bans")
+	}
+	b.Strike("10.0.0.1", strikeAuthFailure)
+	if _, banned := b.Banned("10.0.0.1"); banned {
+		t.Error("disabled BanList should not ban")
+	}
+
+	var nilList *BanList
+	nilList.Strike("10.0.0.1", strikeAuthFailure) // must not panic
+}
+
+func TestBanList_Unban(t *testing.T) {
+	b, _ := newTestBanList(AutoBanConfig{Threshold: 1, Window: time.Minute, Duration: time.Hour})
+	b.Strike("10.0.0.1", strikeUnknownHost)
+	if !b.Unban("10.0.0.1") {
+		t.Fatal("Unban should report the IP was banned")
+	}
+	if _, banned := b.Banned("10.0.0.1"); banned {
+		t.Error("IP still banned after Unban")
+	}
+	if b.Unban("10.0.0.1") {
+		t.Error("second Unban should report the IP was not banned")
+	}
+}
+
+func TestBanMiddleware(t *testing.T) {
+	bans, _ := newTestBanList(AutoBanConfig{Threshold: 1, Window: time.Minute, Duration: time.Hour})
+	s := &Server{cfg: &GatewayConfig{}, bans: bans}
+	h := s.banMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	call := func() int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := call(); code != http.StatusOK {
+		t.Fatalf("before ban: status = %d", code)
+	}
+	bans.Strike("10.0.0.1", strikeUnknownHost)
+	if code := call(); code != http.StatusForbidden {
+		t.Errorf("banned: status = %d, want 403", code)
+	}
+}
+
+func TestStrikeOnUnauthorized(t *testing.T) {
+	bans, _ := newTestBanList(AutoBanConfig{Threshold: 2, Window: time.Minute, Duration: time.Hour})
+	s := &Server{cfg: &GatewayConfig{}, bans: bans}
+	h := s.strikeOnUnauthorized(adminAuthMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		&AdminAuthConfig{Method: "bearer", Token: "secret"}))
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/_status/api", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("Authorization", "Bearer wrong")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if _, banned := bans.Banned("10.0.0.1"); !banned {
+		t.Error("repeated auth failures should ban the client")
+	}
+}
+
+func TestHandleStatusBans(t *testing.T) {
+	bans, _ := newTestBanList(AutoBanConfig{Threshold: 1, Window: time.Minute, Duration: time.Hour})
+	bans.Strike("10.0.0.1", strikeRateLimited)
+	s := &Server{cfg: &GatewayConfig{}, bans: bans}
+
+	w := httptest.NewRecorder()
+	s.handleStatusBans(w, httptest.NewRequest(http.MethodGet, "/_status/bans", nil))
+	var got bansResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got.Bans) != 1 || got.Bans[0].IP != "10.0.0.1" {
+		t.Fatalf("bans = %+v", got.Bans)
+	}
+
+	w = httptest.NewRecorder()
+	s.handleStatusBans(w, httptest.NewRequest(http.MethodDelete, "/_status/bans?ip=10.0.0.1", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("DELETE: status = %d, want 200", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.handleStatusBans(w, httptest.NewRequest(http.MethodDelete, "/_status/bans?ip=10.0.0.1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("DELETE of unbanned IP: status = %d, want 404", w.Code)
+	}
+}
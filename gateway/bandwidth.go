@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// BandwidthTracker counts the bytes proxied to and from each container since
+// the gateway started: request bodies received from clients and response
+// bodies (or tunnelled WebSocket bytes) sent back to them.
+type BandwidthTracker struct {
+	mu     sync.Mutex
+	totals map[string]*Bandwidth
+}
+
+// Bandwidth is a pair of byte counters.
+type Bandwidth struct {
+	Received int64
+	Sent     int64
+}
+
+// NewBandwidthTracker creates an empty tracker.
+func NewBandwidthTracker() *BandwidthTracker {
+	return &BandwidthTracker{totals: make(map[string]*Bandwidth)}
+}
+
+// Add counts received and sent bytes for a container.
+func (b *BandwidthTracker) Add(name string, received, sent int64) {
+	if received == 0 && sent == 0 {
+		return
+	}
+	b.mu.Lock()
+	t, ok := b.totals[name]
+	if !ok {
+		t = &Bandwidth{}
+		b.totals[name] = t
+	}
+	t.Received += received
+	t.Sent += sent
+	b.mu.Unlock()
+	RecordBandwidth(name, received, sent)
+}
+
+// Totals returns the bytes counted for a container.
+func (b *BandwidthTracker) Totals(name string) Bandwidth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.totals[name]; ok {
+		return *t
+	}
+	return Bandwidth{}
+}
+
+// Forget drops the counters of a container removed from the configuration.
+func (b *BandwidthTracker) Forget(name string) {
+	b.mu.Lock()
+	delete(b.totals, name)
+	b.mu.Unlock()
+}
+
+// countingReadCloser counts the bytes read from a request body.
+type countingReadCloser struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
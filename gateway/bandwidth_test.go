@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBandwidthTracker(t *testing.T) {
+	b := NewBandwidthTracker()
+	b.Add("app", 10, 100)
+	b.Add("app", 5, 0)
+	b.Add("db", 0, 0)
+
+	if got := b.Totals("app"); got != (Bandwidth{Received: 15, Sent: 100}) {
+		t.Errorf("app totals = %+v, want received 15, sent 100", got)
+	}
+	if got := b.Totals("db"); got != (Bandwidth{}) {
+		t.Errorf("db totals = %+v, want zero", got)
+	}
+
+	b.Forget("app")
+	if got := b.Totals("app"); got != (Bandwidth{}) {
+		t.Errorf("app totals after Forget = %+v, want zero", got)
+	}
+}
+
+func TestProxyRequest_CountsBytes(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, "echo: "+string(body))
+	}))
+	defer backend.Close()
+	host, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(NewFakeRuntime())}
+	cfg := &ContainerConfig{Name: host, TargetPort: port, Target: TargetDNS}
+
+	w := httptest.NewRecorder()
+	s.proxyRequest(w, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("hello")), cfg)
+	if w.Body.String() != "echo: hello" {
+		t.Fatalf("response = %q, want the backend's echo", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.proxyRequest(w, httptest.NewRequest(http.MethodGet, "/", nil), cfg)
+
+	want := Bandwidth{Received: 5, Sent: int64(len("echo: hello") + len("echo: "))}
+	if got := s.manager.bandwidth.Totals(host); got != want {
+		t.Errorf("totals = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"io"
+	"sync"
+)
+
+// copyBufferSize is the size of the buffers io.Copy and
+// httputil.ReverseProxy would otherwise allocate for every copy.
+const copyBufferSize = 32 << 10
+
+// BufferPool recycles fixed-size copy buffers through a sync.Pool. It
+// implements httputil.BufferPool, so proxied responses and WebSocket tunnels
+// reuse buffers instead of allocating 32 KiB each.
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// NewBufferPool creates a pool of buffers of size bytes.
+func NewBufferPool(size int) *BufferPool {
+	p := &BufferPool{size: size}
+	p.pool.New = func() any {
+		b := make([]byte, size)
+		return &b
+	}
+	return p
+}
+
+// Get returns a buffer of the pool's size.
+func (p *BufferPool) Get() []byte {
+	return *p.pool.Get().(*[]byte)
+}
+
+// Put returns b to the pool. Buffers of another size are dropped.
+func (p *BufferPool) Put(b []byte) {
+	if cap(b) != p.size {
+		return
+	}
+	b = b[:p.size]
+	p.pool.Put(&b)
+}
+
+// copyBuffers is shared by the reverse proxy and the WebSocket tunnels.
+var copyBuffers = NewBufferPool(copyBufferSize)
+
+// copyPooled is io.Copy with a buffer from copyBuffers.
+func copyPooled(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get()
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(dst, src, buf)
+}
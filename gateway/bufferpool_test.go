@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBufferPool(t *testing.T) {
+	p := NewBufferPool(1024)
+	b := p.Get()
+	if len(b) != 1024 {
+		t.Fatalf("len(Get()) = %d, want 1024", len(b))
+	}
+
+	// A returned buffer is handed out again at full length, even if it was
+	// resliced; foreign sizes are dropped.
+	p.Put(b[:10])
+	p.Put(make([]byte, 512))
+	for i := 0; i < 3; i++ {
+		if got := p.Get(); len(got) != 1024 {
+			t.Errorf("len(Get()) = %d after Put, want 1024", len(got))
+		}
+	}
+}
+
+func TestCopyPooled(t *testing.T) {
+	src := strings.Repeat("websocket frame ", 10_000) // larger than one buffer
+	var dst bytes.Buffer
+	n, err := copyPooled(&dst, strings.NewReader(src))
+	if err != nil || n != int64(len(src)) {
+		t.Fatalf("copyPooled = (%d, %v), want (%d, nil)", n, err, len(src))
+	}
+	if dst.String() != src {
+		t.Error("copied data differs from the source")
+	}
+}
+
+func TestCopyPooled_Allocations(t *testing.T) {
+	src := strings.NewReader(strings.Repeat("x", 4*copyBufferSize))
+	var buf bytes.Buffer
+	buf.Grow(4 * copyBufferSize)
+	dst := onlyWriter{&buf}
+	copyPooled(dst, onlyReader{src}) // warm the pool
+
+	allocs := testing.AllocsPerRun(100, func() {
+		src.Seek(0, 0)
+		buf.Reset()
+		copyPooled(dst, onlyReader{src})
+	})
+	// The only allocation is the slice header Put stores in the pool; a fresh
+	// 32 KiB buffer per copy would be a second one.
+	if allocs > 1 {
+		t.Errorf("copyPooled allocates %.0f times per copy, want pooled buffers", allocs)
+	}
+}
+
+// onlyWriter and onlyReader hide ReadFrom / WriteTo so io.CopyBuffer has to
+// use the buffer.
+type onlyWriter struct{ w *bytes.Buffer }
+
+func (w onlyWriter) Write(p []byte) (int, error) { return w.w.Write(p) }
+
+type onlyReader struct{ r *strings.Reader }
+
+func (r onlyReader) Read(p []byte) (int, error) { return r.r.Read(p) }
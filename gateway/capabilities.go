@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+)
+
+// Docker API operations checked by detect_capabilities. A socket proxy such
+// as docker-socket-proxy may forbid some of them; the gateway then does
+// without instead of failing at runtime.
+const (
+	CapabilityStart   = "start"   // on-demand starts; required
+	CapabilityStop    = "stop"    // idle stops, schedule_stop, sleep, restarts
+	CapabilityKill    = "kill"    // /_status/kill
+	CapabilityExec    = "exec"    // command hooks
+	CapabilityNetwork = "network" // network_attach
+	CapabilityImages  = "images"  // update_check_interval
+)
+
+// capabilityList is every operation checked, in report order.
+var capabilityList = []string{
+	CapabilityStart, CapabilityStop, CapabilityKill,
+	CapabilityExec, CapabilityNetwork, CapabilityImages,
+}
+
+// capabilityProbeTimeout bounds the whole detection.
+const capabilityProbeTimeout = 10 * time.Second
+
+// capabilityProbeName names a container, network and image that do not
+// exist. The daemon answers 404 for it when the operation is allowed; a
+// proxy that forbids the operation answers 403 before reaching the daemon.
+const capabilityProbeName = "dag-capability-probe-does-not-exist"
+
+// ErrCapabilityDisabled is returned by DockerClient operations found
+// forbidden by detect_capabilities.
+var ErrCapabilityDisabled = errors.New("not allowed by the Docker API (detect_capabilities)")
+
+// capabilities records the operations found forbidden. The zero value, and
+// a nil pointer, allow everything.
+type capabilities struct {
+	mu       sync.RWMutex
+	detected bool
+	denied   map[string]string // forbidden operation → error of its probe
+}
+
+func (c *capabilities) allowed(op string) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, denied := c.denied[op]
+	return !denied
+}
+
+// report returns "ok" or "forbidden" per operation, or nil when detection
+// did not run.
+func (c *capabilities) report() map[string]string {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.detected {
+		return nil
+	}
+	out := make(map[string]string, len(capabilityList))
+	for _, op := range capabilityList {
+		out[op] = "ok"
+		if _, denied := c.denied[op]; denied {
+			out[op] = "forbidden"
+		}
+	}
+	return out
+}
+
+func (c *capabilities) set(denied map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.detected = true
+	c.denied = denied
+}
+
+// Capable reports whether op may be used: always true unless
+// DetectCapabilities found it forbidden.
+func (d *DockerClient) Capable(op string) bool {
+	return d == nil || d.caps.allowed(op)
+}
+
+// Capabilities returns "ok" or "forbidden" per operation, or nil when
+// DetectCapabilities did not run.
+func (d *DockerClient) Capabilities() map[string]string {
+	if d == nil {
+		return nil
+	}
+	return d.caps.report()
+}
+
+// capabilityError wraps ErrCapabilityDisabled for op.
+func capabilityError(op string) error {
+	return fmt.Errorf("%s: %w", op, ErrCapabilityDisabled)
+}
+
+// DetectCapabilities probes each operation against an object that does not
+// exist, so nothing is changed, and disables the ones answered with 403 or
+// 401. Any other answer, including an unreachable daemon, keeps the
+// operation enabled. It returns the forbidden operations with the error
+// their probe got.
+func (d *DockerClient) DetectCapabilities(ctx context.Context) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, capabilityProbeTimeout)
+	defer cancel()
+
+	probes := map[string]func() error{
+		CapabilityStart: func() error {
+			return d.cli.ContainerStart(ctx, capabilityProbeName, container.StartOptions{})
+		},
+		CapabilityStop: func() error {
+			return d.cli.ContainerStop(ctx, capabilityProbeName, container.StopOptions{})
+		},
+		CapabilityKill: func() error {
+			return d.cli.ContainerKill(ctx, capabilityProbeName, "KILL")
+		},
+		CapabilityExec: func() error {
+			_, err := d.cli.ContainerExecCreate(ctx, capabilityProbeName, container.ExecOptions{Cmd: []string{"true"}})
+			return err
+		},
+		CapabilityNetwork: func() error {
+			return d.cli.NetworkConnect(ctx, capabilityProbeName, capabilityProbeName, nil)
+		},
+		CapabilityImages: func() error {
+			_, err := d.cli.ImageInspect(ctx, capabilityProbeName)
+			return err
+		},
+	}
+	denied := make(map[string]string)
+	for op, probe := range probes {
+		if err := probe(); cerrdefs.IsPermissionDenied(err) || cerrdefs.IsUnauthorized(err) {
+			denied[op] = err.Error()
+		}
+	}
+	if d.caps == nil {
+		d.caps = &capabilities{}
+	}
+	d.caps.set(denied)
+	for _, op := range capabilityList {
+		_, forbidden := denied[op]
+		SetDockerCapability(op, !forbidden)
+	}
+	return denied
+}
+
+// capabilityEffects explains what the gateway does without each operation.
+var capabilityEffects = map[string]string{
+	CapabilityStart:   "containers cannot be started: requests to stopped containers will fail",
+	CapabilityStop:    "containers are never stopped: idle_timeout, schedule_stop, sleep and restarts are skipped",
+	CapabilityKill:    "/_status/kill is unavailable",
+	CapabilityExec:    "command hooks are skipped",
+	CapabilityNetwork: "network_attach is skipped",
+	CapabilityImages:  "image update checks are skipped",
+}
+
+// LogCapabilities logs each forbidden operation and its consequence.
+func LogCapabilities(denied map[string]string) {
+	if len(denied) == 0 {
+		slog.Info("docker capabilities: all operations allowed")
+		return
+	}
+	for _, op := range capabilityList {
+		probeErr, forbidden := denied[op]
+		if !forbidden {
+			continue
+		}
+		level := slog.LevelWarn
+		if op == CapabilityStart {
+			level = slog.LevelError
+		}
+		slog.Log(context.Background(), level, "docker capabilities: operation forbidden by the Docker API",
+			"operation", op, "effect", capabilityEffects[op], "error", probeErr)
+	}
+}
+
+// writeCapabilityError answers an admin action whose operation is disabled.
+func writeCapabilityError(w http.ResponseWriter, err error) {
+	http.Error(w, fmt.Sprintf("%v; see capabilities in /_status/api", err), http.StatusNotImplemented)
+}
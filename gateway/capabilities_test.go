@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newSocketProxy fakes a Docker daemon behind a socket proxy that forbids
+// the paths ending in one of the given suffixes.
+func newSocketProxy(t *testing.T, forbidden ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, suffix := range forbidden {
+			if strings.HasSuffix(r.URL.Path, suffix) {
+				http.Error(w, "<html><body><h1>403 Forbidden</h1></body></html>", http.StatusForbidden)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such container: ` + capabilityProbeName + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	srv := newSocketProxy(t, "/stop", "/kill", "/exec")
+	d := newTestDockerClient(t, srv.URL)
+	if d.Capabilities() != nil {
+		t.Error("capabilities must not be reported before detection")
+	}
+
+	denied := d.DetectCapabilities(context.Background())
+	if len(denied) != 3 || denied[CapabilityStop] == "" || denied[CapabilityKill] == "" || denied[CapabilityExec] == "" {
+		t.Fatalf("denied = %v, want stop, kill and exec", denied)
+	}
+	want := map[string]string{
+		CapabilityStart: "ok", CapabilityStop: "forbidden", CapabilityKill: "forbidden",
+		CapabilityExec: "forbidden", CapabilityNetwork: "ok", CapabilityImages: "ok",
+	}
+	got := d.Capabilities()
+	for op, status := range want {
+		if got[op] != status {
+			t.Errorf("%s = %q, want %q", op, got[op], status)
+		}
+	}
+
+	if err := d.StopContainer(context.Background(), "app"); !errors.Is(err, ErrCapabilityDisabled) {
+		t.Errorf("StopContainer error = %v, want ErrCapabilityDisabled", err)
+	}
+	if _, _, err := d.ExecCommand(context.Background(), "app", []string{"true"}); !errors.Is(err, ErrCapabilityDisabled) {
+		t.Errorf("ExecCommand error = %v, want ErrCapabilityDisabled", err)
+	}
+
+	// A command hook is skipped rather than failing the stage.
+	hooks := []HookConfig{{Container: "app", Command: []string{"true"}}}
+	if err := runHooks(context.Background(), d.ExecCommand, "app", hookPreStart, hooks); err != nil {
+		t.Errorf("hook with exec forbidden: %v", err)
+	}
+}
+
+func TestDetectCapabilities_Unreachable(t *testing.T) {
+	srv := newSocketProxy(t)
+	d := newTestDockerClient(t, srv.URL)
+	srv.Close()
+
+	if denied := d.DetectCapabilities(context.Background()); len(denied) != 0 {
+		t.Errorf("denied = %v: an unreachable daemon must not disable anything", denied)
+	}
+	if !d.Capable(CapabilityStop) {
+		t.Error("stop should stay enabled")
+	}
+}
+
+func TestStatusActions_CapabilityDisabled(t *testing.T) {
+	srv := newSocketProxy(t, "/stop", "/kill")
+	d := newTestDockerClient(t, srv.URL)
+	d.DetectCapabilities(context.Background())
+
+	s := &Server{
+		cfg:         &GatewayConfig{Containers: []ContainerConfig{{Name: "app"}}},
+		manager:     NewContainerManager(d),
+		rateLimiter: newRateLimiter(RateLimitConfig{}),
+	}
+	for _, path := range []string{"/_status/sleep", "/_status/kill"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, path+"?container=app", nil)
+		switch path {
+		case "/_status/sleep":
+			s.handleStatusSleep(w, r)
+		default:
+			s.handleStatusKill(w, r)
+		}
+		if w.Code != http.StatusNotImplemented || !strings.Contains(w.Body.String(), "not allowed by the Docker API") {
+			t.Errorf("%s: status = %d, body %q", path, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitState is the state of a per-container circuit breaker.
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// String returns the state name exposed in /_status/api.
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// breakerEntry holds the circuit breaker state of a single container.
+type breakerEntry struct {
+	state         circuitState
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+	trial         uint64 // numbers the trials, so a stale release is ignored
+}
+
+// CircuitBreaker short-circuits requests to containers whose backend keeps
+// failing. After threshold consecutive failures the circuit opens and requests
+// are rejected for the cooldown; then a single trial request is let through
+// (half-open) whose outcome closes or re-opens the circuit.
+type CircuitBreaker struct {
+	mu      sync.Mutex
+	entries map[string]*breakerEntry
+}
+
+// NewCircuitBreaker creates a CircuitBreaker with every circuit closed.
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{entries: make(map[string]*breakerEntry)}
+}
+
+// Allow reports whether a request to the container may be proxied. The
+// caller must call release once the request is over: when it was the
+// half-open trial and ended without RecordSuccess or RecordFailure (refused
+// by a drain or a full queue, or its client left), release lets the next
+// request be the trial instead of keeping the circuit half-open for good.
+func (cb *CircuitBreaker) Allow(name string, cooldown time.Duration) (allowed bool, release func()) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	e, ok := cb.entries[name]
+	if !ok {
+		return true, func() {}
+	}
+	switch e.state {
+	case circuitOpen:
+		if time.Since(e.openedAt) < cooldown {
+			return false, func() {}
+		}
+		e.state = circuitHalfOpen
+		SetCircuitState(name, circuitHalfOpen)
+		return true, cb.startTrial(name, e)
+	case circuitHalfOpen:
+		if e.trialInFlight {
+			return false, func() {}
+		}
+		return true, cb.startTrial(name, e)
+	default:
+		return true, func() {}
+	}
+}
+
+// startTrial marks a trial request in flight and returns its release. The
+// caller holds mu.
+func (cb *CircuitBreaker) startTrial(name string, e *breakerEntry) func() {
+	e.trialInFlight = true
+	e.trial++
+	trial := e.trial
+	return func() {
+		cb.mu.Lock()
+		defer cb.mu.Unlock()
+		if e.state == circuitHalfOpen && e.trialInFlight && e.trial == trial {
+			e.trialInFlight = false
+		}
+	}
+}
+
+// RecordSuccess closes the circuit and clears the failure count.
+func (cb *CircuitBreaker) RecordSuccess(name string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	e, ok := cb.entries[name]
+	if !ok {
+		return
+	}
+	if e.state != circuitClosed {
+		SetCircuitState(name, circuitClosed)
+	}
+	e.state = circuitClosed
+	e.failures = 0
+	e.trialInFlight = false
+}
+
+// RecordFailure counts a backend failure and returns true when it trips the
+// circuit open. A threshold <= 0 disables the breaker.
+func (cb *CircuitBreaker) RecordFailure(name string, threshold int) bool {
+	if threshold <= 0 {
+		return false
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	e, ok := cb.entries[name]
+	if !ok {
+		e = &breakerEntry{}
+		cb.entries[name] = e
+	}
+	switch e.state {
+	case circuitOpen:
+		return false
+	case circuitHalfOpen:
+		// The trial request failed — back to open for another cooldown.
+	default:
+		e.failures++
+		if e.failures < threshold {
+			return false
+		}
+	}
+	e.state = circuitOpen
+	e.openedAt = time.Now()
+	e.trialInFlight = false
+	SetCircuitState(name, circuitOpen)
+	CircuitTripsTotal.WithLabelValues(name).Inc()
+	return true
+}
+
+// State returns the current circuit state of a container.
+func (cb *CircuitBreaker) State(name string) circuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if e, ok := cb.entries[name]; ok {
+		return e.state
+	}
+	return circuitClosed
+}
+
+// RetryAfter returns how long an open circuit stays open. It returns 0 for
+// closed or half-open circuits.
+func (cb *CircuitBreaker) RetryAfter(name string, cooldown time.Duration) time.Duration {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	e, ok := cb.entries[name]
+	if !ok || e.state != circuitOpen {
+		return 0
+	}
+	if remaining := cooldown - time.Since(e.openedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
@@ -0,0 +1,215 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// ─── CircuitBreaker ───────────────────────────────────────────────────────────
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = time.Minute
+
+	t.Run("opens after threshold consecutive failures", func(t *testing.T) {
+		cb := NewCircuitBreaker()
+		if cb.RecordFailure("app", 3) || cb.RecordFailure("app", 3) {
+			t.Fatal("circuit tripped before threshold")
+		}
+		if !allowed(cb, cooldown) {
+			t.Error("closed circuit should allow requests")
+		}
+		if !cb.RecordFailure("app", 3) {
+			t.Fatal("circuit should trip at threshold")
+		}
+		if cb.State("app") != circuitOpen {
+			t.Errorf("State() = %v, want open", cb.State("app"))
+		}
+		if allowed(cb, cooldown) {
+			t.Error("open circuit should reject requests during cooldown")
+		}
+		if cb.RetryAfter("app", cooldown) <= 0 {
+			t.Error("RetryAfter() should be positive while open")
+		}
+	})
+
+	t.Run("success resets the failure count", func(t *testing.T) {
+		cb := NewCircuitBreaker()
+		cb.RecordFailure("app", 2)
+		cb.RecordSuccess("app")
+		if cb.RecordFailure("app", 2) {
+			t.Error("failure count should restart after a success")
+		}
+	})
+
+	t.Run("half-open lets a single trial through", func(t *testing.T) {
+		cb := NewCircuitBreaker()
+		cb.RecordFailure("app", 1)
+		cb.mu.Lock()
+		cb.entries["app"].openedAt = time.Now().Add(-cooldown)
+		cb.mu.Unlock()
+
+		if !allowed(cb, cooldown) {
+			t.Fatal("trial request should be allowed after cooldown")
+		}
+		if cb.State("app") != circuitHalfOpen {
+			t.Errorf("State() = %v, want half_open", cb.State("app"))
+		}
+		if allowed(cb, cooldown) {
+			t.Error("only one trial request should be in flight")
+		}
+		cb.RecordSuccess("app")
+		if cb.State("app") != circuitClosed || !allowed(cb, cooldown) {
+			t.Error("successful trial should close the circuit")
+		}
+	})
+
+	t.Run("failed trial re-opens the circuit", func(t *testing.T) {
+		cb := NewCircuitBreaker()
+		cb.RecordFailure("app", 1)
+		cb.mu.Lock()
+		cb.entries["app"].openedAt = time.Now().Add(-cooldown)
+		cb.mu.Unlock()
+		allowed(cb, cooldown)
+
+		if !cb.RecordFailure("app", 1) {
+			t.Error("failed trial should trip the circuit again")
+		}
+		if cb.State("app") != circuitOpen {
+			t.Errorf("State() = %v, want open", cb.State("app"))
+		}
+	})
+
+	t.Run("zero threshold disables the breaker", func(t *testing.T) {
+		cb := NewCircuitBreaker()
+		for i := 0; i < 10; i++ {
+			cb.RecordFailure("app", 0)
+		}
+		if !allowed(cb, cooldown) || cb.State("app") != circuitClosed {
+			t.Error("disabled breaker should never open")
+		}
+	})
+}
+
+func TestCircuitBreaker_ReleaseTrial(t *testing.T) {
+	const cooldown = time.Minute
+	cb := NewCircuitBreaker()
+	cb.RecordFailure("app", 1)
+	cb.mu.Lock()
+	cb.entries["app"].openedAt = time.Now().Add(-cooldown)
+	cb.mu.Unlock()
+
+	ok, release := cb.Allow("app", cooldown)
+	if !ok || allowed(cb, cooldown) {
+		t.Fatal("want a single trial request")
+	}
+	release()
+	ok, release = cb.Allow("app", cooldown)
+	if !ok {
+		t.Fatal("trial released without an outcome should let the next request through")
+	}
+	cb.RecordFailure("app", 1)
+	release() // stale: the trial was recorded
+	if cb.State("app") != circuitOpen || allowed(cb, cooldown) {
+		t.Error("release after a recorded failure changed the open circuit")
+	}
+}
+
+func TestCircuitBreaker_TrialRefusedByFullQueue(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: port,
+		CircuitBreakerThreshold: 1, MaxConcurrentRequests: 1, Queue: QueueConfig{Size: 1, Timeout: time.Minute}})
+	cfg := g.server.activeContainer("app")
+
+	g.manager.breaker.RecordFailure("app", 1)
+	g.manager.breaker.mu.Lock()
+	g.manager.breaker.entries["app"].openedAt = time.Now().Add(-cfg.CircuitBreakerCooldown)
+	g.manager.breaker.mu.Unlock()
+
+	// The trial request finds the only slot and queue place taken.
+	release, err := g.manager.limiter.Acquire(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go g.manager.limiter.Acquire(ctx, cfg)
+	waitFor(t, func() bool {
+		g.manager.limiter.mu.Lock()
+		defer g.manager.limiter.mu.Unlock()
+		return g.manager.limiter.slots["app"].waiting == 1
+	})
+	if w := g.get("app.local", "/"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("trial status = %d, want 503 for the full queue", w.Code)
+	}
+	cancel()
+	release()
+
+	if w := g.get("app.local", "/"); w.Code != http.StatusOK {
+		t.Fatalf("next request status = %d, want 200: the circuit kept a refused trial in flight", w.Code)
+	}
+	if g.manager.breaker.State("app") != circuitClosed {
+		t.Errorf("State() = %v, want closed after a successful trial", g.manager.breaker.State("app"))
+	}
+}
+
+func TestCircuitBreaker_IgnoresCanceledRequests(t *testing.T) {
+	arrived := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer backend.Close()
+	host, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: port,
+		CircuitBreakerThreshold: 2, UnhealthyThreshold: 2})
+
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		r.Host = "app.local"
+		done := make(chan struct{})
+		go func() {
+			g.handler.ServeHTTP(httptest.NewRecorder(), r)
+			close(done)
+		}()
+		select {
+		case <-arrived:
+		case <-done:
+			t.Fatalf("request %d did not reach the backend: the circuit opened", i)
+		}
+		cancel()
+		<-done
+	}
+	if state := g.manager.breaker.State("app"); state != circuitClosed {
+		t.Errorf("State() = %v after clients left, want closed", state)
+	}
+	if n := g.manager.health.Failures("app"); n != 0 {
+		t.Errorf("passive health failures = %d after clients left, want 0", n)
+	}
+}
+
+// allowed reports whether cb lets a request to app through, as a request
+// that is still in flight.
+func allowed(cb *CircuitBreaker, cooldown time.Duration) bool {
+	ok, _ := cb.Allow("app", cooldown)
+	return ok
+}
+
+func TestCircuitStateString(t *testing.T) {
+	for state, want := range map[circuitState]string{
+		circuitClosed:   "closed",
+		circuitOpen:     "open",
+		circuitHalfOpen: "half_open",
+	} {
+		if got := state.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"net/http"
+	"sync"
+)
+
+// clientLimiter counts the requests each client IP has in flight, so a
+// single client cannot hold every gateway connection at once.
+type clientLimiter struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+func newClientLimiter() *clientLimiter {
+	return &clientLimiter{inFlight: make(map[string]int)}
+}
+
+// acquire counts a request of ip unless the client already has max in
+// flight. A max of 0 disables the limit.
+func (l *clientLimiter) acquire(ip string, max int) bool {
+	if max <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[ip] >= max {
+		return false
+	}
+	l.inFlight[ip]++
+	return true
+}
+
+// release ends a request counted by acquire. Idle clients are dropped from
+// the map, so it only holds clients with requests in flight.
+func (l *clientLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[ip] <= 1 {
+		delete(l.inFlight, ip)
+		return
+	}
+	l.inFlight[ip]--
+}
+
+// clientLimitMiddleware answers 429 to clients that already have
+// max_concurrent_per_ip requests in flight. WebSocket tunnels count until
+// they close.
+func (s *Server) clientLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		max := s.GetConfig().Gateway.MaxConcurrentPerIP
+		if max <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip := s.clientIP(r)
+		if !s.clientLimiter.acquire(ip, max) {
+			ClientConcurrencyRejectedTotal.Inc()
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many concurrent requests", http.StatusTooManyRequests)
+			return
+		}
+		defer s.clientLimiter.release(ip)
+		next.ServeHTTP(w, r)
+	})
+}
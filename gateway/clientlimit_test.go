@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestClientLimiter(t *testing.T) {
+	l := newClientLimiter()
+	if !l.acquire("10.0.0.1", 2) || !l.acquire("10.0.0.1", 2) {
+		t.Fatal("requests under the limit were rejected")
+	}
+	if l.acquire("10.0.0.1", 2) {
+		t.Error("third request of a client with max 2 was accepted")
+	}
+	if !l.acquire("10.0.0.2", 2) {
+		t.Error("another client was rejected")
+	}
+	l.release("10.0.0.1")
+	if !l.acquire("10.0.0.1", 2) {
+		t.Error("request after a release was rejected")
+	}
+	if !l.acquire("10.0.0.1", 0) {
+		t.Error("max 0 should disable the limit")
+	}
+
+	l.release("10.0.0.1")
+	l.release("10.0.0.1")
+	l.release("10.0.0.2")
+	if len(l.inFlight) != 0 {
+		t.Errorf("idle clients kept in the map: %v", l.inFlight)
+	}
+}
+
+func TestClientLimitMiddleware(t *testing.T) {
+	cfg := &GatewayConfig{}
+	cfg.Gateway.MaxConcurrentPerIP = 1
+	cfg.Gateway.TrustedProxies = []string{"10.0.0.0/8"}
+	s := &Server{cfg: cfg, clientLimiter: newClientLimiter(),
+		trustedCIDRs: parseTrustedProxies(cfg.Gateway.TrustedProxies)}
+
+	entered, unblock := make(chan struct{}), make(chan struct{})
+	h := s.clientLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			entered <- struct{}{}
+			<-unblock
+		}
+	}))
+	call := func(path, forwardedFor string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		call("/slow", "203.0.113.7")
+	}()
+	<-entered
+
+	w := call("/", "203.0.113.7")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("second request of a busy client: status = %d, Retry-After = %q, want 429 with Retry-After",
+			w.Code, w.Header().Get("Retry-After"))
+	}
+	// Another client behind the same trusted proxy is not affected.
+	if code := call("/", "203.0.113.8").Code; code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", code)
+	}
+
+	close(unblock)
+	wg.Wait()
+	if code := call("/", "203.0.113.7").Code; code != http.StatusOK {
+		t.Errorf("after the first request ended: status = %d, want 200", code)
+	}
+}
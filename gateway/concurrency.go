@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors returned by ConcurrencyLimiter.Acquire.
+var (
+	errQueueFull    = errors.New("request queue is full")
+	errQueueTimeout = errors.New("timed out waiting in the request queue")
+)
+
+// containerSlots holds the in-flight slots of a single container.
+type containerSlots struct {
+	sem     chan struct{} // capacity = max_concurrent_requests
+	waiting int
+}
+
+// ConcurrencyLimiter caps the requests proxied to each container at once.
+// Requests over the cap wait in a bounded queue (blocked channel senders are
+// served in arrival order) until a slot frees up or the queue timeout
+// expires, so a just-woken single-threaded app is not hit by the whole
+// backlog at once.
+type ConcurrencyLimiter struct {
+	mu    sync.Mutex
+	slots map[string]*containerSlots
+}
+
+// NewConcurrencyLimiter creates a limiter with no containers tracked.
+func NewConcurrencyLimiter() *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{slots: make(map[string]*containerSlots)}
+}
+
+// get returns the slots of the container, replacing them when the limit
+// changed on reload. Requests holding an old slot release it to the old
+// channel, so the new limit applies to new requests only.
+func (l *ConcurrencyLimiter) get(name string, max int) *containerSlots {
+	cs, ok := l.slots[name]
+	if !ok || cap(cs.sem) != max {
+		cs = &containerSlots{sem: make(chan struct{}, max)}
+		l.slots[name] = cs
+	}
+	return cs
+}
+
+// Acquire takes a slot for a request to cfg, waiting in the queue when all
+// slots are busy. The returned release func must be called when the request
+// is done. Containers without max_concurrent_requests are never limited.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, cfg *ContainerConfig) (release func(), err error) {
+	if cfg.MaxConcurrentRequests <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	cs := l.get(cfg.Name, cfg.MaxConcurrentRequests)
+	select {
+	case cs.sem <- struct{}{}:
+		l.mu.Unlock()
+		return func() { <-cs.sem }, nil
+	default:
+	}
+	if cs.waiting >= cfg.Queue.Size {
+		l.mu.Unlock()
+		RecordQueueRejected(cfg.Name, "full")
+		return nil, errQueueFull
+	}
+	cs.waiting++
+	QueuedRequests.WithLabelValues(cfg.Name).Inc()
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		cs.waiting--
+		l.mu.Unlock()
+		QueuedRequests.WithLabelValues(cfg.Name).Dec()
+	}()
+
+	start := time.Now()
+	timer := time.NewTimer(cfg.Queue.Timeout)
+	defer timer.Stop()
+	select {
+	case cs.sem <- struct{}{}:
+		QueueWaitDuration.WithLabelValues(cfg.Name).Observe(time.Since(start).Seconds())
+		return func() { <-cs.sem }, nil
+	case <-timer.C:
+		RecordQueueRejected(cfg.Name, "timeout")
+		return nil, errQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Forget drops the slots of a container that is no longer managed.
+func (l *ConcurrencyLimiter) Forget(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.slots, name)
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func limitedConfig(max, queueSize int, timeout time.Duration) *ContainerConfig {
+	return &ContainerConfig{
+		Name:                  "app",
+		MaxConcurrentRequests: max,
+		Queue:                 QueueConfig{Size: queueSize, Timeout: timeout},
+	}
+}
+
+func TestConcurrencyLimiter_Unlimited(t *testing.T) {
+	l := NewConcurrencyLimiter()
+	cfg := &ContainerConfig{Name: "app"}
+	for i := 0; i < 100; i++ {
+		if _, err := l.Acquire(context.Background(), cfg); err != nil {
+			t.Fatalf("unlimited container: %v", err)
+		}
+	}
+}
+
+func TestConcurrencyLimiter_QueueWaitsForSlot(t *testing.T) {
+	l := NewConcurrencyLimiter()
+	cfg := limitedConfig(1, 1, time.Second)
+
+	release, err := l.Acquire(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan error, 1)
+	go func() {
+		rel, err := l.Acquire(context.Background(), cfg)
+		if err == nil {
+			rel()
+		}
+		got <- err
+	}()
+
+	// The second request is queued until the first releases its slot.
+	select {
+	case err := <-got:
+		t.Fatalf("queued request returned early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Errorf("queued request: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued request was not served after release")
+	}
+}
+
+func TestConcurrencyLimiter_QueueFull(t *testing.T) {
+	l := NewConcurrencyLimiter()
+	cfg := limitedConfig(1, 1, time.Second)
+
+	release, _ := l.Acquire(context.Background(), cfg)
+	defer release()
+
+	// Occupy the only queue place.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Acquire(ctx, cfg)
+	waitFor(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.slots["app"].waiting == 1
+	})
+
+	if _, err := l.Acquire(context.Background(), cfg); !errors.Is(err, errQueueFull) {
+		t.Errorf("err = %v, want errQueueFull", err)
+	}
+}
+
+func TestConcurrencyLimiter_QueueTimeout(t *testing.T) {
+	l := NewConcurrencyLimiter()
+	cfg := limitedConfig(1, 5, 20*time.Millisecond)
+
+	release, _ := l.Acquire(context.Background(), cfg)
+	defer release()
+
+	if _, err := l.Acquire(context.Background(), cfg); !errors.Is(err, errQueueTimeout) {
+		t.Errorf("err = %v, want errQueueTimeout", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w := l.slots["app"].waiting; w != 0 {
+		t.Errorf("waiting = %d after timeout, want 0", w)
+	}
+}
+
+func TestConcurrencyLimiter_LimitChangeOnReload(t *testing.T) {
+	l := NewConcurrencyLimiter()
+	release, _ := l.Acquire(context.Background(), limitedConfig(1, 1, time.Millisecond))
+	defer release()
+
+	// Raising the limit takes effect for new requests immediately.
+	cfg := limitedConfig(2, 1, time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, err := l.Acquire(context.Background(), cfg); err != nil {
+			t.Fatalf("request %d after raising the limit: %v", i+1, err)
+		}
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
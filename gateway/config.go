@@ -3,8 +3,14 @@ package gateway
 import (
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"reflect"
+	"runtime"
+	"slices"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -24,6 +30,31 @@ type GatewayConfig struct {
 	Gateway    GlobalConfig      `yaml:"gateway"`
 	Containers []ContainerConfig `yaml:"containers"`
 	Groups     []GroupConfig     `yaml:"groups"`
+	TCPRoutes  []TCPRouteConfig  `yaml:"tcp_routes"`
+}
+
+// TCPRouteConfig forwards the raw TCP connections received on Listen to a
+// container, for services that do not speak HTTP (databases, SSH, game
+// servers). Routes sharing a listen address are told apart by the server
+// name (SNI) of a TLS ClientHello; the TLS session is passed through to the
+// container untouched.
+type TCPRouteConfig struct {
+	// Listen is the address the gateway accepts connections on, e.g.
+	// ":5432". Listen addresses are bound at startup and not hot-reloaded.
+	Listen string `yaml:"listen"`
+	// SNI restricts the route to TLS connections for this server name.
+	// (default: "" — every connection not matched by an SNI route)
+	SNI string `yaml:"sni"`
+	// Container is the container connections are forwarded to.
+	Container string `yaml:"container"`
+	// Port is the container port connections are forwarded to.
+	// (default: the container's target_port)
+	Port string `yaml:"port"`
+	// WakeMode selects what a connection that wakes the container gets:
+	// "hold" keeps it open until the container is ready, up to its
+	// start_timeout, and then forwards it; "close" closes it at once, for
+	// clients that retry on their own. (default: "hold")
+	WakeMode string `yaml:"wake_mode"`
 }
 
 // GroupConfig defines a load-balanced group of containers behind a single host.
@@ -32,12 +63,112 @@ type GroupConfig struct {
 	Name string `yaml:"name"`
 	// Host is the incoming Host header that routes to this group
 	Host string `yaml:"host"`
-	// Strategy is the load-balancing algorithm. (default: "round-robin")
+	// Strategy is the load-balancing algorithm: "round-robin" hands each
+	// member weight requests in a row, "weighted" spreads them through the
+	// round, "least_connections" picks the member with the fewest requests in
+	// flight relative to its weight. (default: "round-robin")
 	Strategy string `yaml:"strategy"`
-	// Containers is the ordered list of container names in this group
-	Containers []string `yaml:"containers"`
+	// Members is the ordered list of group members. In YAML each entry is a
+	// container name or an object with per-member overrides (GroupMember).
+	Members []GroupMember `yaml:"containers"`
+	// Containers is the ordered list of container names in this group,
+	// filled from Members when the config is loaded.
+	Containers []string `yaml:"-"`
+	// StartOrder is how members are started when the group wakes up:
+	// "sequential" waits for each member to be ready before starting the
+	// next, in Containers order; "parallel" starts them all at once.
+	// (default: "sequential")
+	StartOrder string `yaml:"start_order"`
+	// StartStagger is an extra delay between members: after a member is
+	// ready ("sequential") or between launching them ("parallel").
+	// (default: 0)
+	StartStagger time.Duration `yaml:"start_stagger"`
+	// Hedge sends slow read-only requests to a second member.
+	// See HedgeConfig for details. (default: disabled)
+	Hedge HedgeConfig `yaml:"hedge"`
+	// HealthInterval is how often each member is checked, with its readiness
+	// check, while the group runs. A member that is stopped or fails two
+	// checks in a row gets no requests until a check passes.
+	// (default: 0 — disabled)
+	HealthInterval time.Duration `yaml:"health_interval"`
+	// Middlewares lists gateway.middlewares applied, in order, to every
+	// request routed to the group. (default: [])
+	Middlewares []string `yaml:"middlewares"`
+	// Tenant is the gateway.tenants entry the group belongs to; its members
+	// must belong to the same one. (default: "", admin_auth only)
+	Tenant string `yaml:"tenant"`
+	// Affinity "cookie" keeps a client on the member it was first sent to,
+	// through a signed cookie, for apps with in-memory sessions. The client
+	// moves to another member only when its own leaves the group or is
+	// degraded. (default: "none")
+	Affinity string `yaml:"affinity"`
+}
+
+// HedgeConfig enables request hedging for a group: a GET or HEAD request the
+// picked member has not answered within Delay is also sent to the next
+// member, and whichever answers first is returned; the other is cancelled.
+type HedgeConfig struct {
+	// Delay is how long to wait for the picked member before hedging.
+	// (default: 0 — disabled)
+	Delay time.Duration `yaml:"delay"`
+	// Paths restricts hedging to requests whose path starts with one of
+	// these prefixes. (default: [] — every path)
+	Paths []string `yaml:"paths"`
+}
+
+// GroupMember is one entry of a group's containers list. A member that has
+// no top-level container entry is declared as a copy of the group's first
+// member that has one, so replicas need not repeat their settings.
+type GroupMember struct {
+	// Name is the container name.
+	Name string `yaml:"name"`
+	// Weight is the member's share of the group's requests: a member with
+	// weight 2 gets twice the requests of a member with weight 1. (default: 1)
+	Weight int `yaml:"weight"`
+	// TargetPort overrides the container's target_port. (default: "")
+	TargetPort string `yaml:"target_port"`
+	// HealthPath overrides the container's health_path. (default: "")
+	HealthPath string `yaml:"health_path"`
+}
+
+// UnmarshalYAML accepts a plain container name as well as an object.
+func (m *GroupMember) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*m = GroupMember{Name: value.Value}
+		return nil
+	}
+	type plain GroupMember
+	return value.Decode((*plain)(m))
+}
+
+// weight returns the weight of the i-th member of Containers, 1 when the
+// group was built without Members.
+func (g *GroupConfig) weight(i int) int {
+	if len(g.Members) != len(g.Containers) || g.Members[i].Weight <= 0 {
+		return 1
+	}
+	return g.Members[i].Weight
 }
 
+// Group load-balancing strategies.
+const (
+	strategyRoundRobin       = "round-robin"
+	strategyWeighted         = "weighted"
+	strategyLeastConnections = "least_connections"
+)
+
+// Group session affinity modes.
+const (
+	affinityNone   = "none"
+	affinityCookie = "cookie"
+)
+
+// Group start orders.
+const (
+	startOrderSequential = "sequential"
+	startOrderParallel   = "parallel"
+)
+
 // AdminAuthConfig holds optional authentication settings for admin endpoints
 // (/_status/*, /_metrics). When Method is "none" (the default), no authentication
 // is enforced and the gateway behaves exactly as before this feature.
@@ -53,35 +184,742 @@ type AdminAuthConfig struct {
 	Token string `yaml:"token"`
 }
 
+// AdminAPIConfig controls the REST API under /_api/v1 that changes the
+// configured containers at runtime, behind admin_auth.
+type AdminAPIConfig struct {
+	// Enabled serves /_api/v1/containers. (default: false)
+	Enabled bool `yaml:"enabled"`
+	// Persist writes every change back to the containers section of the
+	// config file, so it survives a reload and a restart. Without it the
+	// changes last until the next reload of the file. (default: false)
+	Persist bool `yaml:"persist"`
+}
+
+// TenantConfig is a team sharing the gateway. Its credentials open the admin
+// dashboard and API scoped to the containers and groups whose tenant field
+// names it; gateway-wide endpoints stay reserved to admin_auth.
+type TenantConfig struct {
+	// Name is what containers and groups put in their tenant field.
+	Name string `yaml:"name"`
+	// Credentials lists the users (method "basic") and API keys (method
+	// "bearer") of the tenant. At least one is required.
+	Credentials []AdminAuthConfig `yaml:"credentials"`
+}
+
+// Middleware types accepted by MiddlewareConfig.Type.
+const (
+	MiddlewareAuth      = "auth"
+	MiddlewareRateLimit = "rate_limit"
+	MiddlewareHeaders   = "headers"
+	MiddlewarePlugin    = "plugin"
+	MiddlewareScript    = "script"
+)
+
+// MiddlewareConfig defines one named middleware. Only the settings of its
+// Type are used.
+type MiddlewareConfig struct {
+	// Type is "auth", "rate_limit", "headers", "plugin" or "script".
+	Type string `yaml:"type"`
+	// Auth holds the credentials required by type "auth". Method must be
+	// "basic" or "bearer".
+	Auth AdminAuthConfig `yaml:"auth"`
+	// RateLimit is the per-client-IP token bucket of type "rate_limit".
+	// Rate and burst are required.
+	RateLimit RateLimitPolicy `yaml:"rate_limit"`
+	// RequestHeaders are set on requests before they reach the container;
+	// an empty value removes the header. (type "headers")
+	RequestHeaders map[string]string `yaml:"request_headers"`
+	// ResponseHeaders are set on responses before they reach the client;
+	// an empty value removes the header. (type "headers")
+	ResponseHeaders map[string]string `yaml:"response_headers"`
+	// Plugin is the name a Go program registered with RegisterMiddleware.
+	// (type "plugin")
+	Plugin string `yaml:"plugin"`
+	// Script is the Lua filter of type "script". See ScriptConfig.
+	Script ScriptConfig `yaml:"script"`
+}
+
+// ScriptConfig is a Lua script defining on_request and/or on_wake. Exactly
+// one of File and Source is set.
+type ScriptConfig struct {
+	// File is the path of the script, read when the configuration is loaded.
+	File string `yaml:"file"`
+	// Source is the script inline.
+	Source string `yaml:"source"`
+	// Timeout bounds each call into the script; a call running longer fails.
+	// (default: 50ms)
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// RobotsConfig controls what crawlers get from the gateway itself.
+type RobotsConfig struct {
+	// Disable stops both the X-Robots-Tag header and the gateway's
+	// robots.txt. (default: false)
+	Disable bool `yaml:"disable"`
+	// Tag is the X-Robots-Tag header of the loading, scheduled, error and
+	// status pages. (default: "noindex, nofollow")
+	Tag string `yaml:"tag"`
+	// RobotsTxt answers /robots.txt for a container that is not running,
+	// instead of waking it. A running container serves its own.
+	// (default: "User-agent: *\nDisallow: /\n")
+	RobotsTxt string `yaml:"robots_txt"`
+}
+
+// NotificationConfig configures one outgoing notification channel.
+type NotificationConfig struct {
+	// Type is the notifier kind: "slack", "ntfy" or "gotify".
+	Type string `yaml:"type"`
+	// URL is the Slack webhook URL, the ntfy server base URL
+	// (e.g. "https://ntfy.sh") or the Gotify server base URL.
+	URL string `yaml:"url"`
+	// Topic is the ntfy topic to publish to. Required when Type is "ntfy".
+	Topic string `yaml:"topic"`
+	// Token is the Gotify application token (required for "gotify") or an
+	// optional ntfy access token.
+	Token string `yaml:"token"`
+	// Events restricts the notifier to the listed event types (e.g.
+	// ["start_failure", "crash_loop"]). (default: [] — all events)
+	Events []string `yaml:"events"`
+}
+
+// MQTTConfig configures the MQTT / Home Assistant integration. When Broker is
+// empty the integration is disabled.
+type MQTTConfig struct {
+	// Broker is the broker address, e.g. "tcp://mosquitto:1883" or
+	// "ssl://broker.example.com:8883". (default: "" — disabled)
+	Broker string `yaml:"broker"`
+	// Username for the broker. Overridable via MQTT_USERNAME env var.
+	Username string `yaml:"username"`
+	// Password for the broker. Overridable via MQTT_PASSWORD env var.
+	Password string `yaml:"password"`
+	// ClientID is the MQTT client identifier. (default: "docker-gateway")
+	ClientID string `yaml:"client_id"`
+	// TopicPrefix is the root of the state and command topics
+	// (<prefix>/<container>/state, <prefix>/<container>/set).
+	// (default: "docker-gateway")
+	TopicPrefix string `yaml:"topic_prefix"`
+	// DiscoveryPrefix is the Home Assistant MQTT discovery prefix.
+	// (default: "homeassistant")
+	DiscoveryPrefix string `yaml:"discovery_prefix"`
+}
+
+// LogFileConfig configures a log file with size- and age-based rotation.
+// When Path is empty no file is written.
+type LogFileConfig struct {
+	// Path of the active log file, e.g. "/var/log/gateway/access.log".
+	// Rotated files are stored next to it as
+	// "access-<timestamp>.log[.gz]". (default: "" — disabled)
+	Path string `yaml:"path"`
+	// MaxSizeMB rotates the file before it grows beyond this size.
+	// 0 disables size-based rotation. (default: 100)
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// RotateEvery rotates the file once it has been open this long, e.g.
+	// "24h" for daily files. 0 disables time-based rotation. (default: 0)
+	RotateEvery time.Duration `yaml:"rotate_every"`
+	// MaxBackups is the number of rotated files kept. 0 keeps all.
+	// (default: 0)
+	MaxBackups int `yaml:"max_backups"`
+	// MaxAge deletes rotated files older than this. 0 keeps them regardless
+	// of age. (default: 0)
+	MaxAge time.Duration `yaml:"max_age"`
+	// Compress gzips rotated files. (default: false)
+	Compress bool `yaml:"compress"`
+}
+
+func (l *LogFileConfig) validate() error {
+	if l.Path == "" {
+		return nil
+	}
+	if l.MaxSizeMB < 0 || l.MaxBackups < 0 || l.RotateEvery < 0 || l.MaxAge < 0 {
+		return fmt.Errorf("max_size_mb, rotate_every, max_backups and max_age cannot be negative")
+	}
+	return nil
+}
+
+// AccessLogConfig configures the structured access log: one JSON record per
+// request routed to a container.
+type AccessLogConfig struct {
+	// Enabled turns the access log on. (default: false)
+	Enabled bool `yaml:"enabled"`
+	// Fields selects the fields of each record, from: host, method, path,
+	// query, proto, status, duration_ms, bytes, container, group, client_ip,
+	// user_agent, referer, request_id. (default: host, method, path, status,
+	// duration_ms, bytes, container, group, client_ip, request_id)
+	Fields []string `yaml:"fields"`
+	// File writes the access log to a rotating file instead of stdout.
+	// See LogFileConfig for details. (default: stdout)
+	File LogFileConfig `yaml:"file"`
+	// Sample writes only one in Sample records of successful requests
+	// (status below 400); errors are always written. (default: 1 — every
+	// request)
+	Sample int `yaml:"sample"`
+}
+
+// TracingConfig configures OpenTelemetry tracing. Spans are exported to an
+// OTLP/HTTP collector; when Endpoint is empty tracing is disabled.
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector base URL, e.g. "http://tempo:4318"
+	// ("/v1/traces" is appended unless already present). Overridable via
+	// OTEL_EXPORTER_OTLP_ENDPOINT env var. (default: "" — disabled)
+	Endpoint string `yaml:"endpoint"`
+	// ServiceName is reported as the service.name resource attribute.
+	// Overridable via OTEL_SERVICE_NAME env var. (default: "docker-gateway")
+	ServiceName string `yaml:"service_name"`
+	// SampleRatio is the fraction of new traces that are recorded, between 0
+	// and 1. Requests carrying a traceparent header follow the caller's
+	// sampling decision. (default: 1.0)
+	SampleRatio float64 `yaml:"sample_ratio"`
+	// Headers are added to every export request, e.g. for collector auth.
+	// (default: {})
+	Headers map[string]string `yaml:"headers"`
+}
+
+// SyslogConfig forwards the application and access logs to a syslog server
+// in RFC 5424 format. When Address is empty forwarding is disabled.
+type SyslogConfig struct {
+	// Address is the syslog server, e.g. "udp://syslog:514" or
+	// "tcp://syslog:601". TCP messages use octet-counting framing (RFC 6587).
+	// (default: "" — disabled)
+	Address string `yaml:"address"`
+	// AppName is the APP-NAME field of every message. (default: "docker-gateway")
+	AppName string `yaml:"app_name"`
+	// Facility is the syslog facility: "user", "daemon" or "local0".."local7".
+	// (default: "local0")
+	Facility string `yaml:"facility"`
+}
+
+// LokiConfig pushes the application and access logs to Grafana Loki. When URL
+// is empty forwarding is disabled.
+type LokiConfig struct {
+	// URL is the Loki base URL, e.g. "http://loki:3100" ("/loki/api/v1/push"
+	// is appended unless already present). (default: "" — disabled)
+	URL string `yaml:"url"`
+	// TenantID is sent as X-Scope-OrgID for multi-tenant Loki. (default: "")
+	TenantID string `yaml:"tenant_id"`
+	// Labels are added to every stream, next to the built-in "stream",
+	// "level", "container" and "host" labels. (default: {job: "docker-gateway"})
+	Labels map[string]string `yaml:"labels"`
+	// Headers are added to every push request, e.g. for authentication.
+	// (default: {})
+	Headers map[string]string `yaml:"headers"`
+}
+
+// DebugConfig enables diagnostic endpoints. All of them are behind admin_auth.
+type DebugConfig struct {
+	// Pprof serves the net/http/pprof profiles under /_debug/pprof/.
+	// (default: false)
+	Pprof bool `yaml:"pprof"`
+}
+
+// NetworkAttachConfig lets the gateway join the Docker network of a backend
+// it shares no network with. The gateway's own container is connected on
+// demand and disconnected again once no running backend needs the network.
+type NetworkAttachConfig struct {
+	// Enabled turns automatic network attachment on. (default: false)
+	Enabled bool `yaml:"enabled"`
+	// Container is the name or ID of the gateway's own container.
+	// (default: the hostname, i.e. the short container ID set by Docker)
+	Container string `yaml:"container"`
+}
+
+// WakeOnLANConfig powers on the machine of a remote docker_host that was
+// suspended to save energy: when its daemon does not answer, a wake sends a
+// Wake-on-LAN magic packet, waits for the daemon, then starts the container.
+type WakeOnLANConfig struct {
+	// MAC is the hardware address of the Docker host's network card, e.g.
+	// "00:11:22:33:44:55". Setting it enables Wake-on-LAN. (default: "")
+	MAC string `yaml:"mac"`
+	// Broadcast is the host:port the magic packet is sent to over UDP,
+	// usually the broadcast address of the host's subnet.
+	// (default: "255.255.255.255:9")
+	Broadcast string `yaml:"broadcast"`
+	// BootTimeout is how long to wait for the daemon to answer once the
+	// packet is sent; it is added to the container's start_timeout.
+	// (default: 2m)
+	BootTimeout time.Duration `yaml:"boot_timeout"`
+}
+
+// MDNSConfig advertises the configured *.local hosts over multicast DNS, so
+// LAN clients resolve them to the gateway without any DNS server or hosts
+// file change. The gateway must see the LAN's multicast traffic, which in
+// practice means running it with host networking.
+type MDNSConfig struct {
+	// Enabled turns the mDNS responder on. (default: false)
+	Enabled bool `yaml:"enabled"`
+	// Interface is the network interface to answer on.
+	// (default: "", all multicast interfaces chosen by the system)
+	Interface string `yaml:"interface"`
+	// Address is the IPv4 address advertised for every host.
+	// (default: the first IPv4 address of Interface, or of the first
+	// non-loopback interface that is up)
+	Address string `yaml:"address"`
+	// TTL is the lifetime of the advertised records. (default: 120s)
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// DNSServerConfig runs an embedded DNS server that answers A/AAAA queries for
+// every configured and discovered host with the gateway's addresses. Pointing
+// the LAN's DNS setting at the gateway then makes every app name resolve;
+// other names are forwarded to Upstream.
+type DNSServerConfig struct {
+	// Enabled turns the DNS server on. (default: false)
+	Enabled bool `yaml:"enabled"`
+	// Listen is the UDP and TCP address to serve on. (default: ":53")
+	Listen string `yaml:"listen"`
+	// Addresses are the IPv4/IPv6 addresses returned for gateway hosts.
+	// (default: the first IPv4 address of the first non-loopback interface)
+	Addresses []string `yaml:"addresses"`
+	// Upstream is the "host:port" resolver other queries are forwarded to.
+	// Port 53 is assumed when omitted. (default: "", answer REFUSED)
+	Upstream string `yaml:"upstream"`
+	// TTL is the lifetime of the returned records. (default: 60s)
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// HAConfig lets several gateway replicas run behind one load balancer by
+// sharing request activity, container start states, start locks and rate
+// limits through Redis. Every replica must use the same Redis and prefix.
+type HAConfig struct {
+	// Redis is the "redis://[:password@]host[:port][/db]" URL of the shared
+	// store; use rediss:// for TLS. Overridable via HA_REDIS_URL env var.
+	// (default: "", HA disabled)
+	Redis string `yaml:"redis"`
+	// Password overrides the password of the URL. Overridable via
+	// HA_REDIS_PASSWORD env var. (default: "")
+	Password string `yaml:"password"`
+	// KeyPrefix namespaces the keys of this gateway cluster. (default: "dag")
+	KeyPrefix string `yaml:"key_prefix"`
+	// InstanceID identifies this replica in the shared state.
+	// (default: the hostname)
+	InstanceID string `yaml:"instance_id"`
+}
+
+// PrewarmConfig tunes predictive pre-warming. The gateway remembers, per
+// container, the hours of the week that saw requests over the last four
+// weeks; a container with prewarm enabled is started Lead before an hour
+// that was busy in at least MinWeeks of them. Hours are taken in the
+// schedule_timezone of the container.
+type PrewarmConfig struct {
+	// HistoryFile persists the request history across restarts.
+	// (default: "", history kept in memory only)
+	HistoryFile string `yaml:"history_file"`
+	// Lead is how long before a busy hour the container is started.
+	// (default: 5m)
+	Lead time.Duration `yaml:"lead"`
+	// MinWeeks is how many of the last four weeks an hour must have been
+	// busy in to be pre-warmed, from 1 to 4. (default: 2)
+	MinWeeks int `yaml:"min_weeks"`
+}
+
+// AvailabilityConfig controls the lifecycle log from which the availability
+// of each container over the last 24 hours, 7 and 30 days is computed.
+type AvailabilityConfig struct {
+	// HistoryFile persists the lifecycle log across restarts.
+	// (default: "", log kept in memory only)
+	HistoryFile string `yaml:"history_file"`
+}
+
+// RateLimitPolicy is a per-client-IP token bucket: up to Burst requests can
+// be made back to back, and the bucket refills at Rate requests per second.
+type RateLimitPolicy struct {
+	// Rate is the sustained number of requests per second.
+	Rate float64 `yaml:"rate"`
+	// Burst is the number of requests allowed at once.
+	Burst int `yaml:"burst"`
+}
+
+// RateLimitConfig holds the rate limits of the internal endpoints. Unset
+// rates and bursts fall back to the defaults below.
+type RateLimitConfig struct {
+	// Health limits /_health, polled every 2 s by the loading page.
+	// (default: rate 2, burst 10)
+	Health RateLimitPolicy `yaml:"health"`
+	// Logs limits /_logs, polled every 3 s by the loading page.
+	// (default: rate 1, burst 5)
+	Logs RateLimitPolicy `yaml:"logs"`
+	// StatusAPI limits /_status/api, polled every 5 s by the dashboard.
+	// (default: rate 1, burst 10)
+	StatusAPI RateLimitPolicy `yaml:"status_api"`
+	// StatusWake limits POST /_status/wake. (default: rate 0.5, burst 5)
+	StatusWake RateLimitPolicy `yaml:"status_wake"`
+	// StatusSleep limits POST /_status/sleep. (default: rate 0.5, burst 5)
+	StatusSleep RateLimitPolicy `yaml:"status_sleep"`
+	// StatusKill limits POST /_status/kill. (default: rate 0.5, burst 5)
+	StatusKill RateLimitPolicy `yaml:"status_kill"`
+	// StatusReset limits POST /_status/reset. (default: rate 0.5, burst 5)
+	StatusReset RateLimitPolicy `yaml:"status_reset"`
+	// StatusDisk limits GET /_status/disk, which makes Docker walk every
+	// image, volume and build cache record. (default: rate 0.2, burst 5)
+	StatusDisk RateLimitPolicy `yaml:"status_disk"`
+	// StatusPrune limits POST /_status/disk/prune. (default: rate 0.1, burst 2)
+	StatusPrune RateLimitPolicy `yaml:"status_prune"`
+	// StatusState limits GET and POST /_status/state. (default: rate 0.2,
+	// burst 5)
+	StatusState RateLimitPolicy `yaml:"status_state"`
+}
+
+// policies returns the policies keyed by endpoint, the same names used by
+// the gateway_rate_limited_total metric.
+func (c *RateLimitConfig) policies() map[string]*RateLimitPolicy {
+	return map[string]*RateLimitPolicy{
+		"health":       &c.Health,
+		"logs":         &c.Logs,
+		"status_api":   &c.StatusAPI,
+		"status_wake":  &c.StatusWake,
+		"status_sleep": &c.StatusSleep,
+		"status_kill":  &c.StatusKill,
+		"status_reset": &c.StatusReset,
+		"status_disk":  &c.StatusDisk,
+		"status_prune": &c.StatusPrune,
+		"status_state": &c.StatusState,
+	}
+}
+
+// defaultRateLimits are the limits applied when rate_limits is not set.
+var defaultRateLimits = RateLimitConfig{
+	Health:      RateLimitPolicy{Rate: 2, Burst: 10},
+	Logs:        RateLimitPolicy{Rate: 1, Burst: 5},
+	StatusAPI:   RateLimitPolicy{Rate: 1, Burst: 10},
+	StatusWake:  RateLimitPolicy{Rate: 0.5, Burst: 5},
+	StatusSleep: RateLimitPolicy{Rate: 0.5, Burst: 5},
+	StatusKill:  RateLimitPolicy{Rate: 0.5, Burst: 5},
+	StatusReset: RateLimitPolicy{Rate: 0.5, Burst: 5},
+	StatusDisk:  RateLimitPolicy{Rate: 0.2, Burst: 5},
+	StatusPrune: RateLimitPolicy{Rate: 0.1, Burst: 2},
+	StatusState: RateLimitPolicy{Rate: 0.2, Burst: 5},
+}
+
+// setDefaults fills unset rates and bursts from defaultRateLimits.
+func (c *RateLimitConfig) setDefaults() {
+	defaults := defaultRateLimits
+	def := defaults.policies()
+	for name, p := range c.policies() {
+		if p.Rate == 0 {
+			p.Rate = def[name].Rate
+		}
+		if p.Burst == 0 {
+			p.Burst = def[name].Burst
+		}
+	}
+}
+
+// setDefaults fills unset fields from defaultUpstreamConfig.
+func (c *UpstreamConfig) setDefaults() {
+	def := defaultUpstreamConfig
+	if c.DialTimeout == 0 {
+		c.DialTimeout = def.DialTimeout
+	}
+	if c.KeepAlive == 0 {
+		c.KeepAlive = def.KeepAlive
+	}
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = def.MaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost == 0 {
+		c.MaxIdleConnsPerHost = def.MaxIdleConnsPerHost
+	}
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = def.IdleConnTimeout
+	}
+}
+
+// AutoBanConfig temporarily bans client IPs that keep tripping failure
+// signals: admin auth failures, rate-limit hits and requests for unknown
+// hosts. Bans apply to every endpoint and are listed on /_status/bans.
+type AutoBanConfig struct {
+	// Enabled turns automatic banning on. (default: false)
+	Enabled bool `yaml:"enabled"`
+	// Threshold is the number of strikes within Window that triggers a ban.
+	// (default: 10)
+	Threshold int `yaml:"threshold"`
+	// Window is the sliding window strikes are counted in. (default: 1m)
+	Window time.Duration `yaml:"window"`
+	// Duration is how long a ban lasts. (default: 15m)
+	Duration time.Duration `yaml:"duration"`
+	// Exempt lists CIDR blocks that are never banned, e.g. the LAN or the
+	// monitoring host. (default: [])
+	Exempt []string `yaml:"exempt"`
+}
+
+// ACMEConfig obtains and renews TLS certificates from Let's Encrypt (or any
+// ACME CA) for the hosts of containers and groups, and serves HTTPS on
+// HTTPSPort. Challenges are answered with HTTP-01 on gateway.port, which
+// must be reachable on port 80 from the internet. Not hot-reloaded; hosts
+// added later get a certificate on their first HTTPS request.
+type ACMEConfig struct {
+	// Enabled turns on HTTPS with automatic certificates. (default: false)
+	Enabled bool `yaml:"enabled"`
+	// Email is given to the CA for expiry and account notices.
+	// (default: "", none)
+	Email string `yaml:"email"`
+	// CacheDir stores the account key and the certificates, so a restart
+	// does not request them again. Required.
+	CacheDir string `yaml:"cache_dir"`
+	// HTTPSPort is the port HTTPS is served on. (default: "8443")
+	HTTPSPort string `yaml:"https_port"`
+	// DirectoryURL is the ACME directory of the CA, e.g. the Let's Encrypt
+	// staging one for tests. (default: Let's Encrypt production)
+	DirectoryURL string `yaml:"directory_url"`
+	// Hosts lists extra names to get certificates for, such as the one the
+	// dashboard is reached on. (default: [])
+	Hosts []string `yaml:"hosts"`
+	// RedirectHTTP answers plain-HTTP requests for hosts with a certificate
+	// with a redirect to HTTPS. (default: false)
+	RedirectHTTP bool `yaml:"redirect_http"`
+}
+
+// HTTPServerConfig tunes the gateway's HTTP server: slowloris protection and
+// resource limits. Bound at startup; not hot-reloaded.
+type HTTPServerConfig struct {
+	// ReadHeaderTimeout is how long a client may take to send the request
+	// headers. Keep it short to shed slowloris attacks. (default: 10s)
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	// ReadTimeout is how long a client may take to send the whole request,
+	// body included. (default: 30s)
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+	// WriteTimeout bounds the time from the end of the request headers to the
+	// end of the response, so it must exceed the slowest backend response.
+	// (default: 30s)
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// IdleTimeout is how long a keep-alive connection may sit idle between
+	// requests. (default: 120s)
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// MaxHeaderBytes caps the size of the request line and headers.
+	// (default: 1048576 — 1 MiB)
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+	// MaxConnections caps the client connections open at once; further
+	// clients wait until a connection closes. WebSocket tunnels count until
+	// they end. (default: 0 — unlimited)
+	MaxConnections int `yaml:"max_connections"`
+}
+
+// UpstreamConfig tunes the connection pool used to reach the backends. The
+// defaults match Go's http.DefaultTransport; raise MaxIdleConnsPerHost for
+// high-concurrency backends, and keep ResponseHeaderTimeout at 0 for
+// long-polling apps.
+type UpstreamConfig struct {
+	// DialTimeout bounds connecting to a backend. (default: 30s)
+	DialTimeout time.Duration `yaml:"dial_timeout"`
+	// KeepAlive is the interval of TCP keep-alive probes on backend
+	// connections. (default: 30s)
+	KeepAlive time.Duration `yaml:"keep_alive"`
+	// DisableKeepAlives closes the backend connection after every request
+	// instead of reusing it. (default: false)
+	DisableKeepAlives bool `yaml:"disable_keep_alives"`
+	// MaxIdleConns caps the idle connections kept across all backends.
+	// (default: 100)
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// MaxIdleConnsPerHost caps the idle connections kept per backend. Under
+	// high concurrency a low value makes the gateway open and close
+	// connections constantly. (default: 2)
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host"`
+	// MaxConnsPerHost caps the connections, active or idle, per backend.
+	// (default: 0 — unlimited)
+	MaxConnsPerHost int `yaml:"max_conns_per_host"`
+	// IdleConnTimeout is how long an idle backend connection is kept.
+	// (default: 90s)
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout"`
+	// ResponseHeaderTimeout bounds the wait for a backend's response headers
+	// once the request is sent. (default: 0 — no limit)
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"`
+	// DisableCompression stops the gateway from asking backends for gzip on
+	// behalf of clients that did not. (default: false)
+	DisableCompression bool `yaml:"disable_compression"`
+}
+
 // GlobalConfig holds gateway-wide settings
 type GlobalConfig struct {
 	// Port the gateway listens on (default: "8080")
 	Port string `yaml:"port"`
+	// DockerHost is the Docker daemon address, e.g.
+	// "unix:///var/run/docker.sock", "npipe:////./pipe/docker_engine" on
+	// Windows or "tcp://10.0.0.5:2376". Not hot-reloaded.
+	// (default: "", DOCKER_HOST or the platform's default socket)
+	DockerHost string `yaml:"docker_host"`
+	// DetectCapabilities probes at startup which Docker API operations are
+	// allowed, for setups behind a permission-limiting socket proxy.
+	// Forbidden operations (stop, kill, exec, network, images) are disabled
+	// and reported in /_status/api instead of failing at runtime.
+	// Not hot-reloaded. (default: false)
+	DetectCapabilities bool `yaml:"detect_capabilities"`
+	// ReadOnly makes the gateway an observer: it routes and proxies to
+	// running containers and serves the dashboard, but never starts, stops,
+	// restarts or kills a container. Requests to a stopped container get 503.
+	// (default: false)
+	ReadOnly bool `yaml:"read_only"`
+	// DryRun logs automatic lifecycle decisions (wake triggers, idle stops,
+	// schedules, prewarm, restarts) and publishes them as dry_run events
+	// instead of executing them, to validate idle_timeout and activity rules
+	// against real traffic. Requests to a stopped container get 503; manual
+	// actions (dashboard, MQTT) still run. (default: false)
+	DryRun bool `yaml:"dry_run"`
+	// Server tunes timeouts and limits of the HTTP server. Not hot-reloaded.
+	// See HTTPServerConfig for the defaults.
+	Server HTTPServerConfig `yaml:"server"`
+	// ACME serves HTTPS with certificates issued and renewed automatically.
+	// Not hot-reloaded. See ACMEConfig for details. (default: disabled)
+	ACME ACMEConfig `yaml:"acme"`
+	// Upstream tunes the connection pool used to reach the backends.
+	// See UpstreamConfig for the defaults.
+	Upstream UpstreamConfig `yaml:"upstream"`
 	// LogLines is the number of container log lines shown in the loading page (default: 30)
 	LogLines int `yaml:"log_lines"`
 	// TrustedProxies is a list of CIDR blocks (e.g. "10.0.0.0/8") whose
-	// X-Forwarded-For header is trusted for rate-limiting purposes.
-	// If empty, the gateway always uses RemoteAddr. (default: [])
+	// forwarding headers are trusted: X-Forwarded-For for the client IP,
+	// X-Forwarded-Proto and -Host for origin checks and the backends. Other
+	// peers have their X-Forwarded-*, Forwarded and X-Real-IP headers
+	// stripped. If empty, the gateway always uses RemoteAddr. (default: [])
 	TrustedProxies []string `yaml:"trusted_proxies"`
+	// RateLimits sets the per-IP token buckets of /_health, /_logs,
+	// /_status/api, /_status/wake and /_status/sleep. See RateLimitConfig
+	// for the defaults.
+	RateLimits RateLimitConfig `yaml:"rate_limits"`
+	// MaxConcurrentPerIP caps the requests a single client IP may have in
+	// flight across all endpoints; further requests get 429. The client IP
+	// honours trusted_proxies. (default: 0 — unlimited)
+	MaxConcurrentPerIP int `yaml:"max_concurrent_per_ip"`
+	// AutoBan temporarily bans abusive client IPs.
+	// See AutoBanConfig for details. (default: disabled)
+	AutoBan AutoBanConfig `yaml:"auto_ban"`
 	// DiscoveryInterval controls how often Docker labels are polled for
 	// auto-discovery. Overridable via DISCOVERY_INTERVAL env var. (default: 15s)
 	DiscoveryInterval time.Duration `yaml:"discovery_interval"`
+	// HostPattern routes every host matching the pattern to the container
+	// named by its {container} label, e.g. "{container}.apps.example.com"
+	// sends "jellyfin.apps.example.com" to the container "jellyfin". Containers
+	// then need no host of their own; explicit hosts take precedence.
+	// (default: "", disabled)
+	HostPattern string `yaml:"host_pattern"`
+	// AllowContainerQuery enables the ?container=NAME query parameter and
+	// X-Dag-Container header that select a container when the Host header
+	// matches none. Handy for testing without DNS, but anyone who can reach
+	// the gateway can then probe and wake every configured container.
+	// (default: false)
+	AllowContainerQuery bool `yaml:"allow_container_query"`
 	// AdminAuth configures optional authentication for admin endpoints.
 	// See AdminAuthConfig for details. (default: method "none")
 	AdminAuth AdminAuthConfig `yaml:"admin_auth"`
+	// AdminAPI enables the container CRUD API under /_api/v1, protected by
+	// admin_auth. See AdminAPIConfig. (default: disabled)
+	AdminAPI AdminAPIConfig `yaml:"admin_api"`
+	// AffinitySecret signs the affinity cookies of groups. Set the same value
+	// on every replica so they accept each other's cookies. Overridable via
+	// AFFINITY_SECRET env var. (default: "", a random key per process)
+	AffinitySecret string `yaml:"affinity_secret"`
+	// Tenants scopes admin access per team: a tenant's credentials only see
+	// and act on its own containers and groups. Requires admin_auth.
+	// See TenantConfig. (default: [])
+	Tenants []TenantConfig `yaml:"tenants"`
+	// Middlewares defines named middlewares that containers and groups list
+	// in their own middlewares field. See MiddlewareConfig. (default: {})
+	Middlewares map[string]MiddlewareConfig `yaml:"middlewares"`
+	// Robots keeps search engines from indexing the pages the gateway serves
+	// and from waking containers to fetch /robots.txt. See RobotsConfig.
+	// (default: enabled)
+	Robots RobotsConfig `yaml:"robots"`
+	// ProxyIcons makes the gateway fetch http(s) icon_url images itself and
+	// serve them to the dashboard, cached for a day, for icons on hosts the
+	// browser cannot reach or plain-HTTP icons on an HTTPS dashboard.
+	// (default: false)
+	ProxyIcons bool `yaml:"proxy_icons"`
+	// IconsDir is the only directory file:// icon_url images may be read
+	// from. Icons of discovered containers can never be files.
+	// (default: "/icons")
+	IconsDir string `yaml:"icons_dir"`
 	// ScheduleTimezone is the IANA timezone name used to interpret schedule_start
 	// and schedule_stop cron expressions (e.g. "Europe/Rome", "America/New_York").
 	// Default: "" uses the process's local timezone (time.Local).
 	// Overridable via SCHEDULE_TIMEZONE env var.
 	ScheduleTimezone string `yaml:"schedule_timezone"`
+	// Notifications lists the channels (Slack, ntfy, Gotify) that receive
+	// container lifecycle events. (default: [])
+	Notifications []NotificationConfig `yaml:"notifications"`
+	// MQTT configures state publishing and wake/sleep commands over MQTT with
+	// Home Assistant discovery. See MQTTConfig for details. (default: disabled)
+	MQTT MQTTConfig `yaml:"mqtt"`
+	// LogFile mirrors the application log (not the access log) to a rotating
+	// file in addition to stdout. Not hot-reloaded.
+	// See LogFileConfig for details. (default: stdout only)
+	LogFile LogFileConfig `yaml:"log_file"`
+	// LogLevel is the minimum level of the application log: debug, info,
+	// warn or error. Can be changed at runtime through PUT
+	// /_admin/loglevel. Overridable via LOG_LEVEL env var. (default: "info")
+	LogLevel string `yaml:"log_level"`
+	// AccessLog configures one structured record per proxied request.
+	// See AccessLogConfig for details. (default: disabled)
+	AccessLog AccessLogConfig `yaml:"access_log"`
+	// Tracing configures OpenTelemetry span export over OTLP/HTTP.
+	// See TracingConfig for details. (default: disabled)
+	Tracing TracingConfig `yaml:"tracing"`
+	// Syslog forwards the application and access logs to a syslog server.
+	// See SyslogConfig for details. (default: disabled)
+	Syslog SyslogConfig `yaml:"syslog"`
+	// Loki pushes the application and access logs to Grafana Loki.
+	// See LokiConfig for details. (default: disabled)
+	Loki LokiConfig `yaml:"loki"`
+	// Debug enables diagnostic endpoints such as pprof.
+	// See DebugConfig for details. (default: all disabled)
+	Debug DebugConfig `yaml:"debug"`
+	// NetworkAttach connects the gateway to backend networks on demand.
+	// See NetworkAttachConfig for details. (default: disabled)
+	NetworkAttach NetworkAttachConfig `yaml:"network_attach"`
+	// WakeOnLAN powers on the suspended machine of docker_host before a
+	// wake. See WakeOnLANConfig for details. (default: disabled)
+	WakeOnLAN WakeOnLANConfig `yaml:"wake_on_lan"`
+	// MDNS advertises the configured *.local hosts on the LAN.
+	// See MDNSConfig for details. (default: disabled)
+	MDNS MDNSConfig `yaml:"mdns"`
+	// DNS serves the gateway hosts from an embedded DNS server.
+	// See DNSServerConfig for details. (default: disabled)
+	DNS DNSServerConfig `yaml:"dns"`
+	// HA shares state between gateway replicas through Redis.
+	// See HAConfig for details. (default: disabled)
+	HA HAConfig `yaml:"ha"`
+	// Prewarm tunes the pre-starting of containers with prewarm enabled.
+	// See PrewarmConfig for details.
+	Prewarm PrewarmConfig `yaml:"prewarm"`
+	// Availability controls the lifecycle log behind the availability
+	// figures of the dashboard. See AvailabilityConfig for details.
+	Availability AvailabilityConfig `yaml:"availability"`
 }
 
+// Readiness modes accepted by ContainerConfig.Readiness.
+const (
+	ReadinessProbe        = "probe"
+	ReadinessDockerHealth = "docker_health"
+	ReadinessBoth         = "both"
+)
+
+// Target modes accepted by ContainerConfig.Target.
+const (
+	TargetNetwork   = "network"
+	TargetDNS       = "dns"
+	TargetPublished = "published"
+)
+
+// Wake modes accepted by ContainerConfig.WakeMode ("page", "hold") and
+// TCPRouteConfig.WakeMode ("hold", "close").
+const (
+	WakeModePage  = "page"
+	WakeModeHold  = "hold"
+	WakeModeClose = "close"
+)
+
 // ContainerConfig holds per-container settings
 type ContainerConfig struct {
 	// Name is the Docker container name to manage
 	Name string `yaml:"name"`
 	// Host is the incoming Host header to match (e.g. "myapp.localhost")
 	Host string `yaml:"host"`
+	// PathPrefix routes only the requests for Host under this path (e.g.
+	// "/jellyfin") to the container, so several containers can share one
+	// host. A container on the same host without a prefix gets the rest.
+	// The prefix is stripped before proxying unless KeepPrefix is set.
+	// (default: "")
+	PathPrefix string `yaml:"path_prefix"`
+	// KeepPrefix forwards requests with PathPrefix still in the path, for
+	// apps configured with a matching base URL. (default: false)
+	KeepPrefix bool `yaml:"keep_prefix"`
 	// TargetPort is the port on the container to proxy to (default: "80")
 	TargetPort string `yaml:"target_port"`
 	// StartTimeout is the maximum time to wait for the container to start.
@@ -90,25 +928,126 @@ type ContainerConfig struct {
 	// IdleTimeout is how long the container may be idle (no incoming requests)
 	// before it is automatically stopped. 0 means never auto-stop. (default: 0)
 	IdleTimeout time.Duration `yaml:"idle_timeout"`
-	// Network is an optional Docker network name. When set, GetContainerAddress
-	// will look up the container IP on this specific network. If empty, the
-	// first available network is used. (default: "")
+	// MinUptime keeps a container the gateway woke up running for at least
+	// this long, even if it goes idle sooner, so sporadic requests (crawlers,
+	// monitors) do not make it flap between started and stopped. (default: 0)
+	MinUptime time.Duration `yaml:"min_uptime"`
+	// IdleDrainTimeout is how long an idle stop is postponed while requests
+	// are still in flight or WebSocket tunnels are open to the container.
+	// Once it expires the container is stopped anyway. (default: 10m)
+	IdleDrainTimeout time.Duration `yaml:"idle_drain_timeout"`
+	// Networks is an ordered preference list of Docker networks: the
+	// container IP is taken from the first one it is attached to. If empty,
+	// the first attached network by name is used. (default: [])
+	Networks []string `yaml:"networks"`
+	// Network is the single-network form of Networks, kept for existing
+	// configs. When set and not listed in Networks, it is tried first.
+	// (default: "")
 	Network string `yaml:"network"`
+	// Target selects how the gateway reaches the container: "network" dials
+	// the container IP on a shared Docker network, "dns" dials the container
+	// name through the embedded DNS of a shared user-defined network (no
+	// inspect per request, survives IP changes on recreation), "published"
+	// dials the port that target_port is published on, on the Docker daemon's
+	// host. Use "published" when the gateway shares no network with the
+	// container (remote daemon, host networking). (default: "network")
+	Target string `yaml:"target"`
 	// RedirectPath is the URL path the browser is sent to once the container is
-	// running. Useful when the web UI is not at "/". (default: "/")
+	// running. Useful when the web UI is not at "/". (default: "/", or
+	// PathPrefix + "/")
 	RedirectPath string `yaml:"redirect_path"`
 	// Icon is an optional Simple Icons slug (e.g. "nginx", "redis", "postgresql").
 	// Displayed on the /_status dashboard card. See https://simpleicons.org
 	// for available slugs. (default: "docker")
 	Icon string `yaml:"icon"`
+	// IconURL is an image shown on the dashboard instead of Icon, for apps
+	// without a Simple Icons slug: an http(s) URL, or a file:// URL of an
+	// image mounted into the gateway, which then serves it. (default: "")
+	IconURL string `yaml:"icon_url"`
 	// HealthPath is an optional HTTP endpoint (e.g. "/health") called instead
 	// of a raw TCP dial to confirm container readiness. When empty the gateway
 	// falls back to a TCP probe. (default: "")
 	HealthPath string `yaml:"health_path"`
+	// Readiness selects the signal used to decide the container is ready after
+	// a start: "probe" runs the TCP/HTTP probe, "docker_health" waits for the
+	// image's HEALTHCHECK to report "healthy", and "both" requires the health
+	// status first and then the probe. Containers without a HEALTHCHECK fall
+	// back to the probe. (default: "probe")
+	Readiness string `yaml:"readiness"`
+	// WakeMode selects what a request that wakes the container gets: "page"
+	// serves the loading page, "hold" keeps the request (body included)
+	// until the container is ready, up to StartTimeout, and then proxies it,
+	// for API clients and webhooks that cannot follow a loading page.
+	// (default: "page")
+	WakeMode string `yaml:"wake_mode"`
+	// ProbeInterval is the pause between readiness probe attempts. (default: 500ms)
+	ProbeInterval time.Duration `yaml:"probe_interval"`
+	// ProbeTimeout bounds a single TCP dial or HTTP request of the readiness
+	// probe. (default: 2s)
+	ProbeTimeout time.Duration `yaml:"probe_timeout"`
+	// ProbeInitialDelay is how long to wait after "docker start" before the
+	// first readiness probe is attempted. (default: 0)
+	ProbeInitialDelay time.Duration `yaml:"probe_initial_delay"`
+	// ProbeStatusCodes lists the HTTP status codes the health_path probe treats
+	// as ready (e.g. [200, 401] for an auth-protected health page).
+	// (default: [] — any 2xx)
+	ProbeStatusCodes []int `yaml:"probe_status_codes"`
+	// UnhealthyThreshold is the number of consecutive failed proxy attempts
+	// (connect errors, 502 or 504 responses) after which a running container is
+	// marked degraded and skipped by group routing. 0 disables passive health
+	// checking. (default: 0)
+	UnhealthyThreshold int `yaml:"unhealthy_threshold"`
+	// UnhealthyRestart restarts the container when passive health checking
+	// marks it degraded. Requires UnhealthyThreshold > 0. (default: false)
+	UnhealthyRestart bool `yaml:"unhealthy_restart"`
+	// DisableAccessLog suppresses access-log records for this container, e.g.
+	// for a chatty health-checked service. (default: false)
+	DisableAccessLog bool `yaml:"disable_access_log"`
+	// CircuitBreakerThreshold is the number of consecutive backend failures
+	// (connect errors, 502 or 504 responses) that open the container's circuit
+	// breaker. While open, requests get the error page immediately instead of
+	// being proxied. 0 disables the breaker. (default: 0)
+	CircuitBreakerThreshold int `yaml:"circuit_breaker_threshold"`
+	// CircuitBreakerCooldown is how long the circuit stays open before a single
+	// trial request is let through. (default: 30s)
+	CircuitBreakerCooldown time.Duration `yaml:"circuit_breaker_cooldown"`
+	// WakeRetries is how many times an idempotent, body-less request is
+	// retried when the connection to the container is refused or reset
+	// within WakeRetryWindow of a wake: a passing probe does not always mean
+	// the app is accepting requests yet. 0 disables retries. (default: 0)
+	WakeRetries int `yaml:"wake_retries"`
+	// WakeRetryWindow is how long after a wake failed requests are retried.
+	// (default: 10s)
+	WakeRetryWindow time.Duration `yaml:"wake_retry_window"`
+	// SelfHealInterval enables a background health loop for this container while
+	// it is running: every interval the readiness check is repeated. 0 disables
+	// self-healing. (default: 0)
+	SelfHealInterval time.Duration `yaml:"self_heal_interval"`
+	// SelfHealFailures is the number of consecutive failed background checks
+	// (while Docker still reports "running") that trigger a restart. (default: 3)
+	SelfHealFailures int `yaml:"self_heal_failures"`
+	// SelfHealMaxRestarts bounds how many times the container is restarted
+	// before it passes a check again. (default: 3)
+	SelfHealMaxRestarts int `yaml:"self_heal_max_restarts"`
+	// PushURL is an optional Healthchecks.io or Uptime Kuma push URL. The
+	// gateway pings it after a successful wake and every PushInterval: "up"
+	// while the container is running or asleep on purpose, "down" when it
+	// failed to start, is crash-looping or is degraded. (default: "")
+	PushURL string `yaml:"push_url"`
+	// PushInterval is how often PushURL is pinged. (default: 60s)
+	PushInterval time.Duration `yaml:"push_interval"`
 	// DependsOn lists container names that must be running before this one starts.
 	// Dependencies are started in topological order and must pass their readiness
 	// probe before the next one begins. (default: [])
 	DependsOn []string `yaml:"depends_on"`
+	// Middlewares lists gateway.middlewares applied, in order, to every
+	// request routed to the container, before it is woken or proxied.
+	// (default: [])
+	Middlewares []string `yaml:"middlewares"`
+	// Tenant is the gateway.tenants entry the container belongs to. Only
+	// admin_auth and that tenant's credentials see it on the dashboard.
+	// (default: "", admin_auth only)
+	Tenant string `yaml:"tenant"`
 	// ScheduleStart is an optional standard 5-field cron expression (e.g. "0 8 * * 1-5")
 	// that triggers a proactive container start. When combined with ScheduleStop,
 	// requests outside the active window are blocked with a 503 page.
@@ -120,16 +1059,187 @@ type ContainerConfig struct {
 	// schedule_start / schedule_stop expressions. When set, overrides the global
 	// gateway.schedule_timezone. (default: "" uses gateway.schedule_timezone)
 	ScheduleTimezone string `yaml:"schedule_timezone"`
+	// MaxConcurrentRequests caps the requests proxied to the container at
+	// once; excess requests wait in Queue. WebSocket tunnels are not counted.
+	// (default: 0 — unlimited)
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+	// Queue bounds the requests waiting for a MaxConcurrentRequests slot.
+	// See QueueConfig for details.
+	Queue QueueConfig `yaml:"queue"`
+	// Warmup sends a few requests right after the readiness probe passes.
+	// See WarmupConfig for details.
+	Warmup WarmupConfig `yaml:"warmup"`
+	// Hooks run commands or call URLs around a start. See HooksConfig.
+	Hooks HooksConfig `yaml:"hooks"`
+	// BandwidthLimit caps the rate at which the container's responses (and
+	// WebSocket traffic towards clients) are sent, shared by all its clients,
+	// e.g. "10MB/s", "512KiB/s" or "100Mbit/s". (default: "", unlimited)
+	BandwidthLimit ByteRate `yaml:"bandwidth_limit"`
+	// FlushInterval is how often response data buffered by the proxy is
+	// flushed to the client; a negative value flushes after every write.
+	// Server-Sent Events (text/event-stream) and responses without a
+	// Content-Length are always flushed at once. (default: 0 — no periodic
+	// flush)
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// WebSocket bounds the WebSocket tunnels to the container. See
+	// WebSocketConfig for details.
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	// Prewarm starts the container shortly before the hours it is usually
+	// busy, learnt from its request history. See PrewarmConfig for the
+	// gateway-wide settings. (default: false)
+	Prewarm bool `yaml:"prewarm"`
+	// UpdateCheckInterval enables image update detection: every interval the
+	// digest the registry serves for the container's image tag is compared
+	// with the local one. Images pinned by digest or built locally are
+	// skipped. 0 disables the check. (default: 0)
+	UpdateCheckInterval time.Duration `yaml:"update_check_interval"`
+
+	// Discovered is set for containers found through dag.* labels rather than
+	// the static config file. Not configurable.
+	Discovered bool `yaml:"-"`
 }
 
-// LoadConfig reads and parses the YAML config file.
-// The path is taken from the CONFIG_PATH env var (default: /etc/gateway/config.yaml).
-func LoadConfig() (*GatewayConfig, error) {
-	path := os.Getenv("CONFIG_PATH")
-	if path == "" {
-		path = "/etc/gateway/config.yaml"
+// NetworkPreference returns the networks to resolve the container IP from,
+// in order: Network (unless already listed) followed by Networks.
+func (c *ContainerConfig) NetworkPreference() []string {
+	if c.Network == "" || slices.Contains(c.Networks, c.Network) {
+		return c.Networks
+	}
+	return append([]string{c.Network}, c.Networks...)
+}
+
+// QueueConfig bounds the requests waiting for a free concurrency slot. Requests
+// that find the queue full, or wait longer than Timeout, get a 503.
+type QueueConfig struct {
+	// Size is the maximum number of waiting requests. (default: 100)
+	Size int `yaml:"size"`
+	// Timeout is how long a request waits for a slot. (default: 10s)
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// WarmupConfig primes a freshly started container: Count GET requests to
+// Path are sent once it is ready, before waiting clients are let through.
+type WarmupConfig struct {
+	// Path is the path requested. (default: "/")
+	Path string `yaml:"path"`
+	// Count is the number of requests sent. (default: 0 — disabled)
+	Count int `yaml:"count"`
+}
+
+// WebSocketConfig limits the WebSocket tunnels of a container, so forgotten
+// browser tabs cannot keep it awake or pile up connections.
+type WebSocketConfig struct {
+	// MaxConnections caps the tunnels open at once; further upgrades get
+	// 503. (default: 0 — unlimited)
+	MaxConnections int `yaml:"max_connections"`
+	// IdleTimeout closes a tunnel after no bytes moved in either direction
+	// for this long. (default: 0 — never)
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+// HooksConfig lists the actions run when the gateway starts a container.
+type HooksConfig struct {
+	// PreStart runs in order before docker start; a failing hook aborts the
+	// start with its error. (default: [])
+	PreStart []HookConfig `yaml:"pre_start"`
+	// PostReady runs in order once the readiness probe passed; a failing hook
+	// is reported in the start state, but the container is served. (default: [])
+	PostReady []HookConfig `yaml:"post_ready"`
+	// PreStop runs in order before an idle stop; a failing hook with Veto
+	// set keeps the container running until the next idle check. (default: [])
+	PreStop []HookConfig `yaml:"pre_stop"`
+	// PostStop runs in order after an idle stop; failures are logged.
+	// (default: [])
+	PostStop []HookConfig `yaml:"post_stop"`
+}
+
+// HookConfig is one hook: either Command executed in Container, or a request
+// to URL carrying {"container": ..., "hook": <stage>}, the stage being
+// "pre_start", "post_ready", "pre_stop" or "post_stop".
+type HookConfig struct {
+	// Container is the running container Command is executed in.
+	Container string `yaml:"container"`
+	// Command is the command and its arguments; a non-zero exit code fails
+	// the hook.
+	Command []string `yaml:"command"`
+	// URL is called instead of running a command; a non-2xx status fails the
+	// hook.
+	URL string `yaml:"url"`
+	// Method is the HTTP method used for URL. (default: "POST")
+	Method string `yaml:"method"`
+	// Timeout bounds the hook. (default: 30s)
+	Timeout time.Duration `yaml:"timeout"`
+	// Veto makes a failure of this pre_stop hook cancel the idle stop.
+	// Only valid in pre_stop. (default: false)
+	Veto bool `yaml:"veto"`
+}
+
+// setDefaults fills the method and timeout of every hook.
+func (c *HooksConfig) setDefaults() {
+	for _, hooks := range [][]HookConfig{c.PreStart, c.PostReady, c.PreStop, c.PostStop} {
+		for i := range hooks {
+			if hooks[i].URL != "" && hooks[i].Method == "" {
+				hooks[i].Method = http.MethodPost
+			}
+			if hooks[i].Timeout == 0 {
+				hooks[i].Timeout = 30 * time.Second
+			}
+		}
+	}
+}
+
+// validate checks that the hook is either a command or a URL.
+func (h HookConfig) validate() error {
+	switch {
+	case h.URL != "" && (h.Container != "" || len(h.Command) > 0):
+		return fmt.Errorf("set either url or container/command, not both")
+	case h.URL != "":
+		if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http(s) URL")
+		}
+		switch strings.ToUpper(h.Method) {
+		case "", http.MethodGet, http.MethodPost, http.MethodPut:
+		default:
+			return fmt.Errorf("method must be GET, POST or PUT")
+		}
+	case h.Container == "" || len(h.Command) == 0:
+		return fmt.Errorf("container and command are required without url")
 	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	return nil
+}
+
+// DefaultConfigPath is where config.yaml is read from when CONFIG_PATH is
+// unset: /etc/gateway/config.yaml, or %ProgramData%\docker-gateway\config.yaml
+// on Windows.
+func DefaultConfigPath() string {
+	return defaultConfigPath(runtime.GOOS, os.Getenv("ProgramData"))
+}
+
+func defaultConfigPath(goos, programData string) string {
+	if goos != "windows" {
+		return "/etc/gateway/config.yaml"
+	}
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return strings.TrimRight(programData, `\`) + `\docker-gateway\config.yaml`
+}
 
+// configPath returns CONFIG_PATH, or DefaultConfigPath when it is unset.
+func configPath() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	return DefaultConfigPath()
+}
+
+// LoadConfig reads and parses the YAML config file.
+// The path is taken from the CONFIG_PATH env var (default: DefaultConfigPath).
+func LoadConfig() (*GatewayConfig, error) {
+	path := configPath()
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read config file %q: %w", path, err)
@@ -165,10 +1275,42 @@ func LoadConfig() (*GatewayConfig, error) {
 		cfg.Gateway.AdminAuth.Token = envToken
 	}
 
+	if envLevel := os.Getenv("LOG_LEVEL"); envLevel != "" {
+		cfg.Gateway.LogLevel = envLevel
+	}
+
 	if envTZ := os.Getenv("SCHEDULE_TIMEZONE"); envTZ != "" {
 		cfg.Gateway.ScheduleTimezone = envTZ
 	}
 
+	// Allow MQTT_* env vars to keep broker credentials out of the YAML file.
+	if envUser := os.Getenv("MQTT_USERNAME"); envUser != "" {
+		cfg.Gateway.MQTT.Username = envUser
+	}
+	if envPass := os.Getenv("MQTT_PASSWORD"); envPass != "" {
+		cfg.Gateway.MQTT.Password = envPass
+	}
+
+	if envSecret := os.Getenv("AFFINITY_SECRET"); envSecret != "" {
+		cfg.Gateway.AffinitySecret = envSecret
+	}
+
+	// HA_REDIS_* env vars keep the shared-state credentials out of the YAML file.
+	if envURL := os.Getenv("HA_REDIS_URL"); envURL != "" {
+		cfg.Gateway.HA.Redis = envURL
+	}
+	if envPass := os.Getenv("HA_REDIS_PASSWORD"); envPass != "" {
+		cfg.Gateway.HA.Password = envPass
+	}
+
+	// Standard OpenTelemetry env vars take precedence over the YAML file.
+	if envEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); envEndpoint != "" {
+		cfg.Gateway.Tracing.Endpoint = envEndpoint
+	}
+	if envService := os.Getenv("OTEL_SERVICE_NAME"); envService != "" {
+		cfg.Gateway.Tracing.ServiceName = envService
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
@@ -196,34 +1338,219 @@ func (c *GatewayConfig) Validate() error {
 		return fmt.Errorf("gateway.port cannot be empty")
 	}
 
+	if srv := c.Gateway.Server; srv.ReadHeaderTimeout < 0 || srv.ReadTimeout < 0 || srv.WriteTimeout < 0 ||
+		srv.IdleTimeout < 0 || srv.MaxHeaderBytes < 0 || srv.MaxConnections < 0 {
+		return fmt.Errorf("server: timeouts and limits cannot be negative")
+	}
+
+	if up := c.Gateway.Upstream; up.DialTimeout < 0 || up.KeepAlive < 0 || up.MaxIdleConns < 0 ||
+		up.MaxIdleConnsPerHost < 0 || up.MaxConnsPerHost < 0 || up.IdleConnTimeout < 0 || up.ResponseHeaderTimeout < 0 {
+		return fmt.Errorf("upstream: timeouts and limits cannot be negative")
+	}
+
 	if _, err := resolveLocation(c.Gateway.ScheduleTimezone); err != nil {
 		return fmt.Errorf("schedule_timezone: invalid IANA timezone %q: %w", c.Gateway.ScheduleTimezone, err)
 	}
 
-	// Validate admin_auth settings.
-	switch c.Gateway.AdminAuth.Method {
-	case "", "none":
-		// ok — no authentication
-	case "basic":
-		if c.Gateway.AdminAuth.Username == "" || c.Gateway.AdminAuth.Password == "" {
-			return fmt.Errorf("admin_auth: method=basic requires non-empty username and password")
+	if err := c.Gateway.AdminAuth.validate(); err != nil {
+		return fmt.Errorf("admin_auth: %w", err)
+	}
+
+	for name, m := range c.Gateway.Middlewares {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("middlewares.%s: %w", name, err)
 		}
-	case "bearer":
-		if c.Gateway.AdminAuth.Token == "" {
-			return fmt.Errorf("admin_auth: method=bearer requires non-empty token")
+	}
+
+	tenantSet, err := c.Gateway.validateTenants()
+	if err != nil {
+		return err
+	}
+
+	for i, n := range c.Gateway.Notifications {
+		if err := n.validate(); err != nil {
+			return fmt.Errorf("notifications #%d: %w", i+1, err)
+		}
+	}
+
+	for name, p := range c.Gateway.RateLimits.policies() {
+		if p.Rate < 0 || p.Burst < 0 {
+			return fmt.Errorf("rate_limits.%s: rate and burst cannot be negative", name)
+		}
+	}
+
+	if c.Gateway.MaxConcurrentPerIP < 0 {
+		return fmt.Errorf("max_concurrent_per_ip cannot be negative")
+	}
+
+	if b := c.Gateway.AutoBan; b.Enabled {
+		if b.Threshold < 1 || b.Window <= 0 || b.Duration <= 0 {
+			return fmt.Errorf("auto_ban: threshold, window and duration must be positive")
+		}
+		for _, cidr := range b.Exempt {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("auto_ban: invalid exempt CIDR %q", cidr)
+			}
+		}
+	}
+
+	if c.Gateway.MQTT.Broker != "" {
+		if _, _, err := parseMQTTBroker(c.Gateway.MQTT.Broker); err != nil {
+			return fmt.Errorf("mqtt: %w", err)
+		}
+		if strings.ContainsAny(c.Gateway.MQTT.TopicPrefix, "+#") ||
+			strings.ContainsAny(c.Gateway.MQTT.DiscoveryPrefix, "+#") {
+			return fmt.Errorf("mqtt: topic prefixes cannot contain wildcards '+' or '#'")
+		}
+	}
+
+	if w := c.Gateway.WakeOnLAN; w.MAC != "" {
+		if _, err := parseWakeOnLANMAC(w.MAC); err != nil {
+			return fmt.Errorf("wake_on_lan: %w", err)
+		}
+		if _, _, err := net.SplitHostPort(w.Broadcast); err != nil {
+			return fmt.Errorf("wake_on_lan: broadcast %q must be host:port: %w", w.Broadcast, err)
+		}
+		if w.BootTimeout < 0 {
+			return fmt.Errorf("wake_on_lan: boot_timeout cannot be negative")
+		}
+	}
+
+	if a := c.Gateway.ACME; a.Enabled {
+		if a.CacheDir == "" {
+			return fmt.Errorf("acme: cache_dir is required")
+		}
+		if a.HTTPSPort == c.Gateway.Port {
+			return fmt.Errorf("acme: https_port %s is already used by port", a.HTTPSPort)
+		}
+		if a.DirectoryURL != "" {
+			if u, err := url.Parse(a.DirectoryURL); err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("acme: directory_url %q must be an https:// URL", a.DirectoryURL)
+			}
+		}
+		for _, h := range a.Hosts {
+			if h == "" || strings.ContainsAny(h, ":/*") {
+				return fmt.Errorf("acme: host %q must be a plain host name", h)
+			}
+		}
+	}
+
+	if r := c.Gateway.HA.Redis; r != "" {
+		if _, _, _, _, err := parseRedisURL(r); err != nil {
+			return fmt.Errorf("ha: %w", err)
+		}
+		if strings.ContainsAny(c.Gateway.HA.KeyPrefix, " \t\r\n") {
+			return fmt.Errorf("ha: key_prefix cannot contain whitespace")
+		}
+	}
+
+	if c.Gateway.Prewarm.Lead < 0 {
+		return fmt.Errorf("prewarm: lead cannot be negative")
+	}
+	if w := c.Gateway.Prewarm.MinWeeks; w < 0 || w > usageWeeks {
+		return fmt.Errorf("prewarm: min_weeks must be between 1 and %d, got %d", usageWeeks, w)
+	}
+
+	if p := c.Gateway.HostPattern; p != "" {
+		prefix, suffix, ok := strings.Cut(p, hostPatternPlaceholder)
+		if !ok || strings.Contains(suffix, hostPatternPlaceholder) ||
+			(prefix != "" && !strings.HasSuffix(prefix, ".")) || (suffix != "" && !strings.HasPrefix(suffix, ".")) {
+			return fmt.Errorf("host_pattern %q must contain %s exactly once, as a whole label", p, hostPatternPlaceholder)
+		}
+	}
+
+	if a := c.Gateway.MDNS.Address; a != "" {
+		if ip := net.ParseIP(a); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("mdns: address %q is not an IPv4 address", a)
+		}
+	}
+	if c.Gateway.MDNS.TTL < 0 {
+		return fmt.Errorf("mdns: ttl must be positive")
+	}
+	if d := c.Gateway.DNS; d.Enabled {
+		if _, _, err := net.SplitHostPort(d.Listen); err != nil {
+			return fmt.Errorf("dns: invalid listen address %q", d.Listen)
+		}
+		for _, a := range d.Addresses {
+			if net.ParseIP(a) == nil {
+				return fmt.Errorf("dns: address %q is not an IP address", a)
+			}
+		}
+		if d.Upstream != "" {
+			if _, _, err := net.SplitHostPort(d.Upstream); err != nil {
+				return fmt.Errorf("dns: invalid upstream %q", d.Upstream)
+			}
+		}
+		if d.TTL < 0 {
+			return fmt.Errorf("dns: ttl must be positive")
+		}
+	}
+
+	for _, f := range c.Gateway.AccessLog.Fields {
+		if !knownAccessLogFields[f] {
+			return fmt.Errorf("access_log: unknown field %q", f)
+		}
+	}
+
+	if err := c.Gateway.AccessLog.File.validate(); err != nil {
+		return fmt.Errorf("access_log.file: %w", err)
+	}
+	if err := c.Gateway.LogFile.validate(); err != nil {
+		return fmt.Errorf("log_file: %w", err)
+	}
+	if l := c.Gateway.LogLevel; l != "" {
+		if _, err := parseLogLevel(l); err != nil {
+			return fmt.Errorf("log_level: %w", err)
+		}
+	}
+	if h := c.Gateway.DockerHost; h != "" {
+		if err := validateDockerHost(h, runtime.GOOS); err != nil {
+			return fmt.Errorf("docker_host: %w", err)
+		}
+	}
+	if c.Gateway.AccessLog.Sample < 0 {
+		return fmt.Errorf("access_log: sample cannot be negative")
+	}
+
+	if t := c.Gateway.Tracing; t.Endpoint != "" {
+		if u, err := url.Parse(t.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracing: endpoint %q must be an http(s) URL", t.Endpoint)
+		}
+		if t.SampleRatio < 0 || t.SampleRatio > 1 {
+			return fmt.Errorf("tracing: sample_ratio must be between 0 and 1, got %g", t.SampleRatio)
+		}
+	}
+
+	if c.Gateway.Syslog.Address != "" {
+		if _, _, err := parseSyslogAddress(c.Gateway.Syslog.Address); err != nil {
+			return fmt.Errorf("syslog: %w", err)
+		}
+		if _, ok := syslogFacilities[c.Gateway.Syslog.Facility]; !ok {
+			return fmt.Errorf("syslog: unknown facility %q", c.Gateway.Syslog.Facility)
+		}
+	}
+	if l := c.Gateway.Loki; l.URL != "" {
+		if u, err := url.Parse(l.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("loki: url %q must be an http(s) URL", l.URL)
+		}
+		for k := range l.Labels {
+			if !validLokiLabel(k) {
+				return fmt.Errorf("loki: invalid label name %q", k)
+			}
 		}
-	default:
-		return fmt.Errorf("admin_auth: unknown method %q (allowed: none, basic, bearer)",
-			c.Gateway.AdminAuth.Method)
 	}
 
 	seenNames := make(map[string]bool)
 	seenHosts := make(map[string]bool)
+	// prefixHosts are the hosts shared by path_prefix containers.
+	prefixHosts := make(map[string]bool)
 
 	// Build a set of all container names for reference checking.
 	nameSet := make(map[string]bool, len(c.Containers))
+	tenantOf := make(map[string]string, len(c.Containers))
 	for _, ctr := range c.Containers {
 		nameSet[ctr.Name] = true
+		tenantOf[ctr.Name] = ctr.Tenant
 	}
 
 	// Build a set of containers that are group members (they don't need host).
@@ -241,14 +1568,19 @@ func (c *GatewayConfig) Validate() error {
 			depTargets[dep] = true
 		}
 	}
+	tcpTargets := make(map[string]bool)
+	for _, rt := range c.TCPRoutes {
+		tcpTargets[rt.Container] = true
+	}
 
 	for i, ctr := range c.Containers {
 		if ctr.Name == "" {
 			return fmt.Errorf("container #%d is missing required field 'name'", i+1)
 		}
 
-		// Host is required only if the container is NOT solely a group member or dependency.
-		needsHost := !groupMembers[ctr.Name] && !depTargets[ctr.Name]
+		// Host is required only if the container is NOT solely a group member,
+		// dependency or TCP route target.
+		needsHost := !groupMembers[ctr.Name] && !depTargets[ctr.Name] && !tcpTargets[ctr.Name] && c.Gateway.HostPattern == ""
 		if ctr.Host == "" && needsHost {
 			return fmt.Errorf("container %q is missing required field 'host'", ctr.Name)
 		}
@@ -261,11 +1593,20 @@ func (c *GatewayConfig) Validate() error {
 		}
 		seenNames[ctr.Name] = true
 
+		if ctr.PathPrefix != "" {
+			if ctr.Host == "" {
+				return fmt.Errorf("container %q: path_prefix requires host", ctr.Name)
+			}
+			if err := validatePathPrefix(ctr.PathPrefix); err != nil {
+				return fmt.Errorf("container %q: %w", ctr.Name, err)
+			}
+			prefixHosts[ctr.Host] = true
+		}
 		if ctr.Host != "" {
-			if seenHosts[ctr.Host] {
-				return fmt.Errorf("duplicate host mapped: %q (in container %q)", ctr.Host, ctr.Name)
+			if seenHosts[ctr.Host+ctr.PathPrefix] {
+				return fmt.Errorf("duplicate host mapped: %q (in container %q)", ctr.Host+ctr.PathPrefix, ctr.Name)
 			}
-			seenHosts[ctr.Host] = true
+			seenHosts[ctr.Host+ctr.PathPrefix] = true
 		}
 
 		// Validate depends_on references exist.
@@ -277,6 +1618,138 @@ func (c *GatewayConfig) Validate() error {
 				return fmt.Errorf("container %q cannot depend on itself", ctr.Name)
 			}
 		}
+		for _, mw := range ctr.Middlewares {
+			if _, ok := c.Gateway.Middlewares[mw]; !ok {
+				return fmt.Errorf("container %q uses unknown middleware %q", ctr.Name, mw)
+			}
+		}
+
+		if ctr.Tenant != "" && !tenantSet[ctr.Tenant] {
+			return fmt.Errorf("container %q: tenant %q is not defined in gateway.tenants", ctr.Name, ctr.Tenant)
+		}
+
+		if ctr.IconURL != "" {
+			u, err := parseIconURL(ctr.IconURL)
+			if err != nil {
+				return fmt.Errorf("container %q: icon_url: %w", ctr.Name, err)
+			}
+			if u.Scheme == "file" && !inIconsDir(c.Gateway.IconsDir, u.Path) {
+				return fmt.Errorf("container %q: icon_url: %q is outside gateway.icons_dir %q", ctr.Name, ctr.IconURL, c.Gateway.IconsDir)
+			}
+		}
+
+		if ctr.ProbeInterval < 0 || ctr.ProbeTimeout < 0 || ctr.ProbeInitialDelay < 0 {
+			return fmt.Errorf("container %q: probe durations cannot be negative", ctr.Name)
+		}
+		for _, code := range ctr.ProbeStatusCodes {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("container %q: invalid probe status code %d", ctr.Name, code)
+			}
+		}
+
+		if ctr.UnhealthyThreshold < 0 {
+			return fmt.Errorf("container %q: unhealthy_threshold cannot be negative", ctr.Name)
+		}
+		if ctr.UnhealthyRestart && ctr.UnhealthyThreshold == 0 {
+			return fmt.Errorf("container %q: unhealthy_restart requires unhealthy_threshold > 0", ctr.Name)
+		}
+
+		if ctr.CircuitBreakerThreshold < 0 || ctr.CircuitBreakerCooldown < 0 {
+			return fmt.Errorf("container %q: circuit breaker settings cannot be negative", ctr.Name)
+		}
+
+		if ctr.WakeRetries < 0 || ctr.WakeRetryWindow < 0 {
+			return fmt.Errorf("container %q: wake_retries and wake_retry_window cannot be negative", ctr.Name)
+		}
+
+		if ctr.SelfHealInterval < 0 || ctr.SelfHealFailures < 0 || ctr.SelfHealMaxRestarts < 0 {
+			return fmt.Errorf("container %q: self-heal settings cannot be negative", ctr.Name)
+		}
+
+		if ctr.UpdateCheckInterval < 0 {
+			return fmt.Errorf("container %q: update_check_interval cannot be negative", ctr.Name)
+		}
+
+		if ctr.MaxConcurrentRequests < 0 || ctr.Queue.Size < 0 || ctr.Queue.Timeout < 0 {
+			return fmt.Errorf("container %q: max_concurrent_requests and queue settings cannot be negative", ctr.Name)
+		}
+
+		for i, h := range ctr.Hooks.PreStart {
+			if err := h.validate(); err != nil {
+				return fmt.Errorf("container %q: hooks.pre_start[%d]: %w", ctr.Name, i, err)
+			}
+		}
+		for i, h := range ctr.Hooks.PostReady {
+			if err := h.validate(); err != nil {
+				return fmt.Errorf("container %q: hooks.post_ready[%d]: %w", ctr.Name, i, err)
+			}
+		}
+		for i, h := range ctr.Hooks.PreStop {
+			if err := h.validate(); err != nil {
+				return fmt.Errorf("container %q: hooks.pre_stop[%d]: %w", ctr.Name, i, err)
+			}
+		}
+		for i, h := range ctr.Hooks.PostStop {
+			if err := h.validate(); err != nil {
+				return fmt.Errorf("container %q: hooks.post_stop[%d]: %w", ctr.Name, i, err)
+			}
+		}
+		for _, h := range slices.Concat(ctr.Hooks.PreStart, ctr.Hooks.PostReady, ctr.Hooks.PostStop) {
+			if h.Veto {
+				return fmt.Errorf("container %q: hooks: veto is only valid in pre_stop", ctr.Name)
+			}
+		}
+
+		if ctr.MinUptime < 0 {
+			return fmt.Errorf("container %q: min_uptime cannot be negative", ctr.Name)
+		}
+		if ctr.IdleDrainTimeout < 0 {
+			return fmt.Errorf("container %q: idle_drain_timeout cannot be negative", ctr.Name)
+		}
+
+		if ctr.WebSocket.MaxConnections < 0 {
+			return fmt.Errorf("container %q: websocket.max_connections cannot be negative", ctr.Name)
+		}
+		if ctr.WebSocket.IdleTimeout < 0 {
+			return fmt.Errorf("container %q: websocket.idle_timeout cannot be negative", ctr.Name)
+		}
+
+		if ctr.Warmup.Count < 0 {
+			return fmt.Errorf("container %q: warmup.count cannot be negative", ctr.Name)
+		}
+		if ctr.Warmup.Path != "" && !strings.HasPrefix(ctr.Warmup.Path, "/") {
+			return fmt.Errorf("container %q: warmup.path must start with '/'", ctr.Name)
+		}
+
+		if ctr.PushURL != "" {
+			if u, err := url.Parse(ctr.PushURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("container %q: push_url must be an http(s) URL", ctr.Name)
+			}
+		}
+		if ctr.PushInterval < 0 {
+			return fmt.Errorf("container %q: push_interval cannot be negative", ctr.Name)
+		}
+
+		switch ctr.Readiness {
+		case "", ReadinessProbe, ReadinessDockerHealth, ReadinessBoth:
+		default:
+			return fmt.Errorf("container %q: unknown readiness %q (allowed: probe, docker_health, both)",
+				ctr.Name, ctr.Readiness)
+		}
+
+		switch ctr.WakeMode {
+		case "", WakeModePage, WakeModeHold:
+		default:
+			return fmt.Errorf("container %q: unknown wake_mode %q (allowed: page, hold)",
+				ctr.Name, ctr.WakeMode)
+		}
+
+		switch ctr.Target {
+		case "", TargetNetwork, TargetDNS, TargetPublished:
+		default:
+			return fmt.Errorf("container %q: unknown target %q (allowed: network, dns, published)",
+				ctr.Name, ctr.Target)
+		}
 
 		// Validate per-container schedule_timezone if set.
 		if ctr.ScheduleTimezone != "" {
@@ -298,6 +1771,10 @@ func (c *GatewayConfig) Validate() error {
 		}
 	}
 
+	if err := c.validateTCPRoutes(); err != nil {
+		return err
+	}
+
 	// Validate groups.
 	seenGroupNames := make(map[string]bool)
 	for i, g := range c.Groups {
@@ -313,10 +1790,47 @@ func (c *GatewayConfig) Validate() error {
 		if seenGroupNames[g.Name] {
 			return fmt.Errorf("duplicate group name found: %q", g.Name)
 		}
+		switch g.Strategy {
+		case "", strategyRoundRobin, strategyWeighted, strategyLeastConnections:
+		default:
+			return fmt.Errorf("group %q: unknown strategy %q (allowed: %s, %s, %s)", g.Name, g.Strategy, strategyRoundRobin, strategyWeighted, strategyLeastConnections)
+		}
+		switch g.Affinity {
+		case "", affinityNone, affinityCookie:
+		default:
+			return fmt.Errorf("group %q: unknown affinity %q (allowed: %s, %s)", g.Name, g.Affinity, affinityNone, affinityCookie)
+		}
+		switch g.StartOrder {
+		case "", startOrderSequential, startOrderParallel:
+		default:
+			return fmt.Errorf("group %q: start_order must be %q or %q, got %q", g.Name, startOrderSequential, startOrderParallel, g.StartOrder)
+		}
+		if g.StartStagger < 0 {
+			return fmt.Errorf("group %q: start_stagger cannot be negative", g.Name)
+		}
+		if g.HealthInterval < 0 {
+			return fmt.Errorf("group %q: health_interval cannot be negative", g.Name)
+		}
+		if g.Hedge.Delay < 0 {
+			return fmt.Errorf("group %q: hedge.delay cannot be negative", g.Name)
+		}
+		for _, p := range g.Hedge.Paths {
+			if !strings.HasPrefix(p, "/") {
+				return fmt.Errorf("group %q: hedge.paths entry %q must start with '/'", g.Name, p)
+			}
+		}
+		for j, m := range g.Members {
+			if m.Name == "" {
+				return fmt.Errorf("group %q: member #%d is missing required field 'name'", g.Name, j+1)
+			}
+			if m.Weight < 0 {
+				return fmt.Errorf("group %q: member %q: weight cannot be negative", g.Name, m.Name)
+			}
+		}
 		seenGroupNames[g.Name] = true
 
 		// Group host must not conflict with container hosts or other group hosts.
-		if seenHosts[g.Host] {
+		if seenHosts[g.Host] || prefixHosts[g.Host] {
 			return fmt.Errorf("group %q host %q conflicts with an existing host", g.Name, g.Host)
 		}
 		seenHosts[g.Host] = true
@@ -326,6 +1840,19 @@ func (c *GatewayConfig) Validate() error {
 				return fmt.Errorf("group %q references unknown container %q", g.Name, cn)
 			}
 		}
+		for _, mw := range g.Middlewares {
+			if _, ok := c.Gateway.Middlewares[mw]; !ok {
+				return fmt.Errorf("group %q uses unknown middleware %q", g.Name, mw)
+			}
+		}
+		if g.Tenant != "" && !tenantSet[g.Tenant] {
+			return fmt.Errorf("group %q: tenant %q is not defined in gateway.tenants", g.Name, g.Tenant)
+		}
+		for _, cn := range g.Containers {
+			if t := tenantOf[cn]; t != g.Tenant {
+				return fmt.Errorf("group %q: member %q belongs to tenant %q, not %q", g.Name, cn, t, g.Tenant)
+			}
+		}
 	}
 
 	// Detect dependency cycles via DFS.
@@ -336,6 +1863,135 @@ func (c *GatewayConfig) Validate() error {
 	return nil
 }
 
+// validateTenants checks gateway.tenants and returns the set of their names.
+// Tenants need admin_auth, otherwise every visitor is a full admin, and no
+// credential may open more than one scope.
+func (g *GlobalConfig) validateTenants() (map[string]bool, error) {
+	names := make(map[string]bool, len(g.Tenants))
+	if len(g.Tenants) == 0 {
+		return names, nil
+	}
+	if g.AdminAuth.Method == "" || g.AdminAuth.Method == "none" {
+		return nil, fmt.Errorf("tenants: requires admin_auth, otherwise the dashboard is open to everyone")
+	}
+	seen := map[AdminAuthConfig]bool{g.AdminAuth.credential(): true}
+	for i, t := range g.Tenants {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tenants #%d: missing required field 'name'", i+1)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("tenants: duplicate name %q", t.Name)
+		}
+		names[t.Name] = true
+		if len(t.Credentials) == 0 {
+			return nil, fmt.Errorf("tenants.%s: at least one credential is required", t.Name)
+		}
+		for j, cred := range t.Credentials {
+			if cred.Method != "basic" && cred.Method != "bearer" {
+				return nil, fmt.Errorf("tenants.%s: credential #%d: method must be basic or bearer", t.Name, j+1)
+			}
+			if err := cred.validate(); err != nil {
+				return nil, fmt.Errorf("tenants.%s: credential #%d: %w", t.Name, j+1, err)
+			}
+			if seen[cred.credential()] {
+				return nil, fmt.Errorf("tenants.%s: credential #%d is already used by admin_auth or another tenant", t.Name, j+1)
+			}
+			seen[cred.credential()] = true
+		}
+	}
+	return names, nil
+}
+
+// credential keeps only the fields a request is matched on, so the same
+// user or token configured twice compares equal.
+func (a AdminAuthConfig) credential() AdminAuthConfig {
+	switch a.Method {
+	case "basic":
+		return AdminAuthConfig{Method: a.Method, Username: a.Username}
+	case "bearer":
+		return AdminAuthConfig{Method: a.Method, Token: a.Token}
+	}
+	return AdminAuthConfig{Method: a.Method}
+}
+
+// validate checks the credentials required by the method.
+func (a *AdminAuthConfig) validate() error {
+	switch a.Method {
+	case "", "none":
+		// ok — no authentication
+	case "basic":
+		if a.Username == "" || a.Password == "" {
+			return fmt.Errorf("method=basic requires non-empty username and password")
+		}
+	case "bearer":
+		if a.Token == "" {
+			return fmt.Errorf("method=bearer requires non-empty token")
+		}
+	default:
+		return fmt.Errorf("unknown method %q (allowed: none, basic, bearer)", a.Method)
+	}
+	return nil
+}
+
+// validate checks a single middleware definition.
+func (m *MiddlewareConfig) validate() error {
+	switch m.Type {
+	case MiddlewareAuth:
+		if m.Auth.Method != "basic" && m.Auth.Method != "bearer" {
+			return fmt.Errorf("type=auth requires auth.method basic or bearer")
+		}
+		if err := m.Auth.validate(); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	case MiddlewareRateLimit:
+		if m.RateLimit.Rate <= 0 || m.RateLimit.Burst <= 0 {
+			return fmt.Errorf("type=rate_limit requires a positive rate_limit.rate and rate_limit.burst")
+		}
+	case MiddlewareHeaders:
+		if len(m.RequestHeaders) == 0 && len(m.ResponseHeaders) == 0 {
+			return fmt.Errorf("type=headers requires request_headers or response_headers")
+		}
+	case MiddlewarePlugin:
+		if lookupMiddleware(m.Plugin) == nil {
+			return fmt.Errorf("plugin %q is not registered", m.Plugin)
+		}
+	case MiddlewareScript:
+		if m.Script.Timeout < 0 {
+			return fmt.Errorf("script.timeout must not be negative")
+		}
+		if _, err := compileScript(m.Script); err != nil {
+			return fmt.Errorf("script: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown type %q (allowed: auth, rate_limit, headers, plugin, script)", m.Type)
+	}
+	return nil
+}
+
+// validate checks a single notification channel.
+func (n *NotificationConfig) validate() error {
+	switch n.Type {
+	case "slack", "ntfy", "gotify":
+	default:
+		return fmt.Errorf("unknown type %q (allowed: slack, ntfy, gotify)", n.Type)
+	}
+	if n.URL == "" {
+		return fmt.Errorf("type=%s requires a url", n.Type)
+	}
+	if n.Type == "ntfy" && n.Topic == "" {
+		return fmt.Errorf("type=ntfy requires a topic")
+	}
+	if n.Type == "gotify" && n.Token == "" {
+		return fmt.Errorf("type=gotify requires a token")
+	}
+	for _, e := range n.Events {
+		if !knownEventTypes[EventType(e)] {
+			return fmt.Errorf("unknown event type %q", e)
+		}
+	}
+	return nil
+}
+
 // detectDependencyCycles performs a DFS-based cycle check on the depends_on graph.
 func detectDependencyCycles(containers []ContainerConfig) error {
 	// Build adjacency list.
@@ -394,9 +2050,33 @@ func joinPath(path []string) string {
 
 // applyDefaults fills in sensible defaults for any unset field.
 func applyDefaults(cfg *GatewayConfig) {
+	expandGroupMembers(cfg)
+
 	if cfg.Gateway.Port == "" {
 		cfg.Gateway.Port = "8080"
 	}
+	if cfg.Gateway.Server.ReadHeaderTimeout == 0 {
+		cfg.Gateway.Server.ReadHeaderTimeout = 10 * time.Second
+	}
+	if cfg.Gateway.Server.ReadTimeout == 0 {
+		cfg.Gateway.Server.ReadTimeout = 30 * time.Second
+	}
+	if cfg.Gateway.Server.WriteTimeout == 0 {
+		cfg.Gateway.Server.WriteTimeout = 30 * time.Second
+	}
+	if cfg.Gateway.Server.IdleTimeout == 0 {
+		cfg.Gateway.Server.IdleTimeout = 120 * time.Second
+	}
+	if cfg.Gateway.Server.MaxHeaderBytes == 0 {
+		cfg.Gateway.Server.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+	cfg.Gateway.Upstream.setDefaults()
+	if cfg.Gateway.LogLevel == "" {
+		cfg.Gateway.LogLevel = "info"
+	}
+	if cfg.Gateway.IconsDir == "" {
+		cfg.Gateway.IconsDir = "/icons"
+	}
 	if cfg.Gateway.LogLines == 0 {
 		cfg.Gateway.LogLines = 30
 	}
@@ -406,37 +2086,230 @@ func applyDefaults(cfg *GatewayConfig) {
 	if cfg.Gateway.AdminAuth.Method == "" {
 		cfg.Gateway.AdminAuth.Method = "none"
 	}
+	if cfg.Gateway.Robots.Tag == "" {
+		cfg.Gateway.Robots.Tag = "noindex, nofollow"
+	}
+	if cfg.Gateway.Robots.RobotsTxt == "" {
+		cfg.Gateway.Robots.RobotsTxt = "User-agent: *\nDisallow: /\n"
+	}
+	if cfg.Gateway.ACME.HTTPSPort == "" {
+		cfg.Gateway.ACME.HTTPSPort = "8443"
+	}
+	if cfg.Gateway.WakeOnLAN.Broadcast == "" {
+		cfg.Gateway.WakeOnLAN.Broadcast = "255.255.255.255:9"
+	}
+	if cfg.Gateway.WakeOnLAN.BootTimeout == 0 {
+		cfg.Gateway.WakeOnLAN.BootTimeout = 2 * time.Minute
+	}
+	cfg.Gateway.RateLimits.setDefaults()
+	if cfg.Gateway.AutoBan.Threshold == 0 {
+		cfg.Gateway.AutoBan.Threshold = 10
+	}
+	if cfg.Gateway.AutoBan.Window == 0 {
+		cfg.Gateway.AutoBan.Window = time.Minute
+	}
+	if cfg.Gateway.AutoBan.Duration == 0 {
+		cfg.Gateway.AutoBan.Duration = 15 * time.Minute
+	}
+	if cfg.Gateway.MQTT.ClientID == "" {
+		cfg.Gateway.MQTT.ClientID = "docker-gateway"
+	}
+	if cfg.Gateway.MQTT.TopicPrefix == "" {
+		cfg.Gateway.MQTT.TopicPrefix = "docker-gateway"
+	}
+	if cfg.Gateway.MQTT.DiscoveryPrefix == "" {
+		cfg.Gateway.MQTT.DiscoveryPrefix = "homeassistant"
+	}
+	if cfg.Gateway.MDNS.TTL == 0 {
+		cfg.Gateway.MDNS.TTL = 120 * time.Second
+	}
+	if cfg.Gateway.DNS.Listen == "" {
+		cfg.Gateway.DNS.Listen = ":53"
+	}
+	if u := cfg.Gateway.DNS.Upstream; u != "" {
+		if _, _, err := net.SplitHostPort(u); err != nil {
+			cfg.Gateway.DNS.Upstream = net.JoinHostPort(strings.Trim(u, "[]"), "53")
+		}
+	}
+	if cfg.Gateway.HA.KeyPrefix == "" {
+		cfg.Gateway.HA.KeyPrefix = "dag"
+	}
+	if cfg.Gateway.Prewarm.Lead == 0 {
+		cfg.Gateway.Prewarm.Lead = 5 * time.Minute
+	}
+	if cfg.Gateway.Prewarm.MinWeeks == 0 {
+		cfg.Gateway.Prewarm.MinWeeks = 2
+	}
+	if cfg.Gateway.DNS.TTL == 0 {
+		cfg.Gateway.DNS.TTL = 60 * time.Second
+	}
+	for _, lf := range []*LogFileConfig{&cfg.Gateway.LogFile, &cfg.Gateway.AccessLog.File} {
+		if lf.Path != "" && lf.MaxSizeMB == 0 {
+			lf.MaxSizeMB = 100
+		}
+	}
+	if cfg.Gateway.Tracing.ServiceName == "" {
+		cfg.Gateway.Tracing.ServiceName = "docker-gateway"
+	}
+	if cfg.Gateway.Tracing.SampleRatio == 0 {
+		cfg.Gateway.Tracing.SampleRatio = 1
+	}
+	if cfg.Gateway.Syslog.AppName == "" {
+		cfg.Gateway.Syslog.AppName = "docker-gateway"
+	}
+	if cfg.Gateway.Syslog.Facility == "" {
+		cfg.Gateway.Syslog.Facility = "local0"
+	}
+	if cfg.Gateway.Loki.URL != "" && len(cfg.Gateway.Loki.Labels) == 0 {
+		cfg.Gateway.Loki.Labels = map[string]string{"job": "docker-gateway"}
+	}
 
 	for i := range cfg.Containers {
-		c := &cfg.Containers[i]
-		if c.TargetPort == "" {
-			c.TargetPort = "80"
+		cfg.Containers[i].setDefaults()
+	}
+
+	for i := range cfg.Groups {
+		g := &cfg.Groups[i]
+		if g.Strategy == "" {
+			g.Strategy = strategyRoundRobin
 		}
-		if c.StartTimeout == 0 {
-			c.StartTimeout = 60 * time.Second
+		if g.Affinity == "" {
+			g.Affinity = affinityNone
 		}
-		// IdleTimeout 0 means "never auto-stop" — no default override needed
-		if c.RedirectPath == "" {
-			c.RedirectPath = "/"
+		if g.StartOrder == "" {
+			g.StartOrder = startOrderSequential
 		}
-		if c.Icon == "" {
-			c.Icon = "docker"
+		for j := range g.Members {
+			if g.Members[j].Weight == 0 {
+				g.Members[j].Weight = 1
+			}
 		}
 	}
 
-	for i := range cfg.Groups {
-		g := &cfg.Groups[i]
-		if g.Strategy == "" {
-			g.Strategy = "round-robin"
+	for i := range cfg.TCPRoutes {
+		if cfg.TCPRoutes[i].WakeMode == "" {
+			cfg.TCPRoutes[i].WakeMode = WakeModeHold
+		}
+	}
+}
+
+// setDefaults fills the unset settings of a container.
+func (c *ContainerConfig) setDefaults() {
+	if c.TargetPort == "" {
+		c.TargetPort = "80"
+	}
+	if c.StartTimeout == 0 {
+		c.StartTimeout = 60 * time.Second
+	}
+	// IdleTimeout 0 means "never auto-stop" — no default override needed
+	if c.RedirectPath == "" {
+		c.RedirectPath = c.PathPrefix + "/"
+	}
+	if c.Icon == "" {
+		c.Icon = "docker"
+	}
+	if c.ProbeInterval == 0 {
+		c.ProbeInterval = 500 * time.Millisecond
+	}
+	if c.ProbeTimeout == 0 {
+		c.ProbeTimeout = 2 * time.Second
+	}
+	if c.CircuitBreakerCooldown == 0 {
+		c.CircuitBreakerCooldown = 30 * time.Second
+	}
+	if c.WakeRetryWindow == 0 {
+		c.WakeRetryWindow = 10 * time.Second
+	}
+	if c.IdleDrainTimeout == 0 {
+		c.IdleDrainTimeout = 10 * time.Minute
+	}
+	if c.SelfHealFailures == 0 {
+		c.SelfHealFailures = 3
+	}
+	if c.SelfHealMaxRestarts == 0 {
+		c.SelfHealMaxRestarts = 3
+	}
+	if c.PushInterval == 0 {
+		c.PushInterval = 60 * time.Second
+	}
+	if c.Readiness == "" {
+		c.Readiness = ReadinessProbe
+	}
+	if c.WakeMode == "" {
+		c.WakeMode = WakeModePage
+	}
+	if c.Target == "" {
+		c.Target = TargetNetwork
+	}
+	if c.Queue.Size == 0 {
+		c.Queue.Size = 100
+	}
+	if c.Queue.Timeout == 0 {
+		c.Queue.Timeout = 10 * time.Second
+	}
+	if c.Warmup.Path == "" {
+		c.Warmup.Path = "/"
+	}
+	c.Hooks.setDefaults()
+}
+
+// expandGroupMembers fills each group's Containers from its Members, declares
+// the members missing from the containers list as copies of the group's
+// first declared member, and applies the member overrides. Members that
+// cannot be declared are left to Validate to report.
+func expandGroupMembers(cfg *GatewayConfig) {
+	for gi := range cfg.Groups {
+		g := &cfg.Groups[gi]
+		if len(g.Members) == 0 {
+			continue
+		}
+		index := make(map[string]int, len(cfg.Containers))
+		for i := range cfg.Containers {
+			index[cfg.Containers[i].Name] = i
+		}
+		template := -1
+		for _, m := range g.Members {
+			if i, ok := index[m.Name]; ok {
+				template = i
+				break
+			}
+		}
+
+		g.Containers = make([]string, len(g.Members))
+		for j, m := range g.Members {
+			g.Containers[j] = m.Name
+			i, ok := index[m.Name]
+			if !ok {
+				if template < 0 || m.Name == "" {
+					continue
+				}
+				ctr := cfg.Containers[template]
+				ctr.Name = m.Name
+				ctr.Host = ""    // routed through the group
+				ctr.PushURL = "" // heartbeats are per container
+				ctr.Networks = slices.Clone(ctr.Networks)
+				ctr.DependsOn = slices.Clone(ctr.DependsOn)
+				ctr.ProbeStatusCodes = slices.Clone(ctr.ProbeStatusCodes)
+				cfg.Containers = append(cfg.Containers, ctr)
+				i = len(cfg.Containers) - 1
+				index[m.Name] = i
+			}
+			if m.TargetPort != "" {
+				cfg.Containers[i].TargetPort = m.TargetPort
+			}
+			if m.HealthPath != "" {
+				cfg.Containers[i].HealthPath = m.HealthPath
+			}
 		}
 	}
 }
 
 // BuildHostIndex returns a map from Host header value → ContainerConfig for O(1) lookup.
+// Containers with a path_prefix are indexed by BuildPathPrefixIndex instead.
 func BuildHostIndex(cfg *GatewayConfig) map[string]*ContainerConfig {
 	idx := make(map[string]*ContainerConfig, len(cfg.Containers))
 	for i := range cfg.Containers {
-		if cfg.Containers[i].Host != "" {
+		if cfg.Containers[i].Host != "" && cfg.Containers[i].PathPrefix == "" {
 			idx[cfg.Containers[i].Host] = &cfg.Containers[i]
 		}
 	}
@@ -454,6 +2327,37 @@ func BuildGroupHostIndex(cfg *GatewayConfig) map[string]*GroupConfig {
 	return idx
 }
 
+// hostPatternPlaceholder stands for the container name in host_pattern.
+const hostPatternPlaceholder = "{container}"
+
+// matchHostPattern returns the container name host (without port) stands
+// for under pattern, or "" when it does not match. The name is a single
+// label: "a.b.apps.example.com" does not match "{container}.apps.example.com".
+func matchHostPattern(pattern, host string) string {
+	prefix, suffix, ok := strings.Cut(pattern, hostPatternPlaceholder)
+	if !ok || len(host) <= len(prefix)+len(suffix) {
+		return ""
+	}
+	host = strings.ToLower(host)
+	if !strings.HasPrefix(host, strings.ToLower(prefix)) || !strings.HasSuffix(host, strings.ToLower(suffix)) {
+		return ""
+	}
+	name := host[len(prefix) : len(host)-len(suffix)]
+	if strings.Contains(name, ".") {
+		return ""
+	}
+	return name
+}
+
+// ContainerHost returns the host ctr is reached on: its own host or, without
+// one, its host_pattern host. It is "" for containers without either.
+func (c *GatewayConfig) ContainerHost(ctr *ContainerConfig) string {
+	if ctr.Host != "" || c.Gateway.HostPattern == "" {
+		return ctr.Host
+	}
+	return strings.Replace(c.Gateway.HostPattern, hostPatternPlaceholder, ctr.Name, 1)
+}
+
 // BuildContainerMap returns a map from container name → ContainerConfig for quick lookup.
 func BuildContainerMap(cfg *GatewayConfig) map[string]*ContainerConfig {
 	m := make(map[string]*ContainerConfig, len(cfg.Containers))
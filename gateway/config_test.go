@@ -164,6 +164,9 @@ func TestApplyDefaults(t *testing.T) {
 				if c.HealthPath != "" {
 					t.Errorf("HealthPath = %q, want empty", c.HealthPath)
 				}
+				if c.Readiness != ReadinessProbe {
+					t.Errorf("Readiness = %q, want %q", c.Readiness, ReadinessProbe)
+				}
 			},
 		},
 		{
@@ -196,6 +199,20 @@ func TestApplyDefaults(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "dns upstream without port → port 53",
+			input: GatewayConfig{
+				Gateway: GlobalConfig{DNS: DNSServerConfig{Upstream: "1.1.1.1"}},
+			},
+			check: func(t *testing.T, cfg *GatewayConfig) {
+				if cfg.Gateway.DNS.Upstream != "1.1.1.1:53" {
+					t.Errorf("DNS.Upstream = %q, want %q", cfg.Gateway.DNS.Upstream, "1.1.1.1:53")
+				}
+				if cfg.Gateway.DNS.Listen != ":53" || cfg.Gateway.DNS.TTL != time.Minute {
+					t.Errorf("DNS = %+v, want listen :53 and ttl 1m", cfg.Gateway.DNS)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -338,6 +355,378 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "readiness docker_health → valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Readiness = ReadinessDockerHealth
+			},
+			wantErr: false,
+		},
+		{
+			name: "readiness both → valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Readiness = ReadinessBoth
+			},
+			wantErr: false,
+		},
+		{
+			name: "probe status codes valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].ProbeStatusCodes = []int{200, 401}
+			},
+			wantErr: false,
+		},
+		{
+			name: "probe status code out of range → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].ProbeStatusCodes = []int{42}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative probe interval → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].ProbeInterval = -time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max_concurrent_requests → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].MaxConcurrentRequests = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative queue timeout → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].MaxConcurrentRequests = 2
+				cfg.Containers[0].Queue.Timeout = -time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "warmup valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Warmup = WarmupConfig{Path: "/api/ping", Count: 3}
+			},
+			wantErr: false,
+		},
+		{
+			name: "pre_stop veto hook valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Hooks.PreStop = []HookConfig{{Container: "db", Command: []string{"pg_dump", "-f", "/backup.sql"}, Veto: true}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "veto outside pre_stop → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Hooks.PostStop = []HookConfig{{URL: "https://monitor.example.com/off", Veto: true}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid post_stop hook → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Hooks.PostStop = []HookConfig{{Container: "db"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative min_uptime → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].MinUptime = -time.Minute
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative idle_drain_timeout → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].IdleDrainTimeout = -time.Minute
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative warmup count → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Warmup.Count = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "warmup path without leading slash → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Warmup = WarmupConfig{Path: "api", Count: 1}
+			},
+			wantErr: true,
+		},
+		{
+			name: "unhealthy_restart without threshold → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].UnhealthyRestart = true
+			},
+			wantErr: true,
+		},
+		{
+			name: "unhealthy_restart with threshold → valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].UnhealthyThreshold = 5
+				cfg.Containers[0].UnhealthyRestart = true
+			},
+			wantErr: false,
+		},
+		{
+			name: "readiness unknown → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Readiness = "exec"
+			},
+			wantErr: true,
+		},
+		{
+			name: "target published → valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Target = TargetPublished
+			},
+			wantErr: false,
+		},
+		{
+			name: "target dns → valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Target = TargetDNS
+			},
+			wantErr: false,
+		},
+		{
+			name: "target unknown → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].Target = "host"
+			},
+			wantErr: true,
+		},
+		{
+			name: "push_url not http → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].PushURL = "ftp://hc-ping.com/uuid"
+			},
+			wantErr: true,
+		},
+		{
+			name: "push_url valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].PushURL = "https://hc-ping.com/uuid"
+			},
+			wantErr: false,
+		},
+		{
+			name: "mqtt broker valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.MQTT.Broker = "tcp://mosquitto:1883"
+			},
+			wantErr: false,
+		},
+		{
+			name: "mqtt broker unsupported scheme → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.MQTT.Broker = "ws://mosquitto:9001"
+			},
+			wantErr: true,
+		},
+		{
+			name: "mqtt topic prefix with wildcard → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.MQTT.Broker = "mosquitto:1883"
+				cfg.Gateway.MQTT.TopicPrefix = "gw/#"
+			},
+			wantErr: true,
+		},
+		{
+			name: "access_log fields valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.AccessLog = AccessLogConfig{Enabled: true, Fields: []string{"host", "status", "user_agent"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "access_log unknown field → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.AccessLog.Fields = []string{"host", "cookie"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "access_log file with negative max_backups → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.AccessLog.File = LogFileConfig{Path: "/var/log/access.log", MaxBackups: -1}
+			},
+			wantErr: true,
+		},
+		{
+			name: "docker_host unix socket valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.DockerHost = "unix:///var/run/docker.sock"
+			},
+			wantErr: false,
+		},
+		{
+			name: "docker_host unknown scheme → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.DockerHost = "ftp://docker:21"
+			},
+			wantErr: true,
+		},
+		{
+			name: "tracing endpoint valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Tracing.Endpoint = "http://tempo:4318"
+				cfg.Gateway.Tracing.SampleRatio = 0.25
+			},
+			wantErr: false,
+		},
+		{
+			name: "tracing endpoint without scheme → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Tracing.Endpoint = "tempo:4318"
+			},
+			wantErr: true,
+		},
+		{
+			name: "tracing sample_ratio above 1 → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Tracing.Endpoint = "http://tempo:4318"
+				cfg.Gateway.Tracing.SampleRatio = 1.5
+			},
+			wantErr: true,
+		},
+		{
+			name: "syslog and loki valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Syslog = SyslogConfig{Address: "udp://syslog:514", Facility: "local3"}
+				cfg.Gateway.Loki = LokiConfig{URL: "http://loki:3100", Labels: map[string]string{"env": "prod"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "syslog address without port → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Syslog = SyslogConfig{Address: "udp://syslog", Facility: "local0"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "syslog unknown facility → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Syslog = SyslogConfig{Address: "tcp://syslog:601", Facility: "mail"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "loki invalid label name → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Loki = LokiConfig{URL: "http://loki:3100", Labels: map[string]string{"bad-label": "x"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "host_pattern lets containers omit host",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.HostPattern = "{container}.apps.example.com"
+				cfg.Containers[0].Host = ""
+			},
+			wantErr: false,
+		},
+		{
+			name: "host_pattern without placeholder → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.HostPattern = "apps.example.com"
+			},
+			wantErr: true,
+		},
+		{
+			name: "host_pattern placeholder inside a label → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.HostPattern = "app-{container}.example.com"
+			},
+			wantErr: true,
+		},
+		{
+			name: "mdns address valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.MDNS = MDNSConfig{Enabled: true, Address: "192.168.1.10"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "mdns IPv6 address → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.MDNS = MDNSConfig{Enabled: true, Address: "fd00::10"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "dns server valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.DNS = DNSServerConfig{Enabled: true, Listen: ":53", Addresses: []string{"192.168.1.10", "fd00::10"}, Upstream: "1.1.1.1:53"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "dns server invalid address → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.DNS = DNSServerConfig{Enabled: true, Listen: ":53", Addresses: []string{"gateway.lan"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "ha redis valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.HA = HAConfig{Redis: "redis://redis:6379/1", KeyPrefix: "dag"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "ha unsupported scheme → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.HA = HAConfig{Redis: "http://redis:6379", KeyPrefix: "dag"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "ha key_prefix with whitespace → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.HA = HAConfig{Redis: "redis://redis", KeyPrefix: "my gateway"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "prewarm valid",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Prewarm = PrewarmConfig{Lead: 10 * time.Minute, MinWeeks: 3}
+				cfg.Containers[0].Prewarm = true
+			},
+			wantErr: false,
+		},
+		{
+			name: "prewarm min_weeks beyond history → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Prewarm = PrewarmConfig{Lead: 5 * time.Minute, MinWeeks: 5}
+			},
+			wantErr: true,
+		},
+		{
+			name: "prewarm negative lead → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Gateway.Prewarm = PrewarmConfig{Lead: -time.Minute, MinWeeks: 2}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative update_check_interval → error",
+			modify: func(cfg *GatewayConfig) {
+				cfg.Containers[0].UpdateCheckInterval = -time.Hour
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -354,6 +743,34 @@ func TestValidate(t *testing.T) {
 
 // ─── BuildHostIndex ───────────────────────────────────────────────────────────
 
+func TestMatchHostPattern(t *testing.T) {
+	tests := []struct {
+		pattern, host, want string
+	}{
+		{"{container}.apps.example.com", "jellyfin.apps.example.com", "jellyfin"},
+		{"{container}.apps.example.com", "JellyFin.Apps.Example.com", "jellyfin"},
+		{"{container}.apps.example.com", ".apps.example.com", ""},
+		{"{container}.apps.example.com", "a.b.apps.example.com", ""},
+		{"{container}.apps.example.com", "apps.example.com", ""},
+		{"gw.{container}.lan", "gw.wiki.lan", "wiki"},
+		{"", "wiki.lan", ""},
+	}
+	for _, tt := range tests {
+		if got := matchHostPattern(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("matchHostPattern(%q, %q) = %q, want %q", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+
+	cfg := &GatewayConfig{Gateway: GlobalConfig{HostPattern: "{container}.apps.example.com"}}
+	if got := cfg.ContainerHost(&ContainerConfig{Name: "wiki"}); got != "wiki.apps.example.com" {
+		t.Errorf("ContainerHost = %q, want pattern host", got)
+	}
+	if got := cfg.ContainerHost(&ContainerConfig{Name: "wiki", Host: "docs.lan"}); got != "docs.lan" {
+		t.Errorf("ContainerHost = %q, want own host", got)
+	}
+}
+
+
 func TestBuildHostIndex(t *testing.T) {
 	cfg := &GatewayConfig{
 		Containers: []ContainerConfig{
@@ -406,6 +823,43 @@ func TestBuildHostIndex(t *testing.T) {
 
 // ─── LoadConfig (file-based) ──────────────────────────────────────────────────
 
+func TestValidateDockerHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		goos    string
+		wantErr bool
+	}{
+		{"npipe:////./pipe/docker_engine", "windows", false},
+		{"npipe:////./pipe/docker_engine", "linux", true},
+		{"unix:///var/run/docker.sock", "linux", false},
+		{"tcp://10.0.0.5:2376", "linux", false},
+		{"tcp://10.0.0.5:2376", "windows", false},
+		{"10.0.0.5:2376", "linux", true},
+		{"ssh://user@nas", "linux", true},
+	}
+	for _, tt := range tests {
+		if err := validateDockerHost(tt.host, tt.goos); (err != nil) != tt.wantErr {
+			t.Errorf("validateDockerHost(%q, %s) = %v, wantErr %v", tt.host, tt.goos, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	tests := []struct {
+		goos, programData, want string
+	}{
+		{"linux", "", "/etc/gateway/config.yaml"},
+		{"darwin", "", "/etc/gateway/config.yaml"},
+		{"windows", `D:\Data\`, `D:\Data\docker-gateway\config.yaml`},
+		{"windows", "", `C:\ProgramData\docker-gateway\config.yaml`},
+	}
+	for _, tt := range tests {
+		if got := defaultConfigPath(tt.goos, tt.programData); got != tt.want {
+			t.Errorf("defaultConfigPath(%s, %q) = %q, want %q", tt.goos, tt.programData, got, tt.want)
+		}
+	}
+}
+
 func TestLoadConfig_MissingFile(t *testing.T) {
 	t.Setenv("CONFIG_PATH", "/nonexistent/file.yaml")
 	_, err := LoadConfig()
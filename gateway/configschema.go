@@ -0,0 +1,174 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationPattern matches the strings time.ParseDuration accepts, as YAML
+// durations must be written ("30s", "1h30m", "0").
+const durationPattern = `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+)$`
+
+// byteRatePattern matches the bandwidth strings parseByteRate accepts.
+const byteRatePattern = `^\s*[0-9.]+\s*([A-Za-z]+)?(/s)?\s*$`
+
+// schemaEnums lists the values Validate accepts for enumerated fields,
+// keyed by struct type and YAML field name. An empty value keeps its default.
+var schemaEnums = map[string][]string{
+	"AdminAuthConfig.method":    {"none", "basic", "bearer"},
+	"NotificationConfig.type":   {"slack", "ntfy", "gotify"},
+	"ContainerConfig.readiness": {ReadinessProbe, ReadinessDockerHealth, ReadinessBoth},
+	"ContainerConfig.target":    {TargetNetwork, TargetDNS, TargetPublished},
+	"ContainerConfig.wake_mode": {WakeModePage, WakeModeHold},
+	"GroupConfig.affinity":      {affinityNone, affinityCookie},
+	"GroupConfig.start_order":   {startOrderSequential, startOrderParallel},
+	"GroupConfig.strategy":      {strategyRoundRobin, strategyWeighted, strategyLeastConnections},
+	"HookConfig.method":         {http.MethodGet, http.MethodPost, http.MethodPut},
+	"MiddlewareConfig.type":     {MiddlewareAuth, MiddlewareRateLimit, MiddlewareHeaders, MiddlewarePlugin, MiddlewareScript},
+	"SyslogConfig.facility":     syslogFacilityNames(),
+	"TCPRouteConfig.wake_mode":  {WakeModeHold, WakeModeClose},
+}
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	byteRateType    = reflect.TypeOf(ByteRate(0))
+	groupMemberType = reflect.TypeOf(GroupMember{})
+)
+
+var (
+	configSchemaOnce sync.Once
+	configSchemaJSON []byte
+)
+
+// ConfigSchema returns a JSON Schema (draft 2020-12) of config.yaml, built
+// from the YAML tags of GatewayConfig so it cannot drift from the parser.
+// Editors and CI can validate a config file against it.
+func ConfigSchema() []byte {
+	configSchemaOnce.Do(func() {
+		schema := schemaFor(reflect.TypeOf(GatewayConfig{}))
+		schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+		schema["title"] = "docker-gateway configuration"
+		configSchemaJSON, _ = json.MarshalIndent(schema, "", "  ")
+		configSchemaJSON = append(configSchemaJSON, '\n')
+	})
+	return configSchemaJSON
+}
+
+// WriteConfigSchema writes ConfigSchema to w, for `docker-gateway -config-schema`.
+func WriteConfigSchema(w io.Writer) error {
+	_, err := w.Write(ConfigSchema())
+	return err
+}
+
+// schemaFor describes values of type t as YAML decodes them. A key left
+// empty in YAML is null and keeps the default, so every type allows null.
+func schemaFor(t reflect.Type) map[string]any {
+	switch t {
+	case durationType:
+		return map[string]any{"type": []string{"string", "null"}, "pattern": durationPattern,
+			"description": `Go duration, e.g. "30s", "5m" or "1h30m"`}
+	case byteRateType:
+		return map[string]any{"type": []string{"string", "null"}, "pattern": byteRatePattern,
+			"description": `bandwidth, e.g. "10MB/s", "512KiB/s" or "100Mbit/s"`}
+	case groupMemberType:
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "string", "description": "container name"},
+			objectSchema(t),
+		}}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return objectSchema(t)
+	case reflect.Pointer:
+		return schemaFor(t.Elem())
+	case reflect.Slice:
+		return map[string]any{"type": []string{"array", "null"}, "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": schemaFor(t.Elem())}
+	case reflect.String:
+		// YAML reads an unquoted number (target_port: 8080) into a string.
+		return map[string]any{"type": []string{"string", "number", "null"}}
+	case reflect.Bool:
+		return map[string]any{"type": []string{"boolean", "null"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": []string{"integer", "null"}}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": []string{"number", "null"}}
+	}
+	return map[string]any{}
+}
+
+// objectSchema describes a config struct. Unknown keys are rejected, so a
+// misspelt field is reported instead of silently ignored.
+func objectSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlFieldName(f)
+		if name == "" {
+			continue
+		}
+		prop := schemaFor(f.Type)
+		if values, ok := schemaEnums[t.Name()+"."+name]; ok {
+			prop["enum"] = append([]any{nil, ""}, toAny(values)...)
+		}
+		props[name] = prop
+	}
+	return map[string]any{
+		"type":                 []string{"object", "null"},
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+// yamlFieldName returns the key a struct field is read from, or "" when
+// the field is not part of the YAML document.
+func yamlFieldName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return strings.ToLower(f.Name)
+}
+
+// toAny converts values for use in an enum.
+func toAny(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// syslogFacilityNames returns the accepted syslog facilities, sorted.
+func syslogFacilityNames() []string {
+	names := make([]string, 0, len(syslogFacilities))
+	for name := range syslogFacilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handleConfigSchema serves ConfigSchema.
+func (s *Server) handleConfigSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(ConfigSchema())
+}
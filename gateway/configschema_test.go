@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validateSchema checks doc against the subset of JSON Schema ConfigSchema
+// uses, returning the first violation.
+func validateSchema(schema map[string]any, doc any, path string) error {
+	if alts, ok := schema["oneOf"].([]any); ok {
+		matched := 0
+		for _, alt := range alts {
+			if validateSchema(alt.(map[string]any), doc, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: %d oneOf alternatives match", path, matched)
+		}
+		return nil
+	}
+	if types, ok := schema["type"]; ok && !schemaTypeMatches(types, doc) {
+		return fmt.Errorf("%s: %v (%T) is not %v", path, doc, doc, types)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, v := range enum {
+			found = found || v == doc
+		}
+		if !found {
+			return fmt.Errorf("%s: %v not in %v", path, doc, enum)
+		}
+	}
+	switch v := doc.(type) {
+	case string:
+		if p, ok := schema["pattern"].(string); ok && !regexp.MustCompile(p).MatchString(v) {
+			return fmt.Errorf("%s: %q does not match %s", path, v, p)
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range v {
+			if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		for key, val := range v {
+			sub, ok := props[key].(map[string]any)
+			if !ok {
+				if extra, ok := schema["additionalProperties"].(map[string]any); ok {
+					sub = extra
+				} else {
+					return fmt.Errorf("%s: unknown key %q", path, key)
+				}
+			}
+			if err := validateSchema(sub, val, path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func schemaTypeMatches(types, doc any) bool {
+	var kind string
+	switch v := doc.(type) {
+	case nil:
+		kind = "null"
+	case string:
+		kind = "string"
+	case bool:
+		kind = "boolean"
+	case float64:
+		kind = "number"
+		if v == float64(int64(v)) {
+			kind = "integer"
+		}
+	case []any:
+		kind = "array"
+	case map[string]any:
+		kind = "object"
+	}
+	list, ok := types.([]any)
+	if !ok {
+		list = []any{types}
+	}
+	for _, t := range list {
+		if t == kind || (t == "number" && kind == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSchemaDoc reads a YAML file as the JSON document a validator would see.
+func loadSchemaDoc(t *testing.T, data []byte) any {
+	t.Helper()
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestConfigSchema_ExampleConfigs(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal(ConfigSchema(), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	for _, path := range []string{"../config.yaml", "../config.test.yaml"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := validateSchema(schema, loadSchemaDoc(t, data), "$"); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{"misspelt key", "gateway:\n  prot: \"8080\"\n"},
+		{"bad duration", "containers:\n  - name: app\n    idle_timeout: 10 minutes\n"},
+		{"integer duration", "containers:\n  - name: app\n    idle_timeout: 600\n"},
+		{"bad enum", "containers:\n  - name: app\n    readiness: sometimes\n"},
+		{"wrong type", "gateway:\n  admin_auth: basic\n"},
+	}
+	for _, tt := range tests {
+		if err := validateSchema(schema, loadSchemaDoc(t, []byte(tt.yaml)), "$"); err == nil {
+			t.Errorf("%s: expected a schema violation", tt.name)
+		}
+	}
+
+	ok := "groups:\n  - name: api\n    host: api.local\n    containers:\n      - api-1\n      - name: api-2\n        weight: 2\n" +
+		"containers:\n  - name: api-1\n    target_port: 8080\n    bandwidth_limit: 10MB/s\n    start_timeout: 1m30s\n"
+	if err := validateSchema(schema, loadSchemaDoc(t, []byte(ok)), "$"); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+}
+
+func TestConfigSchema_EnumsNameRealFields(t *testing.T) {
+	fields := make(map[string]bool)
+	seen := make(map[reflect.Type]bool)
+	var walk func(reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Slice || t.Kind() == reflect.Map || t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			return
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			if name := yamlFieldName(t.Field(i)); name != "" {
+				fields[t.Name()+"."+name] = true
+				walk(t.Field(i).Type)
+			}
+		}
+	}
+	walk(reflect.TypeOf(GatewayConfig{}))
+	for key := range schemaEnums {
+		if !fields[key] {
+			t.Errorf("schemaEnums key %q is not a config field", key)
+		}
+	}
+}
+
+func TestHandleConfigSchema(t *testing.T) {
+	s := &Server{cfg: &GatewayConfig{}}
+	w := httptest.NewRecorder()
+	s.handleConfigSchema(w, httptest.NewRequest(http.MethodGet, "/_status/schema", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/schema+json" {
+		t.Fatalf("status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !json.Valid(w.Body.Bytes()) {
+		t.Error("body is not JSON")
+	}
+
+	w = httptest.NewRecorder()
+	s.handleConfigSchema(w, httptest.NewRequest(http.MethodPost, "/_status/schema", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", w.Code)
+	}
+}
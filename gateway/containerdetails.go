@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// healthOutputLimit caps each health-check log entry in the details payload;
+// probes that dump a whole HTML page would otherwise bloat the drawer.
+const healthOutputLimit = 1024
+
+// redactedValue replaces label values whose key looks like a credential.
+const redactedValue = "[redacted]"
+
+// secretLabelKey matches label keys that commonly carry credentials.
+var secretLabelKey = regexp.MustCompile(`(?i)pass|secret|token|key|auth|credential`)
+
+// ContainerDetails is the sanitised inspect view served by
+// /_status/api/containers/{name}. Environment values are never included and
+// label values that look like credentials are redacted.
+type ContainerDetails struct {
+	Name          string            `json:"name"`
+	ID            string            `json:"id"`
+	Image         string            `json:"image"`
+	Created       string            `json:"created,omitempty"`
+	Status        string            `json:"status"`
+	StartedAt     string            `json:"started_at,omitempty"`
+	FinishedAt    string            `json:"finished_at,omitempty"`
+	ExitCode      int               `json:"exit_code"`
+	RestartCount  int               `json:"restart_count"`
+	RestartPolicy string            `json:"restart_policy,omitempty"`
+	Env           []string          `json:"env"`
+	Mounts        []DetailsMount    `json:"mounts"`
+	Networks      []DetailsNetwork  `json:"networks"`
+	Labels        map[string]string `json:"labels"`
+	Health        *DetailsHealth    `json:"health,omitempty"`
+}
+
+// DetailsMount is one bind mount or volume of a container.
+type DetailsMount struct {
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	ReadOnly    bool   `json:"read_only"`
+}
+
+// DetailsNetwork is one network a container is attached to.
+type DetailsNetwork struct {
+	Name    string   `json:"name"`
+	IP      string   `json:"ip,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// DetailsHealth is the Docker HEALTHCHECK state with its recent probe log.
+type DetailsHealth struct {
+	Status        string             `json:"status"`
+	FailingStreak int                `json:"failing_streak"`
+	Log           []DetailsHealthRun `json:"log"`
+}
+
+// DetailsHealthRun is a single health-check probe run.
+type DetailsHealthRun struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+}
+
+// InspectDetails returns the sanitised inspect data of a container for the
+// dashboard details drawer.
+func (d *DockerClient) InspectDetails(ctx context.Context, containerName string) (*ContainerDetails, error) {
+	info, err := d.cli.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+	return containerDetails(info), nil
+}
+
+// containerDetails converts a raw inspect response into ContainerDetails,
+// keeping only environment variable names and redacting secret-like labels.
+func containerDetails(info container.InspectResponse) *ContainerDetails {
+	d := &ContainerDetails{
+		Env:      []string{},
+		Mounts:   []DetailsMount{},
+		Networks: []DetailsNetwork{},
+		Labels:   map[string]string{},
+	}
+	if info.ContainerJSONBase != nil {
+		d.Name = strings.TrimPrefix(info.Name, "/")
+		d.ID = info.ID
+		d.Created = info.Created
+		d.RestartCount = info.RestartCount
+		if info.HostConfig != nil {
+			d.RestartPolicy = restartPolicyString(info.HostConfig.RestartPolicy)
+		}
+		if st := info.State; st != nil {
+			d.Status = st.Status
+			d.StartedAt = dockerTime(st.StartedAt)
+			d.FinishedAt = dockerTime(st.FinishedAt)
+			d.ExitCode = st.ExitCode
+			if st.Health != nil {
+				d.Health = detailsHealth(st.Health)
+			}
+		}
+	}
+
+	if cfg := info.Config; cfg != nil {
+		d.Image = cfg.Image
+		for _, kv := range cfg.Env {
+			name, _, _ := strings.Cut(kv, "=")
+			d.Env = append(d.Env, name)
+		}
+		sort.Strings(d.Env)
+		for k, v := range cfg.Labels {
+			if secretLabelKey.MatchString(k) {
+				v = redactedValue
+			}
+			d.Labels[k] = v
+		}
+	}
+
+	for _, m := range info.Mounts {
+		src := m.Source
+		if m.Type == "volume" && m.Name != "" {
+			src = m.Name
+		}
+		d.Mounts = append(d.Mounts, DetailsMount{
+			Type:        string(m.Type),
+			Source:      src,
+			Destination: m.Destination,
+			ReadOnly:    !m.RW,
+		})
+	}
+
+	if info.NetworkSettings != nil {
+		for name, ep := range info.NetworkSettings.Networks {
+			n := DetailsNetwork{Name: name}
+			if ep != nil {
+				n.IP = ep.IPAddress
+				n.Aliases = ep.Aliases
+			}
+			d.Networks = append(d.Networks, n)
+		}
+		sort.Slice(d.Networks, func(i, j int) bool { return d.Networks[i].Name < d.Networks[j].Name })
+	}
+	return d
+}
+
+// detailsHealth copies the health state, truncating each probe output.
+func detailsHealth(h *container.Health) *DetailsHealth {
+	out := &DetailsHealth{
+		Status:        string(h.Status),
+		FailingStreak: h.FailingStreak,
+		Log:           make([]DetailsHealthRun, 0, len(h.Log)),
+	}
+	for _, run := range h.Log {
+		if run == nil {
+			continue
+		}
+		output := run.Output
+		if len(output) > healthOutputLimit {
+			output = strings.ToValidUTF8(output[:healthOutputLimit], "") + "…"
+		}
+		out.Log = append(out.Log, DetailsHealthRun{
+			Start:    run.Start.UTC().Format(time.RFC3339),
+			End:      run.End.UTC().Format(time.RFC3339),
+			ExitCode: run.ExitCode,
+			Output:   output,
+		})
+	}
+	return out
+}
+
+// restartPolicyString renders a restart policy as Docker's CLI does, e.g.
+// "on-failure:3". It returns "" when no policy is set.
+func restartPolicyString(p container.RestartPolicy) string {
+	if p.Name == container.RestartPolicyOnFailure && p.MaximumRetryCount > 0 {
+		return fmt.Sprintf("%s:%d", p.Name, p.MaximumRetryCount)
+	}
+	return string(p.Name)
+}
+
+// dockerTime normalises a Docker timestamp to RFC 3339, dropping the zero
+// time Docker reports for containers that never started or stopped.
+func dockerTime(s string) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	dockernetwork "github.com/docker/docker/api/types/network"
+)
+
+func testInspectResponse() container.InspectResponse {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:           "abc123",
+			Name:         "/app",
+			Created:      "2026-03-01T11:59:00Z",
+			RestartCount: 2,
+			State: &container.State{
+				Status:     "running",
+				StartedAt:  "2026-03-01T12:00:00.123456789Z",
+				FinishedAt: "0001-01-01T00:00:00Z",
+				Health: &container.Health{
+					Status:        container.Unhealthy,
+					FailingStreak: 3,
+					Log: []*container.HealthcheckResult{
+						{Start: start, End: start.Add(time.Second), ExitCode: 1, Output: strings.Repeat("x", 2000)},
+					},
+				},
+			},
+			HostConfig: &container.HostConfig{
+				RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 3},
+			},
+		},
+		Config: &container.Config{
+			Image: "nginx:alpine",
+			Env:   []string{"PATH=/usr/bin", "DB_PASSWORD=hunter2"},
+			Labels: map[string]string{
+				"dag.enabled":        "true",
+				"dag.admin_api_key":  "s3cr3t",
+				"traefik.basic.auth": "user:hash",
+			},
+		},
+		Mounts: []container.MountPoint{
+			{Type: "bind", Source: "/srv/conf", Destination: "/etc/nginx", RW: false},
+			{Type: "volume", Name: "data", Source: "/var/lib/docker/volumes/data/_data", Destination: "/data", RW: true},
+		},
+		NetworkSettings: &container.NetworkSettings{
+			Networks: map[string]*dockernetwork.EndpointSettings{
+				"web":     {IPAddress: "172.20.0.5", Aliases: []string{"app"}},
+				"backend": {IPAddress: "172.21.0.5"},
+			},
+		},
+	}
+}
+
+func TestContainerDetails(t *testing.T) {
+	d := containerDetails(testInspectResponse())
+
+	if d.Name != "app" || d.ID != "abc123" || d.Image != "nginx:alpine" || d.Status != "running" {
+		t.Errorf("identity = %q %q %q %q", d.Name, d.ID, d.Image, d.Status)
+	}
+	if d.StartedAt != "2026-03-01T12:00:00Z" || d.FinishedAt != "" {
+		t.Errorf("started_at = %q, finished_at = %q", d.StartedAt, d.FinishedAt)
+	}
+	if d.RestartPolicy != "on-failure:3" || d.RestartCount != 2 {
+		t.Errorf("restart = %q (count %d), want on-failure:3 (count 2)", d.RestartPolicy, d.RestartCount)
+	}
+
+	t.Run("env names only", func(t *testing.T) {
+		if strings.Join(d.Env, ",") != "DB_PASSWORD,PATH" {
+			t.Errorf("env = %v", d.Env)
+		}
+		b, _ := json.Marshal(d)
+		if strings.Contains(string(b), "hunter2") || strings.Contains(string(b), "s3cr3t") {
+			t.Errorf("payload leaks a secret: %s", b)
+		}
+	})
+
+	t.Run("labels redacted", func(t *testing.T) {
+		if d.Labels["dag.enabled"] != "true" {
+			t.Errorf("plain label = %q", d.Labels["dag.enabled"])
+		}
+		for _, k := range []string{"dag.admin_api_key", "traefik.basic.auth"} {
+			if d.Labels[k] != redactedValue {
+				t.Errorf("label %s = %q, want redacted", k, d.Labels[k])
+			}
+		}
+	})
+
+	t.Run("mounts", func(t *testing.T) {
+		want := []DetailsMount{
+			{Type: "bind", Source: "/srv/conf", Destination: "/etc/nginx", ReadOnly: true},
+			{Type: "volume", Source: "data", Destination: "/data"},
+		}
+		if len(d.Mounts) != len(want) {
+			t.Fatalf("mounts = %+v", d.Mounts)
+		}
+		for i := range want {
+			if d.Mounts[i] != want[i] {
+				t.Errorf("mount %d = %+v, want %+v", i, d.Mounts[i], want[i])
+			}
+		}
+	})
+
+	t.Run("networks sorted", func(t *testing.T) {
+		if len(d.Networks) != 2 || d.Networks[0].Name != "backend" || d.Networks[1].IP != "172.20.0.5" {
+			t.Errorf("networks = %+v", d.Networks)
+		}
+	})
+
+	t.Run("health output truncated", func(t *testing.T) {
+		if d.Health == nil || d.Health.Status != "unhealthy" || d.Health.FailingStreak != 3 || len(d.Health.Log) != 1 {
+			t.Fatalf("health = %+v", d.Health)
+		}
+		if out := d.Health.Log[0].Output; len(out) > healthOutputLimit+len("…") {
+			t.Errorf("output length = %d, want at most %d", len(out), healthOutputLimit+len("…"))
+		}
+	})
+}
+
+func TestContainerDetails_Empty(t *testing.T) {
+	d := containerDetails(container.InspectResponse{})
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"env":[]`) || !strings.Contains(string(b), `"mounts":[]`) || strings.Contains(string(b), `"health"`) {
+		t.Errorf("payload = %s", b)
+	}
+}
+
+func TestHandleStatusContainer(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.43/containers/app/json":
+			json.NewEncoder(w).Encode(testInspectResponse())
+		default:
+			http.Error(w, `{"message":"boom"}`, http.StatusInternalServerError)
+		}
+	}))
+	defer daemon.Close()
+
+	s := &Server{
+		cfg:         &GatewayConfig{Containers: []ContainerConfig{{Name: "app"}, {Name: "broken"}}},
+		manager:     NewContainerManager(newTestDockerClient(t, daemon.URL)),
+		rateLimiter: newRateLimiter(RateLimitConfig{}),
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"details", http.MethodGet, "/_status/api/containers/app", http.StatusOK},
+		{"POST not allowed", http.MethodPost, "/_status/api/containers/app", http.StatusMethodNotAllowed},
+		{"missing name", http.MethodGet, "/_status/api/containers/", http.StatusNotFound},
+		{"unknown container", http.MethodGet, "/_status/api/containers/nope", http.StatusNotFound},
+		{"docker error", http.MethodGet, "/_status/api/containers/broken", http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleStatusContainer(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var d ContainerDetails
+			if err := json.NewDecoder(w.Body).Decode(&d); err != nil {
+				t.Fatal(err)
+			}
+			if d.Name != "app" || d.Labels["dag.admin_api_key"] != redactedValue {
+				t.Errorf("details = %+v", d)
+			}
+		})
+	}
+}
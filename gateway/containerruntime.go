@@ -0,0 +1,42 @@
+package gateway
+
+import "context"
+
+// ContainerRuntime is the container engine the gateway drives. *DockerClient
+// implements it against the Docker API; FakeRuntime keeps containers in
+// memory so the manager, the server and the watchers can be exercised
+// without a daemon.
+type ContainerRuntime interface {
+	// Lifecycle
+	GetContainerStatus(ctx context.Context, containerName string) (string, error)
+	GetContainerHealth(ctx context.Context, containerName string) (string, error)
+	StartContainer(ctx context.Context, containerName string) error
+	StopContainer(ctx context.Context, containerName string) error
+	KillContainer(ctx context.Context, containerName string) error
+	ExecCommand(ctx context.Context, containerName string, cmd []string) (string, int, error)
+
+	// Inspection
+	InspectContainer(ctx context.Context, containerName string) (*ContainerInfo, error)
+	InspectDetails(ctx context.Context, containerName string) (*ContainerDetails, error)
+	GetContainerLogs(ctx context.Context, containerName string, n int) ([]string, error)
+	ResolveTarget(ctx context.Context, cfg *ContainerConfig) (host, port string, err error)
+	DiscoverLabeledContainers(ctx context.Context) ([]ContainerConfig, error)
+
+	// Networks (network_attach)
+	ContainerNetworks(ctx context.Context, containerName string) ([]string, error)
+	ConnectNetwork(ctx context.Context, network, containerName string) error
+	DisconnectNetwork(ctx context.Context, network, containerName string) error
+
+	// Images (update_check_interval, disk usage)
+	ImageDigests(ctx context.Context, containerName string) (string, []string, error)
+	RegistryDigest(ctx context.Context, ref string) (string, error)
+	DiskUsage(ctx context.Context) (DiskUsageReport, error)
+	PruneDanglingImages(ctx context.Context) (PruneReport, error)
+
+	// Daemon
+	Ping(ctx context.Context) error
+	Capable(op string) bool
+	Capabilities() map[string]string
+}
+
+var _ ContainerRuntime = (*DockerClient)(nil)
@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// crashLoopWindow is the maximum run time after which an exit counts as a
+	// crash. A container that ran longer than this is considered healthy.
+	crashLoopWindow = 60 * time.Second
+	// crashLoopThreshold is the number of consecutive rapid exits that marks a
+	// container as crash-looping and enables start backoff.
+	crashLoopThreshold = 3
+	// crashLoopBaseBackoff is the first backoff delay; it doubles with every
+	// further crash up to crashLoopMaxBackoff.
+	crashLoopBaseBackoff = 10 * time.Second
+	crashLoopMaxBackoff  = 5 * time.Minute
+)
+
+// crashRecord holds the rapid-exit history of a single container.
+type crashRecord struct {
+	count        int
+	lastExit     time.Time // FinishedAt of the last exit already accounted for
+	backoffUntil time.Time
+	stopped      bool // the gateway stopped the container; its exit is no crash
+}
+
+// CrashLoopTracker detects rapid start→exit cycles and computes an
+// exponential backoff before the next start attempt is allowed.
+type CrashLoopTracker struct {
+	mu      sync.Mutex
+	records map[string]*crashRecord
+}
+
+// NewCrashLoopTracker creates an empty CrashLoopTracker.
+func NewCrashLoopTracker() *CrashLoopTracker {
+	return &CrashLoopTracker{records: make(map[string]*crashRecord)}
+}
+
+// backoffFor returns the backoff applied after the given number of crashes.
+func backoffFor(count int) time.Duration {
+	if count < crashLoopThreshold {
+		return 0
+	}
+	backoff := crashLoopBaseBackoff
+	for i := crashLoopThreshold; i < count; i++ {
+		backoff *= 2
+		if backoff >= crashLoopMaxBackoff {
+			return crashLoopMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// RecordCrash counts a crash and, once the threshold is reached, schedules
+// the backoff window. It returns the updated consecutive crash count.
+func (t *CrashLoopTracker) RecordCrash(name string, at time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.record(name)
+	r.count++
+	if b := backoffFor(r.count); b > 0 {
+		r.backoffUntil = at.Add(b)
+	}
+	return r.count
+}
+
+// MarkStopped records that the gateway stopped or killed the container, so
+// the exit ObserveExit sees next is not taken for a crash however short the
+// run was.
+func (t *CrashLoopTracker) MarkStopped(name string) {
+	t.mu.Lock()
+	t.record(name).stopped = true
+	t.mu.Unlock()
+}
+
+// ObserveExit inspects the run time of an exited container. Short runs are
+// recorded as crashes (each exit only once); long runs reset the history.
+// An exit following MarkStopped is skipped.
+func (t *CrashLoopTracker) ObserveExit(name string, startedAt, finishedAt time.Time) {
+	if startedAt.IsZero() || finishedAt.IsZero() || finishedAt.Before(startedAt) {
+		return
+	}
+	t.mu.Lock()
+	r := t.record(name)
+	if !finishedAt.After(r.lastExit) {
+		t.mu.Unlock()
+		return
+	}
+	r.lastExit = finishedAt
+	if r.stopped {
+		r.stopped = false
+		t.mu.Unlock()
+		return
+	}
+	if finishedAt.Sub(startedAt) >= crashLoopWindow {
+		r.count = 0
+		r.backoffUntil = time.Time{}
+		t.mu.Unlock()
+		return
+	}
+	t.mu.Unlock()
+	t.RecordCrash(name, finishedAt)
+}
+
+// Backoff reports whether the container is crash-looping and, if so, until
+// when further start attempts are suppressed.
+func (t *CrashLoopTracker) Backoff(name string) (looping bool, until time.Time, count int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.records[name]
+	if !ok {
+		return false, time.Time{}, 0
+	}
+	return r.count >= crashLoopThreshold, r.backoffUntil, r.count
+}
+
+// Reset forgets the crash history of a container.
+func (t *CrashLoopTracker) Reset(name string) {
+	t.mu.Lock()
+	delete(t.records, name)
+	t.mu.Unlock()
+}
+
+// record returns (or creates) the record for name. Caller must hold t.mu.
+func (t *CrashLoopTracker) record(name string) *crashRecord {
+	r, ok := t.records[name]
+	if !ok {
+		r = &crashRecord{}
+		t.records[name] = r
+	}
+	return r
+}
@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// ─── CrashLoopTracker ─────────────────────────────────────────────────────────
+
+func TestBackoffFor(t *testing.T) {
+	tests := []struct {
+		count int
+		want  time.Duration
+	}{
+		{0, 0},
+		{crashLoopThreshold - 1, 0},
+		{crashLoopThreshold, crashLoopBaseBackoff},
+		{crashLoopThreshold + 1, 2 * crashLoopBaseBackoff},
+		{crashLoopThreshold + 2, 4 * crashLoopBaseBackoff},
+		{crashLoopThreshold + 20, crashLoopMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoffFor(tt.count); got != tt.want {
+			t.Errorf("backoffFor(%d) = %v, want %v", tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestCrashLoopTracker(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+
+	t.Run("rapid exits trigger crash loop", func(t *testing.T) {
+		ct := NewCrashLoopTracker()
+		for i := 0; i < crashLoopThreshold; i++ {
+			started := base.Add(time.Duration(i) * time.Minute)
+			ct.ObserveExit("app", started, started.Add(2*time.Second))
+		}
+		looping, until, count := ct.Backoff("app")
+		if !looping || count != crashLoopThreshold {
+			t.Fatalf("Backoff() = (%v, _, %d), want (true, _, %d)", looping, count, crashLoopThreshold)
+		}
+		if until.IsZero() {
+			t.Error("backoff deadline should be set")
+		}
+	})
+
+	t.Run("same exit is only counted once", func(t *testing.T) {
+		ct := NewCrashLoopTracker()
+		for i := 0; i < 5; i++ {
+			ct.ObserveExit("app", base, base.Add(time.Second))
+		}
+		if _, _, count := ct.Backoff("app"); count != 1 {
+			t.Errorf("count = %d, want 1", count)
+		}
+	})
+
+	t.Run("long run resets history", func(t *testing.T) {
+		ct := NewCrashLoopTracker()
+		for i := 0; i < crashLoopThreshold; i++ {
+			started := base.Add(time.Duration(i) * time.Minute)
+			ct.ObserveExit("app", started, started.Add(time.Second))
+		}
+		started := base.Add(10 * time.Minute)
+		ct.ObserveExit("app", started, started.Add(2*crashLoopWindow))
+		if looping, _, count := ct.Backoff("app"); looping || count != 0 {
+			t.Errorf("Backoff() = (%v, _, %d), want reset", looping, count)
+		}
+	})
+
+	t.Run("exits after a gateway stop are not crashes", func(t *testing.T) {
+		ct := NewCrashLoopTracker()
+		for i := 0; i < crashLoopThreshold; i++ {
+			started := base.Add(time.Duration(i) * time.Minute)
+			ct.MarkStopped("app")
+			ct.ObserveExit("app", started, started.Add(time.Second))
+		}
+		if _, _, count := ct.Backoff("app"); count != 0 {
+			t.Errorf("count = %d after gateway stops, want 0", count)
+		}
+		// Only the exit right after the stop is skipped.
+		started := base.Add(10 * time.Minute)
+		ct.ObserveExit("app", started, started.Add(time.Second))
+		if _, _, count := ct.Backoff("app"); count != 1 {
+			t.Errorf("count = %d after a crash, want 1", count)
+		}
+	})
+
+	t.Run("missing timestamps are ignored", func(t *testing.T) {
+		ct := NewCrashLoopTracker()
+		ct.ObserveExit("app", time.Time{}, base)
+		if _, _, count := ct.Backoff("app"); count != 0 {
+			t.Errorf("count = %d, want 0", count)
+		}
+	})
+
+	t.Run("reset clears state", func(t *testing.T) {
+		ct := NewCrashLoopTracker()
+		ct.RecordCrash("app", base)
+		ct.Reset("app")
+		if _, _, count := ct.Backoff("app"); count != 0 {
+			t.Errorf("count = %d, want 0", count)
+		}
+	})
+}
+
+func TestContainerManager_KillIsNoCrash(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running"})
+	m := NewContainerManager(rt)
+	if err := m.Kill(context.Background(), "app"); err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now().Add(-time.Second)
+	m.crashes.ObserveExit("app", started, time.Now())
+	if _, _, count := m.crashes.Backoff("app"); count != 0 {
+		t.Errorf("crash count = %d after a kill, want 0", count)
+	}
+}
@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// pprofPrefix is where the net/http/pprof profiles are served.
+const pprofPrefix = "/_debug/pprof/"
+
+// handlePprof serves the runtime profiles when gateway.debug.pprof is on and
+// 404s otherwise, so the flag can be flipped with a config reload.
+// net/http/pprof only dispatches profile names under /debug/pprof/, so the
+// name is resolved here.
+func (s *Server) handlePprof(w http.ResponseWriter, r *http.Request) {
+	if !s.GetConfig().Gateway.Debug.Pprof {
+		http.NotFound(w, r)
+		return
+	}
+	switch name := strings.TrimPrefix(r.URL.Path, pprofPrefix); name {
+	case "":
+		pprof.Index(w, r)
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Handler(name).ServeHTTP(w, r)
+	}
+}
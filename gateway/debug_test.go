@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlePprof(t *testing.T) {
+	s := &Server{cfg: &GatewayConfig{}}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handlePprof(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := get("/_debug/pprof/"); w.Code != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want 404", w.Code)
+	}
+
+	s.cfg.Gateway.Debug.Pprof = true
+	if w := get("/_debug/pprof/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("index: status = %d, body lacks profile list", w.Code)
+	}
+	if w := get("/_debug/pprof/goroutine?debug=1"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine profile") {
+		t.Errorf("goroutine: status = %d, body = %.80q", w.Code, w.Body.String())
+	}
+	if w := get("/_debug/pprof/cmdline"); w.Code != http.StatusOK {
+		t.Errorf("cmdline: status = %d", w.Code)
+	}
+	if w := get("/_debug/pprof/nope"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown profile: status = %d, want 404", w.Code)
+	}
+}
@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
@@ -10,34 +11,77 @@ import (
 // DiscoveryManager periodically queries Docker for labeled containers
 // and merges them with the static configuration.
 type DiscoveryManager struct {
-	client         *DockerClient
-	onConfigChange func(*GatewayConfig)
+	client         ContainerRuntime
+	onConfigChange func(*GatewayConfig) error
+	shared         *SharedState
+	reloads        *reloadLog
 
+	passMu       sync.Mutex // one pass at a time, so reloads apply in order
 	mu           sync.Mutex
 	staticConfig *GatewayConfig
-	lastConfig   *GatewayConfig // last config pushed via onConfigChange
+	lastConfig   *GatewayConfig // last config applied via onConfigChange
+	lastFailed   *GatewayConfig // last config onConfigChange or Validate rejected
+	lastDynamic  []ContainerConfig
 }
 
-// NewDiscoveryManager creates a new discovery engine.
-func NewDiscoveryManager(client *DockerClient, staticConfig *GatewayConfig, onConfigChange func(*GatewayConfig)) *DiscoveryManager {
+// NewDiscoveryManager creates a new discovery engine. onConfigChange applies
+// a merged configuration; when it fails the previous one must stay active.
+func NewDiscoveryManager(client ContainerRuntime, staticConfig *GatewayConfig, onConfigChange func(*GatewayConfig) error) *DiscoveryManager {
 	return &DiscoveryManager{
 		client:         client,
 		staticConfig:   staticConfig,
+		lastConfig:     staticConfig, // what the gateway starts with
 		onConfigChange: onConfigChange,
+		reloads:        newReloadLog(),
 	}
 }
 
+// SetSharedState makes discovery HA-aware: only the leader replica queries
+// Docker and publishes what it found; the others apply the published list.
+func (dm *DiscoveryManager) SetSharedState(s *SharedState) {
+	dm.shared = s
+}
+
+// SetReloadLog makes discovery record the outcome of every configuration it
+// tries to apply in l.
+func (dm *DiscoveryManager) SetReloadLog(l *reloadLog) {
+	dm.reloads = l
+}
+
 // UpdateStaticConfig updates the base static config used during merging,
-// typically called after a SIGHUP hot-reload.
-// It clears the cached lastConfig to force a reload on the next discovery pass.
-func (dm *DiscoveryManager) UpdateStaticConfig(cfg *GatewayConfig) {
+// typically called after a SIGHUP hot-reload, and applies it right away
+// with a discovery pass. If the merged configuration is invalid or cannot
+// be applied, the previous static config is restored and the error
+// returned: the gateway keeps running on the configuration it had.
+func (dm *DiscoveryManager) UpdateStaticConfig(cfg *GatewayConfig) error {
+	return dm.updateStaticConfig(cfg, ReloadTriggerStatic)
+}
+
+// StaticConfig returns the static config discovered containers are merged
+// into.
+func (dm *DiscoveryManager) StaticConfig() *GatewayConfig {
 	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.staticConfig
+}
+
+// updateStaticConfig is UpdateStaticConfig, reported under trigger.
+func (dm *DiscoveryManager) updateStaticConfig(cfg *GatewayConfig, trigger string) error {
+	dm.passMu.Lock()
+	defer dm.passMu.Unlock()
+
+	dm.mu.Lock()
+	previous := dm.staticConfig
 	dm.staticConfig = cfg
-	dm.lastConfig = nil // force reload
 	dm.mu.Unlock()
 
-	// Trigger an immediate discovery pass with the new static config
-	dm.runDiscovery(context.Background())
+	if err := dm.runPass(context.Background(), trigger, true); err != nil {
+		dm.mu.Lock()
+		dm.staticConfig = previous
+		dm.mu.Unlock()
+		return err
+	}
+	return nil
 }
 
 // Start begins the polling loop for continuously discovering containers.
@@ -59,36 +103,80 @@ func (dm *DiscoveryManager) Start(ctx context.Context, interval time.Duration) {
 	}()
 }
 
-// runDiscovery executes a single discovery pass
+// runDiscovery executes a single periodic discovery pass.
 func (dm *DiscoveryManager) runDiscovery(ctx context.Context) {
-	dynamicContainers, err := dm.client.DiscoverLabeledContainers(ctx)
+	dm.passMu.Lock()
+	defer dm.passMu.Unlock()
+	dm.runPass(ctx, ReloadTriggerDiscovery, false)
+}
+
+// runPass merges the labeled containers into the static config and applies
+// the result when it changed, or always when force is set. A configuration
+// that failed is not retried until it changes, unless forced; a forced pass
+// falls back to the containers found last when Docker cannot be queried.
+// dm.passMu must be held.
+func (dm *DiscoveryManager) runPass(ctx context.Context, trigger string, force bool) error {
+	dynamicContainers, err := dm.discover(ctx)
 	if err != nil {
 		slog.Error("discovery: failed to list labeled containers", "error", err)
-		return
+		if !force {
+			return err
+		}
+		dynamicContainers = dm.lastDynamic
+	} else {
+		dm.lastDynamic = dynamicContainers
 	}
 
 	merged := dm.mergeConfigs(dynamicContainers)
 
+	dm.mu.Lock()
+	previous := dm.lastConfig
+	unchanged := previous != nil && previous.Equal(merged)
+	failedBefore := dm.lastFailed != nil && dm.lastFailed.Equal(merged)
+	dm.mu.Unlock()
+
+	if !force && (unchanged || failedBefore) {
+		slog.Debug("discovery: config unchanged, skipping reload")
+		return nil
+	}
+
 	// Ensure the merged configuration is valid before pushing it
-	if err := merged.Validate(); err != nil {
-		slog.Warn("discovery: merge resulted in invalid configuration", "error", err)
-		return
+	if err = merged.Validate(); err != nil {
+		err = fmt.Errorf("invalid configuration: %w", err)
+	} else {
+		err = dm.onConfigChange(merged)
 	}
+	dm.reloads.record(trigger, previous, merged, err)
 
-	// Only trigger a reload when the config actually changed.
 	dm.mu.Lock()
-	unchanged := dm.lastConfig != nil && dm.lastConfig.Equal(merged)
-	if !unchanged {
-		dm.lastConfig = merged
+	if err != nil {
+		dm.lastFailed = merged
+	} else {
+		dm.lastConfig, dm.lastFailed = merged, nil
 	}
 	dm.mu.Unlock()
 
-	if unchanged {
-		slog.Debug("discovery: config unchanged, skipping reload")
-		return
+	if err != nil {
+		slog.Error("reload rejected, keeping the previous configuration", "trigger", trigger, "error", err)
+		return err
 	}
+	return nil
+}
 
-	dm.onConfigChange(merged)
+// discover returns the labeled containers. Followers use the list published
+// by the leader, and query Docker themselves only when none is available.
+func (dm *DiscoveryManager) discover(ctx context.Context) ([]ContainerConfig, error) {
+	leader := dm.shared.isLeader()
+	if !leader {
+		if containers, ok := dm.shared.discovered(ctx); ok {
+			return containers, nil
+		}
+	}
+	containers, err := dm.client.DiscoverLabeledContainers(ctx)
+	if err == nil && leader {
+		dm.shared.publishDiscovered(ctx, containers)
+	}
+	return containers, err
 }
 
 // mergeConfigs safely combines the static config with dynamic discoveries
@@ -96,9 +184,11 @@ func (dm *DiscoveryManager) mergeConfigs(dynamic []ContainerConfig) *GatewayConf
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
-	// Copy the static global config
+	// Copy the static global config, groups and TCP routes
 	merged := &GatewayConfig{
-		Gateway: dm.staticConfig.Gateway,
+		Gateway:   dm.staticConfig.Gateway,
+		Groups:    dm.staticConfig.Groups,
+		TCPRoutes: dm.staticConfig.TCPRoutes,
 	}
 
 	seenHosts := make(map[string]bool)
@@ -107,22 +197,35 @@ func (dm *DiscoveryManager) mergeConfigs(dynamic []ContainerConfig) *GatewayConf
 	// 1. Add static containers (highest priority)
 	for _, sc := range dm.staticConfig.Containers {
 		merged.Containers = append(merged.Containers, sc)
-		seenHosts[sc.Host] = true
+		if sc.Host != "" {
+			seenHosts[sc.Host+sc.PathPrefix] = true
+		}
 		seenNames[sc.Name] = true
 	}
 
 	// 2. Add dynamically discovered containers avoiding conflicts
 	for _, dc := range dynamic {
-		if seenHosts[dc.Host] {
-			slog.Debug("discovery: skipping dynamic container, host already defined", "container", dc.Name, "host", dc.Host)
+		if dc.Host == "" && merged.Gateway.HostPattern == "" {
+			slog.Warn("discovery: container missing required dag.host", "container", dc.Name)
+			continue
+		}
+		if dc.Host != "" && seenHosts[dc.Host+dc.PathPrefix] {
+			slog.Debug("discovery: skipping dynamic container, host already defined", "container", dc.Name, "host", dc.Host, "path_prefix", dc.PathPrefix)
 			continue
 		}
 		if seenNames[dc.Name] {
 			slog.Debug("discovery: skipping dynamic container, name already defined", "container", dc.Name)
 			continue
 		}
+		// A label may not read files: drop the icon, not the whole update.
+		if labelFileIcon(&dc) {
+			slog.Warn("discovery: ignoring file icon_url from labels", "container", dc.Name, "icon_url", dc.IconURL)
+			dc.IconURL = ""
+		}
 		merged.Containers = append(merged.Containers, dc)
-		seenHosts[dc.Host] = true
+		if dc.Host != "" {
+			seenHosts[dc.Host+dc.PathPrefix] = true
+		}
 		seenNames[dc.Name] = true
 	}
 
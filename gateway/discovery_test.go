@@ -1,6 +1,9 @@
 package gateway
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -93,6 +96,31 @@ func TestMergeConfigs(t *testing.T) {
 			wantLen:   1,
 			wantNames: []string{"s1"},
 		},
+		{
+			name: "dynamic without host → skipped",
+			staticConfig: &GatewayConfig{
+				Gateway: GlobalConfig{Port: "8080"},
+			},
+			dynamic: []ContainerConfig{
+				{Name: "d1", TargetPort: "80"},
+				{Name: "d2", Host: "d2.local", TargetPort: "80"},
+			},
+			wantLen:   1,
+			wantNames: []string{"d2"},
+		},
+		{
+			name: "dynamic without host kept with host_pattern",
+			staticConfig: &GatewayConfig{
+				Gateway:    GlobalConfig{Port: "8080", HostPattern: "{container}.apps.lan"},
+				Containers: []ContainerConfig{{Name: "s1", TargetPort: "80"}},
+			},
+			dynamic: []ContainerConfig{
+				{Name: "d1", TargetPort: "80"},
+				{Name: "d2", TargetPort: "80"},
+			},
+			wantLen:   3,
+			wantNames: []string{"s1", "d1", "d2"},
+		},
 		{
 			name: "dynamic duplicates among themselves → first wins",
 			staticConfig: &GatewayConfig{
@@ -136,6 +164,18 @@ func TestMergeConfigs(t *testing.T) {
 	}
 }
 
+func TestMergeConfigs_KeepsGroups(t *testing.T) {
+	dm := &DiscoveryManager{staticConfig: &GatewayConfig{
+		Gateway:    GlobalConfig{Port: "8080"},
+		Containers: []ContainerConfig{{Name: "api-1", TargetPort: "80"}},
+		Groups:     []GroupConfig{{Name: "api", Host: "api.local", Containers: []string{"api-1"}}},
+	}}
+	merged := dm.mergeConfigs([]ContainerConfig{{Name: "d1", Host: "d1.local", TargetPort: "80"}})
+	if len(merged.Groups) != 1 || merged.Groups[0].Name != "api" {
+		t.Errorf("merged groups = %+v, want the static api group", merged.Groups)
+	}
+}
+
 // TestMergeConfigs_ConcurrentAccess verifies that concurrent mergeConfigs calls
 // on the same DiscoveryManager don't race on the staticConfig mutex.
 func TestMergeConfigs_ConcurrentAccess(t *testing.T) {
@@ -222,8 +262,9 @@ func TestDiscoveryChangeDetection_SkipsDuplicate(t *testing.T) {
 			Gateway:    GlobalConfig{Port: "8080"},
 			Containers: []ContainerConfig{{Name: "s1", Host: "s1.local", TargetPort: "80"}},
 		},
-		onConfigChange: func(cfg *GatewayConfig) {
+		onConfigChange: func(cfg *GatewayConfig) error {
 			callCount++
+			return nil
 		},
 	}
 
@@ -259,8 +300,9 @@ func TestDiscoveryChangeDetection_DetectsNewContainer(t *testing.T) {
 		staticConfig: &GatewayConfig{
 			Gateway: GlobalConfig{Port: "8080"},
 		},
-		onConfigChange: func(cfg *GatewayConfig) {
+		onConfigChange: func(cfg *GatewayConfig) error {
 			callCount++
+			return nil
 		},
 	}
 
@@ -288,28 +330,75 @@ func TestDiscoveryChangeDetection_DetectsNewContainer(t *testing.T) {
 	}
 }
 
-func TestDiscoveryChangeDetection_UpdateStaticClearsCache(t *testing.T) {
-	dm := &DiscoveryManager{
-		staticConfig: &GatewayConfig{
-			Gateway: GlobalConfig{Port: "8080"},
-		},
-		lastConfig: &GatewayConfig{
-			Gateway: GlobalConfig{Port: "8080"},
-		},
+func TestUpdateStaticConfig_RollsBack(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.SetDiscovered([]ContainerConfig{{Name: "d1", Host: "d1.local", TargetPort: "80"}})
+	static := &GatewayConfig{Containers: []ContainerConfig{{Name: "s1", Host: "s1.local", TargetPort: "80"}}}
+	applyDefaults(static)
+
+	var applied []*GatewayConfig
+	reject := false
+	dm := NewDiscoveryManager(rt, static, func(cfg *GatewayConfig) error {
+		if reject {
+			return errors.New("port in use")
+		}
+		applied = append(applied, cfg)
+		return nil
+	})
+	dm.runDiscovery(context.Background())
+	if len(applied) != 1 {
+		t.Fatalf("applied %d configs, want the first pass", len(applied))
 	}
 
-	newStatic := &GatewayConfig{
-		Gateway: GlobalConfig{Port: "9090"},
+	// An invalid static config is rejected before reaching onConfigChange.
+	invalid := &GatewayConfig{Containers: []ContainerConfig{{Name: "s1", TargetPort: "80"}}}
+	if err := dm.UpdateStaticConfig(invalid); err == nil || !strings.Contains(err.Error(), "invalid configuration") {
+		t.Errorf("invalid config: error = %v", err)
 	}
 
-	// UpdateStaticConfig should clear lastConfig (we can't call the full method
-	// because it requires a DockerClient, so we test the field mutation directly).
-	dm.mu.Lock()
-	dm.staticConfig = newStatic
-	dm.lastConfig = nil // This is what UpdateStaticConfig does
-	dm.mu.Unlock()
+	// A config onConfigChange refuses is rolled back too.
+	next := &GatewayConfig{Containers: []ContainerConfig{{Name: "s2", Host: "s2.local", TargetPort: "80"}}}
+	applyDefaults(next)
+	reject = true
+	if err := dm.UpdateStaticConfig(next); err == nil {
+		t.Error("refused config: want an error")
+	}
+	reject = false
+	if dm.staticConfig != static {
+		t.Error("static config not restored after the failed reload")
+	}
+
+	// Periodic passes keep merging the previous static config, and do not
+	// apply anything while it is unchanged.
+	rt.SetDiscovered([]ContainerConfig{{Name: "d1", Host: "d1.local", TargetPort: "80"}, {Name: "d2", Host: "d2.local", TargetPort: "80"}})
+	dm.runDiscovery(context.Background())
+	if len(applied) != 2 || applied[1].Containers[0].Name != "s1" {
+		t.Fatalf("applied = %d configs, want a second one built on s1", len(applied))
+	}
 
-	if dm.lastConfig != nil {
-		t.Error("lastConfig should be nil after UpdateStaticConfig")
+	st := dm.reloads.Status()
+	if st.Succeeded != 2 || st.Failed != 2 || !st.Last.OK {
+		t.Errorf("reload status = %+v, want 2 succeeded and 2 failed", st)
+	}
+}
+
+// ─── HA ───────────────────────────────────────────────────────────────────────
+
+func TestDiscover_FollowerUsesLeaderList(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	leader, follower := newHAManager(srv, "a"), newHAManager(srv, "b")
+	ctx := context.Background()
+	leader.syncShared(ctx)
+	follower.syncShared(ctx)
+	leader.shared.publishDiscovered(ctx, []ContainerConfig{{Name: "d1", Host: "d1.local", TargetPort: "80"}})
+
+	// No Docker client: a follower must not query Docker when a list exists.
+	dm := &DiscoveryManager{shared: follower.SharedState()}
+	got, err := dm.discover(ctx)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "d1" {
+		t.Errorf("discover = %+v, want the leader's list", got)
 	}
 }
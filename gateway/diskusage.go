@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+)
+
+// DiskUsageCategory sums one kind of Docker object, like a row of
+// `docker system df`. Sizes are in bytes.
+type DiskUsageCategory struct {
+	Count       int   `json:"count"`
+	Active      int   `json:"active"`
+	Size        int64 `json:"size"`
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// DanglingImages counts untagged images, the ones /_status/disk/prune removes.
+type DanglingImages struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+// DiskUsageReport is the /_status/disk payload.
+type DiskUsageReport struct {
+	Images         DiskUsageCategory `json:"images"`
+	Containers     DiskUsageCategory `json:"containers"`
+	Volumes        DiskUsageCategory `json:"volumes"`
+	BuildCache     DiskUsageCategory `json:"build_cache"`
+	DanglingImages DanglingImages    `json:"dangling_images"`
+}
+
+// PruneReport is the result of removing dangling images.
+type PruneReport struct {
+	ImagesDeleted  int    `json:"images_deleted"`
+	SpaceReclaimed uint64 `json:"space_reclaimed"`
+}
+
+// DiskUsage returns the host's Docker disk usage, as `docker system df` does.
+func (d *DockerClient) DiskUsage(ctx context.Context) (DiskUsageReport, error) {
+	du, err := d.cli.DiskUsage(ctx, types.DiskUsageOptions{})
+	if err != nil {
+		return DiskUsageReport{}, err
+	}
+	return diskUsageReport(du), nil
+}
+
+// PruneDanglingImages removes untagged images no container uses. Tagged
+// images, volumes and the build cache are never touched.
+func (d *DockerClient) PruneDanglingImages(ctx context.Context) (PruneReport, error) {
+	rep, err := d.cli.ImagesPrune(ctx, filters.NewArgs(filters.Arg("dangling", "true")))
+	if err != nil {
+		return PruneReport{}, err
+	}
+	deleted := 0
+	for _, item := range rep.ImagesDeleted {
+		if item.Deleted != "" {
+			deleted++
+		}
+	}
+	return PruneReport{ImagesDeleted: deleted, SpaceReclaimed: rep.SpaceReclaimed}, nil
+}
+
+// diskUsageReport sums a raw /system/df response with the same rules as the
+// Docker CLI: an object is active while in use, and only inactive objects
+// count as reclaimable.
+func diskUsageReport(du types.DiskUsage) DiskUsageReport {
+	var rep DiskUsageReport
+
+	rep.Images.Size = du.LayersSize
+	for _, img := range du.Images {
+		if img == nil {
+			continue
+		}
+		rep.Images.Count++
+		if img.Containers > 0 {
+			rep.Images.Active++
+		} else if img.SharedSize != -1 {
+			rep.Images.Reclaimable += img.Size - img.SharedSize
+		} else {
+			rep.Images.Reclaimable += img.Size
+		}
+		if isDanglingImage(img) {
+			rep.DanglingImages.Count++
+			rep.DanglingImages.Size += img.Size
+		}
+	}
+
+	for _, c := range du.Containers {
+		if c == nil {
+			continue
+		}
+		rep.Containers.Count++
+		rep.Containers.Size += c.SizeRw
+		if c.State == "running" || c.State == "paused" || c.State == "restarting" {
+			rep.Containers.Active++
+		} else {
+			rep.Containers.Reclaimable += c.SizeRw
+		}
+	}
+
+	for _, v := range du.Volumes {
+		if v == nil {
+			continue
+		}
+		rep.Volumes.Count++
+		var size int64
+		inUse := false
+		if v.UsageData != nil {
+			if v.UsageData.Size > 0 {
+				size = v.UsageData.Size
+			}
+			inUse = v.UsageData.RefCount > 0
+		}
+		rep.Volumes.Size += size
+		if inUse {
+			rep.Volumes.Active++
+		} else {
+			rep.Volumes.Reclaimable += size
+		}
+	}
+
+	for _, bc := range du.BuildCache {
+		if bc == nil {
+			continue
+		}
+		rep.BuildCache.Count++
+		if bc.InUse {
+			rep.BuildCache.Active++
+		}
+		if bc.Shared {
+			continue
+		}
+		rep.BuildCache.Size += bc.Size
+		if !bc.InUse {
+			rep.BuildCache.Reclaimable += bc.Size
+		}
+	}
+	return rep
+}
+
+// isDanglingImage reports whether an image has no tag.
+func isDanglingImage(img *image.Summary) bool {
+	for _, tag := range img.RepoTags {
+		if tag != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
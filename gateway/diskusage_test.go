@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/volume"
+)
+
+func TestDiskUsageReport(t *testing.T) {
+	du := types.DiskUsage{
+		LayersSize: 1000,
+		Images: []*image.Summary{
+			{RepoTags: []string{"nginx:alpine"}, Size: 400, SharedSize: 100, Containers: 1},
+			{RepoTags: []string{"redis:7"}, Size: 300, SharedSize: 100, Containers: 0},
+			{RepoTags: []string{"<none>:<none>"}, Size: 200, SharedSize: -1, Containers: 0},
+			{Size: 50, SharedSize: -1, Containers: 0},
+		},
+		Containers: []*container.Summary{
+			{State: "running", SizeRw: 10},
+			{State: "exited", SizeRw: 20},
+		},
+		Volumes: []*volume.Volume{
+			{UsageData: &volume.UsageData{Size: 500, RefCount: 1}},
+			{UsageData: &volume.UsageData{Size: 70, RefCount: 0}},
+			{UsageData: &volume.UsageData{Size: -1, RefCount: -1}}, // size not computed
+		},
+		BuildCache: []*build.CacheRecord{
+			{Size: 30, InUse: true},
+			{Size: 40},
+			{Size: 90, Shared: true},
+		},
+	}
+	rep := diskUsageReport(du)
+
+	tests := []struct {
+		name string
+		got  DiskUsageCategory
+		want DiskUsageCategory
+	}{
+		{"images", rep.Images, DiskUsageCategory{Count: 4, Active: 1, Size: 1000, Reclaimable: 200 + 200 + 50}},
+		{"containers", rep.Containers, DiskUsageCategory{Count: 2, Active: 1, Size: 30, Reclaimable: 20}},
+		{"volumes", rep.Volumes, DiskUsageCategory{Count: 3, Active: 1, Size: 570, Reclaimable: 70}},
+		{"build cache", rep.BuildCache, DiskUsageCategory{Count: 3, Active: 1, Size: 70, Reclaimable: 40}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+	if rep.DanglingImages != (DanglingImages{Count: 2, Size: 250}) {
+		t.Errorf("dangling = %+v, want 2 images / 250 bytes", rep.DanglingImages)
+	}
+}
+
+func TestHandleStatusDisk(t *testing.T) {
+	var pruneFilters string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1.43/system/df":
+			w.Write([]byte(`{"LayersSize":1234,"Images":[{"RepoTags":["<none>:<none>"],"Size":99,"SharedSize":-1}]}`))
+		case "/v1.43/images/prune":
+			pruneFilters = r.URL.Query().Get("filters")
+			w.Write([]byte(`{"ImagesDeleted":[{"Untagged":"x"},{"Deleted":"sha256:a"},{"Deleted":"sha256:b"}],"SpaceReclaimed":4096}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer daemon.Close()
+
+	s := &Server{
+		cfg:         &GatewayConfig{},
+		manager:     NewContainerManager(newTestDockerClient(t, daemon.URL)),
+		rateLimiter: newRateLimiter(RateLimitConfig{StatusPrune: RateLimitPolicy{Rate: 1, Burst: 10}}),
+	}
+
+	t.Run("usage", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handleStatusDisk(w, httptest.NewRequest(http.MethodGet, "/_status/disk", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var rep DiskUsageReport
+		if err := json.NewDecoder(w.Body).Decode(&rep); err != nil {
+			t.Fatal(err)
+		}
+		if rep.Images.Size != 1234 || rep.DanglingImages.Count != 1 {
+			t.Errorf("report = %+v", rep)
+		}
+	})
+
+	t.Run("prune validation", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			method string
+			query  string
+			want   int
+		}{
+			{"GET not allowed", http.MethodGet, "?confirm=true", http.StatusMethodNotAllowed},
+			{"not confirmed", http.MethodPost, "", http.StatusBadRequest},
+			{"wrong confirmation", http.MethodPost, "?confirm=yes", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			s.handleStatusDiskPrune(w, httptest.NewRequest(tt.method, "/_status/disk/prune"+tt.query, nil))
+			if w.Code != tt.want {
+				t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+			}
+		}
+		if pruneFilters != "" {
+			t.Error("refused requests must not reach the daemon")
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handleStatusDiskPrune(w, httptest.NewRequest(http.MethodPost, "/_status/disk/prune?confirm=true", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var body struct {
+			OK             bool   `json:"ok"`
+			ImagesDeleted  int    `json:"images_deleted"`
+			SpaceReclaimed uint64 `json:"space_reclaimed"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil || !body.OK || body.ImagesDeleted != 2 || body.SpaceReclaimed != 4096 {
+			t.Errorf("body = %+v (%v)", body, err)
+		}
+		if pruneFilters != `{"dangling":{"true":true}}` {
+			t.Errorf("prune filters = %s, want dangling only", pruneFilters)
+		}
+	})
+}
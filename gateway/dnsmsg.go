@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Minimal DNS wire format (RFC 1035) support: enough to parse questions and
+// answer with A/AAAA records, shared by the mDNS responder and the DNS server.
+
+// DNS record types and classes used by the gateway.
+const (
+	dnsTypeA    uint16 = 1
+	dnsTypeAAAA uint16 = 28
+	dnsTypeANY  uint16 = 255
+	dnsClassIN  uint16 = 1
+
+	// dnsClassUnicast is the mDNS "QU" bit of a question class and the
+	// "cache flush" bit of an answer class (RFC 6762).
+	dnsClassUnicast uint16 = 1 << 15
+)
+
+// DNS header flags.
+const (
+	dnsFlagResponse           uint16 = 1 << 15
+	dnsFlagAuthoritative      uint16 = 1 << 10
+	dnsFlagTruncated          uint16 = 1 << 9
+	dnsFlagRecursionDesired   uint16 = 1 << 8
+	dnsFlagRecursionAvailable uint16 = 1 << 7
+)
+
+// DNS response codes, stored in the low 4 bits of the flags.
+const (
+	dnsRcodeFormatError    uint16 = 1
+	dnsRcodeServerFailure  uint16 = 2
+	dnsRcodeNameError      uint16 = 3
+	dnsRcodeNotImplemented uint16 = 4
+	dnsRcodeRefused        uint16 = 5
+)
+
+// dnsOpcode returns the opcode of a message's flags; 0 is a standard query.
+func dnsOpcode(flags uint16) uint16 {
+	return flags >> 11 & 0xF
+}
+
+var errDNSTruncated = errors.New("dns: message truncated")
+
+// dnsQuestion is one entry of the question section. Name is lower-case and
+// has no trailing dot.
+type dnsQuestion struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// dnsQuery is a parsed DNS message header and question section.
+type dnsQuery struct {
+	ID        uint16
+	Flags     uint16
+	Questions []dnsQuestion
+}
+
+// dnsRecord is an A or AAAA answer.
+type dnsRecord struct {
+	Name  string
+	Class uint16
+	TTL   uint32
+	IP    net.IP
+}
+
+// parseDNSQuery parses the header and questions of a DNS message.
+func parseDNSQuery(b []byte) (*dnsQuery, error) {
+	if len(b) < 12 {
+		return nil, errDNSTruncated
+	}
+	q := &dnsQuery{
+		ID:    binary.BigEndian.Uint16(b[0:]),
+		Flags: binary.BigEndian.Uint16(b[2:]),
+	}
+	qdcount := int(binary.BigEndian.Uint16(b[4:]))
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		name, next, err := readDNSName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(b) {
+			return nil, errDNSTruncated
+		}
+		q.Questions = append(q.Questions, dnsQuestion{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(b[next:]),
+			Class: binary.BigEndian.Uint16(b[next+2:]),
+		})
+		off = next + 4
+	}
+	return q, nil
+}
+
+// readDNSName reads a possibly compressed name at off and returns it with
+// the offset just past it.
+func readDNSName(b []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1 // offset after the name, set at the first pointer
+	for jumps := 0; ; {
+		if off >= len(b) {
+			return "", 0, errDNSTruncated
+		}
+		n := int(b[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), end, nil
+		case n&0xC0 == 0xC0:
+			if off+1 >= len(b) {
+				return "", 0, errDNSTruncated
+			}
+			if jumps++; jumps > 10 {
+				return "", 0, errors.New("dns: too many compression pointers")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3FFF)
+		default:
+			if off+1+n > len(b) {
+				return "", 0, errDNSTruncated
+			}
+			labels = append(labels, string(b[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+// appendDNSName appends name in uncompressed wire format.
+func appendDNSName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// buildDNSResponse encodes a response with the given questions and answers.
+func buildDNSResponse(id, flags uint16, questions []dnsQuestion, answers []dnsRecord) []byte {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(answers)))
+	for _, q := range questions {
+		b = appendDNSName(b, q.Name)
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, q.Class)
+	}
+	for _, rr := range answers {
+		rtype, data := dnsTypeAAAA, rr.IP.To16()
+		if ip4 := rr.IP.To4(); ip4 != nil {
+			rtype, data = dnsTypeA, ip4
+		}
+		b = appendDNSName(b, rr.Name)
+		b = binary.BigEndian.AppendUint16(b, rtype)
+		b = binary.BigEndian.AppendUint16(b, rr.Class)
+		b = binary.BigEndian.AppendUint32(b, rr.TTL)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+		b = append(b, data...)
+	}
+	return b
+}
+
+// dnsAnswers returns the records answering q from addrs, the gateway's
+// addresses: A for IPv4, AAAA for IPv6, both for ANY.
+func dnsAnswers(q dnsQuestion, addrs []net.IP, class uint16, ttl uint32) []dnsRecord {
+	var out []dnsRecord
+	for _, ip := range addrs {
+		isV4 := ip.To4() != nil
+		if q.Type == dnsTypeANY || (q.Type == dnsTypeA && isV4) || (q.Type == dnsTypeAAAA && !isV4) {
+			out = append(out, dnsRecord{Name: q.Name, Class: class, TTL: ttl, IP: ip})
+		}
+	}
+	return out
+}
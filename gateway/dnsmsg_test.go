@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+)
+
+// dnsQueryBytes encodes a query with the given questions.
+func dnsQueryBytes(id uint16, questions ...dnsQuestion) []byte {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(questions)))
+	for _, q := range questions {
+		b = appendDNSName(b, q.Name)
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, q.Class)
+	}
+	return b
+}
+
+func TestParseDNSQuery(t *testing.T) {
+	b := dnsQueryBytes(0x1234,
+		dnsQuestion{Name: "App.Local", Type: dnsTypeA, Class: dnsClassIN},
+		dnsQuestion{Name: "api.example.com", Type: dnsTypeAAAA, Class: dnsClassIN | dnsClassUnicast},
+	)
+	q, err := parseDNSQuery(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.ID != 0x1234 || len(q.Questions) != 2 {
+		t.Fatalf("got %+v", q)
+	}
+	if got := q.Questions[0]; got.Name != "app.local" || got.Type != dnsTypeA {
+		t.Errorf("question 0 = %+v", got)
+	}
+	if got := q.Questions[1]; got.Name != "api.example.com" || got.Class&dnsClassUnicast == 0 {
+		t.Errorf("question 1 = %+v", got)
+	}
+}
+
+func TestParseDNSQuery_CompressionPointer(t *testing.T) {
+	// Second question is "www" followed by a pointer to "example.com" in the first.
+	b := dnsQueryBytes(1, dnsQuestion{Name: "example.com", Type: dnsTypeA, Class: dnsClassIN})
+	binary.BigEndian.PutUint16(b[4:], 2)
+	b = append(b, 3, 'w', 'w', 'w', 0xC0, 12, 0, 1, 0, 1)
+
+	q, err := parseDNSQuery(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Questions) != 2 || q.Questions[1].Name != "www.example.com" || q.Questions[1].Type != dnsTypeA {
+		t.Errorf("got %+v", q.Questions)
+	}
+
+	// A pointer to itself must not loop forever.
+	loop := dnsQueryBytes(1)
+	binary.BigEndian.PutUint16(loop[4:], 1)
+	loop = append(loop, 0xC0, 12, 0, 1, 0, 1)
+	if _, err := parseDNSQuery(loop); err == nil {
+		t.Error("pointer loop: expected an error")
+	}
+}
+
+func TestParseDNSQuery_Truncated(t *testing.T) {
+	b := dnsQueryBytes(1, dnsQuestion{Name: "app.local", Type: dnsTypeA, Class: dnsClassIN})
+	for _, n := range []int{5, 14, len(b) - 2} {
+		if _, err := parseDNSQuery(b[:n]); !errors.Is(err, errDNSTruncated) {
+			t.Errorf("%d bytes: err = %v, want errDNSTruncated", n, err)
+		}
+	}
+}
+
+func TestBuildDNSResponse(t *testing.T) {
+	questions := []dnsQuestion{{Name: "app.lan", Type: dnsTypeANY, Class: dnsClassIN}}
+	answers := dnsAnswers(questions[0], []net.IP{net.ParseIP("192.168.1.10"), net.ParseIP("fd00::10")}, dnsClassIN, 60)
+	if len(answers) != 2 {
+		t.Fatalf("ANY: %d answers, want 2", len(answers))
+	}
+	b := buildDNSResponse(7, dnsFlagResponse|dnsFlagAuthoritative, questions, answers)
+
+	// The header and question section parse back unchanged.
+	q, err := parseDNSQuery(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.ID != 7 || q.Flags != dnsFlagResponse|dnsFlagAuthoritative || len(q.Questions) != 1 || q.Questions[0] != questions[0] {
+		t.Fatalf("got %+v", q)
+	}
+	if n := binary.BigEndian.Uint16(b[6:]); n != 2 {
+		t.Fatalf("ancount = %d, want 2", n)
+	}
+
+	// First answer: A record with 4 bytes of data.
+	off := 12 + len(appendDNSName(nil, "app.lan")) + 4
+	off += len(appendDNSName(nil, "app.lan"))
+	if typ := binary.BigEndian.Uint16(b[off:]); typ != dnsTypeA {
+		t.Errorf("answer type = %d, want A", typ)
+	}
+	if ttl := binary.BigEndian.Uint32(b[off+4:]); ttl != 60 {
+		t.Errorf("ttl = %d, want 60", ttl)
+	}
+	if rdlen := binary.BigEndian.Uint16(b[off+8:]); rdlen != 4 || !net.IP(b[off+10:off+14]).Equal(net.ParseIP("192.168.1.10")) {
+		t.Errorf("rdata = %v (len %d)", b[off+10:off+14], rdlen)
+	}
+}
+
+func TestDNSAnswers_Type(t *testing.T) {
+	addrs := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("fd00::1")}
+	tests := []struct {
+		qtype uint16
+		want  int
+	}{
+		{dnsTypeA, 1},
+		{dnsTypeAAAA, 1},
+		{dnsTypeANY, 2},
+		{16, 0}, // TXT
+	}
+	for _, tt := range tests {
+		if got := dnsAnswers(dnsQuestion{Name: "x", Type: tt.qtype}, addrs, dnsClassIN, 1); len(got) != tt.want {
+			t.Errorf("type %d: %d answers, want %d", tt.qtype, len(got), tt.want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package gateway
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// dnsForwardTimeout bounds a query forwarded to the upstream resolver.
+	dnsForwardTimeout = 5 * time.Second
+	// dnsTCPIdleTimeout closes TCP connections without a new query.
+	dnsTCPIdleTimeout = 10 * time.Second
+)
+
+// DNSServer is an embedded DNS server answering A/AAAA queries for every
+// configured and discovered host with the gateway's addresses. Other names
+// are forwarded to the upstream resolver, or refused without one.
+type DNSServer struct {
+	configProvider func() *GatewayConfig
+
+	mu     sync.Mutex
+	cfg    DNSServerConfig
+	reload chan struct{}
+}
+
+// NewDNSServer creates a disabled server; it starts serving once Sync
+// receives a configuration with enabled set.
+func NewDNSServer(configProvider func() *GatewayConfig) *DNSServer {
+	return &DNSServer{
+		configProvider: configProvider,
+		reload:         make(chan struct{}, 1),
+	}
+}
+
+// Sync applies a new DNS server configuration, rebinding when it changed.
+// Host changes need no Sync: hosts are read from the config on every query.
+func (s *DNSServer) Sync(cfg DNSServerConfig) {
+	s.mu.Lock()
+	changed := !reflect.DeepEqual(s.cfg, cfg)
+	s.cfg = cfg
+	s.mu.Unlock()
+	if changed {
+		trySignal(s.reload)
+	}
+}
+
+func (s *DNSServer) config() DNSServerConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// Start runs the server in the background until ctx is cancelled.
+func (s *DNSServer) Start(ctx context.Context) {
+	go func() {
+		for {
+			cfg := s.config()
+			if !cfg.Enabled {
+				select {
+				case <-ctx.Done():
+					return
+				case <-s.reload:
+					continue
+				}
+			}
+
+			select {
+			case <-s.reload:
+			default:
+			}
+			err := s.session(ctx, cfg)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				continue // configuration changed
+			}
+			slog.Warn("dns: server stopped", "retry_in", time.Minute, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.reload:
+			case <-time.After(time.Minute):
+			}
+		}
+	}()
+}
+
+// session serves UDP and TCP on cfg.Listen until ctx is cancelled or the
+// configuration changes (nil) or a listener fails.
+func (s *DNSServer) session(ctx context.Context, cfg DNSServerConfig) error {
+	addrs := dnsServerAddresses(cfg)
+	if len(addrs) == 0 {
+		return fmt.Errorf("no address to answer with; set dns.addresses")
+	}
+	pc, err := net.ListenPacket("udp", cfg.Listen)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		pc.Close()
+		return err
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.reload:
+		case <-stop:
+		}
+		pc.Close()
+		ln.Close()
+	}()
+
+	handle := func(query []byte, network string) []byte {
+		return s.handle(query, network, cfg, addrs)
+	}
+	slog.Info("dns: server started", "listen", cfg.Listen, "addresses", addrs, "upstream", cfg.Upstream)
+
+	errc := make(chan error, 2)
+	go func() { errc <- serveDNSUDP(pc, handle) }()
+	go func() { errc <- serveDNSTCP(ln, handle) }()
+	err = <-errc
+	close(stop)
+	<-errc
+
+	if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+		return nil // closed by shutdown or a configuration change
+	}
+	return err
+}
+
+// handle answers one query received over network ("udp" or "tcp").
+func (s *DNSServer) handle(query []byte, network string, cfg DNSServerConfig, addrs []net.IP) []byte {
+	hosts := make(map[string]bool)
+	for _, h := range configuredHosts(s.configProvider()) {
+		hosts[h] = true
+	}
+	var forward func([]byte) ([]byte, error)
+	if cfg.Upstream != "" {
+		forward = func(q []byte) ([]byte, error) {
+			return forwardDNS(network, cfg.Upstream, q)
+		}
+	}
+	return dnsReply(query, hosts, addrs, uint32(cfg.TTL.Seconds()), forward)
+}
+
+// dnsReply builds the response to query: gateway hosts are answered with
+// addrs, other names go to forward (REFUSED when nil). It returns nil for
+// messages that must not be answered.
+func dnsReply(query []byte, hosts map[string]bool, addrs []net.IP, ttl uint32, forward func([]byte) ([]byte, error)) []byte {
+	q, err := parseDNSQuery(query)
+	if err != nil {
+		if len(query) < 12 {
+			return nil
+		}
+		return buildDNSResponse(binary.BigEndian.Uint16(query), dnsFlagResponse|dnsRcodeFormatError, nil, nil)
+	}
+	if q.Flags&dnsFlagResponse != 0 {
+		return nil
+	}
+	flags := dnsFlagResponse | q.Flags&dnsFlagRecursionDesired
+	if forward != nil {
+		flags |= dnsFlagRecursionAvailable
+	}
+	switch {
+	case dnsOpcode(q.Flags) != 0:
+		return buildDNSResponse(q.ID, flags|dnsRcodeNotImplemented, nil, nil)
+	case len(q.Questions) != 1:
+		return buildDNSResponse(q.ID, flags|dnsRcodeFormatError, nil, nil)
+	}
+
+	question := q.Questions[0]
+	if hosts[question.Name] {
+		// Other record types get an empty NOERROR answer: the name exists.
+		answers := dnsAnswers(question, addrs, dnsClassIN, ttl)
+		return buildDNSResponse(q.ID, flags|dnsFlagAuthoritative, q.Questions, answers)
+	}
+	if forward == nil {
+		return buildDNSResponse(q.ID, flags|dnsRcodeRefused, q.Questions, nil)
+	}
+	resp, err := forward(query)
+	if err != nil {
+		slog.Debug("dns: upstream query failed", "name", question.Name, "error", err)
+		return buildDNSResponse(q.ID, flags|dnsRcodeServerFailure, q.Questions, nil)
+	}
+	return resp
+}
+
+// forwardDNS sends query to upstream over network and returns the response.
+func forwardDNS(network, upstream string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, upstream, dnsForwardTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsForwardTimeout))
+
+	if network == "tcp" {
+		if err := writeDNSTCP(conn, query); err != nil {
+			return nil, err
+		}
+		return readDNSTCP(conn)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// serveDNSUDP answers the queries received on pc until it is closed.
+func serveDNSUDP(pc net.PacketConn, handle func([]byte, string) []byte) error {
+	buf := make([]byte, 65535)
+	for {
+		n, src, err := pc.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			if resp := handle(query, "udp"); resp != nil {
+				pc.WriteTo(resp, src)
+			}
+		}()
+	}
+}
+
+// serveDNSTCP answers length-prefixed queries on connections accepted from
+// ln until it is closed.
+func serveDNSTCP(ln net.Listener, handle func([]byte, string) []byte) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				conn.SetDeadline(time.Now().Add(dnsTCPIdleTimeout))
+				query, err := readDNSTCP(conn)
+				if err != nil {
+					return
+				}
+				resp := handle(query, "tcp")
+				if resp == nil {
+					return
+				}
+				if err := writeDNSTCP(conn, resp); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// readDNSTCP reads one message with its 2-byte length prefix (RFC 1035 §4.2.2).
+func readDNSTCP(r io.Reader) ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeDNSTCP writes msg with its 2-byte length prefix.
+func writeDNSTCP(w io.Writer, msg []byte) error {
+	_, err := w.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...))
+	return err
+}
+
+// dnsServerAddresses returns the addresses gateway hosts resolve to.
+func dnsServerAddresses(cfg DNSServerConfig) []net.IP {
+	var addrs []net.IP
+	for _, a := range cfg.Addresses {
+		if ip := net.ParseIP(a); ip != nil {
+			addrs = append(addrs, ip)
+		}
+	}
+	if len(addrs) == 0 {
+		if ip := interfaceIPv4(nil); ip != nil {
+			addrs = append(addrs, ip)
+		}
+	}
+	return addrs
+}
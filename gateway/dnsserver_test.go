@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDNSReply(t *testing.T) {
+	hosts := map[string]bool{"app.example.com": true}
+	addrs := []net.IP{net.ParseIP("192.168.1.10"), net.ParseIP("fd00::10")}
+	upstream := func(q []byte) ([]byte, error) {
+		return append([]byte("upstream"), q...), nil
+	}
+
+	tests := []struct {
+		name      string
+		q         dnsQuestion
+		forward   func([]byte) ([]byte, error)
+		wantRcode uint16
+		wantCount uint16
+		forwarded bool
+	}{
+		{"A", dnsQuestion{Name: "App.Example.com", Type: dnsTypeA, Class: dnsClassIN}, nil, 0, 1, false},
+		{"AAAA", dnsQuestion{Name: "app.example.com", Type: dnsTypeAAAA, Class: dnsClassIN}, nil, 0, 1, false},
+		{"MX on gateway host", dnsQuestion{Name: "app.example.com", Type: 15, Class: dnsClassIN}, nil, 0, 0, false},
+		{"other name without upstream", dnsQuestion{Name: "example.org", Type: dnsTypeA, Class: dnsClassIN}, nil, dnsRcodeRefused, 0, false},
+		{"other name forwarded", dnsQuestion{Name: "example.org", Type: dnsTypeA, Class: dnsClassIN}, upstream, 0, 0, true},
+		{"upstream down", dnsQuestion{Name: "example.org", Type: dnsTypeA, Class: dnsClassIN},
+			func([]byte) ([]byte, error) { return nil, errors.New("timeout") }, dnsRcodeServerFailure, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := dnsReply(dnsQueryBytes(99, tt.q), hosts, addrs, 60, tt.forward)
+			if tt.forwarded {
+				if string(resp[:8]) != "upstream" {
+					t.Fatalf("response not from upstream: %q", resp)
+				}
+				return
+			}
+			q, err := parseDNSQuery(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.ID != 99 || q.Flags&dnsFlagResponse == 0 {
+				t.Errorf("id = %d, flags = %#x", q.ID, q.Flags)
+			}
+			if rcode := q.Flags & 0xF; rcode != tt.wantRcode {
+				t.Errorf("rcode = %d, want %d", rcode, tt.wantRcode)
+			}
+			if n := binary.BigEndian.Uint16(resp[6:]); n != tt.wantCount {
+				t.Errorf("ancount = %d, want %d", n, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestDNSReply_Malformed(t *testing.T) {
+	if resp := dnsReply([]byte{1, 2, 3}, nil, nil, 60, nil); resp != nil {
+		t.Error("answered a message shorter than a header")
+	}
+
+	// Truncated question: FORMERR with the query ID.
+	b := dnsQueryBytes(7, dnsQuestion{Name: "app.example.com", Type: dnsTypeA, Class: dnsClassIN})
+	q, err := parseDNSQuery(dnsReply(b[:len(b)-3], nil, nil, 60, nil))
+	if err != nil || q.ID != 7 || q.Flags&0xF != dnsRcodeFormatError {
+		t.Errorf("truncated question: %+v, %v", q, err)
+	}
+
+	// Non-query opcodes are not implemented.
+	binary.BigEndian.PutUint16(b[2:], 5<<11) // UPDATE
+	q, _ = parseDNSQuery(dnsReply(b, nil, nil, 60, nil))
+	if q.Flags&0xF != dnsRcodeNotImplemented {
+		t.Errorf("UPDATE: rcode = %d, want NOTIMP", q.Flags&0xF)
+	}
+}
+
+func TestDNSServer_UDPAndTCP(t *testing.T) {
+	s := NewDNSServer(func() *GatewayConfig {
+		return &GatewayConfig{Containers: []ContainerConfig{{Host: "app.example.com"}}}
+	})
+	cfg := DNSServerConfig{TTL: time.Minute}
+	handle := func(query []byte, network string) []byte {
+		return s.handle(query, network, cfg, []net.IP{net.ParseIP("10.0.0.5")})
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	go serveDNSUDP(pc, handle)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveDNSTCP(ln, handle)
+
+	query := dnsQueryBytes(1, dnsQuestion{Name: "app.example.com", Type: dnsTypeA, Class: dnsClassIN})
+	check := func(network string, resp []byte) {
+		if len(resp) < 4 || !net.IP(resp[len(resp)-4:]).Equal(net.ParseIP("10.0.0.5")) {
+			t.Errorf("%s: response %v does not end with the gateway address", network, resp)
+		}
+	}
+
+	// Queries forwarded over UDP and TCP reach the listeners like a client would.
+	resp, err := forwardDNS("udp", pc.LocalAddr().String(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("udp", resp)
+	resp, err = forwardDNS("tcp", ln.Addr().String(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("tcp", resp)
+}
@@ -8,26 +8,55 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	dockernetwork "github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 // DockerClient handles interactions with the Docker daemon
 type DockerClient struct {
-	cli *client.Client
+	cli  *client.Client
+	caps *capabilities // operations found forbidden by DetectCapabilities
 }
 
-// NewDockerClient creates a new DockerClient instance
-func NewDockerClient() (*DockerClient, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// NewDockerClient creates a new DockerClient instance. host overrides
+// DOCKER_HOST when set (gateway.docker_host).
+func NewDockerClient(host string) (*DockerClient, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, err
 	}
-	return &DockerClient{cli: cli}, nil
+	return &DockerClient{cli: cli, caps: &capabilities{}}, nil
+}
+
+// validateDockerHost checks a docker_host address for the given GOOS. Named
+// pipes exist on Windows only.
+func validateDockerHost(host, goos string) error {
+	u, err := client.ParseHostURL(host)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "unix", "tcp", "http", "https":
+	case "npipe":
+		if goos != "windows" {
+			return fmt.Errorf("%q: named pipes are only available on Windows", host)
+		}
+	default:
+		return fmt.Errorf("%q: unsupported scheme %q (want unix, npipe, tcp, http or https)", host, u.Scheme)
+	}
+	return nil
 }
 
 // ContainerInfo holds lightweight container details for the status dashboard.
@@ -47,6 +76,20 @@ func (d *DockerClient) GetContainerStatus(ctx context.Context, containerName str
 	return info.State.Status, nil
 }
 
+// GetContainerHealth returns the Docker HEALTHCHECK status of a container
+// ("starting", "healthy" or "unhealthy"). It returns an empty string when the
+// image defines no HEALTHCHECK.
+func (d *DockerClient) GetContainerHealth(ctx context.Context, containerName string) (string, error) {
+	info, err := d.cli.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return "", err
+	}
+	if info.State == nil || info.State.Health == nil {
+		return "", nil
+	}
+	return string(info.State.Health.Status), nil
+}
+
 // InspectContainer returns lightweight container details for the status dashboard.
 func (d *DockerClient) InspectContainer(ctx context.Context, containerName string) (*ContainerInfo, error) {
 	info, err := d.cli.ContainerInspect(ctx, containerName)
@@ -66,6 +109,34 @@ func (d *DockerClient) InspectContainer(ctx context.Context, containerName strin
 	return ci, nil
 }
 
+// ImageDigests returns the image reference a container was created from
+// (e.g. "nginx:alpine") and the repo digests of the image it runs. Images
+// that were built locally have no repo digests.
+func (d *DockerClient) ImageDigests(ctx context.Context, containerName string) (string, []string, error) {
+	if !d.Capable(CapabilityImages) {
+		return "", nil, capabilityError(CapabilityImages)
+	}
+	info, err := d.cli.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return "", nil, err
+	}
+	img, err := d.cli.ImageInspect(ctx, info.Image)
+	if err != nil {
+		return "", nil, err
+	}
+	return info.Config.Image, img.RepoDigests, nil
+}
+
+// RegistryDigest asks the registry, through the Docker daemon, for the
+// manifest digest currently served for ref. Only anonymous access is used.
+func (d *DockerClient) RegistryDigest(ctx context.Context, ref string) (string, error) {
+	dist, err := d.cli.DistributionInspect(ctx, ref, "")
+	if err != nil {
+		return "", err
+	}
+	return dist.Descriptor.Digest.String(), nil
+}
+
 // DiscoverLabeledContainers lists all containers with the `gateway.enabled=true` label
 // and parses their labels into ContainerConfig structs.
 func (d *DockerClient) DiscoverLabeledContainers(ctx context.Context) ([]ContainerConfig, error) {
@@ -89,15 +160,15 @@ func (d *DockerClient) DiscoverLabeledContainers(ctx context.Context) ([]Contain
 		}
 		
 		cfg := ContainerConfig{
-			Name: strings.TrimPrefix(c.Names[0], "/"),
+			Name:       strings.TrimPrefix(c.Names[0], "/"),
+			Discovered: true,
 		}
 
-		if host, ok := c.Labels["dag.host"]; ok && host != "" {
-			cfg.Host = host
-		} else {
-			slog.Warn("discovery: container missing required dag.host", "container", cfg.Name)
-			continue
-		}
+		// Without dag.host the container is only reachable through
+		// host_pattern; mergeConfigs drops it when none is set.
+		cfg.Host = c.Labels["dag.host"]
+		cfg.PathPrefix = c.Labels["dag.path_prefix"]
+		cfg.KeepPrefix = c.Labels["dag.keep_prefix"] == "true"
 
 		cfg.TargetPort = "80"
 		if port, ok := c.Labels["dag.target_port"]; ok && port != "" {
@@ -121,11 +192,39 @@ func (d *DockerClient) DiscoverLabeledContainers(ctx context.Context) ([]Contain
 			}
 		}
 
+		if val, ok := c.Labels["dag.min_uptime"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.MinUptime = parseDur
+			} else {
+				slog.Warn("discovery: invalid min_uptime", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		cfg.IdleDrainTimeout = 10 * time.Minute
+		if val, ok := c.Labels["dag.idle_drain_timeout"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.IdleDrainTimeout = parseDur
+			} else {
+				slog.Warn("discovery: invalid idle_drain_timeout", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
 		if val, ok := c.Labels["dag.network"]; ok {
 			cfg.Network = val
 		}
+		if val, ok := c.Labels["dag.networks"]; ok && val != "" {
+			for _, n := range strings.Split(val, ",") {
+				if n = strings.TrimSpace(n); n != "" {
+					cfg.Networks = append(cfg.Networks, n)
+				}
+			}
+		}
+
+		cfg.Target = TargetNetwork
+		if val, ok := c.Labels["dag.target"]; ok && val != "" {
+			cfg.Target = val
+		}
 
-		cfg.RedirectPath = "/"
+		cfg.RedirectPath = cfg.PathPrefix + "/"
 		if val, ok := c.Labels["dag.redirect_path"]; ok && val != "" {
 			cfg.RedirectPath = val
 		}
@@ -134,11 +233,151 @@ func (d *DockerClient) DiscoverLabeledContainers(ctx context.Context) ([]Contain
 		if val, ok := c.Labels["dag.icon"]; ok && val != "" {
 			cfg.Icon = val
 		}
+		// A label must not make the gateway serve one of its own files.
+		if val, ok := c.Labels["dag.icon_url"]; ok && val != "" {
+			if u, err := parseIconURL(val); err == nil && u.Scheme != "file" {
+				cfg.IconURL = val
+			} else {
+				slog.Warn("discovery: icon_url must be an http(s) URL", "value", val, "container", cfg.Name)
+			}
+		}
+		if val, ok := c.Labels["dag.tenant"]; ok && val != "" {
+			cfg.Tenant = val
+		}
 
 		if val, ok := c.Labels["dag.health_path"]; ok && val != "" {
 			cfg.HealthPath = val
 		}
 
+		cfg.ProbeInterval = 500 * time.Millisecond
+		if val, ok := c.Labels["dag.probe_interval"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.ProbeInterval = parseDur
+			} else {
+				slog.Warn("discovery: invalid probe_interval", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
+		cfg.ProbeTimeout = 2 * time.Second
+		if val, ok := c.Labels["dag.probe_timeout"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.ProbeTimeout = parseDur
+			} else {
+				slog.Warn("discovery: invalid probe_timeout", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
+		if val, ok := c.Labels["dag.probe_initial_delay"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.ProbeInitialDelay = parseDur
+			} else {
+				slog.Warn("discovery: invalid probe_initial_delay", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
+		if val, ok := c.Labels["dag.probe_status_codes"]; ok && val != "" {
+			for _, part := range strings.Split(val, ",") {
+				code, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					slog.Warn("discovery: invalid probe_status_codes entry", "value", part, "container", cfg.Name, "error", err)
+					continue
+				}
+				cfg.ProbeStatusCodes = append(cfg.ProbeStatusCodes, code)
+			}
+		}
+
+		if val, ok := c.Labels["dag.unhealthy_threshold"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.UnhealthyThreshold = n
+			} else {
+				slog.Warn("discovery: invalid unhealthy_threshold", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		if val, ok := c.Labels["dag.unhealthy_restart"]; ok && val != "" {
+			cfg.UnhealthyRestart = val == "true"
+		}
+		if val, ok := c.Labels["dag.disable_access_log"]; ok && val != "" {
+			cfg.DisableAccessLog = val == "true"
+		}
+
+		if val, ok := c.Labels["dag.circuit_breaker_threshold"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.CircuitBreakerThreshold = n
+			} else {
+				slog.Warn("discovery: invalid circuit_breaker_threshold", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		cfg.CircuitBreakerCooldown = 30 * time.Second
+		if val, ok := c.Labels["dag.circuit_breaker_cooldown"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.CircuitBreakerCooldown = parseDur
+			} else {
+				slog.Warn("discovery: invalid circuit_breaker_cooldown", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
+		if val, ok := c.Labels["dag.wake_retries"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.WakeRetries = n
+			} else {
+				slog.Warn("discovery: invalid wake_retries", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		cfg.WakeRetryWindow = 10 * time.Second
+		if val, ok := c.Labels["dag.wake_retry_window"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.WakeRetryWindow = parseDur
+			} else {
+				slog.Warn("discovery: invalid wake_retry_window", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
+		if val, ok := c.Labels["dag.self_heal_interval"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.SelfHealInterval = parseDur
+			} else {
+				slog.Warn("discovery: invalid self_heal_interval", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		cfg.SelfHealFailures = 3
+		if val, ok := c.Labels["dag.self_heal_failures"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.SelfHealFailures = n
+			} else {
+				slog.Warn("discovery: invalid self_heal_failures", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		cfg.SelfHealMaxRestarts = 3
+		if val, ok := c.Labels["dag.self_heal_max_restarts"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.SelfHealMaxRestarts = n
+			} else {
+				slog.Warn("discovery: invalid self_heal_max_restarts", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
+		if val, ok := c.Labels["dag.push_url"]; ok {
+			cfg.PushURL = val
+		}
+		cfg.PushInterval = 60 * time.Second
+		if val, ok := c.Labels["dag.push_interval"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.PushInterval = parseDur
+			} else {
+				slog.Warn("discovery: invalid push_interval", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
+		cfg.Readiness = ReadinessProbe
+		if val, ok := c.Labels["dag.readiness"]; ok && val != "" {
+			cfg.Readiness = val
+		}
+
+		cfg.WakeMode = WakeModePage
+		if val, ok := c.Labels["dag.wake_mode"]; ok && val != "" {
+			cfg.WakeMode = val
+		}
+
 		if val, ok := c.Labels["dag.depends_on"]; ok && val != "" {
 			cfg.DependsOn = strings.Split(val, ",")
 			// Trim whitespace from each dependency name
@@ -147,6 +386,12 @@ func (d *DockerClient) DiscoverLabeledContainers(ctx context.Context) ([]Contain
 			}
 		}
 
+		if val, ok := c.Labels["dag.middlewares"]; ok && val != "" {
+			for _, name := range strings.Split(val, ",") {
+				cfg.Middlewares = append(cfg.Middlewares, strings.TrimSpace(name))
+			}
+		}
+
 		if val, ok := c.Labels["dag.schedule_start"]; ok && val != "" {
 			cfg.ScheduleStart = val
 		}
@@ -157,16 +402,104 @@ func (d *DockerClient) DiscoverLabeledContainers(ctx context.Context) ([]Contain
 			cfg.ScheduleTimezone = val
 		}
 
+		if val, ok := c.Labels["dag.max_concurrent_requests"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.MaxConcurrentRequests = n
+			} else {
+				slog.Warn("discovery: invalid max_concurrent_requests", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		cfg.Queue.Size = 100
+		if val, ok := c.Labels["dag.queue_size"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.Queue.Size = n
+			} else {
+				slog.Warn("discovery: invalid queue_size", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		cfg.Queue.Timeout = 10 * time.Second
+		if val, ok := c.Labels["dag.queue_timeout"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.Queue.Timeout = parseDur
+			} else {
+				slog.Warn("discovery: invalid queue_timeout", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		cfg.Warmup.Path = "/"
+		if val, ok := c.Labels["dag.warmup_path"]; ok && val != "" {
+			cfg.Warmup.Path = val
+		}
+		if val, ok := c.Labels["dag.warmup_count"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.Warmup.Count = n
+			} else {
+				slog.Warn("discovery: invalid warmup_count", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		if val, ok := c.Labels["dag.pre_start_url"]; ok && val != "" {
+			cfg.Hooks.PreStart = []HookConfig{{URL: val}}
+		}
+		if val, ok := c.Labels["dag.post_ready_url"]; ok && val != "" {
+			cfg.Hooks.PostReady = []HookConfig{{URL: val}}
+		}
+		if val, ok := c.Labels["dag.pre_stop_url"]; ok && val != "" {
+			cfg.Hooks.PreStop = []HookConfig{{URL: val, Veto: c.Labels["dag.pre_stop_veto"] == "true"}}
+		}
+		if val, ok := c.Labels["dag.post_stop_url"]; ok && val != "" {
+			cfg.Hooks.PostStop = []HookConfig{{URL: val}}
+		}
+		cfg.Hooks.setDefaults()
+		if val, ok := c.Labels["dag.bandwidth_limit"]; ok && val != "" {
+			if rate, err := parseByteRate(val); err == nil {
+				cfg.BandwidthLimit = rate
+			} else {
+				slog.Warn("discovery: invalid bandwidth_limit", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		if val, ok := c.Labels["dag.flush_interval"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.FlushInterval = parseDur
+			} else {
+				slog.Warn("discovery: invalid flush_interval", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		if val, ok := c.Labels["dag.websocket_max_connections"]; ok && val != "" {
+			if n, err := strconv.Atoi(val); err == nil {
+				cfg.WebSocket.MaxConnections = n
+			} else {
+				slog.Warn("discovery: invalid websocket_max_connections", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		if val, ok := c.Labels["dag.websocket_idle_timeout"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.WebSocket.IdleTimeout = parseDur
+			} else {
+				slog.Warn("discovery: invalid websocket_idle_timeout", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+		if val, ok := c.Labels["dag.prewarm"]; ok && val != "" {
+			cfg.Prewarm = val == "true"
+		}
+		if val, ok := c.Labels["dag.update_check_interval"]; ok && val != "" {
+			if parseDur, err := time.ParseDuration(val); err == nil {
+				cfg.UpdateCheckInterval = parseDur
+			} else {
+				slog.Warn("discovery: invalid update_check_interval", "value", val, "container", cfg.Name, "error", err)
+			}
+		}
+
 		configs = append(configs, cfg)
 	}
 
 	return configs, nil
 }
 
-// GetContainerAddress returns the IP address of the container.
-// If network is non-empty, it looks up that specific Docker network.
-// Otherwise it returns the IP from the first available network.
-func (d *DockerClient) GetContainerAddress(ctx context.Context, containerName, network string) (string, error) {
+// GetContainerAddress returns the IP address of the container on the first
+// network of preferred it is attached to. Without preferences, networks are
+// tried in name order so the choice does not depend on map iteration. IPv4
+// is preferred; containers on IPv6-only networks get their global IPv6
+// address.
+func (d *DockerClient) GetContainerAddress(ctx context.Context, containerName string, preferred []string) (string, error) {
 	info, err := d.cli.ContainerInspect(ctx, containerName)
 	if err != nil {
 		return "", err
@@ -177,24 +510,138 @@ func (d *DockerClient) GetContainerAddress(ctx context.Context, containerName, n
 		return "", fmt.Errorf("container %s has no network interfaces", containerName)
 	}
 
-	// Prefer the requested network if specified
-	if network != "" {
-		if n, ok := nets[network]; ok && n.IPAddress != "" {
-			return n.IPAddress, nil
+	// Preferred networks, in order
+	if len(preferred) > 0 {
+		for _, network := range preferred {
+			if ip := endpointIP(nets[network]); ip != "" {
+				slog.Debug("container address resolved", "container", containerName, "network", network, "ip", ip)
+				return ip, nil
+			}
 		}
-		return "", fmt.Errorf("container %s is not on network %q (attached networks: %s)",
-			containerName, network, joinNetworkNames(nets))
+		return "", fmt.Errorf("container %s is not on any of the networks %s (attached networks: %s)",
+			containerName, strings.Join(preferred, ", "), joinNetworkNames(nets))
 	}
 
-	// Fallback: return the first non-empty IP
-	for _, n := range nets {
-		if n.IPAddress != "" {
-			return n.IPAddress, nil
+	// Fallback: the first network by name with an IPv4, then with an IPv6
+	names := make([]string, 0, len(nets))
+	for name := range nets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, v6 := range []bool{false, true} {
+		for _, name := range names {
+			n := nets[name]
+			ip := endpointIP(n)
+			if ip == "" || (!v6 && ip != n.IPAddress) {
+				continue
+			}
+			slog.Debug("container address resolved", "container", containerName, "network", name, "ip", ip)
+			return ip, nil
 		}
 	}
 	return "", fmt.Errorf("could not find IP address for container %s", containerName)
 }
 
+// endpointIP returns the IPv4 address of a network endpoint, or its global
+// IPv6 address on IPv6-only networks.
+func endpointIP(n *dockernetwork.EndpointSettings) string {
+	if n == nil {
+		return ""
+	}
+	if n.IPAddress != "" {
+		return n.IPAddress
+	}
+	return n.GlobalIPv6Address
+}
+
+// ResolveTarget returns the host and port the gateway dials to reach cfg:
+// the container IP and target_port, the container name and target_port with
+// target "dns", or the daemon host and the port target_port is published on
+// with target "published".
+func (d *DockerClient) ResolveTarget(ctx context.Context, cfg *ContainerConfig) (host, port string, err error) {
+	switch cfg.Target {
+	case TargetDNS:
+		// Resolved by Docker's embedded DNS at dial time, no inspect needed.
+		return cfg.Name, cfg.TargetPort, nil
+	case TargetPublished:
+		return d.GetPublishedAddress(ctx, cfg.Name, cfg.TargetPort)
+	}
+	ip, err := d.GetContainerAddress(ctx, cfg.Name, cfg.NetworkPreference())
+	return ip, cfg.TargetPort, err
+}
+
+// GetPublishedAddress returns the host address and port that containerPort
+// (TCP) of the container is published on. Bindings to all interfaces are
+// reached through the Docker daemon's host.
+func (d *DockerClient) GetPublishedAddress(ctx context.Context, containerName, containerPort string) (string, string, error) {
+	info, err := d.cli.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return "", "", err
+	}
+	if info.NetworkSettings == nil {
+		return "", "", fmt.Errorf("container %s has no network settings", containerName)
+	}
+	for p, bindings := range info.NetworkSettings.Ports {
+		if string(p) != containerPort+"/tcp" {
+			continue
+		}
+		for _, b := range bindings {
+			if b.HostPort == "" {
+				continue
+			}
+			host := b.HostIP
+			if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+				host = daemonHostname(d.cli.DaemonHost())
+			}
+			return host, b.HostPort, nil
+		}
+	}
+	return "", "", fmt.Errorf("container %s does not publish port %s/tcp", containerName, containerPort)
+}
+
+// daemonHostname returns the host of a Docker daemon URL such as
+// "tcp://10.0.0.5:2376" or "ssh://user@nas". Local sockets map to loopback.
+func daemonHostname(daemonHost string) string {
+	u, err := url.Parse(daemonHost)
+	if err != nil || u.Hostname() == "" || u.Scheme == "unix" || u.Scheme == "npipe" {
+		return "127.0.0.1"
+	}
+	return u.Hostname()
+}
+
+// ContainerNetworks returns the sorted names of the networks the container
+// is attached to.
+func (d *DockerClient) ContainerNetworks(ctx context.Context, containerName string) ([]string, error) {
+	info, err := d.cli.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if info.NetworkSettings != nil {
+		for name := range info.NetworkSettings.Networks {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ConnectNetwork attaches a container to a Docker network.
+func (d *DockerClient) ConnectNetwork(ctx context.Context, network, containerName string) error {
+	if !d.Capable(CapabilityNetwork) {
+		return capabilityError(CapabilityNetwork)
+	}
+	return d.cli.NetworkConnect(ctx, network, containerName, nil)
+}
+
+// DisconnectNetwork detaches a container from a Docker network.
+func (d *DockerClient) DisconnectNetwork(ctx context.Context, network, containerName string) error {
+	if !d.Capable(CapabilityNetwork) {
+		return capabilityError(CapabilityNetwork)
+	}
+	return d.cli.NetworkDisconnect(ctx, network, containerName, false)
+}
+
 // joinNetworkNames lists attached network names for error messages.
 func joinNetworkNames(nets map[string]*dockernetwork.EndpointSettings) string {
 	names := make([]string, 0, len(nets))
@@ -204,62 +651,187 @@ func joinNetworkNames(nets map[string]*dockernetwork.EndpointSettings) string {
 	return strings.Join(names, ", ")
 }
 
-// ProbeTCP attempts a TCP connection to ip:port, retrying every 300 ms until
-// the connection succeeds or ctx is cancelled. Returns nil on success.
-func (d *DockerClient) ProbeTCP(ctx context.Context, ip, port string) error {
+// ProbeOptions tunes the readiness probes. Zero values fall back to the
+// built-in defaults (500 ms interval, 2 s timeout, any 2xx accepted).
+type ProbeOptions struct {
+	// Interval is the pause between two probe attempts.
+	Interval time.Duration
+	// Timeout bounds a single probe attempt.
+	Timeout time.Duration
+	// StatusCodes lists the HTTP status codes treated as ready. Empty means 2xx.
+	StatusCodes []int
+}
+
+// ProbeOptionsFor builds the probe options configured for a container.
+func ProbeOptionsFor(cfg *ContainerConfig) ProbeOptions {
+	return ProbeOptions{
+		Interval:    cfg.ProbeInterval,
+		Timeout:     cfg.ProbeTimeout,
+		StatusCodes: cfg.ProbeStatusCodes,
+	}
+}
+
+func (o ProbeOptions) interval() time.Duration {
+	if o.Interval <= 0 {
+		return 500 * time.Millisecond
+	}
+	return o.Interval
+}
+
+func (o ProbeOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return 2 * time.Second
+	}
+	return o.Timeout
+}
+
+// accepts reports whether an HTTP status code counts as a successful probe.
+func (o ProbeOptions) accepts(code int) bool {
+	if len(o.StatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, c := range o.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ProbeTCP attempts a TCP connection to ip:port, retrying every opts.Interval
+// until the connection succeeds or ctx is cancelled. Returns nil on success.
+func (d *DockerClient) ProbeTCP(ctx context.Context, ip, port string, opts ProbeOptions) error {
 	addr := net.JoinHostPort(ip, port)
 	for {
-		dialer := &net.Dialer{}
-		conn, err := dialer.DialContext(ctx, "tcp", addr)
-		if err == nil {
-			conn.Close()
+		if err := d.ProbeTCPOnce(ctx, ip, port, opts); err == nil {
 			return nil
 		}
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("TCP probe timed out for %s: %w", addr, ctx.Err())
-		case <-time.After(300 * time.Millisecond):
+		case <-time.After(opts.interval()):
 			// retry
 		}
 	}
 }
 
-// ProbeHTTP performs an HTTP GET to http://ip:port/path, retrying every 500 ms
-// until a 2xx response is received or ctx is cancelled. Returns nil on success.
-func (d *DockerClient) ProbeHTTP(ctx context.Context, ip, port, path string) error {
-	probeURL := fmt.Sprintf("http://%s:%s%s", ip, port, path)
-	httpClient := &http.Client{Timeout: 2 * time.Second}
+// ProbeTCPOnce performs a single TCP dial to ip:port bounded by opts.Timeout.
+func (d *DockerClient) ProbeTCPOnce(ctx context.Context, ip, port string, opts ProbeOptions) error {
+	return probeTCPOnce(ctx, ip, port, opts)
+}
+
+// probeTCPOnce implements ProbeTCPOnce; probing does not involve the daemon,
+// so it works for any ContainerRuntime.
+func probeTCPOnce(ctx context.Context, ip, port string, opts ProbeOptions) error {
+	dialer := &net.Dialer{Timeout: opts.timeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// ProbeHTTP performs an HTTP GET to http://ip:port/path, retrying every
+// opts.Interval until an accepted status code is received or ctx is cancelled.
+// Returns nil on success.
+func (d *DockerClient) ProbeHTTP(ctx context.Context, ip, port, path string, opts ProbeOptions) error {
 	for {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
-		if err != nil {
-			return fmt.Errorf("HTTP probe request creation failed for %s: %w", probeURL, err)
-		}
-		resp, err := httpClient.Do(req)
+		err := d.ProbeHTTPOnce(ctx, ip, port, path, opts)
 		if err == nil {
-			resp.Body.Close()
-			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				return nil
-			}
+			return nil
 		}
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("HTTP probe timed out for %s: %w", probeURL, ctx.Err())
-		case <-time.After(500 * time.Millisecond):
+			return fmt.Errorf("HTTP probe timed out for %s: %w", probeURL(ip, port, path), ctx.Err())
+		case <-time.After(opts.interval()):
 			// retry
 		}
 	}
 }
 
+// ProbeHTTPOnce performs a single HTTP GET to http://ip:port/path bounded by
+// opts.Timeout and checks the response status against opts.StatusCodes.
+func (d *DockerClient) ProbeHTTPOnce(ctx context.Context, ip, port, path string, opts ProbeOptions) error {
+	return probeHTTPOnce(ctx, ip, port, path, opts)
+}
+
+// probeHTTPOnce implements ProbeHTTPOnce.
+func probeHTTPOnce(ctx context.Context, ip, port, path string, opts ProbeOptions) error {
+	target := probeURL(ip, port, path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("HTTP probe request creation failed for %s: %w", target, err)
+	}
+	httpClient := &http.Client{Timeout: opts.timeout()}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if !opts.accepts(resp.StatusCode) {
+		return fmt.Errorf("HTTP probe for %s returned unexpected status %d", target, resp.StatusCode)
+	}
+	return nil
+}
+
+// probeURL builds the readiness probe URL for a container address.
+func probeURL(ip, port, path string) string {
+	return "http://" + net.JoinHostPort(ip, port) + path
+}
+
 // StartContainer starts a container by name.
 func (d *DockerClient) StartContainer(ctx context.Context, containerName string) error {
 	return d.cli.ContainerStart(ctx, containerName, container.StartOptions{})
 }
 
+// ExecCommand runs cmd inside a running container and waits for it to
+// finish. It returns the combined stdout and stderr and the exit code.
+func (d *DockerClient) ExecCommand(ctx context.Context, containerName string, cmd []string) (string, int, error) {
+	if !d.Capable(CapabilityExec) {
+		return "", 0, capabilityError(CapabilityExec)
+	}
+	created, err := d.cli.ContainerExecCreate(ctx, containerName, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	attach, err := d.cli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return "", 0, err
+	}
+	defer attach.Close()
+
+	var out bytes.Buffer
+	if _, err := stdcopy.StdCopy(&out, &out, attach.Reader); err != nil {
+		return out.String(), 0, err
+	}
+	inspect, err := d.cli.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return out.String(), 0, err
+	}
+	return out.String(), inspect.ExitCode, nil
+}
+
 // StopContainer stops a running container gracefully.
 func (d *DockerClient) StopContainer(ctx context.Context, containerName string) error {
+	if !d.Capable(CapabilityStop) {
+		return capabilityError(CapabilityStop)
+	}
 	return d.cli.ContainerStop(ctx, containerName, container.StopOptions{})
 }
 
+// KillContainer stops a container at once with SIGKILL, without the grace
+// period of StopContainer.
+func (d *DockerClient) KillContainer(ctx context.Context, containerName string) error {
+	if !d.Capable(CapabilityKill) {
+		return capabilityError(CapabilityKill)
+	}
+	return d.cli.ContainerKill(ctx, containerName, "KILL")
+}
+
 // GetContainerLogs returns the last n log lines from the container.
 // Lines are sanitised: Docker's 8-byte stream header is stripped and the
 // output is safe for rendering as plain text in the browser.
@@ -313,6 +885,12 @@ func stripDockerLogHeaders(b []byte) string {
 	return buf.String()
 }
 
+// Ping checks that the Docker daemon is reachable.
+func (d *DockerClient) Ping(ctx context.Context) error {
+	_, err := d.cli.Ping(ctx)
+	return err
+}
+
 // Close closes the Docker client connection
 func (d *DockerClient) Close() error {
 	return d.cli.Close()
@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -28,7 +29,7 @@ func TestProbeHTTP(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		err := d.ProbeHTTP(ctx, parts[0], parts[1], "/health")
+		err := d.ProbeHTTP(ctx, parts[0], parts[1], "/health", ProbeOptions{})
 		if err != nil {
 			t.Errorf("ProbeHTTP() error = %v, want nil", err)
 		}
@@ -51,7 +52,7 @@ func TestProbeHTTP(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		err := d.ProbeHTTP(ctx, parts[0], parts[1], "/health")
+		err := d.ProbeHTTP(ctx, parts[0], parts[1], "/health", ProbeOptions{})
 		if err != nil {
 			t.Errorf("ProbeHTTP() error = %v, want nil", err)
 		}
@@ -60,6 +61,39 @@ func TestProbeHTTP(t *testing.T) {
 		}
 	})
 
+	t.Run("custom status codes accept 401", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer srv.Close()
+
+		addr := srv.Listener.Addr().String()
+		parts := strings.SplitN(addr, ":", 2)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		opts := ProbeOptions{StatusCodes: []int{200, 401}}
+		if err := d.ProbeHTTP(ctx, parts[0], parts[1], "/health", opts); err != nil {
+			t.Errorf("ProbeHTTP() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("single attempt rejects status outside list", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		addr := srv.Listener.Addr().String()
+		parts := strings.SplitN(addr, ":", 2)
+
+		opts := ProbeOptions{StatusCodes: []int{204}}
+		if err := d.ProbeHTTPOnce(context.Background(), parts[0], parts[1], "/health", opts); err == nil {
+			t.Error("ProbeHTTPOnce() expected error for 200 when only 204 is accepted")
+		}
+	})
+
 	t.Run("timeout on cancelled context", func(t *testing.T) {
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusServiceUnavailable)
@@ -72,13 +106,38 @@ func TestProbeHTTP(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 800*time.Millisecond)
 		defer cancel()
 
-		err := d.ProbeHTTP(ctx, parts[0], parts[1], "/health")
+		err := d.ProbeHTTP(ctx, parts[0], parts[1], "/health", ProbeOptions{})
 		if err == nil {
 			t.Error("ProbeHTTP() expected timeout error, got nil")
 		}
 	})
 }
 
+// ─── ProbeOptions ─────────────────────────────────────────────────────────────
+
+func TestProbeOptionsDefaults(t *testing.T) {
+	var o ProbeOptions
+	if o.interval() != 500*time.Millisecond {
+		t.Errorf("interval() = %v, want 500ms", o.interval())
+	}
+	if o.timeout() != 2*time.Second {
+		t.Errorf("timeout() = %v, want 2s", o.timeout())
+	}
+	for code, want := range map[int]bool{200: true, 204: true, 301: false, 401: false, 503: false} {
+		if got := o.accepts(code); got != want {
+			t.Errorf("accepts(%d) = %v, want %v", code, got, want)
+		}
+	}
+
+	o = ProbeOptions{Interval: time.Second, Timeout: 5 * time.Second, StatusCodes: []int{401}}
+	if o.interval() != time.Second || o.timeout() != 5*time.Second {
+		t.Errorf("explicit values not honoured: interval=%v timeout=%v", o.interval(), o.timeout())
+	}
+	if o.accepts(200) || !o.accepts(401) {
+		t.Error("explicit StatusCodes should replace the 2xx default")
+	}
+}
+
 // ─── stripDockerLogHeaders ────────────────────────────────────────────────────
 
 func TestStripDockerLogHeaders(t *testing.T) {
@@ -175,3 +234,172 @@ func TestJoinNetworkNames(t *testing.T) {
 		})
 	}
 }
+
+// ─── Published target ─────────────────────────────────────────────────────────
+
+func TestDaemonHostname(t *testing.T) {
+	tests := map[string]string{
+		"unix:///var/run/docker.sock": "127.0.0.1",
+		"npipe:////./pipe/docker":     "127.0.0.1",
+		"tcp://10.0.0.5:2376":         "10.0.0.5",
+		"ssh://admin@nas.lan":         "nas.lan",
+		"tcp://[fd00::5]:2375":        "fd00::5",
+	}
+	for in, want := range tests {
+		if got := daemonHostname(in); got != want {
+			t.Errorf("daemonHostname(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResolveTarget_Published(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Name":"/app","State":{"Status":"running"},"NetworkSettings":{"Ports":{
+			"80/tcp":[{"HostIp":"0.0.0.0","HostPort":"8081"}],
+			"9000/tcp":[{"HostIp":"192.168.1.20","HostPort":"19000"}],
+			"53/udp":[{"HostIp":"0.0.0.0","HostPort":"5353"}]}}}`))
+	}))
+	defer daemon.Close()
+	d := newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String())
+
+	tests := []struct {
+		port     string
+		wantHost string
+		wantPort string
+		wantErr  bool
+	}{
+		{"80", "127.0.0.1", "8081", false}, // all interfaces → daemon host
+		{"9000", "192.168.1.20", "19000", false},
+		{"53", "", "", true}, // only published over UDP
+		{"443", "", "", true},
+	}
+	for _, tt := range tests {
+		cfg := &ContainerConfig{Name: "app", TargetPort: tt.port, Target: TargetPublished}
+		host, port, err := d.ResolveTarget(context.Background(), cfg)
+		if (err != nil) != tt.wantErr || host != tt.wantHost || port != tt.wantPort {
+			t.Errorf("port %s: got %q %q %v, want %q %q (err %v)", tt.port, host, port, err, tt.wantHost, tt.wantPort, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveTarget_DNS(t *testing.T) {
+	// No daemon: the dns target must not inspect the container.
+	d := &DockerClient{}
+	host, port, err := d.ResolveTarget(context.Background(), &ContainerConfig{Name: "app", TargetPort: "3000", Target: TargetDNS})
+	if err != nil || host != "app" || port != "3000" {
+		t.Errorf("got %q %q %v, want app 3000", host, port, err)
+	}
+}
+
+// ─── IPv6 ─────────────────────────────────────────────────────────────────────
+
+func TestGetContainerAddress_IPv6(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Name":"/app","State":{"Status":"running"},"NetworkSettings":{"Networks":{
+			"v6only":{"IPAddress":"","GlobalIPv6Address":"fd00::10"},
+			"dual":{"IPAddress":"172.20.0.5","GlobalIPv6Address":"fd01::5"}}}}`))
+	}))
+	defer daemon.Close()
+	d := newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String())
+
+	tests := []struct {
+		networks []string
+		want     string
+	}{
+		{[]string{"v6only"}, "fd00::10"},
+		{[]string{"dual"}, "172.20.0.5"},
+		{nil, "172.20.0.5"}, // IPv4 preferred when any network has one
+	}
+	for _, tt := range tests {
+		got, err := d.GetContainerAddress(context.Background(), "app", tt.networks)
+		if err != nil || got != tt.want {
+			t.Errorf("networks %v: got %q (%v), want %q", tt.networks, got, err, tt.want)
+		}
+	}
+}
+
+func TestProbeTCP_IPv6(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback not available:", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	d := &DockerClient{}
+	if err := d.ProbeTCPOnce(context.Background(), "::1", port, ProbeOptions{}); err != nil {
+		t.Errorf("ProbeTCPOnce([::1]:%s) = %v", port, err)
+	}
+	if got := probeURL("fd00::10", "8080", "/healthz"); got != "http://[fd00::10]:8080/healthz" {
+		t.Errorf("probeURL = %q", got)
+	}
+}
+
+// ─── Network preference ───────────────────────────────────────────────────────
+
+func TestGetContainerAddress_Preference(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Name":"/app","State":{"Status":"running"},"NetworkSettings":{"Networks":{
+			"frontend":{"IPAddress":"172.18.0.2"},
+			"backend":{"IPAddress":"172.19.0.2"},
+			"monitoring":{"IPAddress":"172.20.0.2"}}}}`))
+	}))
+	defer daemon.Close()
+	d := newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String())
+
+	tests := []struct {
+		name     string
+		networks []string
+		want     string
+		wantErr  bool
+	}{
+		{"first preference", []string{"backend", "frontend"}, "172.19.0.2", false},
+		{"skips missing", []string{"db", "frontend"}, "172.18.0.2", false},
+		{"none attached", []string{"db"}, "", true},
+		{"fallback by name", nil, "172.19.0.2", false}, // backend < frontend < monitoring
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat to catch map-order dependent results.
+			for i := 0; i < 10; i++ {
+				got, err := d.GetContainerAddress(context.Background(), "app", tt.networks)
+				if (err != nil) != tt.wantErr || got != tt.want {
+					t.Fatalf("got %q (%v), want %q", got, err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNetworkPreference(t *testing.T) {
+	tests := []struct {
+		cfg  ContainerConfig
+		want []string
+	}{
+		{ContainerConfig{}, nil},
+		{ContainerConfig{Network: "a"}, []string{"a"}},
+		{ContainerConfig{Networks: []string{"b", "c"}}, []string{"b", "c"}},
+		{ContainerConfig{Network: "a", Networks: []string{"b"}}, []string{"a", "b"}},
+		{ContainerConfig{Network: "b", Networks: []string{"a", "b"}}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.NetworkPreference(); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%+v: NetworkPreference = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Drain timeouts of /_status/sleep and the MQTT sleep command, and of an
+// idle stop.
+const (
+	defaultSleepDrainTimeout = 30 * time.Second
+	maxSleepDrainTimeout     = 5 * time.Minute
+	idleStopDrainTimeout     = 10 * time.Second
+)
+
+// DrainTracker counts the requests in flight to each container and lets a
+// programmatic sleep wait for them to finish. While a container is draining,
+// new requests are turned away so the count can only go down.
+//
+// Open WebSocket tunnels are counted apart: a drain does not wait for them,
+// but the idle watcher postpones a stop while any are open.
+type DrainTracker struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	tunnels  map[string]int
+	draining map[string]bool
+	idle     map[string]chan struct{} // closed when inFlight drops to 0
+}
+
+// NewDrainTracker creates a tracker with no requests in flight.
+func NewDrainTracker() *DrainTracker {
+	return &DrainTracker{
+		inFlight: make(map[string]int),
+		tunnels:  make(map[string]int),
+		draining: make(map[string]bool),
+		idle:     make(map[string]chan struct{}),
+	}
+}
+
+// Begin registers a request to name. It returns false, without registering,
+// while name is draining; otherwise End must be called when the request is
+// done.
+func (d *DrainTracker) Begin(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining[name] {
+		return false
+	}
+	d.inFlight[name]++
+	return true
+}
+
+// End unregisters a request started with Begin.
+func (d *DrainTracker) End(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inFlight[name]--; d.inFlight[name] > 0 {
+		return
+	}
+	delete(d.inFlight, name)
+	if ch, ok := d.idle[name]; ok {
+		close(ch)
+		delete(d.idle, name)
+	}
+}
+
+// Draining reports whether name is refusing new requests.
+func (d *DrainTracker) Draining(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining[name]
+}
+
+// InFlight returns the number of requests in flight to name.
+func (d *DrainTracker) InFlight(name string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight[name]
+}
+
+// OpenTunnel registers a WebSocket tunnel to name. It returns false, without
+// registering, when max tunnels (0: unlimited) are already open; otherwise
+// CloseTunnel must be called when the tunnel closes.
+func (d *DrainTracker) OpenTunnel(name string, max int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if max > 0 && d.tunnels[name] >= max {
+		return false
+	}
+	d.tunnels[name]++
+	return true
+}
+
+// CloseTunnel unregisters a tunnel opened with OpenTunnel.
+func (d *DrainTracker) CloseTunnel(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.tunnels[name]--; d.tunnels[name] <= 0 {
+		delete(d.tunnels, name)
+	}
+}
+
+// Active returns the number of requests in flight and tunnels open to name.
+func (d *DrainTracker) Active(name string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight[name] + d.tunnels[name]
+}
+
+// Drain marks name as draining and waits until its in-flight requests are
+// done or ctx expires. The container stays draining until Finish is called.
+func (d *DrainTracker) Drain(ctx context.Context, name string) error {
+	d.mu.Lock()
+	d.draining[name] = true
+	if d.inFlight[name] == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	ch, ok := d.idle[name]
+	if !ok {
+		ch = make(chan struct{})
+		d.idle[name] = ch
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Finish lets name accept requests again after a drain.
+func (d *DrainTracker) Finish(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.draining, name)
+}
@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrainTracker_WaitsForInFlight(t *testing.T) {
+	d := NewDrainTracker()
+	if !d.Begin("app") || !d.Begin("app") {
+		t.Fatal("Begin should succeed while not draining")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- d.Drain(context.Background(), "app") }()
+	waitFor(t, func() bool { return d.Draining("app") })
+
+	if d.Begin("app") {
+		t.Error("Begin should fail while draining")
+	}
+	if !d.Begin("other") {
+		t.Error("draining one container should not affect others")
+	}
+
+	d.End("app")
+	select {
+	case <-done:
+		t.Fatal("Drain returned with a request still in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	d.End("app")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Drain = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not return once in-flight requests finished")
+	}
+
+	d.Finish("app")
+	if !d.Begin("app") {
+		t.Error("Begin should succeed again after Finish")
+	}
+}
+
+func TestDrainTracker_Tunnels(t *testing.T) {
+	d := NewDrainTracker()
+	d.Begin("app")
+	d.OpenTunnel("app", 0)
+	d.OpenTunnel("app", 0)
+	if got := d.Active("app"); got != 3 {
+		t.Errorf("Active = %d, want 3", got)
+	}
+
+	// A drain waits for requests only, not for tunnels.
+	d.End("app")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Drain(ctx, "app"); err != nil {
+		t.Errorf("Drain = %v, want nil with only tunnels open", err)
+	}
+	d.Finish("app")
+
+	d.CloseTunnel("app")
+	d.CloseTunnel("app")
+	if got := d.Active("app"); got != 0 {
+		t.Errorf("Active after closing tunnels = %d, want 0", got)
+	}
+}
+
+func TestDrainTracker_TunnelLimit(t *testing.T) {
+	d := NewDrainTracker()
+	if !d.OpenTunnel("app", 2) || !d.OpenTunnel("app", 2) {
+		t.Fatal("OpenTunnel refused a tunnel under the limit")
+	}
+	if d.OpenTunnel("app", 2) {
+		t.Error("OpenTunnel accepted a tunnel over the limit")
+	}
+	if !d.OpenTunnel("other", 2) {
+		t.Error("the limit of one container affected another")
+	}
+	d.CloseTunnel("app")
+	if !d.OpenTunnel("app", 2) {
+		t.Error("OpenTunnel refused a tunnel after one closed")
+	}
+}
+
+func TestDrainTracker_Timeout(t *testing.T) {
+	d := NewDrainTracker()
+	d.Begin("app")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Drain(ctx, "app"); err == nil {
+		t.Error("Drain should fail when the timeout expires first")
+	}
+	if got := d.InFlight("app"); got != 1 {
+		t.Errorf("InFlight = %d, want 1", got)
+	}
+}
+
+func TestDrainTracker_NoInFlight(t *testing.T) {
+	d := NewDrainTracker()
+	if err := d.Drain(context.Background(), "app"); err != nil {
+		t.Errorf("Drain with nothing in flight = %v, want nil", err)
+	}
+}
+
+func TestHandleStatusSleep_Validation(t *testing.T) {
+	s := &Server{
+		cfg:         &GatewayConfig{Containers: []ContainerConfig{{Name: "app"}}},
+		rateLimiter: newRateLimiter(RateLimitConfig{}),
+	}
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		want   int
+	}{
+		{"GET not allowed", http.MethodGet, "?container=app", http.StatusMethodNotAllowed},
+		{"missing container", http.MethodPost, "", http.StatusBadRequest},
+		{"unknown container", http.MethodPost, "?container=nope", http.StatusBadRequest},
+		{"invalid timeout", http.MethodPost, "?container=app&timeout=soon", http.StatusBadRequest},
+		{"negative timeout", http.MethodPost, "?container=app&timeout=-1s", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleStatusSleep(w, httptest.NewRequest(tt.method, "/_status/sleep"+tt.query, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lifecycle decisions simulated by dry-run mode.
+const (
+	dryRunStart   = "start"   // wake triggers, schedule_start, prewarm
+	dryRunStop    = "stop"    // idle_timeout, schedule_stop
+	dryRunRestart = "restart" // unhealthy_restart, self-healing
+)
+
+// ErrDryRun is returned instead of executing an automatic lifecycle action
+// while gateway.dry_run is set.
+var ErrDryRun = errors.New("dry-run mode (gateway.dry_run): the action was logged, not executed")
+
+// dryRunRepeat is how often the same wake decision is recorded again while a
+// container keeps receiving requests.
+const dryRunRepeat = time.Minute
+
+// dryRunLog implements gateway.dry_run: automatic lifecycle decisions are
+// logged and published as events instead of being executed.
+type dryRunLog struct {
+	enabled atomic.Bool
+
+	mu    sync.Mutex
+	marks map[string]time.Time // action/container → mark of the last record
+}
+
+func newDryRunLog() *dryRunLog {
+	return &dryRunLog{marks: make(map[string]time.Time)}
+}
+
+// SetDryRun applies gateway.dry_run. Hot-reloaded.
+func (m *ContainerManager) SetDryRun(dryRun bool) {
+	m.dryRun.enabled.Store(dryRun)
+}
+
+// DryRun reports whether gateway.dry_run is set.
+func (m *ContainerManager) DryRun() bool {
+	return m.dryRun.enabled.Load()
+}
+
+// simulate records that the gateway would now take action on name, and why.
+// A decision is recorded once per mark: the idle watcher passes the last
+// activity time, so an idle container is reported once per idle period
+// rather than at every check.
+func (m *ContainerManager) simulate(action, name, reason string, mark time.Time) {
+	key := action + "/" + name
+	m.dryRun.mu.Lock()
+	if last, ok := m.dryRun.marks[key]; ok && last.Equal(mark) {
+		m.dryRun.mu.Unlock()
+		return
+	}
+	m.dryRun.marks[key] = mark
+	m.dryRun.mu.Unlock()
+
+	slog.Info("dry run: lifecycle action not executed", "action", action, "container", name, "reason", reason)
+	RecordDryRunDecision(name, action)
+	m.emit(EventDryRun, name, fmt.Sprintf("would %s: %s", action, reason))
+}
+
+// simulateWake records a wake trigger, at most once per dryRunRepeat.
+func (m *ContainerManager) simulateWake(name, reason string) {
+	m.simulate(dryRunStart, name, reason, time.Now().Truncate(dryRunRepeat))
+}
+
+// dryRunMessage explains why a stopped container is not started.
+func dryRunMessage(name, status string) string {
+	if status == "" {
+		status = "not running"
+	}
+	return fmt.Sprintf("%s is %s. The gateway is in dry-run mode: the start was logged but not executed.", name, status)
+}
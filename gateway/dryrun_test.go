@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collectEvents subscribes to m's events of type evType.
+func collectEvents(m *ContainerManager, evType EventType) func() []Event {
+	var mu sync.Mutex
+	var got []Event
+	m.Events().Subscribe(func(ev Event) {
+		if ev.Type == evType {
+			mu.Lock()
+			got = append(got, ev)
+			mu.Unlock()
+		}
+	})
+	return func() []Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Event(nil), got...)
+	}
+}
+
+func TestDryRun_IdleStop(t *testing.T) {
+	var changes atomic.Int32
+	m := NewContainerManager(newTestDockerClient(t, newStateDaemon(t, "running", &changes).URL))
+	m.SetDryRun(true)
+	events := collectEvents(m, EventDryRun)
+
+	gcfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", Host: "app.local", IdleTimeout: time.Millisecond}}}
+	m.RecordActivity("app")
+	time.Sleep(5 * time.Millisecond)
+
+	m.checkIdle(context.Background(), gcfg)
+	m.checkIdle(context.Background(), gcfg)
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("events = %v, want one per idle period", got)
+	}
+	if !strings.HasPrefix(got[0].Message, "would stop: idle for") {
+		t.Errorf("message = %q", got[0].Message)
+	}
+
+	// New activity starts a new idle period.
+	m.RecordActivity("app")
+	time.Sleep(5 * time.Millisecond)
+	m.checkIdle(context.Background(), gcfg)
+	if n := len(events()); n != 2 {
+		t.Errorf("events after new activity = %d, want 2", n)
+	}
+	if n := changes.Load(); n != 0 {
+		t.Errorf("%d container-changing requests reached the daemon, want 0", n)
+	}
+}
+
+func TestDryRun_Restart(t *testing.T) {
+	var changes atomic.Int32
+	m := NewContainerManager(newTestDockerClient(t, newStateDaemon(t, "running", &changes).URL))
+	m.SetDryRun(true)
+	events := collectEvents(m, EventDryRun)
+
+	err := m.restartContainer(&ContainerConfig{Name: "app", StartTimeout: time.Second}, "passive_health")
+	if !errors.Is(err, ErrDryRun) {
+		t.Errorf("restartContainer error = %v, want ErrDryRun", err)
+	}
+	if got := events(); len(got) != 1 || got[0].Message != "would restart: passive_health" {
+		t.Errorf("events = %v", got)
+	}
+	if n := changes.Load(); n != 0 {
+		t.Errorf("%d container-changing requests reached the daemon, want 0", n)
+	}
+}
+
+func TestDryRun_SimulateWake(t *testing.T) {
+	m := NewContainerManager(NewFakeRuntime())
+	events := collectEvents(m, EventDryRun)
+
+	m.simulateWake("app", "request for app.local/")
+	m.simulateWake("app", "request for app.local/other")
+	m.simulateWake("db", "dependency of app not running")
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("events = %v, want one per container within dryRunRepeat", got)
+	}
+	if got[0].Container != "app" || got[0].Message != "would start: request for app.local/" {
+		t.Errorf("first event = %+v", got[0])
+	}
+}
+
+func TestDryRunMessage(t *testing.T) {
+	if got := dryRunMessage("app", "exited"); !strings.HasPrefix(got, "app is exited.") {
+		t.Errorf("message = %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies a container lifecycle event published on the EventBus.
+type EventType string
+
+const (
+	EventStartSuccess    EventType = "start_success"
+	EventStartFailure    EventType = "start_failure"
+	EventIdleStop        EventType = "idle_stop"
+	EventCrashLoop       EventType = "crash_loop"
+	EventDegraded        EventType = "degraded"
+	EventCircuitOpen     EventType = "circuit_open"
+	EventSelfHealRestart EventType = "self_heal_restart"
+	EventUpdateAvailable EventType = "update_available"
+	EventDryRun          EventType = "dry_run"
+)
+
+// knownEventTypes lists every EventType accepted in configuration filters.
+var knownEventTypes = map[EventType]bool{
+	EventStartSuccess:    true,
+	EventStartFailure:    true,
+	EventIdleStop:        true,
+	EventCrashLoop:       true,
+	EventDegraded:        true,
+	EventCircuitOpen:     true,
+	EventSelfHealRestart: true,
+	EventUpdateAvailable: true,
+	EventDryRun:          true,
+}
+
+// Event describes something that happened to a managed container.
+type Event struct {
+	Type      EventType
+	Container string
+	Message   string
+	Time      time.Time
+}
+
+// EventBus fans out container events to subscribers. Subscribers are called
+// synchronously and must not block; slow work belongs in a goroutine.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []func(Event)
+}
+
+// NewEventBus creates an EventBus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers fn to receive every published event.
+func (b *EventBus) Subscribe(fn func(Event)) {
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, fn)
+	b.mu.Unlock()
+}
+
+// Publish delivers an event to all subscribers.
+func (b *EventBus) Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	b.mu.RLock()
+	subs := b.subscribers
+	b.mu.RUnlock()
+	for _, fn := range subs {
+		fn(ev)
+	}
+}
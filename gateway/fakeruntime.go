@@ -0,0 +1,401 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeContainer is a container held by FakeRuntime.
+type FakeContainer struct {
+	Status   string // Docker state: "running", "exited", ... (default: "created")
+	Health   string // HEALTHCHECK status; empty when the image has none
+	Image    string
+	Networks []string
+	Logs     []string
+	// Host and Port are what ResolveTarget returns, typically the address of
+	// an httptest.Server. Empty values fall back to the container name and
+	// target_port, as with target "dns".
+	Host string
+	Port string
+	// StartErr is returned by every StartContainer call; StartDelay is
+	// waited before the container becomes running.
+	StartErr   error
+	StartDelay time.Duration
+	// Digests are the image's repo digests; RegistryDigest is what the
+	// registry serves for its tag (update_check_interval).
+	Digests        []string
+	RegistryDigest string
+}
+
+// FakeRuntime is an in-memory ContainerRuntime. Containers change state only
+// through its methods, and every lifecycle call is recorded, so tests can
+// drive a ContainerManager or a Server without a Docker daemon.
+type FakeRuntime struct {
+	mu         sync.Mutex
+	containers map[string]*FakeContainer
+	discovered []ContainerConfig
+	denied     map[string]bool // operations reported forbidden
+	detected   bool            // Deny was called: Capabilities reports
+	pingErr    error
+	calls      []string
+}
+
+// NewFakeRuntime returns a FakeRuntime without containers.
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		containers: make(map[string]*FakeContainer),
+		denied:     make(map[string]bool),
+	}
+}
+
+var _ ContainerRuntime = (*FakeRuntime)(nil)
+
+// AddContainer adds (or replaces) a container.
+func (f *FakeRuntime) AddContainer(name string, c FakeContainer) {
+	if c.Status == "" {
+		c.Status = "created"
+	}
+	f.mu.Lock()
+	f.containers[name] = &c
+	f.mu.Unlock()
+}
+
+// SetStatus changes a container's state behind the gateway's back, as a
+// crash or a manual `docker stop` would.
+func (f *FakeRuntime) SetStatus(name, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.containers[name]; ok {
+		c.Status = status
+	}
+}
+
+// SetDiscovered sets the containers DiscoverLabeledContainers returns.
+func (f *FakeRuntime) SetDiscovered(cfgs []ContainerConfig) {
+	f.mu.Lock()
+	f.discovered = append([]ContainerConfig(nil), cfgs...)
+	f.mu.Unlock()
+}
+
+// Deny makes operations (CapabilityStop, ...) fail as if a socket proxy
+// forbade them.
+func (f *FakeRuntime) Deny(ops ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.detected = true
+	for _, op := range ops {
+		f.denied[op] = true
+	}
+}
+
+// SetPingError makes Ping fail with err; nil makes the daemon reachable.
+func (f *FakeRuntime) SetPingError(err error) {
+	f.mu.Lock()
+	f.pingErr = err
+	f.mu.Unlock()
+}
+
+// Calls returns the lifecycle calls made so far, such as "start app",
+// "stop app", "kill app", "exec app", "connect net app".
+func (f *FakeRuntime) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// container returns name's container and records call, or fails like the
+// daemon does for an unknown container. f.mu must be held.
+func (f *FakeRuntime) container(name, call string) (*FakeContainer, error) {
+	if call != "" {
+		f.calls = append(f.calls, call+" "+name)
+	}
+	c, ok := f.containers[name]
+	if !ok {
+		return nil, fmt.Errorf("No such container: %s", name)
+	}
+	return c, nil
+}
+
+func (f *FakeRuntime) GetContainerStatus(_ context.Context, name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.container(name, "")
+	if err != nil {
+		return "", err
+	}
+	return c.Status, nil
+}
+
+func (f *FakeRuntime) GetContainerHealth(_ context.Context, name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.container(name, "")
+	if err != nil {
+		return "", err
+	}
+	return c.Health, nil
+}
+
+func (f *FakeRuntime) StartContainer(ctx context.Context, name string) error {
+	f.mu.Lock()
+	c, err := f.container(name, "start")
+	if err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	startErr, delay := c.StartErr, c.StartDelay
+	f.mu.Unlock()
+	if startErr != nil {
+		return startErr
+	}
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	f.SetStatus(name, "running")
+	return nil
+}
+
+func (f *FakeRuntime) StopContainer(_ context.Context, name string) error {
+	return f.halt(name, "stop", CapabilityStop)
+}
+
+func (f *FakeRuntime) KillContainer(_ context.Context, name string) error {
+	return f.halt(name, "kill", CapabilityKill)
+}
+
+// halt implements StopContainer and KillContainer.
+func (f *FakeRuntime) halt(name, call, op string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.denied[op] {
+		return capabilityError(op)
+	}
+	c, err := f.container(name, call)
+	if err != nil {
+		return err
+	}
+	c.Status = "exited"
+	return nil
+}
+
+func (f *FakeRuntime) ExecCommand(_ context.Context, name string, _ []string) (string, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.denied[CapabilityExec] {
+		return "", 0, capabilityError(CapabilityExec)
+	}
+	c, err := f.container(name, "exec")
+	if err != nil {
+		return "", 0, err
+	}
+	if c.Status != "running" {
+		return "", 0, fmt.Errorf("container %s is not running", name)
+	}
+	return "", 0, nil
+}
+
+func (f *FakeRuntime) InspectContainer(_ context.Context, name string) (*ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.container(name, "")
+	if err != nil {
+		return nil, err
+	}
+	return &ContainerInfo{Status: c.Status, Image: c.Image}, nil
+}
+
+func (f *FakeRuntime) InspectDetails(_ context.Context, name string) (*ContainerDetails, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.container(name, "")
+	if err != nil {
+		return nil, err
+	}
+	d := &ContainerDetails{
+		Name:     name,
+		ID:       name,
+		Image:    c.Image,
+		Status:   c.Status,
+		Env:      []string{},
+		Mounts:   []DetailsMount{},
+		Networks: []DetailsNetwork{},
+		Labels:   map[string]string{},
+	}
+	for _, n := range c.Networks {
+		d.Networks = append(d.Networks, DetailsNetwork{Name: n})
+	}
+	return d, nil
+}
+
+func (f *FakeRuntime) GetContainerLogs(_ context.Context, name string, n int) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.container(name, "")
+	if err != nil {
+		return nil, err
+	}
+	logs := c.Logs
+	if n > 0 && len(logs) > n {
+		logs = logs[len(logs)-n:]
+	}
+	return append([]string(nil), logs...), nil
+}
+
+// ResolveTarget returns the container's Host and Port. Like DockerClient, it
+// needs no container for target "dns": the name is dialed as is.
+func (f *FakeRuntime) ResolveTarget(_ context.Context, cfg *ContainerConfig) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.container(cfg.Name, "")
+	if err != nil {
+		if cfg.Target == TargetDNS {
+			return cfg.Name, cfg.TargetPort, nil
+		}
+		return "", "", err
+	}
+	host, port := c.Host, c.Port
+	if host == "" {
+		host = cfg.Name
+	}
+	if port == "" {
+		port = cfg.TargetPort
+	}
+	return host, port, nil
+}
+
+func (f *FakeRuntime) DiscoverLabeledContainers(context.Context) ([]ContainerConfig, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ContainerConfig(nil), f.discovered...), nil
+}
+
+func (f *FakeRuntime) ContainerNetworks(_ context.Context, name string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.container(name, "")
+	if err != nil {
+		return nil, err
+	}
+	nets := append([]string(nil), c.Networks...)
+	sort.Strings(nets)
+	return nets, nil
+}
+
+func (f *FakeRuntime) ConnectNetwork(_ context.Context, network, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.denied[CapabilityNetwork] {
+		return capabilityError(CapabilityNetwork)
+	}
+	c, err := f.container(name, "connect "+network)
+	if err != nil {
+		return err
+	}
+	for _, n := range c.Networks {
+		if n == network {
+			return nil
+		}
+	}
+	c.Networks = append(c.Networks, network)
+	return nil
+}
+
+func (f *FakeRuntime) DisconnectNetwork(_ context.Context, network, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.denied[CapabilityNetwork] {
+		return capabilityError(CapabilityNetwork)
+	}
+	c, err := f.container(name, "disconnect "+network)
+	if err != nil {
+		return err
+	}
+	for i, n := range c.Networks {
+		if n == network {
+			c.Networks = append(c.Networks[:i], c.Networks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// ImageDigests returns the container's image as the reference and its
+// Digests.
+func (f *FakeRuntime) ImageDigests(_ context.Context, name string) (string, []string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.denied[CapabilityImages] {
+		return "", nil, capabilityError(CapabilityImages)
+	}
+	c, err := f.container(name, "")
+	if err != nil {
+		return "", nil, err
+	}
+	return c.Image, append([]string(nil), c.Digests...), nil
+}
+
+// RegistryDigest returns the RegistryDigest of the first container whose
+// image is ref.
+func (f *FakeRuntime) RegistryDigest(_ context.Context, ref string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.containers {
+		if c.Image == ref && c.RegistryDigest != "" {
+			return c.RegistryDigest, nil
+		}
+	}
+	return "", fmt.Errorf("no registry digest for %s", ref)
+}
+
+func (f *FakeRuntime) DiskUsage(context.Context) (DiskUsageReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rep := DiskUsageReport{}
+	for _, c := range f.containers {
+		rep.Containers.Count++
+		if c.Status == "running" {
+			rep.Containers.Active++
+		}
+	}
+	return rep, nil
+}
+
+func (f *FakeRuntime) PruneDanglingImages(context.Context) (PruneReport, error) {
+	return PruneReport{}, nil
+}
+
+func (f *FakeRuntime) Ping(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pingErr
+}
+
+func (f *FakeRuntime) Capable(op string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.denied[op]
+}
+
+// Capabilities reports like DockerClient.Capabilities once Deny was called.
+func (f *FakeRuntime) Capabilities() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.detected {
+		return nil
+	}
+	out := make(map[string]string, len(capabilityList))
+	for _, op := range capabilityList {
+		out[op] = "ok"
+		if f.denied[op] {
+			out[op] = "forbidden"
+		}
+	}
+	return out
+}
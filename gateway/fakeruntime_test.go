@@ -0,0 +1,303 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGateway is a Server over a FakeRuntime, with its full handler.
+type fakeGateway struct {
+	rt      *FakeRuntime
+	manager *ContainerManager
+	server  *Server
+	handler http.Handler
+}
+
+func newFakeGateway(t *testing.T, rt *FakeRuntime, containers ...ContainerConfig) *fakeGateway {
+	t.Helper()
+	return newFakeGatewayConfig(t, rt, &GatewayConfig{Containers: containers})
+}
+
+// newFakeGatewayConfig is newFakeGateway for a complete configuration.
+func newFakeGatewayConfig(t *testing.T, rt *FakeRuntime, cfg *GatewayConfig) *fakeGateway {
+	t.Helper()
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	m := NewContainerManager(rt)
+	s, err := NewServer(m, NewScheduleManager(rt, m), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeGateway{rt: rt, manager: m, server: s, handler: s.buildHandler(cfg.Gateway.AdminAuth, cfg.Gateway.Tenants)}
+}
+
+func (g *fakeGateway) get(host, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.Host = host
+	g.handler.ServeHTTP(w, r)
+	return w
+}
+
+// waitStarted waits for the start of name to finish and returns its state.
+func (g *fakeGateway) waitStarted(t *testing.T, name string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		status, _ := g.manager.GetStartState(name)
+		if status != string(statusStarting) && status != "unknown" {
+			return status
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%s: start did not finish", name)
+	return ""
+}
+
+// testBackend is what a backend from newBackend answers besides its body.
+type testBackend struct {
+	echo    []string          // request headers returned as X-Echo-<name>
+	uri     bool              // append " <request URI>" to the body
+	request bool              // append "<method> <request body>" to the body
+	headers map[string]string // fixed response headers
+	tcp     bool              // raw TCP: greet with the body, then echo
+}
+
+type backendOption func(*testBackend)
+
+// echoHeaders returns the named request headers as X-Echo-<name>.
+func echoHeaders(names ...string) backendOption {
+	return func(b *testBackend) { b.echo = append(b.echo, names...) }
+}
+
+// echoURI appends the request URI to the body.
+func echoURI(b *testBackend) { b.uri = true }
+
+// echoRequest appends the method and the request body to the body.
+func echoRequest(b *testBackend) { b.request = true }
+
+// backendHeader sets a response header on every answer.
+func backendHeader(key, value string) backendOption {
+	return func(b *testBackend) {
+		if b.headers == nil {
+			b.headers = make(map[string]string)
+		}
+		b.headers[key] = value
+	}
+}
+
+// rawTCP makes the backend a plain TCP server that greets every connection
+// with the body, then echoes what it reads.
+func rawTCP(b *testBackend) { b.tcp = true }
+
+// newBackend serves body on every path and returns its host and port. The
+// options add to what it answers.
+func newBackend(t *testing.T, body string, opts ...backendOption) (string, string) {
+	t.Helper()
+	var b testBackend
+	for _, opt := range opts {
+		opt(&b)
+	}
+	if b.tcp {
+		return newTCPBackend(t, body)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range b.echo {
+			w.Header().Set("X-Echo-"+h, r.Header.Get(h))
+		}
+		for k, v := range b.headers {
+			w.Header().Set(k, v)
+		}
+		parts := []string{}
+		if body != "" {
+			parts = append(parts, body)
+		}
+		if b.request {
+			reqBody, _ := io.ReadAll(r.Body)
+			parts = append(parts, r.Method+" "+string(reqBody))
+		}
+		if b.uri {
+			parts = append(parts, r.URL.RequestURI())
+		}
+		io.WriteString(w, strings.Join(parts, " "))
+	}))
+	t.Cleanup(srv.Close)
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	return host, port
+}
+
+// newTCPBackend implements newBackend with rawTCP.
+func newTCPBackend(t *testing.T, greeting string) (string, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.WriteString(conn, greeting)
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+func TestFakeRuntime_WakeOnRequest(t *testing.T) {
+	host, port := newBackend(t, "hello from app")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: port})
+
+	w := g.get("app.local", "/")
+	if !strings.Contains(w.Body.String(), "app") || strings.Contains(w.Body.String(), "hello from app") {
+		t.Fatalf("first request: status %d, want the loading page", w.Code)
+	}
+	if status := g.waitStarted(t, "app"); status != string(statusRunning) {
+		t.Fatalf("start state = %q, want running", status)
+	}
+
+	w = g.get("app.local", "/")
+	if w.Code != http.StatusOK || w.Body.String() != "hello from app" {
+		t.Errorf("after wake: status %d, body %q", w.Code, w.Body)
+	}
+	if calls := rt.Calls(); !slices.Equal(calls, []string{"start app"}) {
+		t.Errorf("calls = %v, want one start", calls)
+	}
+}
+
+func TestFakeRuntime_WakeStartsDependencies(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	for _, name := range []string{"db", "cache", "app"} {
+		rt.AddContainer(name, FakeContainer{Status: "exited", Host: host, Port: port})
+	}
+	g := newFakeGateway(t, rt,
+		ContainerConfig{Name: "app", Host: "app.local", TargetPort: port, DependsOn: []string{"cache"}},
+		ContainerConfig{Name: "cache", TargetPort: port, DependsOn: []string{"db"}},
+		ContainerConfig{Name: "db", TargetPort: port},
+	)
+
+	g.get("app.local", "/")
+	if status := g.waitStarted(t, "app"); status != string(statusRunning) {
+		t.Fatalf("start state = %q, want running", status)
+	}
+	if calls := rt.Calls(); !slices.Equal(calls, []string{"start db", "start cache", "start app"}) {
+		t.Errorf("calls = %v, want dependencies first", calls)
+	}
+}
+
+func TestFakeRuntime_StartFailure(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", StartErr: errors.New("port is already allocated")})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: "80"})
+
+	g.get("app.local", "/")
+	if status := g.waitStarted(t, "app"); status != string(statusFailed) {
+		t.Fatalf("start state = %q, want failed", status)
+	}
+
+	w := g.get("app.local", "/_health?container=app")
+	var health map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health["status"] != string(statusFailed) || health["error"] == "" {
+		t.Errorf("/_health = %v", health)
+	}
+}
+
+func TestFakeRuntime_GroupLoadingPage(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("a", FakeContainer{Status: "exited", Host: host, Port: port})
+	rt.AddContainer("b", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Containers: []ContainerConfig{{Name: "a", TargetPort: port}, {Name: "b", TargetPort: port}, {Name: "other", Host: "other.local", TargetPort: port}},
+		Groups:     []GroupConfig{{Name: "web", Host: "web.local", Members: []GroupMember{{Name: "a"}, {Name: "b"}}}},
+	})
+
+	// The loading page polls its members on the group host, without
+	// gateway.allow_container_query.
+	g.get("web.local", "/")
+	for _, member := range []string{"a", "b"} {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			w := g.get("web.local", "/_health?container="+member)
+			var health map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+				t.Fatalf("/_health of %s: status %d: %v", member, w.Code, err)
+			}
+			if health["status"] == string(statusRunning) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("/_health of %s = %v, want running", member, health)
+			}
+			time.Sleep(200 * time.Millisecond) // within the /_health rate limit
+		}
+		if w := g.get("web.local", "/_logs?container="+member); w.Code != http.StatusOK {
+			t.Errorf("/_logs of %s: status %d, want 200", member, w.Code)
+		}
+	}
+
+	// Containers outside the group are not reachable through its host.
+	if w := g.get("web.local", "/_health?container=other"); w.Code != http.StatusBadRequest {
+		t.Errorf("/_health of a non-member: status %d, want 400", w.Code)
+	}
+}
+
+func TestFakeRuntime_UnknownContainer(t *testing.T) {
+	g := newFakeGateway(t, NewFakeRuntime(), ContainerConfig{Name: "app", Host: "app.local", TargetPort: "80"})
+	w := g.get("app.local", "/")
+	if !strings.Contains(w.Body.String(), "Container not found") {
+		t.Errorf("status %d: want the not-found error page", w.Code)
+	}
+	if w := g.get("other.local", "/"); w.Code != http.StatusNotFound {
+		t.Errorf("unrouted host: status %d, want 404", w.Code)
+	}
+}
+
+func TestFakeRuntime_IdleWatcher(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running"})
+	rt.AddContainer("db", FakeContainer{Status: "running"})
+	rt.AddContainer("busy", FakeContainer{Status: "running"})
+	g := newFakeGateway(t, rt,
+		ContainerConfig{Name: "app", Host: "app.local", TargetPort: "80", IdleTimeout: time.Millisecond, DependsOn: []string{"db"}},
+		ContainerConfig{Name: "db", TargetPort: "5432"},
+		ContainerConfig{Name: "busy", Host: "busy.local", TargetPort: "80", IdleTimeout: time.Hour},
+	)
+	all := g.server.GetConfig().Containers
+	g.manager.RecordActivityChain([]string{"app"}, all)
+	g.manager.RecordActivity("busy")
+	time.Sleep(5 * time.Millisecond)
+
+	g.manager.checkIdle(context.Background(), g.server.GetConfig())
+	for name, want := range map[string]string{"app": "exited", "db": "exited", "busy": "running"} {
+		if got, _ := rt.GetContainerStatus(context.Background(), name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if calls := rt.Calls(); !slices.Equal(calls, []string{"stop app", "stop db"}) {
+		t.Errorf("calls = %v, want the entry point, then its dependency", calls)
+	}
+}
@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+)
+
+// forwardedMiddleware drops the forwarding headers (X-Forwarded-*,
+// Forwarded, X-Real-IP) of requests whose direct peer is not one of the
+// trusted_proxies, before anything reads them. Origin checks and the
+// headers setForwardedHeaders passes to the backend then only build on
+// values a trusted proxy set, never on ones a client made up.
+func (s *Server) forwardedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.fromTrustedProxy(r) {
+			stripForwardedHeaders(r.Header)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// stripForwardedHeaders removes every forwarding header from h.
+func stripForwardedHeaders(h http.Header) {
+	for name := range h {
+		if strings.HasPrefix(name, "X-Forwarded-") {
+			h.Del(name)
+		}
+	}
+	h.Del("Forwarded")
+	h.Del("X-Real-Ip")
+}
+
+// requestScheme is the scheme the client used: "https" when the gateway
+// terminated TLS or a trusted proxy reports it in X-Forwarded-Proto,
+// "http" otherwise.
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	if p := strings.ToLower(strings.TrimSpace(proto)); p == "https" || p == "http" {
+		return p
+	}
+	return "http"
+}
+
+// requestHost is the host the client asked for: the X-Forwarded-Host of a
+// trusted proxy that rewrote Host, or Host itself.
+func requestHost(r *http.Request) string {
+	host, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Host"), ",")
+	if host = strings.TrimSpace(host); host != "" {
+		return host
+	}
+	return r.Host
+}
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// forwardedGateway proxies to a running backend echoing the forwarding
+// headers, with the test client (192.0.2.1) trusted when trusted is set.
+func forwardedGateway(t *testing.T, trusted bool) *fakeGateway {
+	t.Helper()
+	host, port := newBackend(t, "ok", echoHeaders("X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host", "X-Real-IP", "Forwarded"))
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	cfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: port}}}
+	if trusted {
+		cfg.Gateway.TrustedProxies = []string{"192.0.2.0/24"}
+	}
+	return newFakeGatewayConfig(t, rt, cfg)
+}
+
+func spoofedRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = "app.local"
+	r.Header.Set("X-Forwarded-For", "6.6.6.6")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "public.example.com")
+	r.Header.Set("X-Real-IP", "6.6.6.6")
+	r.Header.Set("Forwarded", "for=6.6.6.6")
+	return r
+}
+
+func TestForwarded_UntrustedPeerStripped(t *testing.T) {
+	g := forwardedGateway(t, false)
+	w := httptest.NewRecorder()
+	g.handler.ServeHTTP(w, spoofedRequest())
+
+	want := map[string]string{
+		"X-Echo-X-Forwarded-For":   "192.0.2.1",
+		"X-Echo-X-Forwarded-Proto": "http",
+		"X-Echo-X-Forwarded-Host":  "app.local",
+		"X-Echo-X-Real-Ip":         "192.0.2.1",
+		"X-Echo-Forwarded":         "",
+	}
+	for h, v := range want {
+		if got := w.Header().Get(h); got != v {
+			t.Errorf("%s = %q, want %q", h, got, v)
+		}
+	}
+}
+
+func TestForwarded_TrustedPeerHonoured(t *testing.T) {
+	g := forwardedGateway(t, true)
+	w := httptest.NewRecorder()
+	g.handler.ServeHTTP(w, spoofedRequest())
+
+	want := map[string]string{
+		"X-Echo-X-Forwarded-For":   "6.6.6.6, 192.0.2.1",
+		"X-Echo-X-Forwarded-Proto": "https",
+		"X-Echo-X-Forwarded-Host":  "public.example.com",
+		"X-Echo-X-Real-Ip":         "6.6.6.6",
+		"X-Echo-Forwarded":         "for=6.6.6.6",
+	}
+	for h, v := range want {
+		if got := w.Header().Get(h); got != v {
+			t.Errorf("%s = %q, want %q", h, got, v)
+		}
+	}
+}
+
+func TestForwarded_OriginBehindProxy(t *testing.T) {
+	for _, trusted := range []bool{true, false} {
+		rt := NewFakeRuntime()
+		rt.AddContainer("app", FakeContainer{Status: "exited"})
+		cfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80"}}}
+		if trusted {
+			cfg.Gateway.TrustedProxies = []string{"192.0.2.0/24"}
+		}
+		g := newFakeGatewayConfig(t, rt, cfg)
+
+		// The proxy rewrote Host to the gateway's internal name.
+		r := httptest.NewRequest(http.MethodPost, "/_status/reset?container=app", nil)
+		r.Host = "gateway:8080"
+		r.Header.Set("Origin", "https://dash.example.com")
+		r.Header.Set("X-Forwarded-Host", "dash.example.com")
+		w := httptest.NewRecorder()
+		g.handler.ServeHTTP(w, r)
+
+		want := http.StatusForbidden
+		if trusted {
+			want = http.StatusOK
+		}
+		if w.Code != want {
+			t.Errorf("trusted=%v: status %d, want %d", trusted, w.Code, want)
+		}
+	}
+}
+
+func TestRequestScheme(t *testing.T) {
+	tests := []struct {
+		proto string
+		tls   bool
+		want  string
+	}{
+		{"", false, "http"},
+		{"", true, "https"},
+		{"https", false, "https"},
+		{"HTTPS, http", false, "https"},
+		{"gopher", false, "http"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.proto != "" {
+			r.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		if tt.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+		if got := requestScheme(r); got != tt.want {
+			t.Errorf("requestScheme(proto=%q, tls=%v) = %q, want %q", tt.proto, tt.tls, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync/atomic"
+)
+
+// Gateway is a complete gateway: the proxy server and every background loop
+// (discovery, idle watcher, schedules, notifiers, ...) built from one
+// configuration. It lets another Go program embed the gateway; the
+// docker-gateway binary is a thin main around it.
+type Gateway struct {
+	runtime     ContainerRuntime
+	ownsRuntime bool // created by New, closed when Run returns
+	manager     *ContainerManager
+	scheduler   *ScheduleManager
+	server      *Server
+	notifier    *NotificationManager
+	mqtt        *MQTTBridge
+	mdns        *MDNSResponder
+	dns         *DNSServer
+	discovery   *DiscoveryManager
+	started     atomic.Bool
+}
+
+// options collects the Option values given to New.
+type options struct {
+	runtime  ContainerRuntime
+	logger   *slog.Logger
+	listener net.Listener
+}
+
+// Option customises a Gateway built by New.
+type Option func(*options)
+
+// WithRuntime makes the gateway drive rt instead of a Docker client built
+// from gateway.docker_host. The caller keeps ownership of rt.
+func WithRuntime(rt ContainerRuntime) Option {
+	return func(o *options) { o.runtime = rt }
+}
+
+// WithLogger makes l the logger of the gateway. The package logs through
+// slog's default logger, so l becomes the process-wide default, wrapped so
+// records logged with a request context carry its request_id and trace_id.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) { o.logger = l }
+}
+
+// WithListener makes the gateway serve on ln instead of binding
+// gateway.port. A new gateway.port is then ignored on reload. Run closes ln
+// when it returns.
+func WithListener(ln net.Listener) Option {
+	return func(o *options) { o.listener = ln }
+}
+
+// New builds a Gateway from a validated configuration (see LoadConfig).
+// Nothing runs until Run is called.
+func New(cfg *GatewayConfig, opts ...Option) (*Gateway, error) {
+	if cfg == nil {
+		return nil, errors.New("gateway: nil configuration")
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger != nil {
+		slog.SetDefault(slog.New(NewContextHandler(o.logger.Handler())))
+	}
+
+	ConfigureLogLevel(cfg.Gateway.LogLevel)
+	ConfigureLogForwarding(cfg.Gateway.Syslog, cfg.Gateway.Loki)
+	ConfigureTracing(cfg.Gateway.Tracing)
+	ConfigureUpstream(cfg.Gateway.Upstream)
+
+	g := &Gateway{runtime: o.runtime}
+	if g.runtime == nil {
+		client, err := NewDockerClient(cfg.Gateway.DockerHost)
+		if err != nil {
+			return nil, fmt.Errorf("docker client: %w", err)
+		}
+		g.runtime, g.ownsRuntime = client, true
+	}
+
+	g.manager = NewContainerManager(g.runtime)
+	g.manager.SyncNetworkAttach(cfg.Gateway.NetworkAttach)
+	g.manager.SyncWakeOnLAN(cfg.Gateway.WakeOnLAN)
+	g.manager.SyncHA(cfg.Gateway.HA)
+	g.manager.SetReadOnly(cfg.Gateway.ReadOnly)
+	g.manager.SetDryRun(cfg.Gateway.DryRun)
+
+	// Forward container lifecycle events to the configured notifiers
+	g.notifier = NewNotificationManager()
+	g.notifier.Sync(cfg.Gateway.Notifications)
+	g.manager.Events().Subscribe(g.notifier.Handle)
+
+	g.scheduler = NewScheduleManager(g.runtime, g.manager)
+
+	server, err := NewServer(g.manager, g.scheduler, cfg)
+	if err != nil {
+		g.closeRuntime()
+		return nil, err
+	}
+	server.listener = o.listener
+	g.server = server
+
+	// Publish container states to MQTT / Home Assistant (disabled without a broker)
+	g.mqtt = NewMQTTBridge(g.manager, g.containers)
+	g.mqtt.Sync(cfg.Gateway.MQTT)
+	g.manager.Events().Subscribe(g.mqtt.Handle)
+
+	// Advertise the configured *.local hosts and answer DNS queries for them
+	// (both disabled by default)
+	g.mdns = NewMDNSResponder(server.GetConfig)
+	g.mdns.Sync(cfg.Gateway.MDNS)
+	g.dns = NewDNSServer(server.GetConfig)
+	g.dns.Sync(cfg.Gateway.DNS)
+
+	g.discovery = NewDiscoveryManager(g.runtime, cfg, g.applyConfig)
+	g.discovery.SetSharedState(g.manager.SharedState())
+	g.discovery.SetReloadLog(server.reloads)
+	server.staticConfig = g.discovery.StaticConfig
+	server.applyStatic = g.discovery.updateStaticConfig
+	return g, nil
+}
+
+// containers returns the containers of the active configuration.
+func (g *Gateway) containers() []ContainerConfig {
+	return g.server.GetConfig().Containers
+}
+
+// applyConfig hands a configuration merged by discovery to every component.
+// The server goes first: if it rejects the configuration nothing else
+// changes.
+func (g *Gateway) applyConfig(cfg *GatewayConfig) error {
+	if err := g.server.ReloadConfig(cfg); err != nil {
+		return err
+	}
+	g.notifier.Sync(cfg.Gateway.Notifications)
+	g.mqtt.Sync(cfg.Gateway.MQTT)
+	g.mdns.Sync(cfg.Gateway.MDNS)
+	g.dns.Sync(cfg.Gateway.DNS)
+	ConfigureTracing(cfg.Gateway.Tracing)
+	ConfigureLogLevel(cfg.Gateway.LogLevel)
+	ConfigureUpstream(cfg.Gateway.Upstream)
+	ConfigureLogForwarding(cfg.Gateway.Syslog, cfg.Gateway.Loki)
+	return nil
+}
+
+// Reload replaces the static configuration, as SIGHUP does for the binary,
+// and applies it with a discovery pass. If the result is invalid or cannot
+// be applied, the error is returned and the previous configuration stays
+// active. Either way the outcome is reported by /_admin/reload/status.
+func (g *Gateway) Reload(cfg *GatewayConfig) error {
+	return g.discovery.UpdateStaticConfig(cfg)
+}
+
+// ReloadFromFile loads config.yaml again (see LoadConfig) and applies it
+// with Reload. A file that does not load is reported like a rejected
+// reload.
+func (g *Gateway) ReloadFromFile() error {
+	cfg, err := LoadConfig()
+	if err != nil {
+		g.server.reloads.record(ReloadTriggerStatic, nil, nil, err)
+		return err
+	}
+	return g.Reload(cfg)
+}
+
+// Addr returns the address the gateway is listening on, or nil before Run
+// has bound it.
+func (g *Gateway) Addr() net.Addr {
+	return g.server.Addr()
+}
+
+// Run starts the background loops and serves until ctx is cancelled, then
+// shuts down gracefully. It may be called once.
+func (g *Gateway) Run(ctx context.Context) error {
+	if !g.started.CompareAndSwap(false, true) {
+		return errors.New("gateway: Run called twice")
+	}
+	defer g.closeRuntime()
+
+	cfg := g.server.GetConfig()
+	if cfg.Gateway.ReadOnly {
+		slog.Warn("read-only mode: containers are never started or stopped")
+	}
+	if cfg.Gateway.DryRun {
+		slog.Warn("dry-run mode: automatic starts and stops are logged, not executed")
+	}
+	if client, ok := g.runtime.(*DockerClient); ok && cfg.Gateway.DetectCapabilities {
+		LogCapabilities(client.DetectCapabilities(ctx))
+	}
+
+	StartLogForwarding(ctx)
+	StartTracing(ctx)
+	g.mqtt.Start(ctx)
+	g.mdns.Start(ctx)
+	g.dns.Start(ctx)
+
+	g.discovery.Start(ctx, cfg.Gateway.DiscoveryInterval)
+	slog.Info("discovery started", "interval", cfg.Gateway.DiscoveryInterval)
+
+	g.scheduler.Start(ctx)
+	schedLoc, _ := ResolveLocation(cfg.Gateway.ScheduleTimezone)
+	g.scheduler.Sync(cfg.Containers, schedLoc)
+	slog.Info("scheduler started")
+
+	// Exchange activity and start states with the other replicas (gateway.ha)
+	g.manager.StartSharedStateSync(ctx)
+	g.manager.StartIdleWatcher(ctx, g.server.GetConfig)
+	// Pre-start containers ahead of their usual busy hours (opt-in per container)
+	g.manager.StartPrewarmer(ctx, g.server.GetConfig)
+	// Lifecycle log behind the dashboard's availability figures
+	g.manager.StartAvailabilityLog(ctx, g.server.GetConfig)
+	// Self-healing, group member checks, image update checks and push
+	// monitors (opt-in per container or group)
+	g.manager.StartSelfHealer(ctx, g.containers)
+	g.manager.StartGroupHealthChecker(ctx, g.server.GetConfig)
+	g.manager.StartImageUpdateChecker(ctx, g.containers)
+	g.manager.StartPushMonitor(ctx, g.containers)
+	// Recompute Docker-derived gauges (group members running, ...)
+	g.manager.StartMetricsRefresher(ctx, g.server.GetConfig)
+	// Leave networks joined for backends that went to sleep (network_attach)
+	g.manager.StartNetworkDetacher(ctx, g.containers)
+
+	return g.server.Start(ctx)
+}
+
+// closeRuntime closes the Docker client New created.
+func (g *Gateway) closeRuntime() {
+	if client, ok := g.runtime.(*DockerClient); ok && g.ownsRuntime {
+		client.Close()
+	}
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew_Embedded(t *testing.T) {
+	host, port := newBackend(t, "hello from app")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+
+	cfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: port}}}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	gw, err := New(cfg, WithRuntime(rt), WithListener(ln), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- gw.Run(ctx) }()
+
+	// The listener is served as soon as Run gets to the server.
+	var resp *http.Response
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/", nil)
+		req.Host = "app.local"
+		resp, err = http.DefaultClient.Do(req)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello from app" {
+		t.Errorf("body = %q, want the backend's response", body)
+	}
+	if gw.Addr().String() != ln.Addr().String() {
+		t.Errorf("Addr = %v, want %v", gw.Addr(), ln.Addr())
+	}
+	if err := gw.Run(ctx); err == nil || !strings.Contains(err.Error(), "twice") {
+		t.Errorf("second Run: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run = %v, want nil after cancellation", err)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+func TestNew_NilConfig(t *testing.T) {
+	if _, err := New(nil, WithRuntime(NewFakeRuntime())); err == nil {
+		t.Error("New(nil) should fail")
+	}
+}
@@ -2,43 +2,193 @@ package gateway
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 	"sync/atomic"
 )
 
-// GroupRouter selects the next container from a group using a load-balancing strategy.
-// Currently supports round-robin; extensible to weighted strategies.
+// GroupRouter selects the next container from a group using a load-balancing
+// strategy: (weighted) round-robin, smooth weighted round-robin or least
+// connections.
 type GroupRouter struct {
 	mu       sync.Mutex
 	counters map[string]*atomic.Uint64
+	// smooth holds the current weights of "weighted" groups, by group name.
+	smooth map[string]*smoothState
+	// active counts the requests in flight per member, for
+	// "least_connections".
+	active map[string]*atomic.Int64
+}
+
+// smoothState is the state of smooth weighted round-robin for one group.
+type smoothState struct {
+	members []string
+	current []int
 }
 
 // NewGroupRouter creates a new GroupRouter.
 func NewGroupRouter() *GroupRouter {
 	return &GroupRouter{
 		counters: make(map[string]*atomic.Uint64),
+		smooth:   make(map[string]*smoothState),
+		active:   make(map[string]*atomic.Int64),
 	}
 }
 
-// Pick returns the next container name from the group via round-robin.
+// Pick returns the next container name from the group according to its
+// strategy.
 func (gr *GroupRouter) Pick(group *GroupConfig) string {
+	return gr.PickFunc(group, nil)
+}
+
+// PickFunc is like Pick but only returns members for which eligible reports
+// true. When no member is eligible (or eligible is nil) it falls back to
+// all members so the group never becomes unroutable.
+func (gr *GroupRouter) PickFunc(group *GroupConfig, eligible func(name string) bool) string {
 	if len(group.Containers) == 0 {
 		return ""
 	}
 	if len(group.Containers) == 1 {
 		return group.Containers[0]
 	}
+	switch group.Strategy {
+	case strategyWeighted:
+		return gr.pickSmooth(group, eligible)
+	case strategyLeastConnections:
+		return gr.pickLeastConnections(group, eligible)
+	}
+	return gr.pickRoundRobin(group, eligible)
+}
 
+// pickRoundRobin picks by round-robin. A member with weight n is picked n
+// times in a row per round.
+func (gr *GroupRouter) pickRoundRobin(group *GroupConfig, eligible func(name string) bool) string {
+	// Each member owns weight consecutive slots of a round.
+	var n uint64
+	for i := range group.Containers {
+		n += uint64(group.weight(i))
+	}
+	idx := gr.counter(group.Name).Add(1) - 1
+	if eligible != nil {
+		for i := uint64(0); i < n; i++ {
+			name := group.Containers[memberAt(group, (idx+i)%n)]
+			if eligible(name) {
+				return name
+			}
+		}
+	}
+	return group.Containers[memberAt(group, idx%n)]
+}
+
+// pickSmooth picks by smooth weighted round-robin, as nginx does: each
+// member gains its weight at every pick and the one ahead is picked and set
+// back by the total, which spreads a member's share through the round
+// (weights 3, 1, 1 give a, b, a, c, a).
+func (gr *GroupRouter) pickSmooth(group *GroupConfig, eligible func(name string) bool) string {
 	gr.mu.Lock()
-	counter, ok := gr.counters[group.Name]
+	defer gr.mu.Unlock()
+	st := gr.smooth[group.Name]
+	if st == nil || !slices.Equal(st.members, group.Containers) {
+		st = &smoothState{members: slices.Clone(group.Containers), current: make([]int, len(group.Containers))}
+		gr.smooth[group.Name] = st
+	}
+	candidates := func(i int) bool { return eligible == nil || eligible(group.Containers[i]) }
+	if eligible != nil && !slices.ContainsFunc(group.Containers, eligible) {
+		candidates = func(int) bool { return true }
+	}
+	best, total := -1, 0
+	for i := range group.Containers {
+		if !candidates(i) {
+			continue
+		}
+		w := group.weight(i)
+		st.current[i] += w
+		total += w
+		if best < 0 || st.current[i] > st.current[best] {
+			best = i
+		}
+	}
+	st.current[best] -= total
+	return group.Containers[best]
+}
+
+// pickLeastConnections picks the member with the fewest requests in flight
+// relative to its weight. Ties go round-robin, so an idle group still
+// spreads its requests.
+func (gr *GroupRouter) pickLeastConnections(group *GroupConfig, eligible func(name string) bool) string {
+	n := len(group.Containers)
+	start := int(gr.counter(group.Name).Add(1)-1) % n
+	pick := func(filter func(string) bool) string {
+		best, bestLoad, bestWeight := "", int64(0), int64(0)
+		for j := 0; j < n; j++ {
+			i := (start + j) % n
+			name := group.Containers[i]
+			if filter != nil && !filter(name) {
+				continue
+			}
+			load, w := gr.Active(name), int64(group.weight(i))
+			// load/w < bestLoad/bestWeight, without the division.
+			if best == "" || load*bestWeight < bestLoad*w {
+				best, bestLoad, bestWeight = name, load, w
+			}
+		}
+		return best
+	}
+	if eligible != nil {
+		if name := pick(eligible); name != "" {
+			return name
+		}
+	}
+	return pick(nil)
+}
+
+// Begin counts a request to the member name as in flight until the returned
+// function is called.
+func (gr *GroupRouter) Begin(name string) (done func()) {
+	gr.mu.Lock()
+	n, ok := gr.active[name]
 	if !ok {
-		counter = &atomic.Uint64{}
-		gr.counters[group.Name] = counter
+		n = &atomic.Int64{}
+		gr.active[name] = n
 	}
 	gr.mu.Unlock()
+	n.Add(1)
+	return func() { n.Add(-1) }
+}
+
+// Active returns the number of requests in flight to the member name.
+func (gr *GroupRouter) Active(name string) int64 {
+	gr.mu.Lock()
+	n := gr.active[name]
+	gr.mu.Unlock()
+	if n == nil {
+		return 0
+	}
+	return n.Load()
+}
+
+// counter returns the round-robin counter of the group name.
+func (gr *GroupRouter) counter(name string) *atomic.Uint64 {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	c, ok := gr.counters[name]
+	if !ok {
+		c = &atomic.Uint64{}
+		gr.counters[name] = c
+	}
+	return c
+}
 
-	idx := counter.Add(1) - 1
-	return group.Containers[idx%uint64(len(group.Containers))]
+// memberAt returns the index of the member owning slot of a weighted round.
+func memberAt(group *GroupConfig, slot uint64) int {
+	for i := range group.Containers {
+		w := uint64(group.weight(i))
+		if slot < w {
+			return i
+		}
+		slot -= w
+	}
+	return len(group.Containers) - 1
 }
 
 // TopologicalSort returns container names in dependency-first order for a target.
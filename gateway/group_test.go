@@ -1,9 +1,16 @@
 package gateway
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // ─── TopologicalSort ──────────────────────────────────────────────────────────
@@ -133,6 +140,21 @@ func TestGroupRouter_RoundRobin(t *testing.T) {
 		}
 	})
 
+	t.Run("weighted distribution", func(t *testing.T) {
+		group := &GroupConfig{
+			Name:       "weighted",
+			Members:    []GroupMember{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}},
+			Containers: []string{"a", "b"},
+		}
+		counts := make(map[string]int)
+		for i := 0; i < 400; i++ {
+			counts[gr.Pick(group)]++
+		}
+		if counts["a"] != 300 || counts["b"] != 100 {
+			t.Errorf("counts = %v, want a:300 b:100", counts)
+		}
+	})
+
 	t.Run("empty group returns empty", func(t *testing.T) {
 		group := &GroupConfig{Name: "empty", Containers: nil}
 		got := gr.Pick(group)
@@ -156,6 +178,121 @@ func TestGroupRouter_RoundRobin(t *testing.T) {
 	})
 }
 
+func TestGroupRouter_PickFunc(t *testing.T) {
+	gr := NewGroupRouter()
+
+	t.Run("skips ineligible members", func(t *testing.T) {
+		group := &GroupConfig{Name: "skip", Containers: []string{"a", "b", "c"}}
+		eligible := func(name string) bool { return name != "b" }
+		for i := 0; i < 30; i++ {
+			if got := gr.PickFunc(group, eligible); got == "b" {
+				t.Fatalf("PickFunc() returned ineligible member %q", got)
+			}
+		}
+	})
+
+	t.Run("falls back to round-robin when nothing is eligible", func(t *testing.T) {
+		group := &GroupConfig{Name: "none", Containers: []string{"a", "b"}}
+		counts := make(map[string]int)
+		for i := 0; i < 10; i++ {
+			counts[gr.PickFunc(group, func(string) bool { return false })]++
+		}
+		if counts["a"] != 5 || counts["b"] != 5 {
+			t.Errorf("fallback distribution = %v, want 5/5", counts)
+		}
+	})
+}
+
+func TestGroupRouter_Weighted(t *testing.T) {
+	gr := NewGroupRouter()
+	group := &GroupConfig{
+		Name:       "smooth",
+		Strategy:   strategyWeighted,
+		Members:    []GroupMember{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}, {Name: "c", Weight: 1}},
+		Containers: []string{"a", "b", "c"},
+	}
+	var got []string
+	for i := 0; i < 10; i++ {
+		got = append(got, gr.Pick(group))
+	}
+	if want := "a b a c a a b a c a"; strings.Join(got, " ") != want {
+		t.Errorf("picks = %v, want %s", got, want)
+	}
+
+	for i := 0; i < 10; i++ {
+		if name := gr.PickFunc(group, func(n string) bool { return n != "a" }); name == "a" {
+			t.Fatalf("PickFunc() returned ineligible member %q", name)
+		}
+	}
+}
+
+func TestGroupRouter_LeastConnections(t *testing.T) {
+	gr := NewGroupRouter()
+	group := &GroupConfig{Name: "lc", Strategy: strategyLeastConnections, Containers: []string{"a", "b", "c"}}
+
+	// Idle members take turns.
+	counts := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		counts[gr.Pick(group)]++
+	}
+	if counts["a"] != 10 || counts["b"] != 10 || counts["c"] != 10 {
+		t.Errorf("idle distribution = %v, want 10 each", counts)
+	}
+
+	doneA := gr.Begin("a")
+	doneB := gr.Begin("b")
+	gr.Begin("b")
+	for i := 0; i < 5; i++ {
+		if got := gr.Pick(group); got != "c" {
+			t.Fatalf("Pick() = %q, want the idle member c", got)
+		}
+	}
+	done := gr.Begin("c")
+	done()
+	if got := gr.PickFunc(group, func(n string) bool { return n != "c" }); got != "a" {
+		t.Errorf("PickFunc() = %q, want a, the least loaded eligible member", got)
+	}
+	doneA()
+	doneB()
+	if gr.Active("a") != 0 || gr.Active("b") != 1 {
+		t.Errorf("active = a:%d b:%d, want 0 and 1", gr.Active("a"), gr.Active("b"))
+	}
+
+	// Weights scale the load a member takes: 2 in flight on a weight-2
+	// member count as 1 on a weight-1 member.
+	weighted := &GroupConfig{
+		Name:       "lcw",
+		Strategy:   strategyLeastConnections,
+		Members:    []GroupMember{{Name: "x", Weight: 2}, {Name: "y", Weight: 1}},
+		Containers: []string{"x", "y"},
+	}
+	gr.Begin("x")
+	if got := gr.Pick(weighted); got != "y" {
+		t.Errorf("Pick() = %q, want y while x has 1 of 2", got)
+	}
+	gr.Begin("y")
+	if got := gr.Pick(weighted); got != "x" {
+		t.Errorf("Pick() = %q, want x at half its weight", got)
+	}
+}
+
+func TestValidate_GroupStrategy(t *testing.T) {
+	cfg := &GatewayConfig{
+		Containers: []ContainerConfig{{Name: "a"}, {Name: "b"}},
+		Groups:     []GroupConfig{{Name: "g", Host: "g.local", Strategy: "random", Members: []GroupMember{{Name: "a"}, {Name: "b"}}}},
+	}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `unknown strategy "random"`) {
+		t.Errorf("error = %v, want unknown strategy", err)
+	}
+	for _, strategy := range []string{strategyRoundRobin, strategyWeighted, strategyLeastConnections} {
+		cfg.Groups[0].Strategy = strategy
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("strategy %q: %v", strategy, err)
+		}
+	}
+}
+
 // ─── BuildGroupHostIndex ──────────────────────────────────────────────────────
 
 func TestBuildGroupHostIndex(t *testing.T) {
@@ -360,6 +497,61 @@ func TestValidate_DependsOn(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "parallel start order with stagger",
+			cfg: GatewayConfig{
+				Gateway:    GlobalConfig{Port: "8080"},
+				Containers: []ContainerConfig{{Name: "node-1", TargetPort: "80"}, {Name: "node-2", TargetPort: "80"}},
+				Groups: []GroupConfig{
+					{Name: "cluster", Host: "c.local", Containers: []string{"node-1", "node-2"}, StartOrder: "parallel", StartStagger: 5 * time.Second},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown start order",
+			cfg: GatewayConfig{
+				Gateway:    GlobalConfig{Port: "8080"},
+				Containers: []ContainerConfig{{Name: "node-1", TargetPort: "80"}},
+				Groups: []GroupConfig{
+					{Name: "cluster", Host: "c.local", Containers: []string{"node-1"}, StartOrder: "random"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hedge with relative path",
+			cfg: GatewayConfig{
+				Gateway:    GlobalConfig{Port: "8080"},
+				Containers: []ContainerConfig{{Name: "node-1", TargetPort: "80"}},
+				Groups: []GroupConfig{
+					{Name: "cluster", Host: "c.local", Containers: []string{"node-1"}, Hedge: HedgeConfig{Delay: time.Second, Paths: []string{"api"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative hedge delay",
+			cfg: GatewayConfig{
+				Gateway:    GlobalConfig{Port: "8080"},
+				Containers: []ContainerConfig{{Name: "node-1", TargetPort: "80"}},
+				Groups: []GroupConfig{
+					{Name: "cluster", Host: "c.local", Containers: []string{"node-1"}, Hedge: HedgeConfig{Delay: -time.Second}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative start stagger",
+			cfg: GatewayConfig{
+				Gateway:    GlobalConfig{Port: "8080"},
+				Containers: []ContainerConfig{{Name: "node-1", TargetPort: "80"}},
+				Groups: []GroupConfig{
+					{Name: "cluster", Host: "c.local", Containers: []string{"node-1"}, StartStagger: -time.Second},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -385,6 +577,9 @@ func TestApplyDefaults_Groups(t *testing.T) {
 	if cfg.Groups[0].Strategy != "round-robin" {
 		t.Errorf("Strategy = %q, want %q", cfg.Groups[0].Strategy, "round-robin")
 	}
+	if cfg.Groups[0].StartOrder != "sequential" {
+		t.Errorf("StartOrder = %q, want %q", cfg.Groups[0].StartOrder, "sequential")
+	}
 }
 
 func TestApplyDefaults_GroupExplicitStrategy(t *testing.T) {
@@ -400,6 +595,85 @@ func TestApplyDefaults_GroupExplicitStrategy(t *testing.T) {
 	}
 }
 
+// ─── EnsureGroupRunning start order ───────────────────────────────────────────
+
+// failingStartDaemon is a Docker API stub reporting every container as
+// stopped and failing every start, recording the start attempts.
+func failingStartDaemon(t *testing.T) (*ContainerManager, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var names []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/start") {
+			mu.Lock()
+			names = append(names, path.Base(path.Dir(r.URL.Path)))
+			mu.Unlock()
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"boom"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"State":{"Status":"exited"},"Config":{"Image":"x"}}`))
+	}))
+	t.Cleanup(daemon.Close)
+	m := NewContainerManager(newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String()))
+	return m, func() []string { mu.Lock(); defer mu.Unlock(); return append([]string(nil), names...) }
+}
+
+func TestEnsureGroupRunning_StartOrder(t *testing.T) {
+	all := []ContainerConfig{{Name: "node-1"}, {Name: "node-2"}, {Name: "node-3"}}
+
+	t.Run("sequential stops at the first failing member", func(t *testing.T) {
+		m, started := failingStartDaemon(t)
+		group := &GroupConfig{Name: "cluster", Containers: []string{"node-1", "node-2", "node-3"}, StartOrder: "sequential"}
+		err := m.EnsureGroupRunning(context.Background(), group, all)
+		if err == nil || !strings.Contains(err.Error(), `member "node-1"`) {
+			t.Fatalf("error = %v, want node-1 failure", err)
+		}
+		if got := started(); len(got) != 1 || got[0] != "node-1" {
+			t.Errorf("started = %v, want only [node-1]", got)
+		}
+	})
+
+	t.Run("parallel starts every member", func(t *testing.T) {
+		m, started := failingStartDaemon(t)
+		group := &GroupConfig{Name: "cluster", Containers: []string{"node-1", "node-2", "node-3"}, StartOrder: "parallel"}
+		err := m.EnsureGroupRunning(context.Background(), group, all)
+		if err == nil || !strings.Contains(err.Error(), `member "node-1"`) {
+			t.Fatalf("error = %v, want the first member's failure", err)
+		}
+		if got := started(); len(got) != 3 {
+			t.Errorf("started = %v, want all three members", got)
+		}
+	})
+
+	t.Run("stagger starts the members in order", func(t *testing.T) {
+		m, started := failingStartDaemon(t)
+		members := []string{"node-1", "node-2", "node-3"}
+		group := &GroupConfig{Name: "cluster", Containers: members, StartOrder: "parallel", StartStagger: 20 * time.Millisecond}
+		m.EnsureGroupRunning(context.Background(), group, all)
+		// Without a stagger parallel members race; with one the daemon
+		// sees them in Containers order.
+		if got := started(); !slices.Equal(got, members) {
+			t.Errorf("started = %v, want %v", got, members)
+		}
+	})
+
+	t.Run("stagger wait honours cancellation", func(t *testing.T) {
+		m, started := failingStartDaemon(t)
+		group := &GroupConfig{Name: "cluster", Containers: []string{"node-1", "node-2"}, StartOrder: "parallel", StartStagger: time.Hour}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := m.EnsureGroupRunning(ctx, group, all); err == nil {
+			t.Fatal("EnsureGroupRunning succeeded after the context expired")
+		}
+		if got := started(); len(got) != 1 {
+			t.Errorf("started = %v, want only the first member", got)
+		}
+	})
+}
+
 // ─── MergeConfigs preserves DependsOn ─────────────────────────────────────────
 
 func TestMergeConfigs_PreservesDependsOn(t *testing.T) {
@@ -477,3 +751,96 @@ groups:
 func writeFile(path, content string) error {
 	return os.WriteFile(path, []byte(content), 0644)
 }
+
+func TestLoadConfig_GroupMemberOverrides(t *testing.T) {
+	yamlContent := `
+gateway:
+  port: "8080"
+containers:
+  - name: "api-1"
+    target_port: "8080"
+    health_path: "/healthz"
+    idle_timeout: 10m
+    depends_on: ["db"]
+  - name: "db"
+    target_port: "5432"
+groups:
+  - name: "api-cluster"
+    host: "api.local"
+    containers:
+      - "api-1"
+      - name: "api-2"
+        target_port: "8081"
+      - name: "api-3"
+        weight: 2
+        health_path: "/ready"
+`
+	path := t.TempDir() + "/config.yaml"
+	if err := writeFile(path, yamlContent); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_PATH", path)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	g := cfg.Groups[0]
+	if want := []string{"api-1", "api-2", "api-3"}; !slices.Equal(g.Containers, want) {
+		t.Errorf("group containers = %v, want %v", g.Containers, want)
+	}
+	if g.weight(0) != 1 || g.weight(2) != 2 {
+		t.Errorf("weights = (%d, %d), want (1, 2)", g.weight(0), g.weight(2))
+	}
+
+	byName := make(map[string]ContainerConfig)
+	for _, c := range cfg.Containers {
+		byName[c.Name] = c
+	}
+	tests := []struct {
+		name, port, health string
+	}{
+		{"api-1", "8080", "/healthz"},
+		{"api-2", "8081", "/healthz"},
+		{"api-3", "8080", "/ready"},
+	}
+	for _, tt := range tests {
+		c, ok := byName[tt.name]
+		if !ok {
+			t.Errorf("container %q not declared", tt.name)
+			continue
+		}
+		if c.TargetPort != tt.port || c.HealthPath != tt.health {
+			t.Errorf("%s = (port %q, health %q), want (%q, %q)", tt.name, c.TargetPort, c.HealthPath, tt.port, tt.health)
+		}
+		if c.IdleTimeout != 10*time.Minute || len(c.DependsOn) != 1 {
+			t.Errorf("%s did not inherit api-1's settings: %+v", tt.name, c)
+		}
+	}
+}
+
+func TestValidate_GroupMembers(t *testing.T) {
+	base := func(members ...GroupMember) GatewayConfig {
+		cfg := GatewayConfig{
+			Gateway:    GlobalConfig{Port: "8080"},
+			Containers: []ContainerConfig{{Name: "api-1", TargetPort: "80"}},
+			Groups:     []GroupConfig{{Name: "api", Host: "api.local", Members: members}},
+		}
+		applyDefaults(&cfg)
+		return cfg
+	}
+
+	if cfg := base(GroupMember{Name: "api-1"}, GroupMember{Name: "api-2", Weight: 3}); cfg.Validate() != nil {
+		t.Errorf("Validate() = %v, want nil for a declared member", cfg.Validate())
+	}
+	if cfg := base(GroupMember{Name: "api-1", Weight: -1}); cfg.Validate() == nil {
+		t.Error("Validate() accepted a negative weight")
+	}
+	if cfg := base(GroupMember{Name: "api-1"}, GroupMember{TargetPort: "81"}); cfg.Validate() == nil {
+		t.Error("Validate() accepted a member without name")
+	}
+	if cfg := base(GroupMember{Name: "api-9"}); cfg.Validate() == nil {
+		t.Error("Validate() accepted a group without any declared member")
+	}
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// groupHealthTick is the granularity of the group health checker. Group
+// health_interval values are rounded up to a multiple of it.
+const groupHealthTick = time.Second
+
+// groupHealthFailures is how many active checks in a row a running member
+// must fail to be taken out of its group's rotation. A member that is not
+// running is down at once.
+const groupHealthFailures = 2
+
+// StartGroupHealthChecker begins a background routine that checks the
+// members of groups with health_interval > 0, with the readiness check of
+// each member, so the group router skips members that are down instead of
+// sending requests to them.
+func (m *ContainerManager) StartGroupHealthChecker(ctx context.Context, configProvider func() *GatewayConfig) {
+	go func() {
+		ticker := time.NewTicker(groupHealthTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkGroupHealth(ctx, configProvider())
+			}
+		}
+	}()
+}
+
+func (m *ContainerManager) checkGroupHealth(ctx context.Context, cfg *GatewayConfig) {
+	now := time.Now()
+	containers := BuildContainerMap(cfg)
+	checked := make(map[string]bool)
+	for _, g := range cfg.Groups {
+		if g.HealthInterval <= 0 {
+			continue
+		}
+		for _, name := range g.Containers {
+			c, ok := containers[name]
+			if !ok || checked[name] {
+				continue
+			}
+			checked[name] = true
+			if !m.health.checkDue(name, g.HealthInterval, now) {
+				continue
+			}
+			// A member the gateway is starting is neither up nor down yet.
+			if status, _ := m.GetStartState(name); status == string(statusStarting) {
+				continue
+			}
+			if status, err := m.client.GetContainerStatus(ctx, name); err != nil || status != "running" {
+				m.recordGroupCheck(g.Name, name, false, 1)
+				continue
+			}
+			passed := m.healthCheckOnce(ctx, c)
+			if !passed {
+				RecordHealthCheckFailure(name)
+			}
+			m.recordGroupCheck(g.Name, name, passed, groupHealthFailures)
+		}
+	}
+	m.health.forgetActive(checked)
+}
+
+// recordGroupCheck records the outcome of the check of member and logs a
+// change of its state.
+func (m *ContainerManager) recordGroupCheck(group, member string, passed bool, threshold int) {
+	if !m.health.RecordCheck(member, passed, threshold) {
+		return
+	}
+	if passed {
+		slog.Info("group member passed its health check, back in rotation", "group", group, "container", member)
+	} else {
+		slog.Warn("group member is down, taken out of rotation", "group", group, "container", member)
+	}
+}
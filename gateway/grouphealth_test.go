@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthTracker_RecordCheck(t *testing.T) {
+	h := NewHealthTracker()
+	if h.RecordCheck("a", false, 2) || h.IsDown("a") {
+		t.Fatal("down after one failure of two")
+	}
+	if !h.RecordCheck("a", false, 2) || !h.IsDown("a") || h.Routable("a") {
+		t.Fatal("not down after two failures")
+	}
+	if h.RecordCheck("a", false, 2) {
+		t.Error("transition reported twice")
+	}
+	if !h.RecordCheck("a", true, 2) || h.IsDown("a") || !h.Routable("a") {
+		t.Error("not back up after a pass")
+	}
+
+	now := time.Now()
+	if !h.checkDue("b", time.Minute, now) || h.checkDue("b", time.Minute, now.Add(time.Second)) ||
+		!h.checkDue("b", time.Minute, now.Add(time.Minute)) {
+		t.Error("checkDue does not follow the interval")
+	}
+}
+
+func TestCheckGroupHealth(t *testing.T) {
+	upHost, upPort := newBackend(t, "up")
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	deadHost, deadPort, _ := net.SplitHostPort(dead.Listener.Addr().String())
+
+	rt := NewFakeRuntime()
+	rt.AddContainer("a", FakeContainer{Status: "running", Host: upHost, Port: upPort})
+	rt.AddContainer("b", FakeContainer{Status: "running", Host: deadHost, Port: deadPort})
+	rt.AddContainer("c", FakeContainer{Status: "exited"})
+	rt.AddContainer("d", FakeContainer{Status: "running", Host: deadHost, Port: deadPort})
+	cfg := &GatewayConfig{
+		Containers: []ContainerConfig{
+			{Name: "a", TargetPort: upPort}, {Name: "b", TargetPort: deadPort},
+			{Name: "c", TargetPort: "80"}, {Name: "d", TargetPort: deadPort},
+		},
+		Groups: []GroupConfig{
+			{Name: "pool", Host: "pool.local", HealthInterval: time.Nanosecond,
+				Members: []GroupMember{{Name: "a"}, {Name: "b"}, {Name: "c"}}},
+			{Name: "unchecked", Host: "other.local", Members: []GroupMember{{Name: "d"}}},
+		},
+	}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	m := NewContainerManager(rt)
+
+	m.checkGroupHealth(context.Background(), cfg)
+	if m.health.IsDown("a") || m.health.IsDown("b") || !m.health.IsDown("c") {
+		t.Errorf("after one check: down a=%v b=%v c=%v, want only the stopped c",
+			m.health.IsDown("a"), m.health.IsDown("b"), m.health.IsDown("c"))
+	}
+	m.checkGroupHealth(context.Background(), cfg)
+	if m.health.IsDown("a") || !m.health.IsDown("b") || m.health.IsDown("d") {
+		t.Errorf("after two checks: down a=%v b=%v d=%v, want b down",
+			m.health.IsDown("a"), m.health.IsDown("b"), m.health.IsDown("d"))
+	}
+
+	gr := NewGroupRouter()
+	for i := 0; i < 6; i++ {
+		if got := gr.PickFunc(&cfg.Groups[0], m.health.Routable); got != "a" {
+			t.Fatalf("PickFunc() = %q, want a, the only member up", got)
+		}
+	}
+
+	// Without health_interval the members are forgotten, not kept down.
+	cfg.Groups[0].HealthInterval = 0
+	m.checkGroupHealth(context.Background(), cfg)
+	if m.health.IsDown("b") || m.health.IsDown("c") {
+		t.Error("members kept down after health_interval was turned off")
+	}
+}
@@ -0,0 +1,519 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// haSyncInterval is how often activity and start states are exchanged
+	// with the other replicas, and the leader lease renewed.
+	haSyncInterval = 2 * time.Second
+	// haLeaseTTL is how long a leader lease lasts without renewal: a replica
+	// that dies is replaced as leader within this delay.
+	haLeaseTTL = 15 * time.Second
+	// haRetryBackoff is how long request-path commands skip an unhealthy
+	// store before one of them tries it again; it doubles with every failed
+	// try, up to haMaxRetryBackoff. The background sync keeps trying every
+	// haSyncInterval and ends the backoff as soon as the store answers.
+	haRetryBackoff    = time.Second
+	haMaxRetryBackoff = 30 * time.Second
+)
+
+// SharedState shares what must agree across gateway replicas (gateway.ha):
+// last activity per container, start states, a start lock per container and
+// rate-limit counters. Every method is a no-op while HA is disabled, and
+// Redis errors fall back to the replica's local view: a store outage
+// degrades to independent replicas, never to failed requests.
+//
+// Keys, under the configured prefix:
+//
+//	<prefix>:seen               hash  "<container>|<instance>" → unix ms
+//	<prefix>:start              hash  container → JSON start state
+//	<prefix>:lock:start:<name>  string instance holding the start, with TTL
+//	<prefix>:rl:<endpoint>:<ip> counter of the current rate-limit window
+//	<prefix>:leader             string instance holding the leader lease
+//	<prefix>:discovered         string JSON containers found by the leader
+type SharedState struct {
+	mu      sync.Mutex
+	cfg     HAConfig
+	client  *redisClient
+	healthy bool
+	retryAt time.Time             // request-path commands skip the store until then
+	backoff time.Duration         // current retry delay while unhealthy
+	pending map[string]startState // local start states not yet pushed
+	pushed  map[string]time.Time  // last activity pushed per container
+	remote  map[string]startState // start states pulled from the store
+
+	leader     bool      // this replica holds the leader lease
+	leaseUntil time.Time // when the lease expires unless renewed
+}
+
+func NewSharedState() *SharedState {
+	return &SharedState{
+		healthy: true,
+		pending: make(map[string]startState),
+		pushed:  make(map[string]time.Time),
+		remote:  make(map[string]startState),
+	}
+}
+
+// Sync applies a new HA configuration, reconnecting when the store changed.
+func (s *SharedState) Sync(cfg HAConfig) {
+	if cfg.InstanceID == "" {
+		cfg.InstanceID, _ = os.Hostname()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cfg == s.cfg {
+		return
+	}
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+	if cfg.Redis != "" {
+		s.client = newRedisClient(cfg.Redis, cfg.Password)
+		slog.Info("ha: sharing state through redis", "instance", cfg.InstanceID, "prefix", cfg.KeyPrefix)
+	}
+	s.cfg = cfg
+	s.pushed = make(map[string]time.Time)
+	s.remote = make(map[string]startState)
+	s.leader = false
+	s.healthy, s.retryAt, s.backoff = true, time.Time{}, 0
+}
+
+// store returns the client and configuration, or a nil client when HA is
+// disabled.
+func (s *SharedState) store() (*redisClient, HAConfig) {
+	if s == nil {
+		return nil, HAConfig{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client, s.cfg
+}
+
+// available reports whether a request-path command should try the store.
+// While it is unhealthy only one command per backoff period does, so an
+// outage costs a single request the dial timeout instead of every request.
+func (s *SharedState) available() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.healthy {
+		return true
+	}
+	now := time.Now()
+	if now.Before(s.retryAt) {
+		return false
+	}
+	s.retryAt = now.Add(max(s.backoff, haRetryBackoff))
+	return true
+}
+
+func (cfg HAConfig) key(parts ...string) string {
+	return cfg.KeyPrefix + ":" + strings.Join(parts, ":")
+}
+
+// report logs store availability changes once, not on every failed command,
+// and backs request-path commands off while the store fails.
+func (s *SharedState) report(err error) {
+	s.mu.Lock()
+	changed := s.healthy != (err == nil)
+	s.healthy = err == nil
+	if err != nil {
+		s.backoff = min(max(2*s.backoff, haRetryBackoff), haMaxRetryBackoff)
+		s.retryAt = time.Now().Add(s.backoff)
+	} else {
+		s.backoff = 0
+	}
+	s.mu.Unlock()
+	switch {
+	case !changed:
+	case err != nil:
+		slog.Warn("ha: shared state unavailable, using local state", "error", err)
+	default:
+		slog.Info("ha: shared state available again")
+	}
+}
+
+// ─── Start states ─────────────────────────────────────────────────────────────
+
+// publishStartState queues a local start state for the next sync.
+func (s *SharedState) publishStartState(name string, st startState) {
+	if c, _ := s.store(); c == nil {
+		return
+	}
+	s.mu.Lock()
+	s.pending[name] = st
+	s.mu.Unlock()
+}
+
+// remoteStartState returns the latest start state of name written by any
+// replica, as of the last sync.
+func (s *SharedState) remoteStartState(name string) (startState, bool) {
+	if s == nil {
+		return startState{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.remote[name]
+	return st, ok
+}
+
+// sharedStartState is the stored form of a startState.
+type sharedStartState struct {
+	Status startStatus `json:"s"`
+	Err    string      `json:"e,omitempty"`
+	At     int64       `json:"t"` // unix ms
+}
+
+func (s *SharedState) syncStartStates(ctx context.Context, c *redisClient, cfg HAConfig) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]startState)
+	s.mu.Unlock()
+
+	if len(pending) > 0 {
+		args := []string{"HSET", cfg.key("start")}
+		for name, st := range pending {
+			b, _ := json.Marshal(sharedStartState{Status: st.Status, Err: st.Err, At: st.At.UnixMilli()})
+			args = append(args, name, string(b))
+		}
+		if _, err := c.Do(ctx, args...); err != nil {
+			// Requeue unless a newer local state was recorded meanwhile.
+			s.mu.Lock()
+			for name, st := range pending {
+				if _, ok := s.pending[name]; !ok {
+					s.pending[name] = st
+				}
+			}
+			s.mu.Unlock()
+			return err
+		}
+	}
+
+	fields, err := redisHash(c.Do(ctx, "HGETALL", cfg.key("start")))
+	if err != nil {
+		return err
+	}
+	remote := make(map[string]startState, len(fields))
+	for name, raw := range fields {
+		var st sharedStartState
+		if json.Unmarshal([]byte(raw), &st) == nil {
+			remote[name] = startState{Status: st.Status, Err: st.Err, At: time.UnixMilli(st.At)}
+		}
+	}
+	s.mu.Lock()
+	s.remote = remote
+	s.mu.Unlock()
+	return nil
+}
+
+// ─── Activity ─────────────────────────────────────────────────────────────────
+
+// syncActivity pushes the local activity that changed since the last sync
+// and returns the latest activity per container across all replicas.
+func (s *SharedState) syncActivity(ctx context.Context, c *redisClient, cfg HAConfig, local map[string]time.Time) (map[string]time.Time, error) {
+	s.mu.Lock()
+	args := []string{"HSET", cfg.key("seen")}
+	for name, t := range local {
+		if t.After(s.pushed[name]) {
+			args = append(args, name+"|"+cfg.InstanceID, strconv.FormatInt(t.UnixMilli(), 10))
+		}
+	}
+	s.mu.Unlock()
+
+	if len(args) > 2 {
+		if _, err := c.Do(ctx, args...); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		for name, t := range local {
+			if t.After(s.pushed[name]) {
+				s.pushed[name] = t
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	fields, err := redisHash(c.Do(ctx, "HGETALL", cfg.key("seen")))
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]time.Time)
+	for field, raw := range fields {
+		name, _, ok := strings.Cut(field, "|")
+		ms, err := strconv.ParseInt(raw, 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		if t := time.UnixMilli(ms); t.After(latest[name]) {
+			latest[name] = t
+		}
+	}
+	// Activity learnt from other replicas is merged locally; it must not be
+	// pushed again under this replica's name.
+	s.mu.Lock()
+	for name, t := range latest {
+		if t.After(s.pushed[name]) {
+			s.pushed[name] = t
+		}
+	}
+	s.mu.Unlock()
+	return latest, nil
+}
+
+// ─── Start lock ───────────────────────────────────────────────────────────────
+
+// acquireStart takes the cluster-wide start lock of name for ttl. It returns
+// false only when another replica holds it; without HA, or when the store is
+// unreachable or backed off after failing, every replica may start the
+// container itself.
+func (s *SharedState) acquireStart(ctx context.Context, name string, ttl time.Duration) bool {
+	c, cfg := s.store()
+	if c == nil || !s.available() {
+		return true
+	}
+	ms := strconv.FormatInt(max(ttl, time.Second).Milliseconds(), 10)
+	reply, err := c.Do(ctx, "SET", cfg.key("lock", "start", name), cfg.InstanceID, "NX", "PX", ms)
+	s.report(err)
+	return err != nil || reply != nil
+}
+
+// releaseStartScript deletes the lock in KEYS[1] if ARGV[1] still holds it.
+// The check and the delete happen in one step, so a lock that expired and
+// was taken by another replica is never dropped.
+const releaseStartScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end return 0`
+
+// releaseStart frees the start lock of name if this replica still holds it.
+func (s *SharedState) releaseStart(ctx context.Context, name string) {
+	c, cfg := s.store()
+	if c == nil || !s.available() {
+		return
+	}
+	_, err := c.Do(ctx, "EVAL", releaseStartScript, "1", cfg.key("lock", "start", name), cfg.InstanceID)
+	s.report(err)
+}
+
+// ─── Leadership ───────────────────────────────────────────────────────────────
+
+// isLeader reports whether this replica runs the cluster-wide duties: idle
+// stops and Docker discovery. Without HA every replica is the leader. A
+// leader that cannot renew its lease, e.g. while the store is unreachable,
+// steps down when the lease expires, so two replicas never act as leader
+// at the same time.
+func (s *SharedState) isLeader() bool {
+	if c, _ := s.store(); c == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leader && time.Now().Before(s.leaseUntil)
+}
+
+// renewLeaseScript extends the lease in KEYS[1] by ARGV[2] ms if ARGV[1]
+// still holds it. The check and the renewal must be one step: a lease that
+// expired in between may already be another replica's, and extending it
+// would leave two leaders.
+const renewLeaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end return 0`
+
+// campaign renews the leader lease held by this replica, or takes it when
+// it is free.
+func (s *SharedState) campaign(ctx context.Context, c *redisClient, cfg HAConfig) error {
+	key := cfg.key("leader")
+	ttl := strconv.FormatInt(haLeaseTTL.Milliseconds(), 10)
+	start := time.Now()
+
+	s.mu.Lock()
+	wasLeader := s.leader
+	s.mu.Unlock()
+
+	var leader bool
+	if wasLeader {
+		renewed, err := c.Do(ctx, "EVAL", renewLeaseScript, "1", key, cfg.InstanceID, ttl)
+		if err != nil {
+			return err
+		}
+		leader = renewed == int64(1)
+	}
+	if !leader {
+		reply, err := c.Do(ctx, "SET", key, cfg.InstanceID, "NX", "PX", ttl)
+		if err != nil {
+			return err
+		}
+		leader = reply != nil
+	}
+
+	s.mu.Lock()
+	s.leader = leader
+	if leader {
+		s.leaseUntil = start.Add(haLeaseTTL)
+	}
+	s.mu.Unlock()
+	switch {
+	case leader && !wasLeader:
+		slog.Info("ha: this replica is now the leader", "instance", cfg.InstanceID)
+	case !leader && wasLeader:
+		slog.Warn("ha: leadership lost", "instance", cfg.InstanceID)
+	}
+	return nil
+}
+
+// publishDiscovered shares the containers found by the leader's discovery.
+// It is skipped while the store is backed off after failing.
+func (s *SharedState) publishDiscovered(ctx context.Context, containers []ContainerConfig) {
+	c, cfg := s.store()
+	if c == nil || !s.available() {
+		return
+	}
+	b, err := json.Marshal(containers)
+	if err != nil {
+		return
+	}
+	_, err = c.Do(ctx, "SET", cfg.key("discovered"), string(b))
+	s.report(err)
+}
+
+// discovered returns the containers last published by the leader. ok is
+// false when the replica must query Docker itself: HA disabled, store
+// unreachable or backed off after failing, or nothing published yet.
+func (s *SharedState) discovered(ctx context.Context) (containers []ContainerConfig, ok bool) {
+	c, cfg := s.store()
+	if c == nil || !s.available() {
+		return nil, false
+	}
+	reply, err := c.Do(ctx, "GET", cfg.key("discovered"))
+	s.report(err)
+	raw, isString := reply.(string)
+	if err != nil || !isString {
+		return nil, false
+	}
+	if err := json.Unmarshal([]byte(raw), &containers); err != nil {
+		return nil, false
+	}
+	return containers, true
+}
+
+// ─── Rate limiting ────────────────────────────────────────────────────────────
+
+// rateWindowScript counts a request in the window KEYS[1] and gives a new
+// window a lifetime of ARGV[1] ms. Both happen in one step: a counter left
+// behind without an expiry by a replica that died in between would limit
+// the client for good.
+const rateWindowScript = `local n = redis.call('INCR', KEYS[1]) if redis.call('PTTL', KEYS[1]) < 0 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end return n`
+
+// allowRate counts a request of ip to endpoint in a window shared by all
+// replicas: up to p.Burst requests per Burst/Rate seconds, which has the
+// burst and long-run rate of the local token bucket. ok is false when the
+// local limiter must decide instead (HA disabled, or store unreachable or
+// backed off after failing).
+func (s *SharedState) allowRate(ctx context.Context, endpoint, ip string, p RateLimitPolicy) (allowed, ok bool) {
+	c, cfg := s.store()
+	if c == nil || !s.available() {
+		return false, false
+	}
+	window := time.Second
+	if p.Rate > 0 {
+		window = max(window, time.Duration(math.Ceil(float64(p.Burst)/p.Rate*1000))*time.Millisecond)
+	}
+	key := cfg.key("rl", endpoint, ip)
+	reply, err := c.Do(ctx, "EVAL", rateWindowScript, "1", key, strconv.FormatInt(window.Milliseconds(), 10))
+	s.report(err)
+	if err != nil {
+		return false, false
+	}
+	n, _ := reply.(int64)
+	return n <= int64(p.Burst), true
+}
+
+// redisHash converts an HGETALL reply into a map.
+func redisHash(reply any, err error) (map[string]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items)%2 != 0 {
+		return nil, errors.New("redis: unexpected HGETALL reply")
+	}
+	m := make(map[string]string, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		k, _ := items[i].(string)
+		v, _ := items[i+1].(string)
+		m[k] = v
+	}
+	return m, nil
+}
+
+// ─── ContainerManager integration ────────────────────────────────────────────
+
+// SyncHA applies the gateway.ha configuration.
+func (m *ContainerManager) SyncHA(cfg HAConfig) {
+	m.shared.Sync(cfg)
+}
+
+// SharedState returns the HA state shared with the other replicas.
+func (m *ContainerManager) SharedState() *SharedState {
+	return m.shared
+}
+
+// StartSharedStateSync periodically exchanges activity and start states with
+// the other replicas and renews the leader lease. Activity seen by any
+// replica counts for the idle watcher and /_status of all of them.
+func (m *ContainerManager) StartSharedStateSync(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(haSyncInterval)
+		defer ticker.Stop()
+		m.syncShared(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.syncShared(ctx)
+			}
+		}
+	}()
+}
+
+func (m *ContainerManager) syncShared(ctx context.Context) {
+	c, cfg := m.shared.store()
+	if c == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, haSyncInterval)
+	defer cancel()
+
+	err := m.shared.syncStartStates(ctx, c, cfg)
+	if err == nil {
+		m.mu.Lock()
+		local := make(map[string]time.Time, len(m.lastSeen))
+		for k, v := range m.lastSeen {
+			local[k] = v
+		}
+		m.mu.Unlock()
+
+		var latest map[string]time.Time
+		if latest, err = m.shared.syncActivity(ctx, c, cfg, local); err == nil {
+			m.mu.Lock()
+			for name, t := range latest {
+				if t.After(m.lastSeen[name]) {
+					m.lastSeen[name] = t
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+	if err == nil {
+		err = m.shared.campaign(ctx, c, cfg)
+	}
+	m.shared.report(err)
+}
@@ -0,0 +1,376 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHAManager returns a manager sharing state through srv as instance id.
+func newHAManager(srv *fakeRedis, id string) *ContainerManager {
+	m := NewContainerManager(NewFakeRuntime())
+	m.SyncHA(HAConfig{Redis: srv.url(), KeyPrefix: "dag", InstanceID: id})
+	return m
+}
+
+// ─── Disabled ─────────────────────────────────────────────────────────────────
+
+func TestSharedState_Disabled(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range map[string]*SharedState{"nil": nil, "unconfigured": NewSharedState()} {
+		t.Run(name, func(t *testing.T) {
+			if !s.acquireStart(ctx, "app", time.Minute) {
+				t.Error("acquireStart = false, want true without HA")
+			}
+			if _, ok := s.allowRate(ctx, "health", "1.2.3.4", RateLimitPolicy{Rate: 1, Burst: 1}); ok {
+				t.Error("allowRate decided without HA, want local fallback")
+			}
+			if _, ok := s.remoteStartState("app"); ok {
+				t.Error("remoteStartState found a state without HA")
+			}
+		})
+	}
+}
+
+func TestSharedState_StoreUnreachable(t *testing.T) {
+	ln := newFakeRedis(t, "")
+	addr := ln.url()
+	ln.ln.Close() // nothing listens there any more
+
+	s := NewSharedState()
+	s.Sync(HAConfig{Redis: addr, KeyPrefix: "dag", InstanceID: "a"})
+	ctx := context.Background()
+	if !s.acquireStart(ctx, "app", time.Minute) {
+		t.Error("acquireStart = false, want true when the store is unreachable")
+	}
+	if _, ok := s.allowRate(ctx, "health", "1.2.3.4", RateLimitPolicy{Rate: 1, Burst: 1}); ok {
+		t.Error("allowRate decided with the store unreachable, want local fallback")
+	}
+}
+
+// newHangingStore starts a store that accepts connections but never answers,
+// and counts the connections made to it.
+func newHangingStore(t *testing.T) (string, *atomic.Int32) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	accepted := new(atomic.Int32)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return ln.Addr().String(), accepted
+}
+
+func TestSharedState_BacksOffUnhealthyStore(t *testing.T) {
+	addr, accepted := newHangingStore(t)
+	s := NewSharedState()
+	s.Sync(HAConfig{Redis: addr, KeyPrefix: "dag", InstanceID: "a"})
+	p := RateLimitPolicy{Rate: 1, Burst: 1}
+	try := func() (bool, time.Duration) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, ok := s.allowRate(ctx, "health", "1.2.3.4", p)
+		return ok, time.Since(start)
+	}
+
+	if ok, _ := try(); ok {
+		t.Fatal("allowRate decided with the store hanging, want local fallback")
+	}
+	// Within the backoff the store is not tried at all.
+	for range 5 {
+		if ok, took := try(); ok || took > 10*time.Millisecond {
+			t.Fatalf("allowRate during backoff = (ok %v, took %v), want an immediate local fallback", ok, took)
+		}
+	}
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("store dialled %d times during backoff, want 1", n)
+	}
+
+	// Once the backoff is over a single request tries again.
+	s.mu.Lock()
+	s.retryAt = time.Now()
+	s.mu.Unlock()
+	try()
+	try()
+	waitFor(t, func() bool { return accepted.Load() == 2 })
+	if n := accepted.Load(); n != 2 {
+		t.Errorf("store dialled %d times after the backoff, want 2", n)
+	}
+}
+
+// ─── Start lock ───────────────────────────────────────────────────────────────
+
+func TestSharedState_StartLockBacksOffUnhealthyStore(t *testing.T) {
+	addr, accepted := newHangingStore(t)
+	s := NewSharedState()
+	s.Sync(HAConfig{Redis: addr, KeyPrefix: "dag", InstanceID: "a"})
+	try := func() (bool, time.Duration) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		ok := s.acquireStart(ctx, "app", time.Minute)
+		s.releaseStart(ctx, "app")
+		return ok, time.Since(start)
+	}
+
+	if ok, _ := try(); !ok {
+		t.Fatal("acquireStart = false with the store hanging, want a local start")
+	}
+	// Within the backoff neither call waits on the store.
+	if ok, took := try(); !ok || took > 10*time.Millisecond {
+		t.Fatalf("acquireStart during backoff = (%v, took %v), want an immediate local start", ok, took)
+	}
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("store dialled %d times, want 1", n)
+	}
+}
+
+func TestSharedState_StartLock(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	a, b := newHAManager(srv, "a").shared, newHAManager(srv, "b").shared
+	ctx := context.Background()
+
+	if !a.acquireStart(ctx, "app", 30*time.Second) {
+		t.Fatal("first acquireStart = false, want true")
+	}
+	if b.acquireStart(ctx, "app", 30*time.Second) {
+		t.Fatal("second replica acquired a held lock")
+	}
+	if !b.acquireStart(ctx, "other", 30*time.Second) {
+		t.Error("lock of another container is held")
+	}
+
+	// Only the holder may release the lock.
+	b.releaseStart(ctx, "app")
+	if b.acquireStart(ctx, "app", 30*time.Second) {
+		t.Fatal("lock released by a replica that does not hold it")
+	}
+	a.releaseStart(ctx, "app")
+	if !b.acquireStart(ctx, "app", 30*time.Second) {
+		t.Error("acquireStart after release = false, want true")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if got := srv.expiries["dag:lock:start:app"]; got != "30000" {
+		t.Errorf("lock TTL = %q ms, want 30000", got)
+	}
+}
+
+// ─── Start states and activity ────────────────────────────────────────────────
+
+func TestSharedState_StartStates(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	a, b := newHAManager(srv, "a"), newHAManager(srv, "b")
+	ctx := context.Background()
+
+	a.setStartState("app", statusFailed, "boom")
+	a.syncShared(ctx)
+	b.syncShared(ctx)
+	if status, errMsg := b.GetStartState("app"); status != "failed" || errMsg != "boom" {
+		t.Errorf("replica b state = (%q, %q), want (failed, boom)", status, errMsg)
+	}
+
+	// A newer local state wins over the shared one.
+	time.Sleep(5 * time.Millisecond)
+	b.setStartState("app", statusStarting, "")
+	if status, _ := b.GetStartState("app"); status != "starting" {
+		t.Errorf("replica b state = %q, want its newer local state starting", status)
+	}
+}
+
+func TestSharedState_Activity(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	a, b := newHAManager(srv, "a"), newHAManager(srv, "b")
+	ctx := context.Background()
+
+	seen := time.Now().Truncate(time.Millisecond)
+	a.mu.Lock()
+	a.lastSeen["app"] = seen
+	a.mu.Unlock()
+	b.mu.Lock()
+	b.lastSeen["app"] = seen.Add(-time.Hour)
+	b.mu.Unlock()
+
+	a.syncShared(ctx)
+	b.syncShared(ctx)
+
+	b.mu.Lock()
+	got := b.lastSeen["app"]
+	b.mu.Unlock()
+	if !got.Equal(seen) {
+		t.Errorf("replica b lastSeen = %v, want the newer activity of replica a %v", got, seen)
+	}
+
+	// Activity learnt from replica a is not pushed back under b's name.
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if v := srv.hashes["dag:seen"]["app|b"]; v != strconv.FormatInt(seen.Add(-time.Hour).UnixMilli(), 10) {
+		t.Errorf("app|b = %q, want replica b's own activity", v)
+	}
+}
+
+// ─── Rate limiting ────────────────────────────────────────────────────────────
+
+func TestRateLimiter_Shared(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	limits := RateLimitConfig{Health: RateLimitPolicy{Rate: 1, Burst: 2}}
+	a, b := newRateLimiter(limits), newRateLimiter(limits)
+	a.shared = newHAManager(srv, "a").shared
+	b.shared = newHAManager(srv, "b").shared
+
+	// The burst is shared: two requests on a exhaust it for b too.
+	if !a.Allow("health", "10.0.0.1") || !a.Allow("health", "10.0.0.1") {
+		t.Fatal("requests within the burst were limited")
+	}
+	if b.Allow("health", "10.0.0.1") {
+		t.Error("replica b allowed a request past the shared burst")
+	}
+	if !b.Allow("health", "10.0.0.2") {
+		t.Error("another client was limited")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if got := srv.expiries["dag:rl:health:10.0.0.1"]; got != "2000" {
+		t.Errorf("window = %q ms, want 2000 (burst / rate)", got)
+	}
+	// Counting and expiring are one step: a replica dying in between must
+	// not leave a counter that never expires.
+	for _, cmd := range srv.commands {
+		if strings.HasPrefix(cmd, "INCR ") || strings.HasPrefix(cmd, "PEXPIRE dag:rl:") {
+			t.Errorf("window counted with a separate %q, want one atomic EVAL", cmd)
+		}
+	}
+}
+
+// ─── Leadership ───────────────────────────────────────────────────────────────
+
+func TestSharedState_Leadership(t *testing.T) {
+	if !NewSharedState().isLeader() {
+		t.Error("isLeader = false without HA, want true")
+	}
+
+	srv := newFakeRedis(t, "")
+	a, b := newHAManager(srv, "a"), newHAManager(srv, "b")
+	ctx := context.Background()
+
+	if a.shared.isLeader() {
+		t.Fatal("replica a is leader before campaigning")
+	}
+	a.syncShared(ctx)
+	b.syncShared(ctx)
+	if !a.shared.isLeader() || b.shared.isLeader() {
+		t.Fatalf("leaders = (a %v, b %v), want only a", a.shared.isLeader(), b.shared.isLeader())
+	}
+
+	// Renewing keeps the lease; b still cannot take it.
+	srv.mu.Lock()
+	srv.commands = nil
+	srv.mu.Unlock()
+	a.syncShared(ctx)
+	b.syncShared(ctx)
+	if !a.shared.isLeader() || b.shared.isLeader() {
+		t.Fatalf("after renewal leaders = (a %v, b %v), want only a", a.shared.isLeader(), b.shared.isLeader())
+	}
+	// The owner check and the renewal are one step: a lease that expires
+	// between them must not be extended for whoever took it.
+	srv.mu.Lock()
+	for _, cmd := range srv.commands {
+		if strings.HasPrefix(cmd, "PEXPIRE dag:leader") {
+			t.Errorf("lease renewed with a separate %q, want one atomic EVAL", cmd)
+		}
+	}
+	srv.mu.Unlock()
+
+	// The lease expired and b took it: a steps down on its next renewal,
+	// without extending b's lease.
+	srv.mu.Lock()
+	delete(srv.strings, "dag:leader")
+	srv.mu.Unlock()
+	b.syncShared(ctx)
+	srv.mu.Lock()
+	srv.expiries["dag:leader"] = "b's lease"
+	srv.mu.Unlock()
+	a.syncShared(ctx)
+	if a.shared.isLeader() || !b.shared.isLeader() {
+		t.Errorf("after takeover leaders = (a %v, b %v), want only b", a.shared.isLeader(), b.shared.isLeader())
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if got := srv.expiries["dag:leader"]; got != "b's lease" {
+		t.Errorf("lease expiry = %q, want b's lease left alone", got)
+	}
+}
+
+func TestSharedState_LeaseExpiresWhenStoreUnreachable(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	m := newHAManager(srv, "a")
+	m.syncShared(context.Background())
+	if !m.shared.isLeader() {
+		t.Fatal("replica is not leader after campaigning")
+	}
+
+	// Without renewals the lease runs out instead of lasting forever.
+	m.shared.mu.Lock()
+	m.shared.leaseUntil = time.Now().Add(-time.Second)
+	m.shared.mu.Unlock()
+	if m.shared.isLeader() {
+		t.Error("replica still leader after its lease expired")
+	}
+}
+
+func TestSharedState_Discovered(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	a, b := newHAManager(srv, "a").shared, newHAManager(srv, "b").shared
+	ctx := context.Background()
+
+	if _, ok := b.discovered(ctx); ok {
+		t.Fatal("discovered found a list before any was published")
+	}
+	want := []ContainerConfig{{Name: "app", Host: "app.example.com", IdleTimeout: time.Minute, Discovered: true}}
+	a.publishDiscovered(ctx, want)
+	got, ok := b.discovered(ctx)
+	if !ok || len(got) != 1 || got[0].Name != "app" || got[0].IdleTimeout != time.Minute || !got[0].Discovered {
+		t.Errorf("discovered = (%+v, %v), want %+v", got, ok, want)
+	}
+}
+
+func TestSharedState_DiscoveredBacksOffUnhealthyStore(t *testing.T) {
+	addr, accepted := newHangingStore(t)
+	s := NewSharedState()
+	s.Sync(HAConfig{Redis: addr, KeyPrefix: "dag", InstanceID: "a"})
+	try := func() (bool, time.Duration) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		s.publishDiscovered(ctx, []ContainerConfig{{Name: "app"}})
+		_, ok := s.discovered(ctx)
+		return ok, time.Since(start)
+	}
+
+	if ok, _ := try(); ok {
+		t.Fatal("discovered = ok with the store hanging, want a local discovery")
+	}
+	// Within the backoff neither call waits on the store.
+	if ok, took := try(); ok || took > 10*time.Millisecond {
+		t.Fatalf("discovered during backoff = (%v, took %v), want an immediate local discovery", ok, took)
+	}
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("store dialled %d times, want 1", n)
+	}
+}
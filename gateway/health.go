@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Passive health states reported in /_status/api.
+const (
+	healthHealthy  = "healthy"
+	healthDegraded = "degraded"
+	// healthDown marks a group member failing its active health checks
+	// (group health_interval).
+	healthDown = "down"
+)
+
+// passiveHealth holds the proxy outcome streak of a single container.
+type passiveHealth struct {
+	consecutiveFailures int
+	degraded            bool
+	degradedAt          time.Time
+}
+
+// activeHealth holds the active health check history of a group member.
+type activeHealth struct {
+	lastCheck time.Time
+	failures  int
+	down      bool
+}
+
+// degradedRetryInterval is how often a degraded group member is offered a
+// single request so it can prove it has recovered.
+const degradedRetryInterval = 30 * time.Second
+
+// HealthTracker derives a passive health signal from proxy results: a
+// container whose requests keep failing (connect errors, 502/504) is marked
+// degraded until a request succeeds again or the container is restarted.
+//
+// It also keeps the outcome of the active checks of group members: a member
+// failing them is down and left out of its group's rotation until a check
+// passes.
+type HealthTracker struct {
+	mu     sync.Mutex
+	states map[string]*passiveHealth
+	active map[string]*activeHealth
+}
+
+// NewHealthTracker creates an empty HealthTracker.
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{states: make(map[string]*passiveHealth), active: make(map[string]*activeHealth)}
+}
+
+// isProxyFailure reports whether a proxied response status counts as a
+// backend failure for passive health checking.
+func isProxyFailure(statusCode int) bool {
+	return statusCode == http.StatusBadGateway || statusCode == http.StatusGatewayTimeout
+}
+
+// RecordSuccess clears the failure streak and the degraded flag.
+func (h *HealthTracker) RecordSuccess(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.states[name]; ok {
+		s.consecutiveFailures = 0
+		s.degraded = false
+	}
+}
+
+// RecordFailure extends the failure streak. It returns true exactly once, on
+// the failure that pushes the streak to threshold and marks the container
+// degraded. A threshold <= 0 disables degradation.
+func (h *HealthTracker) RecordFailure(name string, threshold int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.states[name]
+	if !ok {
+		s = &passiveHealth{}
+		h.states[name] = s
+	}
+	s.consecutiveFailures++
+	if threshold <= 0 || s.degraded || s.consecutiveFailures < threshold {
+		return false
+	}
+	s.degraded = true
+	s.degradedAt = time.Now()
+	return true
+}
+
+// IsDegraded reports whether the container is currently marked degraded.
+func (h *HealthTracker) IsDegraded(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.states[name]
+	return ok && s.degraded
+}
+
+// Routable reports whether a group may send traffic to the container. A
+// member down by its active checks is not; healthy containers are; a
+// degraded one becomes routable again for a single request every
+// degradedRetryInterval.
+func (h *HealthTracker) Routable(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if a, ok := h.active[name]; ok && a.down {
+		return false
+	}
+	s, ok := h.states[name]
+	if !ok || !s.degraded {
+		return true
+	}
+	if time.Since(s.degradedAt) >= degradedRetryInterval {
+		s.degradedAt = time.Now()
+		return true
+	}
+	return false
+}
+
+// Failures returns the current consecutive failure count for a container.
+func (h *HealthTracker) Failures(name string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.states[name]; ok {
+		return s.consecutiveFailures
+	}
+	return 0
+}
+
+// Reset forgets all passive health data for a container, e.g. after a restart.
+func (h *HealthTracker) Reset(name string) {
+	h.mu.Lock()
+	delete(h.states, name)
+	h.mu.Unlock()
+}
+
+// checkDue reports whether the active check of a container is due after
+// interval and marks it checked.
+func (h *HealthTracker) checkDue(name string, interval time.Duration, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	a, ok := h.active[name]
+	if !ok {
+		a = &activeHealth{}
+		h.active[name] = a
+	}
+	if now.Sub(a.lastCheck) < interval {
+		return false
+	}
+	a.lastCheck = now
+	return true
+}
+
+// RecordCheck records the outcome of an active check. The container is down
+// after threshold failures in a row and up again after a pass; changed
+// reports a transition.
+func (h *HealthTracker) RecordCheck(name string, passed bool, threshold int) (changed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	a, ok := h.active[name]
+	if !ok {
+		a = &activeHealth{}
+		h.active[name] = a
+	}
+	if passed {
+		a.failures = 0
+		changed, a.down = a.down, false
+		return changed
+	}
+	a.failures++
+	if a.down || a.failures < threshold {
+		return false
+	}
+	a.down = true
+	return true
+}
+
+// IsDown reports whether active checks took the container out of its group.
+func (h *HealthTracker) IsDown(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	a, ok := h.active[name]
+	return ok && a.down
+}
+
+// forgetActive drops the active check state of containers no longer checked,
+// so a member leaving a group with health_interval is not kept down.
+func (h *HealthTracker) forgetActive(keep map[string]bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for name := range h.active {
+		if !keep[name] {
+			delete(h.active, name)
+		}
+	}
+}
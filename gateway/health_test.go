@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// ─── HealthTracker ────────────────────────────────────────────────────────────
+
+func TestHealthTracker(t *testing.T) {
+	t.Run("degrades exactly once at threshold", func(t *testing.T) {
+		h := NewHealthTracker()
+		if h.RecordFailure("app", 3) || h.RecordFailure("app", 3) {
+			t.Fatal("RecordFailure() reported degraded before threshold")
+		}
+		if !h.RecordFailure("app", 3) {
+			t.Fatal("RecordFailure() should report degraded at threshold")
+		}
+		if h.RecordFailure("app", 3) {
+			t.Error("RecordFailure() should not report degraded twice")
+		}
+		if !h.IsDegraded("app") {
+			t.Error("IsDegraded() = false, want true")
+		}
+		if h.Failures("app") != 4 {
+			t.Errorf("Failures() = %d, want 4", h.Failures("app"))
+		}
+	})
+
+	t.Run("success clears degraded state", func(t *testing.T) {
+		h := NewHealthTracker()
+		h.RecordFailure("app", 1)
+		h.RecordSuccess("app")
+		if h.IsDegraded("app") || h.Failures("app") != 0 {
+			t.Error("RecordSuccess() should reset the streak and degraded flag")
+		}
+	})
+
+	t.Run("zero threshold never degrades", func(t *testing.T) {
+		h := NewHealthTracker()
+		for i := 0; i < 10; i++ {
+			if h.RecordFailure("app", 0) {
+				t.Fatal("RecordFailure() with threshold 0 should never degrade")
+			}
+		}
+		if h.IsDegraded("app") {
+			t.Error("IsDegraded() = true, want false")
+		}
+	})
+
+	t.Run("routable retries a degraded member after the interval", func(t *testing.T) {
+		h := NewHealthTracker()
+		if !h.Routable("unknown") {
+			t.Error("unknown container should be routable")
+		}
+		h.RecordFailure("app", 1)
+		if h.Routable("app") {
+			t.Error("freshly degraded container should not be routable")
+		}
+		h.mu.Lock()
+		h.states["app"].degradedAt = time.Now().Add(-degradedRetryInterval)
+		h.mu.Unlock()
+		if !h.Routable("app") {
+			t.Error("degraded container should be routable once the retry interval elapsed")
+		}
+		if h.Routable("app") {
+			t.Error("only a single retry request should be let through per interval")
+		}
+	})
+
+	t.Run("reset forgets state", func(t *testing.T) {
+		h := NewHealthTracker()
+		h.RecordFailure("app", 1)
+		h.Reset("app")
+		if h.IsDegraded("app") || h.Failures("app") != 0 {
+			t.Error("Reset() should clear all state")
+		}
+	})
+}
+
+func TestIsProxyFailure(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+		http.StatusInternalServerError: false,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  false,
+		http.StatusGatewayTimeout:      true,
+	} {
+		if got := isProxyFailure(code); got != want {
+			t.Errorf("isProxyFailure(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
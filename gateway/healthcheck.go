@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunHealthcheckCommand runs `healthcheck`: a GET of /_gateway/healthz (or
+// /_gateway/readyz with -ready) on the local gateway, failing unless it
+// answers 200. It lets the image's HEALTHCHECK work without curl or wget.
+func RunHealthcheckCommand(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	baseURL := fs.String("url", "", "gateway base URL (default: http://127.0.0.1:<gateway.port>)")
+	ready := fs.Bool("ready", false, "probe /_gateway/readyz instead of /_gateway/healthz")
+	timeout := fs.Duration("timeout", 3*time.Second, "request timeout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *baseURL == "" {
+		*baseURL = "http://127.0.0.1:" + healthcheckPort()
+	}
+	path := "/_gateway/healthz"
+	if *ready {
+		path = "/_gateway/readyz"
+	}
+	target := strings.TrimRight(*baseURL, "/") + path
+
+	client := &http.Client{Timeout: *timeout}
+	resp, err := client.Get(target)
+	if err != nil {
+		return fmt.Errorf("healthcheck: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("healthcheck: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, err = out.Write(body)
+	return err
+}
+
+// healthcheckPort returns gateway.port from the configuration file, or the
+// default port. Only that key is read: a config file the running gateway
+// refused on reload must not make the healthcheck fail.
+func healthcheckPort() string {
+	var cfg struct {
+		Gateway struct {
+			Port string `yaml:"port"`
+		} `yaml:"gateway"`
+	}
+	if data, err := os.ReadFile(configPath()); err == nil {
+		yaml.Unmarshal(data, &cfg)
+	}
+	if cfg.Gateway.Port == "" {
+		return "8080"
+	}
+	return cfg.Gateway.Port
+}
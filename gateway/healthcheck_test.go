@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunHealthcheckCommand(t *testing.T) {
+	ready := true
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_gateway/healthz":
+			writeSelfCheck(w, selfCheckResponse{Status: "ok"})
+		case "/_gateway/readyz":
+			if ready {
+				writeSelfCheck(w, selfCheckResponse{Status: "ok"})
+			} else {
+				writeSelfCheck(w, selfCheckResponse{Status: "unavailable", Checks: map[string]string{"docker": "unreachable"}})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer gw.Close()
+
+	var out bytes.Buffer
+	if err := RunHealthcheckCommand([]string{"-url", gw.URL}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "{\"status\":\"ok\"}\n" {
+		t.Errorf("output = %q", out.String())
+	}
+
+	if err := RunHealthcheckCommand([]string{"-url", gw.URL + "/", "-ready"}, &out); err != nil {
+		t.Errorf("ready: %v", err)
+	}
+	ready = false
+	if err := RunHealthcheckCommand([]string{"-url", gw.URL, "-ready"}, &out); err == nil {
+		t.Error("an unready gateway should fail -ready")
+	}
+
+	addr := gw.URL
+	gw.Close()
+	if err := RunHealthcheckCommand([]string{"-url", addr}, &out); err == nil {
+		t.Error("an unreachable gateway should fail")
+	}
+}
+
+func TestHealthcheckPort(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CONFIG_PATH", filepath.Join(dir, "missing.yaml"))
+	if got := healthcheckPort(); got != "8080" {
+		t.Errorf("without config: port = %q, want 8080", got)
+	}
+
+	// An otherwise invalid config still yields the port.
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("gateway:\n  port: \"9090\"\ncontainers:\n  - name: \"\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_PATH", path)
+	if got := healthcheckPort(); got != "9090" {
+		t.Errorf("port = %q, want 9090", got)
+	}
+}
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// hedgePlan tells proxyRequest to hedge a group request: when the picked
+// member has not answered within delay, the request is also sent to the
+// address returned by second, and the first response wins.
+type hedgePlan struct {
+	group  string
+	delay  time.Duration
+	second func(ctx context.Context) (addr string, err error)
+}
+
+type hedgeCtxKey struct{}
+
+// withHedge returns a copy of ctx that makes proxyRequest hedge with plan.
+func withHedge(ctx context.Context, plan *hedgePlan) context.Context {
+	return context.WithValue(ctx, hedgeCtxKey{}, plan)
+}
+
+// hedgeFromContext returns the hedge plan of ctx, or nil.
+func hedgeFromContext(ctx context.Context) *hedgePlan {
+	p, _ := ctx.Value(hedgeCtxKey{}).(*hedgePlan)
+	return p
+}
+
+// hedgeable reports whether r may be hedged under cfg: only body-less GET and
+// HEAD requests (safe to send twice) on one of cfg.Paths, when set.
+func hedgeable(r *http.Request, cfg HedgeConfig) bool {
+	if cfg.Delay <= 0 || (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+		r.ContentLength != 0 || isWebSocketRequest(r) {
+		return false
+	}
+	if len(cfg.Paths) == 0 {
+		return true
+	}
+	for _, p := range cfg.Paths {
+		if strings.HasPrefix(r.URL.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// hedgeResult is the outcome of one attempt of a hedged request.
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	attempt int // 0 for the picked member, 1 for the hedge
+}
+
+// hedgeTransport sends a request through base and, if no response arrived
+// after the plan's delay, a copy of it to the second member. The first
+// response wins; the other attempt is cancelled. An error does not trigger
+// the hedge: it is returned unless the other attempt is still running.
+type hedgeTransport struct {
+	base http.RoundTripper
+	plan *hedgePlan
+}
+
+func (t *hedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	send := func(r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := t.base.RoundTrip(r.WithContext(ctx))
+			results <- hedgeResult{resp: resp, err: err, attempt: attempt}
+		}()
+	}
+	send(req)
+	inflight := 1
+
+	timer := time.NewTimer(t.plan.delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			addr, err := t.plan.second(req.Context())
+			if err != nil {
+				continue // no member to hedge with: keep waiting
+			}
+			hreq := req.Clone(req.Context())
+			if hreq.Host == req.URL.Host {
+				hreq.Host = addr
+			}
+			hreq.URL.Host = addr
+			send(hreq)
+			inflight++
+		case res := <-results:
+			inflight--
+			if res.err != nil && inflight > 0 {
+				continue // the other attempt may still answer
+			}
+			// Cancel the losing attempt and close its late response.
+			for i, cancel := range cancels {
+				if i != res.attempt {
+					cancel()
+				}
+			}
+			go func(n int) {
+				for ; n > 0; n-- {
+					if lost := <-results; lost.resp != nil {
+						lost.resp.Body.Close()
+					}
+				}
+			}(inflight)
+			if len(cancels) > 1 {
+				winner := "primary"
+				if res.attempt == 1 {
+					winner = "hedge"
+				}
+				RecordGroupHedge(t.plan.group, winner)
+			}
+			if res.err != nil {
+				cancels[res.attempt]()
+				return nil, res.err
+			}
+			res.resp.Body = &cancelOnClose{ReadCloser: res.resp.Body, cancel: cancels[res.attempt]}
+			return res.resp, nil
+		}
+	}
+}
+
+// cancelOnClose releases the context of a winning attempt once its body is
+// consumed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgeable(t *testing.T) {
+	on := HedgeConfig{Delay: 50 * time.Millisecond}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		cfg    HedgeConfig
+		want   bool
+	}{
+		{"get", http.MethodGet, "/", "", on, true},
+		{"head", http.MethodHead, "/", "", on, true},
+		{"disabled", http.MethodGet, "/", "", HedgeConfig{}, false},
+		{"post", http.MethodPost, "/", "", on, false},
+		{"get with body", http.MethodGet, "/", "x", on, false},
+		{"path listed", http.MethodGet, "/api/search?q=1", "", HedgeConfig{Delay: time.Second, Paths: []string{"/api/search"}}, true},
+		{"path not listed", http.MethodGet, "/api/orders", "", HedgeConfig{Delay: time.Second, Paths: []string{"/api/search"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			r := httptest.NewRequest(tt.method, tt.path, body)
+			if got := hedgeable(r, tt.cfg); got != tt.want {
+				t.Errorf("hedgeable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// hedgeBackend answers with name after delay, and records whether the
+// request was cancelled first.
+func hedgeBackend(t *testing.T, name string, delay time.Duration, cancelled *atomic.Bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			io.WriteString(w, name)
+		case <-r.Context().Done():
+			cancelled.Store(true)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func hedgeGet(t *testing.T, primary *httptest.Server, plan *hedgePlan) (string, error) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, primary.URL, nil)
+	tr := &hedgeTransport{base: http.DefaultTransport, plan: plan}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), nil
+}
+
+func TestHedgeTransport(t *testing.T) {
+	t.Run("slow member is hedged and cancelled", func(t *testing.T) {
+		var slowCancelled, fastCancelled atomic.Bool
+		slow := hedgeBackend(t, "slow", 5*time.Second, &slowCancelled)
+		fast := hedgeBackend(t, "fast", 0, &fastCancelled)
+		plan := &hedgePlan{group: "api", delay: 20 * time.Millisecond, second: func(context.Context) (string, error) {
+			return fast.Listener.Addr().String(), nil
+		}}
+
+		got, err := hedgeGet(t, slow, plan)
+		if err != nil || got != "fast" {
+			t.Fatalf("response = (%q, %v), want the hedge's", got, err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for !slowCancelled.Load() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if !slowCancelled.Load() {
+			t.Error("losing attempt was not cancelled")
+		}
+	})
+
+	t.Run("fast member is not hedged", func(t *testing.T) {
+		var cancelled atomic.Bool
+		primary := hedgeBackend(t, "primary", 0, &cancelled)
+		var hedged atomic.Bool
+		plan := &hedgePlan{group: "api", delay: time.Second, second: func(context.Context) (string, error) {
+			hedged.Store(true)
+			return "", errors.New("unused")
+		}}
+
+		got, err := hedgeGet(t, primary, plan)
+		if err != nil || got != "primary" {
+			t.Fatalf("response = (%q, %v), want the primary's", got, err)
+		}
+		if hedged.Load() {
+			t.Error("a request answered before the delay was hedged")
+		}
+	})
+
+	t.Run("no member to hedge with keeps waiting", func(t *testing.T) {
+		var cancelled atomic.Bool
+		primary := hedgeBackend(t, "primary", 50*time.Millisecond, &cancelled)
+		plan := &hedgePlan{group: "api", delay: 10 * time.Millisecond, second: func(context.Context) (string, error) {
+			return "", errors.New("no other member")
+		}}
+
+		if got, err := hedgeGet(t, primary, plan); err != nil || got != "primary" {
+			t.Fatalf("response = (%q, %v), want the primary's", got, err)
+		}
+	})
+
+	t.Run("errors are not hedged", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close() // connection refused
+		var hedged atomic.Bool
+		plan := &hedgePlan{group: "api", delay: time.Second, second: func(context.Context) (string, error) {
+			hedged.Store(true)
+			return "", errors.New("unused")
+		}}
+
+		if _, err := hedgeGet(t, down, plan); err == nil {
+			t.Fatal("RoundTrip succeeded against a closed backend")
+		}
+		if hedged.Load() {
+			t.Error("a failed request was hedged")
+		}
+	})
+}
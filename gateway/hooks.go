@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Hook stages, as sent in webhook bodies and shown in errors.
+const (
+	hookPreStart  = "pre_start"
+	hookPostReady = "post_ready"
+	hookPreStop   = "pre_stop"
+	hookPostStop  = "post_stop"
+)
+
+// maxHookOutput caps the command output quoted in a hook error.
+const maxHookOutput = 200
+
+// hookClient sends webhook hooks; each hook's timeout bounds its request.
+var hookClient = &http.Client{}
+
+// execFunc runs a command in a container; DockerClient.ExecCommand in
+// production.
+type execFunc func(ctx context.Context, container string, cmd []string) (output string, exitCode int, err error)
+
+// runHooks runs the hooks of one stage in order and stops at the first
+// failure, returning it with the hook's position.
+func runHooks(ctx context.Context, exec execFunc, name, stage string, hooks []HookConfig) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	ctx, span := StartSpan(ctx, "container.hooks")
+	defer span.End()
+	span.SetAttr("gateway.hook_stage", stage)
+
+	for i, h := range hooks {
+		err := runHook(ctx, exec, name, stage, h)
+		if err != nil {
+			span.SetError(err)
+			return fmt.Errorf("%s hook %d failed: %w", stage, i+1, err)
+		}
+		slog.Debug("hook done", "container", name, "stage", stage, "hook", i+1)
+	}
+	return nil
+}
+
+// runPreStopHooks runs the hooks preceding an idle stop. Every hook runs: a
+// failing hook with veto set cancels the stop (its error is returned once
+// all hooks ran), other failures are only logged.
+func runPreStopHooks(ctx context.Context, exec execFunc, name string, hooks []HookConfig) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	ctx, span := StartSpan(ctx, "container.hooks")
+	defer span.End()
+	span.SetAttr("gateway.hook_stage", hookPreStop)
+
+	var veto error
+	for i, h := range hooks {
+		err := runHook(ctx, exec, name, hookPreStop, h)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("%s hook %d failed: %w", hookPreStop, i+1, err)
+		span.SetError(err)
+		if h.Veto && veto == nil {
+			veto = err
+			continue
+		}
+		slog.Warn("idle watcher: pre-stop hook failed", "container", name, "error", err)
+	}
+	return veto
+}
+
+// runHook runs a single hook within its timeout.
+func runHook(ctx context.Context, exec execFunc, name, stage string, h HookConfig) error {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	if h.URL != "" {
+		body, _ := json.Marshal(map[string]string{"container": name, "hook": stage})
+		req, err := http.NewRequestWithContext(ctx, strings.ToUpper(h.Method), h.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := hookClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("%s returned status %d", h.URL, resp.StatusCode)
+		}
+		return nil
+	}
+
+	out, code, err := exec(ctx, h.Container, h.Command)
+	if errors.Is(err, ErrCapabilityDisabled) {
+		slog.Warn("hook skipped: exec is not allowed by the Docker API",
+			"container", name, "stage", stage, "command", strings.Join(h.Command, " "))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("exec in %q: %w", h.Container, err)
+	}
+	if code != 0 {
+		out = strings.TrimSpace(out)
+		if len(out) > maxHookOutput {
+			out = "…" + out[len(out)-maxHookOutput:]
+		}
+		if out == "" {
+			return fmt.Errorf("%q in %q exited with code %d", strings.Join(h.Command, " "), h.Container, code)
+		}
+		return fmt.Errorf("%q in %q exited with code %d: %s", strings.Join(h.Command, " "), h.Container, code, out)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHooks_Webhook(t *testing.T) {
+	var got []map[string]string
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, body)
+		methods = append(methods, r.Method)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	hooks := []HookConfig{{URL: srv.URL + "/ok", Method: "POST"}, {URL: srv.URL + "/ok", Method: "put"}}
+	if err := runHooks(context.Background(), nil, "app", hookPreStart, hooks); err != nil {
+		t.Fatalf("runHooks: %v", err)
+	}
+	if len(got) != 2 || got[0]["container"] != "app" || got[0]["hook"] != "pre_start" {
+		t.Errorf("bodies = %v, want container app and hook pre_start", got)
+	}
+	if methods[0] != "POST" || methods[1] != "PUT" {
+		t.Errorf("methods = %v, want [POST PUT]", methods)
+	}
+
+	// The first failure stops the stage.
+	got = nil
+	hooks = []HookConfig{{URL: srv.URL + "/fail", Method: "POST"}, {URL: srv.URL + "/ok", Method: "POST"}}
+	err := runHooks(context.Background(), nil, "app", hookPostReady, hooks)
+	if err == nil || !strings.Contains(err.Error(), "post_ready hook 1 failed") || !strings.Contains(err.Error(), "502") {
+		t.Errorf("err = %v, want post_ready hook 1 failure with status 502", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("%d hooks called after a failure, want 1", len(got))
+	}
+}
+
+func TestRunHooks_WebhookTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	hooks := []HookConfig{{URL: srv.URL, Method: "POST", Timeout: 50 * time.Millisecond}}
+	if err := runHooks(context.Background(), nil, "app", hookPreStart, hooks); err == nil {
+		t.Error("expected a timeout error")
+	}
+}
+
+func TestRunHooks_Exec(t *testing.T) {
+	type call struct {
+		container string
+		cmd       string
+	}
+	var calls []call
+	exec := func(ctx context.Context, container string, cmd []string) (string, int, error) {
+		calls = append(calls, call{container, strings.Join(cmd, " ")})
+		switch cmd[0] {
+		case "false":
+			return "mount: permission denied\n", 32, nil
+		case "missing":
+			return "", 0, errors.New("no such container")
+		}
+		return "", 0, nil
+	}
+
+	tests := []struct {
+		name    string
+		hook    HookConfig
+		wantErr string
+	}{
+		{"success", HookConfig{Container: "nas", Command: []string{"mount", "/share"}}, ""},
+		{"non-zero exit", HookConfig{Container: "nas", Command: []string{"false"}}, `"false" in "nas" exited with code 32: mount: permission denied`},
+		{"exec error", HookConfig{Container: "nas", Command: []string{"missing"}}, `exec in "nas": no such container`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runHooks(context.Background(), exec, "app", hookPreStart, []HookConfig{tt.hook})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("runHooks: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+	if calls[0] != (call{"nas", "mount /share"}) {
+		t.Errorf("first call = %+v, want mount /share in nas", calls[0])
+	}
+}
+
+func TestRunPreStopHooks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	ok := HookConfig{URL: srv.URL + "/ok", Method: "POST"}
+	fail := HookConfig{URL: srv.URL + "/fail", Method: "POST"}
+	veto := HookConfig{URL: srv.URL + "/fail", Method: "POST", Veto: true}
+
+	tests := []struct {
+		name     string
+		hooks    []HookConfig
+		wantVeto bool
+	}{
+		{"no hooks", nil, false},
+		{"all succeed", []HookConfig{ok, ok}, false},
+		{"failure without veto", []HookConfig{fail, ok}, false},
+		{"failure with veto", []HookConfig{ok, veto}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runPreStopHooks(context.Background(), nil, "app", tt.hooks)
+			if (err != nil) != tt.wantVeto {
+				t.Errorf("runPreStopHooks() error = %v, want veto %v", err, tt.wantVeto)
+			}
+		})
+	}
+}
+
+func TestHookConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    HookConfig
+		wantErr bool
+	}{
+		{"exec", HookConfig{Container: "nas", Command: []string{"true"}}, false},
+		{"url", HookConfig{URL: "https://catalog.example.com/register", Method: "POST"}, false},
+		{"both", HookConfig{URL: "https://x.example.com", Container: "nas", Command: []string{"true"}}, true},
+		{"neither", HookConfig{}, true},
+		{"command without container", HookConfig{Command: []string{"true"}}, true},
+		{"bad scheme", HookConfig{URL: "ftp://x.example.com"}, true},
+		{"bad method", HookConfig{URL: "https://x.example.com", Method: "DELETE"}, true},
+		{"negative timeout", HookConfig{Container: "nas", Command: []string{"true"}, Timeout: -time.Second}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hook.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// iconPathPrefix is where the dashboard loads the icons the gateway serves.
+const iconPathPrefix = "/_status/icons/"
+
+const (
+	// iconCacheTTL is how long a proxied icon is served before it is fetched
+	// again. A failed refetch keeps serving the old image.
+	iconCacheTTL = 24 * time.Hour
+	// iconMaxBytes caps the size of an icon, fetched or read from disk.
+	iconMaxBytes = 1 << 20
+	// iconFetchTimeout bounds the download of a proxied icon.
+	iconFetchTimeout = 10 * time.Second
+)
+
+// parseIconURL checks an icon_url: http(s) with a host, or file:// with an
+// absolute path.
+func parseIconURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%q has no host", raw)
+		}
+	case "file":
+		if !strings.HasPrefix(u.Path, "/") {
+			return nil, fmt.Errorf("%q must be an absolute file:// path", raw)
+		}
+	default:
+		return nil, fmt.Errorf("%q must be an http, https or file URL", raw)
+	}
+	return u, nil
+}
+
+// inIconsDir reports whether path lies inside dir.
+func inIconsDir(dir, path string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// proxiedIcon reports whether the gateway serves the icon of c itself: a
+// file, or a remote image with proxy_icons on. The gateway never fetches
+// nor reads the icon of a discovered container, whose URL comes from a
+// label.
+func proxiedIcon(c *ContainerConfig, proxy bool) bool {
+	if c.Discovered {
+		return false
+	}
+	return strings.HasPrefix(c.IconURL, "file:") || proxy
+}
+
+// labelFileIcon reports whether c is a discovered container whose label
+// asks for a file icon, which is never served.
+func labelFileIcon(c *ContainerConfig) bool {
+	return c.Discovered && strings.HasPrefix(c.IconURL, "file:")
+}
+
+// dashboardIconURL is the image URL the dashboard shows for c: icon_url
+// itself, or the gateway's copy when it serves the icon. Empty when c has
+// no icon_url, leaving the Simple Icons slug.
+func dashboardIconURL(c *ContainerConfig, proxy bool) string {
+	if c.IconURL == "" || labelFileIcon(c) {
+		return ""
+	}
+	if !proxiedIcon(c, proxy) {
+		return c.IconURL
+	}
+	return iconPathPrefix + url.PathEscape(c.Name)
+}
+
+// iconCache keeps the proxied icons in memory, by URL.
+type iconCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedIcon
+	client  *http.Client
+	now     func() time.Time
+}
+
+type cachedIcon struct {
+	data        []byte
+	contentType string
+	fetched     time.Time
+}
+
+func newIconCache() *iconCache {
+	return &iconCache{
+		entries: make(map[string]cachedIcon),
+		client:  &http.Client{Timeout: iconFetchTimeout},
+		now:     time.Now,
+	}
+}
+
+// get returns the icon at rawURL, fetching it when it is not cached or
+// older than iconCacheTTL.
+func (c *iconCache) get(ctx context.Context, rawURL string) (cachedIcon, error) {
+	c.mu.Lock()
+	icon, ok := c.entries[rawURL]
+	c.mu.Unlock()
+	if ok && c.now().Sub(icon.fetched) < iconCacheTTL {
+		return icon, nil
+	}
+
+	fresh, err := c.fetch(ctx, rawURL)
+	if err != nil {
+		if ok {
+			return icon, nil
+		}
+		return cachedIcon{}, err
+	}
+	c.mu.Lock()
+	c.entries[rawURL] = fresh
+	c.mu.Unlock()
+	return fresh, nil
+}
+
+func (c *iconCache) fetch(ctx context.Context, rawURL string) (cachedIcon, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return cachedIcon{}, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return cachedIcon{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return cachedIcon{}, fmt.Errorf("icon %s: status %d", rawURL, resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		return cachedIcon{}, fmt.Errorf("icon %s: content type %q is not an image", rawURL, contentType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, iconMaxBytes+1))
+	if err != nil {
+		return cachedIcon{}, err
+	}
+	if len(data) > iconMaxBytes {
+		return cachedIcon{}, fmt.Errorf("icon %s: larger than %d bytes", rawURL, iconMaxBytes)
+	}
+	return cachedIcon{data: data, contentType: contentType, fetched: c.now()}, nil
+}
+
+// forget drops the icons no container uses anymore.
+func (c *iconCache) forget(keep map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for u := range c.entries {
+		if !keep[u] {
+			delete(c.entries, u)
+		}
+	}
+}
+
+// handleStatusIcon serves the icon_url of /_status/icons/NAME: the file of
+// a file:// URL inside icons_dir, or the cached copy of a remote one when
+// proxy_icons is on.
+func (s *Server) handleStatusIcon(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, iconPathPrefix)
+	s.configMu.RLock()
+	c, ok := s.containerMap[name]
+	proxy, iconsDir := s.cfg.Gateway.ProxyIcons, s.cfg.Gateway.IconsDir
+	s.configMu.RUnlock()
+	if !ok || c.IconURL == "" || !visibleTo(c.Tenant, requestTenant(r)) || !proxiedIcon(c, proxy) {
+		http.NotFound(w, r)
+		return
+	}
+	u, err := parseIconURL(c.IconURL)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// An SVG opened directly must not run scripts on the dashboard's origin.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+
+	if u.Scheme == "file" {
+		serveIconFile(w, r, iconsDir, u.Path)
+		return
+	}
+
+	icon, err := s.icons.get(r.Context(), c.IconURL)
+	if err != nil {
+		requestLogger(r.Context()).Warn("icon fetch failed", "container", name, "error", err)
+		http.Error(w, "icon unavailable", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", icon.contentType)
+	http.ServeContent(w, r, "", icon.fetched, bytes.NewReader(icon.data))
+}
+
+// serveIconFile serves the image at path, which must resolve, symlinks
+// included, to a file inside dir.
+func serveIconFile(w http.ResponseWriter, r *http.Request, dir, path string) {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil || !inIconsDir(realDir, realPath) {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(realPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() || info.Size() > iconMaxBytes {
+		http.NotFound(w, r)
+		return
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(realPath))
+	if !strings.HasPrefix(contentType, "image/") {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		contentType = http.DetectContentType(head[:n])
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,230 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseIconURL(t *testing.T) {
+	for _, raw := range []string{"https://git.lan/logo.png", "http://10.0.0.5/icon.svg", "file:///icons/nas.png"} {
+		if _, err := parseIconURL(raw); err != nil {
+			t.Errorf("%s: %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"javascript:alert(1)", "https:///logo.png", "file:icons/nas.png", "/icons/nas.png"} {
+		if _, err := parseIconURL(raw); err == nil {
+			t.Errorf("%s: want an error", raw)
+		}
+	}
+}
+
+func TestDashboardIconURL(t *testing.T) {
+	tests := []struct {
+		iconURL    string
+		proxy      bool
+		discovered bool
+		want       string
+	}{
+		{"", true, false, ""},
+		{"https://git.lan/logo.png", false, false, "https://git.lan/logo.png"},
+		{"https://git.lan/logo.png", true, false, "/_status/icons/my%20app"},
+		{"https://git.lan/logo.png", true, true, "https://git.lan/logo.png"},
+		{"file:///icons/app.png", false, false, "/_status/icons/my%20app"},
+		{"file:///icons/app.png", true, true, ""},
+	}
+	for _, tt := range tests {
+		c := &ContainerConfig{Name: "my app", IconURL: tt.iconURL, Discovered: tt.discovered}
+		if got := dashboardIconURL(c, tt.proxy); got != tt.want {
+			t.Errorf("dashboardIconURL(%q, %v, discovered %v) = %q, want %q", tt.iconURL, tt.proxy, tt.discovered, got, tt.want)
+		}
+	}
+}
+
+func TestStatusIcon_File(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "nas.svg")
+	if err := os.WriteFile(file, []byte("<svg/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rt := NewFakeRuntime()
+	rt.AddContainer("nas", FakeContainer{Status: "exited"})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway:    GlobalConfig{IconsDir: dir},
+		Containers: []ContainerConfig{{Name: "nas", Host: "nas.local", TargetPort: "80", IconURL: "file://" + file}},
+	})
+
+	w := g.get("gw.local", "/_status/icons/nas")
+	if w.Code != http.StatusOK || w.Body.String() != "<svg/>" {
+		t.Fatalf("status %d, body %q", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "sandbox") {
+		t.Errorf("Content-Security-Policy = %q, want a sandbox", csp)
+	}
+
+	w = g.get("gw.local", "/_status/api?fields=name,icon_url")
+	var resp struct {
+		Containers []map[string]any `json:"containers"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Containers[0]["icon_url"]; got != "/_status/icons/nas" {
+		t.Errorf("icon_url = %v, want the gateway path", got)
+	}
+
+	if w := g.get("gw.local", "/_status/icons/other"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown container: status %d, want 404", w.Code)
+	}
+}
+
+func TestStatusIcon_Proxy(t *testing.T) {
+	var fetches atomic.Int32
+	var fail atomic.Bool
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if fail.Load() {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	t.Cleanup(origin.Close)
+
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited"})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway:    GlobalConfig{ProxyIcons: true},
+		Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80", IconURL: origin.URL + "/logo.png"}},
+	})
+	now := time.Now()
+	g.server.icons.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		w := g.get("gw.local", "/_status/icons/app")
+		if w.Code != http.StatusOK || w.Body.String() != "png" || w.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("request %d: status %d, body %q", i, w.Code, w.Body)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetches = %d, want 1 (cached)", n)
+	}
+
+	// Past the TTL a failed refetch keeps serving the cached icon.
+	now = now.Add(iconCacheTTL + time.Minute)
+	fail.Store(true)
+	if w := g.get("gw.local", "/_status/icons/app"); w.Code != http.StatusOK || w.Body.String() != "png" {
+		t.Errorf("stale: status %d, body %q, want the cached icon", w.Code, w.Body)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want a refetch after the TTL", n)
+	}
+}
+
+func TestStatusIcon_FileConfined(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	secret := filepath.Join(outside, "config.yaml")
+	if err := os.WriteFile(secret, []byte("password: s3cret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, "link.png")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rt := NewFakeRuntime()
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway: GlobalConfig{IconsDir: dir},
+		Containers: []ContainerConfig{
+			{Name: "link", Host: "link.local", TargetPort: "80", IconURL: "file://" + filepath.Join(dir, "link.png")},
+			{Name: "text", Host: "text.local", TargetPort: "80", IconURL: "file://" + filepath.Join(dir, "notes.txt")},
+		},
+	})
+	for _, name := range []string{"link", "text"} {
+		if w := g.get("gw.local", "/_status/icons/"+name); w.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, body %q, want 404", name, w.Code, w.Body)
+		}
+	}
+
+	cfg := &GatewayConfig{
+		Gateway:    GlobalConfig{IconsDir: dir},
+		Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80", IconURL: "file://" + secret}},
+	}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "outside gateway.icons_dir") {
+		t.Errorf("error = %v, want the file outside icons_dir refused", err)
+	}
+}
+
+func TestStatusIcon_DiscoveredNotProxied(t *testing.T) {
+	var fetches atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	t.Cleanup(origin.Close)
+
+	rt := NewFakeRuntime()
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{Gateway: GlobalConfig{ProxyIcons: true}})
+	g.server.configMu.Lock()
+	g.server.containerMap["labeled"] = &ContainerConfig{Name: "labeled", IconURL: origin.URL + "/logo.png", Discovered: true}
+	g.server.configMu.Unlock()
+
+	if w := g.get("gw.local", "/_status/icons/labeled"); w.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404 for the icon of a discovered container", w.Code)
+	}
+	if n := fetches.Load(); n != 0 {
+		t.Errorf("fetches = %d, want none from a label URL", n)
+	}
+}
+
+func TestMergeConfigs_DropsLabelFileIcon(t *testing.T) {
+	dm := &DiscoveryManager{staticConfig: &GatewayConfig{
+		Gateway:    GlobalConfig{IconsDir: "/icons"},
+		Containers: []ContainerConfig{{Name: "static", Host: "static.local", TargetPort: "80", IconURL: "file:///icons/static.png"}},
+	}}
+	merged := dm.mergeConfigs([]ContainerConfig{
+		{Name: "labeled", Host: "labeled.local", TargetPort: "80", IconURL: "file:///etc/passwd", Discovered: true},
+		{Name: "remote", Host: "remote.local", TargetPort: "80", IconURL: "https://git.lan/logo.png", Discovered: true},
+	})
+	applyDefaults(merged)
+	if err := merged.Validate(); err != nil {
+		t.Fatalf("Validate = %v, want a label file icon not to block discovery", err)
+	}
+	for _, c := range merged.Containers {
+		want := map[string]string{"static": "file:///icons/static.png", "labeled": "", "remote": "https://git.lan/logo.png"}[c.Name]
+		if c.IconURL != want {
+			t.Errorf("%s: icon_url = %q, want %q", c.Name, c.IconURL, want)
+		}
+	}
+}
+
+func TestStatusIcon_NotProxied(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited"})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: "80", IconURL: "https://git.lan/logo.png"})
+
+	if w := g.get("gw.local", "/_status/icons/app"); w.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404 without proxy_icons", w.Code)
+	}
+}
+
+func TestValidate_IconURL(t *testing.T) {
+	cfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80", IconURL: "ftp://x/y.png"}}}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `container "app": icon_url`) {
+		t.Errorf("error = %v, want an icon_url error", err)
+	}
+}
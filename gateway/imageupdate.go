@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// updateCheckTick is the granularity of the image update loop. Per-container
+// update_check_interval values are rounded up to a multiple of it.
+const updateCheckTick = time.Minute
+
+// updateCheckTimeout bounds a single registry lookup.
+const updateCheckTimeout = 30 * time.Second
+
+// imageUpdateState is the outcome of the last update check of a container.
+type imageUpdateState struct {
+	lastCheck time.Time // last attempt, successful or not
+	checkedAt time.Time // last successful comparison
+	available bool
+	latest    string // digest the registry served at checkedAt
+	notified  string // digest the last update_available event was sent for
+}
+
+// imageUpdates holds per-container image update state.
+type imageUpdates struct {
+	mu     sync.Mutex
+	states map[string]*imageUpdateState
+}
+
+func newImageUpdates() *imageUpdates {
+	return &imageUpdates{states: make(map[string]*imageUpdateState)}
+}
+
+// due reports whether a container's check interval elapsed and marks it checked.
+func (u *imageUpdates) due(name string, interval time.Duration, now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	st, ok := u.states[name]
+	if !ok {
+		st = &imageUpdateState{}
+		u.states[name] = st
+	}
+	if now.Sub(st.lastCheck) < interval {
+		return false
+	}
+	st.lastCheck = now
+	return true
+}
+
+// record stores a comparison result. It returns true when an update became
+// available with a digest that was not announced yet.
+func (u *imageUpdates) record(name string, available bool, latest string, now time.Time) (announce bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	st, ok := u.states[name]
+	if !ok {
+		st = &imageUpdateState{}
+		u.states[name] = st
+	}
+	st.checkedAt = now
+	st.available = available
+	st.latest = latest
+	if !available || st.notified == latest {
+		return false
+	}
+	st.notified = latest
+	return true
+}
+
+// get returns whether an update is available and when that was last checked.
+func (u *imageUpdates) get(name string) (available bool, checkedAt time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if st, ok := u.states[name]; ok {
+		return st.available, st.checkedAt
+	}
+	return false, time.Time{}
+}
+
+// ImageUpdate reports whether the registry serves a newer image for the
+// container's tag, and when that was last checked (zero if never).
+func (m *ContainerManager) ImageUpdate(name string) (available bool, checkedAt time.Time) {
+	return m.updates.get(name)
+}
+
+// imageUpToDate reports whether remote is one of the local repo digests
+// ("repo@sha256:...").
+func imageUpToDate(local []string, remote string) bool {
+	for _, d := range local {
+		if _, digest, ok := strings.Cut(d, "@"); ok && digest == remote {
+			return true
+		}
+	}
+	return false
+}
+
+// StartImageUpdateChecker begins a background routine that compares the image
+// digest of containers with update_check_interval > 0 against the registry.
+// A newer digest is reported in /_status/api and published once as an
+// update_available event.
+func (m *ContainerManager) StartImageUpdateChecker(ctx context.Context, configProvider func() []ContainerConfig) {
+	go func() {
+		ticker := time.NewTicker(updateCheckTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkImageUpdates(ctx, configProvider())
+			}
+		}
+	}()
+}
+
+func (m *ContainerManager) checkImageUpdates(ctx context.Context, cfgs []ContainerConfig) {
+	if !m.client.Capable(CapabilityImages) {
+		return
+	}
+	now := time.Now()
+	for i := range cfgs {
+		cfg := &cfgs[i]
+		if cfg.UpdateCheckInterval <= 0 || !m.updates.due(cfg.Name, cfg.UpdateCheckInterval, now) {
+			continue
+		}
+		m.checkImageUpdate(ctx, cfg.Name, now)
+	}
+}
+
+// checkImageUpdate compares one container's image with the registry.
+func (m *ContainerManager) checkImageUpdate(ctx context.Context, name string, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+
+	ref, local, err := m.client.ImageDigests(ctx, name)
+	if err != nil {
+		slog.Warn("image update: inspect failed", "container", name, "error", err)
+		return
+	}
+	// Pinned images never change, and locally built ones have no registry.
+	if strings.Contains(ref, "@") || len(local) == 0 {
+		slog.Debug("image update: skipped, image pinned by digest or not from a registry",
+			"container", name, "image", ref)
+		return
+	}
+	remote, err := m.client.RegistryDigest(ctx, ref)
+	if err != nil {
+		slog.Warn("image update: registry lookup failed", "container", name, "image", ref, "error", err)
+		return
+	}
+
+	available := !imageUpToDate(local, remote)
+	SetImageUpdateAvailable(name, available)
+	if m.updates.record(name, available, remote, now) {
+		slog.Info("image update available", "container", name, "image", ref, "digest", remote)
+		m.emit(EventUpdateAvailable, name, fmt.Sprintf("%s has a newer image in the registry (%s)", ref, remote))
+	}
+}
@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestImageUpToDate(t *testing.T) {
+	local := []string{"nginx@sha256:aaa", "registry.local/nginx@sha256:bbb"}
+	if !imageUpToDate(local, "sha256:bbb") {
+		t.Error("digest present in RepoDigests should be up to date")
+	}
+	if imageUpToDate(local, "sha256:ccc") {
+		t.Error("unknown digest should be an update")
+	}
+	if imageUpToDate(nil, "sha256:aaa") {
+		t.Error("no local digests should never be up to date")
+	}
+}
+
+func TestImageUpdates_Record(t *testing.T) {
+	u := newImageUpdates()
+	now := time.Now()
+	if !u.due("app", time.Hour, now) || u.due("app", time.Hour, now.Add(time.Minute)) {
+		t.Fatal("due should fire once per interval")
+	}
+	if available, checkedAt := u.get("app"); available || !checkedAt.IsZero() {
+		t.Fatalf("get before any check = %v, %v", available, checkedAt)
+	}
+
+	if u.record("app", false, "sha256:aaa", now) {
+		t.Error("up-to-date result should not be announced")
+	}
+	if !u.record("app", true, "sha256:bbb", now) {
+		t.Error("new digest should be announced")
+	}
+	if u.record("app", true, "sha256:bbb", now) {
+		t.Error("same digest should be announced only once")
+	}
+	if !u.record("app", true, "sha256:ccc", now) {
+		t.Error("a newer digest should be announced again")
+	}
+	if available, checkedAt := u.get("app"); !available || !checkedAt.Equal(now) {
+		t.Errorf("get = %v, %v; want true, %v", available, checkedAt, now)
+	}
+}
+
+func TestCheckImageUpdates(t *testing.T) {
+	var remote atomic.Value
+	remote.Store("sha256:1111111111111111111111111111111111111111111111111111111111111111")
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1.43/containers/app/json":
+			w.Write([]byte(`{"Name":"/app","Image":"sha256:img","Config":{"Image":"nginx:alpine"}}`))
+		case "/v1.43/containers/pinned/json":
+			w.Write([]byte(`{"Name":"/pinned","Image":"sha256:img","Config":{"Image":"nginx@sha256:abc"}}`))
+		case "/v1.43/images/sha256:img/json":
+			w.Write([]byte(`{"Id":"sha256:img","RepoDigests":["nginx@sha256:1111111111111111111111111111111111111111111111111111111111111111"]}`))
+		case "/v1.43/distribution/nginx:alpine/json":
+			w.Write([]byte(`{"Descriptor":{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"` + remote.Load().(string) + `","size":1}}`))
+		default:
+			t.Errorf("unexpected daemon call %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer daemon.Close()
+
+	m := NewContainerManager(newTestDockerClient(t, daemon.URL))
+	var events atomic.Int32
+	m.Events().Subscribe(func(ev Event) {
+		if ev.Type == EventUpdateAvailable && ev.Container == "app" {
+			events.Add(1)
+		}
+	})
+	cfgs := []ContainerConfig{
+		{Name: "app", UpdateCheckInterval: time.Hour},
+		{Name: "pinned", UpdateCheckInterval: time.Hour},
+		{Name: "unflagged"}, // never inspected
+	}
+
+	m.checkImageUpdates(context.Background(), cfgs)
+	if available, checkedAt := m.ImageUpdate("app"); available || checkedAt.IsZero() {
+		t.Fatalf("ImageUpdate = %v, %v; want checked and up to date", available, checkedAt)
+	}
+	if _, checkedAt := m.ImageUpdate("pinned"); !checkedAt.IsZero() {
+		t.Error("image pinned by digest should not be compared")
+	}
+
+	// A newer digest is detected on the next due check and announced once.
+	remote.Store("sha256:2222222222222222222222222222222222222222222222222222222222222222")
+	m.checkImageUpdates(context.Background(), cfgs)
+	if available, _ := m.ImageUpdate("app"); available {
+		t.Fatal("check ran before update_check_interval elapsed")
+	}
+	now := time.Now()
+	m.checkImageUpdate(context.Background(), "app", now)
+	m.checkImageUpdate(context.Background(), "app", now)
+	if available, _ := m.ImageUpdate("app"); !available {
+		t.Error("newer registry digest should flag an update")
+	}
+	if n := events.Load(); n != 1 {
+		t.Errorf("update_available events = %d, want 1", n)
+	}
+}
@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// limitListener caps the number of connections open at once. Accept blocks
+// while the limit is reached, so excess clients wait in the kernel backlog
+// instead of consuming goroutines and buffers.
+type limitListener struct {
+	net.Listener
+	sem       chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newLimitListener wraps l so that at most max connections are open at once.
+// A max of 0 returns l unchanged.
+func newLimitListener(l net.Listener, max int) net.Listener {
+	if max <= 0 {
+		return l
+	}
+	return &limitListener{
+		Listener: l,
+		sem:      make(chan struct{}, max),
+		done:     make(chan struct{}),
+	}
+}
+
+// Accept waits for a free slot, then for the next connection.
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+	c, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitConn{Conn: c, release: func() { <-l.sem }}, nil
+}
+
+// Close closes the listener and unblocks a pending Accept.
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
+
+// limitConn frees its listener slot when closed.
+type limitConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
+
+// trackConnState keeps gateway_open_connections in sync with the server's
+// connections. Hijacked connections (WebSocket tunnels) leave the server and
+// are tracked by gateway_websocket_connections instead.
+func trackConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		OpenConnections.Inc()
+	case http.StateClosed, http.StateHijacked:
+		OpenConnections.Dec()
+	}
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLimitListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := newLimitListener(inner, 1)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+	}
+
+	first := <-accepted
+	select {
+	case <-accepted:
+		t.Fatal("second connection accepted while the limit was reached")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Closing the first connection frees its slot.
+	first.Close()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(time.Second):
+		t.Fatal("second connection not accepted after a slot was freed")
+	}
+}
+
+func TestLimitListener_Unlimited(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inner.Close()
+	if ln := newLimitListener(inner, 0); ln != inner {
+		t.Error("max 0 should return the listener unchanged")
+	}
+}
+
+func TestLimitListener_CloseUnblocksAccept(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := newLimitListener(inner, 1)
+	ln.(*limitListener).sem <- struct{}{} // limit reached
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ln.Accept()
+		done <- err
+	}()
+	ln.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Accept after Close should fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Accept still blocked after Close")
+	}
+}
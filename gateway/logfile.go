@@ -0,0 +1,239 @@
+package gateway
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat is embedded in the name of rotated files, e.g.
+// "access-2026-03-10T12-00-00.000.log". It sorts chronologically.
+const rotatedTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile is an io.Writer appending to a log file that is rotated when
+// it grows beyond MaxSizeMB or was opened more than RotateEvery ago. Rotated
+// files are optionally gzip-compressed and pruned by count and age.
+type RotatingFile struct {
+	cfg LogFileConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	cleanup   sync.WaitGroup
+	cleanupMu sync.Mutex // serialises compressAndPrune runs
+}
+
+// OpenRotatingFile opens (or creates) cfg.Path for appending.
+func OpenRotatingFile(cfg LogFileConfig) (*RotatingFile, error) {
+	f := &RotatingFile{cfg: cfg, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("log file %q: %w", cfg.Path, err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("log file %q: %w", f.cfg.Path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("log file %q: %w", f.cfg.Path, err)
+	}
+	f.file, f.size, f.openedAt = file, info.Size(), f.now()
+	return nil
+}
+
+// Write appends p, rotating first if the write would exceed the size limit or
+// the rotation period elapsed.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.due(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			// Logged asynchronously: the application log may be this file.
+			go slog.Warn("log file: rotation failed", "path", f.cfg.Path, "error", err)
+			if f.file == nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// due reports whether the file must be rotated before writing n more bytes.
+// Caller must hold f.mu.
+func (f *RotatingFile) due(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if max := int64(f.cfg.MaxSizeMB) << 20; max > 0 && f.size+n > max {
+		return true
+	}
+	return f.cfg.RotateEvery > 0 && f.now().Sub(f.openedAt) >= f.cfg.RotateEvery
+}
+
+// rotate renames the current file aside, opens a fresh one and compresses and
+// prunes old files in the background. If the rename fails the current file
+// is reopened, so writes continue. Caller must hold f.mu.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	now := f.now()
+	renameErr := os.Rename(f.cfg.Path, f.rotatedName(now))
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		f.size = 0 // retry after another max_size_mb instead of on every write
+		return fmt.Errorf("log file %q: rotate: %w", f.cfg.Path, renameErr)
+	}
+	f.cleanup.Add(1)
+	go func() {
+		defer f.cleanup.Done()
+		f.compressAndPrune(now)
+	}()
+	return nil
+}
+
+// rotatedName returns the backup name for a rotation at t.
+func (f *RotatingFile) rotatedName(t time.Time) string {
+	dir, prefix, ext := f.nameParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(rotatedTimeFormat)+ext)
+}
+
+// nameParts splits "/var/log/access.log" into "/var/log", "access-", ".log".
+func (f *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(f.cfg.Path)
+	base := filepath.Base(f.cfg.Path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// backups returns the rotated files of f, oldest first, with their rotation
+// time.
+func (f *RotatingFile) backups() ([]string, []time.Time, error) {
+	dir, prefix, ext := f.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	type backup struct {
+		name string
+		at   time.Time
+	}
+	var found []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], ".gz"), ext)
+		at, err := time.Parse(rotatedTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		found = append(found, backup{filepath.Join(dir, name), at})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].at.Before(found[j].at) })
+	names := make([]string, len(found))
+	times := make([]time.Time, len(found))
+	for i, b := range found {
+		names[i], times[i] = b.name, b.at
+	}
+	return names, times, nil
+}
+
+// compressAndPrune gzips uncompressed backups (when enabled) and removes the
+// ones beyond MaxBackups or older than MaxAge at now.
+func (f *RotatingFile) compressAndPrune(now time.Time) {
+	f.cleanupMu.Lock()
+	defer f.cleanupMu.Unlock()
+	names, times, err := f.backups()
+	if err != nil {
+		slog.Warn("log file: listing backups failed", "path", f.cfg.Path, "error", err)
+		return
+	}
+	cutoff := time.Time{}
+	if f.cfg.MaxAge > 0 {
+		cutoff = now.Add(-f.cfg.MaxAge)
+	}
+	for i, name := range names {
+		tooMany := f.cfg.MaxBackups > 0 && i < len(names)-f.cfg.MaxBackups
+		tooOld := !cutoff.IsZero() && times[i].Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(name); err != nil {
+				slog.Warn("log file: removing backup failed", "file", name, "error", err)
+			}
+			continue
+		}
+		if f.cfg.Compress && !strings.HasSuffix(name, ".gz") {
+			if err := gzipFile(name); err != nil {
+				slog.Warn("log file: compressing backup failed", "file", name, "error", err)
+			}
+		}
+	}
+}
+
+// gzipFile replaces name with name.gz.
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// Close closes the file and waits for background compression to finish.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	f.cleanup.Wait()
+	return err
+}
@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a controllable now function starting at a fixed time.
+func fakeClock() (now func() time.Time, advance func(time.Duration)) {
+	t := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	return func() time.Time { return t }, func(d time.Duration) { t = t.Add(d) }
+}
+
+// openTestFile opens a RotatingFile in a temp dir driven by a fake clock.
+func openTestFile(t *testing.T, cfg LogFileConfig) (*RotatingFile, func(time.Duration)) {
+	t.Helper()
+	cfg.Path = filepath.Join(t.TempDir(), "access.log")
+	now, advance := fakeClock()
+	f := &RotatingFile{cfg: cfg, now: now}
+	if err := f.open(); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f, advance
+}
+
+// ─── Rotation ─────────────────────────────────────────────────────────────────
+
+func TestRotatingFile_SizeRotation(t *testing.T) {
+	f, advance := openTestFile(t, LogFileConfig{MaxSizeMB: 1})
+	line := bytes.Repeat([]byte("x"), 600<<10)
+
+	f.Write(line)
+	advance(time.Second)
+	f.Write(line) // would exceed 1 MiB → rotates first
+	f.cleanup.Wait()
+
+	names, _, err := f.backups()
+	if err != nil || len(names) != 1 {
+		t.Fatalf("backups = %v (err %v), want 1", names, err)
+	}
+	if want := "access-2026-03-10T12-00-01.000.log"; filepath.Base(names[0]) != want {
+		t.Errorf("rotated name = %s, want %s", filepath.Base(names[0]), want)
+	}
+	if info, _ := os.Stat(f.cfg.Path); info.Size() != int64(len(line)) {
+		t.Errorf("active file size = %d, want %d", info.Size(), len(line))
+	}
+}
+
+func TestRotatingFile_TimeRotation(t *testing.T) {
+	f, advance := openTestFile(t, LogFileConfig{RotateEvery: 24 * time.Hour})
+
+	f.Write([]byte("day 1\n"))
+	advance(23 * time.Hour)
+	f.Write([]byte("still day 1\n"))
+	if names, _, _ := f.backups(); len(names) != 0 {
+		t.Fatalf("rotated too early: %v", names)
+	}
+	advance(time.Hour)
+	f.Write([]byte("day 2\n"))
+	f.cleanup.Wait()
+
+	if names, _, _ := f.backups(); len(names) != 1 {
+		t.Fatalf("backups = %v, want 1", names)
+	}
+	if data, _ := os.ReadFile(f.cfg.Path); string(data) != "day 2\n" {
+		t.Errorf("active file = %q, want only day 2", data)
+	}
+}
+
+func TestRotatingFile_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("old\n"), 0o644)
+
+	f, err := OpenRotatingFile(LogFileConfig{Path: path, MaxSizeMB: 1})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	f.Write([]byte("new\n"))
+	f.Close()
+
+	if data, _ := os.ReadFile(path); string(data) != "old\nnew\n" {
+		t.Errorf("file = %q, want appended content", data)
+	}
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Error("Write after Close should fail")
+	}
+}
+
+// ─── Backups ──────────────────────────────────────────────────────────────────
+
+func TestRotatingFile_MaxBackups(t *testing.T) {
+	f, advance := openTestFile(t, LogFileConfig{RotateEvery: time.Hour, MaxBackups: 2})
+	for i := 0; i < 5; i++ {
+		f.Write([]byte("line\n"))
+		advance(time.Hour)
+	}
+	f.cleanup.Wait()
+
+	names, _, _ := f.backups()
+	if len(names) != 2 {
+		t.Fatalf("backups = %v, want the 2 newest", names)
+	}
+	if !strings.HasSuffix(names[1], "access-2026-03-10T16-00-00.000.log") {
+		t.Errorf("newest backup = %s", names[1])
+	}
+}
+
+func TestRotatingFile_MaxAge(t *testing.T) {
+	f, advance := openTestFile(t, LogFileConfig{RotateEvery: 24 * time.Hour, MaxAge: 48 * time.Hour})
+	for i := 0; i < 4; i++ {
+		f.Write([]byte("line\n"))
+		advance(24 * time.Hour)
+	}
+	f.Write([]byte("line\n"))
+	f.cleanup.Wait()
+
+	_, times, _ := f.backups()
+	for _, at := range times {
+		if f.now().Sub(at) > 48*time.Hour {
+			t.Errorf("backup from %s is older than max_age", at)
+		}
+	}
+	if len(times) != 3 { // rotated 48h, 24h and 0h ago
+		t.Errorf("kept %d backups, want 3", len(times))
+	}
+}
+
+func TestRotatingFile_Compress(t *testing.T) {
+	f, advance := openTestFile(t, LogFileConfig{RotateEvery: time.Hour, Compress: true})
+	f.Write([]byte("first\n"))
+	advance(time.Hour)
+	f.Write([]byte("second\n"))
+	f.cleanup.Wait()
+
+	names, _, _ := f.backups()
+	if len(names) != 1 || !strings.HasSuffix(names[0], ".log.gz") {
+		t.Fatalf("backups = %v, want one .log.gz", names)
+	}
+	gz, err := os.Open(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+	zr, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatalf("not a gzip file: %v", err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != "first\n" {
+		t.Errorf("compressed content = %q, want %q", data, "first\n")
+	}
+}
@@ -0,0 +1,455 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lokiFlushInterval is how often queued log lines are pushed to Loki.
+	lokiFlushInterval = 2 * time.Second
+	// lokiBatchSize triggers an early push once this many lines are queued.
+	lokiBatchSize = 512
+	// logForwardQueueLimit bounds memory while a sink is unreachable; lines
+	// beyond it are dropped.
+	logForwardQueueLimit = 8192
+)
+
+// syslogFacilities maps the accepted facility names to their RFC 5424 codes.
+var syslogFacilities = map[string]int{
+	"user": 1, "daemon": 3,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// parseSyslogAddress splits "udp://host:514" into network and address.
+func parseSyslogAddress(addr string) (network, hostport string, err error) {
+	u, err := url.Parse(addr)
+	if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" || u.Port() == "" {
+		return "", "", fmt.Errorf("address %q must be udp://host:port or tcp://host:port", addr)
+	}
+	return u.Scheme, u.Host, nil
+}
+
+// validLokiLabel reports whether name is a valid Prometheus/Loki label name.
+func validLokiLabel(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// logLine is one JSON log record on its way to the forwarding sinks.
+type logLine struct {
+	stream    string // "gateway" or "access"
+	at        time.Time
+	level     string
+	container string
+	host      string
+	raw       string // the JSON record without trailing newline
+}
+
+// parseLogLine extracts the fields used for syslog severity and Loki labels
+// from a JSON record written by slog.JSONHandler.
+func parseLogLine(stream string, p []byte) logLine {
+	l := logLine{stream: stream, at: time.Now(), raw: string(bytes.TrimRight(p, "\n"))}
+	var rec struct {
+		Time      time.Time `json:"time"`
+		Level     string    `json:"level"`
+		Container string    `json:"container"`
+		Host      string    `json:"host"`
+	}
+	if json.Unmarshal(p, &rec) == nil {
+		if !rec.Time.IsZero() {
+			l.at = rec.Time
+		}
+		l.level, l.container, l.host = strings.ToLower(rec.Level), rec.Container, rec.Host
+	}
+	return l
+}
+
+// LogForwarder ships the application and access logs to syslog and Loki.
+// Writes never block the caller: lines are queued and sent by the loop
+// started with Start, and dropped when a sink cannot keep up.
+type LogForwarder struct {
+	client   *http.Client
+	hostname string
+
+	mu      sync.RWMutex
+	syslog  SyslogConfig
+	loki    LokiConfig
+	pushURL string // "" when Loki forwarding is disabled
+
+	// syslogQueue feeds the syslog sender; only touched by the sender after
+	// creation.
+	syslogQueue chan logLine
+	syslogConn  net.Conn
+	syslogAddr  string
+	syslogDown  bool
+
+	qmu     sync.Mutex
+	queue   []logLine
+	dropped int
+	kick    chan struct{}
+}
+
+// logForwarder is the process-wide forwarder, in the same way tracer is
+// process-wide: both the default slog handler and the access log write to it.
+var logForwarder = newLogForwarder()
+
+func newLogForwarder() *LogForwarder {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	return &LogForwarder{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		hostname:    hostname,
+		syslogQueue: make(chan logLine, logForwardQueueLimit),
+		kick:        make(chan struct{}, 1),
+	}
+}
+
+// ConfigureLogForwarding applies the syslog and Loki configuration. It is
+// safe to call on every config reload; empty addresses disable the sinks.
+func ConfigureLogForwarding(syslog SyslogConfig, loki LokiConfig) {
+	logForwarder.Sync(syslog, loki)
+}
+
+// StartLogForwarding begins shipping log lines until ctx is cancelled, then
+// flushes the remaining ones.
+func StartLogForwarding(ctx context.Context) {
+	logForwarder.Start(ctx)
+}
+
+// ForwardedLog returns a writer that forwards the JSON records written to it
+// to the configured sinks, tagged with stream ("gateway" or "access"). It
+// accepts and discards everything while forwarding is disabled.
+func ForwardedLog(stream string) io.Writer {
+	return forwardWriter{f: logForwarder, stream: stream}
+}
+
+type forwardWriter struct {
+	f      *LogForwarder
+	stream string
+}
+
+// Write queues one record. It always succeeds so that an io.MultiWriter
+// in front of it keeps writing to stdout and files.
+func (w forwardWriter) Write(p []byte) (int, error) {
+	w.f.forward(w.stream, p)
+	return len(p), nil
+}
+
+// Sync replaces the forwarder configuration.
+func (f *LogForwarder) Sync(syslog SyslogConfig, loki LokiConfig) {
+	pushURL := lokiPushURL(loki.URL)
+	f.mu.Lock()
+	lokiChanged := pushURL != f.pushURL
+	syslogChanged := syslog.Address != f.syslog.Address
+	f.syslog, f.loki, f.pushURL = syslog, loki, pushURL
+	f.mu.Unlock()
+	if lokiChanged && pushURL != "" {
+		slog.Info("log forwarding: pushing to Loki", "url", pushURL)
+	}
+	if syslogChanged && syslog.Address != "" {
+		slog.Info("log forwarding: sending to syslog", "address", syslog.Address)
+	}
+}
+
+// lokiPushURL appends the Loki push path to a base URL, unless the URL
+// already points at it.
+func lokiPushURL(base string) string {
+	if base == "" {
+		return ""
+	}
+	base = strings.TrimRight(base, "/")
+	if strings.HasSuffix(base, "/loki/api/v1/push") {
+		return base
+	}
+	return base + "/loki/api/v1/push"
+}
+
+func (f *LogForwarder) forward(stream string, p []byte) {
+	f.mu.RLock()
+	toSyslog, toLoki := f.syslog.Address != "", f.pushURL != ""
+	f.mu.RUnlock()
+	if !toSyslog && !toLoki {
+		return
+	}
+	line := parseLogLine(stream, p)
+
+	if toSyslog {
+		select {
+		case f.syslogQueue <- line:
+		default: // sender cannot keep up; drop rather than block logging
+		}
+	}
+	if toLoki {
+		f.qmu.Lock()
+		if len(f.queue) >= logForwardQueueLimit {
+			f.dropped++
+			f.qmu.Unlock()
+			return
+		}
+		f.queue = append(f.queue, line)
+		full := len(f.queue) >= lokiBatchSize
+		f.qmu.Unlock()
+		if full {
+			select {
+			case f.kick <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Start runs the syslog sender and the Loki push loop until ctx is cancelled.
+func (f *LogForwarder) Start(ctx context.Context) {
+	go f.runSyslog(ctx)
+	go func() {
+		ticker := time.NewTicker(lokiFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				f.flushLoki(flushCtx)
+				cancel()
+				return
+			case <-ticker.C:
+			case <-f.kick:
+			}
+			f.flushLoki(ctx)
+		}
+	}()
+}
+
+// ─── Syslog ───────────────────────────────────────────────────────────────────
+
+func (f *LogForwarder) runSyslog(ctx context.Context) {
+	defer func() {
+		if f.syslogConn != nil {
+			f.syslogConn.Close()
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-f.syslogQueue:
+			f.mu.RLock()
+			cfg := f.syslog
+			f.mu.RUnlock()
+			if cfg.Address == "" {
+				continue
+			}
+			f.sendSyslog(cfg, line)
+		}
+	}
+}
+
+// sendSyslog writes one message, (re)connecting when the address changed or
+// the previous write failed. Failures are logged once per outage: the
+// warning itself is forwarded too, so logging every failure would loop.
+func (f *LogForwarder) sendSyslog(cfg SyslogConfig, line logLine) {
+	network, addr, err := parseSyslogAddress(cfg.Address)
+	if err != nil {
+		return
+	}
+	if f.syslogConn != nil && f.syslogAddr != cfg.Address {
+		f.syslogConn.Close()
+		f.syslogConn = nil
+	}
+	if f.syslogConn == nil {
+		conn, err := net.DialTimeout(network, addr, 5*time.Second)
+		if err != nil {
+			f.syslogFailed(cfg.Address, err)
+			return
+		}
+		f.syslogConn, f.syslogAddr = conn, cfg.Address
+	}
+
+	msg := formatSyslog(cfg, f.hostname, line)
+	if network == "tcp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+	f.syslogConn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(f.syslogConn, msg); err != nil {
+		f.syslogConn.Close()
+		f.syslogConn = nil
+		f.syslogFailed(cfg.Address, err)
+		return
+	}
+	if f.syslogDown {
+		f.syslogDown = false
+		slog.Info("log forwarding: syslog reachable again", "address", cfg.Address)
+	}
+}
+
+func (f *LogForwarder) syslogFailed(address string, err error) {
+	if !f.syslogDown {
+		f.syslogDown = true
+		slog.Warn("log forwarding: syslog unreachable, dropping messages", "address", address, "error", err)
+	}
+}
+
+// formatSyslog renders an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG
+// The stream ("gateway" or "access") is used as MSGID.
+func formatSyslog(cfg SyslogConfig, hostname string, line logLine) string {
+	pri := syslogFacilities[cfg.Facility]*8 + syslogSeverity(line.level)
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		pri, line.at.UTC().Format(time.RFC3339Nano), hostname, cfg.AppName,
+		os.Getpid(), line.stream, line.raw)
+}
+
+// syslogSeverity maps a slog level name to an RFC 5424 severity.
+func syslogSeverity(level string) int {
+	switch level {
+	case "error":
+		return 3
+	case "warn":
+		return 4
+	case "debug":
+		return 7
+	default:
+		return 6 // informational
+	}
+}
+
+// ─── Loki ─────────────────────────────────────────────────────────────────────
+
+// flushLoki pushes every queued line. Lines are discarded when the push
+// fails: forwarding is best-effort and must never back up logging.
+func (f *LogForwarder) flushLoki(ctx context.Context) {
+	f.qmu.Lock()
+	batch, dropped := f.queue, f.dropped
+	f.queue, f.dropped = nil, 0
+	f.qmu.Unlock()
+	if dropped > 0 {
+		slog.Warn("log forwarding: Loki queue full, lines dropped", "dropped", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	f.mu.RLock()
+	pushURL, cfg := f.pushURL, f.loki
+	f.mu.RUnlock()
+	if pushURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(encodeLokiPush(cfg.Labels, batch))
+	if err != nil {
+		slog.Warn("log forwarding: encoding Loki push failed", "error", err)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushURL, bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("log forwarding: Loki push failed", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", cfg.TenantID)
+	}
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		slog.Warn("log forwarding: Loki push failed", "url", pushURL, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("log forwarding: Loki rejected push", "url", pushURL, "status", resp.StatusCode, "lines", len(batch))
+	}
+}
+
+// Loki push API payload (POST /loki/api/v1/push, JSON encoding).
+type (
+	lokiPush struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	lokiStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+)
+
+// encodeLokiPush groups lines into streams by their label set: the static
+// labels plus stream, level, and the container and host of the record.
+func encodeLokiPush(static map[string]string, lines []logLine) lokiPush {
+	byKey := make(map[string]*lokiStream)
+	var keys []string
+	for _, l := range lines {
+		labels := make(map[string]string, len(static)+4)
+		for k, v := range static {
+			labels[k] = v
+		}
+		labels["stream"] = l.stream
+		if l.level != "" {
+			labels["level"] = l.level
+		}
+		if l.container != "" {
+			labels["container"] = l.container
+		}
+		if l.host != "" {
+			labels["host"] = l.host
+		}
+		key := lokiLabelKey(labels)
+		st, ok := byKey[key]
+		if !ok {
+			st = &lokiStream{Stream: labels}
+			byKey[key] = st
+			keys = append(keys, key)
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(l.at.UnixNano(), 10), l.raw})
+	}
+	out := lokiPush{Streams: make([]lokiStream, 0, len(keys))}
+	for _, k := range keys {
+		out.Streams = append(out.Streams, *byKey[k])
+	}
+	return out
+}
+
+// lokiLabelKey renders a label set in a canonical form for grouping.
+func lokiLabelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLogRecord = `{"time":"2026-03-10T12:00:01.5Z","level":"WARN","msg":"proxy error","container":"app","host":"app.example.com"}` + "\n"
+
+func TestParseLogLine(t *testing.T) {
+	l := parseLogLine("access", []byte(testLogRecord))
+	if l.stream != "access" || l.level != "warn" || l.container != "app" || l.host != "app.example.com" {
+		t.Errorf("parseLogLine = %+v", l)
+	}
+	if want := time.Date(2026, 3, 10, 12, 0, 1, 5e8, time.UTC); !l.at.Equal(want) {
+		t.Errorf("at = %v, want %v", l.at, want)
+	}
+	if strings.HasSuffix(l.raw, "\n") {
+		t.Error("raw line should not keep the trailing newline")
+	}
+
+	// Non-JSON input is still forwarded, with default fields.
+	l = parseLogLine("gateway", []byte("plain text\n"))
+	if l.raw != "plain text" || l.level != "" || l.at.IsZero() {
+		t.Errorf("parseLogLine(plain) = %+v", l)
+	}
+}
+
+func TestFormatSyslog(t *testing.T) {
+	line := parseLogLine("access", []byte(testLogRecord))
+	got := formatSyslog(SyslogConfig{AppName: "gw", Facility: "local0"}, "host1", line)
+	// local0 (16) * 8 + warning (4) = 132
+	if !strings.HasPrefix(got, "<132>1 2026-03-10T12:00:01.5Z host1 gw ") {
+		t.Errorf("header = %q", got)
+	}
+	if !strings.Contains(got, " access - {") {
+		t.Errorf("MSGID/MSG = %q", got)
+	}
+}
+
+func TestParseSyslogAddress(t *testing.T) {
+	if n, a, err := parseSyslogAddress("tcp://syslog:601"); err != nil || n != "tcp" || a != "syslog:601" {
+		t.Errorf("tcp address = %q %q %v", n, a, err)
+	}
+	for _, bad := range []string{"syslog:514", "http://syslog:514", "udp://syslog"} {
+		if _, _, err := parseSyslogAddress(bad); err == nil {
+			t.Errorf("parseSyslogAddress(%q) should fail", bad)
+		}
+	}
+}
+
+func TestLogForwarder_SyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	f := newLogForwarder()
+	f.Sync(SyslogConfig{Address: "udp://" + pc.LocalAddr().String(), AppName: "gw", Facility: "daemon"}, LokiConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.Start(ctx)
+
+	forwardWriter{f: f, stream: "gateway"}.Write([]byte(testLogRecord))
+
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no syslog datagram: %v", err)
+	}
+	// daemon (3) * 8 + warning (4) = 28
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<28>1 ") || !strings.Contains(msg, `"msg":"proxy error"`) {
+		t.Errorf("datagram = %q", msg)
+	}
+}
+
+func TestLogForwarder_LokiPush(t *testing.T) {
+	var got lokiPush
+	var tenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		tenant = r.Header.Get("X-Scope-OrgID")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	f := newLogForwarder()
+	f.Sync(SyslogConfig{}, LokiConfig{URL: srv.URL, TenantID: "homelab", Labels: map[string]string{"job": "gw"}})
+	w := forwardWriter{f: f, stream: "access"}
+	w.Write([]byte(testLogRecord))
+	w.Write([]byte(testLogRecord))
+	w.Write([]byte(`{"time":"2026-03-10T12:00:02Z","level":"INFO","msg":"access","container":"db"}` + "\n"))
+	f.flushLoki(context.Background())
+
+	if tenant != "homelab" {
+		t.Errorf("X-Scope-OrgID = %q", tenant)
+	}
+	if len(got.Streams) != 2 {
+		t.Fatalf("streams = %d, want 2 (one per label set): %+v", len(got.Streams), got.Streams)
+	}
+	first := got.Streams[0]
+	want := map[string]string{"job": "gw", "stream": "access", "level": "warn", "container": "app", "host": "app.example.com"}
+	for k, v := range want {
+		if first.Stream[k] != v {
+			t.Errorf("label %s = %q, want %q", k, first.Stream[k], v)
+		}
+	}
+	if len(first.Values) != 2 || first.Values[0][0] != "1773144001500000000" {
+		t.Errorf("values = %v", first.Values)
+	}
+}
+
+func TestLogForwarder_DisabledDropsLines(t *testing.T) {
+	f := newLogForwarder()
+	forwardWriter{f: f, stream: "gateway"}.Write([]byte(testLogRecord))
+	if len(f.queue) != 0 || len(f.syslogQueue) != 0 {
+		t.Error("lines should not be queued while forwarding is disabled")
+	}
+}
+
+func TestValidLokiLabel(t *testing.T) {
+	for name, want := range map[string]bool{"job": true, "env_1": true, "_x": true, "1abc": false, "a-b": false, "": false} {
+		if got := validLokiLabel(name); got != want {
+			t.Errorf("validLokiLabel(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
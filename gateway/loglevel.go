@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// LogLevel is the minimum level of the application log. main passes it to
+// the slog handler; it follows gateway.log_level and can be changed at
+// runtime through PUT /_admin/loglevel.
+var LogLevel = new(slog.LevelVar)
+
+// logLevels maps the accepted log_level values to slog levels.
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// parseLogLevel parses one of debug, info, warn or error.
+func parseLogLevel(s string) (slog.Level, error) {
+	lvl, ok := logLevels[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("invalid log level %q (want debug, info, warn or error)", s)
+	}
+	return lvl, nil
+}
+
+// configuredLogLevel is the log_level last applied by ConfigureLogLevel.
+var configuredLogLevel struct {
+	mu    sync.Mutex
+	level string
+}
+
+// ConfigureLogLevel applies gateway.log_level. A reload only resets the level
+// when log_level itself changed, so a level set through /_admin/loglevel
+// survives unrelated reloads.
+func ConfigureLogLevel(level string) {
+	lvl, err := parseLogLevel(level)
+	if err != nil {
+		return // rejected by Validate
+	}
+	configuredLogLevel.mu.Lock()
+	defer configuredLogLevel.mu.Unlock()
+	if level == configuredLogLevel.level {
+		return
+	}
+	configuredLogLevel.level = level
+	LogLevel.Set(lvl)
+}
+
+// logLevelOverride returns the current log level when /_admin/loglevel set
+// it to something other than log_level, or "" otherwise.
+func logLevelOverride() string {
+	configuredLogLevel.mu.Lock()
+	configured := configuredLogLevel.level
+	configuredLogLevel.mu.Unlock()
+	current := logLevelName(LogLevel.Level())
+	if lvl, err := parseLogLevel(configured); err == nil && logLevelName(lvl) == current {
+		return ""
+	}
+	return current
+}
+
+// logLevelName returns the log_level name of lvl.
+func logLevelName(lvl slog.Level) string {
+	return strings.ToLower(lvl.String())
+}
+
+// handleAdminLogLevel reports the application log level (GET) or changes it
+// to ?level= until the next change of log_level (PUT).
+func (s *Server) handleAdminLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if !validateOrigin(r) {
+			http.Error(w, "cross-origin request blocked", http.StatusForbidden)
+			return
+		}
+		lvl, err := parseLogLevel(r.URL.Query().Get("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		old := LogLevel.Level()
+		LogLevel.Set(lvl)
+		// Logged at warn so the change shows up whatever the new level.
+		slog.WarnContext(r.Context(), "log level changed via admin API",
+			"from", logLevelName(old), "to", logLevelName(lvl))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]string{"level": logLevelName(LogLevel.Level())})
+}
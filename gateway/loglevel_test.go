@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, " warn ": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := parseLogLevel(in); err != nil || got != want {
+			t.Errorf("parseLogLevel(%q) = (%v, %v), want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "verbose", "warning"} {
+		if _, err := parseLogLevel(in); err == nil {
+			t.Errorf("parseLogLevel(%q) accepted an invalid level", in)
+		}
+	}
+}
+
+func TestConfigureLogLevel(t *testing.T) {
+	defer func(lvl slog.Level) { LogLevel.Set(lvl) }(LogLevel.Level())
+
+	ConfigureLogLevel("warn")
+	if LogLevel.Level() != slog.LevelWarn {
+		t.Fatalf("level = %v, want WARN", LogLevel.Level())
+	}
+	// A runtime change survives a reload with the same log_level ...
+	LogLevel.Set(slog.LevelDebug)
+	ConfigureLogLevel("warn")
+	if LogLevel.Level() != slog.LevelDebug {
+		t.Errorf("unchanged log_level reset the level to %v", LogLevel.Level())
+	}
+	// ... but not a new one.
+	ConfigureLogLevel("error")
+	if LogLevel.Level() != slog.LevelError {
+		t.Errorf("level = %v, want ERROR", LogLevel.Level())
+	}
+}
+
+func TestHandleAdminLogLevel(t *testing.T) {
+	defer func(lvl slog.Level) { LogLevel.Set(lvl) }(LogLevel.Level())
+	LogLevel.Set(slog.LevelInfo)
+	s := &Server{cfg: &GatewayConfig{}}
+	call := func(method, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleAdminLogLevel(w, httptest.NewRequest(method, "/_admin/loglevel"+query, nil))
+		return w
+	}
+
+	if w := call(http.MethodGet, ""); !strings.Contains(w.Body.String(), `"level":"info"`) {
+		t.Errorf("GET = %d %q, want level info", w.Code, w.Body.String())
+	}
+	if w := call(http.MethodPut, "?level=debug"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"level":"debug"`) {
+		t.Errorf("PUT debug = %d %q", w.Code, w.Body.String())
+	}
+	if LogLevel.Level() != slog.LevelDebug {
+		t.Errorf("level = %v after PUT, want DEBUG", LogLevel.Level())
+	}
+	if w := call(http.MethodPut, "?level=loud"); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT invalid level: status = %d, want 400", w.Code)
+	}
+	if w := call(http.MethodPost, "?level=info"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status = %d, want 405", w.Code)
+	}
+}
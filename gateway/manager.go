@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,29 +19,92 @@ const (
 	statusFailed   startStatus = "failed"
 )
 
+// lifecycleStates lists the values returned by lifecycleState.
+var lifecycleStates = []string{"running", "starting", "stopped", "failed"}
+
+// lifecycleState maps the gateway start state and the Docker status to a
+// single container state: running, starting, stopped or failed. It is used for
+// MQTT publishing and the gateway_container_state gauge.
+func lifecycleState(startStatus, dockerStatus string) string {
+	switch {
+	case startStatus == string(statusStarting):
+		return "starting"
+	case dockerStatus == "running":
+		return "running"
+	case startStatus == string(statusFailed):
+		return "failed"
+	default:
+		return "stopped"
+	}
+}
+
 // startState holds the current state of a container start attempt.
 type startState struct {
 	Status startStatus
 	Err    string
+	At     time.Time // when the state was recorded
 }
 
 // ContainerManager orchestrates container lifecycle: starting on demand,
 // preventing concurrent starts, and auto-stopping idle containers.
 type ContainerManager struct {
-	client *DockerClient
+	client    ContainerRuntime
+	health    *HealthTracker
+	breaker   *CircuitBreaker
+	crashes   *CrashLoopTracker
+	selfHeal  *selfHealer
+	updates   *imageUpdates
+	push      *pushMonitor
+	runtime   *RuntimeTracker
+	bandwidth *BandwidthTracker
+	requests  *RequestStats
+	throttle  *bandwidthThrottle
+	limiter   *ConcurrencyLimiter
+	drain     *DrainTracker
+	netAttach *networkAttacher
+	wol       *hostWaker
+	shared    *SharedState
+	usage     *usageHistory
+	lifecycle *lifecycleLog
+	events    *EventBus
+	readOnly  atomic.Bool // gateway.read_only
+	dryRun    *dryRunLog  // gateway.dry_run
 
 	mu          sync.Mutex
 	locks       map[string]*sync.Mutex
 	lastSeen    map[string]time.Time
 	startStates map[string]*startState
+	waitingFrom map[string]time.Time // first loading page shown during the current start
+	idleHeld    map[string]time.Time // first idle check that found the container still busy
 }
 
-func NewContainerManager(client *DockerClient) *ContainerManager {
+func NewContainerManager(client ContainerRuntime) *ContainerManager {
 	return &ContainerManager{
 		client:      client,
+		health:      NewHealthTracker(),
+		breaker:     NewCircuitBreaker(),
+		crashes:     NewCrashLoopTracker(),
+		selfHeal:    newSelfHealer(),
+		updates:     newImageUpdates(),
+		push:        newPushMonitor(),
+		runtime:     NewRuntimeTracker(),
+		bandwidth:   NewBandwidthTracker(),
+		requests:    NewRequestStats(),
+		throttle:    newBandwidthThrottle(),
+		limiter:     NewConcurrencyLimiter(),
+		drain:       NewDrainTracker(),
+		netAttach:   newNetworkAttacher(client),
+		wol:         newHostWaker(),
+		shared:      NewSharedState(),
+		usage:       newUsageHistory(),
+		lifecycle:   newLifecycleLog(),
+		events:      NewEventBus(),
+		dryRun:      newDryRunLog(),
 		locks:       make(map[string]*sync.Mutex),
 		lastSeen:    make(map[string]time.Time),
 		startStates: make(map[string]*startState),
+		waitingFrom: make(map[string]time.Time),
+		idleHeld:    make(map[string]time.Time),
 	}
 }
 
@@ -56,29 +120,102 @@ func (m *ContainerManager) getLock(containerName string) *sync.Mutex {
 
 // setStartState updates the start state for a container (thread-safe).
 func (m *ContainerManager) setStartState(name string, status startStatus, errMsg string) {
+	st := startState{Status: status, Err: errMsg, At: time.Now()}
 	m.mu.Lock()
-	m.startStates[name] = &startState{Status: status, Err: errMsg}
+	m.startStates[name] = &st
+	waitStart, waited := m.waitingFrom[name]
+	if status != statusStarting {
+		delete(m.waitingFrom, name)
+	}
 	m.mu.Unlock()
+	if waited && status == statusRunning {
+		RecordWakeWait(name, st.At.Sub(waitStart).Seconds())
+	}
+	m.shared.publishStartState(name, st)
+	// Keep the state gauge current between metric refreshes. Right after a
+	// transition the start status also describes the Docker state.
+	SetContainerState(name, lifecycleState(string(status), string(status)))
+}
+
+// failStart records a failed start attempt: state, metric and event.
+func (m *ContainerManager) failStart(name string, errMsg string, evType EventType) {
+	m.setStartState(name, statusFailed, errMsg)
+	RecordStart(name, false, 0)
+	m.lifecycle.fail(name, time.Now())
+	m.emit(evType, name, errMsg)
+}
+
+// Events returns the bus on which container lifecycle events are published.
+func (m *ContainerManager) Events() *EventBus {
+	return m.events
+}
+
+// emit publishes a lifecycle event for a container.
+func (m *ContainerManager) emit(evType EventType, name, msg string) {
+	m.events.Publish(Event{Type: evType, Container: name, Message: msg})
 }
 
 // GetStartState returns the current start state for a container.
-// It is used by the server's /_health endpoint.
+// It is used by the server's /_health endpoint. With HA, a newer state
+// recorded by another replica wins over the local one.
 func (m *ContainerManager) GetStartState(name string) (status string, errMsg string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	s, ok := m.startStates[name]
+	s, ok := m.startState(name)
 	if !ok {
 		return "unknown", ""
 	}
 	return string(s.Status), s.Err
 }
 
+// startState returns the latest start state of name, local or shared.
+func (m *ContainerManager) startState(name string) (startState, bool) {
+	m.mu.Lock()
+	s, ok := m.startStates[name]
+	m.mu.Unlock()
+	if remote, found := m.shared.remoteStartState(name); found && (!ok || remote.At.After(s.At)) {
+		return remote, true
+	}
+	if !ok {
+		return startState{}, false
+	}
+	return *s, true
+}
+
+// wokenAt returns when the gateway last brought name up, or the zero time
+// if it is not running because of a gateway start.
+func (m *ContainerManager) wokenAt(name string) time.Time {
+	if s, ok := m.startState(name); ok && s.Status == statusRunning {
+		return s.At
+	}
+	return time.Time{}
+}
+
 // InitStartState marks a container as "starting" before the async goroutine
 // fires. This prevents the first /_health poll from returning "unknown".
 func (m *ContainerManager) InitStartState(name string) {
 	m.setStartState(name, statusStarting, "")
 }
 
+// NoteLoadingPage records that a client was shown the loading page of name.
+// The first one shown while a start is under way begins the wake wait that
+// is observed once the container reports running.
+func (m *ContainerManager) NoteLoadingPage(name string) {
+	RecordRequestOutcome(name, outcomeLoadingPage)
+	m.noteWaiting(name)
+}
+
+// noteWaiting begins the wake wait of name, if a start is under way and no
+// client is waiting yet.
+func (m *ContainerManager) noteWaiting(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if st, ok := m.startStates[name]; !ok || st.Status != statusStarting {
+		return
+	}
+	if _, ok := m.waitingFrom[name]; !ok {
+		m.waitingFrom[name] = time.Now()
+	}
+}
+
 // RecordActivity records the current time as the last activity for a container.
 // Call this on every successfully proxied request.
 func (m *ContainerManager) RecordActivity(containerName string) {
@@ -89,7 +226,9 @@ func (m *ContainerManager) RecordActivity(containerName string) {
 
 // RecordActivityChain records activity for each root container and all of its
 // transitive dependencies. Use this in place of RecordActivity at server call
-// sites, where the full container config is available.
+// sites, where the full container config is available. The hours recorded
+// here also feed the usage history used by prewarm; activity recorded by
+// starts alone does not, so pre-warming cannot reinforce itself.
 //
 // If TopologicalSort fails for a root (e.g. container not yet in config during
 // a discovery lag), RecordActivity is called for that root as a silent fallback.
@@ -102,6 +241,7 @@ func (m *ContainerManager) RecordActivityChain(roots []string, allContainers []C
 		if err != nil {
 			// Transient condition (discovery lag): fall back to recording the root only.
 			m.RecordActivity(root)
+			m.usage.record([]string{root}, now)
 			continue
 		}
 		for _, name := range chain {
@@ -114,10 +254,13 @@ func (m *ContainerManager) RecordActivityChain(roots []string, allContainers []C
 	}
 
 	m.mu.Lock()
+	names := make([]string, 0, len(toUpdate))
 	for name := range toUpdate {
 		m.lastSeen[name] = now
+		names = append(names, name)
 	}
 	m.mu.Unlock()
+	m.usage.record(names, now)
 }
 
 // GetLastSeen returns the last activity timestamp for a container.
@@ -129,6 +272,165 @@ func (m *ContainerManager) GetLastSeen(containerName string) (time.Time, bool) {
 	return t, ok
 }
 
+// RecordProxyResult feeds the status code of a proxied request into the
+// passive health tracker and the circuit breaker. When the failure streak
+// reaches the container's unhealthy_threshold the container is marked degraded
+// and, if unhealthy_restart is set, restarted in the background.
+func (m *ContainerManager) RecordProxyResult(cfg *ContainerConfig, statusCode int) {
+	if !isProxyFailure(statusCode) {
+		m.health.RecordSuccess(cfg.Name)
+		m.breaker.RecordSuccess(cfg.Name)
+		return
+	}
+	if m.breaker.RecordFailure(cfg.Name, cfg.CircuitBreakerThreshold) {
+		slog.Warn("circuit breaker: circuit opened",
+			"container", cfg.Name, "cooldown", cfg.CircuitBreakerCooldown)
+		m.emit(EventCircuitOpen, cfg.Name, fmt.Sprintf("circuit opened for %s after repeated backend failures", cfg.CircuitBreakerCooldown))
+	}
+	if !m.health.RecordFailure(cfg.Name, cfg.UnhealthyThreshold) {
+		return
+	}
+	slog.Warn("passive health: container marked degraded",
+		"container", cfg.Name, "consecutive_failures", m.health.Failures(cfg.Name))
+	m.emit(EventDegraded, cfg.Name, fmt.Sprintf("marked degraded after %d consecutive proxy failures", m.health.Failures(cfg.Name)))
+	if cfg.UnhealthyRestart && !m.ReadOnly() {
+		restartCfg := *cfg
+		go m.restartContainer(&restartCfg, "passive_health") //nolint:errcheck
+	}
+}
+
+// RuntimeSummary returns how long a container ran and slept, and how often it
+// was woken, over the last savingsWindowDays.
+func (m *ContainerManager) RuntimeSummary(name string) UsageSummary {
+	return m.runtime.Summary(name, time.Now())
+}
+
+// CrashLoopState reports whether the container is crash-looping, until when
+// start attempts are suppressed, and the number of consecutive rapid exits.
+func (m *ContainerManager) CrashLoopState(name string) (looping bool, until time.Time, count int) {
+	return m.crashes.Backoff(name)
+}
+
+// crashLoopMessage is the user-facing description of a crash loop.
+func crashLoopMessage(count int, until time.Time) string {
+	wait := time.Until(until).Round(time.Second)
+	if wait <= 0 {
+		return fmt.Sprintf("crash-looping: exited %d times shortly after start", count)
+	}
+	return fmt.Sprintf("crash-looping: exited %d times shortly after start, next attempt in %s", count, wait)
+}
+
+// IsDegraded reports whether passive health checking marked the container degraded.
+func (m *ContainerManager) IsDegraded(name string) bool {
+	return m.health.IsDegraded(name)
+}
+
+// restartContainer stops and re-starts a container, waiting for its readiness
+// probe like a regular on-demand start. reason is included in the logs.
+func (m *ContainerManager) restartContainer(cfg *ContainerConfig, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.StartTimeout+30*time.Second)
+	defer cancel()
+
+	if m.ReadOnly() {
+		slog.Warn("restart skipped: the gateway is read-only", "container", cfg.Name, "reason", reason)
+		return ErrReadOnly
+	}
+	if !m.client.Capable(CapabilityStop) {
+		slog.Warn("restart skipped: stopping containers is not allowed by the Docker API",
+			"container", cfg.Name, "reason", reason)
+		return capabilityError(CapabilityStop)
+	}
+	if m.DryRun() {
+		m.simulate(dryRunRestart, cfg.Name, reason, time.Now())
+		return ErrDryRun
+	}
+	slog.Info("restarting container", "container", cfg.Name, "reason", reason)
+	if err := m.client.StopContainer(ctx, cfg.Name); err != nil {
+		slog.Error("restart: stop failed", "container", cfg.Name, "reason", reason, "error", err)
+		return err
+	}
+	m.crashes.MarkStopped(cfg.Name)
+	m.InitStartState(cfg.Name)
+	if err := m.EnsureRunning(ctx, cfg); err != nil {
+		slog.Error("restart: start failed", "container", cfg.Name, "reason", reason, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Sleep stops a running container on request (e.g. an MQTT command or
+// /_status/sleep) and clears its start state. New requests are refused while
+// the requests in flight drain; after drainTimeout the container is stopped
+// anyway. Dependencies are not stopped.
+func (m *ContainerManager) Sleep(ctx context.Context, name string, drainTimeout time.Duration) error {
+	if m.ReadOnly() {
+		return ErrReadOnly
+	}
+	if !m.client.Capable(CapabilityStop) {
+		return capabilityError(CapabilityStop)
+	}
+	status, err := m.client.GetContainerStatus(ctx, name)
+	if err != nil {
+		return err
+	}
+	if status != "running" {
+		return nil
+	}
+
+	defer m.drain.Finish(name)
+	drainCtx, cancel := context.WithTimeout(ctx, drainTimeout)
+	err = m.drain.Drain(drainCtx, name)
+	cancel()
+	if err != nil {
+		slog.Warn("sleep: drain timed out, stopping anyway",
+			"container", name, "in_flight", m.drain.InFlight(name), "timeout", drainTimeout)
+	}
+
+	if err := m.client.StopContainer(ctx, name); err != nil {
+		return err
+	}
+	m.crashes.MarkStopped(name)
+	m.runtime.Observe(name, false, time.Now())
+	m.lifecycle.sleep(name, time.Now())
+	m.setStartState(name, "unknown", "")
+	return nil
+}
+
+// Kill force-stops a container with SIGKILL, skipping the drain and grace
+// period of Sleep, and clears its start state. It is the way out of a
+// container that hangs on stop or a start attempt that never completes.
+// Dependencies are not stopped.
+func (m *ContainerManager) Kill(ctx context.Context, name string) error {
+	if m.ReadOnly() {
+		return ErrReadOnly
+	}
+	status, err := m.client.GetContainerStatus(ctx, name)
+	if err != nil {
+		return err
+	}
+	if status == "running" || status == "restarting" || status == "paused" {
+		if err := m.client.KillContainer(ctx, name); err != nil {
+			return err
+		}
+		m.crashes.MarkStopped(name)
+		m.runtime.Observe(name, false, time.Now())
+		m.lifecycle.sleep(name, time.Now())
+	}
+	m.setStartState(name, "unknown", "")
+	return nil
+}
+
+// ResetStartState forgets the start state and crash-loop backoff of name, so
+// a "starting" or "failed" state left by a wedged start attempt no longer
+// shows and the next request makes a fresh attempt. It returns the state
+// that was cleared.
+func (m *ContainerManager) ResetStartState(name string) string {
+	prev, _ := m.GetStartState(name)
+	m.crashes.Reset(name)
+	m.setStartState(name, "unknown", "")
+	return prev
+}
+
 // BuildReverseDeps returns, for each container D, the list of containers that
 // declare D in their DependsOn field (direct dependents only).
 func BuildReverseDeps(cfgs []ContainerConfig) map[string][]string {
@@ -142,83 +444,213 @@ func BuildReverseDeps(cfgs []ContainerConfig) map[string][]string {
 }
 
 // EnsureRunning checks whether a container is running and, if not, starts it.
-// Flow: docker start → wait for "running" state → TCP probe → mark ready.
+// Flow: docker start → wait for "running" state → readiness check → mark ready.
 // Uses cfg.StartTimeout as the total budget for the entire sequence.
 func (m *ContainerManager) EnsureRunning(ctx context.Context, cfg *ContainerConfig) error {
+	ctx, span := StartSpan(ctx, "container.start")
+	span.SetAttr("gateway.container", cfg.Name)
+	err := m.ensureRunning(ctx, cfg, span)
+	span.SetError(err)
+	span.End()
+	return err
+}
+
+func (m *ContainerManager) ensureRunning(ctx context.Context, cfg *ContainerConfig, span *Span) error {
 	// Check current Docker status
 	mu := m.getLock(cfg.Name)
 	mu.Lock()
 	defer mu.Unlock()
 
-	// Check if already running
-	status, err := m.client.GetContainerStatus(ctx, cfg.Name)
-	if err == nil && status == "running" {
+	// Check if already running. An unreachable daemon may be a suspended
+	// Docker host to power on first (wake_on_lan).
+	info, err := m.client.InspectContainer(ctx, cfg.Name)
+	if err != nil && m.wol.enabled() && !m.ReadOnly() {
+		if werr := m.wol.wake(ctx, m.client); werr != nil {
+			m.setStartState(cfg.Name, statusFailed, werr.Error())
+			m.lifecycle.fail(cfg.Name, time.Now())
+			return werr
+		}
+		info, err = m.client.InspectContainer(ctx, cfg.Name)
+	}
+	if err == nil && info.Status == "running" {
+		span.SetAttr("gateway.already_running", true)
 		m.RecordActivity(cfg.Name)
 		return nil
 	}
+	if m.ReadOnly() {
+		return fmt.Errorf("container %q is not running: %w", cfg.Name, ErrReadOnly)
+	}
+
+	// Crash-loop detection: account for the last exit and honour the backoff.
+	if err == nil && (info.Status == "exited" || info.Status == "dead") {
+		m.crashes.ObserveExit(cfg.Name, info.StartedAt, info.FinishedAt)
+	}
+	if looping, until, count := m.crashes.Backoff(cfg.Name); looping && time.Now().Before(until) {
+		m.setStartState(cfg.Name, statusFailed, crashLoopMessage(count, until))
+		RecordStart(cfg.Name, false, 0)
+		return fmt.Errorf("container %q is crash-looping, next start attempt in %s",
+			cfg.Name, time.Until(until).Round(time.Second))
+	}
 
 	start := time.Now()
 	m.setStartState(cfg.Name, statusStarting, "")
 
-	// Ask Docker to start it
-	if err := m.client.StartContainer(ctx, cfg.Name); err != nil {
-		m.setStartState(cfg.Name, statusFailed, "docker start failed")
-		RecordStart(cfg.Name, false, 0)
-		return fmt.Errorf("failed to start container %q: %w", cfg.Name, err)
+	// With HA, only the replica holding the start lock calls Docker and
+	// announces the start; the others just wait for readiness.
+	owner := m.shared.acquireStart(ctx, cfg.Name, cfg.StartTimeout)
+	if owner {
+		defer m.shared.releaseStart(context.WithoutCancel(ctx), cfg.Name)
+
+		if err := runHooks(ctx, m.client.ExecCommand, cfg.Name, hookPreStart, cfg.Hooks.PreStart); err != nil {
+			m.failStart(cfg.Name, err.Error(), EventStartFailure)
+			return fmt.Errorf("container %q: %w", cfg.Name, err)
+		}
+
+		// Ask Docker to start it
+		_, dockerSpan := StartSpan(ctx, "docker.start")
+		err = m.client.StartContainer(ctx, cfg.Name)
+		dockerSpan.SetError(err)
+		dockerSpan.End()
+		if err != nil {
+			m.failStart(cfg.Name, "docker start failed", EventStartFailure)
+			return fmt.Errorf("failed to start container %q: %w", cfg.Name, err)
+		}
+	} else {
+		span.SetAttr("gateway.started_by_peer", true)
 	}
 
 	// Poll until readiness probe passes or context expires
-	ip, err := m.client.GetContainerAddress(ctx, cfg.Name, cfg.Network)
+	host, port, err := m.resolveTarget(ctx, cfg)
 	if err != nil {
-		m.setStartState(cfg.Name, statusFailed, "cannot find container IP")
-		RecordStart(cfg.Name, false, 0)
-		return fmt.Errorf("failed to get IP for %q: %w", cfg.Name, err)
+		m.failStart(cfg.Name, "cannot find container address", EventStartFailure)
+		return fmt.Errorf("failed to get address for %q: %w", cfg.Name, err)
 	}
 
-	targetAddr := fmt.Sprintf("%s:%s", ip, cfg.TargetPort)
-	ticker := time.NewTicker(500 * time.Millisecond)
+	targetAddr := net.JoinHostPort(host, port)
+	opts := ProbeOptionsFor(cfg)
+
+	// The readiness span covers the initial delay and every probe attempt.
+	ctx, readySpan := StartSpan(ctx, "container.readiness")
+	defer readySpan.End()
+	readySpan.SetAttr("gateway.readiness", cfg.Readiness)
+	attempts := 0
+
+	// Give slow-booting apps a head start before the first probe.
+	if cfg.ProbeInitialDelay > 0 {
+		select {
+		case <-ctx.Done():
+			m.failStart(cfg.Name, "startup timeout exceeded", EventStartFailure)
+			return fmt.Errorf("timeout waiting for %q (%s) to be reachable", cfg.Name, targetAddr)
+		case <-time.After(cfg.ProbeInitialDelay):
+		}
+	}
+
+	ticker := time.NewTicker(opts.interval())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			m.setStartState(cfg.Name, statusFailed, "startup timeout exceeded")
-			RecordStart(cfg.Name, false, 0)
+			m.failStart(cfg.Name, "startup timeout exceeded", EventStartFailure)
 			return fmt.Errorf("timeout waiting for %q (%s) to be reachable", cfg.Name, targetAddr)
 		case <-ticker.C:
 			// Ensure container didn't crash during boot
-			status, _ := m.client.GetContainerStatus(ctx, cfg.Name)
-			if status == "exited" || status == "dead" {
-				m.setStartState(cfg.Name, statusFailed, "container crashed on boot (see docker logs)")
-				RecordStart(cfg.Name, false, 0)
+			bootInfo, err := m.client.InspectContainer(ctx, cfg.Name)
+			if err == nil && (bootInfo.Status == "exited" || bootInfo.Status == "dead") {
+				m.crashes.ObserveExit(cfg.Name, bootInfo.StartedAt, bootInfo.FinishedAt)
+				msg, evType := "container crashed on boot (see docker logs)", EventStartFailure
+				if looping, until, count := m.crashes.Backoff(cfg.Name); looping {
+					msg, evType = crashLoopMessage(count, until), EventCrashLoop
+				}
+				m.failStart(cfg.Name, msg, evType)
 				return fmt.Errorf("container %q crashed during boot", cfg.Name)
 			}
 
-			// Readiness probe: HTTP if health_path is set, TCP otherwise
-			var probeErr error
-			if cfg.HealthPath != "" {
-				probeErr = m.client.ProbeHTTP(ctx, ip, cfg.TargetPort, cfg.HealthPath)
-			} else {
-				conn, dialErr := net.DialTimeout("tcp", targetAddr, 500*time.Millisecond)
-				if dialErr == nil {
-					conn.Close()
-				}
-				probeErr = dialErr
+			attempts++
+			readySpan.SetAttr("gateway.probe_attempts", attempts)
+			ready, err := m.checkReady(ctx, cfg, host, port, opts)
+			if err != nil {
+				m.failStart(cfg.Name, err.Error(), EventStartFailure)
+				return fmt.Errorf("container %q failed readiness: %w", cfg.Name, err)
 			}
-			if probeErr == nil {
+			if ready {
+				// A failing post-ready hook does not fail the start: the
+				// container is served, with the error shown in its state.
+				hookErr := ""
+				if owner {
+					warmUp(ctx, cfg, host, port, opts)
+					if err := runHooks(ctx, m.client.ExecCommand, cfg.Name, hookPostReady, cfg.Hooks.PostReady); err != nil {
+						slog.Warn("post-ready hook failed", "container", cfg.Name, "error", err)
+						hookErr = err.Error()
+					}
+				}
+				m.health.Reset(cfg.Name)
 				m.RecordActivity(cfg.Name)
-				m.setStartState(cfg.Name, statusRunning, "")
+				m.setStartState(cfg.Name, statusRunning, hookErr)
 				RecordStart(cfg.Name, true, time.Since(start).Seconds())
+				m.runtime.Observe(cfg.Name, true, time.Now())
+				m.runtime.RecordWake(cfg.Name, time.Now())
+				m.lifecycle.wake(cfg.Name, time.Now(), time.Since(start))
+				if owner {
+					m.emit(EventStartSuccess, cfg.Name, fmt.Sprintf("ready after %s", time.Since(start).Round(100*time.Millisecond)))
+				}
 				return nil
 			}
 		}
 	}
 }
 
+// checkReady performs a single readiness check according to cfg.Readiness.
+// It returns (false, nil) while the container is still warming up and a
+// non-nil error only when Docker reports the container as "unhealthy".
+func (m *ContainerManager) checkReady(ctx context.Context, cfg *ContainerConfig, host, port string, opts ProbeOptions) (bool, error) {
+	if cfg.Readiness == ReadinessDockerHealth || cfg.Readiness == ReadinessBoth {
+		_, span := StartSpan(ctx, "probe.docker_health")
+		health, err := m.client.GetContainerHealth(ctx, cfg.Name)
+		span.SetAttr("gateway.health", health)
+		span.End()
+		if err != nil {
+			return false, nil
+		}
+		switch health {
+		case "healthy":
+			if cfg.Readiness == ReadinessDockerHealth {
+				return true, nil
+			}
+		case "unhealthy":
+			return false, fmt.Errorf("container reported unhealthy by its HEALTHCHECK")
+		case "":
+			// No HEALTHCHECK defined in the image — fall back to the probe.
+		default:
+			return false, nil
+		}
+	}
+
+	// Readiness probe: HTTP if health_path is set, TCP otherwise
+	var err error
+	if cfg.HealthPath != "" {
+		_, span := StartSpan(ctx, "probe.http")
+		span.SetAttr("url.path", cfg.HealthPath)
+		err = probeHTTPOnce(ctx, host, port, cfg.HealthPath, opts)
+		span.SetError(err)
+		span.End()
+	} else {
+		_, span := StartSpan(ctx, "probe.tcp")
+		err = probeTCPOnce(ctx, host, port, opts)
+		span.SetError(err)
+		span.End()
+	}
+	return err == nil, nil
+}
+
 // EnsureDepsRunning starts all dependencies for a container in topological order.
 // Each dependency is started sequentially and must pass its readiness probe
 // before the next one begins. Fails fast if any dependency fails.
 func (m *ContainerManager) EnsureDepsRunning(ctx context.Context, target string, allContainers []ContainerConfig) error {
+	ctx, span := StartSpan(ctx, "container.start_dependencies")
+	defer span.End()
+	span.SetAttr("gateway.container", target)
+
 	order, err := TopologicalSort(target, allContainers)
 	if err != nil {
 		return fmt.Errorf("dependency resolution failed for %q: %w", target, err)
@@ -249,9 +681,26 @@ func (m *ContainerManager) EnsureDepsRunning(ctx context.Context, target string,
 	return nil
 }
 
+// Wake starts cfg the way a proxied request does: its dependencies first, in
+// topological order, then the container itself. Every programmatic wake
+// (dashboard, MQTT) goes through here so none leaves a half-started stack.
+func (m *ContainerManager) Wake(ctx context.Context, cfg *ContainerConfig, allContainers []ContainerConfig) error {
+	if len(cfg.DependsOn) > 0 {
+		if err := m.EnsureDepsRunning(ctx, cfg.Name, allContainers); err != nil {
+			m.setStartState(cfg.Name, statusFailed, err.Error())
+			return err
+		}
+	}
+	return m.EnsureRunning(ctx, cfg)
+}
+
 // EnsureGroupRunning starts all group members and their dependencies,
 // returning nil when every member is running and ready.
 func (m *ContainerManager) EnsureGroupRunning(ctx context.Context, group *GroupConfig, allContainers []ContainerConfig) error {
+	ctx, span := StartSpan(ctx, "group.start")
+	defer span.End()
+	span.SetAttr("gateway.group", group.Name)
+	start := time.Now()
 	cfgMap := make(map[string]*ContainerConfig, len(allContainers))
 	for i := range allContainers {
 		cfgMap[allContainers[i].Name] = &allContainers[i]
@@ -264,17 +713,56 @@ func (m *ContainerManager) EnsureGroupRunning(ctx context.Context, group *GroupC
 		}
 	}
 
-	// Start all group members.
+	members := make([]*ContainerConfig, 0, len(group.Containers))
 	for _, memberName := range group.Containers {
 		memberCfg, ok := cfgMap[memberName]
 		if !ok {
 			return fmt.Errorf("group %q: member %q not found", group.Name, memberName)
 		}
-		m.InitStartState(memberName)
-		if err := m.EnsureRunning(ctx, memberCfg); err != nil {
-			return fmt.Errorf("group %q: member %q failed: %w", group.Name, memberName, err)
+		members = append(members, memberCfg)
+	}
+
+	// Start the members in start_order, start_stagger apart.
+	startMember := func(cfg *ContainerConfig) error {
+		m.InitStartState(cfg.Name)
+		if err := m.EnsureRunning(ctx, cfg); err != nil {
+			return fmt.Errorf("group %q: member %q failed: %w", group.Name, cfg.Name, err)
+		}
+		return nil
+	}
+	parallel := group.StartOrder == startOrderParallel
+	span.SetAttr("gateway.group_start_order", group.StartOrder)
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, memberCfg := range members {
+		if i > 0 && group.StartStagger > 0 {
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return fmt.Errorf("group %q: %w", group.Name, ctx.Err())
+			case <-time.After(group.StartStagger):
+			}
+		}
+		if !parallel {
+			if err := startMember(memberCfg); err != nil {
+				return err
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = startMember(memberCfg)
+		}()
+	}
+	wg.Wait()
+	// Report the first failing member in Containers order.
+	for _, err := range errs {
+		if err != nil {
+			return err
 		}
 	}
+	RecordGroupStart(group.Name, time.Since(start).Seconds())
 	return nil
 }
 
@@ -287,7 +775,7 @@ func (m *ContainerManager) EnsureGroupRunning(ctx context.Context, group *GroupC
 // 		m.setStartState(cfg.Name, statusFailed, msg)
 // 		return fmt.Errorf("%s", msg)
 // 	}
-// 	if err := m.client.ProbeTCP(ctx, ip, cfg.TargetPort); err != nil {
+// 	if err := m.client.ProbeTCP(ctx, ip, cfg.TargetPort, ProbeOptionsFor(cfg)); err != nil {
 // 		msg := fmt.Sprintf("app not responding on port %s: %v", cfg.TargetPort, err)
 // 		m.setStartState(cfg.Name, statusFailed, msg)
 // 		return fmt.Errorf("%s", msg)
@@ -354,6 +842,10 @@ func (m *ContainerManager) cascadeStop(ctx context.Context, idleEntryPoints []st
 
 	revDeps := BuildReverseDeps(cfgs)
 	order := topoMergeStop(toStop, cfgs)
+	byName := make(map[string]*ContainerConfig, len(cfgs))
+	for i := range cfgs {
+		byName[cfgs[i].Name] = &cfgs[i]
+	}
 
 	for i := len(order) - 1; i >= 0; i-- {
 		name := order[i]
@@ -380,22 +872,85 @@ func (m *ContainerManager) cascadeStop(ctx context.Context, idleEntryPoints []st
 			continue
 		}
 
+		var hooks HooksConfig
+		if c, ok := byName[name]; ok {
+			hooks = c.Hooks
+		}
+		if err := runPreStopHooks(ctx, m.client.ExecCommand, name, hooks.PreStop); err != nil {
+			// Its dependencies must now keep running too.
+			delete(toStop, name)
+			slog.Warn("idle watcher: stop vetoed by hook, retrying at the next check",
+				"container", name, "error", err)
+			continue
+		}
+
+		// Refuse new requests and let the ones in flight finish first.
+		drainCtx, cancel := context.WithTimeout(ctx, idleStopDrainTimeout)
+		if err := m.drain.Drain(drainCtx, name); err != nil {
+			slog.Warn("idle watcher: drain timed out, stopping anyway",
+				"container", name, "in_flight", m.drain.InFlight(name), "timeout", idleStopDrainTimeout)
+		}
+		cancel()
+
 		slog.Info("idle watcher: cascade stopping container",
 			"container", name, "reason", "cascade_idle",
 			"triggered_by", idleEntryPoints)
 		if err := m.client.StopContainer(ctx, name); err != nil {
+			m.drain.Finish(name)
 			slog.Error("idle watcher: cascade stop failed",
 				"container", name, "error", err)
 		} else {
 			RecordIdleStop(name)
+			m.crashes.MarkStopped(name)
+			m.runtime.Observe(name, false, time.Now())
+			m.lifecycle.sleep(name, time.Now())
 			m.setStartState(name, "unknown", "")
+			m.drain.Finish(name)
+			m.emit(EventIdleStop, name, "stopped after idle timeout")
+			if err := runHooks(ctx, m.client.ExecCommand, name, hookPostStop, hooks.PostStop); err != nil {
+				slog.Warn("idle watcher: post-stop hook failed", "container", name, "error", err)
+			}
 		}
 	}
 }
 
+// holdIdleStop reports whether the idle stop of name must wait because
+// requests or WebSocket tunnels to it are still active. The stop is held for
+// at most limit from the first check that found it busy.
+func (m *ContainerManager) holdIdleStop(name string, now time.Time, limit time.Duration) bool {
+	active := m.drain.Active(name)
+	if active == 0 {
+		m.releaseIdleHold(name)
+		return false
+	}
+	m.mu.Lock()
+	since, ok := m.idleHeld[name]
+	if !ok {
+		since = now
+		m.idleHeld[name] = now
+	}
+	m.mu.Unlock()
+	if now.Sub(since) < limit {
+		slog.Debug("idle watcher: stop postponed, connections still active",
+			"container", name, "active", active)
+		return true
+	}
+	slog.Warn("idle watcher: connections still active after idle_drain_timeout, stopping anyway",
+		"container", name, "active", active, "idle_drain_timeout", limit)
+	m.releaseIdleHold(name)
+	return false
+}
+
+func (m *ContainerManager) releaseIdleHold(name string) {
+	m.mu.Lock()
+	delete(m.idleHeld, name)
+	m.mu.Unlock()
+}
+
 // StartIdleWatcher begins a background routine that periodically checks
 // container activity. If a container's idle_timeout is reached, it shuts it down.
-func (m *ContainerManager) StartIdleWatcher(ctx context.Context, configProvider func() []ContainerConfig) {
+// With HA, only the leader replica stops containers.
+func (m *ContainerManager) StartIdleWatcher(ctx context.Context, configProvider func() *GatewayConfig) {
 	go func() {
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
@@ -404,13 +959,19 @@ func (m *ContainerManager) StartIdleWatcher(ctx context.Context, configProvider
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				m.checkIdle(ctx, configProvider())
+				if m.shared.isLeader() {
+					m.checkIdle(ctx, configProvider())
+				}
 			}
 		}
 	}()
 }
 
-func (m *ContainerManager) checkIdle(ctx context.Context, cfgs []ContainerConfig) {
+func (m *ContainerManager) checkIdle(ctx context.Context, gcfg *GatewayConfig) {
+	if m.ReadOnly() || !m.client.Capable(CapabilityStop) {
+		return // logged once by LogCapabilities
+	}
+	cfgs := gcfg.Containers
 	m.mu.Lock()
 	snapshot := make(map[string]time.Time, len(m.lastSeen))
 	for k, v := range m.lastSeen {
@@ -420,19 +981,32 @@ func (m *ContainerManager) checkIdle(ctx context.Context, cfgs []ContainerConfig
 
 	now := time.Now()
 	var idleEntryPoints []string
-	for _, cfg := range cfgs {
-		// Only entry-points (Host != "") govern idle shutdown.
-		// Pure deps (no Host) are stopped only as part of an entry-point's cascade.
-		if cfg.Host == "" || cfg.IdleTimeout == 0 {
+	for i, cfg := range cfgs {
+		// Only entry-points (routed by a host or host_pattern) govern idle
+		// shutdown. Pure deps are stopped only as part of an entry-point's cascade.
+		if gcfg.ContainerHost(&cfgs[i]) == "" || cfg.IdleTimeout == 0 {
 			continue
 		}
 		last, seen := snapshot[cfg.Name]
 		if !seen {
 			continue
 		}
-		if now.Sub(last) >= cfg.IdleTimeout {
-			idleEntryPoints = append(idleEntryPoints, cfg.Name)
+		if cfg.MinUptime > 0 && now.Sub(m.wokenAt(cfg.Name)) < cfg.MinUptime {
+			continue
+		}
+		if now.Sub(last) < cfg.IdleTimeout {
+			m.releaseIdleHold(cfg.Name)
+			continue
+		}
+		if m.holdIdleStop(cfg.Name, now, cfg.IdleDrainTimeout) {
+			continue
+		}
+		if m.DryRun() {
+			m.simulate(dryRunStop, cfg.Name, fmt.Sprintf("idle for %s (idle_timeout %s)",
+				now.Sub(last).Round(time.Second), cfg.IdleTimeout), last)
+			continue
 		}
+		idleEntryPoints = append(idleEntryPoints, cfg.Name)
 	}
 
 	if len(idleEntryPoints) > 0 {
@@ -2,7 +2,12 @@ package gateway
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -10,7 +15,7 @@ import (
 // ─── Start State Lifecycle ────────────────────────────────────────────────────
 
 func TestStartStateLifecycle(t *testing.T) {
-	m := NewContainerManager(nil) // no Docker client needed for state tests
+	m := NewContainerManager(NewFakeRuntime()) // no Docker client needed for state tests
 
 	t.Run("unknown container returns unknown", func(t *testing.T) {
 		status, errMsg := m.GetStartState("nonexistent")
@@ -56,10 +61,37 @@ func TestStartStateLifecycle(t *testing.T) {
 	})
 }
 
+// ─── lifecycleState ───────────────────────────────────────────────────────────
+
+func TestLifecycleState(t *testing.T) {
+	tests := []struct {
+		startStatus  string
+		dockerStatus string
+		want         string
+	}{
+		{"starting", "running", "starting"},
+		{"starting", "exited", "starting"},
+		{"running", "running", "running"},
+		{"unknown", "running", "running"},
+		{"failed", "exited", "failed"},
+		{"failed", "running", "running"},
+		{"unknown", "exited", "stopped"},
+		{"unknown", "", "stopped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.startStatus+"/"+tt.dockerStatus, func(t *testing.T) {
+			if got := lifecycleState(tt.startStatus, tt.dockerStatus); got != tt.want {
+				t.Errorf("lifecycleState(%q, %q) = %q, want %q",
+					tt.startStatus, tt.dockerStatus, got, tt.want)
+			}
+		})
+	}
+}
+
 // ─── RecordActivity & GetLastSeen ─────────────────────────────────────────────
 
 func TestRecordActivity(t *testing.T) {
-	m := NewContainerManager(nil)
+	m := NewContainerManager(NewFakeRuntime())
 
 	t.Run("unseen container returns false", func(t *testing.T) {
 		_, ok := m.GetLastSeen("never-seen")
@@ -99,7 +131,7 @@ func TestRecordActivity(t *testing.T) {
 // ─── getLock ──────────────────────────────────────────────────────────────────
 
 func TestGetLock(t *testing.T) {
-	m := NewContainerManager(nil)
+	m := NewContainerManager(NewFakeRuntime())
 
 	t.Run("same name returns same mutex", func(t *testing.T) {
 		l1 := m.getLock("app")
@@ -134,7 +166,7 @@ func TestGetLock(t *testing.T) {
 // ─── State management thread safety ──────────────────────────────────────────
 
 func TestStartState_ConcurrentAccess(t *testing.T) {
-	m := NewContainerManager(nil)
+	m := NewContainerManager(NewFakeRuntime())
 
 	var wg sync.WaitGroup
 	for i := 0; i < 50; i++ {
@@ -287,7 +319,7 @@ func TestBuildReverseDeps(t *testing.T) {
 
 func TestRecordActivityChain(t *testing.T) {
 	t.Run("single container no deps: only root updated", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local"},
 		}
@@ -305,7 +337,7 @@ func TestRecordActivityChain(t *testing.T) {
 	})
 
 	t.Run("linear chain A→B→C: all three updated", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", DependsOn: []string{"api"}},
 			{Name: "api", DependsOn: []string{"db"}},
@@ -327,7 +359,7 @@ func TestRecordActivityChain(t *testing.T) {
 	})
 
 	t.Run("multiple roots: union of deps updated", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", DependsOn: []string{"db"}},
 			{Name: "other", Host: "other.local", DependsOn: []string{"redis"}},
@@ -350,7 +382,7 @@ func TestRecordActivityChain(t *testing.T) {
 	})
 
 	t.Run("unknown root: falls back to RecordActivity, no panic", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "other", Host: "other.local"},
 		}
@@ -366,7 +398,7 @@ func TestRecordActivityChain(t *testing.T) {
 	})
 
 	t.Run("shared dep updated once (deduplication)", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", DependsOn: []string{"db"}},
 			{Name: "other", Host: "other.local", DependsOn: []string{"db"}},
@@ -444,7 +476,7 @@ func TestCascadeStop(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("linear chain A→B→C: stops in order A, B, C", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", IdleTimeout: 30 * time.Minute, DependsOn: []string{"api"}},
 			{Name: "api", DependsOn: []string{"db"}},
@@ -477,7 +509,7 @@ func TestCascadeStop(t *testing.T) {
 	})
 
 	t.Run("shared dep: A→db, B→db; B running → db NOT stopped", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", IdleTimeout: 30 * time.Minute, DependsOn: []string{"db"}},
 			{Name: "other", Host: "other.local", IdleTimeout: time.Hour, DependsOn: []string{"db"}},
@@ -509,7 +541,7 @@ func TestCascadeStop(t *testing.T) {
 	})
 
 	t.Run("shared dep: both A and B idle → db stopped", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", IdleTimeout: 30 * time.Minute, DependsOn: []string{"db"}},
 			{Name: "other", Host: "other.local", IdleTimeout: 30 * time.Minute, DependsOn: []string{"db"}},
@@ -542,7 +574,7 @@ func TestCascadeStop(t *testing.T) {
 	})
 
 	t.Run("already stopped containers are skipped", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", IdleTimeout: 30 * time.Minute, DependsOn: []string{"db"}},
 			{Name: "db"},
@@ -573,7 +605,7 @@ func TestCascadeStop(t *testing.T) {
 	})
 
 	t.Run("multiple entry-points idle simultaneously: chains merged", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", IdleTimeout: 30 * time.Minute, DependsOn: []string{"api"}},
 			{Name: "other", Host: "other.local", IdleTimeout: 30 * time.Minute, DependsOn: []string{"cache"}},
@@ -611,7 +643,7 @@ func TestCascadeStop(t *testing.T) {
 
 func TestCheckIdle_Cascade(t *testing.T) {
 	t.Run("entry-point not idle yet: no Docker call attempted", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", IdleTimeout: 30 * time.Minute, DependsOn: []string{"db"}},
 			{Name: "db"},
@@ -625,11 +657,11 @@ func TestCheckIdle_Cascade(t *testing.T) {
 				t.Errorf("checkIdle panicked (tried to call Docker): %v", r)
 			}
 		}()
-		m.checkIdle(context.Background(), cfgs)
+		m.checkIdle(context.Background(), &GatewayConfig{Containers: cfgs})
 	})
 
 	t.Run("pure dep with idle_timeout and no Host: ignored by checkIdle", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "db", IdleTimeout: time.Minute},
 		}
@@ -642,11 +674,137 @@ func TestCheckIdle_Cascade(t *testing.T) {
 				t.Errorf("checkIdle panicked (tried to stop pure dep): %v", r)
 			}
 		}()
-		m.checkIdle(context.Background(), cfgs)
+		m.checkIdle(context.Background(), &GatewayConfig{Containers: cfgs})
+	})
+
+	t.Run("host_pattern container without Host: stopped when idle", func(t *testing.T) {
+		var stopped atomic.Bool
+		daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/containers/app/json"):
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"Name":"/app","State":{"Status":"running","Running":true}}`))
+			case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/containers/app/stop"):
+				stopped.Store(true)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer daemon.Close()
+
+		m := NewContainerManager(newTestDockerClient(t, daemon.URL))
+		gcfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", IdleTimeout: time.Minute}}}
+		gcfg.Gateway.HostPattern = "{container}.apps.example.com"
+		m.mu.Lock()
+		m.lastSeen["app"] = time.Now().Add(-2 * time.Minute)
+		m.mu.Unlock()
+
+		m.checkIdle(context.Background(), gcfg)
+		if !stopped.Load() {
+			t.Error("idle host_pattern container was not stopped")
+		}
+	})
+
+	t.Run("pre_stop hook veto: not stopped", func(t *testing.T) {
+		var stopped atomic.Bool
+		daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/json"):
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"State":{"Status":"running","Running":true}}`))
+			case strings.HasSuffix(r.URL.Path, "/stop"):
+				stopped.Store(true)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer daemon.Close()
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict) // backup still running
+		}))
+		defer hook.Close()
+
+		m := NewContainerManager(newTestDockerClient(t, daemon.URL))
+		cfgs := []ContainerConfig{
+			{Name: "app", Host: "app.local", IdleTimeout: time.Minute, DependsOn: []string{"db"},
+				Hooks: HooksConfig{PreStop: []HookConfig{{URL: hook.URL, Method: "POST", Veto: true}}}},
+			{Name: "db"},
+		}
+		m.mu.Lock()
+		m.lastSeen["app"] = time.Now().Add(-2 * time.Minute)
+		m.mu.Unlock()
+
+		m.checkIdle(context.Background(), &GatewayConfig{Containers: cfgs})
+		if stopped.Load() {
+			t.Error("a container was stopped although the pre_stop hook vetoed the stop")
+		}
+	})
+
+	t.Run("woken within min_uptime: not stopped", func(t *testing.T) {
+		m := NewContainerManager(NewFakeRuntime())
+		cfgs := []ContainerConfig{
+			{Name: "app", Host: "app.local", IdleTimeout: time.Minute, MinUptime: 10 * time.Minute},
+		}
+		m.setStartState("app", statusRunning, "")
+		m.mu.Lock()
+		m.lastSeen["app"] = time.Now().Add(-5 * time.Minute)
+		m.mu.Unlock()
+
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("checkIdle panicked (stopped within min_uptime): %v", r)
+			}
+		}()
+		m.checkIdle(context.Background(), &GatewayConfig{Containers: cfgs})
+	})
+
+	t.Run("active connections: stop postponed until idle_drain_timeout", func(t *testing.T) {
+		var stopped atomic.Bool
+		daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/json"):
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"State":{"Status":"running","Running":true}}`))
+			case strings.HasSuffix(r.URL.Path, "/stop"):
+				stopped.Store(true)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer daemon.Close()
+
+		m := NewContainerManager(newTestDockerClient(t, daemon.URL))
+		cfgs := []ContainerConfig{
+			{Name: "app", Host: "app.local", IdleTimeout: time.Minute, IdleDrainTimeout: 10 * time.Minute},
+		}
+		m.mu.Lock()
+		m.lastSeen["app"] = time.Now().Add(-2 * time.Minute)
+		m.mu.Unlock()
+		m.drain.OpenTunnel("app", 0)
+
+		m.checkIdle(context.Background(), &GatewayConfig{Containers: cfgs})
+		if stopped.Load() {
+			t.Fatal("container stopped with a WebSocket tunnel open")
+		}
+
+		// Held for longer than idle_drain_timeout: stopped anyway.
+		m.mu.Lock()
+		m.idleHeld["app"] = time.Now().Add(-11 * time.Minute)
+		m.mu.Unlock()
+		m.checkIdle(context.Background(), &GatewayConfig{Containers: cfgs})
+		if !stopped.Load() {
+			t.Error("container not stopped after idle_drain_timeout")
+		}
+		if m.drain.Draining("app") {
+			t.Error("container still draining after the stop")
+		}
 	})
 
 	t.Run("zero idle_timeout: never triggers", func(t *testing.T) {
-		m := NewContainerManager(nil)
+		m := NewContainerManager(NewFakeRuntime())
 		cfgs := []ContainerConfig{
 			{Name: "app", Host: "app.local", IdleTimeout: 0},
 		}
@@ -659,6 +817,120 @@ func TestCheckIdle_Cascade(t *testing.T) {
 				t.Errorf("checkIdle panicked (zero timeout triggered stop): %v", r)
 			}
 		}()
-		m.checkIdle(context.Background(), cfgs)
+		m.checkIdle(context.Background(), &GatewayConfig{Containers: cfgs})
 	})
 }
+
+// ─── Wake ────────────────────────────────────────────────────────────────────
+
+func TestWake_StartsDependenciesFirst(t *testing.T) {
+	var mu sync.Mutex
+	var started []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/start") {
+			name := path.Base(path.Dir(r.URL.Path)) // /v1.43/containers/<name>/start
+			mu.Lock()
+			started = append(started, name)
+			mu.Unlock()
+			// The dependency fails to start, which must stop the wake.
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"boom"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"State":{"Status":"exited"},"Config":{"Image":"x"}}`))
+	}))
+	defer daemon.Close()
+
+	m := NewContainerManager(newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String()))
+	all := []ContainerConfig{
+		{Name: "app", DependsOn: []string{"db"}},
+		{Name: "db"},
+	}
+	if err := m.Wake(context.Background(), &all[0], all); err == nil {
+		t.Fatal("Wake should fail when a dependency fails to start")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(started) != 1 || started[0] != "db" {
+		t.Errorf("started = %v, want only [db]", started)
+	}
+	if status, _ := m.GetStartState("app"); status != string(statusFailed) {
+		t.Errorf("app start state = %q, want failed", status)
+	}
+}
+
+// ─── Kill / ResetStartState ──────────────────────────────────────────────────
+
+func TestKill(t *testing.T) {
+	var signal atomic.Value
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/app/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"State":{"Status":"running","Running":true}}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/containers/app/kill"):
+			signal.Store(r.URL.Query().Get("signal"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer daemon.Close()
+
+	m := NewContainerManager(newTestDockerClient(t, daemon.URL))
+	m.setStartState("app", statusStarting, "")
+	if err := m.Kill(context.Background(), "app"); err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	if got, _ := signal.Load().(string); got != "KILL" {
+		t.Errorf("kill signal = %q, want KILL", got)
+	}
+	if status, _ := m.GetStartState("app"); status != "unknown" {
+		t.Errorf("start state after Kill = %q, want unknown", status)
+	}
+}
+
+func TestResetStartState(t *testing.T) {
+	m := NewContainerManager(NewFakeRuntime())
+	now := time.Now()
+	for i := range crashLoopThreshold {
+		m.crashes.RecordCrash("app", now.Add(time.Duration(i)*time.Second))
+	}
+	m.setStartState("app", statusFailed, "crash loop")
+
+	if prev := m.ResetStartState("app"); prev != string(statusFailed) {
+		t.Errorf("ResetStartState = %q, want failed", prev)
+	}
+	if status, errMsg := m.GetStartState("app"); status != "unknown" || errMsg != "" {
+		t.Errorf("start state = (%q, %q), want unknown", status, errMsg)
+	}
+	if looping, _, _ := m.crashes.Backoff("app"); looping {
+		t.Error("crash-loop backoff survived the reset")
+	}
+}
+
+func TestEnsureRunning_CrashLoopBackoffCountsFailedStart(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("loop-app", FakeContainer{Status: "exited"})
+	m := NewContainerManager(rt)
+	now := time.Now()
+	for i := range crashLoopThreshold {
+		m.crashes.RecordCrash("loop-app", now.Add(time.Duration(i)*time.Second))
+	}
+
+	before, _ := gatheredValue(t, "gateway_starts_total", map[string]string{"container": "loop-app", "result": "error"})
+	if err := m.EnsureRunning(context.Background(), &ContainerConfig{Name: "loop-app", StartTimeout: time.Second}); err == nil {
+		t.Fatal("EnsureRunning succeeded during the crash-loop backoff")
+	}
+	if after, _ := gatheredValue(t, "gateway_starts_total", map[string]string{"container": "loop-app", "result": "error"}); after != before+1 {
+		t.Errorf("gateway_starts_total{result=error} = %v, want %v: the suppressed start was not counted", after, before+1)
+	}
+	for _, call := range rt.Calls() {
+		if call == "start loop-app" {
+			t.Error("container started during the crash-loop backoff")
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mdnsGroup is the IPv4 mDNS multicast group (RFC 6762).
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsAnnounceBatch caps the records of one announcement packet, keeping it
+// well below the 1500-byte Ethernet MTU.
+const mdnsAnnounceBatch = 16
+
+// configuredHosts returns the sorted, lower-case host names of all
+// containers and groups, without ports. Containers without a host of their
+// own contribute their host_pattern host.
+func configuredHosts(cfg *GatewayConfig) []string {
+	seen := make(map[string]bool)
+	add := func(host string) {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host = strings.ToLower(strings.TrimSuffix(host, ".")); host != "" {
+			seen[host] = true
+		}
+	}
+	for i := range cfg.Containers {
+		add(cfg.ContainerHost(&cfg.Containers[i]))
+	}
+	for _, g := range cfg.Groups {
+		add(g.Host)
+	}
+	hosts := make([]string, 0, len(seen))
+	for h := range seen {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// mdnsHosts returns the configured hosts under the .local mDNS domain.
+func mdnsHosts(cfg *GatewayConfig) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, h := range configuredHosts(cfg) {
+		if strings.HasSuffix(h, ".local") {
+			hosts[h] = true
+		}
+	}
+	return hosts
+}
+
+// MDNSResponder answers mDNS queries for the configured *.local hosts with
+// the gateway's address, so LAN clients resolve them without any DNS or
+// hosts-file change. It needs host networking to see multicast traffic.
+type MDNSResponder struct {
+	configProvider func() *GatewayConfig
+
+	mu     sync.Mutex
+	cfg    MDNSConfig
+	reload chan struct{}
+}
+
+// NewMDNSResponder creates a disabled responder; it starts answering once
+// Sync receives a configuration with enabled set.
+func NewMDNSResponder(configProvider func() *GatewayConfig) *MDNSResponder {
+	return &MDNSResponder{
+		configProvider: configProvider,
+		reload:         make(chan struct{}, 1),
+	}
+}
+
+// Sync applies a new mDNS configuration, rebinding when it changed. Host
+// changes need no Sync: hosts are read from the config on every query.
+func (m *MDNSResponder) Sync(cfg MDNSConfig) {
+	m.mu.Lock()
+	changed := !reflect.DeepEqual(m.cfg, cfg)
+	m.cfg = cfg
+	m.mu.Unlock()
+	if changed {
+		trySignal(m.reload)
+	}
+}
+
+func (m *MDNSResponder) config() MDNSConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// Start runs the responder in the background until ctx is cancelled.
+func (m *MDNSResponder) Start(ctx context.Context) {
+	go func() {
+		for {
+			cfg := m.config()
+			if !cfg.Enabled {
+				select {
+				case <-ctx.Done():
+					return
+				case <-m.reload:
+					continue
+				}
+			}
+
+			select {
+			case <-m.reload:
+			default:
+			}
+			err := m.session(ctx, cfg)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				continue // configuration changed
+			}
+			slog.Warn("mdns: responder stopped", "retry_in", time.Minute, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.reload:
+			case <-time.After(time.Minute):
+			}
+		}
+	}()
+}
+
+// session joins the multicast group and answers queries until ctx is
+// cancelled or the configuration changes (nil) or the socket fails.
+func (m *MDNSResponder) session(ctx context.Context, cfg MDNSConfig) error {
+	iface, addr, err := mdnsInterface(cfg)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", iface, mdnsGroup)
+	if err != nil {
+		return fmt.Errorf("join %s: %w", mdnsGroup, err)
+	}
+	defer conn.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-m.reload:
+		case <-stop:
+			return
+		}
+		conn.Close()
+	}()
+
+	ttl := uint32(cfg.TTL.Seconds())
+	m.announce(conn, addr, ttl)
+	slog.Info("mdns: responder started", "address", addr, "hosts", len(mdnsHosts(m.configProvider())))
+
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-stop:
+			default:
+				if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+					return err
+				}
+			}
+			return nil
+		}
+		resp, unicast := mdnsResponse(buf[:n], src, mdnsHosts(m.configProvider()), addr, ttl)
+		if resp == nil {
+			continue
+		}
+		dst := mdnsGroup
+		if unicast {
+			dst = src
+		}
+		if _, err := conn.WriteToUDP(resp, dst); err != nil {
+			slog.Debug("mdns: send failed", "to", dst, "error", err)
+		}
+	}
+}
+
+// announce multicasts unsolicited answers for every host, so caches on the
+// LAN learn the records without querying.
+func (m *MDNSResponder) announce(conn *net.UDPConn, addr net.IP, ttl uint32) {
+	hosts := mdnsHosts(m.configProvider())
+	names := make([]string, 0, len(hosts))
+	for h := range hosts {
+		names = append(names, h)
+	}
+	sort.Strings(names)
+	for len(names) > 0 {
+		batch := names[:min(len(names), mdnsAnnounceBatch)]
+		names = names[len(batch):]
+		answers := make([]dnsRecord, len(batch))
+		for i, h := range batch {
+			answers[i] = dnsRecord{Name: h, Class: dnsClassIN | dnsClassUnicast, TTL: ttl, IP: addr}
+		}
+		resp := buildDNSResponse(0, dnsFlagResponse|dnsFlagAuthoritative, nil, answers)
+		if _, err := conn.WriteToUDP(resp, mdnsGroup); err != nil {
+			slog.Debug("mdns: announcement failed", "error", err)
+			return
+		}
+	}
+}
+
+// mdnsResponse builds the answer to an mDNS query for hosts, or nil when
+// none of its questions is about them. unicast is true when the answer must
+// go back to the sender: legacy resolvers querying from a port other than
+// 5353 (RFC 6762 §6.7) and questions with the QU bit set.
+func mdnsResponse(query []byte, src *net.UDPAddr, hosts map[string]bool, addr net.IP, ttl uint32) (resp []byte, unicast bool) {
+	q, err := parseDNSQuery(query)
+	if err != nil || q.Flags&dnsFlagResponse != 0 {
+		return nil, false
+	}
+	legacy := src.Port != mdnsGroup.Port
+
+	var answers []dnsRecord
+	var asked []dnsQuestion
+	for _, question := range q.Questions {
+		if !hosts[question.Name] {
+			continue
+		}
+		if question.Class&dnsClassUnicast != 0 {
+			unicast = true
+		}
+		class := dnsClassIN | dnsClassUnicast // cache flush: we own these names
+		if legacy {
+			class = dnsClassIN
+		}
+		answers = append(answers, dnsAnswers(question, []net.IP{addr}, class, ttl)...)
+		asked = append(asked, dnsQuestion{Name: question.Name, Type: question.Type, Class: dnsClassIN})
+	}
+	if len(answers) == 0 {
+		return nil, false
+	}
+	if legacy {
+		// Legacy unicast responses echo the ID and the questions.
+		return buildDNSResponse(q.ID, dnsFlagResponse|dnsFlagAuthoritative, asked, answers), true
+	}
+	return buildDNSResponse(0, dnsFlagResponse|dnsFlagAuthoritative, nil, answers), unicast
+}
+
+// mdnsInterface returns the interface to join the group on (nil for the
+// system default) and the IPv4 address to advertise.
+func mdnsInterface(cfg MDNSConfig) (*net.Interface, net.IP, error) {
+	var iface *net.Interface
+	if cfg.Interface != "" {
+		i, err := net.InterfaceByName(cfg.Interface)
+		if err != nil {
+			return nil, nil, err
+		}
+		iface = i
+	}
+	if cfg.Address != "" {
+		return iface, net.ParseIP(cfg.Address).To4(), nil
+	}
+	if addr := interfaceIPv4(iface); addr != nil {
+		return iface, addr, nil
+	}
+	return nil, nil, fmt.Errorf("no IPv4 address to advertise; set mdns.address")
+}
+
+// interfaceIPv4 returns the first IPv4 address of iface or, when iface is
+// nil, of the first non-loopback interface that is up.
+func interfaceIPv4(iface *net.Interface) net.IP {
+	candidates := []net.Interface{}
+	if iface != nil {
+		candidates = append(candidates, *iface)
+	} else if all, err := net.Interfaces(); err == nil {
+		candidates = all
+	}
+	for _, i := range candidates {
+		if i.Flags&net.FlagUp == 0 || i.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, _ := i.Addrs()
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				return ipnet.IP.To4()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestConfiguredHosts(t *testing.T) {
+	cfg := &GatewayConfig{
+		Containers: []ContainerConfig{{Host: "App.local"}, {Host: "api.example.com:8080"}, {Host: "app.local."}},
+		Groups:     []GroupConfig{{Host: "web.local"}},
+	}
+	got := configuredHosts(cfg)
+	want := []string{"api.example.com", "app.local", "web.local"}
+	if len(got) != len(want) {
+		t.Fatalf("configuredHosts = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("configuredHosts = %v, want %v", got, want)
+		}
+	}
+	if hosts := mdnsHosts(cfg); len(hosts) != 2 || hosts["api.example.com"] {
+		t.Errorf("mdnsHosts = %v, want only .local hosts", hosts)
+	}
+}
+
+func TestMDNSResponse(t *testing.T) {
+	hosts := map[string]bool{"app.local": true}
+	addr := net.ParseIP("192.168.1.10")
+	peer := &net.UDPAddr{IP: net.ParseIP("192.168.1.20"), Port: 5353}
+	legacy := &net.UDPAddr{IP: net.ParseIP("192.168.1.20"), Port: 53124}
+
+	tests := []struct {
+		name        string
+		src         *net.UDPAddr
+		q           dnsQuestion
+		wantAnswer  bool
+		wantUnicast bool
+	}{
+		{"multicast query", peer, dnsQuestion{Name: "app.local", Type: dnsTypeA, Class: dnsClassIN}, true, false},
+		{"QU bit", peer, dnsQuestion{Name: "app.local", Type: dnsTypeA, Class: dnsClassIN | dnsClassUnicast}, true, true},
+		{"legacy unicast", legacy, dnsQuestion{Name: "app.local", Type: dnsTypeANY, Class: dnsClassIN}, true, true},
+		{"unknown host", peer, dnsQuestion{Name: "nas.local", Type: dnsTypeA, Class: dnsClassIN}, false, false},
+		{"AAAA only", peer, dnsQuestion{Name: "app.local", Type: dnsTypeAAAA, Class: dnsClassIN}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, unicast := mdnsResponse(dnsQueryBytes(42, tt.q), tt.src, hosts, addr, 120)
+			if (resp != nil) != tt.wantAnswer || unicast != tt.wantUnicast {
+				t.Fatalf("answer = %v, unicast = %v; want %v, %v", resp != nil, unicast, tt.wantAnswer, tt.wantUnicast)
+			}
+			if resp == nil {
+				return
+			}
+			q, err := parseDNSQuery(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Flags&dnsFlagResponse == 0 || binary.BigEndian.Uint16(resp[6:]) != 1 {
+				t.Errorf("flags = %#x, ancount = %d", q.Flags, binary.BigEndian.Uint16(resp[6:]))
+			}
+			// Legacy resolvers need their ID and question echoed.
+			if tt.src == legacy && (q.ID != 42 || len(q.Questions) != 1) {
+				t.Errorf("legacy response: id = %d, questions = %v", q.ID, q.Questions)
+			}
+			if tt.src != legacy && (q.ID != 0 || len(q.Questions) != 0) {
+				t.Errorf("mDNS response: id = %d, questions = %v", q.ID, q.Questions)
+			}
+		})
+	}
+}
+
+func TestMDNSResponse_IgnoresResponses(t *testing.T) {
+	b := dnsQueryBytes(0, dnsQuestion{Name: "app.local", Type: dnsTypeA, Class: dnsClassIN})
+	binary.BigEndian.PutUint16(b[2:], dnsFlagResponse)
+	peer := &net.UDPAddr{IP: net.ParseIP("192.168.1.20"), Port: 5353}
+	if resp, _ := mdnsResponse(b, peer, map[string]bool{"app.local": true}, net.ParseIP("192.168.1.10"), 120); resp != nil {
+		t.Error("answered another responder's packet")
+	}
+}
@@ -44,6 +44,26 @@ var (
 		[]string{"container"},
 	)
 
+	// RequestOutcomesTotal counts requests to a container by how they were
+	// answered: proxied to the running container or with the loading page.
+	RequestOutcomesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_request_outcomes_total",
+			Help: "Total requests to a container, by outcome: proxied or loading page.",
+		},
+		[]string{"container", "outcome"}, // outcome: "proxied" or "loading_page"
+	)
+
+	// WakeWaitSeconds tracks the cold-start wait users actually see.
+	WakeWaitSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gateway_wake_wait_seconds",
+			Help:    "Time from the first loading page shown during a start until the container reported running.",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 15, 30, 60, 120},
+		},
+		[]string{"container"},
+	)
+
 	// IdleStopsTotal tracks the idle shutdown watcher.
 	IdleStopsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -52,8 +72,445 @@ var (
 		},
 		[]string{"container"},
 	)
+
+	// DryRunDecisionsTotal counts the lifecycle actions dry-run mode logged
+	// instead of executing.
+	DryRunDecisionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_dry_run_decisions_total",
+			Help: "Lifecycle actions logged but not executed because of gateway.dry_run.",
+		},
+		[]string{"container", "action"}, // action: "start", "stop" or "restart"
+	)
+
+	// CircuitState exposes the per-container circuit breaker state.
+	CircuitState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_circuit_state",
+			Help: "Circuit breaker state per container (0 = closed, 1 = open, 2 = half-open).",
+		},
+		[]string{"container"},
+	)
+
+	// CircuitTripsTotal counts how often a container's circuit breaker opened.
+	CircuitTripsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_circuit_trips_total",
+			Help: "Total times a container's circuit breaker tripped open.",
+		},
+		[]string{"container"},
+	)
+
+	// HealthCheckFailuresTotal counts failed background health checks of running containers.
+	HealthCheckFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_health_check_failures_total",
+			Help: "Total failed self-healing and group member health checks while Docker reported the container as running.",
+		},
+		[]string{"container"},
+	)
+
+	// SelfHealRestartsTotal counts automatic restarts by the self-healing loop.
+	SelfHealRestartsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_self_heal_restarts_total",
+			Help: "Total automatic restarts of unresponsive running containers.",
+		},
+		[]string{"container", "result"}, // result: "success" or "error"
+	)
+
+	// RateLimitedTotal counts requests rejected by the per-IP rate limiter.
+	RateLimitedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_rate_limited_total",
+			Help: "Total requests rejected with 429 by the rate limiter, per internal endpoint.",
+		},
+		[]string{"endpoint"},
+	)
+
+	// AdminAuthFailuresTotal counts rejected requests to admin endpoints.
+	AdminAuthFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_admin_auth_failures_total",
+			Help: "Total requests to admin endpoints rejected for missing or invalid credentials.",
+		},
+		[]string{"method"}, // method: "basic" or "bearer"
+	)
+
+	// MiddlewareRejectionsTotal counts requests answered by a built-in
+	// middleware (gateway.middlewares) instead of the container.
+	MiddlewareRejectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_middleware_rejections_total",
+			Help: "Total requests rejected by a middleware before reaching the container.",
+		},
+		[]string{"middleware", "reason"}, // reason: "unauthorized", "rate_limited", "script", "wake_vetoed" or "script_error"
+	)
+
+	// ConfigReloadsTotal counts configuration reloads by outcome.
+	ConfigReloadsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_config_reloads_total",
+			Help: "Total configuration reloads, by trigger and result.",
+		},
+		[]string{"trigger", "result"}, // trigger: "static" or "discovery"; result: "success" or "error"
+	)
+
+	// WebSocketUpgradesTotal counts proxied WebSocket upgrade attempts.
+	WebSocketUpgradesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_websocket_upgrades_total",
+			Help: "Total WebSocket upgrade attempts proxied to containers.",
+		},
+		[]string{"container", "result"}, // result: "success" or "error"
+	)
+
+	// WakeOnLANTotal counts Docker host power-ons by wake_on_lan.
+	// Labels: result (success|error).
+	WakeOnLANTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_wake_on_lan_total",
+			Help: "Wake-on-LAN power-ons of the Docker host, by whether the daemon answered within boot_timeout.",
+		},
+		[]string{"result"},
+	)
+
+	// WebSocketRejectedTotal counts upgrades refused by websocket.max_connections.
+	WebSocketRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_websocket_rejected_total",
+			Help: "WebSocket upgrades refused because the container had websocket.max_connections tunnels open.",
+		},
+		[]string{"container"},
+	)
+
+	// WebSocketIdleClosedTotal counts tunnels closed by websocket.idle_timeout.
+	WebSocketIdleClosedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_websocket_idle_closed_total",
+			Help: "WebSocket tunnels closed because no bytes moved for websocket.idle_timeout.",
+		},
+		[]string{"container"},
+	)
+
+	// ProxyErrorsTotal counts proxy transport errors by category.
+	ProxyErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_proxy_errors_total",
+			Help: "Total transport errors while proxying to containers, by category.",
+		},
+		// category: "dial_timeout", "refused", "reset", "timeout", "canceled" or "other"
+		[]string{"container", "category"},
+	)
+
+	// WakeRetriesTotal counts requests retried right after a wake.
+	WakeRetriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_wake_retries_total",
+			Help: "Proxied requests retried because the just-woken container refused or reset the connection.",
+		},
+		[]string{"container"},
+	)
+
+	// GroupRequestsTotal counts requests routed through a group, per member.
+	GroupRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_group_requests_total",
+			Help: "Total HTTP requests routed through a group, per member.",
+		},
+		[]string{"group", "member", "status_code"},
+	)
+
+	// GroupPicksTotal counts how often the load balancer picked each member.
+	GroupPicksTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_group_picks_total",
+			Help: "Total times the group load balancer picked a member.",
+		},
+		[]string{"group", "member"},
+	)
+
+	// GroupHedgedRequestsTotal counts hedged group requests by the attempt
+	// that answered first.
+	GroupHedgedRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_group_hedged_requests_total",
+			Help: "Total group requests sent to a second member, by the attempt that answered first.",
+		},
+		// winner: "primary" or "hedge"
+		[]string{"group", "winner"},
+	)
+
+	// GroupMembersRunning exposes how many members of a group are running.
+	GroupMembersRunning = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_group_members_running",
+			Help: "Number of group members Docker reports as running.",
+		},
+		[]string{"group"},
+	)
+
+	// GroupStartDuration tracks how long it takes to bring a whole group up.
+	GroupStartDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gateway_group_start_duration_seconds",
+			Help:    "Time taken to start all members of a group (including dependencies).",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 15, 30, 60, 120, 300},
+		},
+		[]string{"group"},
+	)
+
+	// ActiveRequests tracks HTTP requests currently being proxied.
+	ActiveRequests = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_active_requests",
+			Help: "HTTP requests currently being proxied to a container.",
+		},
+		[]string{"container"},
+	)
+
+	// WebSocketConnections tracks open WebSocket tunnels.
+	WebSocketConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_websocket_connections",
+			Help: "WebSocket connections currently tunnelled to a container.",
+		},
+		[]string{"container"},
+	)
+
+	// TCPConnections tracks open connections of tcp_routes.
+	TCPConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_tcp_connections",
+			Help: "TCP connections currently proxied to a container by tcp_routes.",
+		},
+		[]string{"container"},
+	)
+
+	// TCPConnectionsTotal counts the connections accepted by tcp_routes.
+	TCPConnectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_tcp_connections_total",
+			Help: "Total TCP connections accepted by tcp_routes, by outcome.",
+		},
+		[]string{"container", "outcome"}, // outcome: proxied, wake, refused or error
+	)
+
+	// ContainerState exposes the lifecycle state of each container as a
+	// one-hot gauge: 1 for the current state, 0 for the others.
+	ContainerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_container_state",
+			Help: "Container lifecycle state (1 for the current state: running, starting, stopped or failed).",
+		},
+		[]string{"container", "state"},
+	)
+
+	// ContainerRunningSeconds accumulates the time a container spent running.
+	ContainerRunningSeconds = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_container_running_seconds_total",
+			Help: "Cumulative seconds the container spent running.",
+		},
+		[]string{"container"},
+	)
+
+	// BytesReceivedTotal counts request body bytes proxied to a container.
+	BytesReceivedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_container_bytes_received_total",
+			Help: "Request body bytes received from clients and proxied to the container.",
+		},
+		[]string{"container"},
+	)
+
+	// BytesSentTotal counts response body bytes sent back from a container.
+	BytesSentTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_container_bytes_sent_total",
+			Help: "Response body bytes (and WebSocket bytes) sent from the container to clients.",
+		},
+		[]string{"container"},
+	)
+
+	// ContainerAsleepSeconds accumulates the time a container spent stopped.
+	ContainerAsleepSeconds = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_container_asleep_seconds_total",
+			Help: "Cumulative seconds the container spent stopped (asleep).",
+		},
+		[]string{"container"},
+	)
+
+	// QueuedRequests tracks requests waiting for a max_concurrent_requests slot.
+	QueuedRequests = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_queued_requests",
+			Help: "Requests waiting in the per-container queue for a free concurrency slot.",
+		},
+		[]string{"container"},
+	)
+
+	// QueueRejectedTotal counts queued requests answered with 503.
+	QueueRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_queue_rejected_total",
+			Help: "Requests rejected because the container queue was full or the wait timed out.",
+		},
+		[]string{"container", "reason"}, // reason: "full" or "timeout"
+	)
+
+	// QueueWaitDuration tracks how long queued requests waited for a slot.
+	QueueWaitDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gateway_queue_wait_seconds",
+			Help:    "Time queued requests waited for a concurrency slot.",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"container"},
+	)
+
+	// BansTotal counts client IPs banned by auto_ban.
+	BansTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_bans_total",
+			Help: "Client IPs banned by auto_ban, by the reason of the last strike.",
+		},
+		[]string{"reason"}, // reason: "auth_failure", "rate_limited" or "unknown_host"
+	)
+
+	// BannedClients tracks the currently banned client IPs.
+	BannedClients = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gateway_banned_clients",
+			Help: "Client IPs currently banned by auto_ban.",
+		},
+	)
+
+	// BannedRequestsTotal counts requests rejected because the client is banned.
+	BannedRequestsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gateway_banned_requests_total",
+			Help: "Requests rejected with 403 because the client IP is banned.",
+		},
+	)
+
+	// ClientConcurrencyRejectedTotal counts requests rejected by
+	// max_concurrent_per_ip.
+	ClientConcurrencyRejectedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gateway_client_concurrency_rejected_total",
+			Help: "Requests rejected with 429 because the client IP had max_concurrent_per_ip requests in flight.",
+		},
+	)
+
+	// OpenConnections tracks client connections held by the HTTP server.
+	OpenConnections = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gateway_open_connections",
+			Help: "Client connections currently open on the HTTP server (WebSocket tunnels excluded).",
+		},
+	)
+
+	// ImageUpdateAvailable flags containers whose image tag points to a newer
+	// digest in the registry than the one they run.
+	ImageUpdateAvailable = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_image_update_available",
+			Help: "1 when the registry serves a newer image digest for the container's tag (update_check_interval).",
+		},
+		[]string{"container"},
+	)
+
+	// DockerCapability reports the Docker API operations found allowed by
+	// detect_capabilities.
+	DockerCapability = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_docker_capability",
+			Help: "1 when the Docker API allows the operation, 0 when a socket proxy forbids it (detect_capabilities).",
+		},
+		[]string{"operation"},
+	)
+
+	// BuildInfoGauge is always 1; its labels identify the running build so
+	// outdated gateways can be found with a single query.
+	BuildInfoGauge = promauto.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name:        "gateway_build_info",
+			Help:        "Build information of the running gateway (always 1).",
+			ConstLabels: GetBuildInfo().labels(),
+		},
+		func() float64 { return 1 },
+	)
 )
 
+// containerVecs lists every metric vector labelled by container. Keep it in
+// sync with the vectors above so removed containers are fully cleaned up.
+var containerVecs = []*prometheus.MetricVec{
+	RequestsTotal.MetricVec,
+	RequestDuration.MetricVec,
+	StartsTotal.MetricVec,
+	StartDuration.MetricVec,
+	RequestOutcomesTotal.MetricVec,
+	WakeWaitSeconds.MetricVec,
+	IdleStopsTotal.MetricVec,
+	DryRunDecisionsTotal.MetricVec,
+	CircuitState.MetricVec,
+	CircuitTripsTotal.MetricVec,
+	HealthCheckFailuresTotal.MetricVec,
+	SelfHealRestartsTotal.MetricVec,
+	WebSocketUpgradesTotal.MetricVec,
+	WebSocketRejectedTotal.MetricVec,
+	WebSocketIdleClosedTotal.MetricVec,
+	ProxyErrorsTotal.MetricVec,
+	WakeRetriesTotal.MetricVec,
+	ActiveRequests.MetricVec,
+	WebSocketConnections.MetricVec,
+	TCPConnections.MetricVec,
+	TCPConnectionsTotal.MetricVec,
+	ContainerState.MetricVec,
+	ContainerRunningSeconds.MetricVec,
+	ContainerAsleepSeconds.MetricVec,
+	BytesReceivedTotal.MetricVec,
+	BytesSentTotal.MetricVec,
+	QueuedRequests.MetricVec,
+	QueueRejectedTotal.MetricVec,
+	QueueWaitDuration.MetricVec,
+	ImageUpdateAvailable.MetricVec,
+}
+
+// groupVecs lists every metric vector labelled by group.
+var groupVecs = []*prometheus.MetricVec{
+	GroupRequestsTotal.MetricVec,
+	GroupPicksTotal.MetricVec,
+	GroupHedgedRequestsTotal.MetricVec,
+	GroupMembersRunning.MetricVec,
+	GroupStartDuration.MetricVec,
+}
+
+// ForgetContainerMetrics deletes every series of a container that is no longer
+// managed, including its group member series. It returns the number of
+// deleted series.
+func ForgetContainerMetrics(containerName string) int {
+	n := 0
+	for _, v := range containerVecs {
+		n += v.DeletePartialMatch(prometheus.Labels{"container": containerName})
+	}
+	n += GroupRequestsTotal.DeletePartialMatch(prometheus.Labels{"member": containerName})
+	n += GroupPicksTotal.DeletePartialMatch(prometheus.Labels{"member": containerName})
+	return n
+}
+
+// ForgetGroupMetrics deletes every series of a group that no longer exists.
+// It returns the number of deleted series.
+func ForgetGroupMetrics(groupName string) int {
+	n := 0
+	for _, v := range groupVecs {
+		n += v.DeletePartialMatch(prometheus.Labels{"group": groupName})
+	}
+	return n
+}
+
 // RecordRequest is a thread-safe helper to bump request metrics.
 func RecordRequest(containerName string, statusCode string, durationSec float64) {
 	RequestsTotal.WithLabelValues(containerName, statusCode).Inc()
@@ -74,3 +531,185 @@ func RecordStart(containerName string, success bool, durationSec float64) {
 func RecordIdleStop(containerName string) {
 	IdleStopsTotal.WithLabelValues(containerName).Inc()
 }
+
+// RecordDryRunDecision bumps the dry-run decision counter.
+func RecordDryRunDecision(containerName, action string) {
+	DryRunDecisionsTotal.WithLabelValues(containerName, action).Inc()
+}
+
+// RecordHealthCheckFailure bumps the background (self-healing or group
+// member) health check failure counter.
+func RecordHealthCheckFailure(containerName string) {
+	HealthCheckFailuresTotal.WithLabelValues(containerName).Inc()
+}
+
+// RecordSelfHealRestart bumps the self-healing restart counter.
+func RecordSelfHealRestart(containerName string, success bool) {
+	result := "error"
+	if success {
+		result = "success"
+	}
+	SelfHealRestartsTotal.WithLabelValues(containerName, result).Inc()
+}
+
+// SetCircuitState updates the circuit breaker state gauge.
+func SetCircuitState(containerName string, state circuitState) {
+	CircuitState.WithLabelValues(containerName).Set(float64(state))
+}
+
+// Outcomes of RecordRequestOutcome.
+const (
+	outcomeProxied     = "proxied"
+	outcomeLoadingPage = "loading_page"
+)
+
+// RecordRequestOutcome bumps the request outcome counter of a container.
+func RecordRequestOutcome(containerName, outcome string) {
+	RequestOutcomesTotal.WithLabelValues(containerName, outcome).Inc()
+}
+
+// RecordWakeWait observes the wait of users for a container to come up.
+func RecordWakeWait(containerName string, waitSec float64) {
+	WakeWaitSeconds.WithLabelValues(containerName).Observe(waitSec)
+}
+
+// RecordRateLimited bumps the rate-limited counter for an internal endpoint.
+func RecordRateLimited(endpoint string) {
+	RateLimitedTotal.WithLabelValues(endpoint).Inc()
+}
+
+// RecordQueueRejected bumps the queue rejection counter ("full" or "timeout").
+func RecordQueueRejected(containerName, reason string) {
+	QueueRejectedTotal.WithLabelValues(containerName, reason).Inc()
+}
+
+// RecordAdminAuthFailure bumps the admin authentication failure counter.
+func RecordAdminAuthFailure(method string) {
+	AdminAuthFailuresTotal.WithLabelValues(method).Inc()
+}
+
+// RecordMiddlewareRejection bumps the middleware rejection counter.
+func RecordMiddlewareRejection(middleware, reason string) {
+	MiddlewareRejectionsTotal.WithLabelValues(middleware, reason).Inc()
+}
+
+// RecordConfigReload bumps the configuration reload counter.
+func RecordConfigReload(trigger string, success bool) {
+	result := "error"
+	if success {
+		result = "success"
+	}
+	ConfigReloadsTotal.WithLabelValues(trigger, result).Inc()
+}
+
+// RecordWebSocketUpgrade bumps the WebSocket upgrade counter.
+func RecordWebSocketUpgrade(containerName string, success bool) {
+	result := "error"
+	if success {
+		result = "success"
+	}
+	WebSocketUpgradesTotal.WithLabelValues(containerName, result).Inc()
+}
+
+// Outcomes of a connection accepted by tcp_routes.
+const (
+	tcpOutcomeProxied = "proxied" // forwarded to the running container
+	tcpOutcomeWake    = "wake"    // started the container; then held or closed
+	tcpOutcomeRefused = "refused" // closed: draining, read-only, dry-run or crash loop
+	tcpOutcomeError   = "error"   // closed: the container failed to start or could not be reached
+)
+
+// RecordTCPConnection counts a connection of tcp_routes by outcome.
+func RecordTCPConnection(containerName, outcome string) {
+	TCPConnectionsTotal.WithLabelValues(containerName, outcome).Inc()
+}
+
+// RecordWakeOnLAN counts the Wake-on-LAN power-ons of the Docker host by
+// outcome: the daemon answered in time, or not.
+func RecordWakeOnLAN(success bool) {
+	result := "success"
+	if !success {
+		result = "error"
+	}
+	WakeOnLANTotal.WithLabelValues(result).Inc()
+}
+
+// RecordWebSocketRejected bumps the counter of upgrades refused by the
+// tunnel limit of a container.
+func RecordWebSocketRejected(containerName string) {
+	WebSocketRejectedTotal.WithLabelValues(containerName).Inc()
+}
+
+// RecordWebSocketIdleClosed bumps the counter of tunnels closed for idleness.
+func RecordWebSocketIdleClosed(containerName string) {
+	WebSocketIdleClosedTotal.WithLabelValues(containerName).Inc()
+}
+
+// RecordProxyError bumps the proxy transport error counter.
+func RecordProxyError(containerName, category string) {
+	ProxyErrorsTotal.WithLabelValues(containerName, category).Inc()
+}
+
+// RecordWakeRetry bumps the wake retry counter of a container.
+func RecordWakeRetry(containerName string) {
+	WakeRetriesTotal.WithLabelValues(containerName).Inc()
+}
+
+// RecordGroupPick bumps the member pick counter of a group.
+func RecordGroupPick(groupName, member string) {
+	GroupPicksTotal.WithLabelValues(groupName, member).Inc()
+}
+
+// RecordBandwidth adds proxied bytes to the bandwidth counters of a container.
+func RecordBandwidth(containerName string, received, sent int64) {
+	if received > 0 {
+		BytesReceivedTotal.WithLabelValues(containerName).Add(float64(received))
+	}
+	if sent > 0 {
+		BytesSentTotal.WithLabelValues(containerName).Add(float64(sent))
+	}
+}
+
+// RecordGroupHedge bumps the hedged request counter of a group.
+func RecordGroupHedge(groupName, winner string) {
+	GroupHedgedRequestsTotal.WithLabelValues(groupName, winner).Inc()
+}
+
+// RecordGroupRequest bumps the per-member request counter of a group.
+func RecordGroupRequest(groupName, member, statusCode string) {
+	GroupRequestsTotal.WithLabelValues(groupName, member, statusCode).Inc()
+}
+
+// RecordGroupStart observes the duration of a successful group start.
+func RecordGroupStart(groupName string, durationSec float64) {
+	GroupStartDuration.WithLabelValues(groupName).Observe(durationSec)
+}
+
+// SetContainerState sets the one-hot container state gauge.
+func SetContainerState(containerName, state string) {
+	for _, st := range lifecycleStates {
+		v := 0.0
+		if st == state {
+			v = 1
+		}
+		ContainerState.WithLabelValues(containerName, st).Set(v)
+	}
+}
+
+// SetImageUpdateAvailable records the result of an image update check.
+func SetImageUpdateAvailable(containerName string, available bool) {
+	v := 0.0
+	if available {
+		v = 1
+	}
+	ImageUpdateAvailable.WithLabelValues(containerName).Set(v)
+}
+
+// SetDockerCapability records whether a Docker API operation is allowed.
+func SetDockerCapability(operation string, allowed bool) {
+	v := 0.0
+	if allowed {
+		v = 1
+	}
+	DockerCapability.WithLabelValues(operation).Set(v)
+}
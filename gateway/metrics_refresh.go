@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+// metricsRefreshInterval is how often gauges derived from Docker state are
+// recomputed.
+const metricsRefreshInterval = 15 * time.Second
+
+// StartMetricsRefresher begins a background routine that periodically
+// recomputes gauges which depend on Docker state (container lifecycle state,
+// running group members) and samples container runtime for savings and
+// availability tracking.
+func (m *ContainerManager) StartMetricsRefresher(ctx context.Context, configProvider func() *GatewayConfig) {
+	go func() {
+		ticker := time.NewTicker(metricsRefreshInterval)
+		defer ticker.Stop()
+		for {
+			m.refreshMetrics(ctx, configProvider())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *ContainerManager) refreshMetrics(ctx context.Context, cfg *GatewayConfig) {
+	// Inspect each container at most once per pass.
+	statuses := make(map[string]string)
+	statusOf := func(name string) string {
+		if st, ok := statuses[name]; ok {
+			return st
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		st, _ := m.client.GetContainerStatus(checkCtx, name)
+		cancel()
+		statuses[name] = st
+		return st
+	}
+
+	now := time.Now()
+	for _, c := range cfg.Containers {
+		startStatus, _ := m.GetStartState(c.Name)
+		SetContainerState(c.Name, lifecycleState(startStatus, statusOf(c.Name)))
+		m.runtime.Observe(c.Name, statusOf(c.Name) == "running", now)
+		if st := statusOf(c.Name); st != "" && startStatus != string(statusStarting) {
+			m.lifecycle.observe(c.Name, st == "running", startStatus == string(statusFailed), now)
+		}
+	}
+	for _, g := range cfg.Groups {
+		GroupMembersRunning.WithLabelValues(g.Name).Set(float64(countRunning(g.Containers, statusOf)))
+	}
+}
+
+// countRunning returns how many of the named containers statusOf reports as
+// "running".
+func countRunning(names []string, statusOf func(string) string) int {
+	n := 0
+	for _, name := range names {
+		if statusOf(name) == "running" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,40 @@
+package gateway
+
+import "testing"
+
+// ─── countRunning ─────────────────────────────────────────────────────────────
+
+func TestCountRunning(t *testing.T) {
+	statuses := map[string]string{
+		"api-1": "running",
+		"api-2": "exited",
+		"api-3": "running",
+	}
+	calls := 0
+	statusOf := func(name string) string {
+		calls++
+		return statuses[name]
+	}
+
+	tests := []struct {
+		name  string
+		names []string
+		want  int
+	}{
+		{"all members", []string{"api-1", "api-2", "api-3"}, 2},
+		{"none running", []string{"api-2"}, 0},
+		{"unknown member", []string{"api-1", "missing"}, 1},
+		{"empty group", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			if got := countRunning(tt.names, statusOf); got != tt.want {
+				t.Errorf("countRunning(%v) = %d, want %d", tt.names, got, tt.want)
+			}
+			if calls != len(tt.names) {
+				t.Errorf("statusOf called %d times, want %d", calls, len(tt.names))
+			}
+		})
+	}
+}
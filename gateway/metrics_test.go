@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ─── Series cleanup ───────────────────────────────────────────────────────────
+
+func TestForgetContainerMetrics(t *testing.T) {
+	RecordRequest("forget-me", "200", 0.1)
+	RecordStart("forget-me", true, 1.5)
+	SetContainerState("forget-me", "running")
+	RecordGroupPick("forget-group", "forget-me")
+	RecordDryRunDecision("forget-me", "stop")
+	RecordRequest("keep-me", "200", 0.1)
+
+	if n := ForgetContainerMetrics("forget-me"); n == 0 {
+		t.Fatal("ForgetContainerMetrics() deleted no series")
+	}
+	if n := ForgetContainerMetrics("forget-me"); n != 0 {
+		t.Errorf("second ForgetContainerMetrics() deleted %d series, want 0", n)
+	}
+	if v, _ := gatheredValue(t, "gateway_dry_run_decisions_total", map[string]string{"container": "forget-me"}); v != 0 {
+		t.Errorf("gateway_dry_run_decisions_total of a forgotten container = %v, want no series", v)
+	}
+	if n := ForgetContainerMetrics("keep-me"); n == 0 {
+		t.Error("series of other containers must not be deleted")
+	}
+}
+
+func TestForgetGroupMetrics(t *testing.T) {
+	RecordGroupPick("gone-group", "a")
+	RecordGroupRequest("gone-group", "a", "200")
+	GroupMembersRunning.WithLabelValues("gone-group").Set(1)
+
+	if n := ForgetGroupMetrics("gone-group"); n != 3 {
+		t.Errorf("ForgetGroupMetrics() deleted %d series, want 3", n)
+	}
+}
+
+// ─── Loading page and wake wait ───────────────────────────────────────────────
+
+// gatheredValue returns the value of a counter, or the sample count and sum
+// of a histogram, from the series of name whose labels include labels.
+func gatheredValue(t *testing.T, name string, labels map[string]string) (float64, float64) {
+	t.Helper()
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+	series:
+		for _, m := range mf.GetMetric() {
+			matched := 0
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok {
+					if v != l.GetValue() {
+						continue series
+					}
+					matched++
+				}
+			}
+			if matched != len(labels) {
+				continue
+			}
+			if h := m.GetHistogram(); h != nil {
+				return float64(h.GetSampleCount()), h.GetSampleSum()
+			}
+			return m.GetCounter().GetValue(), 0
+		}
+	}
+	return 0, 0
+}
+
+func TestWakeWaitMetrics(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("wait-app", FakeContainer{Status: "exited", Host: host, Port: port, StartDelay: 50 * time.Millisecond})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "wait-app", Host: "wait.local", TargetPort: port})
+	t.Cleanup(func() { ForgetContainerMetrics("wait-app") })
+
+	g.get("wait.local", "/")
+	g.get("wait.local", "/other") // a second loading page does not restart the wait
+	if status := g.waitStarted(t, "wait-app"); status != string(statusRunning) {
+		t.Fatalf("start state = %q, want running", status)
+	}
+	g.get("wait.local", "/")
+
+	outcomes := func(outcome string) float64 {
+		n, _ := gatheredValue(t, "gateway_request_outcomes_total", map[string]string{"container": "wait-app", "outcome": outcome})
+		return n
+	}
+	if n := outcomes(outcomeLoadingPage); n != 2 {
+		t.Errorf("loading_page outcomes = %g, want 2", n)
+	}
+	if n := outcomes(outcomeProxied); n != 1 {
+		t.Errorf("proxied outcomes = %g, want 1", n)
+	}
+	wakeWait := map[string]string{"container": "wait-app"}
+	count, sum := gatheredValue(t, "gateway_wake_wait_seconds", wakeWait)
+	if count != 1 || sum < 0.04 {
+		t.Errorf("wake wait: %g samples, sum %gs; want one of about the start delay", count, sum)
+	}
+
+	// A start nobody waited for is not observed.
+	g.manager.InitStartState("wait-app")
+	g.manager.setStartState("wait-app", statusRunning, "")
+	if count, _ := gatheredValue(t, "gateway_wake_wait_seconds", wakeWait); count != 1 {
+		t.Errorf("wake wait samples = %g after an unobserved start, want 1", count)
+	}
+}
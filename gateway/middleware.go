@@ -0,0 +1,249 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Middleware wraps the handling of the requests routed to a container or a
+// group. It may answer a request itself or pass it on to next, possibly with
+// a modified request or a wrapped ResponseWriter.
+type Middleware func(next http.Handler) http.Handler
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = make(map[string]Middleware)
+)
+
+// RegisterMiddleware makes m available to middlewares of type "plugin"
+// under name. A program embedding the gateway calls it before loading the
+// configuration, typically from an init function. It panics if name is
+// empty, m is nil or name is already registered.
+func RegisterMiddleware(name string, m Middleware) {
+	if name == "" || m == nil {
+		panic("gateway: RegisterMiddleware needs a name and a middleware")
+	}
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if _, dup := plugins[name]; dup {
+		panic(fmt.Sprintf("gateway: middleware %q registered twice", name))
+	}
+	plugins[name] = m
+}
+
+// lookupMiddleware returns the middleware registered as name, or nil.
+func lookupMiddleware(name string) Middleware {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	return plugins[name]
+}
+
+// WakeCheck decides whether a request may wake its container. A non-nil
+// error keeps the container asleep; its message is shown on the error page.
+type WakeCheck func(r *http.Request) error
+
+type wakeChecksKey struct{}
+
+// WithWakeCheck returns r with check added to the checks run before r wakes
+// a container, so a middleware can veto wakes without answering requests
+// to a running container.
+func WithWakeCheck(r *http.Request, check WakeCheck) *http.Request {
+	checks, _ := r.Context().Value(wakeChecksKey{}).([]WakeCheck)
+	checks = append(checks[:len(checks):len(checks)], check)
+	return r.WithContext(context.WithValue(r.Context(), wakeChecksKey{}, checks))
+}
+
+// checkWake runs the wake checks of r and returns the first veto.
+func checkWake(r *http.Request) error {
+	checks, _ := r.Context().Value(wakeChecksKey{}).([]WakeCheck)
+	for _, check := range checks {
+		if err := check(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wakeVetoMessage is the error page text for a wake a check declined.
+func wakeVetoMessage(name string, err error) string {
+	return fmt.Sprintf("%s is asleep and this request may not wake it: %v", name, err)
+}
+
+// newMiddlewareLimiter returns the rate limiter holding the buckets of the
+// rate_limit middlewares, one endpoint per middleware.
+func newMiddlewareLimiter(shared *SharedState) *rateLimiter {
+	return &rateLimiter{
+		policies: make(map[string]RateLimitPolicy),
+		buckets:  make(map[bucketKey]*tokenBucket),
+		now:      time.Now,
+		shared:   shared,
+	}
+}
+
+// middlewareEndpoint is the rate limiter endpoint of a rate_limit middleware.
+func middlewareEndpoint(name string) string {
+	return "middleware:" + name
+}
+
+// buildMiddlewares instantiates the middlewares defined in gateway.middlewares
+// and returns them with the policies of the rate_limit ones, for the caller
+// to install in s.mwLimiter, whose buckets survive reloads. It fails when a
+// script no longer compiles.
+func (s *Server) buildMiddlewares(defs map[string]MiddlewareConfig) (map[string]Middleware, map[string]RateLimitPolicy, error) {
+	built := make(map[string]Middleware, len(defs))
+	policies := make(map[string]RateLimitPolicy)
+	for name, def := range defs {
+		switch def.Type {
+		case MiddlewareAuth:
+			built[name] = authMiddleware(name, def.Auth)
+		case MiddlewareRateLimit:
+			policies[middlewareEndpoint(name)] = def.RateLimit
+			built[name] = s.rateLimitMiddleware(name)
+		case MiddlewareHeaders:
+			built[name] = headersMiddleware(def.RequestHeaders, def.ResponseHeaders)
+		case MiddlewarePlugin:
+			built[name] = lookupMiddleware(def.Plugin)
+		case MiddlewareScript:
+			m, err := s.scriptMiddleware(name, def.Script)
+			if err != nil {
+				// Validate compiled the script; its file changed since.
+				return nil, nil, fmt.Errorf("middlewares.%s: %w", name, err)
+			}
+			built[name] = m
+		}
+	}
+	return built, policies, nil
+}
+
+// withMiddlewares wraps h in the named middlewares, the first name being the
+// outermost. A name missing from the active configuration, which only
+// happens when a reload races the request, fails the request rather than
+// skipping a check.
+func (s *Server) withMiddlewares(names []string, h http.HandlerFunc) http.Handler {
+	if len(names) == 0 {
+		return h
+	}
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	var handler http.Handler = h
+	for i := len(names) - 1; i >= 0; i-- {
+		m := s.middlewares[names[i]]
+		if m == nil {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "middleware unavailable", http.StatusServiceUnavailable)
+			})
+		}
+		handler = m(handler)
+	}
+	return handler
+}
+
+// authMiddleware requires the credentials of cfg, basic or bearer. They are
+// meant for the gateway, so the Authorization header is not forwarded.
+func authMiddleware(name string, cfg AdminAuthConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var ok bool
+			switch cfg.Method {
+			case "basic":
+				ok = checkBasicAuth(r, cfg.Username, cfg.Password)
+			case "bearer":
+				ok = checkBearerToken(r, cfg.Token)
+			}
+			if !ok {
+				if cfg.Method == "basic" {
+					w.Header().Set("WWW-Authenticate", `Basic realm="`+name+`"`)
+				}
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				RecordMiddlewareRejection(name, "unauthorized")
+				slog.WarnContext(r.Context(), "middleware auth failed",
+					"middleware", name,
+					"remote", r.RemoteAddr,
+					"path", r.URL.Path,
+				)
+				return
+			}
+			r.Header.Del("Authorization")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitMiddleware answers 429 to clients over the bucket of middleware
+// name, like the limits of the internal endpoints.
+func (s *Server) rateLimitMiddleware(name string) Middleware {
+	endpoint := middlewareEndpoint(name)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := s.clientIP(r)
+			if s.mwLimiter.Allow(endpoint, ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			RecordMiddlewareRejection(name, "rate_limited")
+			s.bans.Strike(ip, strikeRateLimited)
+			w.Header().Set("Retry-After", strconv.Itoa(s.mwLimiter.retryAfter(endpoint)))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		})
+	}
+}
+
+// headersMiddleware sets request and response headers; an empty value
+// removes the header.
+func headersMiddleware(request, response map[string]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			applyHeaders(r.Header, request)
+			if len(response) > 0 {
+				w = &headerWriter{ResponseWriter: w, headers: response}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// applyHeaders sets or, for an empty value, deletes each header of set in h.
+func applyHeaders(h http.Header, set map[string]string) {
+	for k, v := range set {
+		if v == "" {
+			h.Del(k)
+		} else {
+			h.Set(k, v)
+		}
+	}
+}
+
+// headerWriter applies response headers when the response starts, after the
+// container's own headers were copied, so it can override and remove them.
+type headerWriter struct {
+	http.ResponseWriter
+	headers map[string]string
+	applied bool
+}
+
+func (h *headerWriter) apply() {
+	if !h.applied {
+		h.applied = true
+		applyHeaders(h.ResponseWriter.Header(), h.headers)
+	}
+}
+
+func (h *headerWriter) WriteHeader(statusCode int) {
+	h.apply()
+	h.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (h *headerWriter) Write(b []byte) (int, error) {
+	h.apply()
+	return h.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (h *headerWriter) Unwrap() http.ResponseWriter {
+	return h.ResponseWriter
+}
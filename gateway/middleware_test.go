@@ -0,0 +1,182 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware_Auth(t *testing.T) {
+	host, port := newBackend(t, "ok", echoHeaders("Authorization"))
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	rt.AddContainer("web", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway: GlobalConfig{Middlewares: map[string]MiddlewareConfig{
+			"login": {Type: MiddlewareAuth, Auth: AdminAuthConfig{Method: "basic", Username: "u", Password: "p"}},
+		}},
+		Containers: []ContainerConfig{
+			{Name: "app", Host: "app.local", TargetPort: port, Middlewares: []string{"login"}},
+			{Name: "web", Host: "web.local", TargetPort: port, Middlewares: []string{"login"}},
+		},
+	})
+
+	w := g.get("app.local", "/")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("without credentials: status %d, want 401 with a challenge", w.Code)
+	}
+	if calls := rt.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want no wake before authentication", calls)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "web.local"
+	req.SetBasicAuth("u", "p")
+	w = httptest.NewRecorder()
+	g.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("with credentials: status %d, body %q", w.Code, w.Body)
+	}
+	if got := w.Header().Get("X-Echo-Authorization"); got != "" {
+		t.Errorf("backend received Authorization %q, want it stripped", got)
+	}
+}
+
+func TestMiddleware_RateLimit(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway: GlobalConfig{Middlewares: map[string]MiddlewareConfig{
+			"slow": {Type: MiddlewareRateLimit, RateLimit: RateLimitPolicy{Rate: 0.01, Burst: 1}},
+		}},
+		Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: port, Middlewares: []string{"slow"}}},
+	})
+
+	if w := g.get("app.local", "/"); w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d", w.Code)
+	}
+	w := g.get("app.local", "/")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("second request: status %d, want 429 with Retry-After", w.Code)
+	}
+
+	// A reload keeps the bucket.
+	g.server.ReloadConfig(g.server.GetConfig())
+	if w := g.get("app.local", "/"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("after reload: status %d, want 429", w.Code)
+	}
+}
+
+func TestMiddleware_Headers(t *testing.T) {
+	host, port := newBackend(t, "ok", echoHeaders("X-Forwarded-User", "Cookie"),
+		backendHeader("Server", "backend"), backendHeader("X-Powered-By", "php"))
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway: GlobalConfig{Middlewares: map[string]MiddlewareConfig{
+			"hdr": {
+				Type:            MiddlewareHeaders,
+				RequestHeaders:  map[string]string{"X-Forwarded-User": "alice", "Cookie": ""},
+				ResponseHeaders: map[string]string{"Server": "gateway", "X-Powered-By": ""},
+			},
+		}},
+		Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: port, Middlewares: []string{"hdr"}}},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.local"
+	req.Header.Set("Cookie", "session=1")
+	w := httptest.NewRecorder()
+	g.handler.ServeHTTP(w, req)
+
+	h := w.Header()
+	if h.Get("X-Echo-X-Forwarded-User") != "alice" || h.Get("X-Echo-Cookie") != "" {
+		t.Errorf("backend saw X-Forwarded-User %q, Cookie %q", h.Get("X-Echo-X-Forwarded-User"), h.Get("X-Echo-Cookie"))
+	}
+	if h.Get("Server") != "gateway" || h.Get("X-Powered-By") != "" {
+		t.Errorf("response Server %q, X-Powered-By %q", h.Get("Server"), h.Get("X-Powered-By"))
+	}
+}
+
+func TestMiddleware_PluginOrder(t *testing.T) {
+	tag := func(s string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Order", s)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	RegisterMiddleware("test-first", tag("first"))
+	RegisterMiddleware("test-second", tag("second"))
+
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("a", FakeContainer{Status: "running", Host: host, Port: port})
+	rt.AddContainer("b", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway: GlobalConfig{Middlewares: map[string]MiddlewareConfig{
+			"one": {Type: MiddlewarePlugin, Plugin: "test-first"},
+			"two": {Type: MiddlewarePlugin, Plugin: "test-second"},
+		}},
+		Containers: []ContainerConfig{
+			{Name: "a", TargetPort: port},
+			{Name: "b", TargetPort: port},
+		},
+		Groups: []GroupConfig{{Name: "pool", Host: "pool.local", Members: []GroupMember{{Name: "a"}, {Name: "b"}}, Middlewares: []string{"two", "one"}}},
+	})
+
+	w := g.get("pool.local", "/")
+	if got := strings.Join(w.Header().Values("X-Order"), ","); got != "second,first" {
+		t.Errorf("X-Order = %q, want the listed order", got)
+	}
+}
+
+func TestRegisterMiddleware_Duplicate(t *testing.T) {
+	RegisterMiddleware("test-dup", func(next http.Handler) http.Handler { return next })
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice should panic")
+		}
+	}()
+	RegisterMiddleware("test-dup", func(next http.Handler) http.Handler { return next })
+}
+
+func TestValidate_Middlewares(t *testing.T) {
+	tests := []struct {
+		name    string
+		mws     map[string]MiddlewareConfig
+		use     []string
+		wantErr string
+	}{
+		{"unknown reference", nil, []string{"nope"}, `unknown middleware "nope"`},
+		{"unknown type", map[string]MiddlewareConfig{"x": {Type: "magic"}}, nil, "middlewares.x: unknown type"},
+		{"auth without method", map[string]MiddlewareConfig{"x": {Type: MiddlewareAuth}}, nil, "requires auth.method"},
+		{"auth without token", map[string]MiddlewareConfig{"x": {Type: MiddlewareAuth, Auth: AdminAuthConfig{Method: "bearer"}}}, nil, "requires non-empty token"},
+		{"rate limit without rate", map[string]MiddlewareConfig{"x": {Type: MiddlewareRateLimit}}, nil, "positive rate_limit.rate"},
+		{"empty headers", map[string]MiddlewareConfig{"x": {Type: MiddlewareHeaders}}, nil, "requires request_headers"},
+		{"unregistered plugin", map[string]MiddlewareConfig{"x": {Type: MiddlewarePlugin, Plugin: "missing"}}, nil, `plugin "missing" is not registered`},
+		{"valid", map[string]MiddlewareConfig{"x": {Type: MiddlewareHeaders, RequestHeaders: map[string]string{"A": "b"}}}, []string{"x"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &GatewayConfig{
+				Gateway:    GlobalConfig{Middlewares: tt.mws},
+				Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80", Middlewares: tt.use}},
+			}
+			applyDefaults(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
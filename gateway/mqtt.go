@@ -0,0 +1,678 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// mqttKeepAlive is the keep-alive interval announced to the broker.
+	mqttKeepAlive = 60 * time.Second
+	// mqttPollInterval is how often container states are re-read and
+	// published when they changed.
+	mqttPollInterval = 10 * time.Second
+	// mqttMaxBackoff caps the delay between reconnect attempts.
+	mqttMaxBackoff = time.Minute
+)
+
+// ─── MQTT 3.1.1 client ────────────────────────────────────────────────────────
+
+// MQTT control packet types (MQTT 3.1.1, section 2.2.1).
+const (
+	mqttPacketConnect    = 1
+	mqttPacketConnack    = 2
+	mqttPacketPublish    = 3
+	mqttPacketSubscribe  = 8
+	mqttPacketSuback     = 9
+	mqttPacketPingreq    = 12
+	mqttPacketPingresp   = 13
+	mqttPacketDisconnect = 14
+)
+
+// mqttOptions holds the CONNECT parameters of an mqttClient.
+type mqttOptions struct {
+	clientID  string
+	username  string
+	password  string
+	keepAlive time.Duration
+	// willTopic/willPayload define a retained last-will message; empty topic
+	// means no will.
+	willTopic   string
+	willPayload string
+}
+
+// mqttClient is a minimal MQTT 3.1.1 client supporting QoS 0 publish and
+// subscribe, which is all the Home Assistant bridge needs.
+type mqttClient struct {
+	conn      net.Conn
+	r         *bufio.Reader
+	keepAlive time.Duration
+
+	wmu sync.Mutex // serialises packet writes
+}
+
+// parseMQTTBroker splits a broker address into a dialable host:port and
+// whether TLS is used. Accepted schemes: tcp, mqtt, ssl, tls, mqtts; a bare
+// "host:port" is treated as tcp.
+func parseMQTTBroker(broker string) (addr string, useTLS bool, err error) {
+	if !strings.Contains(broker, "://") {
+		broker = "tcp://" + broker
+	}
+	u, err := url.Parse(broker)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid broker %q: %w", broker, err)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("invalid broker %q: unsupported scheme %q", broker, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", false, fmt.Errorf("invalid broker %q: missing host", broker)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// dialMQTT connects to the broker and completes the CONNECT/CONNACK handshake.
+func dialMQTT(ctx context.Context, broker string, opts mqttOptions) (*mqttClient, error) {
+	addr, useTLS, err := parseMQTTBroker(broker)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		d := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &mqttClient{conn: conn, r: bufio.NewReader(conn), keepAlive: opts.keepAlive}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := c.writePacket(mqttPacketConnect<<4, encodeMQTTConnect(opts)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	header, body, err := c.readPacket()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if header>>4 != mqttPacketConnack || len(body) < 2 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: expected CONNACK, got packet type %d", header>>4)
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: connection refused (return code %d)", body[1])
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// encodeMQTTConnect builds the variable header and payload of a CONNECT packet.
+func encodeMQTTConnect(opts mqttOptions) []byte {
+	var flags byte = 0x02 // clean session
+	if opts.willTopic != "" {
+		flags |= 0x04 | 0x20 // will flag, will retain (QoS 0)
+	}
+	if opts.password != "" {
+		flags |= 0x40
+	}
+	if opts.username != "" {
+		flags |= 0x80
+	}
+
+	b := appendMQTTString(nil, "MQTT")
+	b = append(b, 4, flags) // protocol level 4 = 3.1.1
+	b = binary.BigEndian.AppendUint16(b, uint16(opts.keepAlive/time.Second))
+	b = appendMQTTString(b, opts.clientID)
+	if opts.willTopic != "" {
+		b = appendMQTTString(b, opts.willTopic)
+		b = appendMQTTString(b, opts.willPayload)
+	}
+	if opts.username != "" {
+		b = appendMQTTString(b, opts.username)
+	}
+	if opts.password != "" {
+		b = appendMQTTString(b, opts.password)
+	}
+	return b
+}
+
+// appendMQTTString appends a length-prefixed UTF-8 string.
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readMQTTString reads a length-prefixed string and returns the remainder.
+func readMQTTString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("mqtt: short string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("mqtt: short string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+// writePacket writes a fixed header with the given first byte followed by body.
+func (c *mqttClient) writePacket(header byte, body []byte) error {
+	pkt := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		pkt = append(pkt, digit)
+		if n == 0 {
+			break
+		}
+	}
+	pkt = append(pkt, body...)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(pkt)
+	return err
+}
+
+// readPacket reads one control packet and returns its first header byte and body.
+func (c *mqttClient) readPacket() (byte, []byte, error) {
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		digit, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// publish sends a QoS 0 PUBLISH packet.
+func (c *mqttClient) publish(topic string, payload []byte, retain bool) error {
+	header := byte(mqttPacketPublish << 4)
+	if retain {
+		header |= 0x01
+	}
+	return c.writePacket(header, append(appendMQTTString(nil, topic), payload...))
+}
+
+// subscribe sends a SUBSCRIBE packet requesting QoS 0 for every filter.
+// The SUBACK is consumed by readLoop.
+func (c *mqttClient) subscribe(packetID uint16, filters ...string) error {
+	b := binary.BigEndian.AppendUint16(nil, packetID)
+	for _, f := range filters {
+		b = appendMQTTString(b, f)
+		b = append(b, 0)
+	}
+	return c.writePacket(mqttPacketSubscribe<<4|0x02, b)
+}
+
+// ping sends a PINGREQ packet.
+func (c *mqttClient) ping() error {
+	return c.writePacket(mqttPacketPingreq<<4, nil)
+}
+
+// readLoop reads packets until the connection fails and passes every
+// incoming PUBLISH to handler. The broker must send something (at least a
+// PINGRESP) within 1.5× the keep-alive interval.
+func (c *mqttClient) readLoop(handler func(topic string, payload []byte)) error {
+	for {
+		if c.keepAlive > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		}
+		header, body, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		switch header >> 4 {
+		case mqttPacketPublish:
+			topic, rest, err := readMQTTString(body)
+			if err != nil {
+				return err
+			}
+			if qos := (header >> 1) & 0x03; qos > 0 {
+				// We only subscribe with QoS 0, but skip the packet ID anyway.
+				if len(rest) < 2 {
+					return errors.New("mqtt: short publish")
+				}
+				rest = rest[2:]
+			}
+			handler(topic, rest)
+		case mqttPacketSuback:
+			if len(body) >= 3 && body[2] == 0x80 {
+				return errors.New("mqtt: subscription rejected by broker")
+			}
+		case mqttPacketPingresp:
+		}
+	}
+}
+
+// close sends DISCONNECT (so the broker discards the last will) and closes
+// the connection.
+func (c *mqttClient) close() {
+	c.writePacket(mqttPacketDisconnect<<4, nil) //nolint:errcheck
+	c.conn.Close()
+}
+
+// ─── Home Assistant bridge ────────────────────────────────────────────────────
+
+// mqttContainerState is the last state published for a container.
+type mqttContainerState struct {
+	State        string
+	LastActivity string
+}
+
+// mqttObjectID turns a name into a Home Assistant object ID ([a-zA-Z0-9_]).
+func mqttObjectID(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// mqttCommand parses the payload of a <prefix>/<container>/set message.
+// It returns "wake", "sleep" or "" for unknown payloads.
+func mqttCommand(payload []byte) string {
+	switch strings.ToLower(strings.TrimSpace(string(payload))) {
+	case "on", "wake", "start":
+		return "wake"
+	case "off", "sleep", "stop":
+		return "sleep"
+	}
+	return ""
+}
+
+// haDiscoveryMessage is a retained Home Assistant discovery config message.
+type haDiscoveryMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+// haDiscoveryMessages builds the discovery configs for a container: a switch
+// (wake/sleep), a state sensor and a last-activity timestamp sensor, grouped
+// under one device per container.
+func haDiscoveryMessages(cfg MQTTConfig, name string) []haDiscoveryMessage {
+	node := mqttObjectID(cfg.TopicPrefix)
+	obj := mqttObjectID(name)
+	base := cfg.TopicPrefix + "/" + name
+	device := map[string]any{
+		"identifiers":  []string{node + "_" + obj},
+		"name":         name,
+		"manufacturer": "docker-gateway",
+		"model":        "Managed container",
+	}
+	common := func(extra map[string]any) []byte {
+		extra["availability_topic"] = cfg.TopicPrefix + "/status"
+		extra["device"] = device
+		b, _ := json.Marshal(extra)
+		return b
+	}
+	topic := func(component, suffix string) string {
+		return cfg.DiscoveryPrefix + "/" + component + "/" + node + "/" + obj + suffix + "/config"
+	}
+	return []haDiscoveryMessage{
+		{Topic: topic("switch", ""), Payload: common(map[string]any{
+			"name":           "Awake",
+			"unique_id":      node + "_" + obj + "_switch",
+			"command_topic":  base + "/set",
+			"state_topic":    base + "/state",
+			"value_template": "{{ 'ON' if value in ['running', 'starting'] else 'OFF' }}",
+			"payload_on":     "ON",
+			"payload_off":    "OFF",
+			"icon":           "mdi:docker",
+		})},
+		{Topic: topic("sensor", "_state"), Payload: common(map[string]any{
+			"name":        "State",
+			"unique_id":   node + "_" + obj + "_state",
+			"state_topic": base + "/state",
+			"icon":        "mdi:state-machine",
+		})},
+		{Topic: topic("sensor", "_last_activity"), Payload: common(map[string]any{
+			"name":         "Last activity",
+			"unique_id":    node + "_" + obj + "_last_activity",
+			"state_topic":  base + "/last_activity",
+			"device_class": "timestamp",
+		})},
+	}
+}
+
+// MQTTBridge publishes container states to an MQTT broker with Home Assistant
+// discovery and accepts wake/sleep commands on <prefix>/<container>/set.
+// Call Sync on startup and on every config hot-reload.
+type MQTTBridge struct {
+	manager        *ContainerManager
+	configProvider func() []ContainerConfig
+
+	mu      sync.Mutex
+	cfg     MQTTConfig
+	reload  chan struct{}
+	refresh chan struct{}
+}
+
+// NewMQTTBridge creates a disabled MQTTBridge; it connects once Sync receives
+// a configuration with a broker.
+func NewMQTTBridge(manager *ContainerManager, configProvider func() []ContainerConfig) *MQTTBridge {
+	return &MQTTBridge{
+		manager:        manager,
+		configProvider: configProvider,
+		reload:         make(chan struct{}, 1),
+		refresh:        make(chan struct{}, 1),
+	}
+}
+
+// Sync applies a new MQTT configuration, reconnecting when it changed.
+func (b *MQTTBridge) Sync(cfg MQTTConfig) {
+	b.mu.Lock()
+	changed := !reflect.DeepEqual(b.cfg, cfg)
+	b.cfg = cfg
+	b.mu.Unlock()
+	if changed {
+		trySignal(b.reload)
+	}
+}
+
+// Handle is an EventBus subscriber: it triggers an immediate state publish.
+func (b *MQTTBridge) Handle(Event) {
+	trySignal(b.refresh)
+}
+
+// trySignal performs a non-blocking send on a 1-buffered signal channel.
+func trySignal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (b *MQTTBridge) config() MQTTConfig {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cfg
+}
+
+// Start runs the bridge in the background until ctx is cancelled,
+// reconnecting with exponential backoff when the broker connection drops.
+func (b *MQTTBridge) Start(ctx context.Context) {
+	go func() {
+		backoff := time.Second
+		for {
+			cfg := b.config()
+			if cfg.Broker == "" {
+				select {
+				case <-ctx.Done():
+					return
+				case <-b.reload:
+					continue
+				}
+			}
+
+			// The config is current; drop a pending reload signal.
+			select {
+			case <-b.reload:
+			default:
+			}
+			err := b.session(ctx, cfg)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				// Configuration changed — reconnect right away.
+				backoff = time.Second
+				continue
+			}
+			slog.Warn("mqtt: connection lost", "broker", cfg.Broker, "retry_in", backoff, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-b.reload:
+				backoff = time.Second
+			case <-time.After(backoff):
+				backoff = min(backoff*2, mqttMaxBackoff)
+			}
+		}
+	}()
+}
+
+// session runs one broker connection. It returns nil when the configuration
+// changed or ctx was cancelled, and an error when the connection failed.
+func (b *MQTTBridge) session(ctx context.Context, cfg MQTTConfig) error {
+	availability := cfg.TopicPrefix + "/status"
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	client, err := dialMQTT(dialCtx, cfg.Broker, mqttOptions{
+		clientID:    cfg.ClientID,
+		username:    cfg.Username,
+		password:    cfg.Password,
+		keepAlive:   mqttKeepAlive,
+		willTopic:   availability,
+		willPayload: "offline",
+	})
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer client.close()
+	slog.Info("mqtt: connected", "broker", cfg.Broker)
+
+	if err := client.publish(availability, []byte("online"), true); err != nil {
+		return err
+	}
+	if err := client.subscribe(1, cfg.TopicPrefix+"/+/set"); err != nil {
+		return err
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- client.readLoop(func(topic string, payload []byte) {
+			b.handleCommand(cfg, topic, payload)
+		})
+	}()
+
+	announced := make(map[string]bool)
+	published := make(map[string]mqttContainerState)
+	if err := b.publishStates(ctx, client, cfg, announced, published); err != nil {
+		return err
+	}
+
+	poll := time.NewTicker(mqttPollInterval)
+	defer poll.Stop()
+	ping := time.NewTicker(mqttKeepAlive / 2)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			client.publish(availability, []byte("offline"), true) //nolint:errcheck
+			return nil
+		case <-b.reload:
+			return nil
+		case err := <-readErr:
+			return err
+		case <-ping.C:
+			if err := client.ping(); err != nil {
+				return err
+			}
+		case <-poll.C:
+		case <-b.refresh:
+		}
+		if err := b.publishStates(ctx, client, cfg, announced, published); err != nil {
+			return err
+		}
+	}
+}
+
+// publishStates announces new containers to Home Assistant, removes the
+// entities of containers no longer configured and publishes every state that
+// changed since the last call.
+func (b *MQTTBridge) publishStates(ctx context.Context, client *mqttClient, cfg MQTTConfig,
+	announced map[string]bool, published map[string]mqttContainerState) error {
+	current := make(map[string]bool)
+	for _, c := range b.configProvider() {
+		current[c.Name] = true
+		base := cfg.TopicPrefix + "/" + c.Name
+
+		if !announced[c.Name] {
+			for _, msg := range haDiscoveryMessages(cfg, c.Name) {
+				if err := client.publish(msg.Topic, msg.Payload, true); err != nil {
+					return err
+				}
+			}
+			announced[c.Name] = true
+		}
+
+		st := b.containerState(ctx, c.Name)
+		prev, seen := published[c.Name]
+		if !seen || prev.State != st.State {
+			if err := client.publish(base+"/state", []byte(st.State), true); err != nil {
+				return err
+			}
+		}
+		if st.LastActivity != "" && (!seen || prev.LastActivity != st.LastActivity) {
+			if err := client.publish(base+"/last_activity", []byte(st.LastActivity), true); err != nil {
+				return err
+			}
+		}
+		published[c.Name] = st
+	}
+
+	// Clear retained messages of containers that disappeared from the config
+	// so Home Assistant removes their entities.
+	for name := range announced {
+		if current[name] {
+			continue
+		}
+		for _, msg := range haDiscoveryMessages(cfg, name) {
+			if err := client.publish(msg.Topic, nil, true); err != nil {
+				return err
+			}
+		}
+		base := cfg.TopicPrefix + "/" + name
+		client.publish(base+"/state", nil, true)         //nolint:errcheck
+		client.publish(base+"/last_activity", nil, true) //nolint:errcheck
+		delete(announced, name)
+		delete(published, name)
+	}
+	return nil
+}
+
+// containerState reads the current state of a container for publishing.
+func (b *MQTTBridge) containerState(ctx context.Context, name string) mqttContainerState {
+	startStatus, _ := b.manager.GetStartState(name)
+	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	dockerStatus, _ := b.manager.client.GetContainerStatus(checkCtx, name)
+	cancel()
+
+	st := mqttContainerState{State: lifecycleState(startStatus, dockerStatus)}
+	if last, ok := b.manager.GetLastSeen(name); ok {
+		st.LastActivity = last.UTC().Format(time.RFC3339)
+	}
+	return st
+}
+
+// handleCommand executes a wake/sleep command received on
+// <prefix>/<container>/set. Commands run in the background.
+func (b *MQTTBridge) handleCommand(cfg MQTTConfig, topic string, payload []byte) {
+	name, ok := strings.CutPrefix(topic, cfg.TopicPrefix+"/")
+	if !ok {
+		return
+	}
+	name, ok = strings.CutSuffix(name, "/set")
+	if !ok || strings.Contains(name, "/") {
+		return
+	}
+
+	var target *ContainerConfig
+	for _, c := range b.configProvider() {
+		if c.Name == name {
+			target = &c
+			break
+		}
+	}
+	if target == nil {
+		slog.Warn("mqtt: command for unknown container", "container", name)
+		return
+	}
+	if b.manager.ReadOnly() {
+		slog.Warn("mqtt: command ignored: the gateway is read-only", "container", name, "payload", string(payload))
+		return
+	}
+
+	switch mqttCommand(payload) {
+	case "wake":
+		slog.Info("mqtt: wake requested", "container", name)
+		b.manager.InitStartState(name)
+		trySignal(b.refresh)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), b.manager.wakeTimeout(target))
+			defer cancel()
+			if err := b.manager.Wake(ctx, target, b.configProvider()); err != nil {
+				slog.Error("mqtt: wake failed", "container", name, "error", err)
+			} else {
+				// Count the wake as activity so idle_timeout applies.
+				b.manager.RecordActivity(name)
+			}
+			trySignal(b.refresh)
+		}()
+	case "sleep":
+		slog.Info("mqtt: sleep requested", "container", name)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), defaultSleepDrainTimeout+30*time.Second)
+			defer cancel()
+			if err := b.manager.Sleep(ctx, name, defaultSleepDrainTimeout); err != nil {
+				slog.Error("mqtt: sleep failed", "container", name, "error", err)
+			}
+			trySignal(b.refresh)
+		}()
+	default:
+		slog.Warn("mqtt: unknown command", "container", name, "payload", string(payload))
+	}
+}
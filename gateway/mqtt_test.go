@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ─── Broker parsing ───────────────────────────────────────────────────────────
+
+func TestParseMQTTBroker(t *testing.T) {
+	tests := []struct {
+		broker   string
+		wantAddr string
+		wantTLS  bool
+		wantErr  bool
+	}{
+		{"tcp://mosquitto:1883", "mosquitto:1883", false, false},
+		{"mqtt://mosquitto", "mosquitto:1883", false, false},
+		{"mosquitto:1884", "mosquitto:1884", false, false},
+		{"ssl://broker.example.com", "broker.example.com:8883", true, false},
+		{"mqtts://broker.example.com:9883", "broker.example.com:9883", true, false},
+		{"tcp://[::1]:1883", "[::1]:1883", false, false},
+		{"ws://broker:9001", "", false, true},
+		{"tcp://:1883", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.broker, func(t *testing.T) {
+			addr, useTLS, err := parseMQTTBroker(tt.broker)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMQTTBroker(%q) error = %v, wantErr %v", tt.broker, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if addr != tt.wantAddr || useTLS != tt.wantTLS {
+				t.Errorf("parseMQTTBroker(%q) = (%q, %v), want (%q, %v)",
+					tt.broker, addr, useTLS, tt.wantAddr, tt.wantTLS)
+			}
+		})
+	}
+}
+
+// ─── Commands ─────────────────────────────────────────────────────────────────
+
+func TestMQTTCommand(t *testing.T) {
+	tests := map[string]string{
+		"ON":      "wake",
+		"wake":    "wake",
+		" start ": "wake",
+		"OFF":     "sleep",
+		"Sleep":   "sleep",
+		"stop":    "sleep",
+		"toggle":  "",
+		"":        "",
+	}
+	for payload, want := range tests {
+		if got := mqttCommand([]byte(payload)); got != want {
+			t.Errorf("mqttCommand(%q) = %q, want %q", payload, got, want)
+		}
+	}
+}
+
+// ─── Home Assistant discovery ─────────────────────────────────────────────────
+
+func TestHADiscoveryMessages(t *testing.T) {
+	cfg := MQTTConfig{TopicPrefix: "docker-gateway", DiscoveryPrefix: "homeassistant"}
+	msgs := haDiscoveryMessages(cfg, "my-app")
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3", len(msgs))
+	}
+
+	wantTopics := []string{
+		"homeassistant/switch/docker_gateway/my_app/config",
+		"homeassistant/sensor/docker_gateway/my_app_state/config",
+		"homeassistant/sensor/docker_gateway/my_app_last_activity/config",
+	}
+	for i, want := range wantTopics {
+		if msgs[i].Topic != want {
+			t.Errorf("topic[%d] = %q, want %q", i, msgs[i].Topic, want)
+		}
+	}
+
+	var sw map[string]any
+	if err := json.Unmarshal(msgs[0].Payload, &sw); err != nil {
+		t.Fatalf("invalid switch payload: %v", err)
+	}
+	if sw["command_topic"] != "docker-gateway/my-app/set" {
+		t.Errorf("command_topic = %v", sw["command_topic"])
+	}
+	if sw["state_topic"] != "docker-gateway/my-app/state" {
+		t.Errorf("state_topic = %v", sw["state_topic"])
+	}
+	if sw["availability_topic"] != "docker-gateway/status" {
+		t.Errorf("availability_topic = %v", sw["availability_topic"])
+	}
+	if _, ok := sw["device"].(map[string]any); !ok {
+		t.Errorf("switch payload has no device block")
+	}
+
+	var last map[string]any
+	if err := json.Unmarshal(msgs[2].Payload, &last); err != nil {
+		t.Fatalf("invalid sensor payload: %v", err)
+	}
+	if last["device_class"] != "timestamp" {
+		t.Errorf("device_class = %v, want timestamp", last["device_class"])
+	}
+}
+
+// ─── Client ↔ broker ──────────────────────────────────────────────────────────
+
+// TestMQTTClient runs the client against a scripted in-process broker.
+func TestMQTTClient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	type published struct {
+		topic   string
+		payload string
+		retain  bool
+	}
+	connectErr := make(chan string, 1)
+	gotPublish := make(chan published, 1)
+	gotSubscribe := make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		broker := &mqttClient{conn: conn, r: bufio.NewReader(conn)}
+
+		// CONNECT
+		header, body, err := broker.readPacket()
+		if err != nil || header>>4 != mqttPacketConnect {
+			connectErr <- "expected CONNECT"
+			return
+		}
+		proto, rest, _ := readMQTTString(body)
+		flags := rest[1]
+		clientID, rest, _ := readMQTTString(rest[4:])
+		willTopic, rest, _ := readMQTTString(rest)
+		willPayload, rest, _ := readMQTTString(rest)
+		user, rest, _ := readMQTTString(rest)
+		pass, _, _ := readMQTTString(rest)
+		switch {
+		case proto != "MQTT":
+			connectErr <- "protocol = " + proto
+		case flags != 0xe6:
+			connectErr <- "unexpected connect flags"
+		case clientID != "gw-test" || user != "u" || pass != "p":
+			connectErr <- "unexpected credentials"
+		case willTopic != "gw/status" || willPayload != "offline":
+			connectErr <- "unexpected will"
+		default:
+			connectErr <- ""
+		}
+		broker.writePacket(mqttPacketConnack<<4, []byte{0, 0})
+
+		// SUBSCRIBE
+		_, body, err = broker.readPacket()
+		if err != nil {
+			return
+		}
+		filter, _, _ := readMQTTString(body[2:])
+		gotSubscribe <- filter
+		broker.writePacket(mqttPacketSuback<<4, []byte{body[0], body[1], 0})
+
+		// PUBLISH
+		header, body, err = broker.readPacket()
+		if err != nil {
+			return
+		}
+		topic, payload, _ := readMQTTString(body)
+		gotPublish <- published{topic: topic, payload: string(payload), retain: header&0x01 == 1}
+
+		// Deliver a command to the client.
+		broker.writePacket(mqttPacketPublish<<4, append(appendMQTTString(nil, "gw/app/set"), "ON"...))
+		time.Sleep(200 * time.Millisecond)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := dialMQTT(ctx, ln.Addr().String(), mqttOptions{
+		clientID:    "gw-test",
+		username:    "u",
+		password:    "p",
+		keepAlive:   time.Minute,
+		willTopic:   "gw/status",
+		willPayload: "offline",
+	})
+	if err != nil {
+		t.Fatalf("dialMQTT() error = %v", err)
+	}
+	defer client.close()
+	if msg := <-connectErr; msg != "" {
+		t.Fatalf("CONNECT: %s", msg)
+	}
+
+	received := make(chan string, 1)
+	go client.readLoop(func(topic string, payload []byte) {
+		received <- topic + "=" + string(payload)
+	})
+
+	if err := client.subscribe(1, "gw/+/set"); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	if filter := <-gotSubscribe; filter != "gw/+/set" {
+		t.Errorf("subscribed filter = %q, want gw/+/set", filter)
+	}
+
+	if err := client.publish("gw/app/state", []byte(strings.Repeat("x", 200)), true); err != nil {
+		t.Fatalf("publish() error = %v", err)
+	}
+	p := <-gotPublish
+	if p.topic != "gw/app/state" || len(p.payload) != 200 || !p.retain {
+		t.Errorf("broker got topic=%q len=%d retain=%v", p.topic, len(p.payload), p.retain)
+	}
+
+	select {
+	case msg := <-received:
+		if msg != "gw/app/set=ON" {
+			t.Errorf("received %q, want gw/app/set=ON", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not receive the broker PUBLISH")
+	}
+}
@@ -0,0 +1,289 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// networkDetachInterval is how often networks attached on demand are checked
+// for running backends.
+const networkDetachInterval = time.Minute
+
+// networkAttacher connects the gateway's own container to backend networks
+// on demand (network_attach). It only ever disconnects networks it attached
+// itself, never the ones the gateway was started with.
+//
+// Ensure runs on every proxied request: a backend already known to share a
+// network with the gateway is answered from verified without any lock.
+// Docker calls are made without mu held; only connects and disconnects are
+// serialised, by changeMu, so they never race each other.
+type networkAttacher struct {
+	client ContainerRuntime
+
+	enabled  atomic.Bool // network_attach is on and the gateway container known
+	verified sync.Map    // backend → network shared with the gateway
+
+	changeMu sync.Mutex // serialises network connects and disconnects
+
+	mu       sync.Mutex
+	cfg      NetworkAttachConfig
+	self     string
+	selfNets map[string]bool // networks of the gateway; nil until loaded
+	attached map[string]bool // networks connected by the attacher
+}
+
+func newNetworkAttacher(client ContainerRuntime) *networkAttacher {
+	return &networkAttacher{
+		client:   client,
+		attached: make(map[string]bool),
+	}
+}
+
+// Sync applies a new network_attach configuration. A different gateway
+// container invalidates everything learnt about the current one.
+func (a *networkAttacher) Sync(cfg NetworkAttachConfig) {
+	if cfg.Container == "" {
+		cfg.Container, _ = os.Hostname()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if cfg.Container != a.self {
+		a.self = cfg.Container
+		a.selfNets = nil
+		a.attached = make(map[string]bool)
+		a.verified.Clear()
+	}
+	a.cfg = cfg
+	a.enabled.Store(cfg.Enabled && a.self != "")
+}
+
+// Ensure makes sure the gateway shares a network with cfg, connecting it to
+// the first preferred network (or the backend's first network) when it
+// shares none.
+func (a *networkAttacher) Ensure(ctx context.Context, cfg *ContainerConfig) error {
+	if !a.enabled.Load() || cfg.Target == TargetPublished {
+		return nil
+	}
+	if _, ok := a.verified.Load(cfg.Name); ok {
+		return nil
+	}
+	if !a.client.Capable(CapabilityNetwork) {
+		return nil
+	}
+	self, selfNets, err := a.gatewayNetworks(ctx)
+	if err != nil || self == "" {
+		return err
+	}
+
+	var network string
+	if prefs := cfg.NetworkPreference(); len(prefs) > 0 {
+		network = prefs[0]
+		for _, n := range prefs {
+			if selfNets[n] {
+				network = n
+				break
+			}
+		}
+	} else {
+		nets, err := a.client.ContainerNetworks(ctx, cfg.Name)
+		if err != nil {
+			return err
+		}
+		for _, n := range nets {
+			if selfNets[n] {
+				a.verify(self, cfg.Name, n)
+				return nil
+			}
+		}
+		for _, n := range nets {
+			if n != "host" && n != "none" {
+				network = n
+				break
+			}
+		}
+		if network == "" {
+			return nil // nothing to join; address resolution reports the error
+		}
+	}
+
+	if !selfNets[network] {
+		if err := a.connect(ctx, self, network, cfg.Name); err != nil {
+			return err
+		}
+	}
+	a.verify(self, cfg.Name, network)
+	return nil
+}
+
+// gatewayNetworks returns the gateway container and a copy of its networks,
+// inspecting it the first time.
+func (a *networkAttacher) gatewayNetworks(ctx context.Context) (string, map[string]bool, error) {
+	a.mu.Lock()
+	self, nets := a.self, maps.Clone(a.selfNets)
+	a.mu.Unlock()
+	if nets != nil || self == "" {
+		return self, nets, nil
+	}
+
+	list, err := a.client.ContainerNetworks(ctx, self)
+	if err != nil {
+		return "", nil, fmt.Errorf("inspect gateway container %q: %w", self, err)
+	}
+	nets = make(map[string]bool, len(list))
+	for _, n := range list {
+		nets[n] = true
+	}
+	a.mu.Lock()
+	if a.self == self && a.selfNets == nil {
+		a.selfNets = maps.Clone(nets)
+	}
+	a.mu.Unlock()
+	return self, nets, nil
+}
+
+// connect joins network for backend, unless a concurrent Ensure already did.
+func (a *networkAttacher) connect(ctx context.Context, self, network, backend string) error {
+	a.changeMu.Lock()
+	defer a.changeMu.Unlock()
+	a.mu.Lock()
+	joined := a.self != self || a.selfNets[network]
+	a.mu.Unlock()
+	if joined {
+		return nil
+	}
+
+	if err := a.client.ConnectNetwork(ctx, network, self); err != nil {
+		return fmt.Errorf("attach gateway to network %q: %w", network, err)
+	}
+	a.mu.Lock()
+	if a.self == self && a.selfNets != nil {
+		a.selfNets[network] = true
+		a.attached[network] = true
+	}
+	a.mu.Unlock()
+	slog.Info("network attach: gateway joined network", "network", network, "for", backend)
+	return nil
+}
+
+// verify records that backend shares network with the gateway self.
+func (a *networkAttacher) verify(self, backend, network string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.self == self {
+		a.verified.Store(backend, network)
+	}
+}
+
+// detachUnused disconnects the networks attached on demand that no running
+// backend uses any more. With network_attach disabled it detaches them all.
+func (a *networkAttacher) detachUnused(ctx context.Context, cfgs []ContainerConfig) {
+	a.mu.Lock()
+	self, enabled, attached := a.self, a.cfg.Enabled, maps.Clone(a.attached)
+	a.mu.Unlock()
+	if len(attached) == 0 {
+		return
+	}
+
+	configured := make(map[string]bool, len(cfgs))
+	for _, c := range cfgs {
+		configured[c.Name] = true
+	}
+	checked := make(map[string]bool)
+	inUse := make(map[string]bool)
+	a.verified.Range(func(k, v any) bool {
+		name, network := k.(string), v.(string)
+		checked[name] = true
+		if !enabled {
+			return true
+		}
+		if !configured[name] {
+			a.verified.CompareAndDelete(name, network)
+			return true
+		}
+		if inUse[network] || !attached[network] {
+			return true
+		}
+		if status, err := a.client.GetContainerStatus(ctx, name); err == nil && status == "running" {
+			inUse[network] = true
+		}
+		return true
+	})
+
+	a.changeMu.Lock()
+	defer a.changeMu.Unlock()
+	for network := range attached {
+		if inUse[network] || a.joinedSince(self, network, checked) {
+			continue
+		}
+		if err := a.client.DisconnectNetwork(ctx, network, self); err != nil {
+			slog.Warn("network attach: detach failed", "network", network, "error", err)
+			continue
+		}
+		a.mu.Lock()
+		if a.self == self {
+			delete(a.attached, network)
+			delete(a.selfNets, network)
+			a.verified.Range(func(k, v any) bool {
+				if v == network {
+					a.verified.Delete(k)
+				}
+				return true
+			})
+		}
+		a.mu.Unlock()
+		slog.Info("network attach: gateway left unused network", "network", network)
+	}
+}
+
+// joinedSince reports whether network must stay: the gateway changed, or a
+// backend outside checked was verified on it while its use was being checked.
+func (a *networkAttacher) joinedSince(self, network string, checked map[string]bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.self != self || !a.attached[network] {
+		return true
+	}
+	joined := false
+	a.verified.Range(func(k, v any) bool {
+		joined = v == network && !checked[k.(string)]
+		return !joined
+	})
+	return joined
+}
+
+// SyncNetworkAttach applies the network_attach configuration.
+func (m *ContainerManager) SyncNetworkAttach(cfg NetworkAttachConfig) {
+	m.netAttach.Sync(cfg)
+}
+
+// StartNetworkDetacher periodically disconnects the gateway from networks it
+// joined for backends that are no longer running.
+func (m *ContainerManager) StartNetworkDetacher(ctx context.Context, configProvider func() []ContainerConfig) {
+	go func() {
+		ticker := time.NewTicker(networkDetachInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.netAttach.detachUnused(ctx, configProvider())
+			}
+		}
+	}()
+}
+
+// resolveTarget joins the backend's network if needed, then returns the host
+// and port to dial for cfg.
+func (m *ContainerManager) resolveTarget(ctx context.Context, cfg *ContainerConfig) (string, string, error) {
+	if err := m.netAttach.Ensure(ctx, cfg); err != nil {
+		return "", "", err
+	}
+	return m.client.ResolveTarget(ctx, cfg)
+}
@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeNetworkDaemon serves container inspects with the given networks and
+// records network connects and disconnects.
+type fakeNetworkDaemon struct {
+	mu       sync.Mutex
+	networks map[string][]string // container → networks
+	status   map[string]string   // container → state
+	calls    []string            // "connect backend gw", "disconnect backend gw"
+}
+
+func (f *fakeNetworkDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("API-Version", "1.43")
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/") // v1.43/<kind>/<name>/<action>
+	if len(parts) != 4 {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case parts[1] == "networks" && r.Method == http.MethodPost:
+		f.calls = append(f.calls, parts[3]+" "+parts[2])
+		w.WriteHeader(http.StatusOK)
+	case parts[1] == "containers" && parts[3] == "json":
+		var nets []string
+		for _, n := range f.networks[parts[2]] {
+			nets = append(nets, `"`+n+`":{"IPAddress":"10.0.0.2"}`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Name":"/` + parts[2] + `","State":{"Status":"` + f.status[parts[2]] + `"},` +
+			`"NetworkSettings":{"Networks":{` + strings.Join(nets, ",") + `}}}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeNetworkDaemon) recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func newTestAttacher(t *testing.T) (*networkAttacher, *fakeNetworkDaemon) {
+	f := &fakeNetworkDaemon{
+		networks: map[string][]string{"gw": {"frontend"}, "app": {"backend"}, "web": {"frontend"}},
+		status:   map[string]string{"gw": "running", "app": "running", "web": "running"},
+	}
+	daemon := httptest.NewServer(f)
+	t.Cleanup(daemon.Close)
+	a := newNetworkAttacher(newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String()))
+	a.Sync(NetworkAttachConfig{Enabled: true, Container: "gw"})
+	return a, f
+}
+
+func TestNetworkAttacher_JoinsAndLeaves(t *testing.T) {
+	a, f := newTestAttacher(t)
+	ctx := context.Background()
+	cfgs := []ContainerConfig{{Name: "app"}, {Name: "web"}}
+
+	// Shares frontend already: nothing to do.
+	if err := a.Ensure(ctx, &cfgs[1]); err != nil {
+		t.Fatal(err)
+	}
+	// app is only on backend: the gateway joins it, once.
+	for i := 0; i < 2; i++ {
+		if err := a.Ensure(ctx, &cfgs[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := f.recorded(); len(got) != 1 || got[0] != "connect backend" {
+		t.Fatalf("calls = %v, want [connect backend]", got)
+	}
+
+	// Still running: the network stays.
+	a.detachUnused(ctx, cfgs)
+	if got := f.recorded(); len(got) != 1 {
+		t.Fatalf("detached a network still in use: %v", got)
+	}
+
+	f.mu.Lock()
+	f.status["app"] = "exited"
+	f.mu.Unlock()
+	a.detachUnused(ctx, cfgs)
+	if got := f.recorded(); len(got) != 2 || got[1] != "disconnect backend" {
+		t.Fatalf("calls = %v, want backend disconnected", got)
+	}
+}
+
+func TestNetworkAttacher_ExplicitNetworkAndDisabled(t *testing.T) {
+	a, f := newTestAttacher(t)
+	ctx := context.Background()
+
+	// The configured network is joined even if another one is shared.
+	if err := a.Ensure(ctx, &ContainerConfig{Name: "web", Network: "metrics"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.recorded(); len(got) != 1 || got[0] != "connect metrics" {
+		t.Fatalf("calls = %v, want [connect metrics]", got)
+	}
+
+	// Disabling leaves every network joined on demand.
+	a.Sync(NetworkAttachConfig{Enabled: false, Container: "gw"})
+	if err := a.Ensure(ctx, &ContainerConfig{Name: "app"}); err != nil {
+		t.Fatal(err)
+	}
+	a.detachUnused(ctx, nil)
+	if got := f.recorded(); len(got) != 2 || got[1] != "disconnect metrics" {
+		t.Fatalf("calls = %v, want [connect metrics, disconnect metrics]", got)
+	}
+}
+
+func TestNetworkAttacher_ConcurrentEnsureJoinsOnce(t *testing.T) {
+	a, f := newTestAttacher(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.Ensure(ctx, &ContainerConfig{Name: "app"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := f.recorded(); len(got) != 1 || got[0] != "connect backend" {
+		t.Fatalf("calls = %v, want [connect backend]", got)
+	}
+}
@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// notifyTimeout bounds a single notification delivery.
+const notifyTimeout = 10 * time.Second
+
+// Notifier delivers a single event to an external service.
+type Notifier interface {
+	Notify(ctx context.Context, ev Event) error
+}
+
+// filteredNotifier pairs a Notifier with the event types it wants.
+type filteredNotifier struct {
+	kind     string
+	notifier Notifier
+	events   map[EventType]bool // empty means "all events"
+}
+
+func (f *filteredNotifier) wants(t EventType) bool {
+	return len(f.events) == 0 || f.events[t]
+}
+
+// NotificationManager forwards container events to the configured notifiers.
+// Call Sync on startup and on every config hot-reload.
+type NotificationManager struct {
+	client *http.Client
+
+	mu        sync.RWMutex
+	notifiers []*filteredNotifier
+}
+
+// NewNotificationManager creates a NotificationManager without notifiers.
+func NewNotificationManager() *NotificationManager {
+	return &NotificationManager{client: &http.Client{Timeout: notifyTimeout}}
+}
+
+// Sync replaces the active notifiers with the ones described by cfgs.
+// Entries are assumed to be validated already.
+func (nm *NotificationManager) Sync(cfgs []NotificationConfig) {
+	notifiers := make([]*filteredNotifier, 0, len(cfgs))
+	for _, c := range cfgs {
+		n, err := newNotifier(c, nm.client)
+		if err != nil {
+			slog.Error("notifications: skipping notifier", "type", c.Type, "error", err)
+			continue
+		}
+		fn := &filteredNotifier{kind: c.Type, notifier: n, events: make(map[EventType]bool, len(c.Events))}
+		for _, e := range c.Events {
+			fn.events[EventType(e)] = true
+		}
+		notifiers = append(notifiers, fn)
+	}
+	nm.mu.Lock()
+	nm.notifiers = notifiers
+	nm.mu.Unlock()
+}
+
+// Handle is an EventBus subscriber: it dispatches the event asynchronously
+// to every notifier whose filter matches.
+func (nm *NotificationManager) Handle(ev Event) {
+	nm.mu.RLock()
+	notifiers := nm.notifiers
+	nm.mu.RUnlock()
+
+	for _, fn := range notifiers {
+		if !fn.wants(ev.Type) {
+			continue
+		}
+		go func(fn *filteredNotifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := fn.notifier.Notify(ctx, ev); err != nil {
+				slog.Warn("notifications: delivery failed",
+					"type", fn.kind, "event", ev.Type, "container", ev.Container, "error", err)
+			}
+		}(fn)
+	}
+}
+
+// newNotifier builds the Notifier for a NotificationConfig.
+func newNotifier(c NotificationConfig, client *http.Client) (Notifier, error) {
+	switch c.Type {
+	case "slack":
+		return &slackNotifier{url: c.URL, client: client}, nil
+	case "ntfy":
+		return &ntfyNotifier{url: strings.TrimRight(c.URL, "/") + "/" + c.Topic, token: c.Token, client: client}, nil
+	case "gotify":
+		return &gotifyNotifier{url: strings.TrimRight(c.URL, "/") + "/message", token: c.Token, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", c.Type)
+	}
+}
+
+// eventTitle returns a short human-readable title for an event.
+func eventTitle(ev Event) string {
+	switch ev.Type {
+	case EventStartSuccess:
+		return ev.Container + " is awake"
+	case EventStartFailure:
+		return ev.Container + " failed to start"
+	case EventIdleStop:
+		return ev.Container + " went to sleep"
+	case EventCrashLoop:
+		return ev.Container + " is crash-looping"
+	case EventDegraded:
+		return ev.Container + " is degraded"
+	case EventCircuitOpen:
+		return ev.Container + " circuit opened"
+	case EventSelfHealRestart:
+		return ev.Container + " is being restarted"
+	case EventUpdateAvailable:
+		return ev.Container + " has an image update"
+	case EventDryRun:
+		return ev.Container + ": dry-run decision"
+	default:
+		return ev.Container + ": " + string(ev.Type)
+	}
+}
+
+// eventIsFailure reports whether an event signals a problem (used for priority).
+func eventIsFailure(t EventType) bool {
+	switch t {
+	case EventStartFailure, EventCrashLoop, EventDegraded, EventCircuitOpen, EventSelfHealRestart:
+		return true
+	}
+	return false
+}
+
+// postNotification sends a prepared request and checks for a 2xx response.
+func postNotification(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ─── Slack ────────────────────────────────────────────────────────────────────
+
+// slackNotifier posts to a Slack incoming webhook.
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *slackNotifier) Notify(ctx context.Context, ev Event) error {
+	body, _ := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", eventTitle(ev), ev.Message),
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return postNotification(n.client, req)
+}
+
+// ─── ntfy ─────────────────────────────────────────────────────────────────────
+
+// ntfyNotifier publishes to an ntfy topic.
+type ntfyNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (n *ntfyNotifier) Notify(ctx context.Context, ev Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, strings.NewReader(ev.Message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", eventTitle(ev))
+	req.Header.Set("Tags", string(ev.Type))
+	if eventIsFailure(ev.Type) {
+		req.Header.Set("Priority", "high")
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	return postNotification(n.client, req)
+}
+
+// ─── Gotify ───────────────────────────────────────────────────────────────────
+
+// gotifyNotifier sends messages to a Gotify server using an application token.
+type gotifyNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (n *gotifyNotifier) Notify(ctx context.Context, ev Event) error {
+	priority := 2
+	if eventIsFailure(ev.Type) {
+		priority = 8
+	}
+	body, _ := json.Marshal(map[string]any{
+		"title":    eventTitle(ev),
+		"message":  ev.Message,
+		"priority": priority,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", n.token)
+	return postNotification(n.client, req)
+}
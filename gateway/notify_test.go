@@ -0,0 +1,187 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ─── Notifiers ────────────────────────────────────────────────────────────────
+
+// captureServer records the last request it received.
+type captureServer struct {
+	mu      sync.Mutex
+	path    string
+	headers http.Header
+	body    string
+	hits    int
+}
+
+func (c *captureServer) handler(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	c.path = r.URL.Path
+	c.headers = r.Header.Clone()
+	c.body = string(b)
+	c.hits++
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestNotifiers(t *testing.T) {
+	ev := Event{Type: EventStartFailure, Container: "my-app", Message: "container crashed on boot"}
+
+	t.Run("slack posts text payload", func(t *testing.T) {
+		cs := &captureServer{}
+		srv := httptest.NewServer(http.HandlerFunc(cs.handler))
+		defer srv.Close()
+
+		n, _ := newNotifier(NotificationConfig{Type: "slack", URL: srv.URL + "/hook"}, srv.Client())
+		if err := n.Notify(context.Background(), ev); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		var payload map[string]string
+		if err := json.Unmarshal([]byte(cs.body), &payload); err != nil {
+			t.Fatalf("invalid JSON payload: %v", err)
+		}
+		if !strings.Contains(payload["text"], "my-app failed to start") {
+			t.Errorf("text = %q, want title", payload["text"])
+		}
+	})
+
+	t.Run("ntfy publishes to topic with headers", func(t *testing.T) {
+		cs := &captureServer{}
+		srv := httptest.NewServer(http.HandlerFunc(cs.handler))
+		defer srv.Close()
+
+		n, _ := newNotifier(NotificationConfig{Type: "ntfy", URL: srv.URL + "/", Topic: "homelab", Token: "tk"}, srv.Client())
+		if err := n.Notify(context.Background(), ev); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		if cs.path != "/homelab" {
+			t.Errorf("path = %q, want /homelab", cs.path)
+		}
+		if cs.headers.Get("Priority") != "high" {
+			t.Errorf("Priority = %q, want high", cs.headers.Get("Priority"))
+		}
+		if cs.headers.Get("Authorization") != "Bearer tk" {
+			t.Errorf("Authorization = %q", cs.headers.Get("Authorization"))
+		}
+		if cs.body != ev.Message {
+			t.Errorf("body = %q, want %q", cs.body, ev.Message)
+		}
+	})
+
+	t.Run("gotify sends app token and priority", func(t *testing.T) {
+		cs := &captureServer{}
+		srv := httptest.NewServer(http.HandlerFunc(cs.handler))
+		defer srv.Close()
+
+		n, _ := newNotifier(NotificationConfig{Type: "gotify", URL: srv.URL, Token: "app"}, srv.Client())
+		if err := n.Notify(context.Background(), ev); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		if cs.path != "/message" {
+			t.Errorf("path = %q, want /message", cs.path)
+		}
+		if cs.headers.Get("X-Gotify-Key") != "app" {
+			t.Errorf("X-Gotify-Key = %q", cs.headers.Get("X-Gotify-Key"))
+		}
+		var payload map[string]any
+		json.Unmarshal([]byte(cs.body), &payload)
+		if payload["priority"] != float64(8) {
+			t.Errorf("priority = %v, want 8", payload["priority"])
+		}
+	})
+
+	t.Run("non-2xx response is an error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer srv.Close()
+
+		n, _ := newNotifier(NotificationConfig{Type: "slack", URL: srv.URL}, srv.Client())
+		if err := n.Notify(context.Background(), ev); err == nil {
+			t.Error("Notify() expected error on 403")
+		}
+	})
+}
+
+// ─── NotificationManager ──────────────────────────────────────────────────────
+
+func TestNotificationManager_Filters(t *testing.T) {
+	cs := &captureServer{}
+	srv := httptest.NewServer(http.HandlerFunc(cs.handler))
+	defer srv.Close()
+
+	nm := NewNotificationManager()
+	nm.Sync([]NotificationConfig{
+		{Type: "slack", URL: srv.URL, Events: []string{"start_failure"}},
+	})
+
+	nm.Handle(Event{Type: EventStartSuccess, Container: "app"})
+	nm.Handle(Event{Type: EventStartFailure, Container: "app"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		cs.mu.Lock()
+		hits := cs.hits
+		cs.mu.Unlock()
+		if hits > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.hits != 1 {
+		t.Errorf("notifier hit %d times, want 1 (filtered)", cs.hits)
+	}
+}
+
+func TestNotificationConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     NotificationConfig
+		wantErr bool
+	}{
+		{"slack ok", NotificationConfig{Type: "slack", URL: "https://hooks.slack.com/x"}, false},
+		{"unknown type", NotificationConfig{Type: "email", URL: "x"}, true},
+		{"missing url", NotificationConfig{Type: "slack"}, true},
+		{"ntfy without topic", NotificationConfig{Type: "ntfy", URL: "https://ntfy.sh"}, true},
+		{"gotify without token", NotificationConfig{Type: "gotify", URL: "https://gotify.local"}, true},
+		{"unknown event", NotificationConfig{Type: "slack", URL: "x", Events: []string{"boom"}}, true},
+		{"known events", NotificationConfig{Type: "slack", URL: "x", Events: []string{"start_failure", "crash_loop"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// ─── EventBus ─────────────────────────────────────────────────────────────────
+
+func TestEventBus(t *testing.T) {
+	b := NewEventBus()
+	var got []Event
+	b.Subscribe(func(ev Event) { got = append(got, ev) })
+	b.Publish(Event{Type: EventIdleStop, Container: "app"})
+	if len(got) != 1 || got[0].Type != EventIdleStop {
+		t.Fatalf("got %v, want one idle_stop event", got)
+	}
+	if got[0].Time.IsZero() {
+		t.Error("Publish() should stamp the event time")
+	}
+}
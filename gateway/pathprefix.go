@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// validatePathPrefix checks a container path_prefix: an absolute path of at
+// least one segment, without a trailing slash or a query.
+func validatePathPrefix(prefix string) error {
+	switch {
+	case !strings.HasPrefix(prefix, "/"):
+		return errors.New(`path_prefix must start with "/"`)
+	case prefix == "/" || strings.HasSuffix(prefix, "/"):
+		return errors.New(`path_prefix must not end with "/"`)
+	case strings.ContainsAny(prefix, "?#"):
+		return errors.New("path_prefix must be a plain path")
+	}
+	return nil
+}
+
+// BuildPathPrefixIndex returns, by Host header value, the containers routed
+// on a path_prefix of that host, longest prefix first.
+func BuildPathPrefixIndex(cfg *GatewayConfig) map[string][]*ContainerConfig {
+	idx := make(map[string][]*ContainerConfig)
+	for i := range cfg.Containers {
+		c := &cfg.Containers[i]
+		if c.Host != "" && c.PathPrefix != "" {
+			idx[c.Host] = append(idx[c.Host], c)
+		}
+	}
+	for _, cs := range idx {
+		sort.SliceStable(cs, func(i, j int) bool { return len(cs[i].PathPrefix) > len(cs[j].PathPrefix) })
+	}
+	return idx
+}
+
+// pathPrefixMatch reports whether path is prefix or below it: "/app" matches
+// "/app" and "/app/x" but not "/application".
+func pathPrefixMatch(prefix, path string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// lookupPrefix returns the path_prefix container of host serving path.
+// configMu must be held.
+func (s *Server) lookupPrefix(host, path string) *ContainerConfig {
+	for _, c := range s.prefixIndex[host] {
+		if pathPrefixMatch(c.PathPrefix, path) {
+			return c
+		}
+	}
+	return nil
+}
+
+// lookupPrefixedByName returns the path_prefix container named by the
+// ?container= parameter when it is served on the host of r. The loading page
+// polls /_health and /_logs with it: those paths are outside the prefix, so
+// the host alone does not tell which container is waking. configMu must be
+// held.
+func (s *Server) lookupPrefixedByName(r *http.Request) *ContainerConfig {
+	name := r.URL.Query().Get("container")
+	if name == "" {
+		return nil
+	}
+	c := s.containerMap[name]
+	if c == nil || c.PathPrefix == "" {
+		return nil
+	}
+	host := r.Host
+	if idx := strings.LastIndex(host, ":"); idx != -1 {
+		host = host[:idx]
+	}
+	if host != c.Host && r.Host != c.Host {
+		return nil
+	}
+	return c
+}
+
+// hasPrefixRoutes reports whether host serves path_prefix containers.
+func (s *Server) hasPrefixRoutes(host string) bool {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return len(s.prefixIndex[host]) > 0
+}
+
+// stripPathPrefix returns r with the path_prefix of cfg removed from its
+// URL, as the container expects to be served at "/", and the prefix added to
+// X-Forwarded-Prefix so the app can build its links. r is returned unchanged
+// without a prefix or with keep_prefix.
+func stripPathPrefix(r *http.Request, cfg *ContainerConfig) *http.Request {
+	if cfg.PathPrefix == "" || cfg.KeepPrefix || !pathPrefixMatch(cfg.PathPrefix, r.URL.Path) {
+		return r
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	u := new(url.URL)
+	*u = *r.URL
+	u.Path = strings.TrimPrefix(u.Path, cfg.PathPrefix)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if u.RawPath != "" {
+		u.RawPath = strings.TrimPrefix(u.RawPath, cfg.PathPrefix)
+		if u.RawPath == "" {
+			u.RawPath = "/"
+		}
+	}
+	r2.URL = u
+	r2.Header = r.Header.Clone()
+	r2.Header.Set("X-Forwarded-Prefix", r.Header.Get("X-Forwarded-Prefix")+cfg.PathPrefix)
+	return r2
+}
@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newPathPrefixGateway(t *testing.T) *fakeGateway {
+	t.Helper()
+	rt := NewFakeRuntime()
+	var ctrs []ContainerConfig
+	for _, c := range []ContainerConfig{
+		{Name: "home", Host: "media.local"},
+		{Name: "jellyfin", Host: "media.local", PathPrefix: "/jellyfin"},
+		{Name: "jellyfin-api", Host: "media.local", PathPrefix: "/jellyfin/api"},
+		{Name: "grafana", Host: "media.local", PathPrefix: "/grafana", KeepPrefix: true},
+	} {
+		host, port := newBackend(t, c.Name, echoURI, echoHeaders("X-Forwarded-Prefix"))
+		rt.AddContainer(c.Name, FakeContainer{Status: "running", Host: host, Port: port})
+		c.TargetPort = port
+		ctrs = append(ctrs, c)
+	}
+	return newFakeGateway(t, rt, ctrs...)
+}
+
+func TestPathPrefix_Routing(t *testing.T) {
+	g := newPathPrefixGateway(t)
+
+	for _, tt := range []struct {
+		host, path string
+		body       string
+		prefix     string
+	}{
+		{"media.local", "/jellyfin", "jellyfin /", "/jellyfin"},
+		{"media.local:8080", "/jellyfin/web/index.html?x=1", "jellyfin /web/index.html?x=1", "/jellyfin"},
+		{"media.local", "/jellyfin/api/items", "jellyfin-api /items", "/jellyfin/api"},
+		{"media.local", "/jellyfinx", "home /jellyfinx", ""},
+		{"media.local", "/grafana/d/1", "grafana /grafana/d/1", ""},
+		{"media.local", "/", "home /", ""},
+	} {
+		w := g.get(tt.host, tt.path)
+		if w.Code != http.StatusOK || w.Body.String() != tt.body {
+			t.Errorf("%s%s: status %d, body %q; want %q", tt.host, tt.path, w.Code, w.Body.String(), tt.body)
+		}
+		if got := w.Header().Get("X-Echo-X-Forwarded-Prefix"); got != tt.prefix {
+			t.Errorf("%s%s: X-Forwarded-Prefix %q, want %q", tt.host, tt.path, got, tt.prefix)
+		}
+	}
+}
+
+func TestPathPrefix_NoFallback(t *testing.T) {
+	host, port := newBackend(t, "jellyfin", echoURI, echoHeaders("X-Forwarded-Prefix"))
+	rt := NewFakeRuntime()
+	rt.AddContainer("jellyfin", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "jellyfin", Host: "media.local", PathPrefix: "/jellyfin", TargetPort: port})
+
+	if w := g.get("media.local", "/other"); w.Code != http.StatusNotFound {
+		t.Errorf("path outside the prefix: status %d, want 404", w.Code)
+	}
+	if calls := rt.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want no start", calls)
+	}
+
+	// The loading page polls /_health with ?container=, outside the prefix.
+	w := g.get("media.local", "/jellyfin/")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<html") {
+		t.Fatalf("status %d, want the loading page", w.Code)
+	}
+	g.waitStarted(t, "jellyfin")
+	w = g.get("media.local", "/_health?container=jellyfin")
+	var health map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil || health["status"] != "running" {
+		t.Errorf("/_health: status %d, %v (%v), want running", w.Code, health, err)
+	}
+	if w := g.get("other.local", "/_health?container=jellyfin"); w.Code != http.StatusBadRequest {
+		t.Errorf("/_health from another host: status %d, want 400", w.Code)
+	}
+}
+
+func TestPathPrefix_Routes(t *testing.T) {
+	g := newPathPrefixGateway(t)
+	w := g.get("gw.local", "/_status/routes?host=media.local&path=/jellyfin/api/x")
+	var resp routesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Hosts) != 4 || resp.Hosts[0].PathPrefix != "" || resp.Hosts[1].PathPrefix != "/grafana" {
+		t.Errorf("hosts = %+v, want the host route then the prefixes in order", resp.Hosts)
+	}
+	if m := resp.Match; m == nil || m.Name != "jellyfin-api" {
+		t.Errorf("match = %+v, want jellyfin-api", m)
+	}
+}
+
+func TestStripPathPrefix(t *testing.T) {
+	cfg := &ContainerConfig{PathPrefix: "/app"}
+	r := httptest.NewRequest(http.MethodGet, "/app/a%2Fb?q=1", nil)
+	r.Header.Set("X-Forwarded-Prefix", "/outer")
+
+	out := stripPathPrefix(r, cfg)
+	if out.URL.Path != "/a/b" || out.URL.EscapedPath() != "/a%2Fb" || out.URL.RawQuery != "q=1" {
+		t.Errorf("URL = %q (escaped %q), want /a%%2Fb?q=1", out.URL.Path, out.URL.EscapedPath())
+	}
+	if got := out.Header.Get("X-Forwarded-Prefix"); got != "/outer/app" {
+		t.Errorf("X-Forwarded-Prefix = %q, want /outer/app", got)
+	}
+	if r.URL.Path != "/app/a/b" || r.Header.Get("X-Forwarded-Prefix") != "/outer" {
+		t.Error("original request modified")
+	}
+}
+
+func TestValidate_PathPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		ctrs   []ContainerConfig
+		groups []GroupConfig
+		want   string
+	}{
+		{[]ContainerConfig{{Name: "a", Host: "h", PathPrefix: "app"}}, nil, `must start with "/"`},
+		{[]ContainerConfig{{Name: "a", Host: "h", PathPrefix: "/app/"}}, nil, `must not end with "/"`},
+		{[]ContainerConfig{{Name: "a", Host: "h", PathPrefix: "/"}}, nil, `must not end with "/"`},
+		{[]ContainerConfig{{Name: "a", PathPrefix: "/app"}, {Name: "b", Host: "h", DependsOn: []string{"a"}}}, nil, "path_prefix requires host"},
+		{[]ContainerConfig{{Name: "a", Host: "h", PathPrefix: "/app"}, {Name: "b", Host: "h", PathPrefix: "/app"}}, nil, `duplicate host mapped: "h/app"`},
+		{[]ContainerConfig{{Name: "a", Host: "h", PathPrefix: "/app"}}, []GroupConfig{{Name: "g", Host: "h", Members: []GroupMember{{Name: "a"}}}}, "conflicts with an existing host"},
+	} {
+		cfg := &GatewayConfig{Containers: tt.ctrs, Groups: tt.groups}
+		applyDefaults(cfg)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: error = %v, want %q", tt.ctrs, err, tt.want)
+		}
+	}
+
+	cfg := &GatewayConfig{Containers: []ContainerConfig{
+		{Name: "a", Host: "h"},
+		{Name: "b", Host: "h", PathPrefix: "/b"},
+	}}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("host shared by a prefix: %v", err)
+	}
+	if cfg.Containers[1].RedirectPath != "/b/" {
+		t.Errorf("redirect_path = %q, want /b/", cfg.Containers[1].RedirectPath)
+	}
+}
@@ -0,0 +1,321 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// usageWeeks is how many past weeks the usage history covers.
+const usageWeeks = 4
+
+// prewarmTick is how often the prewarmer looks for upcoming busy hours.
+const prewarmTick = time.Minute
+
+// usageHistory remembers, per container, the hours that saw requests over the
+// last usageWeeks weeks. Hours are stored as Unix hours (seconds / 3600) so
+// the history does not depend on a timezone; busyWeeks maps them to hours of
+// the week in the location asked for.
+type usageHistory struct {
+	mu     sync.Mutex
+	hours  map[string]map[int64]struct{}
+	warmed map[string]int64 // container → Unix hour last pre-warmed
+	dirty  bool
+}
+
+func newUsageHistory() *usageHistory {
+	return &usageHistory{
+		hours:  make(map[string]map[int64]struct{}),
+		warmed: make(map[string]int64),
+	}
+}
+
+// record marks the hour of t as busy for each container.
+func (u *usageHistory) record(names []string, t time.Time) {
+	hour := t.Unix() / 3600
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, name := range names {
+		hours, ok := u.hours[name]
+		if !ok {
+			hours = make(map[int64]struct{})
+			u.hours[name] = hours
+		}
+		if _, seen := hours[hour]; !seen {
+			hours[hour] = struct{}{}
+			u.dirty = true
+		}
+	}
+}
+
+// busyWeeks returns in how many of the last usageWeeks weeks the container
+// saw requests in the hour starting at the same weekday and wall-clock time
+// as window. AddDate keeps the wall-clock time across DST changes.
+func (u *usageHistory) busyWeeks(name string, window time.Time) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	n := 0
+	for k := 1; k <= usageWeeks; k++ {
+		past := window.AddDate(0, 0, -7*k)
+		if _, ok := u.hours[name][past.Unix()/3600]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// claim marks the window as pre-warmed for the container, returning false if
+// it already was.
+func (u *usageHistory) claim(name string, window time.Time) bool {
+	hour := window.Unix() / 3600
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.warmed[name] == hour {
+		return false
+	}
+	u.warmed[name] = hour
+	return true
+}
+
+// busyHours returns the recorded busy Unix hours of a container, sorted.
+func (u *usageHistory) busyHours(name string) []int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.hours[name]) == 0 {
+		return nil
+	}
+	list := make([]int64, 0, len(u.hours[name]))
+	for h := range u.hours[name] {
+		list = append(list, h)
+	}
+	slices.Sort(list)
+	return list
+}
+
+// merge adds imported busy hours to a container's history.
+func (u *usageHistory) merge(name string, list []int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	hours, ok := u.hours[name]
+	if !ok {
+		hours = make(map[int64]struct{}, len(list))
+		u.hours[name] = hours
+	}
+	for _, h := range list {
+		if _, seen := hours[h]; !seen {
+			hours[h] = struct{}{}
+			u.dirty = true
+		}
+	}
+}
+
+// prune forgets the hours older than the history covers.
+func (u *usageHistory) prune(now time.Time) {
+	oldest := now.Add(-(usageWeeks*7*24+24)*time.Hour).Unix() / 3600
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for name, hours := range u.hours {
+		for h := range hours {
+			if h < oldest {
+				delete(hours, h)
+				u.dirty = true
+			}
+		}
+		if len(hours) == 0 {
+			delete(u.hours, name)
+		}
+	}
+}
+
+// usageFile is the on-disk format of the usage history.
+type usageFile struct {
+	Version    int                `json:"version"`
+	Containers map[string][]int64 `json:"containers"`
+}
+
+// load replaces the history with the contents of path. A missing file is not
+// an error: the history starts empty.
+func (u *usageHistory) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f usageFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.hours = make(map[string]map[int64]struct{}, len(f.Containers))
+	for name, list := range f.Containers {
+		hours := make(map[int64]struct{}, len(list))
+		for _, h := range list {
+			hours[h] = struct{}{}
+		}
+		u.hours[name] = hours
+	}
+	u.dirty = false
+	return nil
+}
+
+// save writes the history to path if it changed since the last save. The
+// file is replaced atomically so a crash never leaves it half written.
+func (u *usageHistory) save(path string) error {
+	u.mu.Lock()
+	if !u.dirty {
+		u.mu.Unlock()
+		return nil
+	}
+	f := usageFile{Version: 1, Containers: make(map[string][]int64, len(u.hours))}
+	for name, hours := range u.hours {
+		list := make([]int64, 0, len(hours))
+		for h := range hours {
+			list = append(list, h)
+		}
+		slices.Sort(list)
+		f.Containers[name] = list
+	}
+	u.dirty = false
+	u.mu.Unlock()
+
+	data, err := json.Marshal(f)
+	if err == nil {
+		err = writeFileAtomic(path, data)
+	}
+	if err != nil {
+		u.mu.Lock()
+		u.dirty = true
+		u.mu.Unlock()
+	}
+	return err
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// prewarmWindow returns the start of the hour, in loc, that begins within
+// lead of now, or the zero time when now+lead still falls in the current hour.
+func prewarmWindow(now time.Time, lead time.Duration, loc *time.Location) time.Time {
+	at := now.Add(lead).In(loc)
+	window := time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), 0, 0, 0, loc)
+	if !window.After(now) {
+		return time.Time{}
+	}
+	return window
+}
+
+// StartPrewarmer begins a background routine that starts containers with
+// prewarm enabled shortly before the hours they are usually busy, and saves
+// the usage history to gateway.prewarm.history_file. With gateway.ha only the
+// leader pre-warms.
+func (m *ContainerManager) StartPrewarmer(ctx context.Context, configProvider func() *GatewayConfig) {
+	if path := configProvider().Gateway.Prewarm.HistoryFile; path != "" {
+		if err := m.usage.load(path); err != nil {
+			slog.Warn("prewarm: cannot load usage history", "path", path, "error", err)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(prewarmTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				m.saveUsage(configProvider().Gateway.Prewarm.HistoryFile)
+				return
+			case <-ticker.C:
+				gcfg := configProvider()
+				now := time.Now()
+				m.usage.prune(now)
+				m.saveUsage(gcfg.Gateway.Prewarm.HistoryFile)
+				if m.shared.isLeader() {
+					m.checkPrewarm(ctx, gcfg, now)
+				}
+			}
+		}
+	}()
+}
+
+func (m *ContainerManager) saveUsage(path string) {
+	if path == "" {
+		return
+	}
+	if err := m.usage.save(path); err != nil {
+		slog.Warn("prewarm: cannot save usage history", "path", path, "error", err)
+	}
+}
+
+// checkPrewarm starts the containers whose next hour was busy in enough of
+// the past weeks. Each window is pre-warmed at most once.
+func (m *ContainerManager) checkPrewarm(ctx context.Context, gcfg *GatewayConfig, now time.Time) {
+	if m.ReadOnly() {
+		return
+	}
+	pcfg := gcfg.Gateway.Prewarm
+	loc, err := resolveLocation(gcfg.Gateway.ScheduleTimezone)
+	if err != nil {
+		loc = time.Local
+	}
+	for i := range gcfg.Containers {
+		cfg := gcfg.Containers[i]
+		if !cfg.Prewarm {
+			continue
+		}
+		ctrLoc := loc
+		if cfg.ScheduleTimezone != "" {
+			if l, err := resolveLocation(cfg.ScheduleTimezone); err == nil {
+				ctrLoc = l
+			}
+		}
+		window := prewarmWindow(now, pcfg.Lead, ctrLoc)
+		if window.IsZero() || m.usage.busyWeeks(cfg.Name, window) < pcfg.MinWeeks {
+			continue
+		}
+		if !m.usage.claim(cfg.Name, window) {
+			continue
+		}
+		if status, err := m.client.GetContainerStatus(ctx, cfg.Name); err != nil || status == "running" {
+			continue
+		}
+		if m.DryRun() {
+			m.simulate(dryRunStart, cfg.Name, "prewarm ahead of "+window.Format("Mon 15:04"), window)
+			continue
+		}
+		go m.prewarm(ctx, cfg, gcfg.Containers, window)
+	}
+}
+
+// prewarm starts a container and its dependencies ahead of a busy window.
+func (m *ContainerManager) prewarm(ctx context.Context, cfg ContainerConfig, allContainers []ContainerConfig, window time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.StartTimeout)
+	defer cancel()
+	slog.Info("prewarm: starting container ahead of busy hour", "container", cfg.Name, "window", window.Format("Mon 15:04"))
+	m.InitStartState(cfg.Name)
+	if err := m.Wake(ctx, &cfg, allContainers); err != nil {
+		slog.Error("prewarm: start failed", "container", cfg.Name, "error", err)
+	}
+}
@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+// ─── Usage history ────────────────────────────────────────────────────────────
+
+func TestUsageHistory_BusyWeeks(t *testing.T) {
+	rome := mustLocation(t, "Europe/Rome")
+	u := newUsageHistory()
+	// Monday 9:xx in two of the last four weeks, plus a Tuesday.
+	window := time.Date(2026, 6, 15, 9, 0, 0, 0, rome) // a Monday
+	u.record([]string{"app"}, window.AddDate(0, 0, -7).Add(10*time.Minute))
+	u.record([]string{"app"}, window.AddDate(0, 0, -7).Add(50*time.Minute)) // same hour
+	u.record([]string{"app"}, window.AddDate(0, 0, -21).Add(30*time.Minute))
+	u.record([]string{"app"}, window.AddDate(0, 0, -6))
+
+	tests := []struct {
+		name   string
+		ctr    string
+		window time.Time
+		want   int
+	}{
+		{"busy hour", "app", window, 2},
+		{"next hour", "app", window.Add(time.Hour), 0},
+		{"tuesday", "app", window.AddDate(0, 0, 1), 1},
+		{"other container", "db", window, 0},
+		{"beyond history", "app", window.AddDate(0, 0, 35), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := u.busyWeeks(tt.ctr, tt.window); got != tt.want {
+				t.Errorf("busyWeeks = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageHistory_BusyWeeksAcrossDST(t *testing.T) {
+	rome := mustLocation(t, "Europe/Rome")
+	u := newUsageHistory()
+	// Clocks moved forward on Sunday 29 March 2026: 9:00 is still 9:00.
+	u.record([]string{"app"}, time.Date(2026, 3, 23, 9, 15, 0, 0, rome))
+	if got := u.busyWeeks("app", time.Date(2026, 3, 30, 9, 0, 0, 0, rome)); got != 1 {
+		t.Errorf("busyWeeks = %d, want 1 for the same wall-clock hour", got)
+	}
+}
+
+func TestUsageHistory_Claim(t *testing.T) {
+	u := newUsageHistory()
+	window := time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC)
+	if !u.claim("app", window) {
+		t.Fatal("first claim = false, want true")
+	}
+	if u.claim("app", window) {
+		t.Error("window pre-warmed twice")
+	}
+	if !u.claim("app", window.Add(time.Hour)) {
+		t.Error("claim of the next window = false, want true")
+	}
+}
+
+func TestUsageHistory_Prune(t *testing.T) {
+	u := newUsageHistory()
+	now := time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC)
+	u.record([]string{"app"}, now.AddDate(0, 0, -7))
+	u.record([]string{"old"}, now.AddDate(0, 0, -40))
+	u.prune(now)
+	if len(u.hours["app"]) != 1 {
+		t.Errorf("app hours = %v, want the recent one kept", u.hours["app"])
+	}
+	if _, ok := u.hours["old"]; ok {
+		t.Error("container without recent activity not forgotten")
+	}
+}
+
+func TestUsageHistory_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	empty := newUsageHistory()
+	if err := empty.load(path); err != nil {
+		t.Fatalf("load of a missing file: %v", err)
+	}
+
+	window := time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC)
+	u := newUsageHistory()
+	u.record([]string{"app", "db"}, window.AddDate(0, 0, -7))
+	if err := u.save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded := newUsageHistory()
+	if err := loaded.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for _, name := range []string{"app", "db"} {
+		if got := loaded.busyWeeks(name, window); got != 1 {
+			t.Errorf("%s busyWeeks after reload = %d, want 1", name, got)
+		}
+	}
+
+	// Nothing changed: the file is not rewritten.
+	os.Remove(path)
+	if err := loaded.save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("unchanged history was saved again")
+	}
+
+	os.WriteFile(path, []byte("not json"), 0o644)
+	if err := newUsageHistory().load(path); err == nil {
+		t.Error("load of a corrupt file succeeded")
+	}
+}
+
+// ─── Pre-warming ──────────────────────────────────────────────────────────────
+
+func TestPrewarmWindow(t *testing.T) {
+	now := time.Date(2026, 6, 15, 8, 56, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		lead time.Duration
+		want time.Time
+	}{
+		{"next hour within lead", 5 * time.Minute, time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC)},
+		{"next hour beyond lead", 3 * time.Minute, time.Time{}},
+		{"long lead", 90 * time.Minute, time.Date(2026, 6, 15, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prewarmWindow(now, tt.lead, time.UTC); !got.Equal(tt.want) {
+				t.Errorf("prewarmWindow = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPrewarm(t *testing.T) {
+	var starts atomic.Int32
+	started := make(chan struct{}, 1)
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/start") {
+			starts.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"boom"}`))
+			started <- struct{}{}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"State":{"Status":"exited"},"Config":{"Image":"x"}}`))
+	}))
+	defer daemon.Close()
+
+	m := NewContainerManager(newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String()))
+	now := time.Date(2026, 6, 15, 8, 57, 0, 0, time.UTC)
+	window := time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC)
+	m.usage.record([]string{"app", "off"}, window.AddDate(0, 0, -7))
+	m.usage.record([]string{"app", "off"}, window.AddDate(0, 0, -14))
+
+	gcfg := &GatewayConfig{
+		Gateway: GlobalConfig{ScheduleTimezone: "UTC", Prewarm: PrewarmConfig{Lead: 5 * time.Minute, MinWeeks: 2}},
+		Containers: []ContainerConfig{
+			{Name: "app", Prewarm: true, StartTimeout: 5 * time.Second},
+			{Name: "off", StartTimeout: 5 * time.Second},
+		},
+	}
+	m.checkPrewarm(context.Background(), gcfg, now)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("busy container was not pre-warmed")
+	}
+
+	// The same window is not pre-warmed again, even after a failed start.
+	m.checkPrewarm(context.Background(), gcfg, now.Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if n := starts.Load(); n != 1 {
+		t.Errorf("start requests = %d, want 1 (only app, once)", n)
+	}
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pushTick is the granularity of the push monitor loop. Per-container
+// push_interval values are rounded up to a multiple of it.
+const pushTick = 5 * time.Second
+
+// pushMonitor tracks when each container's push URL was last pinged.
+type pushMonitor struct {
+	client *http.Client
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newPushMonitor() *pushMonitor {
+	return &pushMonitor{
+		client: &http.Client{Timeout: 10 * time.Second},
+		last:   make(map[string]time.Time),
+	}
+}
+
+// due reports whether a container's push interval elapsed and marks it pushed.
+func (p *pushMonitor) due(name string, interval time.Duration, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now.Sub(p.last[name]) < interval {
+		return false
+	}
+	p.last[name] = now
+	return true
+}
+
+// pushStatus is the outcome reported to an uptime monitor.
+type pushStatus struct {
+	Up  bool
+	Msg string
+}
+
+// pushRequestURL builds the URL pinged for a status. Uptime Kuma push URLs
+// (path containing "/api/push/") get status and msg query parameters; every
+// other URL is treated as Healthchecks.io-compatible, where failures are
+// reported on the "/fail" sub-path.
+func pushRequestURL(raw string, st pushStatus) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(u.Path, "/api/push/") {
+		q := u.Query()
+		if st.Up {
+			q.Set("status", "up")
+		} else {
+			q.Set("status", "down")
+		}
+		q.Set("msg", st.Msg)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	if !st.Up {
+		u.Path = strings.TrimRight(u.Path, "/") + "/fail"
+	}
+	return u.String(), nil
+}
+
+// send pings the push URL. The status message is sent as the request body,
+// which Healthchecks.io shows in the ping log.
+func (p *pushMonitor) send(ctx context.Context, rawURL string, st pushStatus) error {
+	target, err := pushRequestURL(rawURL, st)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(st.Msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	return postNotification(p.client, req)
+}
+
+// pushStatusFor derives the status reported for a container. ok is false
+// while a start is in progress, when nothing should be reported.
+func (m *ContainerManager) pushStatusFor(ctx context.Context, name string) (st pushStatus, ok bool) {
+	startStatus, errMsg := m.GetStartState(name)
+	if startStatus == string(statusStarting) {
+		return pushStatus{}, false
+	}
+	if looping, until, count := m.CrashLoopState(name); looping {
+		return pushStatus{Msg: crashLoopMessage(count, until)}, true
+	}
+
+	dockerStatus, err := m.client.GetContainerStatus(ctx, name)
+	if err != nil {
+		return pushStatus{Msg: fmt.Sprintf("docker inspect failed: %v", err)}, true
+	}
+	if dockerStatus == "running" {
+		if m.IsDegraded(name) {
+			return pushStatus{Msg: "degraded: repeated proxy failures"}, true
+		}
+		if m.breaker.State(name) == circuitOpen {
+			return pushStatus{Msg: "circuit breaker open"}, true
+		}
+		return pushStatus{Up: true, Msg: "running"}, true
+	}
+	if startStatus == string(statusFailed) {
+		return pushStatus{Msg: errMsg}, true
+	}
+	return pushStatus{Up: true, Msg: "asleep"}, true
+}
+
+// pushNow reports the current status of a container to its push URL.
+func (m *ContainerManager) pushNow(ctx context.Context, cfg *ContainerConfig) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	st, ok := m.pushStatusFor(ctx, cfg.Name)
+	if !ok {
+		return
+	}
+	if err := m.push.send(ctx, cfg.PushURL, st); err != nil {
+		slog.Warn("push monitor: ping failed", "container", cfg.Name, "error", err)
+	}
+}
+
+// StartPushMonitor begins a background routine that pings the push_url of
+// every container that has one: right after a start attempt finishes and then
+// every push_interval.
+func (m *ContainerManager) StartPushMonitor(ctx context.Context, configProvider func() []ContainerConfig) {
+	m.events.Subscribe(func(ev Event) {
+		if ev.Type != EventStartSuccess && ev.Type != EventStartFailure && ev.Type != EventCrashLoop {
+			return
+		}
+		for _, cfg := range configProvider() {
+			if cfg.Name == ev.Container && cfg.PushURL != "" {
+				m.push.due(cfg.Name, 0, time.Now()) // restart the interval
+				go m.pushNow(ctx, &cfg)
+				return
+			}
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(pushTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				now := time.Now()
+				for _, cfg := range configProvider() {
+					if cfg.PushURL == "" || !m.push.due(cfg.Name, cfg.PushInterval, now) {
+						continue
+					}
+					m.pushNow(ctx, &cfg)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// ─── Push URLs ────────────────────────────────────────────────────────────────
+
+func TestPushRequestURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		st   pushStatus
+		want string
+	}{
+		{
+			name: "healthchecks up",
+			raw:  "https://hc-ping.com/1234",
+			st:   pushStatus{Up: true, Msg: "running"},
+			want: "https://hc-ping.com/1234",
+		},
+		{
+			name: "healthchecks down appends /fail",
+			raw:  "https://hc-ping.com/1234/",
+			st:   pushStatus{Msg: "crashed"},
+			want: "https://hc-ping.com/1234/fail",
+		},
+		{
+			name: "uptime kuma up",
+			raw:  "http://kuma:3001/api/push/abc",
+			st:   pushStatus{Up: true, Msg: "asleep"},
+			want: "http://kuma:3001/api/push/abc?msg=asleep&status=up",
+		},
+		{
+			name: "uptime kuma down replaces existing params",
+			raw:  "http://kuma:3001/api/push/abc?status=up&msg=OK&ping=",
+			st:   pushStatus{Msg: "degraded"},
+			want: "http://kuma:3001/api/push/abc?msg=degraded&ping=&status=down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pushRequestURL(tt.raw, tt.st)
+			if err != nil {
+				t.Fatalf("pushRequestURL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("pushRequestURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// ─── Scheduling ───────────────────────────────────────────────────────────────
+
+func TestPushMonitor_Due(t *testing.T) {
+	p := newPushMonitor()
+	now := time.Now()
+
+	if !p.due("app", time.Minute, now) {
+		t.Fatal("first check should be due")
+	}
+	if p.due("app", time.Minute, now.Add(30*time.Second)) {
+		t.Error("check within interval should not be due")
+	}
+	if !p.due("app", time.Minute, now.Add(time.Minute)) {
+		t.Error("check after interval should be due")
+	}
+	if !p.due("other", time.Minute, now) {
+		t.Error("containers are tracked independently")
+	}
+}
+
+// ─── Delivery ─────────────────────────────────────────────────────────────────
+
+func TestPushMonitor_Send(t *testing.T) {
+	cs := &captureServer{}
+	srv := httptest.NewServer(http.HandlerFunc(cs.handler))
+	defer srv.Close()
+
+	p := newPushMonitor()
+	if err := p.send(context.Background(), srv.URL+"/ping/uuid", pushStatus{Msg: "failed to start"}); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if cs.path != "/ping/uuid/fail" {
+		t.Errorf("path = %q, want /ping/uuid/fail", cs.path)
+	}
+	if cs.body != "failed to start" {
+		t.Errorf("body = %q, want status message", cs.body)
+	}
+}
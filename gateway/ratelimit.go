@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter keeps one token bucket per endpoint and client IP. Each
+// endpoint has its own policy, so the loading page polling /_health and
+// /_logs at the same time never starves either of them.
+type rateLimiter struct {
+	mu       sync.Mutex
+	policies map[string]RateLimitPolicy
+	buckets  map[bucketKey]*tokenBucket
+	now      func() time.Time
+	// shared, when HA is enabled, counts requests across replicas; the
+	// local buckets are the fallback while the store is unreachable.
+	shared *SharedState
+}
+
+type bucketKey struct {
+	endpoint string
+	ip       string
+}
+
+// tokenBucket holds the tokens left at the time of the last request.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limits RateLimitConfig) *rateLimiter {
+	rl := &rateLimiter{
+		buckets: make(map[bucketKey]*tokenBucket),
+		now:     time.Now,
+	}
+	rl.Sync(limits)
+	return rl
+}
+
+// Sync applies new limits. Existing buckets keep their tokens, capped at the
+// new burst.
+func (rl *rateLimiter) Sync(limits RateLimitConfig) {
+	limits.setDefaults()
+	policies := make(map[string]RateLimitPolicy)
+	for name, p := range limits.policies() {
+		policies[name] = *p
+	}
+	rl.setPolicies(policies)
+}
+
+// setPolicies replaces the policies keyed by endpoint. Buckets of endpoints
+// that are gone are evicted by the next cleanup.
+func (rl *rateLimiter) setPolicies(policies map[string]RateLimitPolicy) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.policies = policies
+	for k, b := range rl.buckets {
+		if burst := float64(policies[k.endpoint].Burst); b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+}
+
+// Allow reports whether ip may call endpoint now, taking a token if so.
+// Endpoints without a policy are never limited.
+func (rl *rateLimiter) Allow(endpoint, ip string) bool {
+	rl.mu.Lock()
+	p, ok := rl.policies[endpoint]
+	rl.mu.Unlock()
+	if !ok {
+		return true
+	}
+	if allowed, ok := rl.shared.allowRate(context.Background(), endpoint, ip, p); ok {
+		return allowed
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+	key := bucketKey{endpoint, ip}
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(p.Burst), last: now}
+		rl.buckets[key] = b
+	} else {
+		b.tokens = math.Min(float64(p.Burst), b.tokens+now.Sub(b.last).Seconds()*p.Rate)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// retryAfter returns how long a client should wait for the next token of
+// endpoint, in whole seconds (at least 1).
+func (rl *rateLimiter) retryAfter(endpoint string) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	p := rl.policies[endpoint]
+	if p.Rate <= 0 {
+		return 1
+	}
+	return int(math.Max(1, math.Ceil(1/p.Rate)))
+}
+
+// startCleanup periodically evicts stale entries from the rate limiter.
+func (rl *rateLimiter) startCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rl.evictStale()
+			}
+		}
+	}()
+}
+
+// evictStale removes buckets that have refilled completely: a new bucket
+// would start with the same tokens.
+func (rl *rateLimiter) evictStale() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+	for k, b := range rl.buckets {
+		p := rl.policies[k.endpoint]
+		if p.Rate <= 0 || b.tokens+now.Sub(b.last).Seconds()*p.Rate >= float64(p.Burst) {
+			delete(rl.buckets, k)
+		}
+	}
+}
+
+// allowRate applies the rate limit of endpoint to the client of r. When the
+// client is over the limit it answers 429 with Retry-After and returns false.
+func (s *Server) allowRate(w http.ResponseWriter, r *http.Request, endpoint string) bool {
+	if s.rateLimiter.Allow(endpoint, s.clientIP(r)) {
+		return true
+	}
+	RecordRateLimited(endpoint)
+	s.bans.Strike(s.clientIP(r), strikeRateLimited)
+	w.Header().Set("Retry-After", strconv.Itoa(s.rateLimiter.retryAfter(endpoint)))
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	return false
+}
@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrReadOnly is returned instead of starting or stopping a container while
+// gateway.read_only is set.
+var ErrReadOnly = errors.New("the gateway is read-only (gateway.read_only) and does not start or stop containers")
+
+// SetReadOnly applies gateway.read_only: the gateway keeps routing to running
+// containers but never starts, stops, restarts or kills one. Hot-reloaded,
+// so lifecycle control can be handed over without a restart.
+func (m *ContainerManager) SetReadOnly(readOnly bool) {
+	m.readOnly.Store(readOnly)
+}
+
+// ReadOnly reports whether gateway.read_only is set.
+func (m *ContainerManager) ReadOnly() bool {
+	return m.readOnly.Load()
+}
+
+// readOnlyMessage explains why a stopped container is not started.
+func readOnlyMessage(name, status string) string {
+	if status == "" {
+		status = "not running"
+	}
+	return fmt.Sprintf("%s is %s. The gateway is in read-only mode and does not start containers.", name, status)
+}
+
+// refuseReadOnly answers an admin action with 403 while the gateway is
+// read-only, and reports whether it did.
+func (s *Server) refuseReadOnly(w http.ResponseWriter) bool {
+	if !s.manager.ReadOnly() {
+		return false
+	}
+	http.Error(w, ErrReadOnly.Error(), http.StatusForbidden)
+	return true
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStateDaemon fakes a Docker daemon where every container is in state,
+// counting the container-changing requests it receives in changes.
+func newStateDaemon(t *testing.T, state string, changes *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			changes.Add(1)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Name":"/app","State":{"Status":"` + state + `"},"Config":{"Image":"app:latest"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReadOnly_ManagerNeverChangesContainers(t *testing.T) {
+	var changes atomic.Int32
+	m := NewContainerManager(newTestDockerClient(t, newStateDaemon(t, "exited", &changes).URL))
+	m.SetReadOnly(true)
+	ctx := context.Background()
+
+	cfg := &ContainerConfig{Name: "app", StartTimeout: time.Second, IdleTimeout: time.Nanosecond}
+	if err := m.EnsureRunning(ctx, cfg); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("EnsureRunning error = %v, want ErrReadOnly", err)
+	}
+	if err := m.Sleep(ctx, "app", 0); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Sleep error = %v, want ErrReadOnly", err)
+	}
+	if err := m.Kill(ctx, "app"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Kill error = %v, want ErrReadOnly", err)
+	}
+	if err := m.restartContainer(cfg, "test"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("restartContainer error = %v, want ErrReadOnly", err)
+	}
+	m.RecordActivity("app")
+	m.checkIdle(ctx, &GatewayConfig{Containers: []ContainerConfig{*cfg}})
+
+	if n := changes.Load(); n != 0 {
+		t.Errorf("%d container-changing requests reached the daemon, want 0", n)
+	}
+
+	m.SetReadOnly(false)
+	if m.ReadOnly() {
+		t.Error("read-only mode should be switched off")
+	}
+}
+
+func TestReadOnly_StatusActionsForbidden(t *testing.T) {
+	var changes atomic.Int32
+	m := NewContainerManager(newTestDockerClient(t, newStateDaemon(t, "exited", &changes).URL))
+	m.SetReadOnly(true)
+	s := &Server{
+		cfg:         &GatewayConfig{Containers: []ContainerConfig{{Name: "app"}}},
+		manager:     m,
+		rateLimiter: newRateLimiter(RateLimitConfig{}),
+	}
+
+	handlers := map[string]http.HandlerFunc{
+		"/_status/wake":  s.handleStatusWake,
+		"/_status/sleep": s.handleStatusSleep,
+		"/_status/kill":  s.handleStatusKill,
+	}
+	for path, h := range handlers {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodPost, path+"?container=app", nil))
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "read-only") {
+			t.Errorf("%s: status = %d, body %q", path, w.Code, w.Body)
+		}
+	}
+	if n := changes.Load(); n != 0 {
+		t.Errorf("%d container-changing requests reached the daemon, want 0", n)
+	}
+}
+
+func TestReadOnlyMessage(t *testing.T) {
+	if got := readOnlyMessage("app", "exited"); !strings.HasPrefix(got, "app is exited.") {
+		t.Errorf("message = %q", got)
+	}
+	if got := readOnlyMessage("app", ""); !strings.HasPrefix(got, "app is not running.") {
+		t.Errorf("message = %q", got)
+	}
+}
@@ -0,0 +1,259 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds every Redis command, so an unreachable store never
+// stalls a request for long.
+const redisTimeout = 2 * time.Second
+
+// redisError is an error reply ("-ERR ...") sent by the server. The
+// connection is still usable after one.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// parseRedisURL splits a "redis://[:password@]host[:port][/db]" URL into a
+// dialable address, whether TLS is used (rediss://), the password and the
+// database number. A bare "host:port" is treated as redis://.
+func parseRedisURL(raw string) (addr string, useTLS bool, password string, db int, err error) {
+	if !strings.Contains(raw, "://") {
+		raw = "redis://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false, "", 0, fmt.Errorf("invalid redis URL %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		useTLS = true
+	default:
+		return "", false, "", 0, fmt.Errorf("invalid redis URL %q: unsupported scheme %q", raw, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", false, "", 0, fmt.Errorf("invalid redis URL %q: missing host", raw)
+	}
+	port := "6379"
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	if u.User != nil {
+		password, _ = u.User.Password()
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if db, err = strconv.Atoi(path); err != nil || db < 0 {
+			return "", false, "", 0, fmt.Errorf("invalid redis URL %q: bad database %q", raw, path)
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, password, db, nil
+}
+
+// redisMaxIdle is how many idle connections a redisClient keeps. More
+// concurrent commands dial extra connections, closed once they complete.
+const redisMaxIdle = 8
+
+// redisClient is a minimal RESP2 client with a small connection pool, enough
+// for the shared HA state. Concurrent commands use separate connections, so
+// one slow reply never queues the others; connections are dialled lazily and
+// dropped after any I/O error, so the next command reconnects.
+type redisClient struct {
+	rawURL   string
+	password string // overrides the URL password when set
+
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool // idle connections are no longer kept
+}
+
+// redisConn is one connection of a redisClient.
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func newRedisClient(rawURL, password string) *redisClient {
+	return &redisClient{rawURL: rawURL, password: password}
+}
+
+// Do sends one command and returns its reply: string, int64, nil (null
+// bulk string), []any, or a redisError.
+func (c *redisClient) Do(ctx context.Context, args ...string) (any, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.roundTrip(ctx, args)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		conn.Close()
+		return reply, err
+	}
+	c.put(conn)
+	return reply, err
+}
+
+// get takes an idle connection, or dials a new one.
+func (c *redisClient) get(ctx context.Context) (*redisConn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+	return c.connect(ctx)
+}
+
+// put returns a healthy connection to the pool.
+func (c *redisClient) put(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= redisMaxIdle {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// Close drops the idle connections. Commands still in flight complete, but
+// their connections are closed instead of kept.
+func (c *redisClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, conn := range c.idle {
+		conn.Close()
+	}
+	c.idle = nil
+}
+
+// connect dials the server and authenticates.
+func (c *redisClient) connect(ctx context.Context) (*redisConn, error) {
+	addr, useTLS, password, db, err := parseRedisURL(c.rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if c.password != "" {
+		password = c.password
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	var conn net.Conn
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		d := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{Conn: conn, r: bufio.NewReader(conn)}
+
+	var setup [][]string
+	if password != "" {
+		setup = append(setup, []string{"AUTH", password})
+	}
+	if db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(db)})
+	}
+	for _, cmd := range setup {
+		if _, err := rc.roundTrip(ctx, cmd); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis %s: %w", strings.ToLower(cmd[0]), err)
+		}
+	}
+	return rc, nil
+}
+
+func (c *redisConn) roundTrip(ctx context.Context, args []string) (any, error) {
+	deadline := time.Now().Add(redisTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.SetDeadline(deadline)
+	if _, err := c.Write(appendRedisCommand(nil, args)); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.r)
+}
+
+// appendRedisCommand encodes args as a RESP array of bulk strings.
+func appendRedisCommand(b []byte, args []string) []byte {
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, '\r', '\n')
+	for _, a := range args {
+		b = append(b, '$')
+		b = strconv.AppendInt(b, int64(len(a)), 10)
+		b = append(b, '\r', '\n')
+		b = append(b, a...)
+		b = append(b, '\r', '\n')
+	}
+	return b
+}
+
+// readRedisReply reads one RESP2 reply.
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRedisReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
@@ -0,0 +1,355 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ─── URL parsing ──────────────────────────────────────────────────────────────
+
+func TestParseRedisURL(t *testing.T) {
+	tests := []struct {
+		raw      string
+		wantAddr string
+		wantTLS  bool
+		wantPass string
+		wantDB   int
+		wantErr  bool
+	}{
+		{"redis://redis:6379", "redis:6379", false, "", 0, false},
+		{"redis://redis", "redis:6379", false, "", 0, false},
+		{"redis:6380", "redis:6380", false, "", 0, false},
+		{"redis://:s3cret@redis/2", "redis:6379", false, "s3cret", 2, false},
+		{"rediss://cache.example.com:6380", "cache.example.com:6380", true, "", 0, false},
+		{"redis://[::1]:6379", "[::1]:6379", false, "", 0, false},
+		{"http://redis:6379", "", false, "", 0, true},
+		{"redis://:6379", "", false, "", 0, true},
+		{"redis://redis/db", "", false, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			addr, useTLS, pass, db, err := parseRedisURL(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRedisURL(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if addr != tt.wantAddr || useTLS != tt.wantTLS || pass != tt.wantPass || db != tt.wantDB {
+				t.Errorf("parseRedisURL(%q) = (%q, %v, %q, %d), want (%q, %v, %q, %d)",
+					tt.raw, addr, useTLS, pass, db, tt.wantAddr, tt.wantTLS, tt.wantPass, tt.wantDB)
+			}
+		})
+	}
+}
+
+// ─── RESP encoding ────────────────────────────────────────────────────────────
+
+func TestAppendRedisCommand(t *testing.T) {
+	got := string(appendRedisCommand(nil, []string{"SET", "k", ""}))
+	want := "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$0\r\n\r\n"
+	if got != want {
+		t.Errorf("appendRedisCommand = %q, want %q", got, want)
+	}
+}
+
+func TestReadRedisReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    any
+		wantErr bool
+	}{
+		{"simple string", "+OK\r\n", "OK", false},
+		{"integer", ":42\r\n", int64(42), false},
+		{"bulk string", "$5\r\nhello\r\n", "hello", false},
+		{"empty bulk string", "$0\r\n\r\n", "", false},
+		{"null bulk string", "$-1\r\n", nil, false},
+		{"array", "*2\r\n$1\r\na\r\n:1\r\n", []any{"a", int64(1)}, false},
+		{"null array", "*-1\r\n", nil, false},
+		{"error", "-ERR wrong type\r\n", nil, true},
+		{"unknown type", "?x\r\n", nil, true},
+		{"missing CR", "+OK\n", nil, true},
+		{"truncated bulk", "$5\r\nhe", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRedisReply(bufio.NewReader(strings.NewReader(tt.in)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readRedisReply(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readRedisReply(%q) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// ─── Client ↔ server ──────────────────────────────────────────────────────────
+
+// fakeRedis is an in-process server implementing the commands used by the
+// HA shared state. Key expiry is recorded but never enforced.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	expiries map[string]string
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{
+		ln:       ln,
+		password: password,
+		strings:  make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+		expiries: make(map[string]string),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) url() string { return "redis://" + f.ln.Addr().String() }
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, it := range items {
+			args[i], _ = it.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+		var out string
+		if !authed && strings.ToUpper(args[0]) != "AUTH" {
+			out = "-NOAUTH Authentication required.\r\n"
+		} else {
+			out = f.exec(args, &authed)
+		}
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) exec(args []string, authed *bool) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, strings.Join(args, " "))
+	bulk := func(s string) string { return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n" }
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[1] != f.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	case "SELECT", "PING":
+		return "+OK\r\n"
+	case "SET":
+		if len(args) > 3 && strings.ToUpper(args[3]) == "NX" {
+			if _, ok := f.strings[args[1]]; ok {
+				return "$-1\r\n"
+			}
+		}
+		f.strings[args[1]] = args[2]
+		if len(args) > 5 {
+			f.expiries[args[1]] = args[5]
+		}
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "DEL":
+		_, ok := f.strings[args[1]]
+		delete(f.strings, args[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "INCR":
+		n, _ := strconv.Atoi(f.strings[args[1]])
+		f.strings[args[1]] = strconv.Itoa(n + 1)
+		return ":" + strconv.Itoa(n+1) + "\r\n"
+	case "PEXPIRE":
+		f.expiries[args[1]] = args[2]
+		return ":1\r\n"
+	case "EVAL":
+		switch {
+		case args[1] == rateWindowScript && args[2] == "1":
+			n, _ := strconv.Atoi(f.strings[args[3]])
+			f.strings[args[3]] = strconv.Itoa(n + 1)
+			if _, ok := f.expiries[args[3]]; !ok {
+				f.expiries[args[3]] = args[4]
+			}
+			return ":" + strconv.Itoa(n+1) + "\r\n"
+		case args[1] == renewLeaseScript && args[2] == "1" && f.strings[args[3]] == args[4]:
+			f.expiries[args[3]] = args[5]
+			return ":1\r\n"
+		case args[1] == releaseStartScript && args[2] == "1" && f.strings[args[3]] == args[4]:
+			delete(f.strings, args[3])
+			delete(f.expiries, args[3])
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "HSET":
+		h := f.hashes[args[1]]
+		if h == nil {
+			h = make(map[string]string)
+			f.hashes[args[1]] = h
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			h[args[i]] = args[i+1]
+		}
+		return ":" + strconv.Itoa((len(args)-2)/2) + "\r\n"
+	case "HGETALL":
+		h := f.hashes[args[1]]
+		out := "*" + strconv.Itoa(2*len(h)) + "\r\n"
+		for k, v := range h {
+			out += bulk(k) + bulk(v)
+		}
+		return out
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func TestRedisClient(t *testing.T) {
+	srv := newFakeRedis(t, "s3cret")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("wrong password", func(t *testing.T) {
+		c := newRedisClient(srv.url(), "nope")
+		defer c.Close()
+		if _, err := c.Do(ctx, "PING"); err == nil {
+			t.Fatal("expected an AUTH error")
+		}
+	})
+
+	srv.mu.Lock()
+	srv.commands = nil
+	srv.mu.Unlock()
+
+	c := newRedisClient(srv.url()+"/3", "s3cret")
+	defer c.Close()
+	if reply, err := c.Do(ctx, "SET", "k", "v"); err != nil || reply != "OK" {
+		t.Fatalf("SET = (%v, %v), want OK", reply, err)
+	}
+	if reply, err := c.Do(ctx, "GET", "k"); err != nil || reply != "v" {
+		t.Fatalf("GET = (%v, %v), want v", reply, err)
+	}
+	if reply, err := c.Do(ctx, "GET", "missing"); err != nil || reply != nil {
+		t.Fatalf("GET missing = (%v, %v), want nil", reply, err)
+	}
+
+	// An error reply keeps the connection usable.
+	if _, err := c.Do(ctx, "BOGUS"); err == nil {
+		t.Fatal("expected an error reply")
+	}
+	if reply, err := c.Do(ctx, "INCR", "n"); err != nil || reply != int64(1) {
+		t.Fatalf("INCR after error = (%v, %v), want 1", reply, err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.commands[0] != "AUTH s3cret" || srv.commands[1] != "SELECT 3" {
+		t.Errorf("setup commands = %q, want AUTH then SELECT", srv.commands[:2])
+	}
+}
+
+func TestRedisClient_Pool(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := newRedisClient(srv.url(), "")
+	defer c.Close()
+	var wg sync.WaitGroup
+	for range 2 * redisMaxIdle {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Do(ctx, "PING"); err != nil {
+				t.Errorf("concurrent PING: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	c.mu.Lock()
+	idle := len(c.idle)
+	c.mu.Unlock()
+	if idle < 1 || idle > redisMaxIdle {
+		t.Errorf("idle connections = %d, want 1..%d", idle, redisMaxIdle)
+	}
+
+	// Sequential commands reuse one connection.
+	c.Close()
+	c = newRedisClient(srv.url(), "")
+	defer c.Close()
+	for range 3 {
+		if _, err := c.Do(ctx, "PING"); err != nil {
+			t.Fatalf("PING: %v", err)
+		}
+	}
+	if len(c.idle) != 1 {
+		t.Errorf("idle connections after sequential commands = %d, want 1", len(c.idle))
+	}
+}
+
+func TestRedisClient_Reconnects(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := newRedisClient(srv.url(), "")
+	defer c.Close()
+	if _, err := c.Do(ctx, "PING"); err != nil {
+		t.Fatalf("PING: %v", err)
+	}
+	// Simulate a dropped connection: the next command must redial.
+	c.mu.Lock()
+	c.idle[0].Close()
+	c.mu.Unlock()
+	if _, err := c.Do(ctx, "PING"); err == nil {
+		t.Fatal("expected an error on the closed connection")
+	}
+	if _, err := c.Do(ctx, "PING"); err != nil {
+		t.Fatalf("PING after reconnect: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reload triggers, as reported by /_admin/reload/status.
+const (
+	// ReloadTriggerStatic is a new config.yaml: SIGHUP or Gateway.Reload.
+	ReloadTriggerStatic = "static"
+	// ReloadTriggerDiscovery is a change in the labeled containers.
+	ReloadTriggerDiscovery = "discovery"
+	// ReloadTriggerAPI is a container changed through /_api/v1/containers.
+	ReloadTriggerAPI = "api"
+)
+
+// ConfigDiff lists what a reload changes, by container and group name and
+// by gateway setting (YAML key).
+type ConfigDiff struct {
+	ContainersAdded   []string `json:"containers_added,omitempty"`
+	ContainersRemoved []string `json:"containers_removed,omitempty"`
+	ContainersChanged []string `json:"containers_changed,omitempty"`
+	GroupsAdded       []string `json:"groups_added,omitempty"`
+	GroupsRemoved     []string `json:"groups_removed,omitempty"`
+	GroupsChanged     []string `json:"groups_changed,omitempty"`
+	Gateway           []string `json:"gateway,omitempty"`
+}
+
+// diffConfigs compares two configurations. A nil side counts as empty.
+func diffConfigs(old, next *GatewayConfig) ConfigDiff {
+	if old == nil {
+		old = &GatewayConfig{}
+	}
+	if next == nil {
+		next = &GatewayConfig{}
+	}
+	var d ConfigDiff
+	d.ContainersAdded, d.ContainersRemoved, d.ContainersChanged = diffNamed(
+		old.Containers, next.Containers, func(c ContainerConfig) string { return c.Name })
+	d.GroupsAdded, d.GroupsRemoved, d.GroupsChanged = diffNamed(
+		old.Groups, next.Groups, func(g GroupConfig) string { return g.Name })
+
+	ov, nv := reflect.ValueOf(old.Gateway), reflect.ValueOf(next.Gateway)
+	for i := 0; i < ov.NumField(); i++ {
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			key, _, _ := strings.Cut(ov.Type().Field(i).Tag.Get("yaml"), ",")
+			d.Gateway = append(d.Gateway, key)
+		}
+	}
+	return d
+}
+
+// diffNamed returns the names only in after, only in before, and in both
+// with different settings, in configuration order.
+func diffNamed[T any](before, after []T, name func(T) string) (added, removed, changed []string) {
+	old := make(map[string]T, len(before))
+	for _, b := range before {
+		old[name(b)] = b
+	}
+	seen := make(map[string]bool, len(after))
+	for _, a := range after {
+		n := name(a)
+		seen[n] = true
+		if b, ok := old[n]; !ok {
+			added = append(added, n)
+		} else if !reflect.DeepEqual(a, b) {
+			changed = append(changed, n)
+		}
+	}
+	for _, b := range before {
+		if !seen[name(b)] {
+			removed = append(removed, name(b))
+		}
+	}
+	return added, removed, changed
+}
+
+// restartSettings are the gateway settings bound at startup. A reload
+// changing one of them is applied without it: the change takes effect at
+// the next restart.
+var restartSettings = map[string]bool{
+	"docker_host":         true,
+	"detect_capabilities": true,
+	"server":              true,
+	"acme":                true,
+}
+
+// ReloadResult is the outcome of one reload attempt.
+type ReloadResult struct {
+	Time    time.Time `json:"time"`
+	Trigger string    `json:"trigger"`
+	OK      bool      `json:"ok"`
+	// Error is why the reload was rejected; the previous configuration
+	// then stayed active.
+	Error string `json:"error,omitempty"`
+	// Diff is what the reload changed, or would have changed.
+	Diff ConfigDiff `json:"diff"`
+	// RequiresRestart lists the changed gateway settings that the reload
+	// left as they were until the gateway restarts.
+	RequiresRestart []string `json:"requires_restart,omitempty"`
+}
+
+// ReloadStatus is the payload of /_admin/reload/status.
+type ReloadStatus struct {
+	Last        *ReloadResult `json:"last"`
+	LastSuccess *time.Time    `json:"last_success,omitempty"`
+	Succeeded   int           `json:"succeeded"`
+	Failed      int           `json:"failed"`
+}
+
+// reloadLog remembers the last reload attempts since the gateway started.
+type reloadLog struct {
+	mu     sync.Mutex
+	status ReloadStatus
+	now    func() time.Time
+}
+
+func newReloadLog() *reloadLog {
+	return &reloadLog{now: time.Now}
+}
+
+// record stores the outcome of applying next over old.
+func (l *reloadLog) record(trigger string, old, next *GatewayConfig, err error) {
+	res := &ReloadResult{
+		Time:    l.now(),
+		Trigger: trigger,
+		OK:      err == nil,
+		Diff:    diffConfigs(old, next),
+	}
+	for _, key := range res.Diff.Gateway {
+		if restartSettings[key] {
+			res.RequiresRestart = append(res.RequiresRestart, key)
+		}
+	}
+	if err != nil {
+		res.Error = err.Error()
+	} else if len(res.RequiresRestart) > 0 {
+		slog.Warn("reload: some settings only take effect after a restart", "settings", res.RequiresRestart)
+	}
+	RecordConfigReload(trigger, res.OK)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.status.Last = res
+	if res.OK {
+		l.status.Succeeded++
+		t := res.Time
+		l.status.LastSuccess = &t
+	} else {
+		l.status.Failed++
+	}
+}
+
+// Status returns the reload history.
+func (l *reloadLog) Status() ReloadStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.status
+}
+
+// handleReloadStatus reports the outcome of the last configuration reload.
+func (s *Server) handleReloadStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(s.reloads.Status())
+}
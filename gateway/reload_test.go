@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	old := &GatewayConfig{
+		Gateway:    GlobalConfig{Port: "8080", LogLevel: "info"},
+		Containers: []ContainerConfig{{Name: "a", Host: "a.local"}, {Name: "b", Host: "b.local"}},
+		Groups:     []GroupConfig{{Name: "g", Host: "g.local"}},
+	}
+	next := &GatewayConfig{
+		Gateway:    GlobalConfig{Port: "9090", LogLevel: "info", TrustedProxies: []string{"10.0.0.0/8"}},
+		Containers: []ContainerConfig{{Name: "b", Host: "b2.local"}, {Name: "c", Host: "c.local"}},
+		Groups:     []GroupConfig{{Name: "g", Host: "g.local"}},
+	}
+	want := ConfigDiff{
+		ContainersAdded:   []string{"c"},
+		ContainersRemoved: []string{"a"},
+		ContainersChanged: []string{"b"},
+		Gateway:           []string{"port", "trusted_proxies"},
+	}
+	if got := diffConfigs(old, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("diff = %+v\nwant %+v", got, want)
+	}
+	if got := diffConfigs(nil, nil); !reflect.DeepEqual(got, ConfigDiff{}) {
+		t.Errorf("diff of nothing = %+v", got)
+	}
+}
+
+func TestGatewayReload_AtomicWithStatus(t *testing.T) {
+	rt := NewFakeRuntime()
+	cfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80"}}}
+	applyDefaults(cfg)
+	gw, err := New(cfg, WithRuntime(rt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A script that compiled when the file was loaded but is gone by the
+	// time the server applies it rejects the whole reload.
+	script := filepath.Join(t.TempDir(), "filter.lua")
+	if err := os.WriteFile(script, []byte("function on_wake() return true end"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := &GatewayConfig{
+		Gateway: GlobalConfig{Middlewares: map[string]MiddlewareConfig{
+			"filter": {Type: MiddlewareScript, Script: ScriptConfig{File: script}},
+		}},
+		Containers: []ContainerConfig{
+			{Name: "app", Host: "app.local", TargetPort: "80", Middlewares: []string{"filter"}},
+			{Name: "new", Host: "new.local", TargetPort: "80"},
+		},
+	}
+	applyDefaults(broken)
+	if err := broken.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(script)
+	if err := gw.Reload(broken); err == nil || !strings.Contains(err.Error(), "middlewares.filter") {
+		t.Fatalf("error = %v, want the script failure", err)
+	}
+	if got := gw.server.GetConfig(); got != cfg {
+		t.Error("active configuration changed by a rejected reload")
+	}
+	st := reloadStatus(t, gw.server)
+	if st.Last == nil || st.Last.OK || st.Last.Trigger != ReloadTriggerStatic || st.LastSuccess != nil {
+		t.Fatalf("status after failure = %+v", st)
+	}
+	if got := st.Last.Diff.ContainersAdded; len(got) != 1 || got[0] != "new" {
+		t.Errorf("diff of the rejected reload: added %v, want [new]", got)
+	}
+
+	// Periodic discovery does not retry the rejected configuration.
+	gw.discovery.runDiscovery(t.Context())
+	if got := gw.server.GetConfig(); got != cfg {
+		t.Error("discovery applied the rejected configuration")
+	}
+
+	fixed := &GatewayConfig{Containers: broken.Containers[1:]}
+	fixed.Containers = append(fixed.Containers, ContainerConfig{Name: "app", Host: "app.local", TargetPort: "80"})
+	applyDefaults(fixed)
+	if err := gw.Reload(fixed); err != nil {
+		t.Fatal(err)
+	}
+	if gw.server.GetConfig().Containers[0].Name != "new" {
+		t.Error("valid reload not applied")
+	}
+	st = reloadStatus(t, gw.server)
+	if !st.Last.OK || st.LastSuccess == nil || st.Succeeded != 1 || st.Failed != 1 {
+		t.Errorf("status after success = %+v", st)
+	}
+	if got, _ := gatheredValue(t, "gateway_config_reloads_total", map[string]string{"trigger": "static", "result": "error"}); got < 1 {
+		t.Errorf("gateway_config_reloads_total{result=error} = %v", got)
+	}
+}
+
+func TestGatewayReload_RequiresRestart(t *testing.T) {
+	rt := NewFakeRuntime()
+	cfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80"}}}
+	applyDefaults(cfg)
+	gw, err := New(cfg, WithRuntime(rt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := &GatewayConfig{
+		Gateway:    GlobalConfig{LogLevel: "debug", ACME: ACMEConfig{Enabled: true, CacheDir: t.TempDir()}},
+		Containers: cfg.Containers,
+	}
+	applyDefaults(next)
+	if err := gw.Reload(next); err != nil {
+		t.Fatal(err)
+	}
+	st := reloadStatus(t, gw.server)
+	if !st.Last.OK || !reflect.DeepEqual(st.Last.RequiresRestart, []string{"acme"}) {
+		t.Errorf("status = %+v, want ok with acme requiring a restart", st.Last)
+	}
+}
+
+func TestGatewayReloadFromFile_LoadError(t *testing.T) {
+	rt := NewFakeRuntime()
+	cfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80"}}}
+	applyDefaults(cfg)
+	gw, err := New(cfg, WithRuntime(rt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("containers: [oops"), 0o644)
+	t.Setenv("CONFIG_PATH", path)
+
+	if err := gw.ReloadFromFile(); err == nil {
+		t.Fatal("want a parse error")
+	}
+	if st := reloadStatus(t, gw.server); st.Last == nil || st.Last.OK || st.Last.Error == "" {
+		t.Errorf("status = %+v, want the load error", st)
+	}
+}
+
+func reloadStatus(t *testing.T, s *Server) ReloadStatus {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.handleReloadStatus(w, httptest.NewRequest(http.MethodGet, "/_admin/reload/status", nil))
+	var st ReloadStatus
+	if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
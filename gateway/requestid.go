@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLen bounds an X-Request-ID accepted from a client.
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+type traceContextKey struct{}
+
+// requestID returns a new random request ID such as "req-9f86d081884c7d65".
+func requestID(prefix string) string {
+	var b [8]byte
+	rand.Read(b[:])
+	return prefix + "-" + hex.EncodeToString(b[:])
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to
+// reuse: non-empty, bounded, and printable ASCII without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDMiddleware assigns a request ID to every request, stores it in the
+// request context (see requestIDFrom and ContextHandler) and returns it in
+// the X-Request-ID response header. A valid X-Request-ID is honoured only
+// when the request comes from a trusted proxy, so clients cannot inject IDs
+// into the gateway and backend logs.
+func (s *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) || !s.fromTrustedProxy(r) {
+			id = requestID("req")
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(withRequestID(r.Context(), id)))
+	})
+}
+
+// withRequestID returns a context carrying the request ID.
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// withTraceContext assigns the W3C trace context of an incoming request.
+// Without an active span (tracing disabled), the caller's traceparent is
+// passed through unchanged or a new trace is started so backends can still
+// correlate their logs.
+func withTraceContext(ctx context.Context, r *http.Request) context.Context {
+	if spanFromContext(ctx) != nil {
+		return ctx
+	}
+	sc, ok := parseTraceparent(r.Header.Get("traceparent"))
+	if !ok {
+		rand.Read(sc.TraceID[:])
+		rand.Read(sc.SpanID[:])
+		sc.Sampled = true
+	}
+	return context.WithValue(ctx, traceContextKey{}, sc)
+}
+
+// requestIDFrom returns the request ID assigned by requestIDMiddleware, or a
+// freshly generated one with the given prefix.
+func requestIDFrom(ctx context.Context, prefix string) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return requestID(prefix)
+}
+
+// traceContextFrom returns the trace context forwarded to backends: the
+// active span when tracing is enabled, the one assigned by withTraceContext
+// otherwise.
+func traceContextFrom(ctx context.Context) (spanContext, bool) {
+	if s := spanFromContext(ctx); s != nil {
+		return s.sc, true
+	}
+	sc, ok := ctx.Value(traceContextKey{}).(spanContext)
+	return sc, ok
+}
+
+// traceparent formats sc as a W3C traceparent header value.
+func (sc spanContext) traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// requestLogger returns the default logger annotated with the request and
+// trace IDs of ctx, so gateway logs can be matched with error pages and
+// backend logs. Use it where ctx is not passed to the log call; the *Context
+// slog functions get the same attributes from ContextHandler.
+func requestLogger(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		logger = logger.With("request_id", id)
+	}
+	if sc, ok := traceContextFrom(ctx); ok {
+		logger = logger.With("trace_id", sc.TraceID.String())
+	}
+	return logger
+}
+
+// detachContext returns a background context carrying the request ID, trace
+// context and active span of ctx, for work (such as an async container start)
+// that outlives the request but should still be attributed to it.
+func detachContext(ctx context.Context) context.Context {
+	out := context.Background()
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		out = context.WithValue(out, requestIDKey{}, id)
+	}
+	if sc, ok := ctx.Value(traceContextKey{}).(spanContext); ok {
+		out = context.WithValue(out, traceContextKey{}, sc)
+	}
+	if s := spanFromContext(ctx); s != nil {
+		out = trace.ContextWithSpan(out, s.span)
+	}
+	return out
+}
+
+// ContextHandler is a slog.Handler that adds the request_id and trace_id of
+// the record's context, so slog.InfoContext(r.Context(), ...) and friends are
+// attributed to the request without threading a logger around.
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps h with request-context attributes.
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: h}
+}
+
+// Handle adds the context attributes and passes the record on.
+func (h *ContextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		rec.AddAttrs(slog.String("request_id", id))
+	}
+	if sc, ok := traceContextFrom(ctx); ok {
+		rec.AddAttrs(slog.String("trace_id", sc.TraceID.String()))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+// WithAttrs keeps the wrapper around the derived handler.
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the wrapper around the derived handler.
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ─── Request ID ───────────────────────────────────────────────────────────────
+
+func TestValidRequestID(t *testing.T) {
+	tests := map[string]bool{
+		"req-9f86d081884c7d65":                 true,
+		"5b1f6c2e-8d4a-4c1b-9f0e-2a7d3c9e1b44": true,
+		"":                                     false,
+		"has space":                            false,
+		"line\nbreak":                          false,
+		strings.Repeat("a", maxRequestIDLen+1): false,
+	}
+	for id, want := range tests {
+		if got := validRequestID(id); got != want {
+			t.Errorf("validRequestID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	s := &Server{trustedCIDRs: parseTrustedProxies([]string{"10.0.0.0/8"})}
+	var seen string
+	h := s.requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFrom(r.Context(), "x")
+	}))
+	serve := func(remote, id string) (string, string) {
+		r := httptest.NewRequest(http.MethodGet, "/_health", nil)
+		r.RemoteAddr = remote
+		if id != "" {
+			r.Header.Set("X-Request-ID", id)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return seen, w.Header().Get("X-Request-ID")
+	}
+
+	t.Run("keeps a valid ID from a trusted proxy", func(t *testing.T) {
+		ctxID, respID := serve("10.1.2.3:4000", "upstream-42")
+		if ctxID != "upstream-42" || respID != "upstream-42" {
+			t.Errorf("context ID = %q, response ID = %q, want upstream-42", ctxID, respID)
+		}
+	})
+
+	t.Run("ignores the ID of an untrusted client", func(t *testing.T) {
+		ctxID, respID := serve("203.0.113.7:4000", "spoofed-1")
+		if !strings.HasPrefix(ctxID, "req-") || ctxID != respID {
+			t.Errorf("context ID = %q, response ID = %q, want the same generated req- ID", ctxID, respID)
+		}
+	})
+
+	t.Run("replaces an invalid ID from a trusted proxy", func(t *testing.T) {
+		ctxID, _ := serve("10.1.2.3:4000", "bad id")
+		if !strings.HasPrefix(ctxID, "req-") {
+			t.Errorf("request ID = %q, want generated req- ID", ctxID)
+		}
+	})
+
+	t.Run("same ID for the whole request", func(t *testing.T) {
+		ctx := withRequestID(context.Background(), "req-1")
+		if requestIDFrom(ctx, "req") != requestIDFrom(ctx, "err") {
+			t.Error("requestIDFrom should return the assigned ID regardless of prefix")
+		}
+	})
+}
+
+func TestContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewContextHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx := withTraceContext(withRequestID(context.Background(), "req-7"), r)
+
+	logger.InfoContext(ctx, "hello")
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["request_id"] != "req-7" || rec["trace_id"] == nil || rec["component"] != "test" {
+		t.Errorf("record = %v, want request_id, trace_id and component", rec)
+	}
+
+	buf.Reset()
+	logger.Info("no context")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("record without request context got a request_id: %s", buf.String())
+	}
+}
+
+// ─── Trace context propagation ────────────────────────────────────────────────
+
+func TestWithTraceContext(t *testing.T) {
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+
+	t.Run("passes the caller's trace through unchanged", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("traceparent", incoming)
+		sc, ok := traceContextFrom(withTraceContext(context.Background(), r))
+		if !ok || sc.traceparent() != incoming {
+			t.Errorf("traceparent = %q, want %q", sc.traceparent(), incoming)
+		}
+	})
+
+	t.Run("starts a new sampled trace", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		sc, ok := traceContextFrom(withTraceContext(context.Background(), r))
+		if !ok || !sc.valid() || !sc.Sampled {
+			t.Errorf("trace context = %+v, want a new sampled trace", sc)
+		}
+	})
+
+	t.Run("survives detachContext", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("traceparent", incoming)
+		ctx := detachContext(withTraceContext(withRequestID(context.Background(), "req-1"), r))
+		if sc, _ := traceContextFrom(ctx); sc.traceparent() != incoming {
+			t.Errorf("detached traceparent = %q, want %q", sc.traceparent(), incoming)
+		}
+		if id, ok := ctx.Value(requestIDKey{}).(string); !ok || id == "" {
+			t.Error("detached context lost the request ID")
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+// requestStatsWindow is how far back RequestStats looks: the current minute
+// and the ones before it.
+const requestStatsWindow = 5 * time.Minute
+
+// requestStatsSlots is the number of one-minute slots kept per container.
+const requestStatsSlots = int(requestStatsWindow / time.Minute)
+
+// latencyBounds are the upper bounds of the latency histogram buckets, from
+// 1ms to about two minutes in steps of 25%. A percentile is reported as the
+// bound of its bucket, so it is at most 25% above the real value.
+var latencyBounds = func() []time.Duration {
+	var bounds []time.Duration
+	for b := float64(time.Millisecond); b < float64(2*time.Minute); b *= 1.25 {
+		bounds = append(bounds, time.Duration(b))
+	}
+	return bounds
+}()
+
+// RequestStats keeps a rolling request rate and latency histogram per
+// container in memory, for the dashboard, independent of Prometheus.
+type RequestStats struct {
+	mu         sync.Mutex
+	containers map[string]*requestWindow
+	now        func() time.Time
+}
+
+// requestWindow is a ring of one-minute slots.
+type requestWindow struct {
+	since time.Time // first request recorded
+	slots [requestStatsSlots]statsSlot
+}
+
+// statsSlot counts the requests of one minute.
+type statsSlot struct {
+	minute  int64 // Unix minute the counts belong to
+	count   int
+	latency []int // per latencyBounds bucket, plus one for slower requests
+}
+
+// RequestSummary is the traffic of a container over requestStatsWindow.
+type RequestSummary struct {
+	Count         int
+	PerMinute     float64
+	P50, P95, P99 time.Duration
+}
+
+// NewRequestStats creates an empty tracker.
+func NewRequestStats() *RequestStats {
+	return &RequestStats{containers: make(map[string]*requestWindow), now: time.Now}
+}
+
+// Record counts a request to a container that took d.
+func (s *RequestStats) Record(name string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	w, ok := s.containers[name]
+	if !ok {
+		w = &requestWindow{since: now}
+		s.containers[name] = w
+	}
+	minute := now.Unix() / 60
+	slot := &w.slots[minute%int64(requestStatsSlots)]
+	if slot.minute != minute {
+		*slot = statsSlot{minute: minute, latency: make([]int, len(latencyBounds)+1)}
+	}
+	slot.count++
+	slot.latency[latencyBucket(d)]++
+}
+
+// latencyBucket returns the histogram bucket of d.
+func latencyBucket(d time.Duration) int {
+	for i, b := range latencyBounds {
+		if d <= b {
+			return i
+		}
+	}
+	return len(latencyBounds)
+}
+
+// Summary returns the request rate and latency percentiles of a container.
+// The rate is averaged over the part of the window since its first request,
+// and at least a minute, so a burst right after a start is not inflated.
+func (s *RequestStats) Summary(name string) RequestSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.containers[name]
+	if !ok {
+		return RequestSummary{}
+	}
+	now := s.now()
+	minute := now.Unix() / 60
+	oldest := minute - int64(requestStatsSlots) + 1
+
+	var sum RequestSummary
+	hist := make([]int, len(latencyBounds)+1)
+	for _, slot := range w.slots {
+		if slot.minute < oldest || slot.count == 0 {
+			continue
+		}
+		sum.Count += slot.count
+		for i, n := range slot.latency {
+			hist[i] += n
+		}
+	}
+	if sum.Count == 0 {
+		return RequestSummary{}
+	}
+
+	start := time.Unix(oldest*60, 0)
+	if w.since.After(start) {
+		start = w.since
+	}
+	span := max(now.Sub(start), time.Minute)
+	sum.PerMinute = float64(sum.Count) / span.Minutes()
+	sum.P50 = percentile(hist, sum.Count, 0.50)
+	sum.P95 = percentile(hist, sum.Count, 0.95)
+	sum.P99 = percentile(hist, sum.Count, 0.99)
+	return sum
+}
+
+// percentile returns the upper bound of the bucket holding the q-th of count
+// requests. Requests slower than the last bound report that bound.
+func percentile(hist []int, count int, q float64) time.Duration {
+	rank := int(q*float64(count) + 0.999999)
+	seen := 0
+	for i, n := range hist {
+		seen += n
+		if seen >= rank {
+			return latencyBounds[min(i, len(latencyBounds)-1)]
+		}
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+// Forget drops the statistics of a container removed from the configuration.
+func (s *RequestStats) Forget(name string) {
+	s.mu.Lock()
+	delete(s.containers, name)
+	s.mu.Unlock()
+}
@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestStats_Summary(t *testing.T) {
+	now := time.Date(2026, 1, 5, 10, 0, 30, 0, time.UTC)
+	s := NewRequestStats()
+	s.now = func() time.Time { return now }
+
+	if got := s.Summary("app"); got != (RequestSummary{}) {
+		t.Errorf("empty summary = %+v", got)
+	}
+
+	// 100 requests: 90 fast, 9 at 200ms, 1 at 2s.
+	for i := 0; i < 90; i++ {
+		s.Record("app", 5*time.Millisecond)
+	}
+	for i := 0; i < 9; i++ {
+		s.Record("app", 200*time.Millisecond)
+	}
+	s.Record("app", 2*time.Second)
+
+	got := s.Summary("app")
+	if got.Count != 100 || got.PerMinute != 100 {
+		t.Errorf("count %d, per minute %g: want 100 over the one-minute floor", got.Count, got.PerMinute)
+	}
+	within := func(name string, d, want time.Duration) {
+		if d < want || d > want*5/4 {
+			t.Errorf("%s = %v, want within 25%% above %v", name, d, want)
+		}
+	}
+	within("p50", got.P50, 5*time.Millisecond)
+	within("p95", got.P95, 200*time.Millisecond)
+	within("p99", got.P99, 200*time.Millisecond)
+
+	// Four minutes later the rate is averaged over the elapsed time.
+	now = now.Add(4 * time.Minute)
+	if got := s.Summary("app"); got.Count != 100 || got.PerMinute != 25 {
+		t.Errorf("after 4m: count %d, per minute %g, want 100 and 25", got.Count, got.PerMinute)
+	}
+
+	// Past the window the requests are gone.
+	now = now.Add(time.Minute)
+	if got := s.Summary("app"); got.Count != 0 {
+		t.Errorf("after the window: count %d, want 0", got.Count)
+	}
+
+	s.Record("app", time.Millisecond)
+	s.Forget("app")
+	if got := s.Summary("app"); got.Count != 0 {
+		t.Errorf("after Forget: count %d, want 0", got.Count)
+	}
+}
+
+func TestLatencyBucket_Overflow(t *testing.T) {
+	if b := latencyBucket(time.Hour); b != len(latencyBounds) {
+		t.Errorf("bucket = %d, want the overflow bucket", b)
+	}
+	hist := make([]int, len(latencyBounds)+1)
+	hist[len(latencyBounds)] = 1
+	if p := percentile(hist, 1, 0.5); p != latencyBounds[len(latencyBounds)-1] {
+		t.Errorf("percentile = %v, want the last bound", p)
+	}
+}
+
+func TestStatusAPI_RequestStats(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: port})
+
+	for i := 0; i < 3; i++ {
+		if w := g.get("app.local", "/"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+	}
+	// The loading page and admin endpoints are not proxied requests.
+	g.get("app.local", "/_status/api")
+
+	w := g.get("app.local", "/_status/api?fields=name,requests_per_min,latency_p95_ms")
+	var resp struct {
+		Containers []map[string]any `json:"containers"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Containers) != 1 {
+		t.Fatalf("containers = %v", resp.Containers)
+	}
+	if rate := resp.Containers[0]["requests_per_min"]; rate != 3.0 {
+		t.Errorf("requests_per_min = %v, want 3", rate)
+	}
+	if _, ok := resp.Containers[0]["latency_p95_ms"]; !ok {
+		t.Error("latency_p95_ms missing")
+	}
+}
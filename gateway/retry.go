@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+)
+
+// wakeRetryBackoff is the wait before the first wake retry; it doubles with
+// every further attempt.
+const wakeRetryBackoff = 100 * time.Millisecond
+
+// wakeRetryable reports whether r can safely be sent again: an idempotent
+// method and no body to replay.
+func wakeRetryable(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return r.ContentLength == 0 && !isWebSocketRequest(r)
+}
+
+// wakeRetryTransport retries a request through base when the connection is
+// refused or reset, until until. A readiness probe that passed does not
+// guarantee the HTTP stack of a just-woken app is accepting requests yet.
+type wakeRetryTransport struct {
+	base    http.RoundTripper
+	name    string
+	retries int
+	until   time.Time
+}
+
+func (t *wakeRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := wakeRetryBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil || attempt > t.retries || time.Now().Add(backoff).After(t.until) {
+			return resp, err
+		}
+		if category := classifyProxyError(err); category != "refused" && category != "reset" {
+			return resp, err
+		}
+		RecordWakeRetry(t.name)
+		requestLogger(req.Context()).Debug("retrying request to just-woken container",
+			"container", t.name, "attempt", attempt, "backoff", backoff, "error", err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// wakeRetryTransportFor returns the transport retrying r to cfg when cfg was
+// woken less than wake_retry_window ago, or base.
+func (s *Server) wakeRetryTransportFor(r *http.Request, cfg *ContainerConfig, base http.RoundTripper) http.RoundTripper {
+	if cfg.WakeRetries <= 0 || !wakeRetryable(r) {
+		return base
+	}
+	woken := s.manager.wokenAt(cfg.Name)
+	if woken.IsZero() || time.Since(woken) >= cfg.WakeRetryWindow {
+		return base
+	}
+	return &wakeRetryTransport{base: base, name: cfg.Name, retries: cfg.WakeRetries, until: woken.Add(cfg.WakeRetryWindow)}
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWakeRetryable(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		want   bool
+	}{
+		{http.MethodGet, "", true},
+		{http.MethodHead, "", true},
+		{http.MethodDelete, "", true},
+		{http.MethodPost, "", false},
+		{http.MethodPatch, "", false},
+		{http.MethodPut, "data", false},
+	}
+	for _, tt := range tests {
+		var body io.Reader
+		if tt.body != "" {
+			body = strings.NewReader(tt.body)
+		}
+		if got := wakeRetryable(httptest.NewRequest(tt.method, "/", body)); got != tt.want {
+			t.Errorf("wakeRetryable(%s, body %q) = %v, want %v", tt.method, tt.body, got, tt.want)
+		}
+	}
+}
+
+// flakyTransport fails the first failures round trips with err.
+type flakyTransport struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+func TestWakeRetryTransport(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	get := func(tr http.RoundTripper) error {
+		resp, err := tr.RoundTrip(httptest.NewRequest(http.MethodGet, "http://app/", nil))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("refused connections are retried", func(t *testing.T) {
+		base := &flakyTransport{failures: 2, err: refused}
+		tr := &wakeRetryTransport{base: base, name: "app", retries: 2, until: time.Now().Add(10 * time.Second)}
+		if err := get(tr); err != nil {
+			t.Fatalf("RoundTrip = %v, want success on the third attempt", err)
+		}
+		if base.calls != 3 {
+			t.Errorf("attempts = %d, want 3", base.calls)
+		}
+	})
+
+	t.Run("retries are bounded", func(t *testing.T) {
+		base := &flakyTransport{failures: 5, err: refused}
+		tr := &wakeRetryTransport{base: base, name: "app", retries: 1, until: time.Now().Add(10 * time.Second)}
+		if err := get(tr); err == nil {
+			t.Fatal("RoundTrip succeeded, want the last error")
+		}
+		if base.calls != 2 {
+			t.Errorf("attempts = %d, want 2", base.calls)
+		}
+	})
+
+	t.Run("no retry past the window", func(t *testing.T) {
+		base := &flakyTransport{failures: 1, err: refused}
+		tr := &wakeRetryTransport{base: base, name: "app", retries: 3, until: time.Now().Add(50 * time.Millisecond)}
+		if err := get(tr); err == nil || base.calls != 1 {
+			t.Errorf("RoundTrip = %v after %d attempts, want the error after 1", err, base.calls)
+		}
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		base := &flakyTransport{failures: 1, err: errors.New("tls: bad certificate")}
+		tr := &wakeRetryTransport{base: base, name: "app", retries: 3, until: time.Now().Add(10 * time.Second)}
+		if err := get(tr); err == nil || base.calls != 1 {
+			t.Errorf("RoundTrip = %v after %d attempts, want the error after 1", err, base.calls)
+		}
+	})
+}
+
+func TestWakeRetryTransportFor(t *testing.T) {
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(NewFakeRuntime())}
+	cfg := &ContainerConfig{Name: "app", WakeRetries: 2, WakeRetryWindow: 10 * time.Second}
+	get := httptest.NewRequest(http.MethodGet, "/", nil)
+	base := http.DefaultTransport
+
+	if tr := s.wakeRetryTransportFor(get, cfg, base); tr != base {
+		t.Error("container the gateway did not wake got retries")
+	}
+	s.manager.setStartState("app", statusRunning, "")
+	if _, ok := s.wakeRetryTransportFor(get, cfg, base).(*wakeRetryTransport); !ok {
+		t.Error("just-woken container got no retries")
+	}
+	if tr := s.wakeRetryTransportFor(httptest.NewRequest(http.MethodPost, "/", nil), cfg, base); tr != base {
+		t.Error("POST request got retries")
+	}
+	if tr := s.wakeRetryTransportFor(get, &ContainerConfig{Name: "app", WakeRetryWindow: 10 * time.Second}, base); tr != base {
+		t.Error("wake_retries 0 should disable retries")
+	}
+	if tr := s.wakeRetryTransportFor(get, &ContainerConfig{Name: "app", WakeRetries: 2, WakeRetryWindow: time.Nanosecond}, base); tr != base {
+		t.Error("container woken outside the window got retries")
+	}
+}
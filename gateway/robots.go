@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+)
+
+// robotsTxtPath is the path crawlers fetch before anything else on a host.
+const robotsTxtPath = "/robots.txt"
+
+// noIndex marks a page the gateway renders itself — loading, scheduled,
+// error or status — so search engines do not index it in place of the app.
+func (s *Server) noIndex(w http.ResponseWriter) {
+	if robots := s.GetConfig().Gateway.Robots; !robots.Disable {
+		w.Header().Set("X-Robots-Tag", robots.Tag)
+	}
+}
+
+// serveRobotsTxt answers a /robots.txt request for a container that is not
+// running with the configured robots.txt, so crawlers do not wake it. It
+// reports whether it answered.
+func (s *Server) serveRobotsTxt(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Path != robotsTxtPath || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+	robots := s.GetConfig().Gateway.Robots
+	if robots.Disable {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodGet {
+		io.WriteString(w, robots.RobotsTxt)
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRobots_AsleepAnswersWithoutWaking(t *testing.T) {
+	host, port := newBackend(t, "app robots")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: port})
+
+	w := g.get("app.local", "/robots.txt")
+	if w.Code != http.StatusOK || w.Body.String() != "User-agent: *\nDisallow: /\n" {
+		t.Errorf("robots.txt: status %d, body %q, want the default", w.Code, w.Body)
+	}
+	if calls := rt.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want no wake for robots.txt", calls)
+	}
+
+	w = g.get("app.local", "/")
+	if got := w.Header().Get("X-Robots-Tag"); got != "noindex, nofollow" {
+		t.Errorf("loading page X-Robots-Tag = %q", got)
+	}
+	g.waitStarted(t, "app")
+
+	// A running container serves its own robots.txt, without the header.
+	w = g.get("app.local", "/robots.txt")
+	if w.Body.String() != "app robots" || w.Header().Get("X-Robots-Tag") != "" {
+		t.Errorf("running: body %q, X-Robots-Tag %q, want the app's", w.Body, w.Header().Get("X-Robots-Tag"))
+	}
+}
+
+func TestRobots_Configured(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway: GlobalConfig{Robots: RobotsConfig{Tag: "noindex", RobotsTxt: "User-agent: *\nAllow: /\n"}},
+		Containers: []ContainerConfig{
+			{Name: "app", Host: "app.local", TargetPort: port},
+		},
+	})
+
+	if w := g.get("app.local", "/robots.txt"); w.Body.String() != "User-agent: *\nAllow: /\n" {
+		t.Errorf("robots.txt body %q", w.Body)
+	}
+	if w := g.get("app.local", "/_status"); w.Header().Get("X-Robots-Tag") != "noindex" {
+		t.Errorf("status page X-Robots-Tag = %q", w.Header().Get("X-Robots-Tag"))
+	}
+}
+
+func TestRobots_Disabled(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway:    GlobalConfig{Robots: RobotsConfig{Disable: true}},
+		Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: port}},
+	})
+
+	w := g.get("app.local", "/robots.txt")
+	if w.Header().Get("X-Robots-Tag") != "" {
+		t.Errorf("X-Robots-Tag = %q, want none", w.Header().Get("X-Robots-Tag"))
+	}
+	// Without the gateway's robots.txt the request wakes the container.
+	if status := g.waitStarted(t, "app"); status != "running" {
+		t.Errorf("state = %q, want running", status)
+	}
+}
@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Route sources reported by /_status/routes.
+const (
+	routeSourceStatic    = "static"
+	routeSourceDiscovery = "discovery"
+)
+
+type routeHostJSON struct {
+	Host       string `json:"host"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+	Container  string `json:"container"`
+	TargetPort string `json:"target_port"`
+	Source     string `json:"source"`
+}
+
+type routeGroupJSON struct {
+	Host     string   `json:"host"`
+	Group    string   `json:"group"`
+	Strategy string   `json:"strategy"`
+	Members  []string `json:"members"`
+}
+
+// routeMatchJSON explains how a single host and path are routed.
+type routeMatchJSON struct {
+	Host string `json:"host"`
+	Path string `json:"path"`
+	// Kind is "group", "container" or "none" (the request gets a 404).
+	Kind string `json:"kind"`
+	Name string `json:"name,omitempty"`
+}
+
+type routesResponse struct {
+	Hosts  []routeHostJSON  `json:"hosts"`
+	Groups []routeGroupJSON `json:"groups"`
+	// HostPattern is gateway.host_pattern; it routes every container by name.
+	HostPattern string `json:"host_pattern,omitempty"`
+	// Unrouted lists containers without a host of their own: reachable only
+	// as a group member or a dependency. Empty when HostPattern is set.
+	Unrouted  []string        `json:"unrouted"`
+	Match     *routeMatchJSON `json:"match,omitempty"`
+	UpdatedAt string          `json:"updated_at"`
+}
+
+// routeSource reports where a container definition came from.
+func routeSource(c *ContainerConfig) string {
+	if c.Discovered {
+		return routeSourceDiscovery
+	}
+	return routeSourceStatic
+}
+
+// handleStatusRoutes dumps the active routing table: the host and group
+// indexes, sorted by host, with the origin of every container. With
+// ?host=name (and ?path=, "/" by default) it also reports which route that
+// request resolves to.
+func (s *Server) handleStatusRoutes(w http.ResponseWriter, r *http.Request) {
+	s.configMu.RLock()
+	result := routesResponse{
+		Hosts:     make([]routeHostJSON, 0, len(s.hostIndex)),
+		Groups:    make([]routeGroupJSON, 0, len(s.groupIndex)),
+		Unrouted:  []string{},
+		UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	for host, c := range s.hostIndex {
+		result.Hosts = append(result.Hosts, routeHostJSON{
+			Host:       host,
+			Container:  c.Name,
+			TargetPort: c.TargetPort,
+			Source:     routeSource(c),
+		})
+	}
+	for host, cs := range s.prefixIndex {
+		for _, c := range cs {
+			result.Hosts = append(result.Hosts, routeHostJSON{
+				Host:       host,
+				PathPrefix: c.PathPrefix,
+				Container:  c.Name,
+				TargetPort: c.TargetPort,
+				Source:     routeSource(c),
+			})
+		}
+	}
+	for host, g := range s.groupIndex {
+		result.Groups = append(result.Groups, routeGroupJSON{
+			Host:     host,
+			Group:    g.Name,
+			Strategy: g.Strategy,
+			Members:  g.Containers,
+		})
+	}
+	result.HostPattern = s.cfg.Gateway.HostPattern
+	for i := range s.cfg.Containers {
+		if s.cfg.Containers[i].Host == "" && result.HostPattern == "" {
+			result.Unrouted = append(result.Unrouted, s.cfg.Containers[i].Name)
+		}
+	}
+	s.configMu.RUnlock()
+
+	sort.Slice(result.Hosts, func(i, j int) bool {
+		a, b := result.Hosts[i], result.Hosts[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return a.PathPrefix < b.PathPrefix
+	})
+	sort.Slice(result.Groups, func(i, j int) bool { return result.Groups[i].Host < result.Groups[j].Host })
+
+	if host := r.URL.Query().Get("host"); host != "" {
+		path := r.URL.Query().Get("path")
+		if path == "" {
+			path = "/"
+		}
+		result.Match = s.matchRoute(host, path)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// matchRoute resolves host and path the same way handleRequest does: groups
+// first, then containers by path prefix and by host, each with and without
+// the port, then host_pattern.
+func (s *Server) matchRoute(host, path string) *routeMatchJSON {
+	probe := &http.Request{Host: host, URL: &url.URL{Path: path}}
+	if g := s.resolveGroup(probe); g != nil {
+		return &routeMatchJSON{Host: host, Path: path, Kind: "group", Name: g.Name}
+	}
+	if c := s.resolveConfig(probe); c != nil {
+		return &routeMatchJSON{Host: host, Path: path, Kind: "container", Name: c.Name}
+	}
+	return &routeMatchJSON{Host: host, Path: path, Kind: "none"}
+}
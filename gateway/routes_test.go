@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleStatusRoutes(t *testing.T) {
+	cfg := &GatewayConfig{
+		Containers: []ContainerConfig{
+			{Name: "web", Host: "web.local", TargetPort: "80"},
+			{Name: "blog", Host: "blog.local", TargetPort: "2368", Discovered: true},
+			{Name: "api-1", TargetPort: "8080"},
+			{Name: "api-2", TargetPort: "8080"},
+		},
+		Groups: []GroupConfig{
+			{Name: "api", Host: "api.local", Strategy: "round-robin", Containers: []string{"api-1", "api-2"}},
+		},
+	}
+	s := &Server{
+		cfg:          cfg,
+		hostIndex:    BuildHostIndex(cfg),
+		groupIndex:   BuildGroupHostIndex(cfg),
+		containerMap: BuildContainerMap(cfg),
+	}
+
+	get := func(target string) routesResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.handleStatusRoutes(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp routesResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("/_status/routes")
+	if len(resp.Hosts) != 2 || resp.Hosts[0].Host != "blog.local" || resp.Hosts[1].Host != "web.local" {
+		t.Fatalf("hosts = %+v, want blog.local and web.local sorted", resp.Hosts)
+	}
+	if resp.Hosts[0].Source != routeSourceDiscovery || resp.Hosts[1].Source != routeSourceStatic {
+		t.Errorf("sources = %q, %q, want discovery, static", resp.Hosts[0].Source, resp.Hosts[1].Source)
+	}
+	if len(resp.Groups) != 1 || resp.Groups[0].Group != "api" || len(resp.Groups[0].Members) != 2 {
+		t.Errorf("groups = %+v", resp.Groups)
+	}
+	if len(resp.Unrouted) != 2 {
+		t.Errorf("unrouted = %v, want api-1 and api-2", resp.Unrouted)
+	}
+	if resp.Match != nil {
+		t.Errorf("match = %+v, want none without ?host", resp.Match)
+	}
+
+	tests := map[string]routeMatchJSON{
+		"web.local:8080": {Kind: "container", Name: "web"},
+		"api.local":      {Kind: "group", Name: "api"},
+		"nope.local":     {Kind: "none"},
+	}
+	for host, want := range tests {
+		m := get("/_status/routes?host=" + host).Match
+		if m == nil || m.Kind != want.Kind || m.Name != want.Name {
+			t.Errorf("match(%q) = %+v, want %+v", host, m, want)
+		}
+	}
+}
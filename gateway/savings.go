@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// savingsWindowDays is the rolling window reported on the dashboard.
+	savingsWindowDays = 7
+	// maxSampleGap bounds the time attributed to a single observation so a
+	// stalled refresher (or a suspended host) does not inflate the totals.
+	maxSampleGap = 2 * time.Minute
+)
+
+// dayUsage accumulates one container's runtime for one calendar day.
+type dayUsage struct {
+	Running time.Duration
+	Asleep  time.Duration
+	Wakes   int
+}
+
+// containerUsage holds the per-day history of a single container.
+type containerUsage struct {
+	lastSample  time.Time
+	lastRunning bool
+	days        map[string]*dayUsage // keyed by "2006-01-02" (UTC)
+}
+
+// UsageSummary is the runtime of one or more containers over the savings window.
+type UsageSummary struct {
+	Running time.Duration
+	Asleep  time.Duration
+	Wakes   int
+}
+
+// RuntimeTracker accumulates how long each container spent running versus
+// stopped, and how often it was woken, to show the savings of sleeping
+// containers. History is kept in memory for savingsWindowDays.
+type RuntimeTracker struct {
+	mu    sync.Mutex
+	usage map[string]*containerUsage
+}
+
+// NewRuntimeTracker creates an empty RuntimeTracker.
+func NewRuntimeTracker() *RuntimeTracker {
+	return &RuntimeTracker{usage: make(map[string]*containerUsage)}
+}
+
+// Observe records the current running state of a container. The time since
+// the previous observation is attributed to the previous state.
+func (t *RuntimeTracker) Observe(name string, running bool, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u := t.get(name)
+	if !u.lastSample.IsZero() {
+		if gap := now.Sub(u.lastSample); gap > 0 {
+			gap = min(gap, maxSampleGap)
+			day := u.day(now)
+			if u.lastRunning {
+				day.Running += gap
+				ContainerRunningSeconds.WithLabelValues(name).Add(gap.Seconds())
+			} else {
+				day.Asleep += gap
+				ContainerAsleepSeconds.WithLabelValues(name).Add(gap.Seconds())
+			}
+		}
+	}
+	u.lastSample = now
+	u.lastRunning = running
+	u.prune(now)
+}
+
+// RecordWake counts a successful on-demand start.
+func (t *RuntimeTracker) RecordWake(name string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(name).day(now).Wakes++
+}
+
+// Summary returns the usage of a container over the last savingsWindowDays.
+func (t *RuntimeTracker) Summary(name string, now time.Time) UsageSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var s UsageSummary
+	u, ok := t.usage[name]
+	if !ok {
+		return s
+	}
+	cutoff := windowStart(now)
+	for key, d := range u.days {
+		if key < cutoff {
+			continue
+		}
+		s.Running += d.Running
+		s.Asleep += d.Asleep
+		s.Wakes += d.Wakes
+	}
+	return s
+}
+
+// Forget drops the history of a container that is no longer managed.
+func (t *RuntimeTracker) Forget(name string) {
+	t.mu.Lock()
+	delete(t.usage, name)
+	t.mu.Unlock()
+}
+
+// Days returns a copy of the per-day history of a container, keyed by
+// "2006-01-02" (UTC).
+func (t *RuntimeTracker) Days(name string) map[string]dayUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.usage[name]
+	if !ok || len(u.days) == 0 {
+		return nil
+	}
+	days := make(map[string]dayUsage, len(u.days))
+	for key, d := range u.days {
+		days[key] = *d
+	}
+	return days
+}
+
+// MergeDays adds imported per-day history to a container, keeping the larger
+// value of each field so importing the same data twice changes nothing.
+// Days outside the savings window are dropped.
+func (t *RuntimeTracker) MergeDays(name string, days map[string]dayUsage, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u := t.get(name)
+	for key, in := range days {
+		d, ok := u.days[key]
+		if !ok {
+			d = &dayUsage{}
+			u.days[key] = d
+		}
+		d.Running = max(d.Running, in.Running)
+		d.Asleep = max(d.Asleep, in.Asleep)
+		d.Wakes = max(d.Wakes, in.Wakes)
+	}
+	u.prune(now)
+}
+
+// get returns (or creates) the usage of name. Caller must hold t.mu.
+func (t *RuntimeTracker) get(name string) *containerUsage {
+	u, ok := t.usage[name]
+	if !ok {
+		u = &containerUsage{days: make(map[string]*dayUsage)}
+		t.usage[name] = u
+	}
+	return u
+}
+
+func (u *containerUsage) day(now time.Time) *dayUsage {
+	key := now.UTC().Format(time.DateOnly)
+	d, ok := u.days[key]
+	if !ok {
+		d = &dayUsage{}
+		u.days[key] = d
+	}
+	return d
+}
+
+func (u *containerUsage) prune(now time.Time) {
+	cutoff := windowStart(now)
+	for key := range u.days {
+		if key < cutoff {
+			delete(u.days, key)
+		}
+	}
+}
+
+// windowStart returns the first day key inside the savings window.
+func windowStart(now time.Time) string {
+	return now.UTC().AddDate(0, 0, -(savingsWindowDays - 1)).Format(time.DateOnly)
+}
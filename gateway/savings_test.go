@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+// ─── Attribution ──────────────────────────────────────────────────────────────
+
+func TestRuntimeTracker_Observe(t *testing.T) {
+	tr := NewRuntimeTracker()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tr.Observe("app", false, now)                     // first sample: nothing attributed
+	tr.Observe("app", true, now.Add(time.Minute))     // 1m asleep
+	tr.Observe("app", true, now.Add(90*time.Second))  // 30s running
+	tr.Observe("app", false, now.Add(2*time.Minute))  // 30s running
+	tr.Observe("app", false, now.Add(12*time.Minute)) // 10m gap, capped
+
+	s := tr.Summary("app", now.Add(12*time.Minute))
+	if s.Running != time.Minute {
+		t.Errorf("Running = %v, want 1m", s.Running)
+	}
+	if want := time.Minute + maxSampleGap; s.Asleep != want {
+		t.Errorf("Asleep = %v, want %v", s.Asleep, want)
+	}
+}
+
+func TestRuntimeTracker_Wakes(t *testing.T) {
+	tr := NewRuntimeTracker()
+	now := time.Now()
+	tr.RecordWake("app", now)
+	tr.RecordWake("app", now)
+	tr.RecordWake("other", now)
+
+	if got := tr.Summary("app", now).Wakes; got != 2 {
+		t.Errorf("app wakes = %d, want 2", got)
+	}
+	if got := tr.Summary("missing", now); got != (UsageSummary{}) {
+		t.Errorf("unknown container summary = %+v, want zero", got)
+	}
+}
+
+// ─── Window ───────────────────────────────────────────────────────────────────
+
+func TestRuntimeTracker_Window(t *testing.T) {
+	tr := NewRuntimeTracker()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tr.RecordWake("app", start)
+	tr.RecordWake("app", start.AddDate(0, 0, 5))
+
+	if got := tr.Summary("app", start.AddDate(0, 0, 6)).Wakes; got != 2 {
+		t.Errorf("wakes within window = %d, want 2", got)
+	}
+	if got := tr.Summary("app", start.AddDate(0, 0, savingsWindowDays)).Wakes; got != 1 {
+		t.Errorf("wakes after first day left the window = %d, want 1", got)
+	}
+
+	// Observing prunes days that fell out of the window.
+	tr.Observe("app", false, start.AddDate(0, 0, savingsWindowDays))
+	if n := len(tr.usage["app"].days); n != 1 {
+		t.Errorf("days kept = %d, want 1", n)
+	}
+}
+
+func TestRuntimeTracker_Forget(t *testing.T) {
+	tr := NewRuntimeTracker()
+	now := time.Now()
+	tr.RecordWake("app", now)
+	tr.Forget("app")
+	if got := tr.Summary("app", now).Wakes; got != 0 {
+		t.Errorf("wakes after Forget = %d, want 0", got)
+	}
+}
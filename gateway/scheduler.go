@@ -135,7 +135,7 @@ func prevFiring(schedule cron.Schedule, now time.Time) (time.Time, bool) {
 // Call Sync on startup and on every config hot-reload.
 type ScheduleManager struct {
 	cron    *cron.Cron
-	client  *DockerClient
+	client  ContainerRuntime
 	manager *ContainerManager
 
 	mu      sync.Mutex
@@ -143,7 +143,7 @@ type ScheduleManager struct {
 }
 
 // NewScheduleManager creates a ScheduleManager. Call Start to begin execution.
-func NewScheduleManager(client *DockerClient, manager *ContainerManager) *ScheduleManager {
+func NewScheduleManager(client ContainerRuntime, manager *ContainerManager) *ScheduleManager {
 	return &ScheduleManager{
 		cron:    cron.New(),
 		client:  client,
@@ -196,6 +196,14 @@ func (sm *ScheduleManager) Sync(containers []ContainerConfig, loc *time.Location
 
 		if cfg.ScheduleStart != "" {
 			id, err := sm.cron.AddFunc(cronExprFromLoc(cfg.ScheduleStart, effectiveLoc), func() {
+				if sm.manager.ReadOnly() {
+					slog.Info("scheduled start skipped: the gateway is read-only", "container", cfg.Name)
+					return
+				}
+				if sm.manager.DryRun() {
+					sm.manager.simulate(dryRunStart, cfg.Name, "schedule_start "+cfg.ScheduleStart, time.Now())
+					return
+				}
 				ctx, cancel := context.WithTimeout(context.Background(), cfg.StartTimeout)
 				defer cancel()
 				sm.manager.InitStartState(cfg.Name)
@@ -214,11 +222,24 @@ func (sm *ScheduleManager) Sync(containers []ContainerConfig, loc *time.Location
 
 		if cfg.ScheduleStop != "" {
 			id, err := sm.cron.AddFunc(cronExprFromLoc(cfg.ScheduleStop, effectiveLoc), func() {
+				if sm.manager.ReadOnly() {
+					slog.Info("scheduled stop skipped: the gateway is read-only", "container", cfg.Name)
+					return
+				}
+				if sm.manager.DryRun() {
+					sm.manager.simulate(dryRunStop, cfg.Name, "schedule_stop "+cfg.ScheduleStop, time.Now())
+					return
+				}
+				if !sm.client.Capable(CapabilityStop) {
+					slog.Warn("scheduled stop skipped: stopping containers is not allowed by the Docker API", "container", cfg.Name)
+					return
+				}
 				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 				defer cancel()
 				if err := sm.client.StopContainer(ctx, cfg.Name); err != nil {
 					slog.Error("scheduled stop failed", "container", cfg.Name, "error", err)
 				} else {
+					sm.manager.crashes.MarkStopped(cfg.Name)
 					slog.Info("scheduled stop succeeded", "container", cfg.Name)
 				}
 			})
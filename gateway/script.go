@@ -0,0 +1,332 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// defaultScriptTimeout bounds a call into a script when script.timeout is 0.
+const defaultScriptTimeout = 50 * time.Millisecond
+
+// scriptStackSize is the call stack depth available to a script.
+const scriptStackSize = 200
+
+// scriptMaxRepLen is the longest string string.rep may build, in bytes.
+const scriptMaxRepLen = 1 << 20
+
+// scriptLibs are the Lua libraries a script may use. io, os, package,
+// debug, coroutine and channel are left out.
+var scriptLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// scriptHiddenGlobals are base functions that load code, reach the file
+// system or expose the interpreter; they are removed from every state.
+var scriptHiddenGlobals = []string{
+	"collectgarbage", "dofile", "getfenv", "load", "loadfile", "loadstring",
+	"module", "newproxy", "print", "require", "setfenv", "_printregs",
+}
+
+// luaScript is a compiled script. Lua states are not safe for concurrent
+// use, so each call takes one from the pool, creating it if needed. After
+// every call the globals of the state are reset to what the top level left,
+// so a global set while handling one request is not seen by another.
+type luaScript struct {
+	proto     *lua.FunctionProto
+	timeout   time.Duration
+	onRequest bool
+	onWake    bool
+	pool      sync.Pool
+}
+
+// compileScript parses cfg and runs its top level once, to report syntax
+// errors and scripts defining neither on_request nor on_wake.
+func compileScript(cfg ScriptConfig) (*luaScript, error) {
+	src, name := cfg.Source, "source"
+	switch {
+	case cfg.File != "" && cfg.Source != "":
+		return nil, fmt.Errorf("set file or source, not both")
+	case cfg.File != "":
+		data, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		src, name = string(data), cfg.File
+	case cfg.Source == "":
+		return nil, fmt.Errorf("file or source is required")
+	}
+
+	chunk, err := parse.Parse(strings.NewReader(src), name)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, err
+	}
+	s := &luaScript{proto: proto, timeout: cfg.Timeout}
+	if s.timeout == 0 {
+		s.timeout = defaultScriptTimeout
+	}
+
+	st, err := s.newState()
+	if err != nil {
+		return nil, err
+	}
+	s.onRequest = st.L.GetGlobal("on_request").Type() == lua.LTFunction
+	s.onWake = st.L.GetGlobal("on_wake").Type() == lua.LTFunction
+	if !s.onRequest && !s.onWake {
+		st.L.Close()
+		return nil, fmt.Errorf("%s defines neither on_request nor on_wake", name)
+	}
+	s.pool.Put(st)
+	return s, nil
+}
+
+// scriptState is a pooled Lua state and its globals after the top level ran.
+type scriptState struct {
+	L       *lua.LState
+	globals map[lua.LValue]lua.LValue
+}
+
+// resetGlobals removes the globals added by the last call and restores the
+// ones it changed. Tables are restored by reference: their contents, like
+// the upvalues of the script's functions, are shared between calls.
+func (st *scriptState) resetGlobals() {
+	g := st.L.G.Global
+	var changed []lua.LValue
+	g.ForEach(func(k, v lua.LValue) {
+		if st.globals[k] != v {
+			changed = append(changed, k)
+		}
+	})
+	for _, k := range changed {
+		g.RawSet(k, lua.LNil)
+	}
+	for k, v := range st.globals {
+		if g.RawGet(k) != v {
+			g.RawSet(k, v)
+		}
+	}
+}
+
+// newState returns a sandboxed state that ran the script's top level.
+func (s *luaScript) newState() (*scriptState, error) {
+	// Without RegistryMaxSize the data stack keeps its initial size: a
+	// script pushing more values fails instead of growing it.
+	L := lua.NewState(lua.Options{SkipOpenLibs: true, CallStackSize: scriptStackSize})
+	for _, lib := range scriptLibs {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, g := range scriptHiddenGlobals {
+		L.SetGlobal(g, lua.LNil)
+	}
+	if str, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		str.RawSetString("rep", L.NewFunction(scriptStringRep))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	L.Push(L.NewFunctionFromProto(s.proto))
+	err := L.PCall(0, 0, nil)
+	L.RemoveContext()
+	if err != nil {
+		L.Close()
+		return nil, err
+	}
+	st := &scriptState{L: L, globals: make(map[lua.LValue]lua.LValue)}
+	L.G.Global.ForEach(func(k, v lua.LValue) { st.globals[k] = v })
+	return st, nil
+}
+
+// scriptStringRep is string.rep refusing results longer than
+// scriptMaxRepLen, which would otherwise let one call allocate gigabytes
+// well within the timeout.
+func scriptStringRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if len(str) > 0 && n > scriptMaxRepLen/len(str) {
+		L.RaiseError("string.rep result longer than %d bytes", scriptMaxRepLen)
+	}
+	L.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+// call runs the global function fn with req and returns its two results.
+// A state whose call failed may be left inconsistent and is discarded.
+func (s *luaScript) call(ctx context.Context, fn string, req *lua.LTable) (lua.LValue, lua.LValue, error) {
+	st, _ := s.pool.Get().(*scriptState)
+	if st == nil {
+		var err error
+		if st, err = s.newState(); err != nil {
+			return nil, nil, err
+		}
+	}
+	L := st.L
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	err := L.CallByParam(lua.P{Fn: L.GetGlobal(fn), NRet: 2, Protect: true}, req)
+	L.RemoveContext()
+	if err != nil {
+		L.Close()
+		return nil, nil, err
+	}
+	first, second := L.Get(-2), L.Get(-1)
+	L.Pop(2)
+	st.resetGlobals()
+	s.pool.Put(st)
+	return first, second, nil
+}
+
+// scriptRequest is the table a script receives for r. Header names are
+// canonical and hold the first value.
+func (s *Server) scriptRequest(r *http.Request) *lua.LTable {
+	req := &lua.LTable{}
+	req.RawSetString("method", lua.LString(r.Method))
+	req.RawSetString("host", lua.LString(r.Host))
+	req.RawSetString("path", lua.LString(r.URL.Path))
+	req.RawSetString("query", lua.LString(r.URL.RawQuery))
+	req.RawSetString("client_ip", lua.LString(s.clientIP(r)))
+	headers := &lua.LTable{}
+	for k, v := range r.Header {
+		if len(v) > 0 {
+			headers.RawSetString(k, lua.LString(v[0]))
+		}
+	}
+	req.RawSetString("headers", headers)
+	return req
+}
+
+// applyScriptRequest copies the changes a script made to req back to r:
+// headers set to a new value or to nil, the path and the query.
+func applyScriptRequest(r *http.Request, req *lua.LTable) {
+	if headers, ok := req.RawGetString("headers").(*lua.LTable); ok {
+		for k := range r.Header {
+			if headers.RawGetString(k) == lua.LNil {
+				r.Header.Del(k)
+			}
+		}
+		headers.ForEach(func(k, v lua.LValue) {
+			if v.Type() == lua.LTNil {
+				return
+			}
+			name := k.String()
+			if r.Header.Get(name) != v.String() {
+				r.Header.Set(name, v.String())
+			}
+		})
+	}
+	if path := req.RawGetString("path").String(); path != r.URL.Path {
+		r.URL.Path = path
+		r.URL.RawPath = ""
+	}
+	if query := req.RawGetString("query"); query.Type() == lua.LTString {
+		r.URL.RawQuery = query.String()
+	}
+}
+
+// writeScriptResponse answers the request with the table returned by
+// on_request: status (default 403), body and headers.
+func writeScriptResponse(w http.ResponseWriter, resp *lua.LTable) {
+	status := http.StatusForbidden
+	if n, ok := resp.RawGetString("status").(lua.LNumber); ok && n >= 100 && n <= 999 {
+		status = int(n)
+	}
+	if headers, ok := resp.RawGetString("headers").(*lua.LTable); ok {
+		headers.ForEach(func(k, v lua.LValue) {
+			w.Header().Set(k.String(), v.String())
+		})
+	}
+	body := ""
+	if b := resp.RawGetString("body"); b.Type() != lua.LTNil {
+		body = b.String()
+	}
+	if w.Header().Get("Content-Type") == "" && body != "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+// scriptMiddleware runs the on_request and on_wake functions of a Lua
+// script. on_request may rewrite the request or answer it by returning a
+// response table; on_wake may keep the container asleep by returning false
+// and a reason. A failing script answers 500 and vetoes the wake.
+func (s *Server) scriptMiddleware(name string, cfg ScriptConfig) (Middleware, error) {
+	script, err := compileScript(cfg)
+	if err != nil {
+		return nil, err
+	}
+	wakeCheck := func(r *http.Request) error {
+		allow, reason, err := script.call(r.Context(), "on_wake", s.scriptRequest(r))
+		if err != nil {
+			RecordMiddlewareRejection(name, "script_error")
+			slog.ErrorContext(r.Context(), "script on_wake failed", "middleware", name, "error", err)
+			return errors.New("wake check failed")
+		}
+		if allow == lua.LFalse {
+			RecordMiddlewareRejection(name, "wake_vetoed")
+			if reason.Type() == lua.LTString {
+				return errors.New(reason.String())
+			}
+			return fmt.Errorf("refused by %s", name)
+		}
+		return nil
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if script.onWake {
+				r = WithWakeCheck(r, wakeCheck)
+			}
+			if !script.onRequest {
+				next.ServeHTTP(w, r)
+				return
+			}
+			req := s.scriptRequest(r)
+			resp, _, err := script.call(r.Context(), "on_request", req)
+			if err != nil {
+				RecordMiddlewareRejection(name, "script_error")
+				slog.ErrorContext(r.Context(), "script on_request failed",
+					"middleware", name,
+					"path", r.URL.Path,
+					"error", err,
+				)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if t, ok := resp.(*lua.LTable); ok {
+				RecordMiddlewareRejection(name, "script")
+				writeScriptResponse(w, t)
+				return
+			}
+			applyScriptRequest(r, req)
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,217 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newScriptGateway serves app.local through a script middleware.
+func newScriptGateway(t *testing.T, rt *FakeRuntime, port string, script ScriptConfig) *fakeGateway {
+	t.Helper()
+	return newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Gateway: GlobalConfig{Middlewares: map[string]MiddlewareConfig{
+			"filter": {Type: MiddlewareScript, Script: script},
+		}},
+		Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: port, Middlewares: []string{"filter"}}},
+	})
+}
+
+func TestScript_RewritesRequest(t *testing.T) {
+	host, port := newBackend(t, "ok", echoHeaders("X-Tenant", "Cookie", "X-Path"))
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newScriptGateway(t, rt, port, ScriptConfig{Source: `
+function on_request(req)
+  req.headers["X-Tenant"] = string.upper(string.match(req.host, "^(%w+)"))
+  req.headers["Cookie"] = nil
+  req.headers["X-Path"] = req.path
+end`})
+
+	w := g.get("app.local", "/a")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %q", w.Code, w.Body)
+	}
+	if got := w.Header().Get("X-Echo-X-Tenant"); got != "APP" {
+		t.Errorf("X-Tenant = %q, want APP", got)
+	}
+	if got := w.Header().Get("X-Echo-X-Path"); got != "/a" {
+		t.Errorf("X-Path = %q, want /a", got)
+	}
+}
+
+func TestScript_ShortCircuit(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newScriptGateway(t, rt, port, ScriptConfig{Source: `
+function on_request(req)
+  if req.path == "/admin" then
+    return {status = 404, body = "not here", headers = {["X-Filter"] = "lua"}}
+  end
+end`})
+
+	w := g.get("app.local", "/admin")
+	if w.Code != http.StatusNotFound || w.Body.String() != "not here" || w.Header().Get("X-Filter") != "lua" {
+		t.Errorf("got %d %q %v, want the script's 404", w.Code, w.Body, w.Header())
+	}
+	if calls := rt.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want no wake for an answered request", calls)
+	}
+}
+
+func TestScript_Timeout(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newScriptGateway(t, rt, port, ScriptConfig{Timeout: 20 * time.Millisecond, Source: `
+function on_request(req)
+  if req.path == "/loop" then
+    while true do end
+  end
+end`})
+
+	start := time.Now()
+	if w := g.get("app.local", "/loop"); w.Code != http.StatusInternalServerError {
+		t.Errorf("looping script: status %d, want 500", w.Code)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("looping script took %v", d)
+	}
+	// The failed state was discarded; the next call gets a fresh one.
+	if w := g.get("app.local", "/"); w.Code != http.StatusOK {
+		t.Errorf("after the timeout: status %d, want 200", w.Code)
+	}
+}
+
+func TestScript_WakeVeto(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newScriptGateway(t, rt, port, ScriptConfig{Source: `
+function on_wake(req)
+  if string.find(req.headers["User-Agent"] or "", "bot") then
+    return false, "crawlers do not wake this app"
+  end
+  return true
+end`})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.local"
+	req.Header.Set("User-Agent", "somebot/1.0")
+	w := httptest.NewRecorder()
+	g.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "crawlers do not wake this app") {
+		t.Errorf("bot: status %d, want 503 with the reason", w.Code)
+	}
+	if calls := rt.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want no start for a vetoed wake", calls)
+	}
+
+	if w := g.get("app.local", "/"); w.Code != http.StatusOK {
+		t.Errorf("browser: status %d, want the loading page", w.Code)
+	}
+	g.waitStarted(t, "app")
+}
+
+func TestScript_Sandbox(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newScriptGateway(t, rt, port, ScriptConfig{Source: `
+function on_request(req)
+  return {status = 200, body = type(os) .. " " .. type(io) .. " " .. type(dofile) .. " " .. type(require)}
+end`})
+
+	if w := g.get("app.local", "/"); w.Body.String() != "nil nil nil nil" {
+		t.Errorf("visible globals = %q, want all nil", w.Body)
+	}
+}
+
+func TestScript_GlobalsResetBetweenCalls(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newScriptGateway(t, rt, port, ScriptConfig{Source: `
+mode = "initial"
+function on_request(req)
+  local body = tostring(counter) .. " " .. mode
+  counter = (counter or 0) + 1
+  mode = "changed"
+  return {status = 200, body = body}
+end`})
+
+	for i := 0; i < 3; i++ {
+		if w := g.get("app.local", "/"); w.Body.String() != "nil initial" {
+			t.Fatalf("request %d saw globals %q, want the ones of the top level", i, w.Body)
+		}
+	}
+}
+
+func TestScript_MemoryLimits(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newScriptGateway(t, rt, port, ScriptConfig{Timeout: time.Second, Source: `
+function on_request(req)
+  if req.path == "/rep" then
+    return {status = 200, body = string.rep("x", 1024 * 1024 * 1024)}
+  elseif req.path == "/stack" then
+    local t = {}
+    for i = 1, 100000 do t[i] = i end
+    return {status = 200, body = select("#", unpack(t))}
+  end
+  return {status = 200, body = string.rep("ab", 3)}
+end`})
+
+	for _, path := range []string{"/rep", "/stack"} {
+		if w := g.get("app.local", path); w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: status %d, want 500 for a script over the memory limits", path, w.Code)
+		}
+	}
+	if w := g.get("app.local", "/"); w.Body.String() != "ababab" {
+		t.Errorf("string.rep within the limit = %q, want ababab", w.Body)
+	}
+}
+
+func TestValidate_ScriptMiddleware(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "filter.lua")
+	if err := os.WriteFile(file, []byte("function on_wake(req) return true end"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		script  ScriptConfig
+		wantErr string
+	}{
+		{"file", ScriptConfig{File: file}, ""},
+		{"missing", ScriptConfig{}, "file or source is required"},
+		{"both", ScriptConfig{File: file, Source: "x = 1"}, "not both"},
+		{"syntax error", ScriptConfig{Source: "function on_request(req"}, "middlewares.x: script:"},
+		{"no hooks", ScriptConfig{Source: "x = 1"}, "neither on_request nor on_wake"},
+		{"negative timeout", ScriptConfig{Source: "function on_wake() end", Timeout: -time.Second}, "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &GatewayConfig{
+				Gateway:    GlobalConfig{Middlewares: map[string]MiddlewareConfig{"x": {Type: MiddlewareScript, Script: tt.script}}},
+				Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: "80"}},
+			}
+			applyDefaults(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
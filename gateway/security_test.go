@@ -76,98 +76,123 @@ func TestValidateOrigin(t *testing.T) {
 
 // ─── rateLimiter ──────────────────────────────────────────────────────────────
 
-func TestRateLimiter_Allow(t *testing.T) {
-	rl := newRateLimiter(100 * time.Millisecond)
+// newTestRateLimiter returns a limiter driven by a fake clock.
+func newTestRateLimiter(limits RateLimitConfig) (*rateLimiter, func(time.Duration)) {
+	rl := newRateLimiter(limits)
+	now, advance := fakeClock()
+	rl.now = now
+	return rl, advance
+}
 
-	// First request from an IP should always be allowed
-	if !rl.Allow("10.0.0.1") {
-		t.Fatal("first request should be allowed")
-	}
+func TestRateLimiter_Burst(t *testing.T) {
+	rl, advance := newTestRateLimiter(RateLimitConfig{Health: RateLimitPolicy{Rate: 2, Burst: 3}})
 
-	// Immediate second request from same IP should be blocked
-	if rl.Allow("10.0.0.1") {
-		t.Fatal("immediate second request should be rate-limited")
+	// A full bucket allows Burst requests back to back...
+	for i := 0; i < 3; i++ {
+		if !rl.Allow("health", "10.0.0.1") {
+			t.Fatalf("request %d within burst should be allowed", i+1)
+		}
+	}
+	// ...then the next one is limited.
+	if rl.Allow("health", "10.0.0.1") {
+		t.Fatal("request beyond burst should be rate-limited")
 	}
 
-	// Different IP should be allowed
-	if !rl.Allow("10.0.0.2") {
+	// Different IP has its own bucket
+	if !rl.Allow("health", "10.0.0.2") {
 		t.Fatal("first request from different IP should be allowed")
 	}
 
-	// Wait for interval to expire
-	time.Sleep(120 * time.Millisecond)
-
-	// Now the original IP should be allowed again
-	if !rl.Allow("10.0.0.1") {
-		t.Fatal("request after interval should be allowed")
+	// At 2 tokens/s, one token is back after 500ms.
+	advance(500 * time.Millisecond)
+	if !rl.Allow("health", "10.0.0.1") {
+		t.Fatal("request after refill should be allowed")
+	}
+	if rl.Allow("health", "10.0.0.1") {
+		t.Fatal("only one token should have been refilled")
 	}
 }
 
-func TestRateLimiter_EvictStale(t *testing.T) {
-	rl := newRateLimiter(50 * time.Millisecond)
+func TestRateLimiter_EndpointsAreIndependent(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{
+		Health: RateLimitPolicy{Rate: 1, Burst: 1},
+		Logs:   RateLimitPolicy{Rate: 1, Burst: 1},
+	})
 
-	// Populate with several IPs
-	for i := 0; i < 100; i++ {
-		rl.Allow("192.168.0." + string(rune('0'+i%10)))
+	// The loading page polls /_health and /_logs at the same time.
+	if !rl.Allow("health", "10.0.0.1") || !rl.Allow("logs", "10.0.0.1") {
+		t.Fatal("health and logs should not share a bucket")
+	}
+	if !rl.Allow("topology", "10.0.0.1") {
+		t.Fatal("endpoints without a policy should never be limited")
 	}
+}
 
-	// Verify map has entries
-	rl.mu.Lock()
-	before := len(rl.lastSeen)
-	rl.mu.Unlock()
-	if before == 0 {
-		t.Fatal("expected entries in lastSeen map")
+func TestRateLimiter_Defaults(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{})
+	for i := 0; i < defaultRateLimits.Health.Burst; i++ {
+		if !rl.Allow("health", "10.0.0.1") {
+			t.Fatalf("request %d within default burst should be allowed", i+1)
+		}
 	}
+	if rl.Allow("health", "10.0.0.1") {
+		t.Fatal("request beyond default burst should be rate-limited")
+	}
+}
 
-	// Wait for entries to become stale (2× interval = 100ms)
-	time.Sleep(120 * time.Millisecond)
+func TestRateLimiter_SyncCapsTokens(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{Health: RateLimitPolicy{Rate: 1, Burst: 10}})
+	rl.Allow("health", "10.0.0.1") // 9 tokens left
 
-	rl.evictStale()
-
-	rl.mu.Lock()
-	after := len(rl.lastSeen)
-	rl.mu.Unlock()
-	if after != 0 {
-		t.Errorf("expected 0 entries after eviction, got %d", after)
+	rl.Sync(RateLimitConfig{Health: RateLimitPolicy{Rate: 1, Burst: 2}})
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if rl.Allow("health", "10.0.0.1") {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("allowed %d requests after lowering burst, want 2", allowed)
 	}
 }
 
-func TestRateLimiter_EvictStale_KeepsFresh(t *testing.T) {
-	rl := newRateLimiter(50 * time.Millisecond) // cutoff = 2×50ms = 100ms
+func TestRateLimiter_EvictStale(t *testing.T) {
+	rl, advance := newTestRateLimiter(RateLimitConfig{Health: RateLimitPolicy{Rate: 10, Burst: 5}})
 
-	rl.Allow("old-ip")
-	time.Sleep(120 * time.Millisecond) // old-ip is now stale (>100ms)
+	rl.Allow("health", "old-ip")
+	rl.Allow("health", "old-ip")
+	advance(200 * time.Millisecond) // old-ip is full again (3 + 2 tokens)
 
-	rl.Allow("fresh-ip") // fresh-ip just recorded
+	rl.Allow("health", "fresh-ip") // fresh-ip just took a token
 
 	rl.evictStale()
 
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	if _, exists := rl.lastSeen["old-ip"]; exists {
+	if _, exists := rl.buckets[bucketKey{"health", "old-ip"}]; exists {
 		t.Error("old-ip should have been evicted")
 	}
-	if _, exists := rl.lastSeen["fresh-ip"]; !exists {
+	if _, exists := rl.buckets[bucketKey{"health", "fresh-ip"}]; !exists {
 		t.Error("fresh-ip should have been kept")
 	}
 }
 
 func TestRateLimiter_StartCleanup(t *testing.T) {
-	rl := newRateLimiter(10 * time.Millisecond)
+	rl := newRateLimiter(RateLimitConfig{Health: RateLimitPolicy{Rate: 100, Burst: 1}})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	rl.startCleanup(ctx, 50*time.Millisecond)
 
-	rl.Allow("auto-clean-ip")
+	rl.Allow("health", "auto-clean-ip")
 
 	// Wait long enough for at least one cleanup pass
 	time.Sleep(100 * time.Millisecond)
 
 	rl.mu.Lock()
-	count := len(rl.lastSeen)
+	count := len(rl.buckets)
 	rl.mu.Unlock()
 
 	if count != 0 {
@@ -179,6 +204,28 @@ func TestRateLimiter_StartCleanup(t *testing.T) {
 	time.Sleep(20 * time.Millisecond)
 }
 
+func TestAllowRate_RetryAfter(t *testing.T) {
+	s := &Server{rateLimiter: newRateLimiter(RateLimitConfig{StatusWake: RateLimitPolicy{Rate: 0.5, Burst: 1}})}
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/_status/wake", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		s.allowRate(w, r, "status_wake")
+		return w
+	}
+
+	if w := call(); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d", w.Code)
+	}
+	w := call()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+}
+
 // ─── Trusted Proxy ────────────────────────────────────────────────────────────
 
 func TestParseTrustedProxies(t *testing.T) {
@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// readyzTimeout bounds the Docker ping of /_gateway/readyz, so a hung daemon
+// fails the probe instead of stalling it.
+const readyzTimeout = 2 * time.Second
+
+// selfCheckResponse is the body of /_gateway/healthz and /_gateway/readyz.
+type selfCheckResponse struct {
+	Status string            `json:"status"`           // "ok" or "unavailable"
+	Checks map[string]string `json:"checks,omitempty"` // check name → "ok" or the failure
+}
+
+// handleGatewayHealthz reports that the gateway process is alive and serving
+// HTTP. It deliberately does not touch Docker: a daemon outage should take the
+// gateway out of rotation (readyz), not get it restarted.
+func (s *Server) handleGatewayHealthz(w http.ResponseWriter, r *http.Request) {
+	writeSelfCheck(w, selfCheckResponse{Status: "ok"})
+}
+
+// handleGatewayReadyz reports whether the gateway can serve traffic: a
+// configuration is loaded and the Docker daemon answers a ping.
+// Unlike /_health it is not rate limited, since orchestrators and load
+// balancers probe it from a single address.
+func (s *Server) handleGatewayReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := selfCheckResponse{Status: "ok", Checks: map[string]string{"config": "ok", "docker": "ok"}}
+
+	if s.GetConfig() == nil {
+		resp.Checks["config"] = "not loaded"
+		resp.Status = "unavailable"
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyzTimeout)
+	defer cancel()
+	if err := s.manager.client.Ping(ctx); err != nil {
+		slog.DebugContext(r.Context(), "readiness check: docker unreachable", "error", err)
+		resp.Checks["docker"] = "unreachable"
+		resp.Status = "unavailable"
+	}
+
+	writeSelfCheck(w, resp)
+}
+
+// writeSelfCheck writes resp with 200 when it is ok and 503 otherwise.
+func writeSelfCheck(w http.ResponseWriter, resp selfCheckResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if resp.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
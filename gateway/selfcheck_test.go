@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+// newTestDockerClient returns a DockerClient talking to the given test daemon.
+func newTestDockerClient(t *testing.T, daemonURL string) *DockerClient {
+	t.Helper()
+	cli, err := client.NewClientWithOpts(client.WithHost(daemonURL), client.WithVersion("1.43"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return &DockerClient{cli: cli}
+}
+
+func TestHandleGatewayHealthz(t *testing.T) {
+	s := &Server{cfg: &GatewayConfig{}}
+	w := httptest.NewRecorder()
+	s.handleGatewayHealthz(w, httptest.NewRequest(http.MethodGet, "/_gateway/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestHandleGatewayReadyz(t *testing.T) {
+	var daemonUp atomic.Bool
+	daemonUp.Store(true)
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !daemonUp.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("API-Version", "1.43")
+		w.Write([]byte("OK"))
+	}))
+	defer daemon.Close()
+
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String()))}
+	readyz := func() (int, selfCheckResponse) {
+		w := httptest.NewRecorder()
+		s.handleGatewayReadyz(w, httptest.NewRequest(http.MethodGet, "/_gateway/readyz", nil))
+		var resp selfCheckResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return w.Code, resp
+	}
+
+	if code, resp := readyz(); code != http.StatusOK || resp.Status != "ok" {
+		t.Errorf("daemon up: %d %+v, want 200 ok", code, resp)
+	}
+
+	daemonUp.Store(false)
+	code, resp := readyz()
+	if code != http.StatusServiceUnavailable || resp.Checks["docker"] != "unreachable" || resp.Checks["config"] != "ok" {
+		t.Errorf("daemon down: %d %+v, want 503 with docker unreachable", code, resp)
+	}
+}
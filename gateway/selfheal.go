@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// selfHealTick is the granularity of the self-healing loop. Per-container
+// self_heal_interval values are rounded up to a multiple of it.
+const selfHealTick = 5 * time.Second
+
+// selfHealState tracks the health-check history of a single container.
+type selfHealState struct {
+	lastCheck time.Time
+	failures  int // consecutive failed checks while Docker reports "running"
+	restarts  int // restarts since the container last passed a check
+}
+
+// selfHealer holds per-container self-healing state.
+type selfHealer struct {
+	mu     sync.Mutex
+	states map[string]*selfHealState
+}
+
+func newSelfHealer() *selfHealer {
+	return &selfHealer{states: make(map[string]*selfHealState)}
+}
+
+// due reports whether a container's check interval elapsed and marks it checked.
+func (sh *selfHealer) due(name string, interval time.Duration, now time.Time) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	st, ok := sh.states[name]
+	if !ok {
+		st = &selfHealState{}
+		sh.states[name] = st
+	}
+	if now.Sub(st.lastCheck) < interval {
+		return false
+	}
+	st.lastCheck = now
+	return true
+}
+
+// recordPass clears the failure streak and the restart budget.
+func (sh *selfHealer) recordPass(name string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if st, ok := sh.states[name]; ok {
+		st.failures = 0
+		st.restarts = 0
+	}
+}
+
+// recordFailure extends the failure streak and reports whether a restart
+// should be attempted: the streak reached threshold and the restart budget is
+// not exhausted. exhausted is true (once per streak) when the streak reached
+// threshold but no restarts are left.
+func (sh *selfHealer) recordFailure(name string, threshold, maxRestarts int) (restart, exhausted bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	st, ok := sh.states[name]
+	if !ok {
+		st = &selfHealState{}
+		sh.states[name] = st
+	}
+	st.failures++
+	if st.failures < threshold {
+		return false, false
+	}
+	if st.restarts >= maxRestarts {
+		return false, st.failures == threshold
+	}
+	st.failures = 0
+	st.restarts++
+	return true, false
+}
+
+// restarts returns how many self-healing restarts happened since the
+// container last passed a health check.
+func (sh *selfHealer) restarts(name string) int {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if st, ok := sh.states[name]; ok {
+		return st.restarts
+	}
+	return 0
+}
+
+// SelfHealRestarts returns the number of self-healing restarts since the
+// container last passed a background health check.
+func (m *ContainerManager) SelfHealRestarts(name string) int {
+	return m.selfHeal.restarts(name)
+}
+
+// StartSelfHealer begins a background routine that re-runs the readiness check
+// against running containers with self_heal_interval > 0. When the check
+// fails self_heal_failures times in a row while Docker still reports
+// "running", the container is restarted (at most self_heal_max_restarts times
+// until it passes a check again).
+func (m *ContainerManager) StartSelfHealer(ctx context.Context, configProvider func() []ContainerConfig) {
+	go func() {
+		ticker := time.NewTicker(selfHealTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkSelfHeal(ctx, configProvider())
+			}
+		}
+	}()
+}
+
+func (m *ContainerManager) checkSelfHeal(ctx context.Context, cfgs []ContainerConfig) {
+	now := time.Now()
+	for i := range cfgs {
+		cfg := cfgs[i]
+		if cfg.SelfHealInterval <= 0 || !m.selfHeal.due(cfg.Name, cfg.SelfHealInterval, now) {
+			continue
+		}
+		// Leave containers alone while the gateway is starting them.
+		if status, _ := m.GetStartState(cfg.Name); status == string(statusStarting) {
+			continue
+		}
+		if status, err := m.client.GetContainerStatus(ctx, cfg.Name); err != nil || status != "running" {
+			continue
+		}
+
+		if m.healthCheckOnce(ctx, &cfg) {
+			m.selfHeal.recordPass(cfg.Name)
+			continue
+		}
+
+		RecordHealthCheckFailure(cfg.Name)
+		restart, exhausted := m.selfHeal.recordFailure(cfg.Name, cfg.SelfHealFailures, cfg.SelfHealMaxRestarts)
+		if exhausted {
+			slog.Error("self-heal: restart budget exhausted, container left running",
+				"container", cfg.Name, "max_restarts", cfg.SelfHealMaxRestarts)
+			continue
+		}
+		if !restart {
+			continue
+		}
+		if m.ReadOnly() {
+			slog.Warn("self-heal: container stopped responding, restart skipped: the gateway is read-only",
+				"container", cfg.Name, "failures", cfg.SelfHealFailures)
+			continue
+		}
+		if m.DryRun() {
+			m.simulate(dryRunRestart, cfg.Name,
+				fmt.Sprintf("self-heal after %d failed health checks", cfg.SelfHealFailures), now)
+			continue
+		}
+		slog.Warn("self-heal: container stopped responding",
+			"container", cfg.Name, "failures", cfg.SelfHealFailures)
+		m.emit(EventSelfHealRestart, cfg.Name,
+			fmt.Sprintf("restarting after %d failed health checks", cfg.SelfHealFailures))
+		restartCfg := cfg
+		go func() {
+			err := m.restartContainer(&restartCfg, "self_heal")
+			RecordSelfHealRestart(restartCfg.Name, err == nil)
+		}()
+	}
+}
+
+// healthCheckOnce runs a single readiness check against a running container.
+func (m *ContainerManager) healthCheckOnce(ctx context.Context, cfg *ContainerConfig) bool {
+	opts := ProbeOptionsFor(cfg)
+	checkCtx, cancel := context.WithTimeout(ctx, opts.timeout()+time.Second)
+	defer cancel()
+
+	host, port, err := m.resolveTarget(checkCtx, cfg)
+	if err != nil {
+		return false
+	}
+	ready, err := m.checkReady(checkCtx, cfg, host, port, opts)
+	return err == nil && ready
+}
@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// ─── selfHealer ───────────────────────────────────────────────────────────────
+
+func TestSelfHealer_Due(t *testing.T) {
+	sh := newSelfHealer()
+	now := time.Now()
+	if !sh.due("app", 30*time.Second, now) {
+		t.Fatal("first check should always be due")
+	}
+	if sh.due("app", 30*time.Second, now.Add(10*time.Second)) {
+		t.Error("check should not be due before the interval elapsed")
+	}
+	if !sh.due("app", 30*time.Second, now.Add(30*time.Second)) {
+		t.Error("check should be due once the interval elapsed")
+	}
+}
+
+func TestSelfHealer_RecordFailure(t *testing.T) {
+	t.Run("restarts after threshold failures", func(t *testing.T) {
+		sh := newSelfHealer()
+		for i := 0; i < 2; i++ {
+			if restart, _ := sh.recordFailure("app", 3, 2); restart {
+				t.Fatalf("restart requested after %d failures", i+1)
+			}
+		}
+		if restart, _ := sh.recordFailure("app", 3, 2); !restart {
+			t.Fatal("restart should be requested at threshold")
+		}
+		if sh.restarts("app") != 1 {
+			t.Errorf("restarts() = %d, want 1", sh.restarts("app"))
+		}
+	})
+
+	t.Run("restart budget is bounded", func(t *testing.T) {
+		sh := newSelfHealer()
+		restarts := 0
+		exhaustedReports := 0
+		for i := 0; i < 10; i++ {
+			restart, exhausted := sh.recordFailure("app", 1, 2)
+			if restart {
+				restarts++
+			}
+			if exhausted {
+				exhaustedReports++
+			}
+		}
+		if restarts != 2 {
+			t.Errorf("restarts = %d, want 2", restarts)
+		}
+		if exhaustedReports != 1 {
+			t.Errorf("exhausted reported %d times, want 1", exhaustedReports)
+		}
+	})
+
+	t.Run("pass resets the budget", func(t *testing.T) {
+		sh := newSelfHealer()
+		sh.recordFailure("app", 1, 1)
+		sh.recordPass("app")
+		if sh.restarts("app") != 0 {
+			t.Errorf("restarts() = %d, want 0 after pass", sh.restarts("app"))
+		}
+		if restart, _ := sh.recordFailure("app", 1, 1); !restart {
+			t.Error("restart should be allowed again after a pass")
+		}
+	})
+}
+
+func TestCheckSelfHeal_SkipsDisabledContainers(t *testing.T) {
+	// A nil Docker client would panic if the loop touched it.
+	m := NewContainerManager(NewFakeRuntime())
+	m.checkSelfHeal(context.Background(), []ContainerConfig{
+		{Name: "app", Host: "app.local"},
+	})
+}
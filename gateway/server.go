@@ -1,45 +1,82 @@
 package gateway
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log/slog"
+	"math"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/crypto/acme/autocert"
 )
 
-const gatewayVersion = "0.3.0"
-
 //go:embed templates/*.html
 var templatesFS embed.FS
 
 // Server handles HTTP traffic for the gateway.
 type Server struct {
-	manager      *ContainerManager
-	configMu     sync.RWMutex
-	cfg          *GatewayConfig
-	hostIndex    map[string]*ContainerConfig
-	groupIndex   map[string]*GroupConfig
-	containerMap map[string]*ContainerConfig
-	trustedCIDRs []*net.IPNet
-	tmpl         *template.Template
-	rateLimiter  *rateLimiter
-	groupRouter  *GroupRouter
-	scheduler    *ScheduleManager
-	schedLoc     *time.Location // resolved from gateway.schedule_timezone; never nil (defaults to time.Local)
-	httpServer   *http.Server
+	manager       *ContainerManager
+	configMu      sync.RWMutex
+	cfg           *GatewayConfig
+	hostIndex     map[string]*ContainerConfig
+	prefixIndex   map[string][]*ContainerConfig
+	groupIndex    map[string]*GroupConfig
+	containerMap  map[string]*ContainerConfig
+	trustedCIDRs  []*net.IPNet
+	tmpl          *template.Template
+	rateLimiter   *rateLimiter
+	middlewares   map[string]Middleware // built from gateway.middlewares
+	mwLimiter     *rateLimiter          // buckets of the rate_limit middlewares
+	bans          *BanList
+	clientLimiter *clientLimiter
+	accessLog     *AccessLogger
+	groupRouter   *GroupRouter
+	affinityKey   []byte     // signs affinity cookies without gateway.affinity_secret
+	icons         *iconCache // proxied icon_url images
+	reloads       *reloadLog // outcome of the last reloads, for /_admin/reload/status
+	reloadMu      sync.Mutex // serialises ReloadConfig
+	scheduler     *ScheduleManager
+	schedLoc      *time.Location // resolved from gateway.schedule_timezone; never nil (defaults to time.Local)
+	handler       atomic.Value   // http.Handler built by buildHandler
+	tunnels       tunnelSet
+	tcp           tcpProxy // listeners of tcp_routes, bound by Start; not hot-reloaded
+	// The static configuration /_api/v1/containers edits, and how a changed
+	// one is applied: through discovery when run by a Gateway.
+	staticConfig func() *GatewayConfig
+	applyStatic  func(cfg *GatewayConfig, trigger string) error
+	apiMu        sync.Mutex // serialises /_api/v1 changes
+
+	listenMu   sync.Mutex
+	httpServer *http.Server
+	listenAddr net.Addr
+	listener   net.Listener     // from WithListener; served instead of binding gateway.port
+	srvCfg     HTTPServerConfig // gateway.server as of Start; not hot-reloaded
+	serveErr   chan error
+	// HTTPS with gateway.acme, set up by Start; not hot-reloaded
+	acme        *autocert.Manager
+	acmeCfg     ACMEConfig
+	httpsServer *http.Server
+	tlsAddr     net.Addr
 }
 
 func NewServer(manager *ContainerManager, scheduler *ScheduleManager, cfg *GatewayConfig) (*Server, error) {
@@ -50,97 +87,364 @@ func NewServer(manager *ContainerManager, scheduler *ScheduleManager, cfg *Gatew
 
 	loc, _ := resolveLocation(cfg.Gateway.ScheduleTimezone) // already validated; error impossible
 
-	return &Server{
-		manager:      manager,
-		scheduler:    scheduler,
-		schedLoc:     loc,
-		cfg:          cfg,
-		hostIndex:    BuildHostIndex(cfg),
-		groupIndex:   BuildGroupHostIndex(cfg),
-		containerMap: BuildContainerMap(cfg),
-		trustedCIDRs: parseTrustedProxies(cfg.Gateway.TrustedProxies),
-		tmpl:         tmpl,
-		rateLimiter:  newRateLimiter(1 * time.Second),
-		groupRouter:  NewGroupRouter(),
-	}, nil
+	accessLog := NewAccessLogger(os.Stdout)
+	accessLog.Sync(cfg.Gateway.AccessLog)
+
+	bans := NewBanList()
+	bans.Sync(cfg.Gateway.AutoBan)
+
+	rateLimiter := newRateLimiter(cfg.Gateway.RateLimits)
+	rateLimiter.shared = manager.SharedState()
+
+	s := &Server{
+		manager:       manager,
+		scheduler:     scheduler,
+		schedLoc:      loc,
+		cfg:           cfg,
+		hostIndex:     BuildHostIndex(cfg),
+		prefixIndex:   BuildPathPrefixIndex(cfg),
+		groupIndex:    BuildGroupHostIndex(cfg),
+		containerMap:  BuildContainerMap(cfg),
+		trustedCIDRs:  parseTrustedProxies(cfg.Gateway.TrustedProxies),
+		tmpl:          tmpl,
+		rateLimiter:   rateLimiter,
+		mwLimiter:     newMiddlewareLimiter(manager.SharedState()),
+		bans:          bans,
+		clientLimiter: newClientLimiter(),
+		accessLog:     accessLog,
+		groupRouter:   NewGroupRouter(),
+		affinityKey:   newAffinityKey(),
+		icons:         newIconCache(),
+		reloads:       newReloadLog(),
+	}
+	middlewares, policies, err := s.buildMiddlewares(cfg.Gateway.Middlewares)
+	if err != nil {
+		return nil, err
+	}
+	s.middlewares = middlewares
+	s.mwLimiter.setPolicies(policies)
+	s.staticConfig = s.GetConfig
+	s.applyStatic = s.reloadStatic
+	return s, nil
 }
 
 // Start listens for HTTP traffic and blocks until ctx is cancelled.
 // On cancellation it performs a graceful shutdown with a 15-second deadline.
 func (s *Server) Start(ctx context.Context) error {
+	cfg := s.GetConfig()
+	s.handler.Store(s.buildHandler(cfg.Gateway.AdminAuth, cfg.Gateway.Tenants))
+
+	s.listenMu.Lock()
+	s.srvCfg = cfg.Gateway.Server
+	s.serveErr = make(chan error, 1)
+	if cfg.Gateway.ACME.Enabled {
+		s.acmeCfg = cfg.Gateway.ACME
+		s.acme = newACMEManager(s.acmeCfg, s.acmeHostPolicy)
+	}
+	srv, err := s.listen(cfg.Gateway.Port)
+	if err != nil {
+		s.listenMu.Unlock()
+		return err
+	}
+	s.httpServer = srv
+	if s.acme != nil {
+		if s.httpsServer, err = s.listenTLS(s.acmeCfg.HTTPSPort); err != nil {
+			srv.Close()
+			s.listenMu.Unlock()
+			return err
+		}
+	}
+	if err := s.listenTCPRoutes(cfg.TCPRoutes); err != nil {
+		srv.Close()
+		if s.httpsServer != nil {
+			s.httpsServer.Close()
+		}
+		s.listenMu.Unlock()
+		return err
+	}
+	s.listenMu.Unlock()
+
+	// Start rate limiter cleanup goroutine
+	s.rateLimiter.startCleanup(ctx, 5*time.Minute)
+	s.mwLimiter.startCleanup(ctx, 5*time.Minute)
+	s.bans.startCleanup(ctx, time.Minute)
+
+	slog.Info("gateway started", "version", Version, "port", cfg.Gateway.Port,
+		"max_connections", cfg.Gateway.Server.MaxConnections)
+	if s.acme != nil {
+		slog.Info("serving HTTPS with ACME certificates", "https_port", s.acmeCfg.HTTPSPort,
+			"cache_dir", s.acmeCfg.CacheDir, "redirect_http", s.acmeCfg.RedirectHTTP)
+	}
+
+	// Block until the root context is cancelled or Serve fails.
+	select {
+	case err := <-s.serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Graceful shutdown with a 15-second deadline.
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownGrace)
+	defer shutdownCancel()
+
+	slog.Info("shutting down gateway", "grace_period", shutdownGrace)
+	s.listenMu.Lock()
+	srv = s.httpServer
+	httpsSrv := s.httpsServer
+	s.listenMu.Unlock()
+	tunnelsClosed := make(chan struct{})
+	go func() {
+		s.tunnels.closeAll(shutdownCtx)
+		close(tunnelsClosed)
+	}()
+	tcpClosed := make(chan struct{})
+	go func() {
+		s.tcp.close(shutdownCtx)
+		close(tcpClosed)
+	}()
+	httpsClosed := make(chan struct{})
+	go func() {
+		if httpsSrv != nil {
+			httpsSrv.Shutdown(shutdownCtx) //nolint:errcheck
+		}
+		close(httpsClosed)
+	}()
+	err = srv.Shutdown(shutdownCtx)
+	<-tunnelsClosed
+	<-tcpClosed
+	<-httpsClosed
+	s.accessLog.Close()
+	return err
+}
+
+// shutdownGrace bounds how long in-flight requests may take to finish when
+// the gateway stops or moves to a new port, and WebSocket tunnels to close
+// when it stops.
+const shutdownGrace = 15 * time.Second
+
+// buildHandler assembles the gateway's routes, with the admin endpoints
+// behind auth. The dashboard endpoints also accept the credentials of
+// tenants, scoped to their containers. It is rebuilt when admin_auth or
+// tenants change on reload.
+func (s *Server) buildHandler(auth AdminAuthConfig, tenants []TenantConfig) http.Handler {
 	mux := http.NewServeMux()
 
 	// ── Functional endpoints (NOT protected by auth) ──
 	mux.HandleFunc("/_health", s.handleHealth)
 	mux.HandleFunc("/_logs", s.handleLogs)
+	mux.HandleFunc("/_gateway/healthz", s.handleGatewayHealthz)
+	mux.HandleFunc("/_gateway/readyz", s.handleGatewayReadyz)
 
 	// ── Admin endpoints (protected by optional auth middleware) ──
-	authCfg := &s.GetConfig().Gateway.AdminAuth
-	mux.Handle("/_status", adminAuthMiddleware(
-		http.HandlerFunc(s.handleStatusPage), authCfg))
-	mux.Handle("/_status/api", adminAuthMiddleware(
-		http.HandlerFunc(s.handleStatusAPI), authCfg))
-	mux.Handle("/_status/wake", adminAuthMiddleware(
-		http.HandlerFunc(s.handleStatusWake), authCfg))
-	mux.Handle("/_metrics", adminAuthMiddleware(
-		promhttp.Handler(), authCfg))
-	mux.Handle("/_topology", adminAuthMiddleware(
-		http.HandlerFunc(s.handleTopology), authCfg))
+	// Failed logins count as auto_ban strikes. Tenant credentials reach the
+	// scoped endpoints only.
+	admin := func(h http.Handler) http.Handler {
+		return s.strikeOnUnauthorized(tenantAuthMiddleware(h, adminAuthMiddleware(h, &auth), tenants, false))
+	}
+	scoped := func(h http.Handler) http.Handler {
+		return s.strikeOnUnauthorized(tenantAuthMiddleware(h, adminAuthMiddleware(h, &auth), tenants, true))
+	}
+	mux.Handle("/_status", scoped(
+		http.HandlerFunc(s.handleStatusPage)))
+	mux.Handle("/_status/api", scoped(
+		http.HandlerFunc(s.handleStatusAPI)))
+	mux.Handle("/_status/api/containers/", scoped(
+		http.HandlerFunc(s.handleStatusContainer)))
+	mux.Handle("/_status/wake", scoped(
+		http.HandlerFunc(s.handleStatusWake)))
+	mux.Handle("/_status/sleep", scoped(
+		http.HandlerFunc(s.handleStatusSleep)))
+	mux.Handle("/_status/kill", scoped(
+		http.HandlerFunc(s.handleStatusKill)))
+	mux.Handle("/_status/reset", scoped(
+		http.HandlerFunc(s.handleStatusReset)))
+	mux.Handle("/_status/disk", admin(
+		http.HandlerFunc(s.handleStatusDisk)))
+	mux.Handle("/_status/disk/prune", admin(
+		http.HandlerFunc(s.handleStatusDiskPrune)))
+	mux.Handle("/_status/state", admin(
+		http.HandlerFunc(s.handleStatusState)))
+	mux.Handle("/_status/schema", admin(
+		http.HandlerFunc(s.handleConfigSchema)))
+	mux.Handle("/_status/routes", admin(
+		http.HandlerFunc(s.handleStatusRoutes)))
+	mux.Handle("/_status/bans", admin(
+		http.HandlerFunc(s.handleStatusBans)))
+	mux.Handle(iconPathPrefix, scoped(
+		http.HandlerFunc(s.handleStatusIcon)))
+	mux.Handle("/_admin/loglevel", admin(
+		http.HandlerFunc(s.handleAdminLogLevel)))
+	mux.Handle("/_admin/reload/status", admin(
+		http.HandlerFunc(s.handleReloadStatus)))
+	mux.Handle(adminAPIContainers, admin(
+		http.HandlerFunc(s.handleAPIContainers)))
+	mux.Handle(adminAPIContainers+"/", admin(
+		http.HandlerFunc(s.handleAPIContainers)))
+	mux.Handle("/_metrics", admin(
+		promhttp.Handler()))
+	mux.Handle("/_version", admin(
+		http.HandlerFunc(s.handleVersion)))
+	mux.Handle("/_topology", scoped(
+		http.HandlerFunc(s.handleTopology)))
+	mux.Handle(pprofPrefix, admin(
+		http.HandlerFunc(s.handlePprof)))
 
 	// ── Catch-all ──
 	mux.HandleFunc("/", s.handleRequest)
 
-	s.httpServer = &http.Server{
-		Addr:         ":" + s.GetConfig().Gateway.Port,
-		Handler:      mux,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  120 * time.Second,
-	}
+	return s.forwardedMiddleware(s.requestIDMiddleware(s.banMiddleware(s.clientLimitMiddleware(mux))))
+}
 
-	// Start rate limiter cleanup goroutine
-	s.rateLimiter.startCleanup(ctx, 5*time.Minute)
+// ServeHTTP dispatches to the current handler, so a rebuilt one takes over
+// from the next request without touching open connections.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.Load().(http.Handler).ServeHTTP(w, r)
+}
 
-	// Run ListenAndServe in a goroutine so we can wait for ctx cancellation.
-	errCh := make(chan error, 1)
+// listen binds port and serves on it in the background with the gateway.server
+// settings of Start. The caller holds listenMu.
+func (s *Server) listen(port string) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           s.plainHandler(),
+		ReadHeaderTimeout: s.srvCfg.ReadHeaderTimeout,
+		ReadTimeout:       s.srvCfg.ReadTimeout,
+		WriteTimeout:      s.srvCfg.WriteTimeout,
+		IdleTimeout:       s.srvCfg.IdleTimeout,
+		MaxHeaderBytes:    s.srvCfg.MaxHeaderBytes,
+		ConnState:         trackConnState,
+	}
+	ln := s.listener
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", srv.Addr); err != nil {
+			return nil, err
+		}
+	} else {
+		srv.Addr = ln.Addr().String()
+	}
+	s.listenAddr = ln.Addr()
+	ln = newLimitListener(ln, s.srvCfg.MaxConnections)
+
+	serveErr := s.serveErr
 	go func() {
-		slog.Info("gateway started", "version", gatewayVersion, "port", s.GetConfig().Gateway.Port)
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			errCh <- err
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			select {
+			case serveErr <- err:
+			default:
+			}
 		}
-		close(errCh)
 	}()
+	return srv, nil
+}
 
-	// Block until the root context is cancelled or ListenAndServe fails.
-	select {
-	case err := <-errCh:
-		return err
-	case <-ctx.Done():
+// rebind moves the gateway to a new port: the new listener is bound first,
+// then the old one stops accepting and its in-flight requests drain for up
+// to shutdownGrace. If the new port cannot be bound the old one is kept and
+// the error returned.
+func (s *Server) rebind(port string) error {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	if s.httpServer == nil {
+		return nil // not started yet; Start binds the configured port
+	}
+	if s.listener != nil {
+		slog.Warn("reload: gateway.port ignored, the gateway serves on the listener it was given",
+			"addr", s.listener.Addr().String())
+		return nil
+	}
+	srv, err := s.listen(port)
+	if err != nil {
+		return fmt.Errorf("cannot listen on port %s: %w", port, err)
 	}
+	old := s.httpServer
+	s.httpServer = srv
+	slog.Info("reload: listening on the new port, draining the old one",
+		"port", port, "old_addr", old.Addr, "grace_period", shutdownGrace)
 
-	// Graceful shutdown with a 15-second deadline.
-	const shutdownGrace = 15 * time.Second
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownGrace)
-	defer shutdownCancel()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer cancel()
+		if err := old.Shutdown(ctx); err != nil {
+			slog.Warn("reload: old listener did not drain in time", "addr", old.Addr, "error", err)
+		}
+	}()
+	return nil
+}
 
-	slog.Info("shutting down gateway", "grace_period", shutdownGrace)
-	return s.httpServer.Shutdown(shutdownCtx)
+// Addr returns the address the gateway is listening on, or nil before Start.
+func (s *Server) Addr() net.Addr {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	return s.listenAddr
+}
+
+// writeTimeout returns gateway.server.write_timeout as applied by Start.
+func (s *Server) writeTimeout() time.Duration {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	return s.srvCfg.WriteTimeout
 }
 
 // ─── Config Hot-Reload ────────────────────────────────────────────────────────
 
-// ReloadConfig safely swaps the active configuration.
-func (s *Server) ReloadConfig(newCfg *GatewayConfig) {
+// ReloadConfig swaps the active configuration atomically. The steps that can
+// fail — building the middlewares, binding a new gateway.port — run first;
+// if one fails the error is returned and the previous configuration stays
+// active, untouched. A new port is bound before the old one is drained, and
+// a new admin_auth applies from the next request.
+func (s *Server) ReloadConfig(newCfg *GatewayConfig) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	middlewares, policies, err := s.buildMiddlewares(newCfg.Gateway.Middlewares)
+	if err != nil {
+		return err
+	}
+	oldCfg := s.GetConfig()
+	if newCfg.Gateway.Port != oldCfg.Gateway.Port {
+		if err := s.rebind(newCfg.Gateway.Port); err != nil {
+			return err
+		}
+	}
+
 	s.configMu.Lock()
-	defer s.configMu.Unlock()
+	s.forgetRemoved(oldCfg, newCfg)
 	s.cfg = newCfg
 	loc, _ := resolveLocation(newCfg.Gateway.ScheduleTimezone)
 	s.schedLoc = loc
 	s.hostIndex = BuildHostIndex(newCfg)
+	s.prefixIndex = BuildPathPrefixIndex(newCfg)
 	s.groupIndex = BuildGroupHostIndex(newCfg)
 	s.containerMap = BuildContainerMap(newCfg)
 	s.trustedCIDRs = parseTrustedProxies(newCfg.Gateway.TrustedProxies)
+	s.accessLog.Sync(newCfg.Gateway.AccessLog)
+	s.rateLimiter.Sync(newCfg.Gateway.RateLimits)
+	s.middlewares = middlewares
+	s.mwLimiter.setPolicies(policies)
+	s.bans.Sync(newCfg.Gateway.AutoBan)
 	s.scheduler.Sync(newCfg.Containers, s.schedLoc)
+	s.manager.SyncNetworkAttach(newCfg.Gateway.NetworkAttach)
+	s.manager.SyncWakeOnLAN(newCfg.Gateway.WakeOnLAN)
+	s.manager.SyncHA(newCfg.Gateway.HA)
+	s.manager.SetReadOnly(newCfg.Gateway.ReadOnly)
+	s.manager.SetDryRun(newCfg.Gateway.DryRun)
+	s.configMu.Unlock()
+
+	authChanged := newCfg.Gateway.AdminAuth != oldCfg.Gateway.AdminAuth ||
+		!reflect.DeepEqual(newCfg.Gateway.Tenants, oldCfg.Gateway.Tenants)
+	if authChanged && s.handler.Load() != nil {
+		s.handler.Store(s.buildHandler(newCfg.Gateway.AdminAuth, newCfg.Gateway.Tenants))
+		slog.Info("reload: admin auth updated", "method", newCfg.Gateway.AdminAuth.Method,
+			"tenants", len(newCfg.Gateway.Tenants))
+	}
+	if newCfg.Gateway.ReadOnly != oldCfg.Gateway.ReadOnly {
+		slog.Info("reload: read-only mode changed", "read_only", newCfg.Gateway.ReadOnly)
+	}
+	if newCfg.Gateway.DryRun != oldCfg.Gateway.DryRun {
+		slog.Info("reload: dry-run mode changed", "dry_run", newCfg.Gateway.DryRun)
+	}
+	return nil
 }
 
 // GetConfig safely retrieves the current configuration.
@@ -150,32 +454,154 @@ func (s *Server) GetConfig() *GatewayConfig {
 	return s.cfg
 }
 
+// forgetRemoved deletes the metric series and runtime history of containers
+// and groups that are present in oldCfg but missing from newCfg, so /_metrics
+// does not keep reporting services that are gone.
+func (s *Server) forgetRemoved(oldCfg, newCfg *GatewayConfig) {
+	if oldCfg == nil {
+		return
+	}
+	for _, name := range removedNames(containerNames(oldCfg), containerNames(newCfg)) {
+		ForgetContainerMetrics(name)
+		s.manager.runtime.Forget(name)
+		s.manager.lifecycle.forget(name)
+		s.manager.bandwidth.Forget(name)
+		s.manager.requests.Forget(name)
+		s.manager.limiter.Forget(name)
+		slog.Debug("metrics: forgot removed container", "container", name)
+	}
+	for _, name := range removedNames(groupNames(oldCfg), groupNames(newCfg)) {
+		ForgetGroupMetrics(name)
+		slog.Debug("metrics: forgot removed group", "group", name)
+	}
+	icons := make(map[string]bool)
+	for _, c := range newCfg.Containers {
+		icons[c.IconURL] = true
+	}
+	s.icons.forget(icons)
+}
+
+func containerNames(cfg *GatewayConfig) []string {
+	names := make([]string, len(cfg.Containers))
+	for i, c := range cfg.Containers {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func groupNames(cfg *GatewayConfig) []string {
+	names := make([]string, len(cfg.Groups))
+	for i, g := range cfg.Groups {
+		names[i] = g.Name
+	}
+	return names
+}
+
+// removedNames returns the entries of before that are not in after.
+func removedNames(before, after []string) []string {
+	keep := make(map[string]bool, len(after))
+	for _, n := range after {
+		keep[n] = true
+	}
+	var removed []string
+	for _, n := range before {
+		if !keep[n] {
+			removed = append(removed, n)
+		}
+	}
+	return removed
+}
+
 // ─── Request routing ──────────────────────────────────────────────────────────
 
-// resolveConfig maps an incoming request to its ContainerConfig by Host header.
-// Returns nil if no container matches (groups are checked separately via resolveGroup).
+// resolveConfig maps an incoming request to its ContainerConfig by Host header
+// and path prefix. Returns nil if no container matches (groups are checked
+// separately via resolveGroup).
 func (s *Server) resolveConfig(r *http.Request) *ContainerConfig {
 	s.configMu.RLock()
 	defer s.configMu.RUnlock()
 
-	host := r.Host
+	if cfg := s.lookupPrefixedByName(r); cfg != nil {
+		return cfg
+	}
+	if cfg := s.lookupHost(r.Host, r.URL.Path); cfg != nil {
+		return cfg
+	}
+	return s.lookupOverride(r)
+}
+
+// resolveStatusConfig maps a /_health or /_logs request to its container.
+// On a group host the loading page names the member being woken with
+// ?container=, which is honoured for members of that group whatever
+// gateway.allow_container_query says.
+func (s *Server) resolveStatusConfig(r *http.Request) *ContainerConfig {
+	if cfg := s.resolveConfig(r); cfg != nil {
+		return cfg
+	}
+	group := s.resolveGroup(r)
+	if group == nil {
+		return nil
+	}
+	name := r.URL.Query().Get("container")
+	if !slices.Contains(group.Containers, name) {
+		return nil
+	}
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.containerMap[name]
+}
+
+// containerHeader names the target container when the Host header matches no
+// route, like the ?container= query parameter but without touching the URL.
+const containerHeader = "X-Dag-Container"
+
+// lookupOverride returns the container selected by the X-Dag-Container
+// header or, without one, the ?container= query parameter (handy for testing
+// without DNS). Both are ignored unless gateway.allow_container_query is set.
+// configMu must be held.
+func (s *Server) lookupOverride(r *http.Request) *ContainerConfig {
+	if !s.cfg.Gateway.AllowContainerQuery {
+		return nil
+	}
+	name := r.Header.Get(containerHeader)
+	if name == "" {
+		name = r.URL.Query().Get("container")
+	}
+	if name == "" {
+		return nil
+	}
+	for i := range s.cfg.Containers {
+		if s.cfg.Containers[i].Name == name {
+			return &s.cfg.Containers[i]
+		}
+	}
+	return nil
+}
+
+// lookupHost returns the container serving path on host: an exact host
+// match, then without the port, then the container named by
+// gateway.host_pattern. On each host a path_prefix container serving path
+// wins over the one without a prefix. configMu must be held.
+func (s *Server) lookupHost(host, path string) *ContainerConfig {
+	if cfg := s.lookupPrefix(host, path); cfg != nil {
+		return cfg
+	}
 	if cfg, ok := s.hostIndex[host]; ok {
 		return cfg
 	}
 	// Strip port and retry
 	if idx := strings.LastIndex(host, ":"); idx != -1 {
-		if cfg, ok := s.hostIndex[host[:idx]]; ok {
+		host = host[:idx]
+		if cfg := s.lookupPrefix(host, path); cfg != nil {
 			return cfg
 		}
-	}
-	// Query-param fallback for testing: ?container=my-app
-	if name := r.URL.Query().Get("container"); name != "" {
-		for i := range s.cfg.Containers {
-			if s.cfg.Containers[i].Name == name {
-				return &s.cfg.Containers[i]
-			}
+		if cfg, ok := s.hostIndex[host]; ok {
+			return cfg
 		}
 	}
+	if name := matchHostPattern(s.cfg.Gateway.HostPattern, host); name != "" {
+		return s.containerMap[name]
+	}
 	return nil
 }
 
@@ -200,6 +626,7 @@ func (s *Server) resolveGroup(r *http.Request) *GroupConfig {
 type metricsResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (m *metricsResponseWriter) WriteHeader(statusCode int) {
@@ -207,6 +634,19 @@ func (m *metricsResponseWriter) WriteHeader(statusCode int) {
 	m.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Write counts the response body bytes for the access log.
+func (m *metricsResponseWriter) Write(b []byte) (int, error) {
+	n, err := m.ResponseWriter.Write(b)
+	m.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, to flush
+// streamed responses and hijack WebSocket connections.
+func (m *metricsResponseWriter) Unwrap() http.ResponseWriter {
+	return m.ResponseWriter
+}
+
 // ─── Main handler ─────────────────────────────────────────────────────────────
 
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
@@ -215,41 +655,74 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, span := startServerSpan(r, "gateway.request")
+	defer span.End()
+	ctx = withTraceContext(ctx, r)
+	r = r.WithContext(ctx)
+	span.SetAttr("gateway.request_id", requestIDFrom(ctx, "req"))
+	span.SetAttr("http.request.method", r.Method)
+	span.SetAttr("url.path", r.URL.Path)
+	span.SetAttr("server.address", r.Host)
+
+	// Access log: one record per request routed to a container.
+	reqStart := time.Now()
+	entry := s.newAccessEntry(r)
+	aw := &metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	w = aw
+	var target *ContainerConfig
+	defer func() {
+		if !s.accessLog.Enabled(target) {
+			return
+		}
+		entry.Container = target.Name
+		entry.Status = aw.statusCode
+		entry.Bytes = aw.bytes
+		entry.Duration = time.Since(reqStart)
+		s.accessLog.Log(entry)
+	}()
+
 	// Try group routing first, then individual container.
+	_, routeSpan := StartSpan(ctx, "gateway.route")
 	if group := s.resolveGroup(r); group != nil {
-		s.handleGroupRequest(w, r, group)
+		routeSpan.SetAttr("gateway.group", group.Name)
+		routeSpan.End()
+		entry.Group = group.Name
+		s.withMiddlewares(group.Middlewares, func(w http.ResponseWriter, r *http.Request) {
+			target = s.handleGroupRequest(w, r, group)
+		}).ServeHTTP(w, r)
 		return
 	}
 
 	// Read cfg and schedLoc atomically under a single lock to
 	// prevent a concurrent hot-reload from swapping the config between reads.
 	s.configMu.RLock()
-	var cfg *ContainerConfig
-	host := r.Host
-	if c, ok := s.hostIndex[host]; ok {
-		cfg = c
-	} else if idx := strings.LastIndex(host, ":"); idx != -1 {
-		if c, ok := s.hostIndex[host[:idx]]; ok {
-			cfg = c
-		}
-	}
+	cfg := s.lookupHost(r.Host, r.URL.Path)
 	if cfg == nil {
-		if name := r.URL.Query().Get("container"); name != "" {
-			for i := range s.cfg.Containers {
-				if s.cfg.Containers[i].Name == name {
-					cfg = &s.cfg.Containers[i]
-					break
-				}
-			}
-		}
+		cfg = s.lookupOverride(r)
 	}
 	schedLoc := s.schedLoc
 	s.configMu.RUnlock()
 
 	if cfg == nil {
+		routeSpan.End()
+		s.bans.Strike(s.clientIP(r), strikeUnknownHost)
 		http.NotFound(w, r)
 		return
 	}
+	routeSpan.SetAttr("gateway.container", cfg.Name)
+	routeSpan.End()
+	target = cfg
+	span.SetAttr("gateway.container", cfg.Name)
+
+	s.withMiddlewares(cfg.Middlewares, func(w http.ResponseWriter, r *http.Request) {
+		s.serveContainer(w, r, cfg, schedLoc, span)
+	}).ServeHTTP(w, r)
+}
+
+// serveContainer handles a request routed to cfg once its middlewares let it
+// through: schedule gate, wake and proxy.
+func (s *Server) serveContainer(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig, schedLoc *time.Location, span *Span) {
+	ctx := r.Context()
 
 	// Determine effective timezone: per-container overrides global.
 	effectiveLoc := schedLoc
@@ -261,6 +734,10 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 
 	// Schedule gate: block access outside the configured cron window.
 	if allowed, nextStart := IsInScheduleWindow(cfg, time.Now(), effectiveLoc); !allowed {
+		if s.serveRobotsTxt(w, r) {
+			return
+		}
+		span.SetAttr("gateway.outcome", "outside_schedule")
 		s.serveScheduledPage(w, r, cfg, nextStart, effectiveLoc)
 		return
 	}
@@ -272,11 +749,17 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		duration := time.Since(start).Seconds()
 		RecordRequest(cfg.Name, strconv.Itoa(mw.statusCode), duration)
+		span.SetAttr("http.response.status_code", mw.statusCode)
 	}()
 
-	ctx := r.Context()
 	status, err := s.manager.client.GetContainerStatus(ctx, cfg.Name)
+	if err != nil && s.manager.DockerHostAsleep(ctx) {
+		// The Docker host is suspended: the wake powers it on first.
+		span.SetAttr("gateway.docker_host_asleep", true)
+		status, err = "host_asleep", nil
+	}
 	if err != nil {
+		span.SetError(err)
 		if strings.Contains(err.Error(), "No such container") {
 			s.serveErrorPage(mw, r, cfg, "Container not found in Docker daemon")
 		} else {
@@ -284,24 +767,45 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	if status != "running" && s.serveRobotsTxt(mw, r) {
+		span.SetAttr("gateway.outcome", "robots_txt")
+		return
+	}
 
 	if status == "running" {
-		// If there are dependencies, ensure they are running too.
-		if len(cfg.DependsOn) > 0 {
+		// If there are dependencies, ensure they are running too. A read-only
+		// gateway proxies regardless; a dry run records the wake and proxies.
+		if len(cfg.DependsOn) > 0 && !s.manager.ReadOnly() {
 			allContainers := s.GetConfig().Containers
 			for _, depName := range cfg.DependsOn {
 				depStatus, _ := s.manager.client.GetContainerStatus(ctx, depName)
+				if depStatus != "running" && s.manager.DryRun() {
+					s.manager.simulateWake(depName, "dependency of "+cfg.Name+" not running")
+					continue
+				}
 				if depStatus != "running" {
+					if verr := checkWake(r); verr != nil {
+						span.SetAttr("gateway.outcome", "wake_vetoed")
+						s.serveErrorPageStatus(mw, r, cfg, wakeVetoMessage(depName, verr), http.StatusServiceUnavailable)
+						return
+					}
 					// Dependency not running — trigger async start of deps + container
 					s.manager.InitStartState(cfg.Name)
+					span.SetAttr("gateway.outcome", "wake")
+					done := make(chan error, 1)
 					go func() {
-						bgCtx, cancel := context.WithTimeout(context.Background(), cfg.StartTimeout+10*time.Second)
+						bgCtx, cancel := context.WithTimeout(detachContext(ctx), s.manager.wakeTimeout(cfg))
 						defer cancel()
-						if err := s.manager.EnsureDepsRunning(bgCtx, cfg.Name, allContainers); err != nil {
-							slog.Error("dependency start error", "container", cfg.Name, "error", err)
+						err := s.manager.EnsureDepsRunning(bgCtx, cfg.Name, allContainers)
+						if err != nil {
+							requestLogger(bgCtx).Error("dependency start error", "container", cfg.Name, "error", err)
 						}
+						done <- err
 					}()
-					s.serveLoadingPage(mw, r, cfg)
+					s.serveWaking(mw, r, cfg, done, func(w http.ResponseWriter, r *http.Request) {
+						s.manager.RecordActivityChain([]string{cfg.Name}, allContainers)
+						s.proxyRequest(w, r, cfg)
+					})
 					return
 				}
 			}
@@ -312,31 +816,72 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.manager.ReadOnly() {
+		span.SetAttr("gateway.outcome", "read_only")
+		s.serveErrorPageStatus(mw, r, cfg, readOnlyMessage(cfg.Name, status), http.StatusServiceUnavailable)
+		return
+	}
+	if s.manager.DryRun() {
+		span.SetAttr("gateway.outcome", "dry_run")
+		s.manager.simulateWake(cfg.Name, "request for "+r.Host+r.URL.Path)
+		s.serveErrorPageStatus(mw, r, cfg, dryRunMessage(cfg.Name, status), http.StatusServiceUnavailable)
+		return
+	}
+
+	// Crash-looping container — don't hammer Docker, explain the backoff instead.
+	if looping, until, count := s.manager.CrashLoopState(cfg.Name); looping && time.Now().Before(until) {
+		span.SetAttr("gateway.outcome", "crash_loop")
+		mw.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+		s.serveCrashLoopPage(mw, r, cfg, crashLoopMessage(count, until))
+		return
+	}
+
+	if verr := checkWake(r); verr != nil {
+		span.SetAttr("gateway.outcome", "wake_vetoed")
+		s.serveErrorPageStatus(mw, r, cfg, wakeVetoMessage(cfg.Name, verr), http.StatusServiceUnavailable)
+		return
+	}
+
 	// Container not running — pre-set state and trigger async start (with deps)
 	s.manager.InitStartState(cfg.Name)
+	span.SetAttr("gateway.outcome", "wake")
+	done := make(chan error, 1)
 	go func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), cfg.StartTimeout+10*time.Second)
+		bgCtx, cancel := context.WithTimeout(detachContext(ctx), s.manager.wakeTimeout(cfg))
 		defer cancel()
-		allContainers := s.GetConfig().Containers
-		if len(cfg.DependsOn) > 0 {
-			if err := s.manager.EnsureDepsRunning(bgCtx, cfg.Name, allContainers); err != nil {
-				slog.Error("dependency start error", "container", cfg.Name, "error", err)
-				return
-			}
-		}
-		if err := s.manager.EnsureRunning(bgCtx, cfg); err != nil {
-			slog.Error("async start error", "container", cfg.Name, "error", err)
+		err := s.manager.Wake(bgCtx, cfg, s.GetConfig().Containers)
+		if err != nil {
+			requestLogger(bgCtx).Error("async start error", "container", cfg.Name, "error", err)
 		}
+		done <- err
 	}()
 
-	s.serveLoadingPage(mw, r, cfg)
+	s.serveWaking(mw, r, cfg, done, func(w http.ResponseWriter, r *http.Request) {
+		s.manager.RecordActivityChain([]string{cfg.Name}, s.GetConfig().Containers)
+		s.proxyRequest(w, r, cfg)
+	})
 }
 
 // handleGroupRequest handles requests routed to a container group.
-// It picks a member via round-robin and proxies (or serves loading page).
-func (s *Server) handleGroupRequest(w http.ResponseWriter, r *http.Request, group *GroupConfig) {
-	// Pick the target member for this request via round-robin.
-	pickedName := s.groupRouter.Pick(group)
+// It picks a member (the one its affinity cookie names, if any, or by the
+// group's strategy) and proxies (or serves loading page).
+// It returns the picked member, or nil if it is missing from the config.
+func (s *Server) handleGroupRequest(w http.ResponseWriter, r *http.Request, group *GroupConfig) *ContainerConfig {
+	// Pick the target member for this request: the one its affinity cookie
+	// pins it to, or the next by the group's strategy, skipping members that
+	// passive health checking marked degraded.
+	pickedName := s.stickyMember(r, group, s.manager.health.Routable)
+	if pickedName == "" {
+		pickedName = s.groupRouter.PickFunc(group, s.manager.health.Routable)
+		if group.Affinity == affinityCookie {
+			s.setAffinityCookie(w, r, group, pickedName)
+		}
+	}
+	defer s.groupRouter.Begin(pickedName)()
+	RecordGroupPick(group.Name, pickedName)
+	span := spanFromContext(r.Context())
+	span.SetAttr("gateway.group", group.Name)
+	span.SetAttr("gateway.container", pickedName)
 
 	s.configMu.RLock()
 	pickedCfg, ok := s.containerMap[pickedName]
@@ -344,7 +889,7 @@ func (s *Server) handleGroupRequest(w http.ResponseWriter, r *http.Request, grou
 
 	if !ok {
 		http.Error(w, fmt.Sprintf("group %q member %q not found", group.Name, pickedName), http.StatusInternalServerError)
-		return
+		return nil
 	}
 
 	start := time.Now()
@@ -352,15 +897,39 @@ func (s *Server) handleGroupRequest(w http.ResponseWriter, r *http.Request, grou
 	defer func() {
 		duration := time.Since(start).Seconds()
 		RecordRequest(pickedCfg.Name, strconv.Itoa(mw.statusCode), duration)
+		RecordGroupRequest(group.Name, pickedCfg.Name, strconv.Itoa(mw.statusCode))
+		span.SetAttr("http.response.status_code", mw.statusCode)
 	}()
 
 	ctx := r.Context()
 	status, err := s.manager.client.GetContainerStatus(ctx, pickedCfg.Name)
+	if (err != nil || status != "running") && s.serveRobotsTxt(mw, r) {
+		span.SetAttr("gateway.outcome", "robots_txt")
+		return pickedCfg
+	}
+	if (err != nil || status != "running") && s.manager.ReadOnly() {
+		span.SetAttr("gateway.outcome", "read_only")
+		s.serveErrorPageStatus(mw, r, pickedCfg, readOnlyMessage(pickedCfg.Name, status), http.StatusServiceUnavailable)
+		return pickedCfg
+	}
+	if (err != nil || status != "running") && s.manager.DryRun() {
+		span.SetAttr("gateway.outcome", "dry_run")
+		s.manager.simulateWake(pickedCfg.Name, "request for group "+group.Name)
+		s.serveErrorPageStatus(mw, r, pickedCfg, dryRunMessage(pickedCfg.Name, status), http.StatusServiceUnavailable)
+		return pickedCfg
+	}
 	if err != nil || status != "running" {
+		if verr := checkWake(r); verr != nil {
+			span.SetAttr("gateway.outcome", "wake_vetoed")
+			s.serveErrorPageStatus(mw, r, pickedCfg, wakeVetoMessage(pickedCfg.Name, verr), http.StatusServiceUnavailable)
+			return pickedCfg
+		}
 		// Not all members running — trigger async group startup.
 		for _, mn := range group.Containers {
 			s.manager.InitStartState(mn)
 		}
+		span.SetAttr("gateway.outcome", "wake")
+		done := make(chan error, 1)
 		go func() {
 			allContainers := s.GetConfig().Containers
 			// Use the max start_timeout among group members.
@@ -373,19 +942,57 @@ func (s *Server) handleGroupRequest(w http.ResponseWriter, r *http.Request, grou
 			if maxTimeout == 0 {
 				maxTimeout = 60 * time.Second
 			}
-			bgCtx, cancel := context.WithTimeout(context.Background(), maxTimeout+10*time.Second)
+			bgCtx, cancel := context.WithTimeout(detachContext(ctx), maxTimeout+10*time.Second)
 			defer cancel()
-			if err := s.manager.EnsureGroupRunning(bgCtx, group, allContainers); err != nil {
-				slog.Error("group start error", "group", group.Name, "error", err)
+			err := s.manager.EnsureGroupRunning(bgCtx, group, allContainers)
+			if err != nil {
+				requestLogger(bgCtx).Error("group start error", "group", group.Name, "error", err)
 			}
+			done <- err
 		}()
-		s.serveLoadingPage(mw, r, pickedCfg)
-		return
+		s.serveWaking(mw, r, pickedCfg, done, func(w http.ResponseWriter, r *http.Request) {
+			s.manager.RecordActivityChain(group.Containers, s.GetConfig().Containers)
+			s.proxyRequest(w, r, pickedCfg)
+		})
+		return pickedCfg
 	}
 
 	allContainers := s.GetConfig().Containers
 	s.manager.RecordActivityChain(group.Containers, allContainers)
+	if hedgeable(r, group.Hedge) && len(group.Containers) > 1 {
+		r = r.WithContext(withHedge(ctx, &hedgePlan{
+			group:  group.Name,
+			delay:  group.Hedge.Delay,
+			second: func(ctx context.Context) (string, error) { return s.hedgeTarget(ctx, group, pickedCfg.Name) },
+		}))
+	}
 	s.proxyRequest(mw, r, pickedCfg)
+	return pickedCfg
+}
+
+// hedgeTarget returns the address of the member a hedged request is sent to:
+// the first routable member after picked, in group order.
+func (s *Server) hedgeTarget(ctx context.Context, group *GroupConfig, picked string) (string, error) {
+	n := len(group.Containers)
+	at := slices.Index(group.Containers, picked)
+	for i := 1; i < n; i++ {
+		name := group.Containers[(at+i)%n]
+		if name == picked || !s.manager.health.Routable(name) {
+			continue
+		}
+		s.configMu.RLock()
+		cfg, ok := s.containerMap[name]
+		s.configMu.RUnlock()
+		if !ok {
+			continue
+		}
+		ip, port, err := s.manager.resolveTarget(ctx, cfg)
+		if err != nil {
+			continue
+		}
+		return net.JoinHostPort(ip, port), nil
+	}
+	return "", fmt.Errorf("group %q: no other member to hedge with", group.Name)
 }
 
 // ─── Internal endpoints ───────────────────────────────────────────────────────
@@ -393,12 +1000,11 @@ func (s *Server) handleGroupRequest(w http.ResponseWriter, r *http.Request, grou
 // handleHealth returns {"status":"starting"|"running"|"failed","error":"..."}.
 // The loading page JS polls this to know when to redirect or show inline error.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if !s.rateLimiter.Allow(s.clientIP(r)) {
-		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	if !s.allowRate(w, r, "health") {
 		return
 	}
 
-	cfg := s.resolveConfig(r)
+	cfg := s.resolveStatusConfig(r)
 	if cfg == nil {
 		http.Error(w, "unknown container", http.StatusBadRequest)
 		return
@@ -423,12 +1029,11 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // handleLogs returns {"lines":["..."]} with the last N log lines.
 func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
-	if !s.rateLimiter.Allow(s.clientIP(r)) {
-		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	if !s.allowRate(w, r, "logs") {
 		return
 	}
 
-	cfg := s.resolveConfig(r)
+	cfg := s.resolveStatusConfig(r)
 	if cfg == nil {
 		http.Error(w, "unknown container", http.StatusBadRequest)
 		return
@@ -453,73 +1058,352 @@ func isWebSocketRequest(r *http.Request) bool {
 
 // proxyRequest forwards an HTTP (or WebSocket) request to the target container.
 func (s *Server) proxyRequest(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig) {
-	ip, err := s.manager.client.GetContainerAddress(r.Context(), cfg.Name, cfg.Network)
+	spanFromContext(r.Context()).SetAttr("gateway.outcome", "proxy")
+	ctx, span := tracer.startSpan(r.Context(), "gateway.proxy", spanKindClient, spanContext{})
+	defer span.End()
+	r = stripPathPrefix(r.WithContext(ctx), cfg)
+
+	// Circuit breaker: fail fast while the backend is known to be failing.
+	allowed, releaseTrial := s.manager.breaker.Allow(cfg.Name, cfg.CircuitBreakerCooldown)
+	defer releaseTrial()
+	if !allowed {
+		retry := s.manager.breaker.RetryAfter(cfg.Name, cfg.CircuitBreakerCooldown)
+		w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+		s.serveErrorPageStatus(w, r, cfg,
+			"Backend is failing repeatedly; requests are paused for a short cooldown (circuit open)",
+			http.StatusServiceUnavailable)
+		return
+	}
+
+	// Sleep in progress: refuse new work so in-flight requests can drain.
+	goingToSleep := func() {
+		span.SetAttr("gateway.draining", true)
+		w.Header().Set("Retry-After", "5")
+		s.serveErrorPageStatus(w, r, cfg,
+			"This service is going to sleep; please retry in a moment",
+			http.StatusServiceUnavailable)
+	}
+	if s.manager.drain.Draining(cfg.Name) {
+		goingToSleep()
+		return
+	}
+
+	ip, port, err := s.manager.resolveTarget(r.Context(), cfg)
 	if err != nil {
+		span.SetError(err)
+		s.manager.RecordProxyResult(cfg, http.StatusBadGateway)
 		s.serveErrorPage(w, r, cfg, fmt.Sprintf("Networking error: %v", err))
 		return
 	}
 
-	addr := fmt.Sprintf("%s:%s", ip, cfg.TargetPort)
+	addr := net.JoinHostPort(ip, port)
+	span.SetAttr("server.address", addr)
 
 	if isWebSocketRequest(r) {
-		s.proxyWebSocket(w, r, addr)
+		span.SetAttr("gateway.websocket", true)
+		status := s.proxyWebSocket(w, r, cfg, addr)
+		span.SetAttr("http.response.status_code", status)
+		RecordWebSocketUpgrade(cfg.Name, status == http.StatusSwitchingProtocols)
+		s.manager.RecordProxyResult(cfg, status)
+		return
+	}
+
+	// WebSocket tunnels are long-lived and are not waited for by a drain;
+	// proxyWebSocket registers them so an idle stop is postponed instead.
+	if !s.manager.drain.Begin(cfg.Name) {
+		goingToSleep()
+		return
+	}
+	defer s.manager.drain.End(cfg.Name)
+
+	// Concurrency limit: wait for a slot, or 503 when the queue is full.
+	release, err := s.manager.limiter.Acquire(r.Context(), cfg)
+	if err != nil {
+		span.SetAttr("gateway.queue", err.Error())
+		if r.Context().Err() != nil {
+			return // client went away while queued
+		}
+		w.Header().Set("Retry-After", "1")
+		s.serveErrorPageStatus(w, r, cfg,
+			"Too many requests for this service right now; please retry in a moment",
+			http.StatusServiceUnavailable)
 		return
 	}
+	defer release()
+
+	ActiveRequests.WithLabelValues(cfg.Name).Inc()
+	defer ActiveRequests.WithLabelValues(cfg.Name).Dec()
 
 	targetURL, _ := url.Parse("http://" + addr)
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	proxy.BufferPool = copyBuffers
+	proxy.Transport = s.wakeRetryTransportFor(r, cfg, upstream)
+	if plan := hedgeFromContext(r.Context()); plan != nil {
+		span.SetAttr("gateway.hedge_delay_ms", plan.delay.Milliseconds())
+		proxy.Transport = &hedgeTransport{base: proxy.Transport, plan: plan}
+	}
+	proxy.FlushInterval = cfg.FlushInterval
+	streamDone := make(chan struct{})
+	defer close(streamDone)
+	eventStream := false
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if !isEventStream(resp) {
+			return nil
+		}
+		eventStream = true
+		span.SetAttr("gateway.event_stream", true)
+		// An event stream stays open far longer than gateway.server.
+		// write_timeout, which would cut it: lift the deadline for it.
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		go s.keepStreamAwake(cfg, tunnelActivityInterval, streamDone)
+		return nil
+	}
+	clientGone := false
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		category := classifyProxyError(err)
+		RecordProxyError(cfg.Name, category)
+		span.SetAttr("gateway.proxy_error", category)
+		span.SetError(err)
+		requestLogger(r.Context()).Warn("proxy error", "container", cfg.Name, "category", category, "error", err)
+		if category == "canceled" {
+			// The client is gone: not a backend failure.
+			clientGone = true
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		msg, status := proxyErrorPage(category)
+		s.serveErrorPageStatus(w, r, cfg, msg, status)
+	}
+
+	// Capture the outcome for passive health checking, the bytes
+	// exchanged for bandwidth accounting and the latency for the dashboard.
+	proxyStart := time.Now()
+	RecordRequestOutcome(cfg.Name, outcomeProxied)
+	rec := &metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	var body *countingReadCloser
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &countingReadCloser{ReadCloser: r.Body}
+		r.Body = body
+	}
+	defer func() {
+		if !clientGone {
+			s.manager.RecordProxyResult(cfg, rec.statusCode)
+		}
+		span.SetAttr("http.response.status_code", rec.statusCode)
+		var received int64
+		if body != nil {
+			received = body.n.Load()
+		}
+		s.manager.bandwidth.Add(cfg.Name, received, rec.bytes)
+		if !eventStream {
+			// An event stream lasts as long as the client stays.
+			s.manager.requests.Record(cfg.Name, time.Since(proxyStart))
+		}
+	}()
 
-	// Pass client IP information to the backend
+	// Pass client IP information to the backend. ReverseProxy appends the
+	// peer to X-Forwarded-For itself after the director, so the director
+	// hands it the chain as it arrived and the client is listed once.
+	priorXFF, hadXFF := r.Header["X-Forwarded-For"]
 	setForwardedHeaders(r, ip)
+	director := proxy.Director
+	proxy.Director = func(out *http.Request) {
+		director(out)
+		if hadXFF {
+			out.Header["X-Forwarded-For"] = priorXFF
+		} else {
+			out.Header.Del("X-Forwarded-For")
+		}
+	}
 
 	r.URL.Host = targetURL.Host
 	r.URL.Scheme = targetURL.Scheme
 	r.Host = targetURL.Host
 
-	proxy.ServeHTTP(w, r)
+	var out http.ResponseWriter = rec
+	if cfg.BandwidthLimit > 0 {
+		span.SetAttr("gateway.bandwidth_limit", int64(cfg.BandwidthLimit))
+		out = &throttledResponseWriter{ResponseWriter: rec, body: throttledWriter{
+			w: rec, ctx: r.Context(), throttle: s.manager.throttle, name: cfg.Name, rate: cfg.BandwidthLimit,
+		}}
+	}
+	proxy.ServeHTTP(out, r)
 }
 
 // proxyWebSocket tunnels a WebSocket upgrade through a raw TCP connection.
 // It hijacks the client conn and opens a new TCP connection to the backend,
-// then copies bidirectionally.
-func (s *Server) proxyWebSocket(w http.ResponseWriter, r *http.Request, backendAddr string) {
-	hijacker, ok := w.(http.Hijacker)
-	if !ok {
-		http.Error(w, "WebSocket proxying not supported by this server", http.StatusInternalServerError)
-		return
-	}
-
-	backend, err := net.DialTimeout("tcp", backendAddr, 10*time.Second)
+// then copies bidirectionally. It returns the HTTP status describing the
+// outcome for passive health checking (101 once the tunnel was established).
+func (s *Server) proxyWebSocket(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig, backendAddr string) int {
+	if !s.manager.drain.OpenTunnel(cfg.Name, cfg.WebSocket.MaxConnections) {
+		RecordWebSocketRejected(cfg.Name)
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "too many WebSocket connections to this service", http.StatusServiceUnavailable)
+		return http.StatusServiceUnavailable
+	}
+	defer s.manager.drain.CloseTunnel(cfg.Name)
+
+	backend, err := upstream.DialContext(r.Context(), "tcp", backendAddr)
 	if err != nil {
+		RecordProxyError(cfg.Name, classifyProxyError(err))
 		http.Error(w, fmt.Sprintf("WebSocket backend unreachable: %v", err), http.StatusBadGateway)
-		return
+		return http.StatusBadGateway
 	}
 	defer backend.Close()
 
-	clientConn, _, err := hijacker.Hijack()
+	clientConn, clientBuf, err := http.NewResponseController(w).Hijack()
 	if err != nil {
-		return
+		http.Error(w, "WebSocket proxying not supported by this server", http.StatusInternalServerError)
+		return http.StatusInternalServerError
 	}
 	defer clientConn.Close()
 
-	// Forward the original upgrade request to the backend
+	// A cancelled request, e.g. during a reload or shutdown, tears the
+	// tunnel down at any stage.
+	stopOnCancel := context.AfterFunc(r.Context(), func() {
+		clientConn.Close()
+		backend.Close()
+	})
+	defer stopOnCancel()
+
+	// Forward the original upgrade request to the backend, followed by any
+	// bytes the client sent right behind it that the server already read.
+	// The request carries the same forwarding, request ID and trace headers
+	// as one sent through the reverse proxy.
+	backend.SetDeadline(time.Now().Add(wsHandshakeTimeout))
+	backendIP, _, _ := net.SplitHostPort(backendAddr)
+	setForwardedHeaders(r, backendIP)
 	if err := r.Write(backend); err != nil {
-		return
+		return http.StatusBadGateway
+	}
+	early, _ := clientBuf.Reader.Peek(clientBuf.Reader.Buffered())
+	if _, err := backend.Write(early); err != nil {
+		return http.StatusBadGateway
 	}
 
-	// Bidirectional copy until one side closes
-	done := make(chan struct{}, 2)
-	copy := func(dst io.Writer, src io.Reader) {
-		io.Copy(dst, src) //nolint:errcheck
-		done <- struct{}{}
+	// Relay the backend's handshake response as is. Only past a 101 does
+	// the stream carry WebSocket frames the gateway can close cleanly.
+	var raw bytes.Buffer
+	br := bufio.NewReader(io.TeeReader(backend, &raw))
+	resp, err := http.ReadResponse(br, r)
+	if err != nil {
+		RecordProxyError(cfg.Name, classifyProxyError(err))
+		return http.StatusBadGateway
+	}
+	backend.SetDeadline(time.Time{})
+	head := raw.Bytes()[:raw.Len()-br.Buffered()]
+	clientConn.SetWriteDeadline(time.Now().Add(wsHandshakeTimeout))
+	if _, err := clientConn.Write(head); err != nil {
+		return resp.StatusCode
+	}
+	clientConn.SetWriteDeadline(time.Time{})
+	rest, _ := br.Peek(br.Buffered())
+
+	WebSocketConnections.WithLabelValues(cfg.Name).Inc()
+	defer WebSocketConnections.WithLabelValues(cfg.Name).Dec()
+
+	// Traffic in either direction counts as activity, so a long session is
+	// not idle-stopped while it is in use.
+	activity := newTunnelActivity(tunnelActivityInterval, func() {
+		s.manager.RecordActivityChain([]string{cfg.Name}, s.GetConfig().Containers)
+	})
+
+	var toClient io.Writer = clientConn
+	if cfg.BandwidthLimit > 0 {
+		toClient = &throttledWriter{w: clientConn, ctx: r.Context(), throttle: s.manager.throttle, name: cfg.Name, rate: cfg.BandwidthLimit}
+	}
+	toClient = activity.writer(toClient)
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		fw := &frameWriter{w: toClient}
+		toClient = fw
+		tunnel := &wsTunnel{client: clientConn, backend: backend, toClient: fw, done: make(chan struct{})}
+		s.tunnels.add(tunnel)
+		defer close(tunnel.done)
+		defer s.tunnels.remove(tunnel)
+	}
+
+	// Full-duplex copy. A direction that reaches EOF half-closes its
+	// destination so the peer sees the end of the stream, and the other
+	// direction gets wsHalfCloseTimeout to finish; an error closes both.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		n, err := copyPooled(activity.writer(backend), clientConn)
+		s.manager.bandwidth.Add(cfg.Name, int64(len(early))+n, 0)
+		endTunnelDirection(backend, clientConn, err)
+	}()
+	go func() {
+		defer wg.Done()
+		// Frames that arrived with the handshake go first.
+		m, err := toClient.Write(rest)
+		n := int64(len(head) + m)
+		if err == nil {
+			var copied int64
+			copied, err = copyPooled(toClient, backend)
+			n += copied
+		}
+		s.manager.bandwidth.Add(cfg.Name, 0, n)
+		endTunnelDirection(clientConn, backend, err)
+	}()
+	if cfg.WebSocket.IdleTimeout > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go activity.watchIdle(cfg.WebSocket.IdleTimeout, stop, func() {
+			RecordWebSocketIdleClosed(cfg.Name)
+			clientConn.Close()
+			backend.Close()
+		})
+	}
+	wg.Wait()
+	return resp.StatusCode
+}
+
+// classifyProxyError maps a proxy transport error to the category label of
+// gateway_proxy_errors_total.
+func classifyProxyError(err error) string {
+	var opErr *net.OpError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "reset"
+	case errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout():
+		return "dial_timeout"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "other"
+	}
+}
+
+// proxyErrorPage returns the error page message and status for a proxy
+// error category. The underlying error is only logged, with the request ID
+// shown on the page, so backend addresses are not revealed to clients.
+func proxyErrorPage(category string) (string, int) {
+	switch category {
+	case "refused":
+		return "The service refused the connection", http.StatusBadGateway
+	case "reset":
+		return "The service closed the connection unexpectedly", http.StatusBadGateway
+	case "dial_timeout":
+		return "Timed out connecting to the service", http.StatusGatewayTimeout
+	case "timeout":
+		return "The service did not respond in time", http.StatusGatewayTimeout
+	default:
+		return "The request to the service failed", http.StatusBadGateway
 	}
-	go copy(backend, clientConn)
-	go copy(clientConn, backend)
-	<-done
 }
 
 // setForwardedHeaders adds X-Forwarded-For, X-Real-IP and X-Forwarded-Proto
-// to the outgoing request so the backend can see the original client IP.
+// to the outgoing request so the backend can see the original client IP, plus
+// X-Request-ID and traceparent so its logs can be correlated with the gateway.
+// Values already present come from a trusted proxy: forwardedMiddleware
+// strips them from any other peer.
 func setForwardedHeaders(r *http.Request, serverIP string) {
 	clientIP, _, _ := net.SplitHostPort(r.RemoteAddr)
 
@@ -535,23 +1419,29 @@ func setForwardedHeaders(r *http.Request, serverIP string) {
 		r.Header.Set("X-Real-IP", clientIP)
 	}
 
-	// X-Forwarded-Proto: respect existing upstream value (not overwritten if already set)
+	// X-Forwarded-Proto and -Host: respect existing upstream values
 	if r.Header.Get("X-Forwarded-Proto") == "" {
-		r.Header.Set("X-Forwarded-Proto", "http")
+		r.Header.Set("X-Forwarded-Proto", requestScheme(r))
+	}
+	if r.Header.Get("X-Forwarded-Host") == "" {
+		r.Header.Set("X-Forwarded-Host", r.Host)
+	}
+	// The routing override is meant for the gateway, not the backend.
+	r.Header.Del(containerHeader)
+
+	if id, ok := r.Context().Value(requestIDKey{}).(string); ok {
+		r.Header.Set("X-Request-ID", id)
+	}
+	if sc, ok := traceContextFrom(r.Context()); ok {
+		r.Header.Set("traceparent", sc.traceparent())
 	}
-	r.Header.Set("X-Forwarded-Host", r.Host)
 }
 
 // clientIP returns the real client IP for rate-limiting purposes.
 // It trusts X-Forwarded-For ONLY if RemoteAddr is from a configured trusted proxy.
 func (s *Server) clientIP(r *http.Request) string {
 	directIP, _, _ := net.SplitHostPort(r.RemoteAddr)
-
-	s.configMu.RLock()
-	trusted := s.trustedCIDRs
-	s.configMu.RUnlock()
-
-	if len(trusted) > 0 && isTrustedProxy(directIP, trusted) {
+	if s.fromTrustedProxy(r) {
 		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 			parts := strings.SplitN(xff, ",", 2)
 			return strings.TrimSpace(parts[0])
@@ -560,6 +1450,18 @@ func (s *Server) clientIP(r *http.Request) string {
 	return directIP
 }
 
+// fromTrustedProxy reports whether the request's direct peer is one of the
+// configured trusted_proxies.
+func (s *Server) fromTrustedProxy(r *http.Request) bool {
+	directIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+	s.configMu.RLock()
+	trusted := s.trustedCIDRs
+	s.configMu.RUnlock()
+
+	return len(trusted) > 0 && isTrustedProxy(directIP, trusted)
+}
+
 // isTrustedProxy checks if the given IP falls within any of the trusted CIDR blocks.
 func isTrustedProxy(ip string, cidrs []*net.IPNet) bool {
 	parsed := net.ParseIP(ip)
@@ -590,6 +1492,8 @@ func parseTrustedProxies(proxies []string) []*net.IPNet {
 
 // validateOrigin blocks cross-origin POST requests from browsers.
 // Requests without an Origin header (curl, scripts) are allowed through.
+// Behind a trusted proxy that rewrites Host, the Origin is compared with
+// its X-Forwarded-Host.
 func validateOrigin(r *http.Request) bool {
 	origin := r.Header.Get("Origin")
 	if origin == "" {
@@ -599,63 +1503,7 @@ func validateOrigin(r *http.Request) bool {
 	if err != nil {
 		return false
 	}
-	return parsed.Host == r.Host
-}
-
-// ─── Rate limiter ─────────────────────────────────────────────────────────────
-
-// rateLimiter enforces a minimum interval between requests per IP.
-type rateLimiter struct {
-	mu          sync.Mutex
-	lastSeen    map[string]time.Time
-	minInterval time.Duration
-}
-
-func newRateLimiter(minInterval time.Duration) *rateLimiter {
-	return &rateLimiter{
-		lastSeen:    make(map[string]time.Time),
-		minInterval: minInterval,
-	}
-}
-
-// Allow returns true if this IP is allowed to proceed (not rate-limited).
-func (rl *rateLimiter) Allow(ip string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	last, ok := rl.lastSeen[ip]
-	if !ok || time.Since(last) >= rl.minInterval {
-		rl.lastSeen[ip] = time.Now()
-		return true
-	}
-	return false
-}
-
-// startCleanup periodically evicts stale entries from the rate limiter.
-func (rl *rateLimiter) startCleanup(ctx context.Context, interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				rl.evictStale()
-			}
-		}
-	}()
-}
-
-// evictStale removes IPs whose last access is older than 2× the rate limit interval.
-func (rl *rateLimiter) evictStale() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	cutoff := time.Now().Add(-2 * rl.minInterval)
-	for ip, last := range rl.lastSeen {
-		if last.Before(cutoff) {
-			delete(rl.lastSeen, ip)
-		}
-	}
+	return parsed.Host == requestHost(r)
 }
 
 // calcIdleRemaining returns seconds until idle-triggered stop.
@@ -691,6 +1539,7 @@ type errorData struct {
 	Error         string
 	RequestID     string
 	RequestPath   string
+	CrashLoop     bool
 }
 
 type scheduledData struct {
@@ -699,35 +1548,97 @@ type scheduledData struct {
 }
 
 type statusPageData struct {
-	Version string
+	Version   string
+	Commit    string
+	GoVersion string
+	// Tenant is the caller's tenant; gateway-wide controls are hidden.
+	Tenant string
 }
 
 type statusContainerJSON struct {
-	Name             string  `json:"name"`
-	Host             string  `json:"host"`
-	Status           string  `json:"status"`
-	StartState       string  `json:"start_state"`
-	Image            string  `json:"image"`
-	Icon             string  `json:"icon"`
-	TargetPort       string  `json:"target_port"`
-	StartTimeout     string  `json:"start_timeout"`
-	IdleTimeout      string  `json:"idle_timeout"`
-	StartedAt        *string `json:"started_at,omitempty"`
-	LastRequest      *string `json:"last_request,omitempty"`
-	IdleTimeoutSec   int64   `json:"idle_timeout_sec"`
-	IdleRemainingSec int64   `json:"idle_remaining_sec"`
-	Network          string  `json:"network"`
+	Name             string   `json:"name"`
+	Host             string   `json:"host"`
+	PathPrefix       string   `json:"path_prefix,omitempty"`
+	Status           string   `json:"status"`
+	StartState       string   `json:"start_state"`
+	Image            string   `json:"image"`
+	Icon             string   `json:"icon"`
+	IconURL          string   `json:"icon_url,omitempty"`
+	Tenant           string   `json:"tenant,omitempty"`
+	TargetPort       string   `json:"target_port"`
+	StartTimeout     string   `json:"start_timeout"`
+	IdleTimeout      string   `json:"idle_timeout"`
+	StartedAt        *string  `json:"started_at,omitempty"`
+	LastRequest      *string  `json:"last_request,omitempty"`
+	IdleTimeoutSec   int64    `json:"idle_timeout_sec"`
+	IdleRemainingSec int64    `json:"idle_remaining_sec"`
+	Network          string   `json:"network"`
+	Networks         []string `json:"networks,omitempty"`
 	// Schedule
 	ScheduleStart      string `json:"schedule_start"`
 	ScheduleStop       string `json:"schedule_stop"`
 	ScheduleTimezone   string `json:"schedule_timezone"`
 	ScheduledDowntime  bool   `json:"scheduled_downtime"`
 	NextScheduledStart string `json:"next_scheduled_start"`
+	// Passive health
+	Health        string `json:"health"`
+	ProxyFailures int    `json:"proxy_failures"`
+	CircuitState  string `json:"circuit_state"`
+	// Crash-loop detection
+	CrashLoop        bool    `json:"crash_loop"`
+	CrashCount       int     `json:"crash_count"`
+	NextStartAttempt *string `json:"next_start_attempt,omitempty"`
+	SelfHealRestarts int     `json:"self_heal_restarts"`
+	// Image update detection (update_check_interval)
+	UpdateAvailable bool    `json:"update_available"`
+	UpdateCheckedAt *string `json:"update_checked_at,omitempty"`
+	// Savings over the last 7 days
+	RunningSecondsWeek int64 `json:"running_seconds_week"`
+	AsleepSecondsWeek  int64 `json:"asleep_seconds_week"`
+	WakesWeek          int   `json:"wakes_week"`
+	// Availability over the last 24h, 7d and 30d, from the lifecycle log
+	Availability map[string]availabilityStats `json:"availability"`
+	// Bytes proxied since the gateway started
+	BytesReceived int64 `json:"bytes_received"`
+	BytesSent     int64 `json:"bytes_sent"`
+	// Proxied requests over the last 5 minutes (requestStatsWindow)
+	RequestsPerMin float64 `json:"requests_per_min"`
+	LatencyP50Ms   int64   `json:"latency_p50_ms"`
+	LatencyP95Ms   int64   `json:"latency_p95_ms"`
+	LatencyP99Ms   int64   `json:"latency_p99_ms"`
 }
 
+// statusAPIResponse is the /_status/api payload. Containers holds a
+// statusContainerJSON per container, or a subset of its fields with ?fields=.
 type statusAPIResponse struct {
-	Containers []statusContainerJSON `json:"containers"`
-	UpdatedAt  string                `json:"updated_at"`
+	Containers []any               `json:"containers"`
+	Savings    statusSavingsJSON   `json:"savings"`
+	Bandwidth  statusBandwidthJSON `json:"bandwidth"`
+	// Capabilities is "ok" or "forbidden" per Docker API operation, present
+	// with detect_capabilities.
+	Capabilities map[string]string `json:"capabilities,omitempty"`
+	// ReadOnly is set with gateway.read_only.
+	ReadOnly bool `json:"read_only"`
+	// DryRun is set with gateway.dry_run.
+	DryRun bool `json:"dry_run"`
+	// Tenant is the caller's tenant when it authenticated with tenant
+	// credentials; only that tenant's containers are listed.
+	Tenant    string `json:"tenant,omitempty"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// statusBandwidthJSON sums the bytes proxied for all containers since the
+// gateway started.
+type statusBandwidthJSON struct {
+	BytesReceived int64 `json:"bytes_received"`
+	BytesSent     int64 `json:"bytes_sent"`
+}
+
+// statusSavingsJSON sums the runtime of all containers over the last 7 days.
+type statusSavingsJSON struct {
+	RunningSecondsWeek int64 `json:"running_seconds_week"`
+	AsleepSecondsWeek  int64 `json:"asleep_seconds_week"`
+	WakesWeek          int   `json:"wakes_week"`
 }
 
 // ─── Topology page types ──────────────────────────────────────────────────────
@@ -742,6 +1653,7 @@ type topologyContainerJSON struct {
 	Status        string   `json:"status"`
 	Image         string   `json:"image"`
 	Icon          string   `json:"icon"`
+	IconURL       string   `json:"icon_url,omitempty"`
 	TargetPort    string   `json:"target_port"`
 	HealthPath    string   `json:"health_path"`
 	DependsOn     []string `json:"depends_on"`
@@ -763,21 +1675,23 @@ type topologyPayload struct {
 	Groups     []topologyGroupJSON     `json:"groups"`
 }
 
-func requestID(prefix string) string {
-	return fmt.Sprintf("%s-%x", prefix, time.Now().UnixNano()%0xFFFFFF)
-}
-
 func (s *Server) serveLoadingPage(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig) {
+	s.manager.NoteLoadingPage(cfg.Name)
+	if wantsJSON(r) {
+		s.serveLoadingJSON(w, r, cfg)
+		return
+	}
 	data := loadingData{
 		ContainerName: cfg.Name,
-		RequestID:     requestID("req"),
+		RequestID:     requestIDFrom(r.Context(), "req"),
 		RequestPath:   r.URL.Path,
 		RedirectPath:  cfg.RedirectPath,
 		StartTimeout:  cfg.StartTimeout.String(),
 	}
+	s.noIndex(w)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := s.tmpl.ExecuteTemplate(w, "loading.html", data); err != nil {
-		slog.Error("template render failed", "template", "loading", "error", err)
+		slog.ErrorContext(r.Context(), "template render failed", "template", "loading", "error", err)
 	}
 }
 
@@ -790,37 +1704,147 @@ func (s *Server) serveScheduledPage(w http.ResponseWriter, r *http.Request, cfg
 		ContainerName: cfg.Name,
 		NextStart:     next,
 	}
+	s.noIndex(w)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusServiceUnavailable)
 	if err := s.tmpl.ExecuteTemplate(w, "scheduled.html", data); err != nil {
-		slog.Error("template render failed", "template", "scheduled", "error", err)
+		slog.ErrorContext(r.Context(), "template render failed", "template", "scheduled", "error", err)
 	}
 }
 
 func (s *Server) serveErrorPage(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig, errMsg string) {
-	data := errorData{
+	s.serveErrorPageStatus(w, r, cfg, errMsg, http.StatusBadGateway)
+}
+
+// serveErrorPageStatus renders the error page with an explicit HTTP status.
+func (s *Server) serveErrorPageStatus(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig, errMsg string, statusCode int) {
+	s.renderError(w, r, errorData{
 		ContainerName: cfg.Name,
 		Error:         errMsg,
-		RequestID:     requestID("err"),
+		RequestID:     requestIDFrom(r.Context(), "err"),
 		RequestPath:   r.URL.Path,
+	}, statusCode)
+}
+
+// serveCrashLoopPage renders the error page in its crash-loop variant (503).
+func (s *Server) serveCrashLoopPage(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig, errMsg string) {
+	s.renderError(w, r, errorData{
+		ContainerName: cfg.Name,
+		Error:         errMsg,
+		RequestID:     requestIDFrom(r.Context(), "err"),
+		RequestPath:   r.URL.Path,
+		CrashLoop:     true,
+	}, http.StatusServiceUnavailable)
+}
+
+// errorJSON is the error page for API clients.
+type errorJSON struct {
+	Error     string `json:"error"`
+	Container string `json:"container"`
+	Status    int    `json:"status"`
+	RequestID string `json:"request_id"`
+	CrashLoop bool   `json:"crash_loop,omitempty"`
+}
+
+// loadingRetryAfter is the Retry-After, in seconds, of the loading page
+// served as JSON.
+const loadingRetryAfter = 5
+
+// loadingJSON is the loading page for API clients.
+type loadingJSON struct {
+	Status       string `json:"status"`
+	Container    string `json:"container"`
+	StartState   string `json:"start_state"`
+	Error        string `json:"error,omitempty"`
+	StartTimeout string `json:"start_timeout"`
+	RetryAfter   int    `json:"retry_after"`
+	RequestID    string `json:"request_id"`
+}
+
+// serveLoadingJSON answers an API client whose request woke cfg with a 503
+// and Retry-After: the HTML loading page would pass for the response.
+func (s *Server) serveLoadingJSON(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig) {
+	state, errMsg := s.manager.GetStartState(cfg.Name)
+	s.noIndex(w)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(loadingRetryAfter))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(loadingJSON{
+		Status:       "starting",
+		Container:    cfg.Name,
+		StartState:   state,
+		Error:        errMsg,
+		StartTimeout: cfg.StartTimeout.String(),
+		RetryAfter:   loadingRetryAfter,
+		RequestID:    requestIDFrom(r.Context(), "req"),
+	})
+}
+
+// renderError writes the error page, or its JSON form when the client
+// prefers JSON (see wantsJSON).
+func (s *Server) renderError(w http.ResponseWriter, r *http.Request, data errorData, statusCode int) {
+	w.Header().Set("Cache-Control", "no-store")
+	s.noIndex(w)
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(errorJSON{
+			Error:     data.Error,
+			Container: data.ContainerName,
+			Status:    statusCode,
+			RequestID: data.RequestID,
+			CrashLoop: data.CrashLoop,
+		})
+		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusBadGateway)
+	w.WriteHeader(statusCode)
 	if err := s.tmpl.ExecuteTemplate(w, "error.html", data); err != nil {
-		slog.Error("template render failed", "template", "error", "error", err)
+		slog.ErrorContext(r.Context(), "template render failed", "template", "error", "error", err)
 	}
 }
 
+// apiUserAgents are User-Agent prefixes of HTTP libraries and command-line
+// tools, which never render HTML.
+var apiUserAgents = []string{
+	"curl/", "Wget/", "Go-http-client/", "python-requests/", "python-httpx/",
+	"aiohttp/", "okhttp/", "axios/", "node-fetch/", "undici", "HTTPie/",
+}
+
+// wantsJSON reports whether r comes from an API client rather than a
+// browser: it accepts JSON but not HTML, is an XMLHttpRequest, or comes from
+// a known HTTP library that does not ask for HTML.
+func wantsJSON(r *http.Request) bool {
+	if r.Header.Get("X-Requested-With") == "XMLHttpRequest" {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/html") {
+		return false
+	}
+	if strings.Contains(accept, "json") {
+		return true
+	}
+	ua := r.Header.Get("User-Agent")
+	return slices.ContainsFunc(apiUserAgents, func(p string) bool { return strings.HasPrefix(ua, p) })
+}
+
 // ─── Status dashboard handlers ────────────────────────────────────────────────
 
 // handleStatusPage serves the status dashboard HTML page.
 func (s *Server) handleStatusPage(w http.ResponseWriter, r *http.Request) {
+	build := GetBuildInfo()
 	data := statusPageData{
-		Version: gatewayVersion,
+		Version:   build.Version,
+		Commit:    build.Commit,
+		GoVersion: build.GoVersion,
+		Tenant:    requestTenant(r),
 	}
+	s.noIndex(w)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := s.tmpl.ExecuteTemplate(w, "status.html", data); err != nil {
-		slog.Error("template render failed", "template", "status", "error", err)
+		slog.ErrorContext(r.Context(), "template render failed", "template", "status", "error", err)
 		http.Error(w, "Failed to render status page", http.StatusInternalServerError)
 	}
 }
@@ -828,45 +1852,117 @@ func (s *Server) handleStatusPage(w http.ResponseWriter, r *http.Request) {
 // handleStatusAPI returns a JSON snapshot of all managed containers.
 // Polled every ~5s by the status dashboard JS.
 func (s *Server) handleStatusAPI(w http.ResponseWriter, r *http.Request) {
-	if !s.rateLimiter.Allow(s.clientIP(r)) {
-		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	if !s.allowRate(w, r, "status_api") {
+		return
+	}
+	filter, err := parseStatusFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	ctx := r.Context()
 	cfg := s.GetConfig()
+	tenant := requestTenant(r)
 	result := statusAPIResponse{
-		UpdatedAt:  time.Now().UTC().Format(time.RFC3339),
-		Containers: make([]statusContainerJSON, 0, len(cfg.Containers)),
+		Tenant:       tenant,
+		UpdatedAt:    time.Now().UTC().Format(time.RFC3339),
+		Containers:   make([]any, 0, len(cfg.Containers)),
+		Capabilities: s.manager.client.Capabilities(),
+		ReadOnly:     s.manager.ReadOnly(),
+		DryRun:       s.manager.DryRun(),
 	}
 
 	for i := range cfg.Containers {
 		c := &cfg.Containers[i]
+		if !visibleTo(c.Tenant, tenant) || !filter.matchName(c.Name) {
+			continue
+		}
 		entry := statusContainerJSON{
 			Name:         c.Name,
-			Host:         c.Host,
+			Host:         cfg.ContainerHost(c),
+			PathPrefix:   c.PathPrefix,
 			Icon:         c.Icon,
+			IconURL:      dashboardIconURL(c, cfg.Gateway.ProxyIcons),
+			Tenant:       c.Tenant,
 			TargetPort:   c.TargetPort,
 			StartTimeout: c.StartTimeout.String(),
 			IdleTimeout:  c.IdleTimeout.String(),
-			Network:      c.Network,
+			Networks:     c.NetworkPreference(),
+		}
+		if len(entry.Networks) > 0 {
+			entry.Network = entry.Networks[0]
 		}
 
 		// Gateway-level start state
 		startState, _ := s.manager.GetStartState(c.Name)
 		entry.StartState = startState
 
-		// Docker inspect for live status + image + timestamps
-		info, err := s.manager.client.InspectContainer(ctx, c.Name)
-		if err != nil {
-			entry.Status = "unknown"
-			entry.Image = "?"
-		} else {
-			entry.Status = info.Status
-			entry.Image = info.Image
-			if !info.StartedAt.IsZero() {
-				ts := info.StartedAt.UTC().Format(time.RFC3339)
-				entry.StartedAt = &ts
+		// Docker inspect for live status + image + timestamps, skipped when
+		// the filter does not need them.
+		if filter.needsInspect() {
+			info, err := s.manager.client.InspectContainer(ctx, c.Name)
+			if err != nil {
+				entry.Status = "unknown"
+				entry.Image = "?"
+			} else {
+				entry.Status = info.Status
+				entry.Image = info.Image
+				if !info.StartedAt.IsZero() {
+					ts := info.StartedAt.UTC().Format(time.RFC3339)
+					entry.StartedAt = &ts
+				}
+			}
+			if !filter.matchState(entry.Status) {
+				continue
+			}
+		}
+
+		// Passive health from proxy outcomes
+		entry.Health = healthHealthy
+		if s.manager.IsDegraded(c.Name) {
+			entry.Health = healthDegraded
+		}
+		if s.manager.health.IsDown(c.Name) {
+			entry.Health = healthDown
+		}
+		entry.ProxyFailures = s.manager.health.Failures(c.Name)
+		entry.CircuitState = s.manager.breaker.State(c.Name).String()
+
+		entry.SelfHealRestarts = s.manager.SelfHealRestarts(c.Name)
+		if available, checkedAt := s.manager.ImageUpdate(c.Name); !checkedAt.IsZero() {
+			ts := checkedAt.UTC().Format(time.RFC3339)
+			entry.UpdateAvailable = available
+			entry.UpdateCheckedAt = &ts
+		}
+
+		usage := s.manager.RuntimeSummary(c.Name)
+		entry.RunningSecondsWeek = int64(usage.Running.Seconds())
+		entry.AsleepSecondsWeek = int64(usage.Asleep.Seconds())
+		entry.WakesWeek = usage.Wakes
+		entry.Availability = s.manager.Availability(c.Name)
+		result.Savings.RunningSecondsWeek += entry.RunningSecondsWeek
+		result.Savings.AsleepSecondsWeek += entry.AsleepSecondsWeek
+		result.Savings.WakesWeek += entry.WakesWeek
+
+		bw := s.manager.bandwidth.Totals(c.Name)
+		entry.BytesReceived, entry.BytesSent = bw.Received, bw.Sent
+		result.Bandwidth.BytesReceived += bw.Received
+		result.Bandwidth.BytesSent += bw.Sent
+
+		traffic := s.manager.requests.Summary(c.Name)
+		entry.RequestsPerMin = math.Round(traffic.PerMinute*10) / 10
+		entry.LatencyP50Ms = traffic.P50.Milliseconds()
+		entry.LatencyP95Ms = traffic.P95.Milliseconds()
+		entry.LatencyP99Ms = traffic.P99.Milliseconds()
+
+		// Crash-loop backoff (only meaningful while the container is down)
+		if looping, until, count := s.manager.CrashLoopState(c.Name); looping && entry.Status != "running" {
+			entry.CrashLoop = true
+			entry.CrashCount = count
+			if until.After(time.Now()) {
+				ts := until.UTC().Format(time.RFC3339)
+				entry.NextStartAttempt = &ts
 			}
 		}
 
@@ -881,6 +1977,10 @@ func (s *Server) handleStatusAPI(w http.ResponseWriter, r *http.Request) {
 		lastSeen, hasSeen := s.manager.GetLastSeen(c.Name)
 		entry.IdleTimeoutSec = int64(c.IdleTimeout.Seconds())
 		entry.IdleRemainingSec = calcIdleRemaining(c.IdleTimeout, lastSeen, hasSeen, now)
+		if woken := s.manager.wokenAt(c.Name); c.MinUptime > 0 && entry.IdleRemainingSec >= 0 && !woken.IsZero() {
+			// min_uptime holds off the stop of a freshly woken container.
+			entry.IdleRemainingSec = max(entry.IdleRemainingSec, int64((c.MinUptime - now.Sub(woken)).Seconds()))
+		}
 
 		// Schedule fields.
 		entry.ScheduleStart = c.ScheduleStart
@@ -902,13 +2002,45 @@ func (s *Server) handleStatusAPI(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		result.Containers = append(result.Containers, entry)
+		result.Containers = append(result.Containers, filter.project(entry))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
+// handleStatusContainer serves GET /_status/api/containers/{name}: the
+// sanitised inspect data behind the dashboard details drawer. Only configured
+// containers can be inspected.
+func (s *Server) handleStatusContainer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.allowRate(w, r, "status_api") {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/_status/api/containers/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if s.tenantContainer(r, name) == nil {
+		http.Error(w, "unknown container", http.StatusNotFound)
+		return
+	}
+
+	details, err := s.manager.client.InspectDetails(r.Context(), name)
+	if err != nil {
+		requestLogger(r.Context()).Error("status-details error", "container", name, "error", err)
+		http.Error(w, fmt.Sprintf("inspect failed: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
 // handleStatusWake triggers a container start from the dashboard.
 func (s *Server) handleStatusWake(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -919,8 +2051,10 @@ func (s *Server) handleStatusWake(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "cross-origin request blocked", http.StatusForbidden)
 		return
 	}
-	if !s.rateLimiter.Allow(s.clientIP(r)) {
-		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	if !s.allowRate(w, r, "status_wake") {
+		return
+	}
+	if s.refuseReadOnly(w) {
 		return
 	}
 
@@ -938,18 +2072,18 @@ func (s *Server) handleStatusWake(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 	}
-	if targetCfg == nil {
+	if targetCfg == nil || !visibleTo(targetCfg.Tenant, requestTenant(r)) {
 		http.Error(w, "unknown container", http.StatusBadRequest)
 		return
 	}
 
-	// Trigger async start
+	// Trigger async start, dependencies included
 	s.manager.InitStartState(targetCfg.Name)
 	go func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), targetCfg.StartTimeout+10*time.Second)
+		bgCtx, cancel := context.WithTimeout(detachContext(r.Context()), s.manager.wakeTimeout(targetCfg))
 		defer cancel()
-		if err := s.manager.EnsureRunning(bgCtx, targetCfg); err != nil {
-			slog.Error("status-wake start error", "container", targetCfg.Name, "error", err)
+		if err := s.manager.Wake(bgCtx, targetCfg, cfg.Containers); err != nil {
+			requestLogger(bgCtx).Error("status-wake start error", "container", targetCfg.Name, "error", err)
 		}
 	}()
 
@@ -957,12 +2091,192 @@ func (s *Server) handleStatusWake(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
 }
 
+// handleStatusSleep is the counterpart of handleStatusWake: it stops the
+// container after letting its in-flight requests drain for at most ?timeout=
+// (default 30s, capped at 5m). The stop runs in the background; the response
+// reports the requests in flight when the drain started.
+func (s *Server) handleStatusSleep(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.statusAction(w, r, "status_sleep")
+	if !ok {
+		return
+	}
+
+	timeout := defaultSleepDrainTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "invalid timeout parameter", http.StatusBadRequest)
+			return
+		}
+		timeout = min(d, maxSleepDrainTimeout)
+	}
+
+	if s.refuseReadOnly(w) {
+		return
+	}
+	if !s.manager.client.Capable(CapabilityStop) {
+		writeCapabilityError(w, capabilityError(CapabilityStop))
+		return
+	}
+
+	inFlight := s.manager.drain.InFlight(name)
+	go func() {
+		bgCtx, cancel := context.WithTimeout(detachContext(r.Context()), timeout+30*time.Second)
+		defer cancel()
+		if err := s.manager.Sleep(bgCtx, name, timeout); err != nil {
+			requestLogger(bgCtx).Error("status-sleep stop error", "container", name, "error", err)
+			return
+		}
+		requestLogger(bgCtx).Info("container put to sleep via admin API", "container", name)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "in_flight": inFlight})
+}
+
+// statusAction checks a POST /_status/<action>?container=NAME request and
+// returns the configured container it targets. It writes the error response
+// and returns false when the request is refused.
+func (s *Server) statusAction(w http.ResponseWriter, r *http.Request, endpoint string) (string, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return "", false
+	}
+	if !validateOrigin(r) {
+		http.Error(w, "cross-origin request blocked", http.StatusForbidden)
+		return "", false
+	}
+	if !s.allowRate(w, r, endpoint) {
+		return "", false
+	}
+
+	name := r.URL.Query().Get("container")
+	if name == "" {
+		http.Error(w, "missing container parameter", http.StatusBadRequest)
+		return "", false
+	}
+	if s.tenantContainer(r, name) != nil {
+		return name, true
+	}
+	http.Error(w, "unknown container", http.StatusBadRequest)
+	return "", false
+}
+
+// handleStatusKill force-stops a container with SIGKILL, without draining,
+// and clears its start state. Unlike /_status/sleep it waits for the kill,
+// so a failure is reported to the caller.
+func (s *Server) handleStatusKill(w http.ResponseWriter, r *http.Request) {
+	if name, ok := s.statusAction(w, r, "status_kill"); ok {
+		s.killContainer(w, r, name)
+	}
+}
+
+// killContainer kills the checked container name for /_status/kill and
+// POST /_api/v1/containers/{name}/kill.
+func (s *Server) killContainer(w http.ResponseWriter, r *http.Request, name string) {
+	if s.refuseReadOnly(w) {
+		return
+	}
+	if !s.manager.client.Capable(CapabilityKill) {
+		writeCapabilityError(w, capabilityError(CapabilityKill))
+		return
+	}
+	if err := s.manager.Kill(r.Context(), name); err != nil {
+		requestLogger(r.Context()).Error("status-kill error", "container", name, "error", err)
+		http.Error(w, fmt.Sprintf("kill failed: %v", err), http.StatusBadGateway)
+		return
+	}
+	requestLogger(r.Context()).Warn("container killed via admin API", "container", name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// handleStatusReset clears a stuck "starting" or "failed" start state and the
+// crash-loop backoff of a container without touching the container itself.
+// The response reports the state that was cleared.
+func (s *Server) handleStatusReset(w http.ResponseWriter, r *http.Request) {
+	if name, ok := s.statusAction(w, r, "status_reset"); ok {
+		s.resetContainer(w, r, name)
+	}
+}
+
+// resetContainer resets the checked container name for /_status/reset and
+// POST /_api/v1/containers/{name}/reset.
+func (s *Server) resetContainer(w http.ResponseWriter, r *http.Request, name string) {
+	prev := s.manager.ResetStartState(name)
+	requestLogger(r.Context()).Info("start state reset via admin API", "container", name, "previous", prev)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "previous": prev})
+}
+
+// handleStatusDisk reports the host's Docker disk usage per object type,
+// like `docker system df`.
+func (s *Server) handleStatusDisk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.allowRate(w, r, "status_disk") {
+		return
+	}
+	report, err := s.manager.client.DiskUsage(r.Context())
+	if err != nil {
+		requestLogger(r.Context()).Error("status-disk error", "error", err)
+		http.Error(w, fmt.Sprintf("disk usage failed: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleStatusDiskPrune removes dangling images. The request must carry
+// confirm=true so a stray POST cannot delete anything.
+func (s *Server) handleStatusDiskPrune(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !validateOrigin(r) {
+		http.Error(w, "cross-origin request blocked", http.StatusForbidden)
+		return
+	}
+	if !s.allowRate(w, r, "status_prune") {
+		return
+	}
+	if s.refuseReadOnly(w) {
+		return
+	}
+	if r.URL.Query().Get("confirm") != "true" {
+		http.Error(w, "prune not confirmed: add confirm=true", http.StatusBadRequest)
+		return
+	}
+	report, err := s.manager.client.PruneDanglingImages(r.Context())
+	if err != nil {
+		requestLogger(r.Context()).Error("status-prune error", "error", err)
+		http.Error(w, fmt.Sprintf("prune failed: %v", err), http.StatusBadGateway)
+		return
+	}
+	requestLogger(r.Context()).Warn("dangling images pruned via admin API",
+		"images_deleted", report.ImagesDeleted, "space_reclaimed", report.SpaceReclaimed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"ok":              true,
+		"images_deleted":  report.ImagesDeleted,
+		"space_reclaimed": report.SpaceReclaimed,
+	})
+}
+
 // ─── Topology page handler ────────────────────────────────────────────────────
 
 // handleTopology serves the container dependency graph page (SVG rendering).
 func (s *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cfg := s.GetConfig()
+	tenant := requestTenant(r)
 
 	type dockerInfo struct {
 		status    string
@@ -971,6 +2285,9 @@ func (s *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
 	}
 	infoMap := make(map[string]dockerInfo, len(cfg.Containers))
 	for i := range cfg.Containers {
+		if !visibleTo(cfg.Containers[i].Tenant, tenant) {
+			continue
+		}
 		name := cfg.Containers[i].Name
 		di := dockerInfo{status: "unknown"}
 		if info, err := s.manager.client.InspectContainer(ctx, name); err == nil {
@@ -990,14 +2307,18 @@ func (s *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
 	}
 	for i := range cfg.Containers {
 		c := &cfg.Containers[i]
-		di := infoMap[c.Name]
+		di, ok := infoMap[c.Name]
+		if !ok {
+			continue
+		}
 		entry := topologyContainerJSON{
 			Name:          c.Name,
-			Host:          c.Host,
+			Host:          cfg.ContainerHost(c),
 			Icon:          c.Icon,
+			IconURL:       dashboardIconURL(c, cfg.Gateway.ProxyIcons),
 			TargetPort:    c.TargetPort,
 			HealthPath:    c.HealthPath,
-			DependsOn:     c.DependsOn,
+			DependsOn:     visibleDeps(c.DependsOn, infoMap),
 			IdleTimeout:   c.IdleTimeout.String(),
 			ScheduleStart: c.ScheduleStart,
 			ScheduleStop:  c.ScheduleStop,
@@ -1012,6 +2333,9 @@ func (s *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
 		payload.Containers = append(payload.Containers, entry)
 	}
 	for _, g := range cfg.Groups {
+		if !visibleTo(g.Tenant, tenant) {
+			continue
+		}
 		payload.Groups = append(payload.Groups, topologyGroupJSON{
 			Name:       g.Name,
 			Host:       g.Host,
@@ -1021,14 +2345,15 @@ func (s *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		slog.Error("topology: marshal payload", "error", err)
+		slog.ErrorContext(r.Context(), "topology: marshal payload", "error", err)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 
 	data := topologyData{DataJSON: template.JS(payloadBytes)}
+	s.noIndex(w)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := s.tmpl.ExecuteTemplate(w, "topology.html", data); err != nil {
-		slog.Error("template render failed", "template", "topology", "error", err)
+		slog.ErrorContext(r.Context(), "template render failed", "template", "topology", "error", err)
 	}
 }
@@ -1,10 +1,19 @@
 package gateway
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 // ─── isWebSocketRequest ───────────────────────────────────────────────────────
@@ -100,6 +109,18 @@ func TestSetForwardedHeaders(t *testing.T) {
 		}
 	})
 
+	t.Run("drops the container routing header", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.1:9999"
+		r.Header.Set("X-Dag-Container", "app1")
+
+		setForwardedHeaders(r, "10.0.0.5")
+
+		if got := r.Header.Get("X-Dag-Container"); got != "" {
+			t.Errorf("X-Dag-Container = %q, want it removed", got)
+		}
+	})
+
 	t.Run("appends to existing X-Forwarded-For chain", func(t *testing.T) {
 		r := httptest.NewRequest(http.MethodGet, "/", nil)
 		r.RemoteAddr = "10.0.0.1:9999"
@@ -136,6 +157,21 @@ func TestSetForwardedHeaders(t *testing.T) {
 			t.Errorf("X-Forwarded-Proto = %q, should remain %q", got, "https")
 		}
 	})
+
+	t.Run("forwards request ID and traceparent", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.1:9999"
+		r = r.WithContext(withTraceContext(withRequestID(r.Context(), "client-id-1"), r))
+
+		setForwardedHeaders(r, "10.0.0.5")
+
+		if got := r.Header.Get("X-Request-ID"); got != "client-id-1" {
+			t.Errorf("X-Request-ID = %q, want %q", got, "client-id-1")
+		}
+		if _, ok := parseTraceparent(r.Header.Get("traceparent")); !ok {
+			t.Errorf("traceparent = %q, want a valid W3C trace context", r.Header.Get("traceparent"))
+		}
+	})
 }
 
 // ─── requestID ────────────────────────────────────────────────────────────────
@@ -188,6 +224,18 @@ func TestMetricsResponseWriter(t *testing.T) {
 		}
 	})
 
+	t.Run("counts body bytes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mw := &metricsResponseWriter{ResponseWriter: rec, statusCode: http.StatusOK}
+
+		mw.Write([]byte("hello "))
+		mw.Write([]byte("world"))
+
+		if mw.bytes != 11 {
+			t.Errorf("bytes = %d, want 11", mw.bytes)
+		}
+	})
+
 	t.Run("proxies write to underlying writer", func(t *testing.T) {
 		rec := httptest.NewRecorder()
 		mw := &metricsResponseWriter{ResponseWriter: rec, statusCode: http.StatusOK}
@@ -204,6 +252,7 @@ func TestMetricsResponseWriter(t *testing.T) {
 func TestResolveConfig(t *testing.T) {
 	s := &Server{
 		cfg: &GatewayConfig{
+			Gateway: GlobalConfig{AllowContainerQuery: true},
 			Containers: []ContainerConfig{
 				{Name: "app1", Host: "app1.local:8080"},
 				{Name: "app2", Host: "app2.local"},
@@ -216,6 +265,7 @@ func TestResolveConfig(t *testing.T) {
 		name     string
 		host     string
 		query    string
+		header   string
 		wantName string
 		wantNil  bool
 	}{
@@ -246,6 +296,31 @@ func TestResolveConfig(t *testing.T) {
 			query:   "container=nope",
 			wantNil: true,
 		},
+		{
+			name:     "header override",
+			host:     "unknown.com",
+			header:   "app2",
+			wantName: "app2",
+		},
+		{
+			name:     "header wins over query param",
+			host:     "unknown.com",
+			query:    "container=app1",
+			header:   "app2",
+			wantName: "app2",
+		},
+		{
+			name:     "host match wins over header",
+			host:     "app1.local:8080",
+			header:   "app2",
+			wantName: "app1",
+		},
+		{
+			name:    "header unknown container",
+			host:    "unknown.com",
+			header:  "nope",
+			wantNil: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -256,6 +331,9 @@ func TestResolveConfig(t *testing.T) {
 			}
 			r := httptest.NewRequest(http.MethodGet, url, nil)
 			r.Host = tt.host
+			if tt.header != "" {
+				r.Header.Set("X-Dag-Container", tt.header)
+			}
 
 			got := s.resolveConfig(r)
 			if tt.wantNil {
@@ -273,3 +351,376 @@ func TestResolveConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveConfig_ContainerQueryDisabled(t *testing.T) {
+	s := &Server{
+		cfg: &GatewayConfig{
+			Containers: []ContainerConfig{{Name: "app1", Host: "app1.local"}},
+		},
+	}
+	s.hostIndex = BuildHostIndex(s.cfg)
+
+	r := httptest.NewRequest(http.MethodGet, "/?container=app1", nil)
+	r.Host = "unknown.com"
+	r.Header.Set("X-Dag-Container", "app1")
+	if got := s.resolveConfig(r); got != nil {
+		t.Errorf("override honoured without allow_container_query: %+v", got)
+	}
+
+	r.Host = "app1.local"
+	if got := s.resolveConfig(r); got == nil || got.Name != "app1" {
+		t.Errorf("host routing broken: %+v", got)
+	}
+}
+
+func TestResolveConfig_HostPattern(t *testing.T) {
+	s := &Server{
+		cfg: &GatewayConfig{
+			Gateway: GlobalConfig{HostPattern: "{container}.apps.example.com"},
+			Containers: []ContainerConfig{
+				{Name: "jellyfin"},
+				{Name: "wiki", Host: "wiki.apps.example.com"},
+				{Name: "gitea", Host: "git.example.com"},
+			},
+		},
+	}
+	s.hostIndex = BuildHostIndex(s.cfg)
+	s.containerMap = BuildContainerMap(s.cfg)
+
+	tests := []struct {
+		host string
+		want string // "" → no match
+	}{
+		{"jellyfin.apps.example.com", "jellyfin"},
+		{"Jellyfin.apps.example.com:8080", "jellyfin"},
+		{"gitea.apps.example.com", "gitea"},
+		{"wiki.apps.example.com", "wiki"},
+		{"unknown.apps.example.com", ""},
+		{"a.jellyfin.apps.example.com", ""},
+		{"jellyfin.example.com", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = tt.host
+		got := s.resolveConfig(r)
+		switch {
+		case tt.want == "" && got != nil:
+			t.Errorf("%s: routed to %q, want no match", tt.host, got.Name)
+		case tt.want != "" && (got == nil || got.Name != tt.want):
+			t.Errorf("%s: got %+v, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+// ─── removedNames ─────────────────────────────────────────────────────────────
+
+func TestRemovedNames(t *testing.T) {
+	got := removedNames([]string{"a", "b", "c"}, []string{"c", "a", "d"})
+	if len(got) != 1 || got[0] != "b" {
+		t.Errorf("removedNames() = %v, want [b]", got)
+	}
+	if got := removedNames(nil, []string{"a"}); len(got) != 0 {
+		t.Errorf("removedNames(nil, ...) = %v, want empty", got)
+	}
+}
+
+// ─── classifyProxyError ───────────────────────────────────────────────────────
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyProxyError(t *testing.T) {
+	// A closed listener yields a genuine "connection refused".
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	_, refusedErr := net.Dial("tcp", addr)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"connection refused", refusedErr, "refused"},
+		{"dial timeout", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, "dial_timeout"},
+		{"read timeout", &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, "timeout"},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, "reset"},
+		{"unexpected EOF", fmt.Errorf("readLoop: %w", io.EOF), "reset"},
+		{"client canceled", context.Canceled, "canceled"},
+		{"deadline exceeded", context.DeadlineExceeded, "timeout"},
+		{"other", errors.New("malformed response"), "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyProxyError(tt.err); got != tt.want {
+				t.Errorf("classifyProxyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyRequest_ErrorPage(t *testing.T) {
+	tmpl, err := template.ParseFS(templatesFS, "templates/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close() // connection refused
+	host, port, _ := net.SplitHostPort(down.Listener.Addr().String())
+
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(NewFakeRuntime()), tmpl: tmpl}
+	cfg := &ContainerConfig{Name: host, TargetPort: port, Target: TargetDNS}
+	call := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		r.Header.Set("Accept", accept)
+		r = r.WithContext(withRequestID(r.Context(), "req-abc123"))
+		w := httptest.NewRecorder()
+		s.proxyRequest(w, r, cfg)
+		return w
+	}
+
+	w := call("text/html,application/xhtml+xml,*/*")
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want the HTML error page", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "req-abc123") || !strings.Contains(body, "refused the connection") {
+		t.Errorf("error page lacks the request ID or the cause: %q", body)
+	}
+
+	w = call("application/json")
+	var got errorJSON
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("JSON error body: %v", err)
+	}
+	want := errorJSON{Error: "The service refused the connection", Container: host, Status: http.StatusBadGateway, RequestID: "req-abc123"}
+	if got != want {
+		t.Errorf("JSON error = %+v, want %+v", got, want)
+	}
+}
+
+func TestWantsJSON(t *testing.T) {
+	tests := []struct {
+		accept, requestedWith, userAgent string
+		want                             bool
+	}{
+		{"application/json", "", "", true},
+		{"application/problem+json", "", "", true},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "", "", false},
+		{"text/html, application/json", "", "", false},
+		{"*/*", "", "", false},
+		{"", "XMLHttpRequest", "", true},
+		{"*/*", "", "curl/8.5.0", true},
+		{"", "", "Go-http-client/1.1", true},
+		{"text/html", "", "python-requests/2.31", false},
+		{"*/*", "", "Mozilla/5.0 (X11; Linux x86_64)", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		r.Header.Set("X-Requested-With", tt.requestedWith)
+		r.Header.Set("User-Agent", tt.userAgent)
+		if got := wantsJSON(r); got != tt.want {
+			t.Errorf("wantsJSON(Accept %q, X-Requested-With %q, User-Agent %q) = %v, want %v",
+				tt.accept, tt.requestedWith, tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestLoadingPage_JSON(t *testing.T) {
+	host, port := newBackend(t, "app")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: port})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	r.Host = "app.local"
+	r.Header.Set("Accept", "application/json")
+	g.handler.ServeHTTP(w, r)
+	g.waitStarted(t, "app")
+
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "5" {
+		t.Errorf("status %d, Retry-After %q; want 503 with Retry-After 5", w.Code, w.Header().Get("Retry-After"))
+	}
+	var got loadingJSON
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("JSON loading body: %v", err)
+	}
+	if got.Status != "starting" || got.Container != "app" || got.StartState != "starting" ||
+		got.StartTimeout != "1m0s" || got.RetryAfter != 5 || got.RequestID == "" {
+		t.Errorf("loading JSON = %+v", got)
+	}
+}
+
+// freePort returns a port that was free a moment ago.
+func freePort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+func TestServer_ReloadListenerSettings(t *testing.T) {
+	cfg := &GatewayConfig{}
+	cfg.Gateway.Port = "0"
+	applyDefaults(cfg)
+	s, err := NewServer(NewContainerManager(NewFakeRuntime()), NewScheduleManager(nil, nil), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Start(ctx)
+
+	waitAddr := func(not net.Addr) string {
+		t.Helper()
+		for i := 0; i < 200; i++ {
+			if a := s.Addr(); a != nil && a != not {
+				_, port, _ := net.SplitHostPort(a.String())
+				return port
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatal("server did not start listening")
+		return ""
+	}
+	get := func(port, token string) (int, error) {
+		req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+port+"/_version", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	oldPort := waitAddr(nil)
+	if code, err := get(oldPort, ""); err != nil || code != http.StatusOK {
+		t.Fatalf("before reload: (%d, %v), want 200", code, err)
+	}
+
+	newCfg := *cfg
+	newCfg.Gateway.Port = freePort(t)
+	newCfg.Gateway.AdminAuth = AdminAuthConfig{Method: "bearer", Token: "secret"}
+	oldAddr := s.Addr()
+	if err := s.ReloadConfig(&newCfg); err != nil {
+		t.Fatal(err)
+	}
+	if port := waitAddr(oldAddr); port != newCfg.Gateway.Port {
+		t.Fatalf("listening on %s, want the new port %s", port, newCfg.Gateway.Port)
+	}
+
+	if code, err := get(newCfg.Gateway.Port, ""); err != nil || code != http.StatusUnauthorized {
+		t.Errorf("new port without token: (%d, %v), want 401", code, err)
+	}
+	if code, err := get(newCfg.Gateway.Port, "secret"); err != nil || code != http.StatusOK {
+		t.Errorf("new port with token: (%d, %v), want 200", code, err)
+	}
+
+	// The old listener is drained and closed.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := get(oldPort, "secret"); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("old port still accepts requests")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A port that cannot be bound keeps the current listener.
+	busy, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	_, busyPort, _ := net.SplitHostPort(busy.Addr().String())
+	badCfg := newCfg
+	badCfg.Gateway.Port = busyPort
+	if err := s.ReloadConfig(&badCfg); err == nil {
+		t.Error("reload to a busy port: want an error")
+	}
+	if got := s.GetConfig().Gateway.Port; got != newCfg.Gateway.Port {
+		t.Errorf("port after a failed rebind = %s, want the previous configuration kept", got)
+	}
+	if code, err := get(newCfg.Gateway.Port, "secret"); err != nil || code != http.StatusOK {
+		t.Errorf("after a failed rebind: (%d, %v), want the current listener to keep serving", code, err)
+	}
+}
+
+// ─── /_status/kill and /_status/reset ────────────────────────────────────────
+
+func TestHandleStatusKillReset_Validation(t *testing.T) {
+	s := &Server{
+		cfg:         &GatewayConfig{Containers: []ContainerConfig{{Name: "app"}}},
+		rateLimiter: newRateLimiter(RateLimitConfig{}),
+	}
+	handlers := map[string]http.HandlerFunc{
+		"/_status/kill":  s.handleStatusKill,
+		"/_status/reset": s.handleStatusReset,
+	}
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		want   int
+	}{
+		{"GET not allowed", http.MethodGet, "?container=app", http.StatusMethodNotAllowed},
+		{"missing container", http.MethodPost, "", http.StatusBadRequest},
+		{"unknown container", http.MethodPost, "?container=nope", http.StatusBadRequest},
+	}
+	for path, h := range handlers {
+		for _, tt := range tests {
+			t.Run(path+" "+tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				h(w, httptest.NewRequest(tt.method, path+tt.query, nil))
+				if w.Code != tt.want {
+					t.Errorf("status = %d, want %d", w.Code, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestHandleStatusReset(t *testing.T) {
+	s := &Server{
+		cfg:         &GatewayConfig{Containers: []ContainerConfig{{Name: "app"}}},
+		manager:     NewContainerManager(NewFakeRuntime()),
+		rateLimiter: newRateLimiter(RateLimitConfig{}),
+	}
+	s.manager.setStartState("app", statusStarting, "")
+
+	w := httptest.NewRecorder()
+	s.handleStatusReset(w, httptest.NewRequest(http.MethodPost, "/_status/reset?container=app", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		OK       bool   `json:"ok"`
+		Previous string `json:"previous"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil || !body.OK || body.Previous != "starting" {
+		t.Errorf("body = %+v (%v), want ok with previous starting", body, err)
+	}
+	if status, _ := s.manager.GetStartState("app"); status != "unknown" {
+		t.Errorf("start state = %q, want unknown", status)
+	}
+}
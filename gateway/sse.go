@@ -0,0 +1,30 @@
+package gateway
+
+import (
+	"mime"
+	"net/http"
+	"time"
+)
+
+// isEventStream reports whether resp is a Server-Sent Events stream. The
+// reverse proxy flushes such responses after every write on its own.
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// keepStreamAwake records activity on the container every interval while an
+// event stream to it is open, until stop is closed: an SSE client waiting
+// for the next event is using the app as much as one sending requests.
+func (s *Server) keepStreamAwake(cfg *ContainerConfig, every time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.manager.RecordActivityChain([]string{cfg.Name}, s.GetConfig().Containers)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsEventStream(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/event-stream", true},
+		{"text/event-stream; charset=utf-8", true},
+		{"text/html", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{"Content-Type": {tt.contentType}}}
+		if got := isEventStream(resp); got != tt.want {
+			t.Errorf("isEventStream(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestProxyRequest_EventStream(t *testing.T) {
+	const events = 6
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := range events {
+			w.Write([]byte("data: tick\n\n"))
+			w.(http.Flusher).Flush()
+			if i < events-1 {
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+	}))
+	defer backend.Close()
+	host, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(NewFakeRuntime())}
+	cfg := &ContainerConfig{Name: host, TargetPort: port, Target: TargetDNS}
+	gw := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.proxyRequest(&metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}, r, cfg)
+	}))
+	// Shorter than the stream: it must not cut it.
+	gw.Config.WriteTimeout = 120 * time.Millisecond
+	gw.Start()
+	defer gw.Close()
+
+	start := time.Now()
+	resp, err := http.Get(gw.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(resp.Body)
+	got := 0
+	for sc.Scan() {
+		if !strings.HasPrefix(sc.Text(), "data:") {
+			continue
+		}
+		if got++; got == 1 && time.Since(start) > 40*time.Millisecond {
+			t.Errorf("first event took %v, want it flushed at once", time.Since(start))
+		}
+	}
+	if got != events {
+		t.Errorf("received %d events, want %d (err %v)", got, events, sc.Err())
+	}
+}
+
+func TestKeepStreamAwake(t *testing.T) {
+	s := &Server{cfg: &GatewayConfig{Containers: []ContainerConfig{{Name: "app"}}}, manager: NewContainerManager(NewFakeRuntime())}
+	stop := make(chan struct{})
+	go s.keepStreamAwake(&s.cfg.Containers[0], 10*time.Millisecond, stop)
+	waitFor(t, func() bool {
+		s.manager.mu.Lock()
+		defer s.manager.mu.Unlock()
+		return !s.manager.lastSeen["app"].IsZero()
+	})
+	close(stop)
+}
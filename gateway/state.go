@@ -0,0 +1,217 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// stateBundleVersion is the format version of StateBundle. Bundles with a
+// different version are refused on import.
+const stateBundleVersion = 1
+
+// maxStateBundleSize caps the body of a state import.
+const maxStateBundleSize = 10 << 20
+
+// StateBundle is the runtime state of a gateway, exported by GET
+// /_status/state and imported by POST /_status/state when the gateway moves
+// to another host. Configuration is not part of it: copy config.yaml along.
+// That includes containers changed through /_api/v1/containers, which are
+// configuration (see admin_api.persist), and affinity pins, which live in
+// client cookies. The log level set through /_admin/loglevel is the one
+// runtime override the gateway has, and is exported.
+type StateBundle struct {
+	Version        int    `json:"version"`
+	GatewayVersion string `json:"gateway_version"`
+	ExportedAt     string `json:"exported_at"`
+	// LogLevel is the application log level set through /_admin/loglevel,
+	// omitted while it matches log_level.
+	LogLevel   string                          `json:"log_level,omitempty"`
+	Containers map[string]ContainerStateBundle `json:"containers"`
+}
+
+// ContainerStateBundle is the exported state of one container.
+type ContainerStateBundle struct {
+	LastRequest string `json:"last_request,omitempty"`
+	// Runtime is the savings history, keyed by UTC day ("2006-01-02").
+	Runtime map[string]DayUsageBundle `json:"runtime,omitempty"`
+	// BusyHours is the prewarm usage history, as Unix hours.
+	BusyHours []int64 `json:"busy_hours,omitempty"`
+	// Schedule is informational: schedules come from the configuration and
+	// are not changed by an import.
+	Schedule *ScheduleBundle `json:"schedule,omitempty"`
+}
+
+// DayUsageBundle is one day of runtime history.
+type DayUsageBundle struct {
+	RunningSeconds int64 `json:"running_seconds"`
+	AsleepSeconds  int64 `json:"asleep_seconds"`
+	Wakes          int   `json:"wakes"`
+}
+
+// ScheduleBundle records the schedule a container had on the exporting gateway.
+type ScheduleBundle struct {
+	Start    string `json:"start,omitempty"`
+	Stop     string `json:"stop,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// StateImportResult summarises what an import applied.
+type StateImportResult struct {
+	Containers int      `json:"containers"`
+	Skipped    []string `json:"skipped"`
+	LogLevel   string   `json:"log_level,omitempty"`
+}
+
+// ExportState collects the runtime state of the given containers.
+func (m *ContainerManager) ExportState(cfgs []ContainerConfig, now time.Time) StateBundle {
+	b := StateBundle{
+		Version:        stateBundleVersion,
+		GatewayVersion: Version,
+		ExportedAt:     now.UTC().Format(time.RFC3339),
+		LogLevel:       logLevelOverride(),
+		Containers:     make(map[string]ContainerStateBundle, len(cfgs)),
+	}
+	for _, c := range cfgs {
+		var cs ContainerStateBundle
+		if t, ok := m.GetLastSeen(c.Name); ok {
+			cs.LastRequest = t.UTC().Format(time.RFC3339Nano)
+		}
+		if days := m.runtime.Days(c.Name); len(days) > 0 {
+			cs.Runtime = make(map[string]DayUsageBundle, len(days))
+			for key, d := range days {
+				cs.Runtime[key] = DayUsageBundle{
+					RunningSeconds: int64(d.Running.Seconds()),
+					AsleepSeconds:  int64(d.Asleep.Seconds()),
+					Wakes:          d.Wakes,
+				}
+			}
+		}
+		cs.BusyHours = m.usage.busyHours(c.Name)
+		if c.ScheduleStart != "" || c.ScheduleStop != "" {
+			cs.Schedule = &ScheduleBundle{Start: c.ScheduleStart, Stop: c.ScheduleStop, Timezone: c.ScheduleTimezone}
+		}
+		b.Containers[c.Name] = cs
+	}
+	return b
+}
+
+// ImportState merges a bundle into the runtime state. Only containers in
+// cfgs are imported; the others are reported as skipped. Merging keeps the
+// newer activity and the larger counters, so an import can be repeated.
+func (m *ContainerManager) ImportState(b StateBundle, cfgs []ContainerConfig, now time.Time) (StateImportResult, error) {
+	if b.Version != stateBundleVersion {
+		return StateImportResult{}, fmt.Errorf("unsupported state bundle version %d (want %d)", b.Version, stateBundleVersion)
+	}
+	var lastSeen map[string]time.Time
+	for name, cs := range b.Containers {
+		if cs.LastRequest == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, cs.LastRequest)
+		if err != nil {
+			return StateImportResult{}, fmt.Errorf("container %q: invalid last_request: %w", name, err)
+		}
+		if lastSeen == nil {
+			lastSeen = make(map[string]time.Time)
+		}
+		lastSeen[name] = t
+	}
+	var level string
+	if b.LogLevel != "" {
+		if _, err := parseLogLevel(b.LogLevel); err != nil {
+			return StateImportResult{}, err
+		}
+		level = b.LogLevel
+	}
+
+	known := make(map[string]bool, len(cfgs))
+	for _, c := range cfgs {
+		known[c.Name] = true
+	}
+	res := StateImportResult{Skipped: []string{}}
+	for name, cs := range b.Containers {
+		if !known[name] {
+			res.Skipped = append(res.Skipped, name)
+			continue
+		}
+		if t, ok := lastSeen[name]; ok {
+			m.mu.Lock()
+			if t.After(m.lastSeen[name]) {
+				m.lastSeen[name] = t
+			}
+			m.mu.Unlock()
+		}
+		if len(cs.Runtime) > 0 {
+			days := make(map[string]dayUsage, len(cs.Runtime))
+			for key, d := range cs.Runtime {
+				days[key] = dayUsage{
+					Running: time.Duration(d.RunningSeconds) * time.Second,
+					Asleep:  time.Duration(d.AsleepSeconds) * time.Second,
+					Wakes:   d.Wakes,
+				}
+			}
+			m.runtime.MergeDays(name, days, now)
+		}
+		m.usage.merge(name, cs.BusyHours)
+		res.Containers++
+	}
+	sort.Strings(res.Skipped)
+	if level != "" {
+		lvl, _ := parseLogLevel(level)
+		LogLevel.Set(lvl)
+		res.LogLevel = level
+	}
+	return res, nil
+}
+
+// handleStatusState exports the runtime state as a JSON bundle (GET) or
+// imports one from the request body (POST).
+func (s *Server) handleStatusState(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if !s.allowRate(w, r, "status_state") {
+			return
+		}
+		bundle := s.manager.ExportState(s.GetConfig().Containers, time.Now())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="gateway-state.json"`)
+		w.Header().Set("Cache-Control", "no-store")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(bundle)
+	case http.MethodPost:
+		if !validateOrigin(r) {
+			http.Error(w, "cross-origin request blocked", http.StatusForbidden)
+			return
+		}
+		if !s.allowRate(w, r, "status_state") {
+			return
+		}
+		var bundle StateBundle
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxStateBundleSize)).Decode(&bundle); err != nil {
+			http.Error(w, fmt.Sprintf("invalid state bundle: %v", err), http.StatusBadRequest)
+			return
+		}
+		res, err := s.manager.ImportState(bundle, s.GetConfig().Containers, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.WarnContext(r.Context(), "runtime state imported via admin API",
+			"containers", res.Containers, "skipped", res.Skipped, "from_version", bundle.GatewayVersion)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":         true,
+			"containers": res.Containers,
+			"skipped":    res.Skipped,
+			"log_level":  res.LogLevel,
+		})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
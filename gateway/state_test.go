@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportState(t *testing.T) {
+	now := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	cfgs := []ContainerConfig{
+		{Name: "app", ScheduleStart: "0 8 * * *", ScheduleStop: "0 20 * * *"},
+		{Name: "db"},
+	}
+
+	src := NewContainerManager(NewFakeRuntime())
+	src.mu.Lock()
+	src.lastSeen["app"] = now.Add(-time.Minute)
+	src.mu.Unlock()
+	src.runtime.RecordWake("app", now)
+	src.runtime.Observe("app", true, now.Add(-time.Minute))
+	src.runtime.Observe("app", false, now)
+	src.usage.record([]string{"app"}, now.Add(-7*24*time.Hour))
+
+	bundle := src.ExportState(cfgs, now)
+	if bundle.Version != stateBundleVersion || len(bundle.Containers) != 2 {
+		t.Fatalf("bundle = %+v", bundle)
+	}
+	app := bundle.Containers["app"]
+	if app.Schedule == nil || app.Schedule.Start != "0 8 * * *" || len(app.BusyHours) != 1 {
+		t.Errorf("app state = %+v", app)
+	}
+
+	// Round trip through JSON, as between two hosts.
+	raw, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded StateBundle
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	decoded.Containers["gone"] = ContainerStateBundle{LastRequest: now.Format(time.RFC3339)}
+
+	dst := NewContainerManager(NewFakeRuntime())
+	for i := 0; i < 2; i++ { // importing twice must not double the counters
+		res, err := dst.ImportState(decoded, cfgs, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Containers != 2 || len(res.Skipped) != 1 || res.Skipped[0] != "gone" {
+			t.Errorf("import %d result = %+v", i, res)
+		}
+	}
+
+	if seen, ok := dst.GetLastSeen("app"); !ok || !seen.Equal(now.Add(-time.Minute)) {
+		t.Errorf("last seen = %v (%v), want %v", seen, ok, now.Add(-time.Minute))
+	}
+	if _, ok := dst.GetLastSeen("gone"); ok {
+		t.Error("unknown container must not be imported")
+	}
+	got, want := dst.runtime.Summary("app", now), src.runtime.Summary("app", now)
+	if got != want {
+		t.Errorf("runtime summary = %+v, want %+v", got, want)
+	}
+	if dst.usage.busyWeeks("app", now) != 1 {
+		t.Error("usage history not imported")
+	}
+
+	t.Run("newer local activity wins", func(t *testing.T) {
+		dst.mu.Lock()
+		dst.lastSeen["app"] = now
+		dst.mu.Unlock()
+		if _, err := dst.ImportState(decoded, cfgs, now); err != nil {
+			t.Fatal(err)
+		}
+		if seen, _ := dst.GetLastSeen("app"); !seen.Equal(now) {
+			t.Errorf("last seen = %v, want %v", seen, now)
+		}
+	})
+}
+
+func TestImportState_Invalid(t *testing.T) {
+	m := NewContainerManager(NewFakeRuntime())
+	tests := []struct {
+		name   string
+		bundle StateBundle
+	}{
+		{"wrong version", StateBundle{Version: 99}},
+		{"bad timestamp", StateBundle{Version: stateBundleVersion, Containers: map[string]ContainerStateBundle{"app": {LastRequest: "yesterday"}}}},
+		{"bad log level", StateBundle{Version: stateBundleVersion, LogLevel: "loud"}},
+	}
+	for _, tt := range tests {
+		if _, err := m.ImportState(tt.bundle, []ContainerConfig{{Name: "app"}}, time.Now()); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	if _, ok := m.GetLastSeen("app"); ok {
+		t.Error("a refused bundle must not be partially applied")
+	}
+}
+
+func TestHandleStatusState(t *testing.T) {
+	defer LogLevel.Set(LogLevel.Level())
+	s := &Server{
+		cfg:         &GatewayConfig{Containers: []ContainerConfig{{Name: "app"}}},
+		manager:     NewContainerManager(NewFakeRuntime()),
+		rateLimiter: newRateLimiter(RateLimitConfig{}),
+	}
+	s.manager.RecordActivity("app")
+
+	w := httptest.NewRecorder()
+	s.handleStatusState(w, httptest.NewRequest(http.MethodGet, "/_status/state", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Fatalf("export status = %d, headers = %v", w.Code, w.Header())
+	}
+	exported := w.Body.Bytes()
+
+	var bundle StateBundle
+	if err := json.Unmarshal(exported, &bundle); err != nil || bundle.Containers["app"].LastRequest == "" {
+		t.Fatalf("exported bundle = %s (%v)", exported, err)
+	}
+	bundle.LogLevel = "debug"
+	body, _ := json.Marshal(bundle)
+	w = httptest.NewRecorder()
+	s.handleStatusState(w, httptest.NewRequest(http.MethodPost, "/_status/state", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("import status = %d: %s", w.Code, w.Body)
+	}
+	if LogLevel.Level() != slog.LevelDebug {
+		t.Errorf("log level = %v, want debug", LogLevel.Level())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		origin string
+		want   int
+	}{
+		{"invalid JSON", http.MethodPost, "{", "", http.StatusBadRequest},
+		{"wrong version", http.MethodPost, `{"version":2}`, "", http.StatusBadRequest},
+		{"cross origin", http.MethodPost, string(exported), "http://evil.example", http.StatusForbidden},
+		{"DELETE not allowed", http.MethodDelete, "", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/_status/state", strings.NewReader(tt.body))
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+		s.handleStatusState(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestRunStateCommand(t *testing.T) {
+	var gotAuth, gotMethod, gotBody string
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_status/state" {
+			http.NotFound(w, r)
+			return
+		}
+		gotAuth = r.Header.Get("Authorization")
+		gotMethod = r.Method
+		body := new(bytes.Buffer)
+		body.ReadFrom(r.Body)
+		gotBody = body.String()
+		w.Write([]byte(`{"version":1}`))
+	}))
+	defer gw.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfgYAML := "gateway:\n  admin_auth:\n    method: bearer\n    token: s3cr3t\ncontainers: []\n"
+	if err := os.WriteFile(path, []byte(cfgYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_PATH", path)
+
+	var out bytes.Buffer
+	if err := RunStateCommand("export-state", []string{"-url", gw.URL}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if gotMethod != http.MethodGet || gotAuth != "Bearer s3cr3t" || out.String() != `{"version":1}` {
+		t.Errorf("export: method %s, auth %q, output %q", gotMethod, gotAuth, out.String())
+	}
+
+	out.Reset()
+	if err := RunStateCommand("import-state", []string{"-url", gw.URL + "/"}, strings.NewReader(`{"version":1}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if gotMethod != http.MethodPost || gotBody != `{"version":1}` {
+		t.Errorf("import: method %s, body %q", gotMethod, gotBody)
+	}
+
+	if err := RunStateCommand("export-state", []string{"-url", gw.URL + "/nope"}, nil, &out); err == nil {
+		t.Error("a non-200 answer should fail the command")
+	}
+}
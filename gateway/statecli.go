@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// stateCommandTimeout bounds a single export-state or import-state call.
+const stateCommandTimeout = 30 * time.Second
+
+// IsStateCommand reports whether name is one of the state subcommands.
+func IsStateCommand(name string) bool {
+	return name == "export-state" || name == "import-state"
+}
+
+// RunStateCommand runs `export-state` (bundle written to out) or
+// `import-state` (bundle read from in) against a running gateway. The URL
+// defaults to the local gateway port from the configuration, and the admin
+// credentials are taken from admin_auth.
+func RunStateCommand(name string, args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	baseURL := fs.String("url", "", "gateway base URL (default: http://127.0.0.1:<gateway.port>)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if *baseURL == "" {
+		*baseURL = "http://127.0.0.1:" + cfg.Gateway.Port
+	}
+	target := strings.TrimRight(*baseURL, "/") + "/_status/state"
+
+	var req *http.Request
+	switch name {
+	case "export-state":
+		req, err = http.NewRequest(http.MethodGet, target, nil)
+	case "import-state":
+		req, err = http.NewRequest(http.MethodPost, target, in)
+		if req != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+	if err != nil {
+		return err
+	}
+	setAdminCredentials(req, cfg.Gateway.AdminAuth)
+
+	client := &http.Client{Timeout: stateCommandTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s: %s", name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// setAdminCredentials authenticates req as admin_auth requires.
+func setAdminCredentials(req *http.Request, auth AdminAuthConfig) {
+	switch auth.Method {
+	case "basic":
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	}
+}
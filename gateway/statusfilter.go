@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// statusAPIFields is the set of JSON field names of statusContainerJSON,
+// i.e. the values accepted by ?fields= on /_status/api.
+var statusAPIFields = jsonFieldNames(reflect.TypeOf(statusContainerJSON{}))
+
+// inspectFields are the fields that need a Docker inspect of the container.
+// crash_loop and its details depend on the live status too.
+var inspectFields = []string{"status", "image", "started_at", "crash_loop", "crash_count", "next_start_attempt"}
+
+// jsonFieldNames returns the JSON names of the exported fields of struct t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// statusFilter holds the query parameters of /_status/api:
+//
+//	?name=web,api-*            container names (path.Match patterns)
+//	?state=running,exited      Docker status of the container
+//	?fields=name,status,...    fields returned for each container
+//
+// Each parameter takes a comma-separated list; an empty list matches all.
+type statusFilter struct {
+	names  []string
+	states map[string]bool
+	fields map[string]bool
+}
+
+// parseStatusFilter parses the /_status/api query, rejecting bad name
+// patterns and unknown fields.
+func parseStatusFilter(q url.Values) (*statusFilter, error) {
+	f := &statusFilter{names: splitList(q.Get("name"))}
+	for _, p := range f.names {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q", p)
+		}
+	}
+	if states := splitList(q.Get("state")); len(states) > 0 {
+		f.states = make(map[string]bool, len(states))
+		for _, s := range states {
+			f.states[s] = true
+		}
+	}
+	if fields := splitList(q.Get("fields")); len(fields) > 0 {
+		f.fields = make(map[string]bool, len(fields))
+		for _, name := range fields {
+			if !statusAPIFields[name] {
+				return nil, fmt.Errorf("unknown field %q", name)
+			}
+			f.fields[name] = true
+		}
+	}
+	return f, nil
+}
+
+// splitList splits a comma-separated parameter, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// matchName reports whether the container name passes ?name=.
+func (f *statusFilter) matchName(name string) bool {
+	if len(f.names) == 0 {
+		return true
+	}
+	for _, p := range f.names {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchState reports whether the Docker status passes ?state=.
+func (f *statusFilter) matchState(status string) bool {
+	return len(f.states) == 0 || f.states[status]
+}
+
+// needsInspect reports whether the response depends on a Docker inspect.
+// Without ?state= and with ?fields= limited to gateway-side data, the
+// container is not inspected at all.
+func (f *statusFilter) needsInspect() bool {
+	if len(f.states) > 0 || len(f.fields) == 0 {
+		return true
+	}
+	for _, name := range inspectFields {
+		if f.fields[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// project returns entry reduced to the fields of ?fields=, or entry itself
+// when no fields were requested.
+func (f *statusFilter) project(entry statusContainerJSON) any {
+	if len(f.fields) == 0 {
+		return entry
+	}
+	raw, _ := json.Marshal(entry)
+	var all map[string]json.RawMessage
+	json.Unmarshal(raw, &all)
+	out := make(map[string]json.RawMessage, len(f.fields))
+	for name := range f.fields {
+		if v, ok := all[name]; ok {
+			out[name] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseStatusFilter(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"", false},
+		{"name=web,api-*&state=running&fields=name,status", false},
+		{"fields=name,,status", false},
+		{"fields=name,bogus", true},
+		{"name=web[", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			_, err := parseStatusFilter(q)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusFilter_Match(t *testing.T) {
+	q, _ := url.ParseQuery("name=web,api-*&state=running,paused")
+	f, err := parseStatusFilter(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"web": true, "api-v2": true, "webapp": false, "db": false} {
+		if got := f.matchName(name); got != want {
+			t.Errorf("matchName(%q) = %v, want %v", name, got, want)
+		}
+	}
+	for state, want := range map[string]bool{"running": true, "paused": true, "exited": false} {
+		if got := f.matchState(state); got != want {
+			t.Errorf("matchState(%q) = %v, want %v", state, got, want)
+		}
+	}
+}
+
+func TestStatusFilter_NeedsInspect(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"fields=name,last_request", false},
+		{"fields=name,status", true},
+		{"fields=name,crash_loop", true},
+		{"fields=name&state=running", true},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		f, _ := parseStatusFilter(q)
+		if got := f.needsInspect(); got != tt.want {
+			t.Errorf("%q: needsInspect = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHandleStatusAPI_Filters(t *testing.T) {
+	var inspects atomic.Int32
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		w.Header().Set("Content-Type", "application/json")
+		inspects.Add(1)
+		status := "exited"
+		if strings.Contains(r.URL.Path, "/containers/web/") {
+			status = "running"
+		}
+		w.Write([]byte(`{"State":{"Status":"` + status + `"},"Config":{"Image":"nginx"}}`))
+	}))
+	defer daemon.Close()
+
+	s := &Server{
+		cfg:         &GatewayConfig{Containers: []ContainerConfig{{Name: "web"}, {Name: "api"}, {Name: "db"}}},
+		manager:     NewContainerManager(newTestDockerClient(t, "tcp://"+daemon.Listener.Addr().String())),
+		rateLimiter: newRateLimiter(RateLimitConfig{StatusAPI: RateLimitPolicy{Burst: 100}}),
+	}
+	get := func(query string) (int, []map[string]any) {
+		w := httptest.NewRecorder()
+		s.handleStatusAPI(w, httptest.NewRequest(http.MethodGet, "/_status/api?"+query, nil))
+		var resp struct {
+			Containers []map[string]any `json:"containers"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Containers
+	}
+
+	if _, got := get("state=running"); len(got) != 1 || got[0]["name"] != "web" {
+		t.Errorf("state=running: %v", got)
+	}
+
+	inspects.Store(0)
+	_, got := get("name=web,a*&fields=name,last_request")
+	if len(got) != 2 || len(got[0]) != 1 || got[0]["name"] != "web" || got[1]["name"] != "api" {
+		t.Errorf("name+fields: %v", got)
+	}
+	if n := inspects.Load(); n != 0 {
+		t.Errorf("containers inspected %d times, want 0", n)
+	}
+
+	if code, _ := get("fields=nope"); code != http.StatusBadRequest {
+		t.Errorf("unknown field: status = %d, want 400", code)
+	}
+}
@@ -0,0 +1,427 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tcpHelloTimeout bounds the wait for the TLS ClientHello of a connection
+// to a listen address with SNI routes.
+const tcpHelloTimeout = 10 * time.Second
+
+// validateTCPRoutes checks the tcp_routes section: a listen address and a
+// known container per route, and one route per listen address and SNI.
+func (c *GatewayConfig) validateTCPRoutes() error {
+	containers := make(map[string]bool, len(c.Containers))
+	for _, ctr := range c.Containers {
+		containers[ctr.Name] = true
+	}
+	seen := make(map[string]bool)
+	for i, rt := range c.TCPRoutes {
+		if rt.Listen == "" {
+			return fmt.Errorf("tcp route #%d is missing required field 'listen'", i+1)
+		}
+		_, port, err := net.SplitHostPort(rt.Listen)
+		if err != nil || !validPort(port) {
+			return fmt.Errorf("tcp route %q: listen must be [host]:port", rt.Listen)
+		}
+		if port == c.Gateway.Port || (c.Gateway.ACME.Enabled && port == c.Gateway.ACME.HTTPSPort) {
+			return fmt.Errorf("tcp route %q: port %s is already used by the gateway", rt.Listen, port)
+		}
+		if rt.Container == "" {
+			return fmt.Errorf("tcp route %q is missing required field 'container'", rt.Listen)
+		}
+		if !containers[rt.Container] {
+			return fmt.Errorf("tcp route %q: unknown container %q", rt.Listen, rt.Container)
+		}
+		if rt.Port != "" && !validPort(rt.Port) {
+			return fmt.Errorf("tcp route %q: invalid port %q", rt.Listen, rt.Port)
+		}
+		switch rt.WakeMode {
+		case "", WakeModeHold, WakeModeClose:
+		default:
+			return fmt.Errorf("tcp route %q: unknown wake_mode %q (allowed: %s, %s)", rt.Listen, rt.WakeMode, WakeModeHold, WakeModeClose)
+		}
+		key := rt.Listen + " " + strings.ToLower(rt.SNI)
+		if seen[key] {
+			if rt.SNI == "" {
+				return fmt.Errorf("tcp route %q: more than one route without sni", rt.Listen)
+			}
+			return fmt.Errorf("tcp route %q: duplicate sni %q", rt.Listen, rt.SNI)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// validPort reports whether port is a TCP port number.
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// tcpProxy holds the listeners of tcp_routes and their open connections.
+type tcpProxy struct {
+	mu        sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+// track registers an accepted connection until the returned function is
+// called.
+func (p *tcpProxy) track(c net.Conn) (untrack func()) {
+	p.mu.Lock()
+	if p.conns == nil {
+		p.conns = make(map[net.Conn]struct{})
+	}
+	p.conns[c] = struct{}{}
+	p.mu.Unlock()
+	p.wg.Add(1)
+	return func() {
+		p.mu.Lock()
+		delete(p.conns, c)
+		p.mu.Unlock()
+		p.wg.Done()
+	}
+}
+
+// close stops accepting connections and waits for the open ones to end
+// until ctx expires; those left then are closed.
+func (p *tcpProxy) close(ctx context.Context) {
+	p.mu.Lock()
+	for _, ln := range p.listeners {
+		ln.Close()
+	}
+	p.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		p.mu.Lock()
+		for c := range p.conns {
+			c.Close()
+		}
+		p.mu.Unlock()
+		<-done
+	}
+}
+
+// listenTCPRoutes binds the listen addresses of routes and serves them.
+func (s *Server) listenTCPRoutes(routes []TCPRouteConfig) error {
+	bound := make(map[string]bool)
+	for _, rt := range routes {
+		if bound[rt.Listen] {
+			continue
+		}
+		bound[rt.Listen] = true
+		ln, err := net.Listen("tcp", rt.Listen)
+		if err != nil {
+			s.tcp.close(context.Background())
+			return fmt.Errorf("tcp route: cannot listen on %s: %w", rt.Listen, err)
+		}
+		s.tcp.mu.Lock()
+		s.tcp.listeners = append(s.tcp.listeners, ln)
+		s.tcp.mu.Unlock()
+		slog.Info("accepting TCP connections", "listen", rt.Listen)
+		go s.serveTCPRoutes(ln, rt.Listen)
+	}
+	return nil
+}
+
+// serveTCPRoutes accepts the connections of the listen address until ln is
+// closed.
+func (s *Server) serveTCPRoutes(ln net.Listener, listen string) {
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Warn("tcp route: accept failed", "listen", listen, "error", err)
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+		go s.serveTCPConn(conn, listen)
+	}
+}
+
+// serveTCPConn routes a connection accepted on listen and forwards it to its
+// container. A container that is not running is started first, and the
+// connection held until it is ready or, with wake_mode "close", closed: the
+// client connects again once it is.
+func (s *Server) serveTCPConn(client net.Conn, listen string) {
+	defer client.Close()
+	defer s.tcp.track(client)()
+
+	var routes []TCPRouteConfig
+	sniRoutes := false
+	for _, rt := range s.GetConfig().TCPRoutes {
+		if rt.Listen == listen {
+			routes = append(routes, rt)
+			sniRoutes = sniRoutes || rt.SNI != ""
+		}
+	}
+	var sni string
+	var hello []byte
+	if sniRoutes {
+		client.SetReadDeadline(time.Now().Add(tcpHelloTimeout))
+		sni, hello = readClientHello(client)
+		client.SetReadDeadline(time.Time{})
+	}
+	route := matchTCPRoute(routes, sni)
+	if route == nil {
+		slog.Debug("tcp route: no route for connection", "listen", listen, "sni", sni, "remote", client.RemoteAddr())
+		return
+	}
+	cfg := s.activeContainer(route.Container)
+	if cfg == nil {
+		return
+	}
+	log := slog.With("listen", listen, "container", cfg.Name, "remote", client.RemoteAddr().String())
+	if s.manager.drain.Draining(cfg.Name) {
+		RecordTCPConnection(cfg.Name, tcpOutcomeRefused)
+		return
+	}
+
+	ctx := context.Background()
+	status, err := s.manager.client.GetContainerStatus(ctx, cfg.Name)
+	if err != nil {
+		log.Warn("tcp route: cannot get container status", "error", err)
+		RecordTCPConnection(cfg.Name, tcpOutcomeError)
+		return
+	}
+	var until time.Time
+	if status != "running" {
+		done := s.wakeForTCP(log, cfg)
+		if done == nil || route.WakeMode == WakeModeClose {
+			return
+		}
+		held, ok := s.holdTCPConn(log, client, cfg, done)
+		if !ok {
+			return
+		}
+		hello = append(hello, held...)
+		// The readiness check may cover another port than the route's.
+		until = time.Now().Add(cfg.StartTimeout)
+	}
+	s.spliceTCP(ctx, log, client, hello, cfg, route, until)
+}
+
+// matchTCPRoute returns the route for a connection with the server name sni
+// ("" for plain TCP): the route for that name, else the route without sni.
+func matchTCPRoute(routes []TCPRouteConfig, sni string) *TCPRouteConfig {
+	var fallback *TCPRouteConfig
+	for i := range routes {
+		switch {
+		case routes[i].SNI == "":
+			fallback = &routes[i]
+		case sni != "" && strings.EqualFold(routes[i].SNI, sni):
+			return &routes[i]
+		}
+	}
+	return fallback
+}
+
+// errHelloRead stops the TLS handshake of readClientHello once the
+// ClientHello is parsed.
+var errHelloRead = errors.New("client hello read")
+
+// readClientHello reads the TLS ClientHello of conn and returns its server
+// name, "" if the client does not speak TLS or sends none, and the bytes
+// read, which the backend must receive first.
+func readClientHello(conn net.Conn) (string, []byte) {
+	var buf bytes.Buffer
+	var sni string
+	tls.Server(helloConn{Conn: conn, r: io.TeeReader(conn, &buf)}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			sni = hello.ServerName
+			return nil, errHelloRead
+		},
+	}).Handshake()
+	return sni, buf.Bytes()
+}
+
+// helloConn lets crypto/tls read a ClientHello without answering it.
+type helloConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c helloConn) Read(p []byte) (int, error)  { return c.r.Read(p) }
+func (c helloConn) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
+
+// wakeForTCP starts cfg for a connection that found it stopped, like a
+// request does, and returns the channel reporting the end of the start. It
+// returns nil if the gateway may not start containers.
+func (s *Server) wakeForTCP(log *slog.Logger, cfg *ContainerConfig) <-chan error {
+	switch {
+	case s.manager.ReadOnly():
+		RecordTCPConnection(cfg.Name, tcpOutcomeRefused)
+		return nil
+	case s.manager.DryRun():
+		s.manager.simulateWake(cfg.Name, "tcp connection")
+		RecordTCPConnection(cfg.Name, tcpOutcomeRefused)
+		return nil
+	}
+	if looping, until, _ := s.manager.CrashLoopState(cfg.Name); looping && time.Now().Before(until) {
+		RecordTCPConnection(cfg.Name, tcpOutcomeRefused)
+		return nil
+	}
+	RecordTCPConnection(cfg.Name, tcpOutcomeWake)
+	s.manager.InitStartState(cfg.Name)
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.manager.wakeTimeout(cfg))
+		defer cancel()
+		err := s.manager.Wake(ctx, cfg, s.GetConfig().Containers)
+		if err != nil {
+			log.Error("tcp route: start error", "error", err)
+		}
+		done <- err
+	}()
+	return done
+}
+
+// maxHoldTCP bounds the bytes buffered from a client while its connection
+// is held; past it the client is left to wait on TCP flow control.
+const maxHoldTCP = 64 << 10
+
+// holdTCPConn keeps client open while cfg starts, up to start_timeout, and
+// returns what the client sent meanwhile, for the backend. Reading the
+// client notices when it gives up; the wake goes on in the background then.
+// It reports false if the connection must be closed.
+func (s *Server) holdTCPConn(log *slog.Logger, client net.Conn, cfg *ContainerConfig, done <-chan error) ([]byte, bool) {
+	s.manager.noteWaiting(cfg.Name)
+	var held []byte
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 4<<10)
+		for {
+			n, err := client.Read(buf)
+			held = append(held, buf[:n]...)
+			if err != nil || len(held) >= maxHoldTCP {
+				readErr <- err
+				return
+			}
+		}
+	}()
+	// stopReading ends the read loop and makes held safe to use.
+	reading := readErr
+	stopReading := func() {
+		if reading != nil {
+			client.SetReadDeadline(time.Now())
+			<-reading
+			client.SetReadDeadline(time.Time{})
+		}
+	}
+
+	timer := time.NewTimer(cfg.StartTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case err := <-done:
+			stopReading()
+			if err != nil {
+				RecordTCPConnection(cfg.Name, tcpOutcomeError)
+				return nil, false
+			}
+			return held, true
+		case <-timer.C:
+			stopReading()
+			log.Warn("tcp route: container not ready in time, closing held connection", "start_timeout", cfg.StartTimeout)
+			RecordTCPConnection(cfg.Name, tcpOutcomeError)
+			return nil, false
+		case err := <-reading:
+			reading = nil
+			if err != nil {
+				return nil, false // client went away
+			}
+		}
+	}
+}
+
+// spliceTCP connects client to the port of route on cfg, retrying until
+// until if it is set, and copies bytes both ways until either side closes,
+// early going first. Like a WebSocket tunnel, the open connection keeps the
+// container from being idle-stopped.
+func (s *Server) spliceTCP(ctx context.Context, log *slog.Logger, client net.Conn, early []byte, cfg *ContainerConfig, route *TCPRouteConfig, until time.Time) {
+	target := *cfg
+	if route.Port != "" {
+		target.TargetPort = route.Port
+	}
+	host, port, err := s.manager.resolveTarget(ctx, &target)
+	if err != nil {
+		log.Warn("tcp route: cannot resolve container address", "error", err)
+		RecordTCPConnection(cfg.Name, tcpOutcomeError)
+		return
+	}
+	backend, err := dialTCPRoute(ctx, net.JoinHostPort(host, port), until, cfg.ProbeInterval)
+	if err != nil {
+		log.Warn("tcp route: backend unreachable", "error", err)
+		RecordTCPConnection(cfg.Name, tcpOutcomeError)
+		return
+	}
+	defer backend.Close()
+
+	s.manager.drain.OpenTunnel(cfg.Name, 0)
+	defer s.manager.drain.CloseTunnel(cfg.Name)
+	RecordTCPConnection(cfg.Name, tcpOutcomeProxied)
+	TCPConnections.WithLabelValues(cfg.Name).Inc()
+	defer TCPConnections.WithLabelValues(cfg.Name).Dec()
+
+	// Traffic in either direction counts as activity, as on a WebSocket
+	// tunnel.
+	s.manager.RecordActivityChain([]string{cfg.Name}, s.GetConfig().Containers)
+	activity := newTunnelActivity(tunnelActivityInterval, func() {
+		s.manager.RecordActivityChain([]string{cfg.Name}, s.GetConfig().Containers)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := backend.Write(early)
+		n := int64(len(early))
+		if err == nil {
+			var copied int64
+			copied, err = copyPooled(activity.writer(backend), client)
+			n += copied
+		}
+		s.manager.bandwidth.Add(cfg.Name, n, 0)
+		endTunnelDirection(backend, client, err)
+	}()
+	go func() {
+		defer wg.Done()
+		n, err := copyPooled(activity.writer(client), backend)
+		s.manager.bandwidth.Add(cfg.Name, 0, n)
+		endTunnelDirection(client, backend, err)
+	}()
+	wg.Wait()
+}
+
+// dialTCPRoute connects to addr, retrying every interval until until while
+// the container's port is not open yet.
+func dialTCPRoute(ctx context.Context, addr string, until time.Time, interval time.Duration) (net.Conn, error) {
+	for {
+		conn, err := upstream.DialContext(ctx, "tcp", addr)
+		if err == nil || time.Now().Add(interval).After(until) {
+			return conn, err
+		}
+		time.Sleep(interval)
+	}
+}
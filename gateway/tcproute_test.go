@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveTCP serves the routes of listen on a free port and returns its
+// address.
+func (g *fakeGateway) serveTCP(t *testing.T, listen string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.server.tcp.listeners = append(g.server.tcp.listeners, ln)
+	t.Cleanup(func() { g.server.tcp.close(context.Background()) })
+	go g.server.serveTCPRoutes(ln, listen)
+	return ln.Addr().String()
+}
+
+func TestTCPRoute_Proxy(t *testing.T) {
+	host, port := newBackend(t, "db\n", rawTCP)
+	rt := NewFakeRuntime()
+	rt.AddContainer("db", FakeContainer{Status: "running", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Containers: []ContainerConfig{{Name: "db", TargetPort: "80"}},
+		TCPRoutes:  []TCPRouteConfig{{Listen: ":15432", Container: "db", Port: port}},
+	})
+	addr := g.serveTCP(t, ":15432")
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	br := bufio.NewReader(conn)
+	io.WriteString(conn, "ping\n")
+	greeting, _ := br.ReadString('\n')
+	echo, _ := br.ReadString('\n')
+	if greeting != "db\n" || echo != "ping\n" {
+		t.Fatalf("read %q %q, want the greeting and echo of db", greeting, echo)
+	}
+	if g.manager.drain.Active("db") != 1 {
+		t.Errorf("open connections of db = %d, want 1", g.manager.drain.Active("db"))
+	}
+}
+
+func TestTCPRoute_SNI(t *testing.T) {
+	rt := NewFakeRuntime()
+	var ctrs []ContainerConfig
+	for _, name := range []string{"a", "b"} {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		}))
+		t.Cleanup(srv.Close)
+		host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+		rt.AddContainer(name, FakeContainer{Status: "running", Host: host, Port: port})
+		ctrs = append(ctrs, ContainerConfig{Name: name, TargetPort: port})
+	}
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Containers: ctrs,
+		TCPRoutes: []TCPRouteConfig{
+			{Listen: ":8443", SNI: "A.db.local", Container: "a"},
+			{Listen: ":8443", Container: "b"},
+		},
+	})
+	addr := g.serveTCP(t, ":8443")
+	client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{
+		DialContext:     func(ctx context.Context, _, _ string) (net.Conn, error) { return net.Dial("tcp", addr) },
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	t.Cleanup(client.CloseIdleConnections)
+
+	for host, want := range map[string]string{"a.db.local": "a", "other.local": "b"} {
+		resp, err := client.Get("https://" + host + "/")
+		if err != nil {
+			t.Fatalf("%s: %v", host, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != want {
+			t.Errorf("%s went to %q, want %q", host, body, want)
+		}
+	}
+}
+
+func TestTCPRoute_WakeHold(t *testing.T) {
+	host, port := newBackend(t, "db\n", rawTCP)
+	rt := NewFakeRuntime()
+	rt.AddContainer("db", FakeContainer{Status: "exited", Host: host, Port: port, StartDelay: 200 * time.Millisecond})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Containers: []ContainerConfig{{Name: "db", TargetPort: port}},
+		TCPRoutes:  []TCPRouteConfig{{Listen: ":15432", Container: "db"}},
+	})
+	addr := g.serveTCP(t, ":15432")
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// Sent while the container starts: held and forwarded once it is ready.
+	io.WriteString(conn, "ping\n")
+	br := bufio.NewReader(conn)
+	greeting, _ := br.ReadString('\n')
+	echo, _ := br.ReadString('\n')
+	if greeting != "db\n" || echo != "ping\n" {
+		t.Fatalf("read %q %q, want the greeting and echo of db", greeting, echo)
+	}
+}
+
+func TestTCPRoute_WakeHoldTimeout(t *testing.T) {
+	host, port := newBackend(t, "db\n", rawTCP)
+	rt := NewFakeRuntime()
+	rt.AddContainer("db", FakeContainer{Status: "exited", Host: host, Port: port, StartDelay: 2 * time.Second})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Containers: []ContainerConfig{{Name: "db", TargetPort: port, StartTimeout: 100 * time.Millisecond}},
+		TCPRoutes:  []TCPRouteConfig{{Listen: ":15432", Container: "db"}},
+	})
+	addr := g.serveTCP(t, ":15432")
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	if n, err := conn.Read(make([]byte, 1)); n != 0 || err == nil || os.IsTimeout(err) {
+		t.Fatalf("Read() = %d, %v, want the connection closed after start_timeout", n, err)
+	}
+}
+
+func TestTCPRoute_WakeClose(t *testing.T) {
+	host, port := newBackend(t, "db\n", rawTCP)
+	rt := NewFakeRuntime()
+	rt.AddContainer("db", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGatewayConfig(t, rt, &GatewayConfig{
+		Containers: []ContainerConfig{{Name: "db", TargetPort: port}},
+		TCPRoutes:  []TCPRouteConfig{{Listen: ":15432", Container: "db", WakeMode: WakeModeClose}},
+	})
+	addr := g.serveTCP(t, ":15432")
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, 1)); n != 0 || err == nil {
+		t.Fatalf("Read() = %d, %v, want the connection closed", n, err)
+	}
+	conn.Close()
+	if status := g.waitStarted(t, "db"); status != string(statusRunning) {
+		t.Fatalf("start state = %q, want running", status)
+	}
+
+	conn, err = net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if greeting, _ := bufio.NewReader(conn).ReadString('\n'); greeting != "db\n" {
+		t.Errorf("after the wake read %q, want the greeting of db", greeting)
+	}
+}
+
+func TestValidate_TCPRoutes(t *testing.T) {
+	tests := []struct {
+		name  string
+		route TCPRouteConfig
+		want  string
+	}{
+		{"no listen", TCPRouteConfig{Container: "db"}, "missing required field 'listen'"},
+		{"bad listen", TCPRouteConfig{Listen: "5432", Container: "db"}, "listen must be [host]:port"},
+		{"gateway port", TCPRouteConfig{Listen: ":8080", Container: "db"}, "already used by the gateway"},
+		{"no container", TCPRouteConfig{Listen: ":5432"}, "missing required field 'container'"},
+		{"unknown container", TCPRouteConfig{Listen: ":5432", Container: "x"}, `unknown container "x"`},
+		{"bad port", TCPRouteConfig{Listen: ":5432", Container: "db", Port: "pg"}, `invalid port "pg"`},
+		{"bad wake_mode", TCPRouteConfig{Listen: ":5432", Container: "db", WakeMode: "page"}, `unknown wake_mode "page"`},
+		{"duplicate", TCPRouteConfig{Listen: ":6000", Container: "db"}, "more than one route without sni"},
+		{"duplicate sni", TCPRouteConfig{Listen: ":6000", SNI: "DB.local", Container: "db"}, `duplicate sni "DB.local"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &GatewayConfig{
+				Containers: []ContainerConfig{{Name: "db", TargetPort: "5432"}},
+				TCPRoutes: []TCPRouteConfig{
+					{Listen: ":6000", Container: "db"},
+					{Listen: ":6000", SNI: "db.local", Container: "db"},
+					tt.route,
+				},
+			}
+			applyDefaults(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// tenantCtxKey carries the tenant of a request authenticated with one of
+// its gateway.tenants credentials.
+type tenantCtxKey struct{}
+
+// requestTenant returns the tenant whose credentials authenticated r, or ""
+// for admin_auth, which sees every container.
+func requestTenant(r *http.Request) string {
+	t, _ := r.Context().Value(tenantCtxKey{}).(string)
+	return t
+}
+
+// matchTenant returns the tenant whose credentials r carries.
+func matchTenant(r *http.Request, tenants []TenantConfig) (string, bool) {
+	for _, t := range tenants {
+		for _, cred := range t.Credentials {
+			switch cred.Method {
+			case "basic":
+				if checkBasicAuth(r, cred.Username, cred.Password) {
+					return t.Name, true
+				}
+			case "bearer":
+				if checkBearerToken(r, cred.Token) {
+					return t.Name, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// tenantAuthMiddleware lets a request with tenant credentials through to
+// next, scoped to its tenant, when scoped is set, and refuses it with 403
+// on gateway-wide endpoints otherwise. Any other request goes through
+// admin, the admin_auth check. Without tenants admin is returned unchanged.
+func tenantAuthMiddleware(next, admin http.Handler, tenants []TenantConfig, scoped bool) http.Handler {
+	if len(tenants) == 0 {
+		return admin
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := matchTenant(r, tenants)
+		if !ok {
+			admin.ServeHTTP(w, r)
+			return
+		}
+		if !scoped {
+			slog.WarnContext(r.Context(), "tenant denied a gateway-wide endpoint",
+				"tenant", tenant, "path", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantCtxKey{}, tenant)))
+	})
+}
+
+// visibleTo reports whether a container or group of the given tenant is
+// shown to a caller scoped to caller ("" is admin_auth, which sees all).
+func visibleTo(tenant, caller string) bool {
+	return caller == "" || tenant == caller
+}
+
+// tenantContainer returns the configured container name as seen by r's
+// tenant: nil when it does not exist or belongs to another tenant, so a
+// tenant cannot probe for the names of other teams' containers.
+func (s *Server) tenantContainer(r *http.Request, name string) *ContainerConfig {
+	cfg := s.GetConfig()
+	for i := range cfg.Containers {
+		if c := &cfg.Containers[i]; c.Name == name {
+			if !visibleTo(c.Tenant, requestTenant(r)) {
+				return nil
+			}
+			return c
+		}
+	}
+	return nil
+}
+
+// visibleDeps drops the dependencies missing from visible, the containers
+// shown to the caller, so the topology of a tenant does not name the
+// containers of others.
+func visibleDeps[T any](deps []string, visible map[string]T) []string {
+	out := make([]string, 0, len(deps))
+	for _, d := range deps {
+		if _, ok := visible[d]; ok {
+			out = append(out, d)
+		}
+	}
+	return out
+}
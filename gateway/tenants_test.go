@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func tenantTestConfig() *GatewayConfig {
+	return &GatewayConfig{
+		Gateway: GlobalConfig{
+			AdminAuth: AdminAuthConfig{Method: "bearer", Token: "admin-token"},
+			Tenants: []TenantConfig{
+				{Name: "team-a", Credentials: []AdminAuthConfig{{Method: "bearer", Token: "token-a"}}},
+				{Name: "team-b", Credentials: []AdminAuthConfig{{Method: "basic", Username: "bob", Password: "pw"}}},
+			},
+		},
+		Containers: []ContainerConfig{
+			{Name: "app-a", Host: "a.local", TargetPort: "80", Tenant: "team-a"},
+			{Name: "app-b", Host: "b.local", TargetPort: "80", Tenant: "team-b"},
+			{Name: "shared", Host: "shared.local", TargetPort: "80"},
+		},
+	}
+}
+
+// do sends an admin request with the given Authorization header.
+func (g *fakeGateway) do(method, path, auth string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, nil)
+	r.Host = "gw.local"
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	g.handler.ServeHTTP(w, r)
+	return w
+}
+
+func basicAuth(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func statusNames(t *testing.T, w *httptest.ResponseRecorder) (names []string, tenant string) {
+	t.Helper()
+	var resp struct {
+		Tenant     string `json:"tenant"`
+		Containers []struct {
+			Name string `json:"name"`
+		} `json:"containers"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("status %d: %v", w.Code, err)
+	}
+	for _, c := range resp.Containers {
+		names = append(names, c.Name)
+	}
+	return names, resp.Tenant
+}
+
+func TestTenants_StatusScoped(t *testing.T) {
+	rt := NewFakeRuntime()
+	for _, name := range []string{"app-a", "app-b", "shared"} {
+		rt.AddContainer(name, FakeContainer{Status: "exited"})
+	}
+	g := newFakeGatewayConfig(t, rt, tenantTestConfig())
+
+	names, tenant := statusNames(t, g.do(http.MethodGet, "/_status/api", "Bearer token-a"))
+	if strings.Join(names, ",") != "app-a" || tenant != "team-a" {
+		t.Errorf("team-a sees %v (tenant %q), want [app-a]", names, tenant)
+	}
+	names, _ = statusNames(t, g.do(http.MethodGet, "/_status/api", basicAuth("bob", "pw")))
+	if strings.Join(names, ",") != "app-b" {
+		t.Errorf("team-b sees %v, want [app-b]", names)
+	}
+	names, tenant = statusNames(t, g.do(http.MethodGet, "/_status/api", "Bearer admin-token"))
+	if len(names) != 3 || tenant != "" {
+		t.Errorf("admin sees %v (tenant %q), want every container", names, tenant)
+	}
+
+	if w := g.do(http.MethodGet, "/_status/api", "Bearer wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token: status %d, want 401", w.Code)
+	}
+
+	w := g.do(http.MethodGet, "/_status", "Bearer token-a")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Tenant: team-a") {
+		t.Errorf("status page: status %d, want the tenant badge", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "Docker disk usage and cleanup") {
+		t.Error("status page offers disk usage to a tenant")
+	}
+
+	w = g.do(http.MethodGet, "/_topology", "Bearer token-a")
+	if body := w.Body.String(); !strings.Contains(body, `"app-a"`) || strings.Contains(body, `"app-b"`) || strings.Contains(body, `"shared"`) {
+		t.Errorf("topology of team-a lists other containers: %s", body)
+	}
+}
+
+func TestTenants_ActionsScoped(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app-a", FakeContainer{Status: "exited", Host: host, Port: port})
+	rt.AddContainer("app-b", FakeContainer{Status: "exited"})
+	rt.AddContainer("shared", FakeContainer{Status: "exited"})
+	cfg := tenantTestConfig()
+	cfg.Containers[0].TargetPort = port
+	g := newFakeGatewayConfig(t, rt, cfg)
+
+	if w := g.do(http.MethodPost, "/_status/wake?container=app-b", "Bearer token-a"); w.Code != http.StatusBadRequest {
+		t.Errorf("wake of another tenant's container: status %d, want 400", w.Code)
+	}
+	if w := g.do(http.MethodPost, "/_status/kill?container=shared", "Bearer token-a"); w.Code != http.StatusBadRequest {
+		t.Errorf("kill of an admin-only container: status %d, want 400", w.Code)
+	}
+	if w := g.do(http.MethodGet, "/_status/api/containers/app-b", "Bearer token-a"); w.Code != http.StatusNotFound {
+		t.Errorf("details of another tenant's container: status %d, want 404", w.Code)
+	}
+	if calls := rt.Calls(); len(calls) != 0 {
+		t.Fatalf("calls = %v, want none", calls)
+	}
+
+	if w := g.do(http.MethodPost, "/_status/wake?container=app-a", "Bearer token-a"); w.Code != http.StatusOK {
+		t.Fatalf("wake of its own container: status %d", w.Code)
+	}
+	if status := g.waitStarted(t, "app-a"); status != "running" {
+		t.Errorf("app-a state = %q, want running", status)
+	}
+}
+
+func TestTenants_GatewayWideEndpointsForbidden(t *testing.T) {
+	g := newFakeGatewayConfig(t, NewFakeRuntime(), tenantTestConfig())
+
+	for _, path := range []string{"/_metrics", "/_status/disk", "/_status/routes", "/_admin/loglevel", "/_admin/reload/status"} {
+		if w := g.do(http.MethodGet, path, "Bearer token-a"); w.Code != http.StatusForbidden {
+			t.Errorf("%s: status %d, want 403 for a tenant", path, w.Code)
+		}
+		if w := g.do(http.MethodGet, path, "Bearer admin-token"); w.Code == http.StatusForbidden || w.Code == http.StatusUnauthorized {
+			t.Errorf("%s: status %d for admin_auth", path, w.Code)
+		}
+	}
+}
+
+func TestValidate_Tenants(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*GatewayConfig)
+		want   string
+	}{
+		{"no admin_auth", func(c *GatewayConfig) { c.Gateway.AdminAuth = AdminAuthConfig{} }, "tenants: requires admin_auth"},
+		{"duplicate name", func(c *GatewayConfig) { c.Gateway.Tenants[1].Name = "team-a" }, `duplicate name "team-a"`},
+		{"no credentials", func(c *GatewayConfig) { c.Gateway.Tenants[0].Credentials = nil }, "tenants.team-a: at least one credential"},
+		{"method none", func(c *GatewayConfig) {
+			c.Gateway.Tenants[0].Credentials[0] = AdminAuthConfig{Method: "none"}
+		}, "method must be basic or bearer"},
+		{"admin token reused", func(c *GatewayConfig) {
+			c.Gateway.Tenants[0].Credentials[0].Token = "admin-token"
+		}, "already used by admin_auth or another tenant"},
+		{"unknown tenant", func(c *GatewayConfig) { c.Containers[2].Tenant = "team-c" }, `container "shared": tenant "team-c" is not defined`},
+		{"group across tenants", func(c *GatewayConfig) {
+			c.Groups = []GroupConfig{{Name: "mix", Host: "mix.local", Tenant: "team-a", Members: []GroupMember{{Name: "app-a"}, {Name: "app-b"}}}}
+		}, `group "mix": member "app-b" belongs to tenant "team-b", not "team-a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tenantTestConfig()
+			tt.modify(cfg)
+			applyDefaults(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	cfg := tenantTestConfig()
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+}
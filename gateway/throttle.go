@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ByteRate is a bandwidth in bytes per second, written in YAML and labels as
+// a size per second: "10MB/s", "512KiB/s", "100Mbit/s". The "/s" is optional.
+type ByteRate int64
+
+// byteRateUnits maps the accepted units to their size in bytes. Decimal
+// units are powers of 1000, binary units (KiB, ...) powers of 1024.
+var byteRateUnits = map[string]float64{
+	"b":    1,
+	"kb":   1e3,
+	"mb":   1e6,
+	"gb":   1e9,
+	"kib":  1 << 10,
+	"mib":  1 << 20,
+	"gib":  1 << 30,
+	"kbit": 1e3 / 8,
+	"mbit": 1e6 / 8,
+	"gbit": 1e9 / 8,
+}
+
+// parseByteRate parses a bandwidth such as "10MB/s". A plain number is in
+// bytes per second; "0" or "" disables the limit.
+func parseByteRate(s string) (ByteRate, error) {
+	v := strings.TrimSuffix(strings.TrimSpace(s), "/s")
+	if v == "" {
+		return 0, nil
+	}
+	i := strings.IndexFunc(v, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	num, unit := v, "b"
+	if i >= 0 {
+		num, unit = strings.TrimSpace(v[:i]), strings.ToLower(strings.TrimSpace(v[i:]))
+	}
+	size, ok := byteRateUnits[unit]
+	f, err := strconv.ParseFloat(num, 64)
+	if !ok || err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q (want e.g. \"10MB/s\", \"512KiB/s\" or \"100Mbit/s\")", s)
+	}
+	return ByteRate(f * size), nil
+}
+
+// UnmarshalYAML parses the rate from its string form.
+func (r *ByteRate) UnmarshalYAML(value *yaml.Node) error {
+	rate, err := parseByteRate(value.Value)
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+// throttleChunk bounds the bytes written at once, so a large write is paced
+// in small steps instead of one long wait.
+const throttleChunk = 16 << 10
+
+// bandwidthThrottle paces the responses of each container to its
+// bandwidth_limit. All responses of a container share one bucket, so the
+// limit holds however many clients are downloading at once.
+type bandwidthThrottle struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newBandwidthThrottle() *bandwidthThrottle {
+	return &bandwidthThrottle{buckets: make(map[string]*tokenBucket)}
+}
+
+// reserve takes n bytes from the container's bucket, which holds up to one
+// second of traffic, and returns how long to wait before sending them.
+func (t *bandwidthThrottle) reserve(name string, rate ByteRate, n int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	b, ok := t.buckets[name]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rate), last: now}
+		t.buckets[name] = b
+	}
+	b.tokens = min(float64(rate), b.tokens+now.Sub(b.last).Seconds()*float64(rate))
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / float64(rate) * float64(time.Second))
+}
+
+// throttledWriter writes to w at no more than rate bytes per second.
+type throttledWriter struct {
+	w        io.Writer
+	ctx      context.Context
+	throttle *bandwidthThrottle
+	name     string
+	rate     ByteRate
+}
+
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), throttleChunk)]
+		if wait := tw.throttle.reserve(tw.name, tw.rate, len(chunk)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-tw.ctx.Done():
+				timer.Stop()
+				return written, tw.ctx.Err()
+			case <-timer.C:
+			}
+		}
+		n, err := tw.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+// throttledResponseWriter paces the body of a proxied response.
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	body throttledWriter
+}
+
+func (t *throttledResponseWriter) Write(p []byte) (int, error) {
+	return t.body.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (t *throttledResponseWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
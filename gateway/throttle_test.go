@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseByteRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ByteRate
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"2048", 2048, false},
+		{"10MB/s", 10_000_000, false},
+		{"10mb", 10_000_000, false},
+		{"512KiB/s", 512 << 10, false},
+		{"1.5 GiB/s", 3 << 29, false},
+		{"100Mbit/s", 12_500_000, false},
+		{"fast", 0, true},
+		{"10XB/s", 0, true},
+		{"-1MB/s", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseByteRate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseByteRate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseByteRate(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteRate_YAML(t *testing.T) {
+	var ctr ContainerConfig
+	if err := yaml.Unmarshal([]byte(`bandwidth_limit: "2MiB/s"`), &ctr); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if ctr.BandwidthLimit != 2<<20 {
+		t.Errorf("BandwidthLimit = %d, want %d", ctr.BandwidthLimit, 2<<20)
+	}
+	if err := yaml.Unmarshal([]byte(`bandwidth_limit: "lots"`), &ctr); err == nil {
+		t.Error("Unmarshal accepted an invalid bandwidth")
+	}
+}
+
+func TestThrottledWriter(t *testing.T) {
+	const rate = 1 << 20 // 1 MiB/s, one second of burst
+	throttle := newBandwidthThrottle()
+	var out bytes.Buffer
+	tw := &throttledWriter{w: &out, ctx: context.Background(), throttle: throttle, name: "media", rate: rate}
+
+	// The burst goes out at once; the next 200 KiB wait for the refill.
+	start := time.Now()
+	if _, err := tw.Write(make([]byte, rate)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("burst took %v, want immediate", elapsed)
+	}
+	n, err := tw.Write(make([]byte, 200<<10))
+	if err != nil || n != 200<<10 {
+		t.Fatalf("Write = (%d, %v)", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("1.2 MiB at 1 MiB/s took %v, want about 200ms", elapsed)
+	}
+	if out.Len() != rate+200<<10 {
+		t.Errorf("written %d bytes, want %d", out.Len(), rate+200<<10)
+	}
+
+	// Another writer of the same container shares the bucket.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	other := &throttledWriter{w: &out, ctx: ctx, throttle: throttle, name: "media", rate: rate}
+	if _, err := other.Write(make([]byte, rate)); err == nil {
+		t.Error("second writer was not paced by the shared bucket")
+	}
+}
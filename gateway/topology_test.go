@@ -6,7 +6,6 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
-	"time"
 )
 
 func TestHandleTopology_ReturnsHTML(t *testing.T) {
@@ -17,7 +16,7 @@ func TestHandleTopology_ReturnsHTML(t *testing.T) {
 	s := &Server{
 		cfg:         &GatewayConfig{Containers: []ContainerConfig{}, Groups: []GroupConfig{}},
 		tmpl:        tmpl,
-		rateLimiter: newRateLimiter(time.Second),
+		rateLimiter: newRateLimiter(RateLimitConfig{}),
 		manager:     NewContainerManager(&DockerClient{}),
 	}
 
@@ -0,0 +1,341 @@
+package gateway
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// traceFlushInterval is how often finished spans are exported.
+	traceFlushInterval = 5 * time.Second
+	// traceBatchSize is the largest number of spans sent in one export.
+	traceBatchSize = 256
+	// traceQueueLimit bounds memory while the collector is unreachable;
+	// spans beyond it are dropped.
+	traceQueueLimit = 4096
+	// traceShutdownTimeout bounds the final flush of a replaced or stopped
+	// exporter.
+	traceShutdownTimeout = 5 * time.Second
+)
+
+// Span kinds used by the gateway.
+const (
+	spanKindInternal = trace.SpanKindInternal
+	spanKindServer   = trace.SpanKindServer
+	spanKindClient   = trace.SpanKindClient
+)
+
+// traceID and spanID are W3C trace-context identifiers.
+type (
+	traceID [16]byte
+	spanID  [8]byte
+)
+
+func (t traceID) String() string { return hex.EncodeToString(t[:]) }
+func (s spanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// spanContext identifies a span across process boundaries. It is used on
+// its own to forward a trace context when tracing is disabled.
+type spanContext struct {
+	TraceID traceID
+	SpanID  spanID
+	Sampled bool
+}
+
+func (sc spanContext) valid() bool {
+	return sc.TraceID != traceID{} && sc.SpanID != spanID{}
+}
+
+// otel returns sc as a remote OpenTelemetry span context.
+func (sc spanContext) otel() trace.SpanContext {
+	var flags trace.TraceFlags
+	if sc.Sampled {
+		flags = trace.FlagsSampled
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID(sc.TraceID),
+		SpanID:     trace.SpanID(sc.SpanID),
+		TraceFlags: flags,
+		Remote:     true,
+	})
+}
+
+// Span is a single timed operation. A nil *Span is valid and records nothing,
+// so call sites do not need to check whether tracing is enabled.
+type Span struct {
+	span trace.Span
+	sc   spanContext
+}
+
+func wrapSpan(span trace.Span) *Span {
+	osc := span.SpanContext()
+	return &Span{span: span, sc: spanContext{
+		TraceID: traceID(osc.TraceID()),
+		SpanID:  spanID(osc.SpanID()),
+		Sampled: osc.IsSampled(),
+	}}
+}
+
+// SetAttr records a string, bool, int, int64 or float64 attribute.
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	var kv attribute.KeyValue
+	switch v := value.(type) {
+	case string:
+		kv = attribute.String(key, v)
+	case bool:
+		kv = attribute.Bool(key, v)
+	case int:
+		kv = attribute.Int(key, v)
+	case int64:
+		kv = attribute.Int64(key, v)
+	case float64:
+		kv = attribute.Float64(key, v)
+	default:
+		kv = attribute.Stringer(key, stringer{v})
+	}
+	s.span.SetAttributes(kv)
+}
+
+// stringer formats any value as an attribute of last resort.
+type stringer struct{ v any }
+
+func (s stringer) String() string { return fmt.Sprint(s.v) }
+
+// SetError marks the span as failed.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End finishes the span and queues it for export.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+// spanFromContext returns the active span of ctx, or nil.
+func spanFromContext(ctx context.Context) *Span {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil
+	}
+	return wrapSpan(span)
+}
+
+// Tracer creates spans with the OpenTelemetry SDK and exports them in
+// batches to an OTLP/HTTP collector (OpenTelemetry Collector, Tempo,
+// Jaeger ≥ 1.35) on /v1/traces.
+type Tracer struct {
+	mu       sync.RWMutex
+	cfg      TracingConfig
+	endpoint string // "" when tracing is disabled
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// tracer is the process-wide tracer used by the request path, in the same
+// way the Prometheus collectors in metrics.go are process-wide.
+var tracer = newTracer()
+
+func newTracer() *Tracer {
+	return &Tracer{}
+}
+
+// ConfigureTracing applies the tracing configuration. It is safe to call on
+// every config reload; an empty endpoint disables tracing.
+func ConfigureTracing(cfg TracingConfig) {
+	tracer.Sync(cfg)
+}
+
+// StartTracing exports spans until ctx is cancelled, then flushes the
+// remaining ones.
+func StartTracing(ctx context.Context) {
+	tracer.Start(ctx)
+}
+
+// Sync replaces the tracer configuration. A changed configuration gets a
+// new exporter; the previous one flushes its spans in the background.
+func (t *Tracer) Sync(cfg TracingConfig) {
+	endpoint := otlpTracesURL(cfg.Endpoint)
+	t.mu.RLock()
+	unchanged := endpoint == t.endpoint && cfg.ServiceName == t.cfg.ServiceName &&
+		cfg.SampleRatio == t.cfg.SampleRatio && maps.Equal(cfg.Headers, t.cfg.Headers)
+	t.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	var provider *sdktrace.TracerProvider
+	if endpoint != "" {
+		exporter, err := otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpointURL(endpoint),
+			otlptracehttp.WithHeaders(cfg.Headers),
+		)
+		if err != nil {
+			slog.Error("tracing: creating the exporter failed, tracing disabled", "endpoint", endpoint, "error", err)
+			endpoint = ""
+		} else {
+			provider = newTracerProvider(cfg, sdktrace.NewBatchSpanProcessor(exporter,
+				sdktrace.WithBatchTimeout(traceFlushInterval),
+				sdktrace.WithMaxExportBatchSize(traceBatchSize),
+				sdktrace.WithMaxQueueSize(traceQueueLimit),
+			))
+			slog.Info("tracing: exporting spans", "endpoint", endpoint, "sample_ratio", cfg.SampleRatio)
+		}
+	}
+	t.use(cfg, endpoint, provider)
+}
+
+// newTracerProvider returns a provider sending the spans of new traces
+// sampled at cfg.SampleRatio, and of continued traces following the
+// caller's decision, to processor.
+func newTracerProvider(cfg TracingConfig, processor sdktrace.SpanProcessor) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", cfg.ServiceName),
+			attribute.String("service.version", Version),
+		)),
+	)
+}
+
+// use swaps in provider, shutting the previous one down.
+func (t *Tracer) use(cfg TracingConfig, endpoint string, provider *sdktrace.TracerProvider) {
+	t.mu.Lock()
+	old := t.provider
+	t.cfg, t.endpoint, t.provider, t.tracer = cfg, endpoint, provider, nil
+	if provider != nil {
+		t.tracer = provider.Tracer("docker-gateway", trace.WithInstrumentationVersion(Version))
+	}
+	t.mu.Unlock()
+	if old != nil {
+		go shutdownTracerProvider(old)
+	}
+}
+
+func shutdownTracerProvider(p *sdktrace.TracerProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), traceShutdownTimeout)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		slog.Warn("tracing: flushing spans failed", "error", err)
+	}
+}
+
+// otlpTracesURL appends the OTLP traces path to a collector base URL, unless
+// the URL already points at it.
+func otlpTracesURL(endpoint string) string {
+	if endpoint == "" {
+		return ""
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+	if strings.HasSuffix(endpoint, "/v1/traces") {
+		return endpoint
+	}
+	return endpoint + "/v1/traces"
+}
+
+// startSpan starts a span as a child of the active span of ctx (or of remote,
+// when valid) and returns a context carrying it. It returns a nil span when
+// tracing is disabled.
+func (t *Tracer) startSpan(ctx context.Context, name string, kind trace.SpanKind, remote spanContext) (context.Context, *Span) {
+	t.mu.RLock()
+	tr := t.tracer
+	t.mu.RUnlock()
+	if tr == nil {
+		return ctx, nil
+	}
+	if remote.valid() && !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, remote.otel())
+	}
+	ctx, span := tr.Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, wrapSpan(span)
+}
+
+// StartSpan starts an internal span as a child of the active span of ctx.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return tracer.startSpan(ctx, name, spanKindInternal, spanContext{})
+}
+
+// startServerSpan starts the root span of an incoming request, continuing the
+// caller's trace when the request carries a valid traceparent header.
+func startServerSpan(r *http.Request, name string) (context.Context, *Span) {
+	remote, _ := parseTraceparent(r.Header.Get("traceparent"))
+	return tracer.startSpan(r.Context(), name, spanKindServer, remote)
+}
+
+// parseTraceparent parses a W3C traceparent header
+// ("00-<32 hex trace id>-<16 hex span id>-<2 hex flags>").
+func parseTraceparent(h string) (spanContext, bool) {
+	var sc spanContext
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return spanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return spanContext{}, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil || !sc.valid() {
+		return spanContext{}, false
+	}
+	sc.Sampled = flags&0x01 == 1
+	return sc, true
+}
+
+// Start flushes and stops the exporter once ctx is cancelled; spans are
+// exported in the background until then.
+func (t *Tracer) Start(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		t.mu.Lock()
+		provider := t.provider
+		t.cfg, t.endpoint, t.provider, t.tracer = TracingConfig{}, "", nil, nil
+		t.mu.Unlock()
+		if provider != nil {
+			shutdownTracerProvider(provider)
+		}
+	}()
+}
+
+// flush exports every finished span now.
+func (t *Tracer) flush(ctx context.Context) {
+	t.mu.RLock()
+	provider := t.provider
+	t.mu.RUnlock()
+	if provider == nil {
+		return
+	}
+	if err := provider.ForceFlush(ctx); err != nil {
+		slog.Warn("tracing: export failed", "error", err)
+	}
+}
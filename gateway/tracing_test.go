@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ─── Trace context ────────────────────────────────────────────────────────────
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantOK      bool
+		wantSampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x", true, true},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x", false, false},
+		{"invalid version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"all-zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"short span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01", false, false},
+		{"not hex", "00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"empty", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := parseTraceparent(tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("parseTraceparent(%q) ok = %v, want %v", tt.header, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if sc.Sampled != tt.wantSampled {
+				t.Errorf("Sampled = %v, want %v", sc.Sampled, tt.wantSampled)
+			}
+			if got := sc.TraceID.String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("TraceID = %s", got)
+			}
+		})
+	}
+}
+
+func TestOTLPTracesURL(t *testing.T) {
+	tests := map[string]string{
+		"":                                   "",
+		"http://tempo:4318":                  "http://tempo:4318/v1/traces",
+		"http://tempo:4318/":                 "http://tempo:4318/v1/traces",
+		"https://otel.example.com/v1/traces": "https://otel.example.com/v1/traces",
+	}
+	for in, want := range tests {
+		if got := otlpTracesURL(in); got != want {
+			t.Errorf("otlpTracesURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// ─── Sampling ─────────────────────────────────────────────────────────────────
+
+// recordingTracer returns a tracer whose ended spans go to the returned
+// recorder instead of a collector.
+func recordingTracer(t *testing.T, cfg TracingConfig) (*Tracer, *tracetest.SpanRecorder) {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tr := newTracer()
+	tr.use(cfg, "memory", newTracerProvider(cfg, rec))
+	t.Cleanup(func() { tr.use(TracingConfig{}, "", nil) })
+	return tr, rec
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	remote, _ := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	tr, rec := recordingTracer(t, TracingConfig{SampleRatio: 0})
+	_, root := tr.startSpan(context.Background(), "request", spanKindServer, spanContext{})
+	root.End()
+	if len(rec.Ended()) != 0 {
+		t.Error("ratio 0 should sample no new trace")
+	}
+	_, continued := tr.startSpan(context.Background(), "request", spanKindServer, remote)
+	continued.End()
+	if len(rec.Ended()) != 1 {
+		t.Error("a sampled caller should be followed whatever the ratio")
+	}
+
+	tr, rec = recordingTracer(t, TracingConfig{SampleRatio: 1})
+	remote.Sampled = false
+	_, unsampled := tr.startSpan(context.Background(), "request", spanKindServer, remote)
+	unsampled.End()
+	if len(rec.Ended()) != 0 {
+		t.Error("an unsampled caller should disable recording for the whole trace")
+	}
+}
+
+// ─── Spans ────────────────────────────────────────────────────────────────────
+
+func TestSpan_NilSafe(t *testing.T) {
+	tr := newTracer() // no endpoint: disabled
+	ctx, span := tr.startSpan(context.Background(), "op", spanKindInternal, spanContext{})
+	if span != nil {
+		t.Fatal("disabled tracer should return a nil span")
+	}
+	span.SetAttr("k", "v")
+	span.SetError(errors.New("boom"))
+	span.End()
+	if spanFromContext(ctx) != nil {
+		t.Error("context should carry no span")
+	}
+}
+
+func TestTracer_StartSpan(t *testing.T) {
+	tr, rec := recordingTracer(t, TracingConfig{SampleRatio: 1})
+
+	remote, _ := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, root := tr.startSpan(context.Background(), "request", spanKindServer, remote)
+	if root.sc.TraceID != remote.TraceID {
+		t.Error("server span should continue the remote trace")
+	}
+
+	// Child spans inherit the trace, also across detachContext.
+	_, child := tr.startSpan(detachContext(ctx), "start", spanKindInternal, spanContext{})
+	if child.sc.TraceID != root.sc.TraceID || child.sc.SpanID == root.sc.SpanID {
+		t.Error("child span should share the trace with its own span id")
+	}
+	child.SetAttr("gateway.container", "app")
+	child.SetError(errors.New("connection refused"))
+	child.End()
+	root.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended %d spans, want 2", len(spans))
+	}
+	start, request := spans[0], spans[1]
+	if start.Parent().SpanID() != trace.SpanID(root.sc.SpanID) {
+		t.Errorf("start span parent = %s, want the request span", start.Parent().SpanID())
+	}
+	if request.Parent().SpanID() != trace.SpanID(remote.SpanID) || !request.Parent().IsRemote() {
+		t.Errorf("request span parent = %s, want the remote caller", request.Parent().SpanID())
+	}
+	if request.SpanKind() != trace.SpanKindServer || start.SpanKind() != trace.SpanKindInternal {
+		t.Errorf("span kinds = (%v, %v), want (server, internal)", request.SpanKind(), start.SpanKind())
+	}
+	if start.Status().Code != codes.Error || start.Status().Description != "connection refused" {
+		t.Errorf("start status = %+v, want the error", start.Status())
+	}
+}
+
+// ─── OTLP export ──────────────────────────────────────────────────────────────
+
+func TestTracer_Flush(t *testing.T) {
+	var got coltracepb.ExportTraceServiceRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %q, want /v1/traces", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := proto.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid OTLP payload: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer srv.Close()
+
+	tr := newTracer()
+	tr.Sync(TracingConfig{
+		Endpoint:    srv.URL,
+		ServiceName: "gw-test",
+		SampleRatio: 1,
+		Headers:     map[string]string{"Authorization": "Bearer t"},
+	})
+	defer tr.use(TracingConfig{}, "", nil)
+	ctx, parent := tr.startSpan(context.Background(), "gateway.request", spanKindServer, spanContext{})
+	_, child := tr.startSpan(ctx, "probe.tcp", spanKindInternal, spanContext{})
+	child.SetAttr("gateway.probe_attempts", 3)
+	child.End()
+	parent.End()
+	tr.flush(context.Background())
+
+	if auth != "Bearer t" {
+		t.Errorf("Authorization = %q, want configured header", auth)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected payload shape: %v", &got)
+	}
+	var service string
+	for _, attr := range got.ResourceSpans[0].Resource.Attributes {
+		if attr.Key == "service.name" {
+			service = attr.Value.GetStringValue()
+		}
+	}
+	if service != "gw-test" {
+		t.Errorf("service.name = %q, want gw-test", service)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	probe := spans[0]
+	if probe.Name != "probe.tcp" || !bytes.Equal(probe.ParentSpanId, spans[1].SpanId) {
+		t.Errorf("probe span = %v, want child of the request span", probe)
+	}
+	if len(probe.Attributes) != 1 || probe.Attributes[0].Value.GetIntValue() != 3 {
+		t.Errorf("probe attributes = %v", probe.Attributes)
+	}
+}
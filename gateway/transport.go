@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultUpstreamConfig mirrors http.DefaultTransport, which the gateway
+// used for backend requests before gateway.upstream existed.
+var defaultUpstreamConfig = UpstreamConfig{
+	DialTimeout:         30 * time.Second,
+	KeepAlive:           30 * time.Second,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: http.DefaultMaxIdleConnsPerHost,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// UpstreamTransport is the connection pool shared by all proxied requests
+// and WebSocket tunnels. It is rebuilt when gateway.upstream changes.
+type UpstreamTransport struct {
+	mu        sync.RWMutex
+	cfg       UpstreamConfig
+	dialer    *net.Dialer
+	transport *http.Transport
+}
+
+// upstream is the process-wide backend transport, configured from
+// gateway.upstream the same way the tracer is from gateway.tracing.
+var upstream = newUpstreamTransport(defaultUpstreamConfig)
+
+func newUpstreamTransport(cfg UpstreamConfig) *UpstreamTransport {
+	u := &UpstreamTransport{}
+	u.build(cfg)
+	return u
+}
+
+// ConfigureUpstream applies the backend transport settings. It is safe to
+// call on every config reload.
+func ConfigureUpstream(cfg UpstreamConfig) {
+	upstream.Sync(cfg)
+}
+
+// build replaces the dialer and transport. The caller holds mu, if needed.
+func (u *UpstreamTransport) build(cfg UpstreamConfig) {
+	keepAlive := cfg.KeepAlive
+	if cfg.DisableKeepAlives {
+		keepAlive = -1 // no TCP keep-alive probes either
+	}
+	u.cfg = cfg
+	u.dialer = &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: keepAlive}
+	u.transport = &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           u.dialer.DialContext,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+		DisableCompression:    cfg.DisableCompression,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+}
+
+// Sync rebuilds the transport when cfg changed. Requests in flight finish on
+// the old transport, whose idle connections are closed.
+func (u *UpstreamTransport) Sync(cfg UpstreamConfig) {
+	u.mu.Lock()
+	if cfg == u.cfg {
+		u.mu.Unlock()
+		return
+	}
+	old := u.transport
+	u.build(cfg)
+	u.mu.Unlock()
+
+	old.CloseIdleConnections()
+	slog.Info("upstream: transport settings applied", "dial_timeout", cfg.DialTimeout,
+		"max_idle_conns_per_host", cfg.MaxIdleConnsPerHost, "idle_conn_timeout", cfg.IdleConnTimeout,
+		"disable_keep_alives", cfg.DisableKeepAlives)
+}
+
+// RoundTrip sends req through the current transport.
+func (u *UpstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u.mu.RLock()
+	t := u.transport
+	u.mu.RUnlock()
+	return t.RoundTrip(req)
+}
+
+// DialContext opens a raw connection to a backend, e.g. for a WebSocket
+// tunnel, with the configured timeout and keep-alive.
+func (u *UpstreamTransport) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	u.mu.RLock()
+	d := u.dialer
+	u.mu.RUnlock()
+	return d.DialContext(ctx, network, addr)
+}
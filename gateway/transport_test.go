@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpstreamConfig_Defaults(t *testing.T) {
+	var c UpstreamConfig
+	c.setDefaults()
+	if c != defaultUpstreamConfig {
+		t.Errorf("defaults = %+v, want %+v", c, defaultUpstreamConfig)
+	}
+
+	c = UpstreamConfig{MaxIdleConnsPerHost: 64, DisableCompression: true}
+	c.setDefaults()
+	if c.MaxIdleConnsPerHost != 64 || !c.DisableCompression || c.DialTimeout != 30*time.Second {
+		t.Errorf("explicit settings not kept: %+v", c)
+	}
+}
+
+func TestUpstreamTransport_Sync(t *testing.T) {
+	u := newUpstreamTransport(defaultUpstreamConfig)
+	first := u.transport
+
+	u.Sync(defaultUpstreamConfig)
+	if u.transport != first {
+		t.Error("unchanged settings rebuilt the transport")
+	}
+
+	cfg := defaultUpstreamConfig
+	cfg.MaxIdleConnsPerHost = 32
+	cfg.DisableCompression = true
+	u.Sync(cfg)
+	if u.transport == first {
+		t.Fatal("changed settings did not rebuild the transport")
+	}
+	if u.transport.MaxIdleConnsPerHost != 32 || !u.transport.DisableCompression {
+		t.Errorf("transport = %+v, want the new settings", u.transport)
+	}
+}
+
+// countConns starts a backend counting the TCP connections it accepts.
+func countConns(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv, &conns
+}
+
+func TestUpstreamTransport_KeepAlives(t *testing.T) {
+	get := func(u *UpstreamTransport, url string) {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		resp, err := u.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	srv, conns := countConns(t)
+	u := newUpstreamTransport(defaultUpstreamConfig)
+	for i := 0; i < 3; i++ {
+		get(u, srv.URL)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("keep-alive: %d connections for 3 requests, want 1", n)
+	}
+
+	srv, conns = countConns(t)
+	cfg := defaultUpstreamConfig
+	cfg.DisableKeepAlives = true
+	u.Sync(cfg)
+	for i := 0; i < 3; i++ {
+		get(u, srv.URL)
+	}
+	if n := conns.Load(); n != 3 {
+		t.Errorf("disable_keep_alives: %d connections for 3 requests, want 3", n)
+	}
+}
+
+func TestUpstreamTransport_DialTimeout(t *testing.T) {
+	cfg := defaultUpstreamConfig
+	cfg.DialTimeout = 50 * time.Millisecond
+	u := newUpstreamTransport(cfg)
+	if u.dialer.Timeout != 50*time.Millisecond {
+		t.Errorf("dialer timeout = %v, want 50ms", u.dialer.Timeout)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conn, err := u.DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("DialContext: %v", err)
+	}
+	conn.Close()
+}
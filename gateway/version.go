@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Version and Commit identify the running build. Release builds set them at
+// link time:
+//
+//	go build -ldflags "-X docker-gateway/gateway.Version=v0.4.0 -X docker-gateway/gateway.Commit=1a2b3c4"
+//
+// Without ldflags, Commit falls back to the VCS revision the Go toolchain
+// stamps into the binary.
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+// BuildInfo describes the running gateway binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// GetBuildInfo returns the version, commit and Go toolchain of the binary.
+func GetBuildInfo() BuildInfo {
+	commit := Commit
+	if commit == "unknown" {
+		if rev := vcsRevision(); rev != "" {
+			commit = rev
+		}
+	}
+	return BuildInfo{
+		Version:   Version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// labels returns the build info as gateway_build_info labels.
+func (b BuildInfo) labels() prometheus.Labels {
+	return prometheus.Labels{"version": b.Version, "commit": b.Commit, "go_version": b.GoVersion}
+}
+
+// vcsRevision returns the short VCS revision embedded by `go build`, if any.
+func vcsRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			if len(s.Value) > 12 {
+				return s.Value[:12]
+			}
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// handleVersion returns the build info as JSON.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(GetBuildInfo())
+}
@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestGetBuildInfo(t *testing.T) {
+	oldVersion, oldCommit := Version, Commit
+	defer func() { Version, Commit = oldVersion, oldCommit }()
+
+	Version, Commit = "v1.2.3", "abc1234"
+	got := GetBuildInfo()
+	if got.Version != "v1.2.3" || got.Commit != "abc1234" || got.GoVersion != runtime.Version() {
+		t.Errorf("GetBuildInfo = %+v", got)
+	}
+
+	// Without ldflags the commit is never empty.
+	Commit = "unknown"
+	if got := GetBuildInfo(); got.Commit == "" {
+		t.Error("commit should fall back to the VCS revision or stay \"unknown\"")
+	}
+}
+
+func TestHandleVersion(t *testing.T) {
+	oldVersion := Version
+	defer func() { Version = oldVersion }()
+	Version = "v1.2.3"
+
+	s := &Server{cfg: &GatewayConfig{}}
+	w := httptest.NewRecorder()
+	s.handleVersion(w, httptest.NewRequest(http.MethodGet, "/_version", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got BuildInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Version != "v1.2.3" || got.GoVersion != runtime.Version() {
+		t.Errorf("body = %+v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxHoldBody bounds the request body buffered while a wake_mode "hold"
+// request waits for its container.
+const maxHoldBody = 10 << 20
+
+// serveWaking answers a request that triggered a wake: with the loading page
+// or, for wake_mode "hold", by holding it until done reports the end of the
+// wake and then calling proxy.
+func (s *Server) serveWaking(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig, done <-chan error, proxy func(http.ResponseWriter, *http.Request)) {
+	if cfg.WakeMode != WakeModeHold {
+		s.serveLoadingPage(w, r, cfg)
+		return
+	}
+	s.holdRequest(w, r, cfg, done, proxy)
+}
+
+// holdRequest buffers the body of r, so the client is not left blocked
+// mid-upload and the body survives the wait, and waits up to start_timeout
+// for the wake. The wake goes on in the background if the client leaves or
+// the wait times out.
+func (s *Server) holdRequest(w http.ResponseWriter, r *http.Request, cfg *ContainerConfig, done <-chan error, proxy func(http.ResponseWriter, *http.Request)) {
+	span := spanFromContext(r.Context())
+	span.SetAttr("gateway.wake_mode", WakeModeHold)
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxHoldBody+1))
+		r.Body.Close()
+		if err != nil {
+			return // client went away
+		}
+		if len(body) > maxHoldBody {
+			s.serveErrorPageStatus(w, r, cfg,
+				fmt.Sprintf("Request body too large to hold while the service starts (limit %d MiB)", maxHoldBody>>20),
+				http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+
+	// The hold may outlast gateway.server.write_timeout, which would cut
+	// the held response: leave the whole write_timeout for the answer.
+	if wt := s.writeTimeout(); wt > 0 {
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(cfg.StartTimeout + wt))
+	}
+	s.manager.noteWaiting(cfg.Name)
+	timer := time.NewTimer(cfg.StartTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			span.SetAttr("gateway.outcome", "wake_failed")
+			_, errMsg := s.manager.GetStartState(cfg.Name)
+			if errMsg == "" {
+				errMsg = err.Error()
+			}
+			s.serveErrorPage(w, r, cfg, "Container failed to start: "+errMsg)
+			return
+		}
+		proxy(w, r)
+	case <-timer.C:
+		span.SetAttr("gateway.outcome", "hold_timeout")
+		w.Header().Set("Retry-After", strconv.Itoa(loadingRetryAfter))
+		s.serveErrorPageStatus(w, r, cfg,
+			fmt.Sprintf("The service did not become ready within %s; it is still starting, please retry", cfg.StartTimeout),
+			http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+	}
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (g *fakeGateway) post(host, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Host = host
+	g.handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestWakeHold_ReplaysRequest(t *testing.T) {
+	host, port := newBackend(t, "", echoRequest)
+	rt := NewFakeRuntime()
+	rt.AddContainer("api", FakeContainer{Status: "exited", Host: host, Port: port, StartDelay: 50 * time.Millisecond})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "api", Host: "api.local", TargetPort: port, WakeMode: WakeModeHold})
+
+	w := g.post("api.local", "/v1/items", `{"id": 1}`)
+	if w.Code != http.StatusOK || w.Body.String() != `POST {"id": 1}` {
+		t.Errorf("status %d, body %q; want the request proxied once started", w.Code, w.Body)
+	}
+	if status, _ := g.manager.GetStartState("api"); status != "running" {
+		t.Errorf("start state %q, want running", status)
+	}
+}
+
+func TestWakeHold_StartFailure(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("api", FakeContainer{Status: "exited", StartErr: errors.New("port is already allocated")})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "api", Host: "api.local", TargetPort: "80", WakeMode: WakeModeHold})
+
+	w := g.post("api.local", "/", "x")
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "failed to start") {
+		t.Errorf("status %d, want the 502 error page: %s", w.Code, w.Body)
+	}
+}
+
+func TestWakeHold_Timeout(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("api", FakeContainer{Status: "exited", StartDelay: time.Second})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "api", Host: "api.local", TargetPort: "80",
+		WakeMode: WakeModeHold, StartTimeout: 50 * time.Millisecond})
+
+	w := g.get("api.local", "/")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "5" {
+		t.Errorf("status %d, Retry-After %q; want 503 with Retry-After 5", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestWakeHold_BodyTooLarge(t *testing.T) {
+	rt := NewFakeRuntime()
+	rt.AddContainer("api", FakeContainer{Status: "exited", StartDelay: time.Second})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "api", Host: "api.local", TargetPort: "80", WakeMode: WakeModeHold})
+
+	w := g.post("api.local", "/", strings.Repeat("x", maxHoldBody+1))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413", w.Code)
+	}
+}
+
+func TestWakeMode_PageByDefault(t *testing.T) {
+	host, port := newBackend(t, "app")
+	rt := NewFakeRuntime()
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "app", Host: "app.local", TargetPort: port})
+
+	if w := g.get("app.local", "/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<html") {
+		t.Errorf("status %d, want the loading page", w.Code)
+	}
+	g.waitStarted(t, "app")
+}
+
+func TestValidate_WakeMode(t *testing.T) {
+	cfg := &GatewayConfig{Containers: []ContainerConfig{{Name: "a", Host: "h", WakeMode: "queue"}}}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "unknown wake_mode") {
+		t.Errorf("error = %v, want unknown wake_mode", err)
+	}
+}
+
+func TestWakeHold_OutlastsWriteTimeout(t *testing.T) {
+	host, port := newBackend(t, "", echoRequest)
+	rt := NewFakeRuntime()
+	rt.AddContainer("api", FakeContainer{Status: "exited", Host: host, Port: port, StartDelay: 300 * time.Millisecond})
+	g := newFakeGateway(t, rt, ContainerConfig{Name: "api", Host: "api.local", TargetPort: port, WakeMode: WakeModeHold})
+	// Shorter than the start: the held response must still be written.
+	g.server.srvCfg.WriteTimeout = 100 * time.Millisecond
+	gw := httptest.NewUnstartedServer(g.handler)
+	gw.Config.WriteTimeout = g.server.srvCfg.WriteTimeout
+	gw.Start()
+	defer gw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, gw.URL+"/v1/items", strings.NewReader("x"))
+	req.Host = "api.local"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("held request: %v, want the proxied reply", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "POST x" {
+		t.Errorf("status %d, body %q, want the request proxied once started", resp.StatusCode, body)
+	}
+}
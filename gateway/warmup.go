@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// warmUp sends cfg.Warmup.Count GET requests to cfg.Warmup.Path on a
+// container that just passed its readiness probe, so JIT compilation and
+// caches are primed before the first real request. Requests run one after
+// the other; failures and error statuses are logged and otherwise ignored,
+// as the container is already known to be ready.
+func warmUp(ctx context.Context, cfg *ContainerConfig, host, port string, opts ProbeOptions) {
+	if cfg.Warmup.Count <= 0 {
+		return
+	}
+	ctx, span := StartSpan(ctx, "container.warmup")
+	defer span.End()
+	span.SetAttr("url.path", cfg.Warmup.Path)
+
+	target := probeURL(host, port, cfg.Warmup.Path)
+	client := &http.Client{Timeout: opts.timeout()}
+	for i := 0; i < cfg.Warmup.Count; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			slog.Warn("warm-up request creation failed", "container", cfg.Name, "url", target, "error", err)
+			return
+		}
+		if cfg.Host != "" {
+			req.Host = cfg.Host
+		}
+		req.Header.Set("User-Agent", "docker-gateway-warmup")
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Debug("warm-up request failed", "container", cfg.Name, "url", target, "error", err)
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		slog.Debug("warm-up request done", "container", cfg.Name, "url", target, "status", resp.StatusCode)
+	}
+}
@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWarmUp(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = append(got, r.Host+r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError) // ignored: warm-up only primes
+	}))
+	defer backend.Close()
+	host, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+	opts := ProbeOptions{Timeout: time.Second}
+
+	tests := []struct {
+		name string
+		cfg  ContainerConfig
+		want []string
+	}{
+		{
+			name: "disabled",
+			cfg:  ContainerConfig{Name: "app", Warmup: WarmupConfig{Path: "/"}},
+			want: nil,
+		},
+		{
+			name: "count requests with the container host",
+			cfg:  ContainerConfig{Name: "app", Host: "app.example.com", Warmup: WarmupConfig{Path: "/api/ping", Count: 3}},
+			want: []string{"app.example.com/api/ping", "app.example.com/api/ping", "app.example.com/api/ping"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			got = nil
+			mu.Unlock()
+			warmUp(context.Background(), &tt.cfg, host, port, opts)
+			mu.Lock()
+			defer mu.Unlock()
+			if len(got) != len(tt.want) {
+				t.Fatalf("requests = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("request %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWarmUp_UnreachableDoesNotBlock(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close() // nothing listens: every request fails fast
+
+	cfg := ContainerConfig{Name: "app", Warmup: WarmupConfig{Path: "/", Count: 5}}
+	done := make(chan struct{})
+	go func() {
+		warmUp(context.Background(), &cfg, host, port, ProbeOptions{Timeout: time.Second})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("warmUp blocked on an unreachable container")
+	}
+}
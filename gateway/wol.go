@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// wolPollInterval is how often the daemon is pinged while the host boots.
+	wolPollInterval = 2 * time.Second
+	// wolResendInterval repeats the magic packet, which is not acknowledged
+	// and may be lost, while the host has not answered.
+	wolResendInterval = 15 * time.Second
+	// wolProbeTimeout bounds the ping that tells a suspended host from a
+	// failing request.
+	wolProbeTimeout = 3 * time.Second
+)
+
+// parseWakeOnLANMAC parses wake_on_lan.mac, which must be a 48-bit address.
+func parseWakeOnLANMAC(s string) (net.HardwareAddr, error) {
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(mac) != 6 {
+		return nil, fmt.Errorf("mac %q is not a 48-bit address", s)
+	}
+	return mac, nil
+}
+
+// magicPacket is the Wake-on-LAN payload: six 0xFF bytes, then the MAC
+// address sixteen times.
+func magicPacket(mac net.HardwareAddr) []byte {
+	return append(bytes.Repeat([]byte{0xFF}, 6), bytes.Repeat(mac, 16)...)
+}
+
+// sendMagicPacket sends the magic packet for mac to addr over UDP.
+func sendMagicPacket(addr string, mac net.HardwareAddr) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(magicPacket(mac))
+	return err
+}
+
+// hostWaker powers on the Docker host with Wake-on-LAN. Concurrent wakes
+// share one power-on.
+type hostWaker struct {
+	cfg  atomic.Pointer[WakeOnLANConfig]
+	mu   sync.Mutex // held during a power-on
+	send func(addr string, mac net.HardwareAddr) error
+	poll time.Duration
+}
+
+func newHostWaker() *hostWaker {
+	h := &hostWaker{send: sendMagicPacket, poll: wolPollInterval}
+	h.cfg.Store(&WakeOnLANConfig{})
+	return h
+}
+
+// Sync applies the wake_on_lan configuration.
+func (h *hostWaker) Sync(cfg WakeOnLANConfig) {
+	h.cfg.Store(&cfg)
+}
+
+func (h *hostWaker) enabled() bool {
+	return h.cfg.Load().MAC != ""
+}
+
+// asleep reports whether Wake-on-LAN is configured and the daemon does not
+// answer.
+func (h *hostWaker) asleep(ctx context.Context, rt ContainerRuntime) bool {
+	if !h.enabled() {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, wolProbeTimeout)
+	defer cancel()
+	return rt.Ping(ctx) != nil
+}
+
+// wake powers on the Docker host when its daemon does not answer and waits,
+// for at most boot_timeout, until it does.
+func (h *hostWaker) wake(ctx context.Context, rt ContainerRuntime) error {
+	cfg := *h.cfg.Load()
+	if !h.asleep(ctx, rt) {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.asleep(ctx, rt) {
+		return nil // another wake powered it on meanwhile
+	}
+	mac, err := parseWakeOnLANMAC(cfg.MAC)
+	if err != nil {
+		return fmt.Errorf("wake_on_lan: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.BootTimeout)
+	defer cancel()
+	slog.Info("docker host unreachable, sending Wake-on-LAN", "mac", cfg.MAC, "broadcast", cfg.Broadcast)
+	start := time.Now()
+	var sent time.Time
+	ticker := time.NewTicker(h.poll)
+	defer ticker.Stop()
+	for {
+		if time.Since(sent) >= wolResendInterval {
+			if err := h.send(cfg.Broadcast, mac); err != nil {
+				RecordWakeOnLAN(false)
+				return fmt.Errorf("wake_on_lan: send magic packet: %w", err)
+			}
+			sent = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			RecordWakeOnLAN(false)
+			return fmt.Errorf("docker host did not answer within %s of Wake-on-LAN", cfg.BootTimeout)
+		case <-ticker.C:
+		}
+		if rt.Ping(ctx) == nil {
+			RecordWakeOnLAN(true)
+			slog.Info("docker host is up", "after", time.Since(start).Round(time.Second))
+			return nil
+		}
+	}
+}
+
+// SyncWakeOnLAN applies the wake_on_lan configuration.
+func (m *ContainerManager) SyncWakeOnLAN(cfg WakeOnLANConfig) {
+	m.wol.Sync(cfg)
+}
+
+// DockerHostAsleep reports whether the Docker host is down with
+// wake_on_lan configured, so a wake would power it on first.
+func (m *ContainerManager) DockerHostAsleep(ctx context.Context) bool {
+	return m.wol.asleep(ctx, m.client)
+}
+
+// wakeTimeout bounds a background wake of cfg: its start_timeout with some
+// slack, plus the boot of the Docker host when Wake-on-LAN may run.
+func (m *ContainerManager) wakeTimeout(cfg *ContainerConfig) time.Duration {
+	d := cfg.StartTimeout + 10*time.Second
+	if c := m.wol.cfg.Load(); c.MAC != "" {
+		d += c.BootTimeout
+	}
+	return d
+}
@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// suspendedRuntime is a FakeRuntime whose daemon does not answer while down.
+type suspendedRuntime struct {
+	*FakeRuntime
+	down atomic.Bool
+}
+
+var errDaemonDown = errors.New("dial tcp 10.0.0.5:2376: connect: no route to host")
+
+func (r *suspendedRuntime) Ping(ctx context.Context) error {
+	if r.down.Load() {
+		return errDaemonDown
+	}
+	return r.FakeRuntime.Ping(ctx)
+}
+
+func (r *suspendedRuntime) GetContainerStatus(ctx context.Context, name string) (string, error) {
+	if r.down.Load() {
+		return "", errDaemonDown
+	}
+	return r.FakeRuntime.GetContainerStatus(ctx, name)
+}
+
+func (r *suspendedRuntime) InspectContainer(ctx context.Context, name string) (*ContainerInfo, error) {
+	if r.down.Load() {
+		return nil, errDaemonDown
+	}
+	return r.FakeRuntime.InspectContainer(ctx, name)
+}
+
+func TestMagicPacket(t *testing.T) {
+	mac, err := parseWakeOnLANMAC("00:11:22:33:44:55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := magicPacket(mac)
+	if len(p) != 102 || !bytes.Equal(p[:6], bytes.Repeat([]byte{0xFF}, 6)) {
+		t.Fatalf("packet = % x", p)
+	}
+	for i := 0; i < 16; i++ {
+		if !bytes.Equal(p[6+6*i:12+6*i], mac) {
+			t.Fatalf("repetition %d = % x, want the MAC", i, p[6+6*i:12+6*i])
+		}
+	}
+
+	if _, err := parseWakeOnLANMAC("00:00:5e:00:53:01:02:03"); err == nil {
+		t.Error("EUI-64 address accepted")
+	}
+}
+
+func TestSendMagicPacket(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	mac, _ := parseWakeOnLANMAC("00:11:22:33:44:55")
+
+	if err := sendMagicPacket(conn.LocalAddr().String(), mac); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 200)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], magicPacket(mac)) {
+		t.Errorf("received % x", buf[:n])
+	}
+}
+
+func TestWakeOnLAN_PowersOnHostBeforeStart(t *testing.T) {
+	host, port := newBackend(t, "ok")
+	rt := &suspendedRuntime{FakeRuntime: NewFakeRuntime()}
+	rt.AddContainer("app", FakeContainer{Status: "exited", Host: host, Port: port})
+	rt.down.Store(true)
+
+	cfg := &GatewayConfig{
+		Gateway:    GlobalConfig{WakeOnLAN: WakeOnLANConfig{MAC: "00:11:22:33:44:55"}},
+		Containers: []ContainerConfig{{Name: "app", Host: "app.local", TargetPort: port}},
+	}
+	applyDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	m := NewContainerManager(rt)
+	m.SyncWakeOnLAN(cfg.Gateway.WakeOnLAN)
+	var sent atomic.Int32
+	m.wol.poll = 5 * time.Millisecond
+	m.wol.send = func(addr string, mac net.HardwareAddr) error {
+		if addr != "255.255.255.255:9" || mac.String() != "00:11:22:33:44:55" {
+			t.Errorf("packet for %s to %s", mac, addr)
+		}
+		sent.Add(1)
+		rt.down.Store(false) // the host resumes
+		return nil
+	}
+	s, err := NewServer(m, NewScheduleManager(rt, m), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := gatheredValue(t, "gateway_wake_on_lan_total", map[string]string{"result": "success"})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = "app.local"
+	s.buildHandler(cfg.Gateway.AdminAuth, nil).ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<html") {
+		t.Fatalf("status %d, want the loading page", w.Code)
+	}
+
+	g := &fakeGateway{manager: m}
+	if status := g.waitStarted(t, "app"); status != "running" {
+		t.Fatalf("state = %q, want running", status)
+	}
+	if n := sent.Load(); n != 1 {
+		t.Errorf("magic packets = %d, want 1", n)
+	}
+	if calls := rt.Calls(); len(calls) != 1 || calls[0] != "start app" {
+		t.Errorf("calls = %v, want the start after the host woke", calls)
+	}
+	if after, _ := gatheredValue(t, "gateway_wake_on_lan_total", map[string]string{"result": "success"}); after != before+1 {
+		t.Errorf("gateway_wake_on_lan_total{success} = %v, want %v", after, before+1)
+	}
+}
+
+func TestWakeOnLAN_BootTimeout(t *testing.T) {
+	rt := &suspendedRuntime{FakeRuntime: NewFakeRuntime()}
+	rt.AddContainer("app", FakeContainer{Status: "exited"})
+	rt.down.Store(true)
+
+	m := NewContainerManager(rt)
+	m.SyncWakeOnLAN(WakeOnLANConfig{MAC: "00:11:22:33:44:55", Broadcast: "192.168.1.255:9", BootTimeout: 30 * time.Millisecond})
+	m.wol.poll = 5 * time.Millisecond
+	m.wol.send = func(string, net.HardwareAddr) error { return nil }
+
+	err := m.EnsureRunning(context.Background(), &ContainerConfig{Name: "app", TargetPort: "80", StartTimeout: time.Second})
+	if err == nil || !strings.Contains(err.Error(), "did not answer within 30ms") {
+		t.Fatalf("error = %v, want a boot timeout", err)
+	}
+	if status, _ := m.GetStartState("app"); status != string(statusFailed) {
+		t.Errorf("state = %q, want failed", status)
+	}
+	if calls := rt.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want no start", calls)
+	}
+}
+
+func TestWakeOnLAN_Disabled(t *testing.T) {
+	rt := &suspendedRuntime{FakeRuntime: NewFakeRuntime()}
+	rt.AddContainer("app", FakeContainer{Status: "exited"})
+	rt.down.Store(true)
+	m := NewContainerManager(rt)
+
+	if m.DockerHostAsleep(context.Background()) {
+		t.Error("host reported asleep without wake_on_lan")
+	}
+	if got := m.wakeTimeout(&ContainerConfig{StartTimeout: time.Minute}); got != 70*time.Second {
+		t.Errorf("wakeTimeout = %s, want start_timeout + 10s", got)
+	}
+}
+
+func TestValidate_WakeOnLAN(t *testing.T) {
+	for _, tt := range []struct {
+		wol  WakeOnLANConfig
+		want string
+	}{
+		{WakeOnLANConfig{MAC: "not-a-mac"}, "wake_on_lan: address not-a-mac"},
+		{WakeOnLANConfig{MAC: "00:11:22:33:44:55", Broadcast: "192.168.1.255"}, "wake_on_lan: broadcast"},
+		{WakeOnLANConfig{MAC: "00:11:22:33:44:55", BootTimeout: -time.Second}, "boot_timeout cannot be negative"},
+	} {
+		cfg := &GatewayConfig{Gateway: GlobalConfig{WakeOnLAN: tt.wol}}
+		applyDefaults(cfg)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: error = %v, want %q", tt.wol, err, tt.want)
+		}
+	}
+}
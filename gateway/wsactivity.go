@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// tunnelActivityInterval is how often a WebSocket tunnel with traffic
+// refreshes the activity of its container. It only has to be well under the
+// one-minute period of the idle watcher.
+const tunnelActivityInterval = 15 * time.Second
+
+// tunnelActivity follows the traffic of a WebSocket tunnel: it records
+// activity on the container while bytes move, at most once per interval, and
+// knows when the last byte moved for websocket.idle_timeout. Both directions
+// of a tunnel share one, so a chat that only receives is not idle.
+type tunnelActivity struct {
+	every    time.Duration
+	record   func()
+	last     atomic.Int64 // UnixNano of the last record
+	lastByte atomic.Int64 // UnixNano of the last write in either direction
+}
+
+func newTunnelActivity(every time.Duration, record func()) *tunnelActivity {
+	a := &tunnelActivity{every: every, record: record}
+	now := time.Now().UnixNano()
+	a.last.Store(now) // the upgrade request was recorded already
+	a.lastByte.Store(now)
+	return a
+}
+
+// touch notes traffic and records activity unless it was recorded less than
+// every ago.
+func (a *tunnelActivity) touch() {
+	now := time.Now().UnixNano()
+	a.lastByte.Store(now)
+	last := a.last.Load()
+	if now-last < int64(a.every) || !a.last.CompareAndSwap(last, now) {
+		return
+	}
+	a.record()
+}
+
+// idleFor returns how long no bytes moved through the tunnel.
+func (a *tunnelActivity) idleFor() time.Duration {
+	return time.Duration(time.Now().UnixNano() - a.lastByte.Load())
+}
+
+// watchIdle calls closeTunnel once the tunnel has been idle for timeout, or
+// returns when stop is closed first.
+func (a *tunnelActivity) watchIdle(timeout time.Duration, stop <-chan struct{}, closeTunnel func()) {
+	ticker := time.NewTicker(max(timeout/4, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if a.idleFor() >= timeout {
+				closeTunnel()
+				return
+			}
+		}
+	}
+}
+
+// writer wraps w so that every successful write touches the activity.
+func (a *tunnelActivity) writer(w io.Writer) io.Writer {
+	return &activityWriter{w: w, activity: a}
+}
+
+type activityWriter struct {
+	w        io.Writer
+	activity *tunnelActivity
+}
+
+func (aw *activityWriter) Write(p []byte) (int, error) {
+	n, err := aw.w.Write(p)
+	if n > 0 {
+		aw.activity.touch()
+	}
+	return n, err
+}
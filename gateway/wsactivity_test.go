@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTunnelActivity(t *testing.T) {
+	var records atomic.Int32
+	a := newTunnelActivity(30*time.Millisecond, func() { records.Add(1) })
+	var up, down bytes.Buffer
+	client, backend := a.writer(&up), a.writer(&down)
+
+	// Right after the upgrade nothing needs recording.
+	client.Write([]byte("hello"))
+	if got := records.Load(); got != 0 {
+		t.Fatalf("records = %d right after the upgrade, want 0", got)
+	}
+
+	// Both directions share the interval.
+	time.Sleep(40 * time.Millisecond)
+	backend.Write([]byte("hi"))
+	client.Write([]byte("again"))
+	if got := records.Load(); got != 1 {
+		t.Fatalf("records = %d after one interval, want 1", got)
+	}
+
+	// Empty writes are not traffic.
+	time.Sleep(40 * time.Millisecond)
+	backend.Write(nil)
+	if got := records.Load(); got != 1 {
+		t.Errorf("records = %d after an empty write, want 1", got)
+	}
+	if up.String() != "helloagain" || down.String() != "hi" {
+		t.Errorf("written = %q / %q, want the bytes passed through", up.String(), down.String())
+	}
+}
+
+func TestTunnelActivity_WatchIdle(t *testing.T) {
+	a := newTunnelActivity(time.Hour, func() {})
+	w := a.writer(io.Discard)
+	closed := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go a.watchIdle(60*time.Millisecond, stop, func() { close(closed) })
+
+	// Traffic keeps the tunnel open past the timeout.
+	for range 6 {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ping"))
+	}
+	select {
+	case <-closed:
+		t.Fatal("tunnel with traffic was closed")
+	default:
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("idle tunnel was not closed")
+	}
+}
+
+// wsBackend accepts WebSocket upgrades and then stays silent.
+func wsBackend(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+					return
+				}
+				io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+	return ln
+}
+
+// dialWebSocket sends an upgrade request through the gateway and returns
+// the connection and the response status.
+func dialWebSocket(t *testing.T, addr string) (net.Conn, int) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: app\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &bufferedConn{Conn: conn, r: br}, resp.StatusCode
+}
+
+// bufferedConn reads through the reader that parsed the handshake, so the
+// frames it buffered are not lost.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+func TestProxyWebSocket_Limits(t *testing.T) {
+	backend := wsBackend(t)
+	host, port, _ := net.SplitHostPort(backend.Addr().String())
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(NewFakeRuntime())}
+	cfg := &ContainerConfig{Name: host, TargetPort: port, Target: TargetDNS,
+		WebSocket: WebSocketConfig{MaxConnections: 1, IdleTimeout: 100 * time.Millisecond}}
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Wrapped like in handleRequest: the hijack must get through.
+		s.proxyRequest(&metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}, r, cfg)
+	}))
+	defer gw.Close()
+	addr := gw.Listener.Addr().String()
+
+	first, status := dialWebSocket(t, addr)
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("first upgrade = %d, want 101", status)
+	}
+	if _, status := dialWebSocket(t, addr); status != http.StatusServiceUnavailable {
+		t.Errorf("upgrade over max_connections = %d, want 503", status)
+	}
+
+	// The silent tunnel is closed after the idle timeout, freeing its slot.
+	first.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := first.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("read on idle tunnel = %v, want EOF", err)
+	}
+	waitFor(t, func() bool { return s.manager.drain.Active(host) == 0 })
+	if _, status := dialWebSocket(t, addr); status != http.StatusSwitchingProtocols {
+		t.Errorf("upgrade after the idle close = %d, want 101", status)
+	}
+}
@@ -0,0 +1,251 @@
+package gateway
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// WebSocket tunnel deadlines.
+const (
+	// wsHandshakeTimeout bounds the exchange of the upgrade request and the
+	// backend's response.
+	wsHandshakeTimeout = 30 * time.Second
+	// wsHalfCloseTimeout bounds how long one direction of a tunnel may stay
+	// open after the other reached the end of its stream.
+	wsHalfCloseTimeout = 30 * time.Second
+)
+
+// errTunnelClosed stops the server-to-client copy of a tunnel once the
+// gateway has sent its close frame.
+var errTunnelClosed = errors.New("websocket tunnel closed by the gateway")
+
+// closeGoingAway is the close frame sent to clients when the gateway shuts
+// down: status 1001 (going away) with a short reason.
+var closeGoingAway = func() []byte {
+	reason := "gateway shutting down"
+	frame := []byte{0x88, byte(2 + len(reason)), 0x03, 0xe9} // FIN+close, 1001
+	return append(frame, reason...)
+}()
+
+// frameTracker follows the frame boundaries of a WebSocket byte stream
+// without buffering it (RFC 6455 section 5.2).
+type frameTracker struct {
+	hdr       []byte // header bytes of the frame being read
+	remaining uint64 // payload bytes left in the current frame
+	inPayload bool
+}
+
+// atBoundary reports whether the bytes seen so far end on a whole frame.
+func (f *frameTracker) atBoundary() bool {
+	return len(f.hdr) == 0 && !f.inPayload
+}
+
+// consume advances over p up to the end of the first frame that ends in it
+// and returns the number of bytes consumed.
+func (f *frameTracker) consume(p []byte) int {
+	n := 0
+	for n < len(p) {
+		if f.inPayload {
+			k := min(uint64(len(p)-n), f.remaining)
+			n += int(k)
+			if f.remaining -= k; f.remaining == 0 {
+				f.inPayload = false
+				return n
+			}
+			continue
+		}
+		f.hdr = append(f.hdr, p[n])
+		n++
+		if size, ok := frameHeaderSize(f.hdr); ok && len(f.hdr) == size {
+			f.remaining = framePayloadLen(f.hdr)
+			f.hdr = f.hdr[:0]
+			if f.remaining == 0 {
+				return n
+			}
+			f.inPayload = true
+		}
+	}
+	return n
+}
+
+// frameHeaderSize returns the full size of a frame header from its first
+// bytes, once at least two are known.
+func frameHeaderSize(hdr []byte) (int, bool) {
+	if len(hdr) < 2 {
+		return 0, false
+	}
+	size := 2
+	switch hdr[1] & 0x7f {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+	if hdr[1]&0x80 != 0 { // masked
+		size += 4
+	}
+	return size, true
+}
+
+// framePayloadLen decodes the payload length of a complete frame header.
+func framePayloadLen(hdr []byte) uint64 {
+	switch n := hdr[1] & 0x7f; n {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(hdr[2:4]))
+	case 127:
+		return binary.BigEndian.Uint64(hdr[2:10])
+	default:
+		return uint64(n)
+	}
+}
+
+// frameWriter carries the server-to-client side of a tunnel and tracks its
+// frames, so that goAway can send a close frame without cutting one in two.
+type frameWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	frames  frameTracker
+	closing bool // goAway was called; close at the next boundary
+	closed  bool // close frame sent; later data is dropped
+}
+
+func (fw *frameWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.closed {
+		return 0, errTunnelClosed
+	}
+	if !fw.closing {
+		n, err := fw.w.Write(p)
+		for off := 0; off < n; {
+			off += fw.frames.consume(p[off:n])
+		}
+		return n, err
+	}
+	// Finish the frame in progress, then close.
+	k := fw.frames.consume(p)
+	n, err := fw.w.Write(p[:k])
+	if err != nil {
+		return n, err
+	}
+	if fw.frames.atBoundary() {
+		fw.sendClose()
+		return n, errTunnelClosed
+	}
+	return n, nil
+}
+
+// goAway sends the client a going-away close frame, right away when the
+// stream is between frames or else once the current frame is written.
+func (fw *frameWriter) goAway() {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.closing {
+		return
+	}
+	fw.closing = true
+	if fw.frames.atBoundary() {
+		fw.sendClose()
+	}
+}
+
+// sendClose writes the close frame. Caller must hold fw.mu.
+func (fw *frameWriter) sendClose() {
+	fw.closed = true
+	fw.w.Write(closeGoingAway)
+}
+
+// wsTunnel is an open WebSocket tunnel. Hijacked connections are invisible
+// to http.Server.Shutdown, so the server tracks them itself to close them
+// when it stops.
+type wsTunnel struct {
+	client   net.Conn
+	backend  net.Conn
+	toClient *frameWriter
+	done     chan struct{} // closed when the tunnel is torn down
+}
+
+// shutdown tells both ends that the tunnel is closing: the client gets a
+// close frame and the backend sees the client's side close, as if the
+// client had left. Either end closing then ends the tunnel.
+func (t *wsTunnel) shutdown() {
+	t.toClient.goAway()
+	if cw, ok := t.backend.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	}
+}
+
+// endTunnelDirection finishes one direction of a tunnel, which copied from
+// src to dst and ended with err. At the end of the stream (or after the
+// gateway's close frame) dst is half-closed and the other direction, which
+// reads dst, gets wsHalfCloseTimeout to finish. On an error both ends are
+// closed, which stops the other direction at once.
+func endTunnelDirection(dst, src net.Conn, err error) {
+	if err == nil || errors.Is(err, errTunnelClosed) {
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok && cw.CloseWrite() == nil {
+			dst.SetReadDeadline(time.Now().Add(wsHalfCloseTimeout))
+			return
+		}
+	}
+	dst.Close()
+	src.Close()
+}
+
+// tunnelSet tracks the open WebSocket tunnels of a server.
+type tunnelSet struct {
+	mu      sync.Mutex
+	tunnels map[*wsTunnel]struct{}
+}
+
+func (ts *tunnelSet) add(t *wsTunnel) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.tunnels == nil {
+		ts.tunnels = make(map[*wsTunnel]struct{})
+	}
+	ts.tunnels[t] = struct{}{}
+}
+
+func (ts *tunnelSet) remove(t *wsTunnel) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.tunnels, t)
+}
+
+func (ts *tunnelSet) list() []*wsTunnel {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	out := make([]*wsTunnel, 0, len(ts.tunnels))
+	for t := range ts.tunnels {
+		out = append(out, t)
+	}
+	return out
+}
+
+// closeAll shuts every open tunnel down and waits for them to close until
+// ctx expires; the tunnels left then are closed at once.
+func (ts *tunnelSet) closeAll(ctx context.Context) {
+	tunnels := ts.list()
+	if len(tunnels) == 0 {
+		return
+	}
+	slog.Info("closing WebSocket tunnels", "count", len(tunnels))
+	for _, t := range tunnels {
+		go t.shutdown() // may wait for a frame being written to a slow client
+	}
+	for _, t := range tunnels {
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			t.client.Close()
+			t.backend.Close()
+			<-t.done
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// wsFrame builds an unmasked binary frame (or a masked one with mask set).
+func wsFrame(payload []byte, mask bool) []byte {
+	frame := []byte{0x82}
+	maskBit := byte(0)
+	if mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	if mask {
+		frame = append(frame, 1, 2, 3, 4)
+	}
+	return append(frame, payload...)
+}
+
+func TestFrameTracker(t *testing.T) {
+	stream := bytes.Join([][]byte{
+		wsFrame([]byte("hi"), false),
+		wsFrame(nil, false),
+		wsFrame(bytes.Repeat([]byte("x"), 300), true),
+		wsFrame(bytes.Repeat([]byte("y"), 70_000), false),
+	}, nil)
+	ends := map[int]bool{}
+	off := 0
+	for _, f := range [][]byte{
+		wsFrame([]byte("hi"), false), wsFrame(nil, false),
+		wsFrame(bytes.Repeat([]byte("x"), 300), true), wsFrame(bytes.Repeat([]byte("y"), 70_000), false),
+	} {
+		off += len(f)
+		ends[off] = true
+	}
+
+	// Feed the stream one byte at a time: the tracker must be at a boundary
+	// exactly at the end of each frame.
+	var f frameTracker
+	for i := range stream {
+		if n := f.consume(stream[i : i+1]); n != 1 {
+			t.Fatalf("consume(1 byte) = %d at offset %d", n, i)
+		}
+		if got := f.atBoundary(); got != ends[i+1] {
+			t.Fatalf("atBoundary = %v after %d bytes, want %v", got, i+1, ends[i+1])
+		}
+	}
+
+	// In one call, consume stops at the end of the first frame.
+	var g frameTracker
+	if n := g.consume(stream); n != len(wsFrame([]byte("hi"), false)) {
+		t.Errorf("consume(stream) = %d, want the first frame's length", n)
+	}
+}
+
+func TestFrameWriter_GoAway(t *testing.T) {
+	t.Run("between frames: close right away", func(t *testing.T) {
+		var out bytes.Buffer
+		fw := &frameWriter{w: &out}
+		fw.Write(wsFrame([]byte("hello"), false))
+		fw.goAway()
+		if !bytes.HasSuffix(out.Bytes(), closeGoingAway) {
+			t.Fatalf("close frame not sent: %x", out.Bytes())
+		}
+		if _, err := fw.Write(wsFrame([]byte("late"), false)); err != errTunnelClosed {
+			t.Errorf("Write after close = %v, want errTunnelClosed", err)
+		}
+	})
+
+	t.Run("mid-frame: close after the frame", func(t *testing.T) {
+		var out bytes.Buffer
+		fw := &frameWriter{w: &out}
+		frame := wsFrame([]byte("hello world"), false)
+		next := wsFrame([]byte("next"), false)
+		fw.Write(frame[:5])
+		fw.goAway()
+		if out.Len() != 5 {
+			t.Fatalf("close frame sent mid-frame: %x", out.Bytes())
+		}
+		n, err := fw.Write(append(frame[5:], next...))
+		if err != errTunnelClosed || n != len(frame)-5 {
+			t.Fatalf("Write = (%d, %v), want the rest of the frame and errTunnelClosed", n, err)
+		}
+		want := append(append([]byte{}, frame...), closeGoingAway...)
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("written = %x, want the frame then the close frame", out.Bytes())
+		}
+	})
+}
+
+func TestServer_ShutdownClosesTunnels(t *testing.T) {
+	backendEOF := make(chan struct{})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		conn.Write(wsFrame([]byte("welcome"), false))
+		io.Copy(io.Discard, conn) // returns when the gateway half-closes
+		close(backendEOF)
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(NewFakeRuntime())}
+	cfg := &ContainerConfig{Name: host, TargetPort: port, Target: TargetDNS}
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.proxyRequest(w, r, cfg)
+	}))
+	defer gw.Close()
+
+	conn, status := dialWebSocket(t, gw.Listener.Addr().String())
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade = %d, want 101", status)
+	}
+	welcome := wsFrame([]byte("welcome"), false)
+	got := make([]byte, len(welcome))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.ReadFull(conn, got); err != nil || !bytes.Equal(got, welcome) {
+		t.Fatalf("first frame = %x (%v), want %x", got, err, welcome)
+	}
+	waitFor(t, func() bool { return len(s.tunnels.list()) == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	closed := make(chan struct{})
+	go func() {
+		s.tunnels.closeAll(ctx)
+		close(closed)
+	}()
+
+	rest, _ := io.ReadAll(conn)
+	if !bytes.Equal(rest, closeGoingAway) {
+		t.Errorf("client received %x, want a going-away close frame", rest)
+	}
+	conn.Close() // the client answers the close frame by leaving
+	select {
+	case <-backendEOF:
+	case <-time.After(time.Second):
+		t.Error("backend did not see the client side close")
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("closeAll did not return once the tunnel closed")
+	}
+	if n := len(s.tunnels.list()); n != 0 {
+		t.Errorf("%d tunnels still tracked", n)
+	}
+}
+
+func TestProxyWebSocket_HalfCloseAndBufferedData(t *testing.T) {
+	// The backend reads the client's stream to its end, then answers.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		if _, err := http.ReadRequest(br); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		got, _ := io.ReadAll(br)
+		io.WriteString(conn, "echo:"+string(got))
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(NewFakeRuntime())}
+	cfg := &ContainerConfig{Name: host, TargetPort: port, Target: TargetDNS}
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.proxyRequest(w, r, cfg)
+	}))
+	defer gw.Close()
+
+	conn, err := net.Dial("tcp", gw.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The first bytes travel in the same write as the upgrade request, so
+	// the gateway's HTTP server has them buffered when it hijacks.
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: app\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\nearly ")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade = %v (%v), want 101", resp, err)
+	}
+	io.WriteString(conn, "late")
+	conn.(*net.TCPConn).CloseWrite()
+
+	// The client finished sending; the backend's answer must still arrive.
+	got, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatalf("reading the answer: %v", err)
+	}
+	if string(got) != "echo:early late" {
+		t.Errorf("answer = %q, want %q", got, "echo:early late")
+	}
+}
+
+func TestProxyWebSocket_ForwardsRequestIDAndTraceparent(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	upgrade := make(chan *http.Request, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		upgrade <- req
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	s := &Server{cfg: &GatewayConfig{}, manager: NewContainerManager(NewFakeRuntime())}
+	cfg := &ContainerConfig{Name: host, TargetPort: port, Target: TargetDNS}
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(withTraceContext(withRequestID(r.Context(), "ws-id-1"), r))
+		s.proxyRequest(w, r, cfg)
+	}))
+	defer gw.Close()
+
+	conn, status := dialWebSocket(t, gw.Listener.Addr().String())
+	defer conn.Close()
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade = %d, want 101", status)
+	}
+	req := <-upgrade
+	if got := req.Header.Get("X-Request-ID"); got != "ws-id-1" {
+		t.Errorf("X-Request-ID = %q, want %q", got, "ws-id-1")
+	}
+	if _, ok := parseTraceparent(req.Header.Get("traceparent")); !ok {
+		t.Errorf("traceparent = %q, want a valid W3C trace context", req.Header.Get("traceparent"))
+	}
+}
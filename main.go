@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -9,61 +11,72 @@ import (
 	"docker-gateway/gateway"
 )
 
-var version = "dev"
-
 func main() {
-	// Configure structured JSON logging as the global default.
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
-	slog.Info("starting docker-gateway", "version", version)
+	// `docker-gateway export-state|import-state` talk to the running gateway.
+	if len(os.Args) > 1 && gateway.IsStateCommand(os.Args[1]) {
+		if err := gateway.RunStateCommand(os.Args[1], os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	// `docker-gateway healthcheck` probes the local gateway for HEALTHCHECK.
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		if err := gateway.RunHealthcheckCommand(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	// `docker-gateway -config-schema` prints the JSON Schema of config.yaml.
+	if len(os.Args) > 1 && (os.Args[1] == "-config-schema" || os.Args[1] == "--config-schema") {
+		if err := gateway.WriteConfigSchema(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Configure structured JSON logging as the global default. Records logged
+	// with a request context carry its request_id and trace_id.
+	logOpts := &slog.HandlerOptions{Level: gateway.LogLevel}
+	slog.SetDefault(slog.New(gateway.NewContextHandler(slog.NewJSONHandler(os.Stdout, logOpts))))
+	slog.Info("starting docker-gateway", "version", gateway.Version, "commit", gateway.GetBuildInfo().Commit)
 
 	// Root context — cancelled on SIGTERM / SIGINT for graceful shutdown.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Load YAML configuration (path from CONFIG_PATH env, default /etc/gateway/config.yaml)
+	// Load YAML configuration (path from CONFIG_PATH env, default /etc/gateway/config.yaml,
+	// %ProgramData%\docker-gateway\config.yaml on Windows)
 	cfg, err := gateway.LoadConfig()
 	if err != nil {
 		slog.Error("failed to load config", "error", err)
 		os.Exit(1)
 	}
 
-	// Initialize Docker client
-	dockerClient, err := gateway.NewDockerClient()
-	if err != nil {
-		slog.Error("failed to initialize Docker client", "error", err)
-		os.Exit(1)
+	// Mirror the application log to a rotating file, if configured, and to
+	// the syslog / Loki forwarder (a no-op until a sink is configured)
+	logWriters := []io.Writer{os.Stdout, gateway.ForwardedLog("gateway")}
+	if cfg.Gateway.LogFile.Path != "" {
+		logFile, err := gateway.OpenRotatingFile(cfg.Gateway.LogFile)
+		if err != nil {
+			slog.Error("failed to open log file", "error", err)
+			os.Exit(1)
+		}
+		defer logFile.Close()
+		logWriters = append(logWriters, logFile)
 	}
-	defer dockerClient.Close()
-
-	// Initialize Container Manager
-	manager := gateway.NewContainerManager(dockerClient)
+	logger := slog.New(slog.NewJSONHandler(io.MultiWriter(logWriters...), logOpts))
 
-	// Initialize Cron Scheduler
-	scheduler := gateway.NewScheduleManager(dockerClient, manager)
-
-	// Initialize and start the HTTP server
-	server, err := gateway.NewServer(manager, scheduler, cfg)
+	// Wire the server and every background loop; the Docker client is built
+	// from gateway.docker_host.
+	gw, err := gateway.New(cfg, gateway.WithLogger(logger))
 	if err != nil {
-		slog.Error("failed to initialize server", "error", err)
+		slog.Error("failed to initialize gateway", "error", err)
 		os.Exit(1)
 	}
 
-	// Initialize Auto-Discovery
-	discoveryManager := gateway.NewDiscoveryManager(dockerClient, cfg, server.ReloadConfig)
-	discoveryManager.Start(ctx, cfg.Gateway.DiscoveryInterval)
-	slog.Info("discovery started", "interval", cfg.Gateway.DiscoveryInterval)
-
-	// Start scheduler and register initial jobs.
-	scheduler.Start(ctx)
-	schedLoc, _ := gateway.ResolveLocation(cfg.Gateway.ScheduleTimezone)
-	scheduler.Sync(cfg.Containers, schedLoc)
-	slog.Info("scheduler started")
-
-	// Start idle-watcher goroutine with a callback to get the latest config
-	manager.StartIdleWatcher(ctx, func() []gateway.ContainerConfig {
-		return server.GetConfig().Containers
-	})
-
 	// Signal handling: SIGHUP → hot-reload config, SIGTERM/SIGINT → graceful shutdown.
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
@@ -72,12 +85,10 @@ func main() {
 			switch sig {
 			case syscall.SIGHUP:
 				slog.Info("received SIGHUP, reloading static configuration")
-				newCfg, err := gateway.LoadConfig()
-				if err != nil {
-					slog.Error("hot-reload failed", "error", err)
+				if err := gw.ReloadFromFile(); err != nil {
+					slog.Error("hot-reload failed, keeping the previous configuration", "error", err)
 					continue
 				}
-				discoveryManager.UpdateStaticConfig(newCfg)
 				slog.Info("static configuration reloaded and discovery pass triggered")
 			case syscall.SIGTERM, syscall.SIGINT:
 				slog.Info("received shutdown signal, initiating graceful shutdown", "signal", sig.String())
@@ -87,7 +98,7 @@ func main() {
 		}
 	}()
 
-	if err := server.Start(ctx); err != nil {
+	if err := gw.Run(ctx); err != nil {
 		slog.Error("server error", "error", err)
 		os.Exit(1)
 	}